@@ -0,0 +1,207 @@
+from http import HTTPStatus
+from typing import Any, cast
+from urllib.parse import quote
+
+import httpx
+
+from ...client import AuthenticatedClient, Client
+from ...types import Response, UNSET
+from ... import errors
+
+from ...models.dlq_message import DLQMessage
+from ...models.problem import Problem
+from typing import cast
+
+
+
+def _get_kwargs(
+    seq: int,
+
+) -> dict[str, Any]:
+    
+
+    
+
+    
+
+    _kwargs: dict[str, Any] = {
+        "method": "get",
+        "url": "/dlq/messages/{seq}".format(seq=quote(str(seq), safe=""),),
+    }
+
+
+    return _kwargs
+
+
+
+def _parse_response(*, client: AuthenticatedClient | Client, response: httpx.Response) -> DLQMessage | Problem:
+    if response.status_code == 200:
+        response_200 = DLQMessage.from_dict(response.json())
+
+
+
+        return response_200
+
+    if response.status_code == 401:
+        response_401 = Problem.from_dict(response.json())
+
+
+
+        return response_401
+
+    if response.status_code == 404:
+        response_404 = Problem.from_dict(response.json())
+
+
+
+        return response_404
+
+    if response.status_code == 503:
+        response_503 = Problem.from_dict(response.json())
+
+
+
+        return response_503
+
+    response_default = Problem.from_dict(response.json())
+
+
+
+    return response_default
+
+
+
+def _build_response(*, client: AuthenticatedClient | Client, response: httpx.Response) -> Response[DLQMessage | Problem]:
+    return Response(
+        status_code=HTTPStatus(response.status_code),
+        content=response.content,
+        headers=response.headers,
+        parsed=_parse_response(client=client, response=response),
+    )
+
+
+def sync_detailed(
+    seq: int,
+    *,
+    client: AuthenticatedClient | Client,
+
+) -> Response[DLQMessage | Problem]:
+    """ Get a dead-lettered message
+
+     Returns one message in the DLQ stream with its payload. Read-only (ADR-0040);
+    every call is recorded as an audit event.
+
+    Args:
+        seq (int):
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Response[DLQMessage | Problem]
+     """
+
+
+    kwargs = _get_kwargs(
+        seq=seq,
+
+    )
+
+    response = client.get_httpx_client().request(
+        **kwargs,
+    )
+
+    return _build_response(client=client, response=response)
+
+def sync(
+    seq: int,
+    *,
+    client: AuthenticatedClient | Client,
+
+) -> DLQMessage | Problem | None:
+    """ Get a dead-lettered message
+
+     Returns one message in the DLQ stream with its payload. Read-only (ADR-0040);
+    every call is recorded as an audit event.
+
+    Args:
+        seq (int):
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        DLQMessage | Problem
+     """
+
+
+    return sync_detailed(
+        seq=seq,
+client=client,
+
+    ).parsed
+
+async def asyncio_detailed(
+    seq: int,
+    *,
+    client: AuthenticatedClient | Client,
+
+) -> Response[DLQMessage | Problem]:
+    """ Get a dead-lettered message
+
+     Returns one message in the DLQ stream with its payload. Read-only (ADR-0040);
+    every call is recorded as an audit event.
+
+    Args:
+        seq (int):
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Response[DLQMessage | Problem]
+     """
+
+
+    kwargs = _get_kwargs(
+        seq=seq,
+
+    )
+
+    response = await client.get_async_httpx_client().request(
+        **kwargs
+    )
+
+    return _build_response(client=client, response=response)
+
+async def asyncio(
+    seq: int,
+    *,
+    client: AuthenticatedClient | Client,
+
+) -> DLQMessage | Problem | None:
+    """ Get a dead-lettered message
+
+     Returns one message in the DLQ stream with its payload. Read-only (ADR-0040);
+    every call is recorded as an audit event.
+
+    Args:
+        seq (int):
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        DLQMessage | Problem
+     """
+
+
+    return (await asyncio_detailed(
+        seq=seq,
+client=client,
+
+    )).parsed
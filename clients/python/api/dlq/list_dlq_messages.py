@@ -0,0 +1,252 @@
+from http import HTTPStatus
+from typing import Any, cast
+from urllib.parse import quote
+
+import httpx
+
+from ...client import AuthenticatedClient, Client
+from ...types import Response, UNSET
+from ... import errors
+
+from ...models.dlq_message import DLQMessage
+from ...models.problem import Problem
+from ...types import UNSET, Unset
+from typing import cast
+
+
+
+def _get_kwargs(
+    *,
+    stream: str | Unset = UNSET,
+    consumer: str | Unset = UNSET,
+    limit: int | Unset = 100,
+
+) -> dict[str, Any]:
+    
+
+    
+
+    params: dict[str, Any] = {}
+
+    params["stream"] = stream
+
+    params["consumer"] = consumer
+
+    params["limit"] = limit
+
+
+    params = {k: v for k, v in params.items() if v is not UNSET and v is not None}
+
+
+    _kwargs: dict[str, Any] = {
+        "method": "get",
+        "url": "/dlq/messages",
+        "params": params,
+    }
+
+
+    return _kwargs
+
+
+
+def _parse_response(*, client: AuthenticatedClient | Client, response: httpx.Response) -> Problem | list[DLQMessage]:
+    if response.status_code == 200:
+        response_200 = []
+        _response_200 = response.json()
+        for response_200_item_data in (_response_200):
+            response_200_item = DLQMessage.from_dict(response_200_item_data)
+
+
+
+            response_200.append(response_200_item)
+
+        return response_200
+
+    if response.status_code == 401:
+        response_401 = Problem.from_dict(response.json())
+
+
+
+        return response_401
+
+    if response.status_code == 503:
+        response_503 = Problem.from_dict(response.json())
+
+
+
+        return response_503
+
+    response_default = Problem.from_dict(response.json())
+
+
+
+    return response_default
+
+
+
+def _build_response(*, client: AuthenticatedClient | Client, response: httpx.Response) -> Response[Problem | list[DLQMessage]]:
+    return Response(
+        status_code=HTTPStatus(response.status_code),
+        content=response.content,
+        headers=response.headers,
+        parsed=_parse_response(client=client, response=response),
+    )
+
+
+def sync_detailed(
+    *,
+    client: AuthenticatedClient | Client,
+    stream: str | Unset = UNSET,
+    consumer: str | Unset = UNSET,
+    limit: int | Unset = 100,
+
+) -> Response[Problem | list[DLQMessage]]:
+    """ List dead-lettered messages
+
+     Returns the messages in the DLQ stream, oldest first, with their original subject,
+    stream sequence, delivery count and age. Payloads are omitted; fetch one with
+    `getDLQMessage`. Read-only: replay and purge are operator actions taken with the
+    `dlq` command (ADR-0040). Every call is recorded as an audit event.
+
+    Args:
+        stream (str | Unset):
+        consumer (str | Unset):
+        limit (int | Unset):  Default: 100.
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Response[Problem | list[DLQMessage]]
+     """
+
+
+    kwargs = _get_kwargs(
+        stream=stream,
+consumer=consumer,
+limit=limit,
+
+    )
+
+    response = client.get_httpx_client().request(
+        **kwargs,
+    )
+
+    return _build_response(client=client, response=response)
+
+def sync(
+    *,
+    client: AuthenticatedClient | Client,
+    stream: str | Unset = UNSET,
+    consumer: str | Unset = UNSET,
+    limit: int | Unset = 100,
+
+) -> Problem | list[DLQMessage] | None:
+    """ List dead-lettered messages
+
+     Returns the messages in the DLQ stream, oldest first, with their original subject,
+    stream sequence, delivery count and age. Payloads are omitted; fetch one with
+    `getDLQMessage`. Read-only: replay and purge are operator actions taken with the
+    `dlq` command (ADR-0040). Every call is recorded as an audit event.
+
+    Args:
+        stream (str | Unset):
+        consumer (str | Unset):
+        limit (int | Unset):  Default: 100.
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Problem | list[DLQMessage]
+     """
+
+
+    return sync_detailed(
+        client=client,
+stream=stream,
+consumer=consumer,
+limit=limit,
+
+    ).parsed
+
+async def asyncio_detailed(
+    *,
+    client: AuthenticatedClient | Client,
+    stream: str | Unset = UNSET,
+    consumer: str | Unset = UNSET,
+    limit: int | Unset = 100,
+
+) -> Response[Problem | list[DLQMessage]]:
+    """ List dead-lettered messages
+
+     Returns the messages in the DLQ stream, oldest first, with their original subject,
+    stream sequence, delivery count and age. Payloads are omitted; fetch one with
+    `getDLQMessage`. Read-only: replay and purge are operator actions taken with the
+    `dlq` command (ADR-0040). Every call is recorded as an audit event.
+
+    Args:
+        stream (str | Unset):
+        consumer (str | Unset):
+        limit (int | Unset):  Default: 100.
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Response[Problem | list[DLQMessage]]
+     """
+
+
+    kwargs = _get_kwargs(
+        stream=stream,
+consumer=consumer,
+limit=limit,
+
+    )
+
+    response = await client.get_async_httpx_client().request(
+        **kwargs
+    )
+
+    return _build_response(client=client, response=response)
+
+async def asyncio(
+    *,
+    client: AuthenticatedClient | Client,
+    stream: str | Unset = UNSET,
+    consumer: str | Unset = UNSET,
+    limit: int | Unset = 100,
+
+) -> Problem | list[DLQMessage] | None:
+    """ List dead-lettered messages
+
+     Returns the messages in the DLQ stream, oldest first, with their original subject,
+    stream sequence, delivery count and age. Payloads are omitted; fetch one with
+    `getDLQMessage`. Read-only: replay and purge are operator actions taken with the
+    `dlq` command (ADR-0040). Every call is recorded as an audit event.
+
+    Args:
+        stream (str | Unset):
+        consumer (str | Unset):
+        limit (int | Unset):  Default: 100.
+
+    Raises:
+        errors.UnexpectedStatus: If the server returns an undocumented status code and Client.raise_on_unexpected_status is True.
+        httpx.TimeoutException: If the request takes longer than Client.timeout.
+
+    Returns:
+        Problem | list[DLQMessage]
+     """
+
+
+    return (await asyncio_detailed(
+        client=client,
+stream=stream,
+consumer=consumer,
+limit=limit,
+
+    )).parsed
@@ -2,6 +2,8 @@
 
 from .calendar_instance import CalendarInstance
 from .calendar_instance_attendees_item import CalendarInstanceAttendeesItem
+from .dlq_message import DLQMessage
+from .dlq_message_original_headers import DLQMessageOriginalHeaders
 from .person import Person
 from .ping_response_200 import PingResponse200
 from .problem import Problem
@@ -11,6 +13,8 @@ from .provider_suggestion import ProviderSuggestion
 __all__ = (
     "CalendarInstance",
     "CalendarInstanceAttendeesItem",
+    "DLQMessage",
+    "DLQMessageOriginalHeaders",
     "Person",
     "PingResponse200",
     "Problem",
@@ -0,0 +1,243 @@
+from __future__ import annotations
+
+from collections.abc import Mapping
+from typing import Any, TypeVar, BinaryIO, TextIO, TYPE_CHECKING, Generator
+
+from attrs import define as _attrs_define
+from attrs import field as _attrs_field
+
+from ..types import UNSET, Unset
+
+from ..types import UNSET, Unset
+from typing import cast
+import datetime
+
+if TYPE_CHECKING:
+  from ..models.dlq_message_original_headers import DLQMessageOriginalHeaders
+
+
+
+
+
+T = TypeVar("T", bound="DLQMessage")
+
+
+
+@_attrs_define
+class DLQMessage:
+    """ A message dead-lettered to the DLQ stream after exhausting its consumer's
+    MaxDeliver (ADR-0022). The original subject, headers, stream sequence, delivery
+    count and failure details are recorded by the DLQ forwarder; messages
+    dead-lettered before it recorded them carry only the stream and consumer named
+    by their DLQ subject.
+
+        Example:
+            {'seq': 12, 'subject': 'dlq.ha_events.engine_processor', 'stream': 'HA_EVENTS', 'consumer': 'engine_processor',
+                'original_subject': 'ha.events.light.kitchen', 'original_seq': 409, 'deliveries': 5, 'first_failed_at':
+                '2026-06-26T12:59:45Z', 'last_failed_at': '2026-06-26T13:00:00Z', 'last_error': 'rules: kitchen_lights: call
+                light.turn_on: context deadline exceeded', 'dead_lettered_at': '2026-06-26T13:00:00Z', 'age_seconds': 3600}
+
+        Attributes:
+            seq (int): The message's sequence in the DLQ stream; identifies it to the `dlq` command for replay and purge.
+            subject (str): The DLQ subject, `dlq.{stream}.{consumer}`.
+            stream (str): The stream the message was consumed from.
+            consumer (str): The durable consumer that exhausted MaxDeliver.
+            dead_lettered_at (datetime.datetime): When the message was dead-lettered, as an RFC 3339 timestamp.
+            age_seconds (int): Seconds since the message was dead-lettered.
+            original_subject (str | Unset): The subject the message was originally published on, when recorded.
+            original_seq (int | Unset): The message's sequence in its original stream, when recorded.
+            deliveries (int | Unset): Delivery attempts before the message was dead-lettered, when recorded.
+            first_failed_at (datetime.datetime | Unset): When the consumer first failed to process the message, when
+                recorded.
+            last_failed_at (datetime.datetime | Unset): When the consumer last failed to process the message, when
+                recorded.
+            last_error (str | Unset): The error of the last failed attempt, on one line and truncated to 1024 bytes, when
+                recorded.
+            original_headers (DLQMessageOriginalHeaders | Unset): The original message's headers, including its
+                `Nats-Msg-Id` and W3C trace context, when recorded. Returned only by `getDLQMessage`.
+            payload (str | Unset): The message payload as text. Returned only by `getDLQMessage`.
+     """
+
+    seq: int
+    subject: str
+    stream: str
+    consumer: str
+    dead_lettered_at: datetime.datetime
+    age_seconds: int
+    original_subject: str | Unset = UNSET
+    original_seq: int | Unset = UNSET
+    deliveries: int | Unset = UNSET
+    first_failed_at: datetime.datetime | Unset = UNSET
+    last_failed_at: datetime.datetime | Unset = UNSET
+    last_error: str | Unset = UNSET
+    original_headers: DLQMessageOriginalHeaders | Unset = UNSET
+    payload: str | Unset = UNSET
+    additional_properties: dict[str, Any] = _attrs_field(init=False, factory=dict)
+
+
+
+
+
+    def to_dict(self) -> dict[str, Any]:
+        from ..models.dlq_message_original_headers import DLQMessageOriginalHeaders
+        seq = self.seq
+
+        subject = self.subject
+
+        stream = self.stream
+
+        consumer = self.consumer
+
+        dead_lettered_at = self.dead_lettered_at.isoformat()
+
+        age_seconds = self.age_seconds
+
+        original_subject = self.original_subject
+
+        original_seq = self.original_seq
+
+        deliveries = self.deliveries
+
+        first_failed_at: str | Unset = UNSET
+        if not isinstance(self.first_failed_at, Unset):
+            first_failed_at = self.first_failed_at.isoformat()
+
+        last_failed_at: str | Unset = UNSET
+        if not isinstance(self.last_failed_at, Unset):
+            last_failed_at = self.last_failed_at.isoformat()
+
+        last_error = self.last_error
+
+        original_headers: dict[str, Any] | Unset = UNSET
+        if not isinstance(self.original_headers, Unset):
+            original_headers = self.original_headers.to_dict()
+
+        payload = self.payload
+
+
+        field_dict: dict[str, Any] = {}
+        field_dict.update(self.additional_properties)
+        field_dict.update({
+            "seq": seq,
+            "subject": subject,
+            "stream": stream,
+            "consumer": consumer,
+            "dead_lettered_at": dead_lettered_at,
+            "age_seconds": age_seconds,
+        })
+        if original_subject is not UNSET:
+            field_dict["original_subject"] = original_subject
+        if original_seq is not UNSET:
+            field_dict["original_seq"] = original_seq
+        if deliveries is not UNSET:
+            field_dict["deliveries"] = deliveries
+        if first_failed_at is not UNSET:
+            field_dict["first_failed_at"] = first_failed_at
+        if last_failed_at is not UNSET:
+            field_dict["last_failed_at"] = last_failed_at
+        if last_error is not UNSET:
+            field_dict["last_error"] = last_error
+        if original_headers is not UNSET:
+            field_dict["original_headers"] = original_headers
+        if payload is not UNSET:
+            field_dict["payload"] = payload
+
+        return field_dict
+
+
+
+    @classmethod
+    def from_dict(cls: type[T], src_dict: Mapping[str, Any]) -> T:
+        from ..models.dlq_message_original_headers import DLQMessageOriginalHeaders
+        d = dict(src_dict)
+        seq = d.pop("seq")
+
+        subject = d.pop("subject")
+
+        stream = d.pop("stream")
+
+        consumer = d.pop("consumer")
+
+        dead_lettered_at = datetime.datetime.fromisoformat(d.pop("dead_lettered_at"))
+
+
+
+
+        age_seconds = d.pop("age_seconds")
+
+        original_subject = d.pop("original_subject", UNSET)
+
+        original_seq = d.pop("original_seq", UNSET)
+
+        deliveries = d.pop("deliveries", UNSET)
+
+        _first_failed_at = d.pop("first_failed_at", UNSET)
+        first_failed_at: datetime.datetime | Unset
+        if isinstance(_first_failed_at,  Unset):
+            first_failed_at = UNSET
+        else:
+            first_failed_at = datetime.datetime.fromisoformat(_first_failed_at)
+
+
+
+
+        _last_failed_at = d.pop("last_failed_at", UNSET)
+        last_failed_at: datetime.datetime | Unset
+        if isinstance(_last_failed_at,  Unset):
+            last_failed_at = UNSET
+        else:
+            last_failed_at = datetime.datetime.fromisoformat(_last_failed_at)
+
+
+
+
+        last_error = d.pop("last_error", UNSET)
+
+        _original_headers = d.pop("original_headers", UNSET)
+        original_headers: DLQMessageOriginalHeaders | Unset
+        if isinstance(_original_headers,  Unset):
+            original_headers = UNSET
+        else:
+            original_headers = DLQMessageOriginalHeaders.from_dict(_original_headers)
+
+
+
+
+        payload = d.pop("payload", UNSET)
+
+        dlq_message = cls(
+            seq=seq,
+            subject=subject,
+            stream=stream,
+            consumer=consumer,
+            dead_lettered_at=dead_lettered_at,
+            age_seconds=age_seconds,
+            original_subject=original_subject,
+            original_seq=original_seq,
+            deliveries=deliveries,
+            first_failed_at=first_failed_at,
+            last_failed_at=last_failed_at,
+            last_error=last_error,
+            original_headers=original_headers,
+            payload=payload,
+        )
+
+
+        dlq_message.additional_properties = d
+        return dlq_message
+
+    @property
+    def additional_keys(self) -> list[str]:
+        return list(self.additional_properties.keys())
+
+    def __getitem__(self, key: str) -> Any:
+        return self.additional_properties[key]
+
+    def __setitem__(self, key: str, value: Any) -> None:
+        self.additional_properties[key] = value
+
+    def __delitem__(self, key: str) -> None:
+        del self.additional_properties[key]
+
+    def __contains__(self, key: str) -> bool:
+        return key in self.additional_properties
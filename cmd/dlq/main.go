@@ -0,0 +1,311 @@
+// Command dlq inspects and recovers messages dead-lettered to the DLQ stream
+// (ADR-0022). It lists them with their original subject, stream sequence,
+// delivery count, age and last error; prints one with its headers and payload;
+// replays messages to their original subject under a fresh Nats-Msg-Id; and
+// purges them by filter. Every
+// action is recorded as an audit event from source ruby_dlq (ADR-0019).
+//
+// It connects as the admin NATS user, configured through the same environment
+// as the services (pkg/boot; VAULT_NKEY_PATH defaults to the admin seed).
+// scripts/dlq.sh sets that environment for a deployment.
+//
+// Usage:
+//
+//	go run ./cmd/dlq list [--stream S] [--consumer C] [--older-than D] [--limit N]
+//	go run ./cmd/dlq view <seq>
+//	go run ./cmd/dlq replay <seq>...
+//	go run ./cmd/dlq purge (--seq N,... | --stream S | --consumer C | --older-than D | --all)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/audit"
+	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/dlq"
+)
+
+const usage = `usage: dlq <command> [flags]
+
+Commands:
+  list     list dead-lettered messages, oldest first
+  view     print one message with its headers and payload
+  replay   publish messages back to their original subject
+  purge    delete messages matching a filter
+
+Run "dlq <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	parse, ok := map[string]func([]string) (action, error){
+		"list":   parseList,
+		"view":   parseView,
+		"replay": parseReplay,
+		"purge":  parsePurge,
+	}[cmd]
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "dlq: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	run, err := parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "dlq %s: %v\n", cmd, err)
+		os.Exit(2)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	nc, err := connect()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "dlq: %v\n", err)
+		os.Exit(1)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "dlq: jetstream context: %v\n", err)
+		os.Exit(1)
+	}
+	auditPub := audit.NewPublisher(nc, "ruby_dlq", logger)
+
+	err = run(dlq.New(js, auditPub), os.Stdout)
+
+	// Hand the audit records to the server before exiting.
+	auditPub.Close()
+	_ = nc.Flush()
+	nc.Close()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "dlq %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// action runs a parsed command against the DLQ, writing its report to w.
+type action func(c *dlq.Client, w io.Writer) error
+
+// connect opens a NATS connection as the admin user, the way the services do.
+func connect() (*nats.Conn, error) {
+	cfg := boot.LoadConfig("admin")
+	seed, err := boot.FetchNATSSeed(cfg.VaultAddr, cfg.VaultToken, cfg.VaultNKEYPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: fetch NATS seed: %w", err)
+	}
+	// The context only bounds the certificate renewal goroutine, which a
+	// command this short-lived never needs.
+	nc, err := boot.BootstrapNATSTLS(context.Background(), cfg, "ruby-core-dlq", seed)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	return nc, nil
+}
+
+// filterFlags registers the flags that select messages and returns a function
+// that builds the Filter once fs has been parsed.
+func filterFlags(fs *flag.FlagSet) func() (dlq.Filter, error) {
+	stream := fs.String("stream", "", "only messages from this stream (e.g. HA_EVENTS)")
+	consumer := fs.String("consumer", "", "only messages from this consumer (e.g. engine_processor)")
+	olderThan := fs.Duration("older-than", 0, "only messages dead-lettered at least this long ago (e.g. 24h)")
+	return func() (dlq.Filter, error) {
+		f := dlq.Filter{Stream: *stream, Consumer: *consumer}
+		if *olderThan < 0 {
+			return f, errors.New("--older-than must not be negative")
+		}
+		if *olderThan > 0 {
+			f.Before = time.Now().Add(-*olderThan)
+		}
+		return f, nil
+	}
+}
+
+func parseList(args []string) (action, error) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	limit := fs.Int("limit", 100, "list at most this many messages (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	f, err := filter()
+	if err != nil {
+		return nil, err
+	}
+	f.Limit = *limit
+	return func(c *dlq.Client, w io.Writer) error { return list(c, f, w) }, nil
+}
+
+func list(c *dlq.Client, f dlq.Filter, w io.Writer) error {
+	msgs, err := c.List(f)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SEQ\tAGE\tSTREAM\tSEQ IN STREAM\tDELIVERIES\tCONSUMER\tSUBJECT\tLAST ERROR")
+	for _, m := range msgs {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Seq, m.Age(now).Truncate(time.Second),
+			m.Stream, orDash(m.OrigSeq), orDash(uint64(m.Deliveries)), m.Consumer, orUnknown(m.OrigSubject), //nolint:gosec // G115: delivery counts are small and non-negative
+			truncate(orUnknown(m.LastError), listErrorWidth))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "%d message(s)\n", len(msgs))
+	return nil
+}
+
+func parseView(args []string) (action, error) {
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, errors.New("expected exactly one DLQ sequence")
+	}
+	seqs, err := parseSeqs(fs.Args())
+	if err != nil {
+		return nil, err
+	}
+	return func(c *dlq.Client, w io.Writer) error { return view(c, seqs[0], w) }, nil
+}
+
+func view(c *dlq.Client, seq uint64, w io.Writer) error {
+	m, err := c.Get(seq)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Sequence:       %d\n", m.Seq)
+	_, _ = fmt.Fprintf(w, "DLQ subject:    %s\n", m.Subject)
+	_, _ = fmt.Fprintf(w, "Dead-lettered:  %s (%s ago)\n", m.Time.UTC().Format(time.RFC3339), m.Age(time.Now()).Truncate(time.Second))
+	_, _ = fmt.Fprintf(w, "Stream:         %s\n", m.Stream)
+	_, _ = fmt.Fprintf(w, "Seq in stream:  %s\n", orDash(m.OrigSeq))
+	_, _ = fmt.Fprintf(w, "Consumer:       %s\n", m.Consumer)
+	_, _ = fmt.Fprintf(w, "Deliveries:     %s\n", orDash(uint64(m.Deliveries))) //nolint:gosec // G115: delivery counts are small and non-negative
+	_, _ = fmt.Fprintf(w, "Subject:        %s\n", orUnknown(m.OrigSubject))
+	if !m.FirstFailure.IsZero() {
+		_, _ = fmt.Fprintf(w, "First failure:  %s\n", m.FirstFailure.UTC().Format(time.RFC3339))
+		_, _ = fmt.Fprintf(w, "Last failure:   %s\n", m.LastFailure.UTC().Format(time.RFC3339))
+	}
+	_, _ = fmt.Fprintf(w, "Last error:     %s\n", orUnknown(m.LastError))
+	for _, k := range slices.Sorted(maps.Keys(m.Header)) {
+		for _, v := range m.Header[k] {
+			_, _ = fmt.Fprintf(w, "Header:         %s: %s\n", k, v)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%s\n", m.Data)
+	return nil
+}
+
+func parseReplay(args []string) (action, error) {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New("expected one or more DLQ sequences")
+	}
+	seqs, err := parseSeqs(fs.Args())
+	if err != nil {
+		return nil, err
+	}
+	return func(c *dlq.Client, w io.Writer) error { return replay(c, seqs, w) }, nil
+}
+
+func replay(c *dlq.Client, seqs []uint64, w io.Writer) error {
+	var failed int
+	for _, seq := range seqs {
+		id, err := c.Replay(seq)
+		if err != nil {
+			failed++
+			_, _ = fmt.Fprintf(w, "%d: %v\n", seq, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%d: replayed as %s\n", seq, id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d message(s) not replayed", failed, len(seqs))
+	}
+	return nil
+}
+
+func parsePurge(args []string) (action, error) {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	filter := filterFlags(fs)
+	seqList := fs.String("seq", "", "only these comma-separated DLQ sequences")
+	all := fs.Bool("all", false, "purge every message in the DLQ")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	f, err := filter()
+	if err != nil {
+		return nil, err
+	}
+	if *seqList != "" {
+		if f.Seqs, err = parseSeqs(strings.Split(*seqList, ",")); err != nil {
+			return nil, err
+		}
+	}
+	if f.Stream == "" && f.Consumer == "" && f.Before.IsZero() && len(f.Seqs) == 0 && !*all {
+		return nil, errors.New("refusing to purge without a filter; pass --all to purge every message")
+	}
+	return func(c *dlq.Client, w io.Writer) error {
+		n, err := c.Purge(f)
+		_, _ = fmt.Fprintf(w, "purged %d message(s)\n", n)
+		return err
+	}, nil
+}
+
+func parseSeqs(args []string) ([]uint64, error) {
+	seqs := make([]uint64, 0, len(args))
+	for _, a := range args {
+		seq, err := strconv.ParseUint(strings.TrimSpace(a), 10, 64)
+		if err != nil || seq == 0 {
+			return nil, fmt.Errorf("invalid DLQ sequence %q", a)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, nil
+}
+
+// listErrorWidth is how much of each message's last error list shows; view
+// prints it in full.
+const listErrorWidth = 60
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func orDash(n uint64) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "(unknown)"
+	}
+	return s
+}
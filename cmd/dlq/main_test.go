@@ -0,0 +1,40 @@
+//go:build fast
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePurge_RequiresFilterOrAll(t *testing.T) {
+	if _, err := parsePurge(nil); err == nil || !strings.Contains(err.Error(), "--all") {
+		t.Errorf("purge with no filter: error = %v, want a refusal naming --all", err)
+	}
+	for _, args := range [][]string{
+		{"--all"},
+		{"--stream", "HA_EVENTS"},
+		{"--consumer", "engine_processor"},
+		{"--older-than", "24h"},
+		{"--seq", "3,5"},
+	} {
+		if _, err := parsePurge(args); err != nil {
+			t.Errorf("purge %v: %v", args, err)
+		}
+	}
+	if _, err := parsePurge([]string{"--older-than", "-1h"}); err == nil {
+		t.Error("purge with a negative --older-than was accepted")
+	}
+}
+
+func TestParseSeqs(t *testing.T) {
+	seqs, err := parseSeqs([]string{"3", " 5"})
+	if err != nil || len(seqs) != 2 || seqs[0] != 3 || seqs[1] != 5 {
+		t.Errorf("parseSeqs = %v, %v; want [3 5]", seqs, err)
+	}
+	for _, bad := range []string{"0", "-1", "x", ""} {
+		if _, err := parseSeqs([]string{bad}); err == nil {
+			t.Errorf("parseSeqs(%q) succeeded", bad)
+		}
+	}
+}
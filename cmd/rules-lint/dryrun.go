@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/presence_notify"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/replay"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// capture records the messages processors would have published.
+type capture struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (c *capture) Publish(subject string, data []byte) error {
+	return c.PublishMsg(&nats.Msg{Subject: subject, Data: data})
+}
+
+func (c *capture) PublishMsg(m *nats.Msg) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, m)
+	return nil
+}
+
+// take returns and clears the recorded messages.
+func (c *capture) take() []*nats.Msg {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msgs := c.msgs
+	c.msgs = nil
+	return msgs
+}
+
+func dryRunFile(cfg *config.CompiledConfig, path string, w io.Writer) error {
+	f, err := os.Open(path) //nolint:gosec // path is an operator-supplied CLI argument
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return dryRun(cfg, f, w)
+}
+
+// dryRun feeds each NDJSON event in r through the presence_notify and rules
+// processors, in order, and writes the rules that fired and the messages they
+// published to w. Actions after a delay run only if the input contains the
+// firing of the delay's schedule.
+func dryRun(cfg *config.CompiledConfig, r io.Reader, w io.Writer) error {
+	quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+	presenceCfg := processor.Config{RuleCfg: cfg, State: state.NewMemory(0)}
+	// Schedules, including those of delays, are registered in memory and never
+	// fire; a recorded ruby_engine.events.schedule.rules.{id} event stands in
+	// for a firing.
+	rulesCfg := processor.Config{
+		RuleCfg:   cfg,
+		State:     state.NewMemory(0),
+		Schedules: scheduler.New(state.NewMemory(0), nil, quiet).For("rules"),
+	}
+	pub := &capture{}
+
+	presence := presence_notify.New(quiet)
+	if err := presence.InitializeOffline(presenceCfg, pub); err != nil {
+		return err
+	}
+	proc := rules.New(quiet, presence_notify.Handles)
+	if err := proc.InitializeOffline(rulesCfg, pub); err != nil {
+		return err
+	}
+	defer proc.Shutdown()
+
+	ctx := context.Background()
+	var nEvents, nFired, nMsgs int
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		evt, err := replay.ParseLine(sc.Bytes())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		subject, data := evt.Subject, evt.Data
+		nEvents++
+
+		var errs []error
+		for _, p := range []processor.Processor{presence, proc} {
+			if slices.ContainsFunc(p.Subscriptions(), func(pat string) bool { return natsx.MatchSubject(pat, subject) }) {
+				errs = append(errs, p.ProcessEvent(ctx, subject, data))
+			}
+		}
+
+		msgs := pub.take()
+		err = errors.Join(errs...)
+		if len(msgs) == 0 && err == nil {
+			continue
+		}
+		fired := firedRules(msgs)
+		nFired += len(fired)
+		nMsgs += len(msgs)
+		_, _ = fmt.Fprintf(w, "line %d: %s fired %v\n", line, subject, fired)
+		for _, m := range msgs {
+			_, _ = fmt.Fprintf(w, "  publish %s %s\n", m.Subject, describe(m.Data))
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "  error: %v\n", err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "dry run: %d event(s), %d rule firing(s), %d message(s)\n", nEvents, nFired, nMsgs)
+	return nil
+}
+
+// firedRules returns the distinct rule names carried by the commands in msgs.
+func firedRules(msgs []*nats.Msg) []string {
+	var out []string
+	for _, m := range msgs {
+		if !strings.HasPrefix(m.Subject, schemas.CommandSubjectPrefix) {
+			continue
+		}
+		var evt schemas.CloudEvent
+		if json.Unmarshal(m.Data, &evt) != nil {
+			continue
+		}
+		if name, ok := evt.Data["rule"].(string); ok && !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// describe renders a published CloudEvent as its type and data.
+func describe(data []byte) string {
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return string(data)
+	}
+	b, _ := json.Marshal(evt.Data)
+	return evt.Type + " " + string(b)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/presence_notify"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+)
+
+// consumedSubjects are the filter subjects of the engine's pull consumers; a
+// trigger outside them never reaches a processor.
+var consumedSubjects = engineSubjects(natsx.Manifest())
+
+// engineSubjects returns the filter subjects of the engine_* consumers in the
+// topology manifest, which services/engine/main.go creates one per processor.
+func engineSubjects(t natsx.Topology) []string {
+	var out []string
+	for _, c := range t.Consumers {
+		if strings.HasPrefix(c.Durable, "engine_") && c.FilterSubject != "" {
+			out = append(out, c.FilterSubject)
+		}
+	}
+	return out
+}
+
+// finding is a lint warning about a rule that loads but will not behave as
+// written.
+type finding struct {
+	rule    string
+	message string
+}
+
+// lint flags the rules in cfg that no processor will act on, or that depend on
+// data the gateway does not forward.
+func lint(cfg *config.CompiledConfig) []finding {
+	var out []finding
+	for _, rule := range cfg.Rules {
+		add := func(format string, args ...any) {
+			out = append(out, finding{rule: rule.Name, message: fmt.Sprintf(format, args...)})
+		}
+
+		subject, err := rules.TriggerSubject(rule.Trigger)
+		if err != nil {
+			add("unusable trigger, so no processor subscribes to it: %v", err)
+			continue
+		}
+		if !consumed(subject) {
+			add("trigger subject %s is not consumed by the engine (consumers: %s)",
+				subject, strings.Join(consumedSubjects, ", "))
+			continue
+		}
+		if rule.Trigger.Source == "ha" {
+			for _, field := range conditionFields(rule.Conditions) {
+				if !forwarded(cfg.Passlist, rule.Trigger.Type, field) {
+					add("condition field %q is not in the %s passlist, so the gateway drops it; add it to trigger.attributes",
+						field, rule.Trigger.Type)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// owner names the processor that acts on rule.
+func owner(rule schemas.Rule) string {
+	if presence_notify.Handles(rule) {
+		return "presence_notify"
+	}
+	return "rules"
+}
+
+// consumed reports whether events on subject (which may end in ">") are
+// delivered by one of the engine's consumers.
+func consumed(subject string) bool {
+	probe := strings.Replace(subject, ">", "x", 1)
+	return slices.ContainsFunc(consumedSubjects, func(pattern string) bool {
+		return natsx.MatchSubject(pattern, probe)
+	})
+}
+
+// forwarded reports whether the gateway's lean projection keeps field for
+// domain. "state" is always kept, and a domain without a passlist keeps all.
+func forwarded(passlist map[string][]string, domain, field string) bool {
+	attrs := passlist[domain]
+	return field == "state" || len(attrs) == 0 || slices.Contains(attrs, field)
+}
+
+// conditionFields returns the event data fields read by conds, recursing into
+// composites.
+func conditionFields(conds []schemas.Condition) []string {
+	var out []string
+	for _, c := range conds {
+		if c.Field != "" && !slices.Contains(out, c.Field) {
+			out = append(out, c.Field)
+		}
+		for _, f := range conditionFields(c.Conditions) {
+			if !slices.Contains(out, f) {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
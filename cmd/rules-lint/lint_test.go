@@ -0,0 +1,122 @@
+//go:build fast
+
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+)
+
+func notify(title string) []schemas.Action {
+	return []schemas.Action{{Type: schemas.ActionTypeNotify, Params: map[string]string{
+		"title": title, "message": "m", "device": "d",
+	}}}
+}
+
+func TestEngineSubjects_FromManifest(t *testing.T) {
+	want := []string{"ha.events.>", "ruby_presence.events.>", "ruby_engine.events.schedule.>"}
+	if got := engineSubjects(natsx.Manifest()); !slices.Equal(got, want) {
+		t.Errorf("engineSubjects = %v, want %v", got, want)
+	}
+
+	extra := natsx.Topology{Consumers: []natsx.PullConsumerConfig{
+		natsx.DefaultPullConsumerConfig("NEW", "engine_*", "new.events.>"),
+		natsx.DefaultPullConsumerConfig("COMMANDS", "notifier_processor", "ruby_engine.commands.notify.>"),
+	}}
+	if got := engineSubjects(extra); !slices.Equal(got, []string{"new.events.>"}) {
+		t.Errorf("engineSubjects = %v, want only the engine consumer", got)
+	}
+}
+
+func TestLint_FlagsRulesNoProcessorActsOn(t *testing.T) {
+	above := 25.0
+	cfg := &config.CompiledConfig{
+		Passlist: map[string][]string{"sensor": {"state"}},
+		Rules: []schemas.Rule{
+			{Name: "ok", Trigger: schemas.Trigger{Source: "ha", Type: "person", ID: "wife"}, Actions: notify("ok")},
+			{Name: "scheduled", Trigger: schemas.Trigger{Source: "schedule", ID: "morning", Cron: "0 7 * * *"}, Actions: notify("ok")},
+			{Name: "unconsumed", Trigger: schemas.Trigger{Source: "calendar", Type: "event"}, Actions: notify("x")},
+			{Name: "bad_token", Trigger: schemas.Trigger{Source: "ha", Type: "Sensor"}, Actions: notify("x")},
+			{
+				Name:       "filtered_attr",
+				Trigger:    schemas.Trigger{Source: "ha", Type: "sensor", ID: "temp"},
+				Conditions: []schemas.Condition{{Type: schemas.ConditionTypeNumericState, Field: "temperature", Above: &above}},
+				Actions:    notify("x"),
+			},
+		},
+	}
+
+	got := map[string]string{}
+	for _, f := range lint(cfg) {
+		got[f.rule] = f.message
+	}
+	for _, rule := range []string{"ok", "scheduled"} {
+		if msg, ok := got[rule]; ok {
+			t.Errorf("valid rule %s flagged: %s", rule, msg)
+		}
+	}
+	for rule, want := range map[string]string{
+		"unconsumed":    "not consumed by the engine",
+		"bad_token":     "unusable trigger",
+		"filtered_attr": `"temperature" is not in the sensor passlist`,
+	} {
+		if !strings.Contains(got[rule], want) {
+			t.Errorf("%s: finding %q does not contain %q", rule, got[rule], want)
+		}
+	}
+}
+
+func TestDryRun_PrintsFiredRulesAndCommands(t *testing.T) {
+	cfg := &config.CompiledConfig{Rules: []schemas.Rule{
+		{
+			Name:       "door_opened",
+			Trigger:    schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+			Conditions: []schemas.Condition{{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"}},
+			Actions:    notify("Door opened"),
+		},
+		{
+			Name:       "wife_home",
+			Trigger:    schemas.Trigger{Source: "ha", Type: "person", ID: "wife"},
+			Conditions: []schemas.Condition{{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "home"}},
+			Actions:    notify("Welcome"),
+		},
+	}}
+	events := strings.Join([]string{
+		`{"id":"1","source":"ha","type":"state_changed","subject":"binary_sensor.front_door","data":{"state":"on"}}`,
+		`{"id":"2","source":"ha","type":"state_changed","subject":"binary_sensor.front_door","data":{"state":"on"}}`,
+		``,
+		`{"subject":"ha.events.person.wife","event":{"id":"3","source":"ha","type":"state_changed","data":{"state":"home"}}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := dryRun(cfg, strings.NewReader(events), &out); err != nil {
+		t.Fatalf("dryRun: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"line 1: ha.events.binary_sensor.front_door fired [door_opened]",
+		"line 4: ha.events.person.wife fired [wife_home]",
+		`command.notify {"device":"d","message":"m","rule":"door_opened","title":"Door opened"}`,
+		"dry run: 3 event(s), 2 rule firing(s), 2 message(s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "line 2:") {
+		t.Errorf("repeated state must not re-fire:\n%s", got)
+	}
+}
+
+func TestDryRun_RejectsUnroutableEvent(t *testing.T) {
+	err := dryRun(&config.CompiledConfig{}, strings.NewReader(`{"id":"1","source":"ruby_presence","type":"state"}`), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected a line-numbered error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Command rules-lint checks engine rule files before they are deployed. It
+// validates every *.yaml file in a rules directory the way the engine does at
+// boot, reporting each schema error with its file and line, then flags valid
+// rules that no processor will act on (e.g. a trigger source the engine does
+// not consume, or a condition on an attribute the gateway filters out).
+//
+// With --events it also dry-runs the rules against a recorded NDJSON file of
+// CloudEvents, printing which rules fire for each event and the commands they
+// would publish. Nothing is published and no NATS connection is made; entity
+// state is held in memory for the length of the run.
+//
+// Each NDJSON line is either {"subject": "<nats subject>", "event": <CloudEvent>}
+// or a bare HA CloudEvent, whose NATS subject is derived from its "subject"
+// (entity ID) as ha.events.{domain}.{name}.
+//
+// Usage:
+//
+//	go run ./cmd/rules-lint [--dir configs/rules] [--events recorded.ndjson] [--strict] [-v]
+//
+// Exits 1 on any schema error (or any warning with --strict).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+)
+
+func main() {
+	dir := flag.String("dir", config.Dir(), "directory of *.yaml rule files")
+	events := flag.String("events", "", "NDJSON file of recorded CloudEvents to dry-run against the rules")
+	strict := flag.Bool("strict", false, "exit non-zero on warnings as well as errors")
+	verbose := flag.Bool("v", false, "list each rule with the processor and subject that act on it")
+	flag.Parse()
+
+	paths, err := filepath.Glob(filepath.Join(*dir, "*.yaml"))
+	if err != nil {
+		log.Fatalf("glob %q: %v", *dir, err)
+	}
+	if len(paths) == 0 {
+		log.Fatalf("no rule files found in %q", *dir)
+	}
+
+	// Parse each file on its own so every error is reported, not just the first.
+	var nErrs, nRules int
+	files := make(map[string]string) // rule name → file
+	for _, path := range paths {
+		rf, err := config.ParseFile(path)
+		if err != nil {
+			for line := range strings.SplitSeq(err.Error(), "\n") {
+				fmt.Printf("error: %s\n", line)
+				nErrs++
+			}
+			continue
+		}
+		nRules += len(rf.Rules)
+		for _, r := range rf.Rules {
+			files[r.Name] = path
+		}
+	}
+	if nErrs > 0 {
+		fmt.Printf("%d error(s) in %d file(s)\n", nErrs, len(paths))
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadDir(*dir)
+	if err != nil {
+		log.Fatalf("load %q: %v", *dir, err)
+	}
+
+	if *verbose {
+		for _, r := range cfg.Rules {
+			subject, _ := rules.TriggerSubject(r.Trigger)
+			fmt.Printf("rule %q: %s on %s\n", r.Name, owner(r), subject)
+		}
+	}
+
+	findings := lint(cfg)
+	for _, f := range findings {
+		fmt.Printf("warning: %s: rule %q: %s\n", files[f.rule], f.rule, f.message)
+	}
+	fmt.Printf("%d rule(s) in %d file(s): 0 errors, %d warning(s)\n", nRules, len(paths), len(findings))
+
+	if *events != "" {
+		if err := dryRunFile(cfg, *events, os.Stdout); err != nil {
+			log.Fatalf("dry run: %v", err)
+		}
+	}
+
+	if *strict && len(findings) > 0 {
+		os.Exit(1)
+	}
+}
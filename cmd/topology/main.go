@@ -0,0 +1,138 @@
+// Command topology compares the JetStream streams, KV buckets and durable
+// consumers on a live NATS server with the manifest in natsx.Manifest and
+// reconciles them. diff prints every difference, including drift in fields the
+// server cannot change in place and objects the manifest does not declare;
+// apply prints the same diff, then creates what is missing and updates drifted
+// fields. Immutable drift and unmanaged objects are never changed.
+//
+// It connects as the admin NATS user, configured through the same environment
+// as the services (pkg/boot; VAULT_NKEY_PATH defaults to the admin seed).
+// scripts/topology.sh sets that environment for a deployment.
+//
+// Usage:
+//
+//	go run ./cmd/topology diff
+//	go run ./cmd/topology apply
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+const usage = `usage: topology <command>
+
+Commands:
+  diff    print how the live server differs from the manifest
+  apply   print the diff, then create and update what it can
+`
+
+func main() {
+	if len(os.Args) != 2 || (os.Args[1] != "diff" && os.Args[1] != "apply") {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+
+	nc, err := connect()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "topology: %v\n", err)
+		os.Exit(1)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "topology: jetstream context: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = run(js, cmd == "apply", os.Stdout)
+	nc.Close()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "topology %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// run prints the drift between the server and the manifest and, if apply is set
+// and anything is fixable, reconciles it.
+func run(js nats.JetStreamContext, apply bool, w io.Writer) error {
+	m := natsx.Manifest()
+	drifts, err := natsx.Plan(js, m)
+	if err != nil {
+		return err
+	}
+	if err := printDrift(w, drifts); err != nil {
+		return err
+	}
+	if !apply || fixable(drifts) == 0 {
+		return nil
+	}
+	applied, err := natsx.Apply(js, m)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "applied %d change(s)\n", fixable(applied))
+	return nil
+}
+
+// connect opens a NATS connection as the admin user, the way the services do.
+func connect() (*nats.Conn, error) {
+	cfg := boot.LoadConfig("admin")
+	seed, err := boot.FetchNATSSeed(cfg.VaultAddr, cfg.VaultToken, cfg.VaultNKEYPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: fetch NATS seed: %w", err)
+	}
+	// The context only bounds the certificate renewal goroutine, which a
+	// command this short-lived never needs.
+	nc, err := boot.BootstrapNATSTLS(context.Background(), cfg, "ruby-core-topology", seed)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	return nc, nil
+}
+
+// printDrift writes drifts as a table followed by a count of each action.
+func printDrift(w io.Writer, drifts []natsx.Drift) error {
+	if len(drifts) == 0 {
+		_, _ = fmt.Fprintln(w, "in sync with the manifest")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ACTION\tKIND\tNAME\tFIELD\tLIVE\tWANT")
+	counts := make(map[natsx.DriftAction]int)
+	for _, d := range drifts {
+		counts[d.Action]++
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Action, d.Kind, d.Name, orDash(d.Field), orDash(d.Live), orDash(d.Want))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "%d to create, %d to update, %d immutable (not applied), %d unmanaged\n",
+		counts[natsx.DriftCreate], counts[natsx.DriftUpdate], counts[natsx.DriftImmutable], counts[natsx.DriftUnmanaged])
+	return nil
+}
+
+func fixable(drifts []natsx.Drift) int {
+	n := 0
+	for _, d := range drifts {
+		if d.Fixable() {
+			n++
+		}
+	}
+	return n
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+//go:build fast
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+func TestPrintDrift(t *testing.T) {
+	var b strings.Builder
+	if err := printDrift(&b, nil); err != nil || !strings.Contains(b.String(), "in sync") {
+		t.Errorf("no drift: %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	err := printDrift(&b, []natsx.Drift{
+		{Action: natsx.DriftUpdate, Kind: natsx.KindBucket, Name: "idempotency", Field: "ttl", Live: "24h0m0s", Want: "30m0s"},
+		{Action: natsx.DriftImmutable, Kind: natsx.KindStream, Name: "HA_EVENTS", Field: "storage", Live: "Memory", Want: "File"},
+		{Action: natsx.DriftUnmanaged, Kind: natsx.KindConsumer, Name: "HA_EVENTS/debug"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"idempotency", "24h0m0s", "30m0s",
+		"HA_EVENTS/debug",
+		"0 to create, 1 to update, 1 immutable (not applied), 1 unmanaged",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
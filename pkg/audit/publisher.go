@@ -44,6 +44,7 @@ type Publisher struct {
 	source    string // ADR-0027 source token, e.g. "ruby_engine"
 	log       *slog.Logger
 	ch        chan schemas.AuditEvent
+	done      chan struct{} // closed when drain has published the last queued event
 	closeOnce sync.Once
 	dropped   metric.Int64Counter // ruby_core_audit_publish_dropped_total{service}
 }
@@ -68,6 +69,7 @@ func NewPublisher(nc *nats.Conn, source string, log *slog.Logger) *Publisher {
 		source:  natsx.SubjectToken(source),
 		log:     log,
 		ch:      make(chan schemas.AuditEvent, publishChannelCap),
+		done:    make(chan struct{}),
 		dropped: dropped,
 	}
 	go p.drain()
@@ -78,11 +80,19 @@ func NewPublisher(nc *nats.Conn, source string, log *slog.Logger) *Publisher {
 // It returns immediately without blocking the caller (ADR-0019).
 // If the internal buffer is full, the event is dropped and a Warn is logged.
 func (p *Publisher) Record(correlationID, causationID, action, natsSubject, outcome string) {
+	p.RecordDetails(correlationID, causationID, action, natsSubject, outcome, nil)
+}
+
+// RecordDetails is Record with action-specific context attached as the event's
+// data.details (e.g. the filter an operator purged by). It has the same
+// non-blocking contract as Record.
+func (p *Publisher) RecordDetails(correlationID, causationID, action, natsSubject, outcome string, details map[string]any) {
 	evt := schemas.NewAuditEvent(newID(), p.source, correlationID, causationID, schemas.AuditData{
 		Actor:   p.source,
 		Action:  action,
 		Subject: natsSubject,
 		Outcome: outcome,
+		Details: details,
 	})
 
 	// Recover guards against the unlikely race where Close() is called concurrently
@@ -109,15 +119,19 @@ func (p *Publisher) Record(correlationID, causationID, action, natsSubject, outc
 	}
 }
 
-// Close drains remaining events and stops the background goroutine.
+// Close drains remaining events and stops the background goroutine, returning once
+// the last queued event has been handed to the connection. Short-lived callers such
+// as CLIs should still Flush the connection before exiting.
 // Must be called during graceful shutdown after all producers have stopped.
 func (p *Publisher) Close() {
 	p.closeOnce.Do(func() { close(p.ch) })
+	<-p.done
 }
 
 // drain is the background goroutine that serialises publishes to NATS.
 // It exits cleanly when the channel is closed by Close().
 func (p *Publisher) drain() {
+	defer close(p.done)
 	for evt := range p.ch {
 		// Convert the dot-separated action to a valid ADR-0027 subject token.
 		// e.g. "event.processed" → "event_processed"
@@ -22,10 +22,30 @@ const (
 	// Must not exceed DefaultWorkerCount (ADR-0024).
 	DefaultFetchBatch = 20
 
-	// DefaultWorkerCount is the fixed size of the consumer worker pool (ADR-0024).
+	// DefaultWorkerCount is the upper bound of the consumer worker pool (ADR-0024).
 	// Must be >= DefaultFetchBatch.
 	DefaultWorkerCount = 20
 
+	// DefaultMinWorkerCount is the lower bound the worker pool adapts down to when
+	// handlers slow down, fail or go idle (natsx.WorkerPool).
+	DefaultMinWorkerCount = 2
+
+	// DefaultAdaptInterval is how often a worker pool re-evaluates its worker limit
+	// from the latency, errors and backlog it observed.
+	DefaultAdaptInterval = 5 * time.Second
+
+	// DefaultAdaptMinSamples is the fewest messages an adaptation interval must handle
+	// before its error rate and latency are trusted.
+	DefaultAdaptMinSamples = 10
+
+	// DefaultAdaptMaxErrorRate is the share of naked or terminated messages in an
+	// interval at which a worker pool halves its worker limit.
+	DefaultAdaptMaxErrorRate = 0.25
+
+	// DefaultAdaptLatencyFactor is how far above its baseline the mean handler latency
+	// may rise before a worker pool reads it as saturation and sheds a worker.
+	DefaultAdaptLatencyFactor = 2
+
 	// DefaultIdempotencyTTL is how long a processed event ID is retained in the shared
 	// idempotency store before expiry (ADR-0025). Dedup only needs to outlive the
 	// maximum redelivery window — MaxDeliver(5) × AckWait(30s) + Σ BackOff(15s) ≈ 165s
@@ -38,10 +58,24 @@ const (
 	// hygiene, not the dedup guarantee.
 	DefaultIdempotencyTTL = 30 * time.Minute
 
+	// DefaultIdempotencyLease is how long an in-flight idempotency claim holds off other
+	// workers before it can be taken over (ADR-0025). It matches AckWait, so a crashed
+	// worker's claim lapses by the time JetStream redelivers its message.
+	DefaultIdempotencyLease = DefaultAckWait
+
 	// DefaultDLQMaxAge is the retention window for messages in the DLQ stream.
 	// This is a starting default; tune as DLQ monitoring tooling matures (ADR-0022).
 	DefaultDLQMaxAge = 7 * 24 * time.Hour
 
+	// DefaultRejectedMaxAge is the retention window for the REJECTED stream: events that
+	// failed schema validation (ADR-0014), kept for inspection like the DLQ.
+	DefaultRejectedMaxAge = 7 * 24 * time.Hour
+
+	// DefaultRejectedDuplicates is the REJECTED stream's dedup window. Each engine
+	// processor consumer rejects a malformed event under the same Nats-Msg-Id; the
+	// window covers one consumer lagging behind another.
+	DefaultRejectedDuplicates = time.Hour
+
 	// DefaultAuditMaxAge is the minimum retention for the AUDIT_EVENTS stream.
 	// Must survive a prolonged audit-sink outage before messages are discarded (ADR-0019).
 	DefaultAuditMaxAge = 72 * time.Hour
@@ -56,6 +90,20 @@ const (
 	// reconciliation/replay after an outage. Previously unbounded, the stream grew until
 	// it exhausted the JetStream store and starved the discard=new KV buckets.
 	DefaultHAEventsMaxAge = 48 * time.Hour
+
+	// DefaultSchedulesMaxAge is the retention window for the SCHEDULES stream. The
+	// engine scheduler checks the stream before re-publishing a firing whose KV
+	// update was lost, so this bounds how long an engine can stay down between
+	// publishing a firing and recording it without that firing repeating.
+	DefaultSchedulesMaxAge = 7 * 24 * time.Hour
+
+	// DefaultPublishAckWait bounds each attempt of a JetStream publish: how long the
+	// publisher waits for the stream's ack before retrying (natsx.AckedPublisher).
+	DefaultPublishAckWait = 2 * time.Second
+
+	// DefaultPublishPendingWindow caps the async publishes of a natsx.Batch awaiting
+	// their acks; publishing blocks once the window is full.
+	DefaultPublishPendingWindow = 256
 )
 
 // Per-stream byte caps (ADR-0034) — defense in depth so no single stream can exhaust
@@ -63,11 +111,13 @@ const (
 // discard=old, so it self-evicts at its cap rather than failing new writes; the sum
 // sits under the server's max_file_store. Age limits remain the primary bound.
 const (
-	MaxBytesHAEvents int64 = 512 * 1024 * 1024 // 512 MiB
-	MaxBytesAudit    int64 = 256 * 1024 * 1024 // 256 MiB
-	MaxBytesDLQ      int64 = 64 * 1024 * 1024  // 64 MiB
-	MaxBytesCommands int64 = 16 * 1024 * 1024  // 16 MiB
-	MaxBytesPresence int64 = 32 * 1024 * 1024  // 32 MiB
+	MaxBytesHAEvents  int64 = 512 * 1024 * 1024 // 512 MiB
+	MaxBytesAudit     int64 = 256 * 1024 * 1024 // 256 MiB
+	MaxBytesDLQ       int64 = 64 * 1024 * 1024  // 64 MiB
+	MaxBytesRejected  int64 = 32 * 1024 * 1024  // 32 MiB
+	MaxBytesCommands  int64 = 16 * 1024 * 1024  // 16 MiB
+	MaxBytesPresence  int64 = 32 * 1024 * 1024  // 32 MiB
+	MaxBytesSchedules int64 = 16 * 1024 * 1024  // 16 MiB
 
 	// Audit-sink consumer defaults — lower throughput than the engine consumer
 	// because audit events are emitted only on critical actions (low volume).
@@ -81,6 +131,19 @@ const (
 
 	// DefaultAuditSinkMaxAckPending caps outstanding unacknowledged audit messages.
 	DefaultAuditSinkMaxAckPending = 32
+
+	// Command consumer defaults — notify commands arrive a few at a time, so the
+	// notifier's pool is bounded well below the HA_EVENTS firehose consumers.
+
+	// DefaultCommandsWorkerCount is the upper bound of the notifier worker pool.
+	DefaultCommandsWorkerCount = 5
+
+	// DefaultCommandsFetchBatch is the fetch batch size for the notifier consumer.
+	// Must not exceed DefaultCommandsWorkerCount.
+	DefaultCommandsFetchBatch = 5
+
+	// DefaultCommandsMaxAckPending caps outstanding unacknowledged notify commands.
+	DefaultCommandsMaxAckPending = 32
 )
 
 // DefaultBackOff is the JetStream consumer redelivery backoff schedule.
@@ -91,3 +154,12 @@ var DefaultBackOff = []time.Duration{
 	4 * time.Second,
 	8 * time.Second,
 }
+
+// DefaultPublishBackOff is the wait before each retry of a JetStream publish that timed
+// out. 3 intervals produce 4 attempts, all well inside a stream's 2-minute duplicate
+// window, so a retry of a publish the server did store is dropped as a duplicate.
+var DefaultPublishBackOff = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	2 * time.Second,
+}
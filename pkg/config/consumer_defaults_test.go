@@ -24,3 +24,17 @@ func TestIdempotencyTTLBoundedByRedeliveryWindow(t *testing.T) {
 			DefaultIdempotencyTTL)
 	}
 }
+
+// A claim must outlive one AckWait so a slow-but-alive worker is not overtaken before
+// JetStream itself would redeliver, and stay well inside the TTL so claims never
+// outlast the dedup record they guard (ADR-0025).
+func TestIdempotencyLeaseCoversAckWait(t *testing.T) {
+	if DefaultIdempotencyLease < DefaultAckWait {
+		t.Errorf("DefaultIdempotencyLease (%s) is shorter than DefaultAckWait (%s)",
+			DefaultIdempotencyLease, DefaultAckWait)
+	}
+	if DefaultIdempotencyLease >= DefaultIdempotencyTTL {
+		t.Errorf("DefaultIdempotencyLease (%s) must be shorter than DefaultIdempotencyTTL (%s)",
+			DefaultIdempotencyLease, DefaultIdempotencyTTL)
+	}
+}
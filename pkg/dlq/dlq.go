@@ -0,0 +1,329 @@
+// Package dlq inspects and recovers messages dead-lettered to the DLQ stream
+// (ADR-0022). A consumer's DLQ forwarder republishes each message that exhausts
+// MaxDeliver to dlq.{stream}.{consumer}, with the natsx.HeaderDLQ* headers naming
+// where it came from and why it failed; a Client lists and views those messages,
+// replays them to their original subject and purges them.
+//
+// Every call is recorded as an audit event (ADR-0019) with the action dlq.listed,
+// dlq.viewed, dlq.replayed or dlq.purged.
+package dlq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+var (
+	// ErrNotFound is returned for a DLQ sequence that holds no message, e.g. one
+	// that was purged or aged out.
+	ErrNotFound = errors.New("dlq: message not found")
+	// ErrNoOriginalSubject is returned by Replay for a message dead-lettered without
+	// the natsx.HeaderDLQSubject header, whose original subject is therefore unknown.
+	ErrNoOriginalSubject = errors.New("dlq: original subject unknown")
+)
+
+// jetStream is the subset of nats.JetStreamContext used by Client.
+// The narrow interface enables unit testing without a live NATS connection.
+type jetStream interface {
+	StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	GetMsg(name string, seq uint64, opts ...nats.JSOpt) (*nats.RawStreamMsg, error)
+	DeleteMsg(name string, seq uint64, opts ...nats.JSOpt) error
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// Recorder is the interface implemented by audit.Publisher.
+type Recorder interface {
+	RecordDetails(correlationID, causationID, action, natsSubject, outcome string, details map[string]any)
+}
+
+// Message is a dead-lettered message as stored in the DLQ stream.
+type Message struct {
+	Seq         uint64    // sequence in the DLQ stream
+	Subject     string    // DLQ subject, dlq.{stream}.{consumer}
+	Stream      string    // stream the message was consumed from
+	Consumer    string    // consumer that exhausted MaxDeliver
+	OrigSubject string    // subject it was originally published on; "" if not recorded
+	OrigSeq     uint64    // sequence in the original stream; 0 if not recorded
+	Deliveries  int       // delivery attempts before it was dead-lettered; 0 if not recorded
+	Time        time.Time // when it was dead-lettered
+	// FirstFailure and LastFailure bound the consumer's failed attempts and
+	// LastError is the error of the last one; zero if not recorded.
+	FirstFailure time.Time
+	LastFailure  time.Time
+	LastError    string
+	OrigHeader   nats.Header // headers of the original message; nil if not recorded
+	Header       nats.Header // headers of the DLQ message itself
+	Data         []byte
+}
+
+// Age returns how long before now the message was dead-lettered.
+func (m Message) Age(now time.Time) time.Duration { return now.Sub(m.Time) }
+
+// Filter selects DLQ messages. The zero Filter matches every message.
+type Filter struct {
+	Stream   string    // original stream name, case-insensitive
+	Consumer string    // consumer name
+	Before   time.Time // only messages dead-lettered before this time
+	Seqs     []uint64  // only these DLQ sequences
+	Limit    int       // List returns at most this many; 0 means no limit
+}
+
+// Match reports whether m is selected by f, ignoring Limit.
+func (f Filter) Match(m Message) bool {
+	if f.Stream != "" && !strings.EqualFold(f.Stream, m.Stream) {
+		return false
+	}
+	if f.Consumer != "" && f.Consumer != m.Consumer {
+		return false
+	}
+	if !f.Before.IsZero() && !m.Time.Before(f.Before) {
+		return false
+	}
+	if len(f.Seqs) > 0 {
+		for _, s := range f.Seqs {
+			if s == m.Seq {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// details renders f for the audit record.
+func (f Filter) details() map[string]any {
+	d := map[string]any{}
+	if f.Stream != "" {
+		d["stream"] = f.Stream
+	}
+	if f.Consumer != "" {
+		d["consumer"] = f.Consumer
+	}
+	if !f.Before.IsZero() {
+		d["before"] = f.Before.UTC().Format(time.RFC3339)
+	}
+	if len(f.Seqs) > 0 {
+		d["seqs"] = f.Seqs
+	}
+	if f.Limit > 0 {
+		d["limit"] = f.Limit
+	}
+	return d
+}
+
+// Client reads and recovers messages in the DLQ stream.
+type Client struct {
+	js    jetStream
+	audit Recorder
+}
+
+// New returns a Client over js that records every action through audit.
+func New(js nats.JetStreamContext, audit Recorder) *Client {
+	return newClient(js, audit)
+}
+
+func newClient(js jetStream, audit Recorder) *Client {
+	return &Client{js: js, audit: audit}
+}
+
+// List returns the DLQ messages matching f, oldest first.
+func (c *Client) List(f Filter) ([]Message, error) {
+	msgs, err := c.scan(f)
+	c.record("", "", "dlq.listed", natsx.DLQStream, err, f.details())
+	return msgs, err
+}
+
+// Get returns the message stored at DLQ sequence seq, or ErrNotFound.
+func (c *Client) Get(seq uint64) (Message, error) {
+	m, err := c.get(seq)
+	c.record("", "", "dlq.viewed", m.Subject, err, map[string]any{"seq": seq})
+	return m, err
+}
+
+// Replay publishes the payload stored at DLQ sequence seq back to its original
+// subject, with its original headers, and returns the Nats-Msg-Id it was published
+// with. The ID replaces the original one and is fresh, so
+// neither JetStream's duplicate window nor the consumer's idempotency store treats
+// the replay as the message that failed. The natsx.HeaderDLQConsumer header names
+// the consumer that dead-lettered it; the other consumers of the stream, which
+// processed the original, ack the replay unprocessed. The DLQ copy is kept; Purge it
+// once the replay has been processed.
+func (c *Client) Replay(seq uint64) (string, error) {
+	m, err := c.get(seq)
+	if err == nil && m.OrigSubject == "" {
+		err = fmt.Errorf("%w for seq %d", ErrNoOriginalSubject, seq)
+	}
+	var id string
+	if err == nil {
+		id = replayID(m)
+		out := nats.NewMsg(m.OrigSubject)
+		out.Data = m.Data
+		for k, vs := range m.OrigHeader {
+			out.Header[k] = vs
+		}
+		out.Header.Set(nats.MsgIdHdr, id)
+		if m.Consumer != "" {
+			out.Header.Set(natsx.HeaderDLQConsumer, m.Consumer)
+		}
+		if _, perr := c.js.PublishMsg(out); perr != nil {
+			err = fmt.Errorf("dlq: replay seq %d to %s: %w", seq, m.OrigSubject, perr)
+		}
+	}
+	correlationID, causationID := correlationFields(m.Data)
+	c.record(correlationID, causationID, "dlq.replayed", m.OrigSubject, err, map[string]any{
+		"seq":    seq,
+		"msg_id": id,
+	})
+	return id, err
+}
+
+// Purge deletes the DLQ messages matching f, ignoring f.Limit, and returns how
+// many were deleted.
+func (c *Client) Purge(f Filter) (int, error) {
+	f.Limit = 0
+	msgs, err := c.scan(f)
+	n := 0
+	for _, m := range msgs {
+		if derr := c.js.DeleteMsg(natsx.DLQStream, m.Seq); derr != nil {
+			err = fmt.Errorf("dlq: delete seq %d: %w", m.Seq, derr)
+			break
+		}
+		n++
+	}
+	details := f.details()
+	details["purged"] = n
+	c.record("", "", "dlq.purged", natsx.DLQStream, err, details)
+	return n, err
+}
+
+// scan reads the messages matching f. With f.Seqs only those sequences are read;
+// otherwise the whole stream is walked from its first to its last sequence.
+func (c *Client) scan(f Filter) ([]Message, error) {
+	seqs := f.Seqs
+	if len(seqs) == 0 {
+		info, err := c.js.StreamInfo(natsx.DLQStream)
+		if err != nil {
+			return nil, fmt.Errorf("dlq: stream info: %w", err)
+		}
+		if info.State.Msgs == 0 {
+			return nil, nil
+		}
+		for seq := info.State.FirstSeq; seq <= info.State.LastSeq; seq++ {
+			seqs = append(seqs, seq)
+		}
+	}
+
+	var out []Message
+	for _, seq := range seqs {
+		m, err := c.get(seq)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted or aged out of the stream
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !f.Match(m) {
+			continue
+		}
+		out = append(out, m)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (c *Client) get(seq uint64) (Message, error) {
+	raw, err := c.js.GetMsg(natsx.DLQStream, seq)
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return Message{Seq: seq}, fmt.Errorf("%w: seq %d", ErrNotFound, seq)
+	}
+	if err != nil {
+		return Message{Seq: seq}, fmt.Errorf("dlq: get seq %d: %w", seq, err)
+	}
+	return parse(raw), nil
+}
+
+func (c *Client) record(correlationID, causationID, action, subject string, err error, details map[string]any) {
+	if c.audit == nil {
+		return
+	}
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		details["error"] = err.Error()
+	}
+	c.audit.RecordDetails(correlationID, causationID, action, subject, outcome, details)
+}
+
+// parse builds a Message from a DLQ stream message. Messages dead-lettered before
+// the forwarder set the natsx.HeaderDLQ* headers fall back to the stream and
+// consumer tokens of their dlq.{stream}.{consumer} subject.
+func parse(raw *nats.RawStreamMsg) Message {
+	m := Message{
+		Seq:         raw.Sequence,
+		Subject:     raw.Subject,
+		Stream:      raw.Header.Get(natsx.HeaderDLQStream),
+		Consumer:    raw.Header.Get(natsx.HeaderDLQConsumer),
+		OrigSubject: raw.Header.Get(natsx.HeaderDLQSubject),
+		Time:        raw.Time,
+		LastError:   raw.Header.Get(natsx.HeaderDLQError),
+		OrigHeader:  natsx.DLQOriginalHeaders(raw.Header),
+		Header:      raw.Header,
+		Data:        raw.Data,
+	}
+	m.OrigSeq, _ = strconv.ParseUint(raw.Header.Get(natsx.HeaderDLQSequence), 10, 64)
+	m.Deliveries, _ = strconv.Atoi(raw.Header.Get(natsx.HeaderDLQDeliveries))
+	m.FirstFailure, _ = time.Parse(time.RFC3339Nano, raw.Header.Get(natsx.HeaderDLQFirstFailure))
+	m.LastFailure, _ = time.Parse(time.RFC3339Nano, raw.Header.Get(natsx.HeaderDLQLastFailure))
+	if tokens := strings.Split(raw.Subject, "."); len(tokens) == 3 {
+		if m.Stream == "" {
+			m.Stream = tokens[1]
+		}
+		if m.Consumer == "" {
+			m.Consumer = tokens[2]
+		}
+	}
+	return m
+}
+
+// replayID returns a fresh Nats-Msg-Id for replaying m, derived from the ID the
+// consumer saw it under so the replay can be traced back to the original.
+func replayID(m Message) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	orig := m.OrigHeader.Get(nats.MsgIdHdr)
+	if orig == "" {
+		var ce struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(m.Data, &ce) == nil {
+			orig = ce.ID
+		}
+	}
+	if orig == "" {
+		orig = fmt.Sprintf("%s.%d", m.Stream, m.OrigSeq)
+	}
+	return orig + "-replay-" + hex.EncodeToString(b[:])
+}
+
+// correlationFields returns the correlationid of a CloudEvent payload and its id as
+// the causation of the replay; both are empty for other payloads.
+func correlationFields(data []byte) (correlationID, causationID string) {
+	var ce struct {
+		ID            string `json:"id"`
+		CorrelationID string `json:"correlationid"`
+	}
+	_ = json.Unmarshal(data, &ce)
+	return ce.CorrelationID, ce.ID
+}
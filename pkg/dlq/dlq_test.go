@@ -0,0 +1,263 @@
+//go:build fast
+
+package dlq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// ---------------------------------------------------------------------------
+// Test helpers: in-memory DLQ stream and audit recorder
+// ---------------------------------------------------------------------------
+
+// mockJS implements jetStream over an in-memory DLQ stream.
+type mockJS struct {
+	msgs      map[uint64]*nats.RawStreamMsg
+	last      uint64
+	published []*nats.Msg
+}
+
+func newMockJS() *mockJS { return &mockJS{msgs: make(map[uint64]*nats.RawStreamMsg)} }
+
+// add appends a dead-lettered message; header pairs are key, value, ...
+func (m *mockJS) add(subject string, at time.Time, data string, header ...string) uint64 {
+	m.last++
+	h := nats.Header{}
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	m.msgs[m.last] = &nats.RawStreamMsg{Subject: subject, Sequence: m.last, Header: h, Data: []byte(data), Time: at}
+	return m.last
+}
+
+func (m *mockJS) StreamInfo(string, ...nats.JSOpt) (*nats.StreamInfo, error) {
+	info := &nats.StreamInfo{}
+	info.State.Msgs = uint64(len(m.msgs))
+	info.State.LastSeq = m.last
+	for seq := uint64(1); seq <= m.last; seq++ {
+		if _, ok := m.msgs[seq]; ok {
+			info.State.FirstSeq = seq
+			break
+		}
+	}
+	return info, nil
+}
+
+func (m *mockJS) GetMsg(_ string, seq uint64, _ ...nats.JSOpt) (*nats.RawStreamMsg, error) {
+	if raw, ok := m.msgs[seq]; ok {
+		return raw, nil
+	}
+	return nil, nats.ErrMsgNotFound
+}
+
+func (m *mockJS) DeleteMsg(_ string, seq uint64, _ ...nats.JSOpt) error {
+	if _, ok := m.msgs[seq]; !ok {
+		return nats.ErrMsgNotFound
+	}
+	delete(m.msgs, seq)
+	return nil
+}
+
+func (m *mockJS) PublishMsg(msg *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	m.published = append(m.published, msg)
+	return &nats.PubAck{}, nil
+}
+
+type auditRecord struct {
+	correlationID, causationID, action, subject, outcome string
+	details                                              map[string]any
+}
+
+type mockRecorder struct{ records []auditRecord }
+
+func (r *mockRecorder) RecordDetails(correlationID, causationID, action, subject, outcome string, details map[string]any) {
+	r.records = append(r.records, auditRecord{correlationID, causationID, action, subject, outcome, details})
+}
+
+var t0 = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+// newFixture returns a DLQ holding one enriched HA_EVENTS message (seq 1), one
+// legacy message with no headers (seq 2) and one enriched SCHEDULES message (seq 3).
+func newFixture() (*mockJS, *mockRecorder, *Client) {
+	js := newMockJS()
+	js.add("dlq.ha_events.engine_processor", t0, `{"id":"evt-1","correlationid":"corr-1"}`,
+		natsx.HeaderDLQSubject, "ha.events.light.kitchen",
+		natsx.HeaderDLQStream, "HA_EVENTS",
+		natsx.HeaderDLQConsumer, "engine_processor",
+		natsx.HeaderDLQSequence, "409",
+		natsx.HeaderDLQDeliveries, "5",
+		natsx.HeaderDLQFirstFailure, "2026-06-01T11:59:45Z",
+		natsx.HeaderDLQLastFailure, "2026-06-01T11:59:59.5Z",
+		natsx.HeaderDLQError, "rules: kitchen: timeout",
+		natsx.HeaderDLQOrigPrefix+nats.MsgIdHdr, "ha-evt-1",
+		natsx.HeaderDLQOrigPrefix+"traceparent", traceparent)
+	js.add("dlq.ha_events.engine_processor", t0.Add(time.Hour), `{"id":"evt-2"}`)
+	js.add("dlq.schedules.engine_schedules", t0.Add(2*time.Hour), `{"id":"evt-3"}`,
+		natsx.HeaderDLQSubject, "ruby_engine.events.schedule.rules.morning",
+		natsx.HeaderDLQStream, "SCHEDULES",
+		natsx.HeaderDLQConsumer, "engine_schedules")
+	rec := &mockRecorder{}
+	return js, rec, newClient(js, rec)
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestList_ParsesHeadersAndFallsBackToSubject(t *testing.T) {
+	_, rec, c := newFixture()
+	msgs, err := c.List(Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("List returned %d messages, want 3", len(msgs))
+	}
+
+	m := msgs[0]
+	if m.Stream != "HA_EVENTS" || m.Consumer != "engine_processor" || m.OrigSubject != "ha.events.light.kitchen" ||
+		m.OrigSeq != 409 || m.Deliveries != 5 || !m.Time.Equal(t0) {
+		t.Errorf("enriched message = %+v", m)
+	}
+	if !m.FirstFailure.Equal(t0.Add(-15*time.Second)) || !m.LastFailure.Equal(t0.Add(-500*time.Millisecond)) ||
+		m.LastError != "rules: kitchen: timeout" {
+		t.Errorf("failure fields = %v, %v, %q", m.FirstFailure, m.LastFailure, m.LastError)
+	}
+	if m.OrigHeader.Get(nats.MsgIdHdr) != "ha-evt-1" || m.OrigHeader.Get("traceparent") != traceparent || len(m.OrigHeader) != 2 {
+		t.Errorf("OrigHeader = %v", m.OrigHeader)
+	}
+	if got := m.Age(t0.Add(90 * time.Second)); got != 90*time.Second {
+		t.Errorf("Age = %v, want 90s", got)
+	}
+
+	legacy := msgs[1]
+	if legacy.Stream != "ha_events" || legacy.Consumer != "engine_processor" || legacy.OrigSubject != "" || legacy.OrigSeq != 0 ||
+		legacy.OrigHeader != nil || !legacy.LastFailure.IsZero() {
+		t.Errorf("legacy message = %+v, want stream and consumer from its subject only", legacy)
+	}
+
+	if len(rec.records) != 1 || rec.records[0].action != "dlq.listed" || rec.records[0].outcome != "success" {
+		t.Errorf("audit records = %+v, want one successful dlq.listed", rec.records)
+	}
+}
+
+func TestList_Filters(t *testing.T) {
+	_, _, c := newFixture()
+	cases := []struct {
+		name string
+		f    Filter
+		want []uint64
+	}{
+		{"stream is case-insensitive", Filter{Stream: "ha_events"}, []uint64{1, 2}},
+		{"consumer", Filter{Consumer: "engine_schedules"}, []uint64{3}},
+		{"before", Filter{Before: t0.Add(time.Hour)}, []uint64{1}},
+		{"seqs", Filter{Seqs: []uint64{3, 1, 99}}, []uint64{3, 1}},
+		{"limit", Filter{Limit: 2}, []uint64{1, 2}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs, err := c.List(tc.f)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []uint64
+			for _, m := range msgs {
+				got = append(got, m.Seq)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("seqs = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("seqs = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	_, rec, c := newFixture()
+	if _, err := c.Get(42); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(42) error = %v, want ErrNotFound", err)
+	}
+	if len(rec.records) != 1 || rec.records[0].action != "dlq.viewed" || rec.records[0].outcome != "failure" {
+		t.Errorf("audit records = %+v, want one failed dlq.viewed", rec.records)
+	}
+}
+
+func TestReplay_RestoresHeadersWithFreshID(t *testing.T) {
+	js, rec, c := newFixture()
+	id, err := c.Replay(1)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if !strings.HasPrefix(id, "ha-evt-1-replay-") {
+		t.Errorf("replay id = %q, want it derived from the original Nats-Msg-Id", id)
+	}
+	if len(js.published) != 1 {
+		t.Fatalf("published %d messages, want 1", len(js.published))
+	}
+	out := js.published[0]
+	if out.Subject != "ha.events.light.kitchen" || string(out.Data) != `{"id":"evt-1","correlationid":"corr-1"}` {
+		t.Errorf("published %s %s", out.Subject, out.Data)
+	}
+	if out.Header.Get(nats.MsgIdHdr) != id {
+		t.Errorf("Nats-Msg-Id = %q, want %q", out.Header.Get(nats.MsgIdHdr), id)
+	}
+	if out.Header.Get("traceparent") != traceparent || out.Header.Get(natsx.HeaderDLQStream) != "" || len(out.Header) != 3 {
+		t.Errorf("replay headers = %v, want the original headers and the consumer", out.Header)
+	}
+	if got := out.Header.Get(natsx.HeaderDLQConsumer); got != "engine_processor" {
+		t.Errorf("%s = %q, want the consumer that dead-lettered it", natsx.HeaderDLQConsumer, got)
+	}
+	if again, _ := c.Replay(1); again == id {
+		t.Errorf("second replay reused id %q", id)
+	}
+
+	r := rec.records[0]
+	if r.action != "dlq.replayed" || r.outcome != "success" || r.subject != "ha.events.light.kitchen" ||
+		r.correlationID != "corr-1" || r.causationID != "evt-1" || r.details["msg_id"] != id {
+		t.Errorf("audit record = %+v", r)
+	}
+}
+
+func TestReplay_RefusesUnknownOriginalSubject(t *testing.T) {
+	js, rec, c := newFixture()
+	if _, err := c.Replay(2); !errors.Is(err, ErrNoOriginalSubject) {
+		t.Fatalf("Replay(2) error = %v, want ErrNoOriginalSubject", err)
+	}
+	if len(js.published) != 0 {
+		t.Errorf("published %d messages, want none", len(js.published))
+	}
+	if rec.records[0].outcome != "failure" {
+		t.Errorf("audit outcome = %q, want failure", rec.records[0].outcome)
+	}
+}
+
+func TestPurge_DeletesMatchesOnly(t *testing.T) {
+	js, rec, c := newFixture()
+	n, err := c.Purge(Filter{Stream: "HA_EVENTS", Limit: 1})
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("purged %d, want 2 (Limit is ignored)", n)
+	}
+	if _, ok := js.msgs[3]; !ok || len(js.msgs) != 1 {
+		t.Errorf("remaining messages = %v, want only seq 3", js.msgs)
+	}
+	r := rec.records[0]
+	if r.action != "dlq.purged" || r.details["purged"] != 2 || r.details["stream"] != "HA_EVENTS" {
+		t.Errorf("audit record = %+v", r)
+	}
+}
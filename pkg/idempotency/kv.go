@@ -2,7 +2,9 @@ package idempotency
 
 import (
 	"errors"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nats-io/nats.go"
 )
@@ -12,32 +14,103 @@ import (
 type kvClient interface {
 	Get(key string) (nats.KeyValueEntry, error)
 	Put(key string, value []byte) (uint64, error)
+	Create(key string, value []byte) (uint64, error)
+	Update(key string, value []byte, last uint64) (uint64, error)
+	Delete(key string, opts ...nats.DeleteOpt) error
 }
 
+// doneValue marks an id as processed. It is the same presence marker Mark wrote before
+// claims existed, so entries from older engines still read as done.
+var doneValue = []byte{1}
+
+// claimPrefix starts the value of an in-flight claim; the rest is the lease expiry in
+// Unix milliseconds.
+const claimPrefix = "inflight:"
+
 // kvStore is a NATS KV-backed idempotency store.
 // TTL is enforced at the bucket level (KeyValueConfig.TTL).
 type kvStore struct {
 	kv kvClient
 }
 
-// Seen reports whether id exists in the KV bucket (and has not expired).
-func (s *kvStore) Seen(id string) (bool, error) {
-	_, err := s.kv.Get(sanitizeKey(id))
+// claim takes id for processing until now+lease. Claimed carries the revision of the
+// claim entry, which release needs to drop it without touching a successor's claim.
+//
+// The create is revision-checked by the server, so of two workers racing on a fresh id
+// exactly one wins. An expired claim (its holder crashed or stalled past the lease) is
+// taken over with an Update against the revision that was read, so again one taker wins.
+func (s *kvStore) claim(id string, now time.Time, lease time.Duration) (ClaimResult, uint64, error) {
+	key := sanitizeKey(id)
+	value := claimValue(now.Add(lease))
+	rev, err := s.kv.Create(key, value)
 	if err == nil {
-		return true, nil
+		return Claimed, rev, nil
+	}
+	if !errors.Is(err, nats.ErrKeyExists) {
+		return 0, 0, err
 	}
+
+	e, err := s.kv.Get(key)
 	if errors.Is(err, nats.ErrKeyNotFound) {
-		return false, nil
+		// Released or expired between the create and the read: the holder gave it up
+		// just now, so treat it as still in flight and let the retry claim it.
+		return InFlight, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
 	}
-	return false, err
+	expiry, inFlight := parseClaim(e.Value())
+	if !inFlight {
+		return Done, 0, nil
+	}
+	if now.Before(expiry) {
+		return InFlight, 0, nil
+	}
+	rev, err = s.kv.Update(key, value, e.Revision())
+	if errors.Is(err, nats.ErrKeyExists) {
+		return InFlight, 0, nil // another worker took the lapsed claim first
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return Claimed, rev, nil
 }
 
-// Mark writes id to the KV bucket as a presence marker.
-func (s *kvStore) Mark(id string) error {
-	_, err := s.kv.Put(sanitizeKey(id), []byte{1})
+// complete records id as processed, replacing any claim on it.
+func (s *kvStore) complete(id string) error {
+	_, err := s.kv.Put(sanitizeKey(id), doneValue)
 	return err
 }
 
+// release deletes the claim on id written at revision rev. If the claim has since been
+// taken over, the delete is refused and the successor's claim is left alone.
+func (s *kvStore) release(id string, rev uint64) error {
+	err := s.kv.Delete(sanitizeKey(id), nats.LastRevision(rev))
+	if errors.Is(err, nats.ErrKeyExists) {
+		return nil
+	}
+	return err
+}
+
+// claimValue encodes an in-flight claim expiring at expiry.
+func claimValue(expiry time.Time) []byte {
+	return []byte(claimPrefix + strconv.FormatInt(expiry.UnixMilli(), 10))
+}
+
+// parseClaim reports whether v is an in-flight claim and, if so, when it expires. A claim
+// whose expiry cannot be parsed reads as already expired so it can be taken over.
+func parseClaim(v []byte) (expiry time.Time, inFlight bool) {
+	rest, ok := strings.CutPrefix(string(v), claimPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return time.Time{}, true
+	}
+	return time.UnixMilli(ms), true
+}
+
 // sanitizeKey replaces characters not valid in NATS KV keys.
 // NATS KV allows [a-zA-Z0-9_\-.]; colons are the only common problematic character
 // in our IDs (e.g. "HA_EVENTS:42"). Dots are preserved to avoid colliding distinct IDs.
@@ -1,6 +1,7 @@
 // Package idempotency provides a hybrid idempotency tracking store backed by
 // an in-memory TTL cache (fast path) and a NATS KV bucket (durable path).
-// See ADR-0025 for design rationale and the acknowledged race window.
+// Claims are taken atomically in KV so concurrent deliveries of one event ID are
+// serialized. See ADR-0025 for design rationale.
 package idempotency
 
 import (
@@ -8,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -16,77 +18,143 @@ import (
 	"go.opentelemetry.io/otel/metric"
 )
 
-// Store is the interface for checking and marking processed event IDs.
+// ClaimResult is the outcome of Store.Claim.
+type ClaimResult int
+
+const (
+	// Claimed means the caller now holds the id and must Complete or Release it.
+	Claimed ClaimResult = iota
+	// Done means the id was already processed; the caller should discard the event.
+	Done
+	// InFlight means another worker holds an unexpired claim on the id; the caller
+	// should retry later rather than process or discard the event.
+	InFlight
+)
+
+// String returns the result's name for logs.
+func (r ClaimResult) String() string {
+	switch r {
+	case Claimed:
+		return "claimed"
+	case Done:
+		return "done"
+	case InFlight:
+		return "in_flight"
+	}
+	return fmt.Sprintf("ClaimResult(%d)", int(r))
+}
+
+// Store is the interface for claiming and completing event IDs.
 type Store interface {
-	// Seen reports whether id has been seen and processed recently.
-	Seen(id string) (bool, error)
-	// Mark records id as processed. Should be called after the side-effect succeeds.
-	Mark(id string) error
+	// Claim atomically takes id for processing. Only one caller across all workers and
+	// instances holds a claim at a time; an unfinished claim expires after the store's
+	// lease so a crashed worker does not block the id forever.
+	Claim(id string) (ClaimResult, error)
+	// Complete records id as processed, ending the caller's claim. Should be called
+	// after the side-effect succeeds.
+	Complete(id string) error
+	// Release drops the caller's claim without recording id as processed, so a retry
+	// can claim it. Should be called when the side-effect fails.
+	Release(id string) error
 	// Close releases background resources (e.g. eviction goroutine).
 	Close() error
 }
 
-// hybridStore checks the in-memory cache first (fast path), then NATS KV (durable path).
-// Writes go to both stores. KV write failures are logged but do not surface as errors,
-// consistent with ADR-0025's acknowledged small race window.
+// hybridStore checks the in-memory cache of completed IDs first (fast path), then takes
+// the claim in NATS KV (durable path). Completions go to both stores.
 type hybridStore struct {
 	mem      *memStore
 	kv       *kvStore
+	lease    time.Duration
 	service  string
 	markFail metric.Int64Counter // ruby_core_idempotency_mark_failures_total{service}
+
+	mu   sync.Mutex
+	held map[string]uint64 // id → KV revision of a claim this store holds
 }
 
 // NewHybridStore returns a Store backed by an in-memory TTL cache and a NATS KV bucket.
-// In production, pass the nats.KeyValue returned by CreateOrBindKVBucket. service labels
-// the durable-mark-failure metric (#137).
-func NewHybridStore(kv nats.KeyValue, ttl time.Duration, service string) Store {
+// In production, pass the nats.KeyValue returned by CreateOrBindKVBucket. lease bounds
+// how long an unfinished claim blocks other workers. service labels the
+// durable-mark-failure metric (#137).
+func NewHybridStore(kv nats.KeyValue, ttl, lease time.Duration, service string) Store {
 	markFail, _ := otel.Meter("github.com/primaryrutabaga/ruby-core/pkg/idempotency").Int64Counter(
 		"ruby_core_idempotency_mark_failures_total",
-		metric.WithDescription("Durable (KV) idempotency mark writes that failed — widens the at-least-once race window (ADR-0025)"),
+		metric.WithDescription("Durable (KV) idempotency completions that failed — the claim stays in flight until its lease expires (ADR-0025)"),
 	)
 	return &hybridStore{
 		mem:      newMemStore(ttl),
 		kv:       &kvStore{kv: kv},
+		lease:    lease,
 		service:  service,
 		markFail: markFail,
 	}
 }
 
-// Seen checks the memory cache first; on a miss, falls through to the KV store.
-// A KV hit also warms the memory cache to speed up subsequent checks.
-func (h *hybridStore) Seen(id string) (bool, error) {
+// Claim answers Done from the memory cache when it can; otherwise it takes the claim in
+// KV. A Done answer from KV also warms the memory cache to speed up subsequent checks.
+func (h *hybridStore) Claim(id string) (ClaimResult, error) {
 	if ok, err := h.mem.Seen(id); err != nil || ok {
-		return ok, err
+		return Done, err
 	}
-	ok, err := h.kv.Seen(id)
+	res, rev, err := h.kv.claim(id, time.Now(), h.lease)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	if ok {
+	switch res {
+	case Claimed:
+		h.mu.Lock()
+		if h.held == nil {
+			h.held = make(map[string]uint64)
+		}
+		h.held[id] = rev
+		h.mu.Unlock()
+	case Done:
 		// Warm the memory cache for future lookups.
 		_ = h.mem.Mark(id)
 	}
-	return ok, nil
+	return res, nil
 }
 
-// Mark writes id to both the memory cache and the KV bucket.
-// A KV write failure is logged but not returned (ADR-0025 race window acknowledgement).
-func (h *hybridStore) Mark(id string) error {
+// Complete writes id to both the memory cache and the KV bucket. A KV write failure is
+// returned: the claim then stays in flight until its lease expires, and deliveries in
+// that window are deferred rather than processed again.
+func (h *hybridStore) Complete(id string) error {
+	h.take(id)
 	if err := h.mem.Mark(id); err != nil {
 		return err
 	}
-	if err := h.kv.Mark(id); err != nil {
+	if err := h.kv.complete(id); err != nil {
 		if h.markFail != nil {
 			h.markFail.Add(context.Background(), 1, metric.WithAttributes(attribute.String("service", h.service)))
 		}
-		slog.Warn("idempotency: kv mark failed (non-fatal, race window acknowledged)",
-			slog.String("id", id),
-			slog.String("error", err.Error()),
-		)
+		return fmt.Errorf("idempotency: kv complete %q: %w", id, err)
 	}
 	return nil
 }
 
+// Release deletes this store's claim on id from KV. It is a no-op when the store holds
+// no claim on id.
+func (h *hybridStore) Release(id string) error {
+	rev, ok := h.take(id)
+	if !ok {
+		return nil
+	}
+	if err := h.kv.release(id, rev); err != nil {
+		return fmt.Errorf("idempotency: kv release %q: %w", id, err)
+	}
+	return nil
+}
+
+// take forgets and returns the revision of the claim held on id.
+func (h *hybridStore) take(id string) (uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rev, ok := h.held[id]
+	delete(h.held, id)
+	return rev, ok
+}
+
 // Close stops the memory store's background eviction goroutine.
 func (h *hybridStore) Close() error {
 	return h.mem.Close()
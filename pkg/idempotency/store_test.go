@@ -4,6 +4,7 @@ package idempotency
 
 import (
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,19 +15,27 @@ import (
 // Test helpers: mock kvClient and nats.KeyValueEntry
 // ---------------------------------------------------------------------------
 
-// mockKVClient implements kvClient for unit tests.
+// mockKVClient implements kvClient for unit tests. Like a KV bucket, every write bumps
+// a per-key revision, and Create/Update refuse a write whose expected revision is stale.
 type mockKVClient struct {
-	data     map[string][]byte
-	getErr   error
-	putErr   error
-	putCalls []string // records keys passed to Put
+	mu          sync.Mutex
+	data        map[string][]byte
+	revs        map[string]uint64
+	seq         uint64
+	getErr      error
+	putErr      error
+	createErr   error
+	putCalls    []string // records keys passed to Put
+	deleteCalls []string // records keys passed to Delete
 }
 
 func newMockKV() *mockKVClient {
-	return &mockKVClient{data: make(map[string][]byte)}
+	return &mockKVClient{data: make(map[string][]byte), revs: make(map[string]uint64)}
 }
 
 func (m *mockKVClient) Get(key string) (nats.KeyValueEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.getErr != nil {
 		return nil, m.getErr
 	}
@@ -34,28 +43,66 @@ func (m *mockKVClient) Get(key string) (nats.KeyValueEntry, error) {
 	if !ok {
 		return nil, nats.ErrKeyNotFound
 	}
-	return &mockEntry{val: v}, nil
+	return &mockEntry{val: v, rev: m.revs[key]}, nil
 }
 
 func (m *mockKVClient) Put(key string, value []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.putCalls = append(m.putCalls, key)
 	if m.putErr != nil {
 		return 0, m.putErr
 	}
-	if m.data == nil {
-		m.data = make(map[string][]byte)
+	return m.write(key, value), nil
+}
+
+func (m *mockKVClient) Create(key string, value []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.createErr != nil {
+		return 0, m.createErr
+	}
+	if _, ok := m.data[key]; ok {
+		return 0, nats.ErrKeyExists
+	}
+	return m.write(key, value), nil
+}
+
+func (m *mockKVClient) Update(key string, value []byte, last uint64) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.revs[key] != last {
+		return 0, nats.ErrKeyExists
 	}
+	return m.write(key, value), nil
+}
+
+// Delete ignores opts: the mock cannot read nats.LastRevision, so it always deletes.
+func (m *mockKVClient) Delete(key string, _ ...nats.DeleteOpt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteCalls = append(m.deleteCalls, key)
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mockKVClient) write(key string, value []byte) uint64 {
+	m.seq++
 	m.data[key] = value
-	return 1, nil
+	m.revs[key] = m.seq
+	return m.seq
 }
 
 // mockEntry implements nats.KeyValueEntry.
-type mockEntry struct{ val []byte }
+type mockEntry struct {
+	val []byte
+	rev uint64
+}
 
 func (e *mockEntry) Bucket() string             { return "test" }
 func (e *mockEntry) Key() string                { return "" }
 func (e *mockEntry) Value() []byte              { return e.val }
-func (e *mockEntry) Revision() uint64           { return 1 }
+func (e *mockEntry) Revision() uint64           { return e.rev }
 func (e *mockEntry) Delta() uint64              { return 0 }
 func (e *mockEntry) Created() time.Time         { return time.Time{} }
 func (e *mockEntry) Operation() nats.KeyValueOp { return nats.KeyValuePut }
@@ -135,48 +182,109 @@ func TestMemStore_Close_StopsGoroutine(t *testing.T) {
 // kvStore tests
 // ---------------------------------------------------------------------------
 
-func TestKVStore_Seen_NotPresent(t *testing.T) {
-	s := &kvStore{kv: newMockKV()}
-	ok, err := s.Seen("event-001")
+func TestKVStore_Claim_NewID(t *testing.T) {
+	m := newMockKV()
+	s := &kvStore{kv: m}
+	now := time.Now()
+
+	res, rev, err := s.claim("HA_EVENTS:42", now, time.Minute)
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("claim() error: %v", err)
 	}
-	if ok {
-		t.Error("Seen() = true for missing key, want false")
+	if res != Claimed || rev == 0 {
+		t.Fatalf("claim() = %v rev %d, want claimed with a revision", res, rev)
+	}
+	expiry, inFlight := parseClaim(m.data["HA_EVENTS_42"]) // colon → underscore sanitization
+	if !inFlight || expiry.UnixMilli() != now.Add(time.Minute).UnixMilli() {
+		t.Errorf("stored claim = %q, want in flight until now+1m", m.data["HA_EVENTS_42"])
 	}
 }
 
-func TestKVStore_Seen_Present(t *testing.T) {
+func TestKVStore_Claim_Done(t *testing.T) {
 	m := newMockKV()
-	m.data["event_001"] = []byte{1} // pre-populate (sanitized key)
+	m.write("event-001", doneValue)
 	s := &kvStore{kv: m}
 
-	ok, err := s.Seen("event:001") // colon → underscore sanitization
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	res, _, err := s.claim("event-001", time.Now(), time.Minute)
+	if err != nil || res != Done {
+		t.Fatalf("claim() = %v, %v; want done nil", res, err)
 	}
-	if !ok {
-		t.Error("Seen() = false for present key, want true")
+}
+
+func TestKVStore_Claim_HeldByAnother(t *testing.T) {
+	s := &kvStore{kv: newMockKV()}
+	now := time.Now()
+
+	if res, _, err := s.claim("event-001", now, time.Minute); err != nil || res != Claimed {
+		t.Fatalf("first claim() = %v, %v; want claimed nil", res, err)
+	}
+	res, _, err := s.claim("event-001", now.Add(30*time.Second), time.Minute)
+	if err != nil || res != InFlight {
+		t.Fatalf("second claim() inside the lease = %v, %v; want in_flight nil", res, err)
 	}
 }
 
-func TestKVStore_Seen_GetError(t *testing.T) {
+func TestKVStore_Claim_TakesOverExpiredLease(t *testing.T) {
 	m := newMockKV()
-	m.getErr = errors.New("kv read error")
 	s := &kvStore{kv: m}
+	now := time.Now()
+
+	_, first, _ := s.claim("event-001", now, time.Minute)
+	res, second, err := s.claim("event-001", now.Add(2*time.Minute), time.Minute)
+	if err != nil || res != Claimed {
+		t.Fatalf("claim() after the lease = %v, %v; want claimed nil", res, err)
+	}
+	if second == first {
+		t.Errorf("takeover revision = %d, want a new revision", second)
+	}
+	if expiry, _ := parseClaim(m.data["event-001"]); !expiry.After(now.Add(2 * time.Minute)) {
+		t.Errorf("takeover expiry = %s, want the lease renewed", expiry)
+	}
+}
 
-	_, err := s.Seen("event-001")
+func TestKVStore_Claim_LosesTakeoverRace(t *testing.T) {
+	m := newMockKV()
+	m.write("event-001", claimValue(time.Now().Add(-time.Second)))
+	s := &kvStore{kv: &racingKV{mockKVClient: m}}
+
+	res, _, err := s.claim("event-001", time.Now(), time.Minute)
+	if err != nil || res != InFlight {
+		t.Fatalf("claim() = %v, %v; want in_flight nil when another worker takes over first", res, err)
+	}
+}
+
+// racingKV lets another worker take the claim over between claim's Get and Update.
+type racingKV struct{ *mockKVClient }
+
+func (r *racingKV) Get(key string) (nats.KeyValueEntry, error) {
+	e, err := r.mockKVClient.Get(key)
 	if err == nil {
-		t.Fatal("expected error, got nil")
+		r.write(key, claimValue(time.Now().Add(time.Minute)))
 	}
+	return e, err
 }
 
-func TestKVStore_Mark_SanitizesKey(t *testing.T) {
+func TestKVStore_Claim_Errors(t *testing.T) {
+	m := newMockKV()
+	m.createErr = errors.New("kv write error")
+	if _, _, err := (&kvStore{kv: m}).claim("event-001", time.Now(), time.Minute); err == nil {
+		t.Error("expected error from Create failure, got nil")
+	}
+
+	m = newMockKV()
+	m.write("event-001", doneValue)
+	m.getErr = errors.New("kv read error")
+	if _, _, err := (&kvStore{kv: m}).claim("event-001", time.Now(), time.Minute); err == nil {
+		t.Error("expected error from Get failure, got nil")
+	}
+}
+
+func TestKVStore_Complete_SanitizesKey(t *testing.T) {
 	m := newMockKV()
 	s := &kvStore{kv: m}
 
-	if err := s.Mark("HA_EVENTS:42"); err != nil {
-		t.Fatalf("Mark() error: %v", err)
+	if err := s.complete("HA_EVENTS:42"); err != nil {
+		t.Fatalf("complete() error: %v", err)
 	}
 	if len(m.putCalls) != 1 {
 		t.Fatalf("Put called %d times, want 1", len(m.putCalls))
@@ -186,101 +294,178 @@ func TestKVStore_Mark_SanitizesKey(t *testing.T) {
 	}
 }
 
-func TestKVStore_Mark_PutError(t *testing.T) {
+func TestKVStore_Complete_PutError(t *testing.T) {
 	m := newMockKV()
 	m.putErr = errors.New("kv write error")
 	s := &kvStore{kv: m}
 
-	err := s.Mark("event-001")
+	err := s.complete("event-001")
 	if err == nil {
 		t.Fatal("expected error from Put failure, got nil")
 	}
 }
 
+func TestParseClaim(t *testing.T) {
+	expiry := time.UnixMilli(1_750_000_000_000)
+	if got, ok := parseClaim(claimValue(expiry)); !ok || !got.Equal(expiry) {
+		t.Errorf("parseClaim(claimValue) = %s %v, want %s true", got, ok, expiry)
+	}
+	if _, ok := parseClaim(doneValue); ok {
+		t.Error("parseClaim(doneValue) reports in flight, want done")
+	}
+	if got, ok := parseClaim([]byte(claimPrefix + "garbage")); !ok || !got.IsZero() {
+		t.Errorf("parseClaim(garbage) = %s %v, want an expired claim", got, ok)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // hybridStore tests
 // ---------------------------------------------------------------------------
 
-func TestHybridStore_Seen_MissOnBoth(t *testing.T) {
-	h := &hybridStore{
-		mem: newMemStore(time.Hour),
-		kv:  &kvStore{kv: newMockKV()},
+func newTestHybrid(kv kvClient) *hybridStore {
+	return &hybridStore{
+		mem:   newMemStore(time.Hour),
+		kv:    &kvStore{kv: kv},
+		lease: time.Minute,
 	}
+}
+
+func TestHybridStore_ClaimCompleteThenDone_HitsMemCache(t *testing.T) {
+	m := newMockKV()
+	h := newTestHybrid(m)
 	defer func() { _ = h.Close() }()
 
-	ok, err := h.Seen("event-001")
+	if res, err := h.Claim("event-001"); err != nil || res != Claimed {
+		t.Fatalf("Claim() = %v, %v; want claimed nil", res, err)
+	}
+	if err := h.Complete("event-001"); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	// Force errors so a kv claim would fail — mem cache should be hit first
+	m.createErr = errors.New("should not be called")
+
+	res, err := h.Claim("event-001")
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("Claim() error: %v", err)
 	}
-	if ok {
-		t.Error("Seen() = true on empty store, want false")
+	if res != Done {
+		t.Errorf("Claim() after Complete() = %v, want done", res)
 	}
 }
 
-func TestHybridStore_MarkThenSeen_HitsMemCache(t *testing.T) {
+func TestHybridStore_ConcurrentClaims_OneWins(t *testing.T) {
 	m := newMockKV()
-	h := &hybridStore{
-		mem: newMemStore(time.Hour),
-		kv:  &kvStore{kv: m},
+	// Two stores share one bucket, as two engine instances do.
+	a, b := newTestHybrid(m), newTestHybrid(m)
+	defer func() { _ = a.Close(); _ = b.Close() }()
+
+	results := make(chan ClaimResult, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(results); i++ {
+		s := a
+		if i%2 == 1 {
+			s = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.Claim("event-001")
+			if err != nil {
+				t.Errorf("Claim() error: %v", err)
+			}
+			results <- res
+		}()
 	}
-	defer func() { _ = h.Close() }()
+	wg.Wait()
+	close(results)
 
-	if err := h.Mark("event-001"); err != nil {
-		t.Fatalf("Mark() error: %v", err)
+	claimed := 0
+	for res := range results {
+		switch res {
+		case Claimed:
+			claimed++
+		case InFlight:
+		default:
+			t.Errorf("Claim() = %v, want claimed or in_flight", res)
+		}
 	}
+	if claimed != 1 {
+		t.Errorf("%d concurrent claims succeeded, want exactly 1", claimed)
+	}
+}
 
-	// Force getErr so kv.Seen would fail — mem cache should be hit first
-	m.getErr = errors.New("should not be called")
+func TestHybridStore_ReleaseAllowsReclaim(t *testing.T) {
+	m := newMockKV()
+	h := newTestHybrid(m)
+	defer func() { _ = h.Close() }()
 
-	ok, err := h.Seen("event-001")
-	if err != nil {
-		t.Fatalf("Seen() error: %v", err)
+	if res, _ := h.Claim("event-001"); res != Claimed {
+		t.Fatalf("Claim() = %v, want claimed", res)
 	}
-	if !ok {
-		t.Error("Seen() = false after Mark(), want true")
+	if err := h.Release("event-001"); err != nil {
+		t.Fatalf("Release() error: %v", err)
+	}
+	if res, err := h.Claim("event-001"); err != nil || res != Claimed {
+		t.Fatalf("Claim() after Release() = %v, %v; want claimed nil", res, err)
 	}
 }
 
-func TestHybridStore_KVWriteFailureInMark_ReturnsNil(t *testing.T) {
+func TestHybridStore_ReleaseWithoutClaim_NoOp(t *testing.T) {
 	m := newMockKV()
-	m.putErr = errors.New("kv write error")
-	h := &hybridStore{
-		mem: newMemStore(time.Hour),
-		kv:  &kvStore{kv: m},
-	}
+	m.write("event-001", claimValue(time.Now().Add(time.Minute))) // held by another worker
+	h := newTestHybrid(m)
 	defer func() { _ = h.Close() }()
 
-	// KV write failure should not surface as an error from Mark (ADR-0025 race window).
-	if err := h.Mark("event-001"); err != nil {
-		t.Errorf("Mark() returned error on KV write failure, want nil: %v", err)
+	if err := h.Release("event-001"); err != nil {
+		t.Fatalf("Release() error: %v", err)
+	}
+	if len(m.deleteCalls) != 0 {
+		t.Errorf("Release() deleted %v, want another worker's claim left alone", m.deleteCalls)
 	}
 }
 
-func TestHybridStore_KVHit_WarmsMemCache(t *testing.T) {
+func TestHybridStore_CompleteKVFailure_ReturnsError(t *testing.T) {
 	m := newMockKV()
-	m.data["event_001"] = []byte{1}
-	h := &hybridStore{
-		mem: newMemStore(time.Hour),
-		kv:  &kvStore{kv: m},
+	h := newTestHybrid(m)
+	defer func() { _ = h.Close() }()
+
+	if res, _ := h.Claim("event-001"); res != Claimed {
+		t.Fatalf("Claim() = %v, want claimed", res)
+	}
+	m.putErr = errors.New("kv write error")
+	if err := h.Complete("event-001"); err == nil {
+		t.Error("Complete() returned nil on KV write failure, want the error")
 	}
+	// The local cache still records the completion, so this instance skips the event.
+	if res, _ := h.Claim("event-001"); res != Done {
+		t.Errorf("Claim() after failed Complete() = %v, want done from the memory cache", res)
+	}
+}
+
+func TestHybridStore_KVDone_WarmsMemCache(t *testing.T) {
+	m := newMockKV()
+	m.write("event_001", doneValue)
+	h := newTestHybrid(m)
 	defer func() { _ = h.Close() }()
 
-	// First Seen: miss in mem, hit in kv (warms mem cache)
-	ok, err := h.Seen("event:001")
-	if err != nil || !ok {
-		t.Fatalf("first Seen(): ok=%v err=%v, want true nil", ok, err)
+	// First Claim: miss in mem, done in kv (warms mem cache)
+	res, err := h.Claim("event:001")
+	if err != nil || res != Done {
+		t.Fatalf("first Claim(): %v %v, want done nil", res, err)
 	}
 
-	// Poison kv so any further kv.Get call fails
+	// Poison kv so any further kv call fails
+	m.createErr = errors.New("should not reach kv on second call")
 	m.getErr = errors.New("should not reach kv on second call")
 
-	// Second Seen: should be served from mem cache now
-	ok, err = h.Seen("event:001")
+	// Second Claim: should be served from mem cache now
+	res, err = h.Claim("event:001")
 	if err != nil {
-		t.Fatalf("second Seen() error: %v", err)
+		t.Fatalf("second Claim() error: %v", err)
 	}
-	if !ok {
-		t.Error("second Seen() = false, want true (mem cache should have warmed)")
+	if res != Done {
+		t.Errorf("second Claim() = %v, want done (mem cache should have warmed)", res)
 	}
 }
 
@@ -0,0 +1,228 @@
+package natsx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+)
+
+// limiter is a counting semaphore whose size can change while slots are held. A
+// shrunk limiter admits no one until enough holders release.
+type limiter struct {
+	mu      sync.Mutex
+	limit   int
+	inUse   int
+	peak    int           // highest inUse since the last takePeak
+	changed chan struct{} // closed and replaced whenever a slot may have freed up
+}
+
+func newLimiter(n int) *limiter {
+	return &limiter{limit: n, changed: make(chan struct{})}
+}
+
+// tryAcquire takes a slot if one is free.
+func (l *limiter) tryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse >= l.limit {
+		return false
+	}
+	l.inUse++
+	l.peak = max(l.peak, l.inUse)
+	return true
+}
+
+// acquire waits for a slot until ctx is done, reporting whether it got one.
+func (l *limiter) acquire(ctx context.Context) bool {
+	for {
+		if l.tryAcquire() {
+			return true
+		}
+		l.mu.Lock()
+		ch := l.changed
+		l.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.inUse--
+	l.notifyLocked()
+	l.mu.Unlock()
+}
+
+func (l *limiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = n
+	l.notifyLocked()
+	l.mu.Unlock()
+}
+
+func (l *limiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// takePeak returns the highest number of slots held at once since the last call.
+func (l *limiter) takePeak() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	peak := l.peak
+	l.peak = l.inUse
+	return peak
+}
+
+func (l *limiter) notifyLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// window is what a WorkerPool observed over one adaptation interval.
+type window struct {
+	processed int
+	failed    int           // naked or terminated
+	latency   time.Duration // summed handler time
+	saturated bool          // a fetched message waited for a free worker
+	peak      int           // most handlers running at once
+}
+
+// meanLatency is the mean handler time of w, or 0 if nothing was processed.
+func (w window) meanLatency() time.Duration {
+	if w.processed == 0 {
+		return 0
+	}
+	return w.latency / time.Duration(w.processed)
+}
+
+// concurrency adapts a WorkerPool's worker limit within [min, max] from what each
+// window observed, additive-increase / multiplicative-decrease:
+//
+//   - a window whose error rate reaches DefaultAdaptMaxErrorRate halves the limit:
+//     the handlers' dependency is failing, and more concurrency only hammers it;
+//   - a mean handler latency above DefaultAdaptLatencyFactor times the baseline steps
+//     the limit down: the dependency is saturated and requests are queueing;
+//   - every worker busy while the consumer still has pending messages steps it up;
+//   - a pool running under half its limit steps it down, so a quiet stream holds few
+//     workers and the next burst ramps up from its observed need.
+//
+// The fetch batch follows the limit, capped at FetchBatch, so a pool never pulls more
+// messages than it can start. With min == max the limit is fixed.
+type concurrency struct {
+	min, max int
+	maxBatch int
+	lim      *limiter
+
+	mu       sync.Mutex
+	win      window
+	baseline time.Duration // typical healthy mean latency; follows slowly upwards
+	pending  int64         // consumer's undelivered messages at the last adjust; -1 unknown
+}
+
+func newConcurrency(minWorkers, maxWorkers, maxBatch int) *concurrency {
+	if minWorkers <= 0 || minWorkers > maxWorkers {
+		minWorkers = maxWorkers
+	}
+	return &concurrency{
+		min:      minWorkers,
+		max:      maxWorkers,
+		maxBatch: maxBatch,
+		lim:      newLimiter(maxWorkers),
+		pending:  -1,
+	}
+}
+
+// adaptive reports whether the limit can change at all.
+func (c *concurrency) adaptive() bool { return c.min < c.max }
+
+// batch is the number of messages to request in the next Fetch.
+func (c *concurrency) batch() int { return max(1, min(c.maxBatch, c.lim.size())) }
+
+// observe records one handled message.
+func (c *concurrency) observe(d time.Duration, failed bool) {
+	c.mu.Lock()
+	c.win.processed++
+	c.win.latency += d
+	if failed {
+		c.win.failed++
+	}
+	c.mu.Unlock()
+}
+
+// waited records that a fetched message had to wait for a free worker.
+func (c *concurrency) waited() {
+	c.mu.Lock()
+	c.win.saturated = true
+	c.mu.Unlock()
+}
+
+// adjust closes the current window and applies the next limit. pending is the
+// consumer's count of undelivered messages, or -1 if unknown. It returns the old and
+// new limits.
+func (c *concurrency) adjust(pending int64) (from, to int) {
+	c.mu.Lock()
+	w := c.win
+	c.win = window{}
+	c.pending = pending
+	c.mu.Unlock()
+	w.peak = c.lim.takePeak()
+
+	from = c.lim.size()
+	to = nextLimit(from, c.min, c.max, w, pending, c.baseline)
+	c.baseline = nextBaseline(c.baseline, w)
+	if to != from {
+		c.lim.setLimit(to)
+	}
+	return from, to
+}
+
+// lastPending is the pending count seen at the last adjust, or -1 if unknown.
+func (c *concurrency) lastPending() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending
+}
+
+// nextLimit is the worker limit after window w; see concurrency for the rules.
+func nextLimit(cur, lo, hi int, w window, pending int64, baseline time.Duration) int {
+	if lo >= hi {
+		return cur
+	}
+	if w.processed >= config.DefaultAdaptMinSamples {
+		if float64(w.failed)/float64(w.processed) >= config.DefaultAdaptMaxErrorRate {
+			return max(lo, cur/2)
+		}
+		if baseline > 0 && w.meanLatency() > time.Duration(config.DefaultAdaptLatencyFactor)*baseline {
+			return max(lo, cur-1)
+		}
+	}
+	if w.saturated && pending != 0 {
+		return min(hi, cur+1)
+	}
+	if !w.saturated && w.peak < cur/2 {
+		return max(lo, cur-1)
+	}
+	return cur
+}
+
+// nextBaseline updates the latency baseline from a healthy window: it drops to a lower
+// mean at once and rises towards a higher one by an eighth per window, so a lasting
+// change in handler cost is accepted without being mistaken for saturation.
+func nextBaseline(baseline time.Duration, w window) time.Duration {
+	if w.processed < config.DefaultAdaptMinSamples ||
+		float64(w.failed)/float64(w.processed) >= config.DefaultAdaptMaxErrorRate {
+		return baseline
+	}
+	mean := w.meanLatency()
+	if baseline == 0 || mean < baseline {
+		return mean
+	}
+	return baseline + (mean-baseline)/8
+}
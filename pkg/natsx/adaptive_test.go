@@ -0,0 +1,127 @@
+//go:build fast
+
+package natsx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+)
+
+func TestNextLimit(t *testing.T) {
+	n := config.DefaultAdaptMinSamples
+	ms := time.Millisecond
+	cases := []struct {
+		name     string
+		cur      int
+		w        window
+		pending  int64
+		baseline time.Duration
+		want     int
+	}{
+		{"saturated with backlog grows", 4, window{saturated: true, peak: 4}, 100, 0, 5},
+		{"saturated with unknown pending grows", 4, window{saturated: true, peak: 4}, -1, 0, 5},
+		{"saturated at max holds", 8, window{saturated: true, peak: 8}, 100, 0, 8},
+		{"saturated but drained holds", 4, window{saturated: true, peak: 4}, 0, 0, 4},
+		{"idle shrinks", 6, window{peak: 1}, 0, 0, 5},
+		{"idle at min holds", 2, window{}, 0, 0, 2},
+		{"busy holds", 4, window{processed: n, latency: time.Duration(n) * ms, peak: 3}, 0, ms, 4},
+		{"errors halve", 8, window{processed: n, failed: n / 2, saturated: true, peak: 8}, 100, 0, 4},
+		{"errors halve to min", 3, window{processed: n, failed: n, peak: 3}, 100, 0, 2},
+		{"too few samples ignore errors", 4, window{processed: n - 1, failed: n - 1, saturated: true, peak: 4}, 100, 0, 5},
+		{"slow handlers step down", 6, window{processed: n, latency: time.Duration(n) * 10 * ms, saturated: true, peak: 6}, 100, ms, 5},
+		{"no baseline ignores latency", 6, window{processed: n, latency: time.Duration(n) * 10 * ms, saturated: true, peak: 6}, 100, 0, 7},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nextLimit(tc.cur, 2, 8, tc.w, tc.pending, tc.baseline); got != tc.want {
+				t.Errorf("nextLimit = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextLimit_FixedPool(t *testing.T) {
+	if got := nextLimit(4, 4, 4, window{saturated: true, peak: 4}, 100, 0); got != 4 {
+		t.Errorf("nextLimit with min == max = %d, want 4", got)
+	}
+}
+
+func TestNextBaseline(t *testing.T) {
+	n := config.DefaultAdaptMinSamples
+	healthy := func(mean time.Duration) window {
+		return window{processed: n, latency: time.Duration(n) * mean}
+	}
+	if got := nextBaseline(0, healthy(8*time.Millisecond)); got != 8*time.Millisecond {
+		t.Errorf("first baseline = %v, want 8ms", got)
+	}
+	if got := nextBaseline(8*time.Millisecond, healthy(4*time.Millisecond)); got != 4*time.Millisecond {
+		t.Errorf("baseline after a faster window = %v, want 4ms", got)
+	}
+	if got := nextBaseline(8*time.Millisecond, healthy(16*time.Millisecond)); got != 9*time.Millisecond {
+		t.Errorf("baseline after a slower window = %v, want 9ms", got)
+	}
+	failing := healthy(time.Millisecond)
+	failing.failed = n
+	if got := nextBaseline(8*time.Millisecond, failing); got != 8*time.Millisecond {
+		t.Errorf("baseline after a failing window = %v, want unchanged 8ms", got)
+	}
+	if got := nextBaseline(8*time.Millisecond, window{processed: 1, latency: time.Millisecond}); got != 8*time.Millisecond {
+		t.Errorf("baseline after a sparse window = %v, want unchanged 8ms", got)
+	}
+}
+
+func TestLimiter_Shrink(t *testing.T) {
+	l := newLimiter(2)
+	if !l.tryAcquire() || !l.tryAcquire() {
+		t.Fatal("expected two free slots")
+	}
+	l.setLimit(1)
+	l.release()
+	if l.tryAcquire() {
+		t.Fatal("shrunk limiter admitted a holder while at its new limit")
+	}
+	l.release()
+	if !l.tryAcquire() {
+		t.Fatal("expected a slot once holders fell below the new limit")
+	}
+	if got := l.takePeak(); got != 2 {
+		t.Errorf("takePeak = %d, want 2", got)
+	}
+	if got := l.takePeak(); got != 1 {
+		t.Errorf("takePeak after reset = %d, want 1 (slots still held)", got)
+	}
+}
+
+func TestLimiter_GrowWakesWaiter(t *testing.T) {
+	l := newLimiter(1)
+	l.tryAcquire()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got := make(chan bool)
+	go func() { got <- l.acquire(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	l.setLimit(2)
+	if !<-got {
+		t.Fatal("waiter not admitted after the limit grew")
+	}
+}
+
+func TestConcurrency_BatchFollowsLimit(t *testing.T) {
+	c := newConcurrency(2, 10, 5)
+	if !c.adaptive() {
+		t.Fatal("2..10 pool should be adaptive")
+	}
+	if got := c.batch(); got != 5 {
+		t.Errorf("batch at limit 10 = %d, want FetchBatch 5", got)
+	}
+	c.lim.setLimit(3)
+	if got := c.batch(); got != 3 {
+		t.Errorf("batch at limit 3 = %d, want 3", got)
+	}
+	if newConcurrency(0, 4, 4).adaptive() {
+		t.Error("MinWorkerCount 0 should fix the pool")
+	}
+}
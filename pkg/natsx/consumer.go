@@ -1,7 +1,6 @@
 package natsx
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
@@ -18,6 +17,9 @@ type PullConsumerConfig struct {
 	Durable string
 	// FilterSubject narrows which subjects this consumer receives.
 	FilterSubject string
+	// FilterSubjects, when set, narrows the consumer to any of these subjects and
+	// replaces FilterSubject. The subjects must not overlap (NATS 2.10+).
+	FilterSubjects []string
 	// MaxDeliver is the maximum number of delivery attempts before a message is considered poison (ADR-0022).
 	MaxDeliver int
 	// MaxAckPending is the maximum number of unacknowledged messages the server will hold in flight (ADR-0024).
@@ -26,40 +28,89 @@ type PullConsumerConfig struct {
 	AckWait time.Duration
 	// BackOff defines the server-side redelivery delay schedule (ADR-0022).
 	BackOff []time.Duration
-	// WorkerCount is the size of the fixed worker pool. Must be >= FetchBatch (ADR-0024).
+	// WorkerCount is the upper bound of the worker pool. Must be >= FetchBatch (ADR-0024).
 	WorkerCount int
-	// FetchBatch is the number of messages requested per Fetch call. Must be <= WorkerCount.
+	// MinWorkerCount is the lower bound the worker pool adapts down to. 0, or
+	// WorkerCount, fixes the pool at WorkerCount.
+	MinWorkerCount int
+	// FetchBatch is the largest number of messages requested per Fetch call; the pool
+	// requests no more than its current worker limit. Must be <= WorkerCount.
 	FetchBatch int
+	// OptStartSeq, when non-zero, makes a newly created consumer start at this stream
+	// sequence instead of the beginning of the stream. It has no effect on a consumer
+	// that already exists.
+	OptStartSeq uint64
+	// DeliverNew, when set and OptStartSeq is zero, makes a newly created consumer
+	// start with the next message published to the stream instead of the beginning
+	// of the stream. It has no effect on a consumer that already exists.
+	DeliverNew bool
 }
 
 // DefaultPullConsumerConfig returns a PullConsumerConfig pre-populated with Phase 3 defaults
 // from pkg/config. The caller provides only the stream, durable name, and filter subject.
 func DefaultPullConsumerConfig(stream, durable, filterSubj string) PullConsumerConfig {
 	return PullConsumerConfig{
-		Stream:        stream,
-		Durable:       durable,
-		FilterSubject: filterSubj,
-		MaxDeliver:    config.DefaultMaxDeliver,
-		MaxAckPending: config.DefaultMaxAckPending,
-		AckWait:       config.DefaultAckWait,
-		BackOff:       config.DefaultBackOff,
-		WorkerCount:   config.DefaultWorkerCount,
-		FetchBatch:    config.DefaultFetchBatch,
+		Stream:         stream,
+		Durable:        durable,
+		FilterSubject:  filterSubj,
+		MaxDeliver:     config.DefaultMaxDeliver,
+		MaxAckPending:  config.DefaultMaxAckPending,
+		AckWait:        config.DefaultAckWait,
+		BackOff:        config.DefaultBackOff,
+		WorkerCount:    config.DefaultWorkerCount,
+		MinWorkerCount: config.DefaultMinWorkerCount,
+		FetchBatch:     config.DefaultFetchBatch,
 	}
 }
 
-// EnsurePullConsumer creates (or verifies) a durable pull consumer and returns a bound
-// pull subscription. It uses the two-step NATS pattern: AddConsumer for server-side config,
-// then PullSubscribe with Bind to attach (ADR-0024).
+// EnsurePullConsumer creates or reconciles a durable pull consumer and returns a bound
+// pull subscription. It uses the two-step NATS pattern: AddConsumer for server-side
+// config, then PullSubscribe with Bind to attach (ADR-0024).
 //
-// The consumer is idempotent: if it already exists, the subscription binds to it directly.
-// If the existing consumer has a different configuration, AddConsumer will return an error.
+// The consumer is idempotent: if it already exists, drifted delivery settings
+// (AckWait, MaxDeliver, MaxAckPending, BackOff, FilterSubject) are updated in place
+// before binding, and drift the server cannot update is logged. Services take cfg from
+// Manifest().Consumer so the manifest stays the one place these settings live.
 func EnsurePullConsumer(js nats.JetStreamContext, cfg PullConsumerConfig) (*nats.Subscription, error) {
 	if cfg.FetchBatch > cfg.WorkerCount {
 		return nil, fmt.Errorf("natsx: FetchBatch (%d) must not exceed WorkerCount (%d)",
 			cfg.FetchBatch, cfg.WorkerCount)
 	}
 
+	drifts, err := reconcileConsumer(js, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	warnImmutable(drifts)
+
+	// Bind a pull subscription to the pre-existing durable consumer. A consumer with
+	// FilterSubjects binds without a subject.
+	subject := cfg.FilterSubject
+	if len(cfg.FilterSubjects) > 0 {
+		subject = ""
+	}
+	sub, err := js.PullSubscribe(subject, cfg.Durable,
+		nats.Bind(cfg.Stream, cfg.Durable),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("natsx: pull subscribe %q: %w", cfg.Durable, err)
+	}
+	return sub, nil
+}
+
+// UpdatePullConsumer reconciles the existing durable consumer with cfg, as
+// EnsurePullConsumer does, without binding a subscription. Services call it when a
+// setting they derive at runtime, such as FilterSubjects, changes.
+func UpdatePullConsumer(js nats.JetStreamContext, cfg PullConsumerConfig) error {
+	drifts, err := reconcileConsumer(js, cfg, true)
+	warnImmutable(drifts)
+	return err
+}
+
+// consumerConfig is the server-side config of a new consumer created from cfg.
+// OptStartSeq and DeliverNew only apply here: they have no effect on a consumer
+// that already exists.
+func consumerConfig(cfg PullConsumerConfig) *nats.ConsumerConfig {
 	consumerCfg := &nats.ConsumerConfig{
 		Durable:       cfg.Durable,
 		FilterSubject: cfg.FilterSubject,
@@ -69,25 +120,15 @@ func EnsurePullConsumer(js nats.JetStreamContext, cfg PullConsumerConfig) (*nats
 		AckWait:       cfg.AckWait,
 		MaxAckPending: cfg.MaxAckPending,
 	}
-
-	// Create the consumer only if it does not already exist.
-	_, err := js.ConsumerInfo(cfg.Stream, cfg.Durable)
-	if err != nil {
-		if !errors.Is(err, nats.ErrConsumerNotFound) {
-			return nil, fmt.Errorf("natsx: consumer info %q: %w", cfg.Durable, err)
-		}
-		// Consumer does not exist — create it.
-		if _, err = js.AddConsumer(cfg.Stream, consumerCfg); err != nil {
-			return nil, fmt.Errorf("natsx: add consumer %q: %w", cfg.Durable, err)
-		}
+	if len(cfg.FilterSubjects) > 0 {
+		consumerCfg.FilterSubject, consumerCfg.FilterSubjects = "", cfg.FilterSubjects
 	}
-
-	// Bind a pull subscription to the pre-existing durable consumer.
-	sub, err := js.PullSubscribe(cfg.FilterSubject, cfg.Durable,
-		nats.Bind(cfg.Stream, cfg.Durable),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("natsx: pull subscribe %q: %w", cfg.Durable, err)
+	switch {
+	case cfg.OptStartSeq > 0:
+		consumerCfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		consumerCfg.OptStartSeq = cfg.OptStartSeq
+	case cfg.DeliverNew:
+		consumerCfg.DeliverPolicy = nats.DeliverNewPolicy
 	}
-	return sub, nil
+	return consumerCfg
 }
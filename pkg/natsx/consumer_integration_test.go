@@ -103,8 +103,8 @@ func publish(t *testing.T, nc *natsgo.Conn, subject, eventID string) {
 
 // runFetchLoop runs a minimal fetch-check-ack loop that mirrors the engine's
 // Consumer.handle pattern. It processes messages from sub until ctx is cancelled
-// or the loop hits a fatal fetch error. Each message is idempotency-checked via
-// store; if not seen, processFn is called and the message is acked and marked.
+// or the loop hits a fatal fetch error. Each message's event ID is claimed via
+// store; if claimed, processFn is called and the message is completed and acked.
 func runFetchLoop(
 	ctx context.Context,
 	sub *natsgo.Subscription,
@@ -138,15 +138,19 @@ func runFetchLoop(
 				eventID = msg.Subject
 			}
 
-			seen, err := store.Seen(eventID)
-			if err != nil || seen {
+			claim, err := store.Claim(eventID)
+			if err != nil || claim == idempotency.InFlight {
+				_ = msg.Nak()
+				continue
+			}
+			if claim == idempotency.Done {
 				_ = msg.Ack() // ack duplicates to remove from pending
 				continue
 			}
 
 			processFn(msg.Subject, msg.Data)
 
-			if err := store.Mark(eventID); err != nil {
+			if err := store.Complete(eventID); err != nil {
 				_ = msg.Nak()
 				continue
 			}
@@ -213,7 +217,7 @@ func TestEnsurePullConsumer_Integration_IdempotencyRejectsDuplicate(t *testing.T
 	if err != nil {
 		t.Fatalf("CreateOrBindKVBucket: %v", err)
 	}
-	store := idempotency.NewHybridStore(kvBucket, 24*time.Hour, "test")
+	store := idempotency.NewHybridStore(kvBucket, 24*time.Hour, time.Minute, "test")
 	defer func() { _ = store.Close() }()
 
 	const subject = "test.events.dedup"
@@ -245,6 +249,110 @@ func TestEnsurePullConsumer_Integration_IdempotencyRejectsDuplicate(t *testing.T
 	}
 }
 
+// TestIdempotency_Integration_ClaimIsExclusive verifies against a real KV bucket that
+// two stores (two engine instances) cannot both claim one event ID, that a released
+// claim can be taken again, and that an expired lease is taken over.
+func TestIdempotency_Integration_ClaimIsExclusive(t *testing.T) {
+	nc := startNATS(t)
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("JetStream: %v", err)
+	}
+	kvBucket, err := idempotency.CreateOrBindKVBucket(js, "TEST_CLAIMS", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateOrBindKVBucket: %v", err)
+	}
+	a := idempotency.NewHybridStore(kvBucket, time.Hour, time.Minute, "test")
+	b := idempotency.NewHybridStore(kvBucket, time.Hour, time.Minute, "test")
+	defer func() { _ = a.Close(); _ = b.Close() }()
+
+	if res, err := a.Claim("evt-claim-001"); err != nil || res != idempotency.Claimed {
+		t.Fatalf("a.Claim = %v, %v; want claimed", res, err)
+	}
+	if res, err := b.Claim("evt-claim-001"); err != nil || res != idempotency.InFlight {
+		t.Fatalf("b.Claim while a holds it = %v, %v; want in_flight", res, err)
+	}
+	if err := a.Release("evt-claim-001"); err != nil {
+		t.Fatalf("a.Release: %v", err)
+	}
+	if res, err := b.Claim("evt-claim-001"); err != nil || res != idempotency.Claimed {
+		t.Fatalf("b.Claim after release = %v, %v; want claimed", res, err)
+	}
+	if err := b.Complete("evt-claim-001"); err != nil {
+		t.Fatalf("b.Complete: %v", err)
+	}
+	if res, err := a.Claim("evt-claim-001"); err != nil || res != idempotency.Done {
+		t.Fatalf("a.Claim after completion = %v, %v; want done", res, err)
+	}
+
+	// A claim whose holder never finishes is taken over once its lease lapses.
+	short := idempotency.NewHybridStore(kvBucket, time.Hour, 100*time.Millisecond, "test")
+	defer func() { _ = short.Close() }()
+	if res, err := short.Claim("evt-claim-002"); err != nil || res != idempotency.Claimed {
+		t.Fatalf("short.Claim = %v, %v; want claimed", res, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if res, err := a.Claim("evt-claim-002"); err != nil || res != idempotency.Claimed {
+		t.Fatalf("a.Claim after the lease lapsed = %v, %v; want claimed", res, err)
+	}
+	// The crashed holder's late release must not drop the new claim.
+	if err := short.Release("evt-claim-002"); err != nil {
+		t.Fatalf("short.Release: %v", err)
+	}
+	if res, err := b.Claim("evt-claim-002"); err != nil || res != idempotency.InFlight {
+		t.Fatalf("b.Claim after a stale release = %v, %v; want in_flight", res, err)
+	}
+}
+
+// TestWorkerPool_Integration_SlowHandlerNotRedelivered verifies a handler running
+// longer than BackOff[0], the AckWait the server applies, keeps its message through
+// in-progress heartbeats instead of having it redelivered while it still runs.
+func TestWorkerPool_Integration_SlowHandlerNotRedelivered(t *testing.T) {
+	nc := startNATS(t)
+	js := ensureStream(t, nc)
+
+	cfg := natsx.DefaultPullConsumerConfig("TEST_STREAM", "test_slow_consumer", "test.events.>")
+	sub, err := natsx.EnsurePullConsumer(js, cfg)
+	if err != nil {
+		t.Fatalf("EnsurePullConsumer: %v", err)
+	}
+	t.Cleanup(func() { _ = sub.Unsubscribe() })
+
+	var deliveries atomic.Int32
+	handled := make(chan struct{})
+	pool, err := natsx.NewWorkerPool(sub, cfg, func(_ context.Context, _ *natsgo.Msg) natsx.Result {
+		if deliveries.Add(1) == 1 {
+			time.Sleep(3 * cfg.BackOff[0])
+			close(handled)
+		}
+		return natsx.Ack()
+	}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewWorkerPool: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		_ = pool.Run(ctx)
+		close(stopped)
+	}()
+	publish(t, nc, "test.events.slow", "evt-slow-001")
+
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler did not finish")
+	}
+	time.Sleep(2 * cfg.BackOff[0]) // room for a redelivery to arrive, if one was made
+	cancel()
+	<-stopped
+
+	if got := deliveries.Load(); got != 1 {
+		t.Errorf("message delivered %d times, want 1", got)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // testStore — a minimal in-memory idempotency.Store for integration tests
 // that don't need KV durability.
@@ -258,6 +366,13 @@ func newTestStore() idempotency.Store {
 	return &testStore{seen: make(map[string]bool)}
 }
 
-func (s *testStore) Seen(id string) (bool, error) { return s.seen[id], nil }
-func (s *testStore) Mark(id string) error         { s.seen[id] = true; return nil }
-func (s *testStore) Close() error                 { return nil }
+func (s *testStore) Claim(id string) (idempotency.ClaimResult, error) {
+	if s.seen[id] {
+		return idempotency.Done, nil
+	}
+	return idempotency.Claimed, nil
+}
+
+func (s *testStore) Complete(id string) error { s.seen[id] = true; return nil }
+func (s *testStore) Release(string) error     { return nil }
+func (s *testStore) Close() error             { return nil }
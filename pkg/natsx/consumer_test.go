@@ -5,6 +5,8 @@ package natsx
 import (
 	"testing"
 
+	"github.com/nats-io/nats.go"
+
 	"github.com/primaryrutabaga/ruby-core/pkg/config"
 )
 
@@ -57,3 +59,34 @@ func TestEnsurePullConsumer_FetchBatchExceedsWorkerCount(t *testing.T) {
 		t.Fatal("expected error when FetchBatch > WorkerCount, got nil")
 	}
 }
+
+func TestConsumerConfig_StartPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		startSeq   uint64
+		deliverNew bool
+		want       nats.DeliverPolicy
+	}{
+		{"default", 0, false, nats.DeliverAllPolicy},
+		{"deliver new", 0, true, nats.DeliverNewPolicy},
+		{"start sequence wins", 42, true, nats.DeliverByStartSequencePolicy},
+	}
+	for _, c := range cases {
+		cfg := DefaultPullConsumerConfig("S", "d", "a.>")
+		cfg.OptStartSeq, cfg.DeliverNew = c.startSeq, c.deliverNew
+		got := consumerConfig(cfg)
+		if got.DeliverPolicy != c.want || got.OptStartSeq != c.startSeq {
+			t.Errorf("%s: DeliverPolicy = %v, OptStartSeq = %d; want %v, %d",
+				c.name, got.DeliverPolicy, got.OptStartSeq, c.want, c.startSeq)
+		}
+	}
+}
+
+func TestConsumerConfig_FilterSubjects(t *testing.T) {
+	cfg := DefaultPullConsumerConfig("S", "d", "a.>")
+	cfg.FilterSubjects = []string{"a.b.>", "a.c"}
+	got := consumerConfig(cfg)
+	if got.FilterSubject != "" || len(got.FilterSubjects) != 2 {
+		t.Errorf("filters = %q %q, want only the list", got.FilterSubject, got.FilterSubjects)
+	}
+}
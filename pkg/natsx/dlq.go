@@ -0,0 +1,372 @@
+package natsx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DLQStream is the JetStream stream that captures dead-lettered messages on dlq.>
+// (ADR-0022). See EnsureDLQStream.
+const DLQStream = "DLQ"
+
+// Headers set on every message the DLQ forwarder publishes, describing where the
+// dead-lettered payload came from and why it failed. The DLQ subject only names the
+// stream (lowercased) and the consumer; these headers carry what is needed to
+// diagnose and replay it.
+const (
+	// HeaderDLQSubject is the subject the message was originally published on.
+	HeaderDLQSubject = "Ruby-Dlq-Subject"
+	// HeaderDLQStream is the name of the stream the message was consumed from.
+	HeaderDLQStream = "Ruby-Dlq-Stream"
+	// HeaderDLQConsumer is the durable consumer that exhausted MaxDeliver. A message
+	// replayed from the DLQ carries it too: every other consumer of the stream acks
+	// the replay unprocessed (see WorkerPool).
+	HeaderDLQConsumer = "Ruby-Dlq-Consumer"
+	// HeaderDLQSequence is the message's sequence in the original stream.
+	HeaderDLQSequence = "Ruby-Dlq-Sequence"
+	// HeaderDLQDeliveries is the delivery count reported by the max-delivery advisory.
+	HeaderDLQDeliveries = "Ruby-Dlq-Deliveries"
+	// HeaderDLQFirstFailure and HeaderDLQLastFailure are the RFC 3339 times of the
+	// first and last processing failure the consumer saw. Absent when the consumer
+	// did not observe them, e.g. after an engine restart between deliveries.
+	HeaderDLQFirstFailure = "Ruby-Dlq-First-Failure"
+	HeaderDLQLastFailure  = "Ruby-Dlq-Last-Failure"
+	// HeaderDLQError is the last processing error, flattened to one line and
+	// truncated to MaxDLQErrorLen bytes.
+	HeaderDLQError = "Ruby-Dlq-Error"
+	// HeaderDLQOrigPrefix prefixes each of the original message's headers. They are
+	// renamed rather than copied so Nats-Msg-Id does not dedup distinct failures of
+	// the same event within the DLQ stream, and a stale traceparent is not mistaken
+	// for the DLQ message's own trace context.
+	HeaderDLQOrigPrefix = "Ruby-Dlq-Orig-"
+)
+
+// MaxDLQErrorLen bounds the HeaderDLQError value.
+const MaxDLQErrorLen = 1024
+
+// SetDLQOriginalHeaders copies orig into h under HeaderDLQOrigPrefix.
+func SetDLQOriginalHeaders(h, orig nats.Header) {
+	for k, vs := range orig {
+		h[HeaderDLQOrigPrefix+k] = append([]string(nil), vs...)
+	}
+}
+
+// DLQOriginalHeaders returns the original message headers stored in h by
+// SetDLQOriginalHeaders, with their original names, or nil if there are none.
+func DLQOriginalHeaders(h nats.Header) nats.Header {
+	var orig nats.Header
+	for k, vs := range h {
+		name, ok := strings.CutPrefix(k, HeaderDLQOrigPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if orig == nil {
+			orig = nats.Header{}
+		}
+		orig[name] = append([]string(nil), vs...)
+	}
+	return orig
+}
+
+// DLQErrorHeader renders err as a HeaderDLQError value: header values cannot span
+// lines, so line breaks become spaces, and the result is cut to MaxDLQErrorLen bytes
+// on a rune boundary.
+func DLQErrorHeader(err string) string {
+	s := strings.Join(strings.Fields(err), " ")
+	if len(s) <= MaxDLQErrorLen {
+		return s
+	}
+	cut := MaxDLQErrorLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// ---------------------------------------------------------------------------
+// FailureLog — why unacked messages failed, for the DLQ envelope
+// ---------------------------------------------------------------------------
+
+// maxFailures bounds a FailureLog. Entries normally leave when the message is
+// acked or dead-lettered; the bound covers messages whose advisory never arrives.
+const maxFailures = 1024
+
+// deliveryFailure summarises the failed deliveries of one stream message.
+type deliveryFailure struct {
+	first, last time.Time
+	err         string
+}
+
+// FailureLog remembers the last processing error per stream sequence of one
+// consumer so its DLQForwarder can record why a message was dead-lettered. The
+// consumer loop calls Record when it naks a message and Forget when it acks one.
+// A nil *FailureLog records nothing.
+type FailureLog struct {
+	mu      sync.Mutex
+	entries map[uint64]deliveryFailure
+	now     func() time.Time
+}
+
+// NewFailureLog returns an empty FailureLog.
+func NewFailureLog() *FailureLog {
+	return &FailureLog{entries: make(map[uint64]deliveryFailure), now: time.Now}
+}
+
+// Record notes a failed delivery of stream sequence seq, evicting the
+// longest-idle entry when full.
+func (l *FailureLog) Record(seq uint64, err error) {
+	if l == nil {
+		return
+	}
+	msg := "unknown error"
+	if err != nil {
+		msg = err.Error()
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.entries[seq]
+	if !ok {
+		if len(l.entries) >= maxFailures {
+			l.evictOldest()
+		}
+		f.first = now
+	}
+	f.last, f.err = now, msg
+	l.entries[seq] = f
+}
+
+// RecordMsg is Record for a JetStream message, keyed by its stream sequence.
+// Messages without JetStream metadata are ignored.
+func (l *FailureLog) RecordMsg(msg *nats.Msg, err error) {
+	if meta, merr := msg.Metadata(); merr == nil {
+		l.Record(meta.Sequence.Stream, err)
+	}
+}
+
+// ForgetMsg is Forget for a JetStream message.
+func (l *FailureLog) ForgetMsg(msg *nats.Msg) {
+	if meta, err := msg.Metadata(); err == nil {
+		l.Forget(meta.Sequence.Stream)
+	}
+}
+
+// evictOldest drops the entry whose last failure is oldest. Callers hold l.mu.
+func (l *FailureLog) evictOldest() {
+	var oldest uint64
+	var at time.Time
+	for seq, f := range l.entries {
+		if at.IsZero() || f.last.Before(at) {
+			oldest, at = seq, f.last
+		}
+	}
+	delete(l.entries, oldest)
+}
+
+// Forget drops seq once its message no longer needs a failure record.
+func (l *FailureLog) Forget(seq uint64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	delete(l.entries, seq)
+	l.mu.Unlock()
+}
+
+// take returns and drops the failure record for seq.
+func (l *FailureLog) take(seq uint64) (deliveryFailure, bool) {
+	if l == nil {
+		return deliveryFailure{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.entries[seq]
+	delete(l.entries, seq)
+	return f, ok
+}
+
+// ---------------------------------------------------------------------------
+// DLQForwarder — routes dead-lettered messages to the DLQ stream (ADR-0022)
+// ---------------------------------------------------------------------------
+
+// maxDeliverAdvisory is the payload emitted by the NATS server when a consumer
+// exceeds MaxDeliver attempts for a message.
+type maxDeliverAdvisory struct {
+	Stream     string `json:"stream"`
+	Consumer   string `json:"consumer"`
+	StreamSeq  uint64 `json:"stream_seq"`
+	Deliveries int    `json:"deliveries"`
+}
+
+// dlqJetStream is the subset of nats.JetStreamContext used by DLQForwarder.
+type dlqJetStream interface {
+	GetMsg(name string, seq uint64, opts ...nats.JSOpt) (*nats.RawStreamMsg, error)
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// DLQForwarder subscribes to the NATS server max-delivery advisory for one durable
+// consumer and republishes each message it gives up on to dlq.{stream}.{consumer}
+// in the DLQ stream (ADR-0022), enveloped in the HeaderDLQ* headers that make it
+// diagnosable and replayable. Every service wires one to each of its durable
+// consumers; the NATS user needs to subscribe to the consumer's advisory subject
+// and publish to its DLQ subject.
+//
+// Why advisory-based: ConsumerConfig has no Republish field in nats.go v1.48.0.
+// StreamConfig.RePublish copies ALL messages, not just dead-lettered ones.
+// The advisory is the only server-side signal for max-delivery exhaustion.
+type DLQForwarder struct {
+	js        dlqJetStream
+	sub       *nats.Subscription
+	msgCh     chan *nats.Msg
+	log       *slog.Logger
+	stream    string              // e.g. "HA_EVENTS"
+	consumer  string              // e.g. "engine_rules"
+	failures  *FailureLog         // the consumer's failure log; nil-safe
+	forwarded metric.Int64Counter // ruby_core_dlq_forwarded_total{stream,consumer}
+}
+
+// logger returns f.log if set, otherwise slog.Default().
+func (f *DLQForwarder) logger() *slog.Logger {
+	if f.log != nil {
+		return f.log
+	}
+	return slog.Default()
+}
+
+// NewDLQForwarder subscribes to the max-delivery advisory for the given stream and
+// consumer and returns a DLQForwarder ready to be started with Run. failures is the
+// consumer's FailureLog, or nil if the consumer does not keep one; the envelope then
+// omits the failure times and error.
+func NewDLQForwarder(nc *nats.Conn, js nats.JetStreamContext, stream, consumer string, failures *FailureLog, log *slog.Logger) (*DLQForwarder, error) {
+	advisorySubj := fmt.Sprintf("$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.%s.%s", stream, consumer)
+	msgCh := make(chan *nats.Msg, 64)
+	sub, err := nc.ChanSubscribe(advisorySubj, msgCh)
+	if err != nil {
+		return nil, fmt.Errorf("natsx: dlq forwarder subscribe %q: %w", advisorySubj, err)
+	}
+	forwarded, _ := otel.Meter(meterName).Int64Counter(
+		"ruby_core_dlq_forwarded_total",
+		metric.WithDescription("Messages routed to the DLQ stream after max-delivery exhaustion"),
+	)
+	return &DLQForwarder{
+		js:        js,
+		sub:       sub,
+		msgCh:     msgCh,
+		log:       log,
+		stream:    stream,
+		consumer:  consumer,
+		failures:  failures,
+		forwarded: forwarded,
+	}, nil
+}
+
+// Consumer returns the name of the durable consumer f forwards for.
+func (f *DLQForwarder) Consumer() string { return f.consumer }
+
+// Run processes advisory messages until ctx is cancelled.
+func (f *DLQForwarder) Run(ctx context.Context) error {
+	for {
+		select {
+		case msg, ok := <-f.msgCh:
+			if !ok {
+				return nil
+			}
+			f.handleAdvisory(msg)
+		case <-ctx.Done():
+			_ = f.sub.Unsubscribe()
+			return nil
+		}
+	}
+}
+
+// handleAdvisory fetches the original message by sequence and publishes it to the DLQ stream
+// (see dlqMessage). Failures are logged but non-fatal — the consumer has already moved on.
+//
+// Risk: if the stream's retention has evicted the message before the advisory fires, the
+// DLQ routing silently drops. Streams with a MaxAge keep it well past the retry window.
+func (f *DLQForwarder) handleAdvisory(msg *nats.Msg) {
+	var adv maxDeliverAdvisory
+	if err := json.Unmarshal(msg.Data, &adv); err != nil {
+		f.logger().Error("natsx: dlq: unmarshal advisory", slog.String("error", err.Error()))
+		return
+	}
+
+	orig, err := f.js.GetMsg(f.stream, adv.StreamSeq)
+	if err != nil {
+		f.logger().Error("natsx: dlq: get original message, dropped from DLQ",
+			slog.String("stream", f.stream),
+			slog.String("consumer", f.consumer),
+			slog.Uint64("seq", adv.StreamSeq),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	// BuildDLQSubject requires lowercase tokens (ADR-0027).
+	dlqSubj, err := BuildDLQSubject(strings.ToLower(f.stream), f.consumer)
+	if err != nil {
+		f.logger().Error("natsx: dlq: build subject",
+			slog.String("stream", f.stream),
+			slog.String("consumer", f.consumer),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	failure, _ := f.failures.take(adv.StreamSeq)
+	if _, err := f.js.PublishMsg(f.dlqMessage(dlqSubj, orig, adv, failure)); err != nil {
+		f.logger().Error("natsx: dlq: publish failed",
+			slog.Uint64("seq", adv.StreamSeq),
+			slog.String("subject", dlqSubj),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if f.forwarded != nil {
+		f.forwarded.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("stream", f.stream),
+			attribute.String("consumer", f.consumer),
+		))
+	}
+	f.logger().Info("natsx: dlq: routed",
+		slog.String("stream", f.stream),
+		slog.String("consumer", f.consumer),
+		slog.Uint64("seq", adv.StreamSeq),
+		slog.String("subject", dlqSubj),
+		slog.Int("deliveries", adv.Deliveries),
+		slog.String("last_error", failure.err),
+	)
+}
+
+// dlqMessage builds the DLQ envelope for orig: its payload unchanged, its headers
+// renamed under HeaderDLQOrigPrefix, and headers recording its original subject,
+// stream, sequence, delivery count and, when the consumer saw them, the first/last
+// failure times and last error.
+func (f *DLQForwarder) dlqMessage(dlqSubj string, orig *nats.RawStreamMsg, adv maxDeliverAdvisory, failure deliveryFailure) *nats.Msg {
+	m := nats.NewMsg(dlqSubj)
+	m.Data = orig.Data
+	SetDLQOriginalHeaders(m.Header, orig.Header)
+	m.Header.Set(HeaderDLQSubject, orig.Subject)
+	m.Header.Set(HeaderDLQStream, f.stream)
+	m.Header.Set(HeaderDLQConsumer, f.consumer)
+	m.Header.Set(HeaderDLQSequence, strconv.FormatUint(adv.StreamSeq, 10))
+	m.Header.Set(HeaderDLQDeliveries, strconv.Itoa(adv.Deliveries))
+	if !failure.first.IsZero() {
+		m.Header.Set(HeaderDLQFirstFailure, failure.first.UTC().Format(time.RFC3339Nano))
+		m.Header.Set(HeaderDLQLastFailure, failure.last.UTC().Format(time.RFC3339Nano))
+		m.Header.Set(HeaderDLQError, DLQErrorHeader(failure.err))
+	}
+	return m
+}
@@ -0,0 +1,232 @@
+//go:build fast
+
+package natsx
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDLQOriginalHeaders_RoundTrip(t *testing.T) {
+	orig := nats.Header{}
+	orig.Set(nats.MsgIdHdr, "evt-1")
+	orig.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	orig.Add("X-Multi", "a")
+	orig.Add("X-Multi", "b")
+
+	h := nats.Header{}
+	h.Set(HeaderDLQStream, "HA_EVENTS")
+	SetDLQOriginalHeaders(h, orig)
+	if h.Get(nats.MsgIdHdr) != "" || h.Get("traceparent") != "" {
+		t.Errorf("original headers copied unprefixed: %v", h)
+	}
+	if got := h.Get(HeaderDLQOrigPrefix + nats.MsgIdHdr); got != "evt-1" {
+		t.Errorf("prefixed Nats-Msg-Id = %q, want evt-1", got)
+	}
+
+	back := DLQOriginalHeaders(h)
+	if len(back) != 3 || back.Get(nats.MsgIdHdr) != "evt-1" || back.Get("traceparent") != orig.Get("traceparent") ||
+		strings.Join(back.Values("X-Multi"), ",") != "a,b" {
+		t.Errorf("DLQOriginalHeaders = %v, want %v", back, orig)
+	}
+	if DLQOriginalHeaders(nats.Header{HeaderDLQStream: {"HA_EVENTS"}}) != nil {
+		t.Error("DLQOriginalHeaders without prefixed headers should be nil")
+	}
+}
+
+func TestDLQErrorHeader(t *testing.T) {
+	if got := DLQErrorHeader("rules: eval\n\tkitchen: timeout\r\n"); got != "rules: eval kitchen: timeout" {
+		t.Errorf("DLQErrorHeader = %q", got)
+	}
+	long := strings.Repeat("é", MaxDLQErrorLen) // 2 bytes per rune
+	got := DLQErrorHeader(long)
+	if len(got) > MaxDLQErrorLen || !utf8.ValidString(got) || len(got) < MaxDLQErrorLen-1 {
+		t.Errorf("DLQErrorHeader(long) has %d bytes, valid=%v", len(got), utf8.ValidString(got))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// maxDeliverAdvisory parsing test
+// ---------------------------------------------------------------------------
+
+func TestMaxDeliverAdvisory_Parse(t *testing.T) {
+	payload := `{
+		"type": "io.nats.jetstream.advisory.v1.max_deliver",
+		"id": "some-id",
+		"timestamp": "2026-02-23T10:00:00Z",
+		"stream": "HA_EVENTS",
+		"consumer": "engine_processor",
+		"stream_seq": 42,
+		"consumer_seq": 42,
+		"deliveries": 5
+	}`
+
+	var adv maxDeliverAdvisory
+	if err := json.Unmarshal([]byte(payload), &adv); err != nil {
+		t.Fatalf("unmarshal advisory: %v", err)
+	}
+	if adv.Stream != "HA_EVENTS" {
+		t.Errorf("Stream = %q, want %q", adv.Stream, "HA_EVENTS")
+	}
+	if adv.StreamSeq != 42 {
+		t.Errorf("StreamSeq = %d, want 42", adv.StreamSeq)
+	}
+	if adv.Deliveries != 5 {
+		t.Errorf("Deliveries = %d, want 5", adv.Deliveries)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// FailureLog and DLQForwarder tests
+// ---------------------------------------------------------------------------
+
+func TestFailureLog_RecordsFirstAndLastFailure(t *testing.T) {
+	l := NewFailureLog()
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.Record(7, errors.New("first"))
+	now = now.Add(15 * time.Second)
+	l.Record(7, errors.New("rules: kitchen: timeout"))
+
+	f, ok := l.take(7)
+	if !ok {
+		t.Fatal("take(7) found no failure")
+	}
+	if !f.first.Equal(now.Add(-15*time.Second)) || !f.last.Equal(now) || f.err != "rules: kitchen: timeout" {
+		t.Errorf("failure = %+v", f)
+	}
+	if _, ok := l.take(7); ok {
+		t.Error("take(7) returned the failure twice")
+	}
+
+	l.Record(8, errors.New("x"))
+	l.Forget(8)
+	if _, ok := l.take(8); ok {
+		t.Error("forget(8) left the failure behind")
+	}
+
+	var nilLog *FailureLog
+	nilLog.Record(1, errors.New("x")) // must not panic
+	if _, ok := nilLog.take(1); ok {
+		t.Error("nil failureLog returned a failure")
+	}
+}
+
+func TestFailureLog_EvictsOldestWhenFull(t *testing.T) {
+	l := NewFailureLog()
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { now = now.Add(time.Second); return now }
+	for seq := uint64(1); seq <= maxFailures+1; seq++ {
+		l.Record(seq, errors.New("x"))
+	}
+	if len(l.entries) != maxFailures {
+		t.Errorf("entries = %d, want %d", len(l.entries), maxFailures)
+	}
+	if _, ok := l.entries[1]; ok {
+		t.Error("oldest entry (seq 1) was not evicted")
+	}
+}
+
+func TestDLQMessage_EnvelopesOriginal(t *testing.T) {
+	f := &DLQForwarder{stream: "HA_EVENTS", consumer: "engine_rules"}
+	orig := &nats.RawStreamMsg{
+		Subject:  "ha.events.light.kitchen",
+		Sequence: 409,
+		Header: nats.Header{
+			nats.MsgIdHdr: {"evt-1"},
+			"traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		},
+		Data: []byte(`{"id":"evt-1"}`),
+	}
+	first := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	failure := deliveryFailure{first: first, last: first.Add(15 * time.Second), err: "rules: eval\nkitchen: timeout"}
+
+	m := f.dlqMessage("dlq.ha_events.engine_rules", orig, maxDeliverAdvisory{StreamSeq: 409, Deliveries: 5}, failure)
+
+	if m.Subject != "dlq.ha_events.engine_rules" || string(m.Data) != `{"id":"evt-1"}` {
+		t.Errorf("DLQ message = %s %s", m.Subject, m.Data)
+	}
+	want := map[string]string{
+		HeaderDLQSubject:                    "ha.events.light.kitchen",
+		HeaderDLQStream:                     "HA_EVENTS",
+		HeaderDLQConsumer:                   "engine_rules",
+		HeaderDLQSequence:                   "409",
+		HeaderDLQDeliveries:                 "5",
+		HeaderDLQFirstFailure:               "2026-06-01T12:00:00Z",
+		HeaderDLQLastFailure:                "2026-06-01T12:00:15Z",
+		HeaderDLQError:                      "rules: eval kitchen: timeout",
+		HeaderDLQOrigPrefix + nats.MsgIdHdr: "evt-1",
+		HeaderDLQOrigPrefix + "traceparent": orig.Header.Get("traceparent"),
+	}
+	for k, v := range want {
+		if got := m.Header.Get(k); got != v {
+			t.Errorf("header %s = %q, want %q", k, got, v)
+		}
+	}
+	if m.Header.Get(nats.MsgIdHdr) != "" {
+		t.Error("DLQ message carries the original Nats-Msg-Id; it would dedup within the DLQ stream")
+	}
+
+	// Without a failure record the failure headers are omitted.
+	m = f.dlqMessage("dlq.ha_events.engine_rules", orig, maxDeliverAdvisory{StreamSeq: 409, Deliveries: 5}, deliveryFailure{})
+	if m.Header.Get(HeaderDLQError) != "" || m.Header.Get(HeaderDLQFirstFailure) != "" {
+		t.Errorf("failure headers set without a failure record: %v", m.Header)
+	}
+}
+
+// mockDLQJetStream implements dlqJetStream over a single stored message.
+type mockDLQJetStream struct {
+	orig      *nats.RawStreamMsg
+	published []*nats.Msg
+}
+
+func (m *mockDLQJetStream) GetMsg(_ string, seq uint64, _ ...nats.JSOpt) (*nats.RawStreamMsg, error) {
+	if m.orig == nil || m.orig.Sequence != seq {
+		return nil, nats.ErrMsgNotFound
+	}
+	return m.orig, nil
+}
+
+func (m *mockDLQJetStream) PublishMsg(msg *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	m.published = append(m.published, msg)
+	return &nats.PubAck{}, nil
+}
+
+func TestDLQForwarder_HandleAdvisoryTakesConsumerFailure(t *testing.T) {
+	js := &mockDLQJetStream{orig: &nats.RawStreamMsg{
+		Subject:  "ruby_engine.commands.notify.phone",
+		Sequence: 12,
+		Header:   nats.Header{},
+		Data:     []byte(`{"id":"cmd-1"}`),
+	}}
+	failures := NewFailureLog()
+	failures.Record(12, errors.New("ha: 502 bad gateway"))
+	f := &DLQForwarder{js: js, stream: "COMMANDS", consumer: "notifier_processor", failures: failures}
+
+	f.handleAdvisory(&nats.Msg{Data: []byte(`{"stream":"COMMANDS","consumer":"notifier_processor","stream_seq":12,"deliveries":5}`)})
+
+	if len(js.published) != 1 {
+		t.Fatalf("published %d messages, want 1", len(js.published))
+	}
+	m := js.published[0]
+	if m.Subject != "dlq.commands.notifier_processor" || m.Header.Get(HeaderDLQError) != "ha: 502 bad gateway" ||
+		m.Header.Get(HeaderDLQConsumer) != "notifier_processor" {
+		t.Errorf("DLQ message = %s %v", m.Subject, m.Header)
+	}
+	if _, ok := failures.take(12); ok {
+		t.Error("forwarded message's failure was left in the log")
+	}
+
+	// An advisory for a message no longer in the stream publishes nothing.
+	f.handleAdvisory(&nats.Msg{Data: []byte(`{"stream_seq":99,"deliveries":5}`)})
+	if len(js.published) != 1 {
+		t.Errorf("published %d messages after a missing original, want 1", len(js.published))
+	}
+}
@@ -0,0 +1,442 @@
+package natsx
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DriftAction is what reconciling a Drift does about it.
+type DriftAction string
+
+const (
+	// DriftCreate: the object is missing on the server and is created.
+	DriftCreate DriftAction = "create"
+	// DriftUpdate: a field differs and is updated in place.
+	DriftUpdate DriftAction = "update"
+	// DriftImmutable: a field differs but the server cannot change it in place. It is
+	// reported and never applied; fixing it means recreating the object.
+	DriftImmutable DriftAction = "immutable"
+	// DriftUnmanaged: the object exists on the server but not in the manifest. It is
+	// reported and left alone.
+	DriftUnmanaged DriftAction = "unmanaged"
+)
+
+// Drift kinds.
+const (
+	KindStream   = "stream"
+	KindBucket   = "bucket"
+	KindConsumer = "consumer"
+)
+
+// Drift is one difference between the topology manifest and a live server.
+type Drift struct {
+	Action DriftAction
+	Kind   string
+	// Name is the stream or bucket name, or "{stream}/{durable}" for a consumer.
+	Name string
+	// Field, Live and Want describe a differing field; they are empty for
+	// DriftCreate and DriftUnmanaged.
+	Field string
+	Live  string
+	Want  string
+}
+
+// Fixable reports whether reconciling applies d.
+func (d Drift) Fixable() bool {
+	return d.Action == DriftCreate || d.Action == DriftUpdate
+}
+
+// topologyAdmin is the subset of nats.JetStreamContext used to plan and apply the
+// topology.
+type topologyAdmin interface {
+	StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	StreamNames(opts ...nats.JSOpt) <-chan string
+	AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	UpdateStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	CreateKeyValue(cfg *nats.KeyValueConfig) (nats.KeyValue, error)
+	ConsumerInfo(stream, name string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+	ConsumersInfo(stream string, opts ...nats.JSOpt) <-chan *nats.ConsumerInfo
+	AddConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+	UpdateConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+}
+
+// Plan compares every stream, bucket and consumer in t with the live server and
+// returns the differences without changing anything. Consumer family instances are
+// found by listing the consumers of each stream t declares consumers on; streams,
+// buckets and consumers absent from t are reported as DriftUnmanaged.
+func Plan(js topologyAdmin, t Topology) ([]Drift, error) {
+	return reconcileTopology(js, t, false)
+}
+
+// Apply reconciles the live server with t: it creates what is missing and updates
+// drifted fields in place. It returns the same drift Plan would have, including the
+// immutable and unmanaged entries it left alone.
+func Apply(js topologyAdmin, t Topology) ([]Drift, error) {
+	return reconcileTopology(js, t, true)
+}
+
+func reconcileTopology(js topologyAdmin, t Topology, apply bool) ([]Drift, error) {
+	var drifts []Drift
+	managed := make(map[string]bool)
+	for _, s := range t.Streams {
+		managed[s.Name] = true
+		d, err := reconcileStream(js, s, apply)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+	for _, b := range t.Buckets {
+		managed[kvStreamName(b.Bucket)] = true
+		d, err := reconcileBucket(js, b, apply)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+
+	var streams []string
+	for _, c := range t.Consumers {
+		if !slices.Contains(streams, c.Stream) {
+			streams = append(streams, c.Stream)
+		}
+	}
+	for _, stream := range streams {
+		d, err := reconcileConsumers(js, t, stream, apply)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+
+	var unmanaged []Drift
+	for name := range js.StreamNames() {
+		if managed[name] {
+			continue
+		}
+		if bucket, ok := strings.CutPrefix(name, "KV_"); ok {
+			unmanaged = append(unmanaged, Drift{Action: DriftUnmanaged, Kind: KindBucket, Name: bucket})
+		} else {
+			unmanaged = append(unmanaged, Drift{Action: DriftUnmanaged, Kind: KindStream, Name: name})
+		}
+	}
+	slices.SortFunc(unmanaged, func(a, b Drift) int { return cmp.Compare(a.Name, b.Name) })
+	return append(drifts, unmanaged...), nil
+}
+
+// reconcileConsumers compares the consumers on stream with t: each live consumer
+// against the entry or family it belongs to, and each exact entry that has no live
+// consumer as missing. Family instances are never created here — the service that
+// names them does that.
+func reconcileConsumers(js topologyAdmin, t Topology, stream string, apply bool) ([]Drift, error) {
+	var live []*nats.ConsumerInfo
+	for info := range js.ConsumersInfo(stream) {
+		live = append(live, info)
+	}
+	slices.SortFunc(live, func(a, b *nats.ConsumerInfo) int { return cmp.Compare(a.Name, b.Name) })
+
+	var drifts []Drift
+	seen := make(map[string]bool)
+	for _, info := range live {
+		seen[info.Name] = true
+		want, err := t.Consumer(stream, info.Name)
+		if err != nil {
+			drifts = append(drifts, Drift{Action: DriftUnmanaged, Kind: KindConsumer, Name: consumerDriftName(stream, info.Name)})
+			continue
+		}
+		d, err := reconcileConsumer(js, want, apply)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+	for _, c := range t.Consumers {
+		if c.Stream != stream || isFamily(c.Durable) || seen[c.Durable] {
+			continue
+		}
+		d, err := reconcileConsumer(js, c, apply)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+	return drifts, nil
+}
+
+// reconcileStream compares the live stream with want and, when apply is set, creates
+// it or updates its drifted fields.
+func reconcileStream(js topologyAdmin, want nats.StreamConfig, apply bool) ([]Drift, error) {
+	info, err := js.StreamInfo(want.Name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if apply {
+			if _, err = js.AddStream(&want); err != nil {
+				return nil, fmt.Errorf("add stream %q: %w", want.Name, err)
+			}
+		}
+		return diffStream(nil, want), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stream info %q: %w", want.Name, err)
+	}
+	drifts := diffStream(&info.Config, want)
+	if apply && hasUpdate(drifts) {
+		cfg := patchStream(info.Config, want)
+		if _, err = js.UpdateStream(&cfg); err != nil {
+			return drifts, fmt.Errorf("update stream %q: %w", want.Name, err)
+		}
+	}
+	return drifts, nil
+}
+
+// reconcileBucket compares the live bucket's backing stream with want and, when apply
+// is set, creates the bucket or updates the stream's drifted fields.
+func reconcileBucket(js topologyAdmin, want nats.KeyValueConfig, apply bool) ([]Drift, error) {
+	info, err := js.StreamInfo(kvStreamName(want.Bucket))
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if apply {
+			if _, err = js.CreateKeyValue(&want); err != nil {
+				return nil, fmt.Errorf("kv: create %q: %w", want.Bucket, err)
+			}
+		}
+		return diffBucket(nil, want), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kv: stream info %q: %w", want.Bucket, err)
+	}
+	drifts := diffBucket(&info.Config, want)
+	if apply && hasUpdate(drifts) {
+		cfg := patchBucket(info.Config, want)
+		if _, err = js.UpdateStream(&cfg); err != nil {
+			return drifts, fmt.Errorf("kv: update %q: %w", want.Bucket, err)
+		}
+	}
+	return drifts, nil
+}
+
+// reconcileConsumer compares the live consumer with want and, when apply is set,
+// creates it or updates its drifted fields.
+func reconcileConsumer(js topologyAdmin, want PullConsumerConfig, apply bool) ([]Drift, error) {
+	info, err := js.ConsumerInfo(want.Stream, want.Durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) || errors.Is(err, nats.ErrStreamNotFound) {
+		if apply {
+			if _, err = js.AddConsumer(want.Stream, consumerConfig(want)); err != nil {
+				return nil, fmt.Errorf("natsx: add consumer %q: %w", want.Durable, err)
+			}
+		}
+		return diffConsumer(nil, want), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("natsx: consumer info %q: %w", want.Durable, err)
+	}
+	drifts := diffConsumer(&info.Config, want)
+	if apply && hasUpdate(drifts) {
+		cfg := patchConsumer(info.Config, want)
+		if _, err = js.UpdateConsumer(want.Stream, &cfg); err != nil {
+			return drifts, fmt.Errorf("natsx: update consumer %q: %w", want.Durable, err)
+		}
+	}
+	return drifts, nil
+}
+
+// warnImmutable logs the drift a boot-time reconcile had to leave in place.
+func warnImmutable(drifts []Drift) {
+	for _, d := range drifts {
+		if d.Action != DriftImmutable {
+			continue
+		}
+		slog.Warn("natsx: topology drift cannot be reconciled in place — recreate to apply (see cmd/topology)",
+			slog.String("kind", d.Kind),
+			slog.String("name", d.Name),
+			slog.String("field", d.Field),
+			slog.String("live", d.Live),
+			slog.String("want", d.Want),
+		)
+	}
+}
+
+// diffStream returns how live differs from want; a nil live is a missing stream.
+// Subjects and retention limits update in place; storage and retention policy are
+// fixed at creation.
+func diffStream(live *nats.StreamConfig, want nats.StreamConfig) []Drift {
+	if live == nil {
+		return []Drift{{Action: DriftCreate, Kind: KindStream, Name: want.Name}}
+	}
+	f := fieldDiff{kind: KindStream, name: want.Name}
+	f.check("subjects", fmtSubjects(live.Subjects), fmtSubjects(want.Subjects), DriftUpdate)
+	f.check("max_age", fmtAge(live.MaxAge), fmtAge(want.MaxAge), DriftUpdate)
+	f.check("max_bytes", fmtLimit(live.MaxBytes), fmtLimit(want.MaxBytes), DriftUpdate)
+	f.check("max_msgs", fmtLimit(live.MaxMsgs), fmtLimit(want.MaxMsgs), DriftUpdate)
+	f.check("discard", live.Discard.String(), want.Discard.String(), DriftUpdate)
+	if want.Duplicates != 0 {
+		f.check("duplicates", live.Duplicates.String(), want.Duplicates.String(), DriftUpdate)
+	}
+	f.check("storage", live.Storage.String(), want.Storage.String(), DriftImmutable)
+	f.check("retention", live.Retention.String(), want.Retention.String(), DriftImmutable)
+	return f.drifts
+}
+
+// diffBucket returns how the backing stream live of a KV bucket differs from want; a
+// nil live is a missing bucket. The TTL is the stream's max age and the history its
+// per-subject message limit, so both update in place; storage is fixed at creation.
+func diffBucket(live *nats.StreamConfig, want nats.KeyValueConfig) []Drift {
+	if live == nil {
+		return []Drift{{Action: DriftCreate, Kind: KindBucket, Name: want.Bucket}}
+	}
+	f := fieldDiff{kind: KindBucket, name: want.Bucket}
+	f.check("ttl", fmtAge(live.MaxAge), fmtAge(want.TTL), DriftUpdate)
+	f.check("max_bytes", fmtLimit(live.MaxBytes), fmtLimit(want.MaxBytes), DriftUpdate)
+	f.check("history", fmtLimit(live.MaxMsgsPerSubject), fmtLimit(kvHistory(want)), DriftUpdate)
+	f.check("storage", live.Storage.String(), want.Storage.String(), DriftImmutable)
+	return f.drifts
+}
+
+// diffConsumer returns how live differs from want; a nil live is a missing consumer.
+// Delivery settings update in place; the ack policy is fixed at creation. An empty
+// want.FilterSubject (a family whose filter the service chooses) is not compared.
+func diffConsumer(live *nats.ConsumerConfig, want PullConsumerConfig) []Drift {
+	name := consumerDriftName(want.Stream, want.Durable)
+	if live == nil {
+		return []Drift{{Action: DriftCreate, Kind: KindConsumer, Name: name}}
+	}
+	f := fieldDiff{kind: KindConsumer, name: name}
+	switch {
+	case len(want.FilterSubjects) > 0:
+		f.check("filter_subjects", fmtSubjects(liveFilters(live)), fmtSubjects(want.FilterSubjects), DriftUpdate)
+	case want.FilterSubject != "":
+		f.check("filter_subject", live.FilterSubject, want.FilterSubject, DriftUpdate)
+	}
+	f.check("ack_wait", live.AckWait.String(), effectiveAckWait(want).String(), DriftUpdate)
+	f.check("max_deliver", strconv.Itoa(live.MaxDeliver), strconv.Itoa(want.MaxDeliver), DriftUpdate)
+	f.check("max_ack_pending", strconv.Itoa(live.MaxAckPending), strconv.Itoa(want.MaxAckPending), DriftUpdate)
+	f.check("backoff", fmtDurations(live.BackOff), fmtDurations(want.BackOff), DriftUpdate)
+	f.check("ack_policy", live.AckPolicy.String(), nats.AckExplicitPolicy.String(), DriftImmutable)
+	return f.drifts
+}
+
+// patchStream returns live with want's updatable fields, keeping everything the
+// manifest does not declare (e.g. a stream's duplicate window, unless set) as the
+// server has it.
+func patchStream(live, want nats.StreamConfig) nats.StreamConfig {
+	live.Subjects = want.Subjects
+	live.MaxAge = want.MaxAge
+	live.MaxBytes = want.MaxBytes
+	live.MaxMsgs = want.MaxMsgs
+	live.Discard = want.Discard
+	if want.Duplicates != 0 {
+		live.Duplicates = want.Duplicates
+	}
+	return live
+}
+
+// patchBucket returns the backing stream live with want's updatable bucket fields.
+func patchBucket(live nats.StreamConfig, want nats.KeyValueConfig) nats.StreamConfig {
+	live.MaxAge = want.TTL
+	live.MaxBytes = want.MaxBytes
+	live.MaxMsgsPerSubject = kvHistory(want)
+	return live
+}
+
+// patchConsumer returns live with want's updatable fields.
+func patchConsumer(live nats.ConsumerConfig, want PullConsumerConfig) nats.ConsumerConfig {
+	switch {
+	case len(want.FilterSubjects) > 0:
+		live.FilterSubject, live.FilterSubjects = "", want.FilterSubjects
+	case want.FilterSubject != "":
+		live.FilterSubject = want.FilterSubject
+	}
+	live.AckWait = want.AckWait
+	live.MaxDeliver = want.MaxDeliver
+	live.MaxAckPending = want.MaxAckPending
+	live.BackOff = want.BackOff
+	return live
+}
+
+// liveFilters is the filter of a live consumer as a list: its FilterSubjects, or
+// its FilterSubject alone.
+func liveFilters(live *nats.ConsumerConfig) []string {
+	if len(live.FilterSubjects) > 0 || live.FilterSubject == "" {
+		return live.FilterSubjects
+	}
+	return []string{live.FilterSubject}
+}
+
+// effectiveAckWait is the AckWait the server reports for a consumer created from cfg:
+// when a BackOff schedule is set, the server replaces AckWait with its first step.
+func effectiveAckWait(cfg PullConsumerConfig) time.Duration {
+	if len(cfg.BackOff) > 0 {
+		return cfg.BackOff[0]
+	}
+	return cfg.AckWait
+}
+
+// kvHistory is the per-key history CreateKeyValue gives a bucket created from cfg.
+func kvHistory(cfg nats.KeyValueConfig) int64 {
+	if cfg.History > 0 {
+		return int64(cfg.History)
+	}
+	return 1
+}
+
+// kvStreamName is the name of the stream backing a KV bucket.
+func kvStreamName(bucket string) string {
+	return "KV_" + bucket
+}
+
+func consumerDriftName(stream, durable string) string {
+	return stream + "/" + durable
+}
+
+func hasUpdate(drifts []Drift) bool {
+	return slices.ContainsFunc(drifts, func(d Drift) bool { return d.Action == DriftUpdate })
+}
+
+// fieldDiff collects the fields of one object that differ.
+type fieldDiff struct {
+	kind, name string
+	drifts     []Drift
+}
+
+func (f *fieldDiff) check(field, live, want string, action DriftAction) {
+	if live != want {
+		f.drifts = append(f.drifts, Drift{Action: action, Kind: f.kind, Name: f.name, Field: field, Live: live, Want: want})
+	}
+}
+
+// fmtLimit formats a count or size limit; the server stores "no limit" as -1 and
+// accepts 0 for it.
+func fmtLimit(v int64) string {
+	if v <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func fmtAge(d time.Duration) string {
+	if d <= 0 {
+		return "unlimited"
+	}
+	return d.String()
+}
+
+func fmtDurations(ds []time.Duration) string {
+	if len(ds) == 0 {
+		return "none"
+	}
+	s := make([]string, len(ds))
+	for i, d := range ds {
+		s[i] = d.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func fmtSubjects(subjects []string) string {
+	return strings.Join(slices.Sorted(slices.Values(subjects)), ",")
+}
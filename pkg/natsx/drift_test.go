@@ -0,0 +1,345 @@
+//go:build fast
+
+package natsx
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDiffStream(t *testing.T) {
+	want := nats.StreamConfig{
+		Name:      "HA_EVENTS",
+		Subjects:  []string{"ha.events.>"},
+		Storage:   nats.FileStorage,
+		Retention: nats.LimitsPolicy,
+		MaxAge:    48 * time.Hour,
+		MaxBytes:  512 << 20,
+	}
+	// What the server reports for want: unset limits come back as -1.
+	live := func(edit func(*nats.StreamConfig)) *nats.StreamConfig {
+		cfg := want
+		cfg.MaxMsgs = -1
+		cfg.Duplicates = 2 * time.Minute
+		if edit != nil {
+			edit(&cfg)
+		}
+		return &cfg
+	}
+
+	cases := []struct {
+		name  string
+		live  *nats.StreamConfig
+		field string
+		want  DriftAction
+	}{
+		{"identical", live(nil), "", ""},
+		{"missing", nil, "", DriftCreate},
+		{"max_age differs (was unbounded)", live(func(c *nats.StreamConfig) { c.MaxAge = 0 }), "max_age", DriftUpdate},
+		{"max_bytes differs (was unbounded)", live(func(c *nats.StreamConfig) { c.MaxBytes = -1 }), "max_bytes", DriftUpdate},
+		{"max_msgs differs", live(func(c *nats.StreamConfig) { c.MaxMsgs = 1000 }), "max_msgs", DriftUpdate},
+		{"subjects differ", live(func(c *nats.StreamConfig) { c.Subjects = []string{"ha.>"} }), "subjects", DriftUpdate},
+		{"storage differs", live(func(c *nats.StreamConfig) { c.Storage = nats.MemoryStorage }), "storage", DriftImmutable},
+		{"retention differs", live(func(c *nats.StreamConfig) { c.Retention = nats.WorkQueuePolicy }), "retention", DriftImmutable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			drifts := diffStream(c.live, want)
+			if c.want == "" {
+				if len(drifts) != 0 {
+					t.Fatalf("drift = %+v, want none", drifts)
+				}
+				return
+			}
+			if len(drifts) != 1 || drifts[0].Action != c.want || drifts[0].Field != c.field {
+				t.Fatalf("drift = %+v, want one %s of %q", drifts, c.want, c.field)
+			}
+		})
+	}
+}
+
+func TestDiffStream_SubjectOrderIgnored(t *testing.T) {
+	want := nats.StreamConfig{Name: "S", Subjects: []string{"a.>", "b.>"}}
+	live := nats.StreamConfig{Name: "S", Subjects: []string{"b.>", "a.>"}}
+	if drifts := diffStream(&live, want); len(drifts) != 0 {
+		t.Errorf("drift = %+v, want none", drifts)
+	}
+}
+
+func TestDiffBucket(t *testing.T) {
+	want := nats.KeyValueConfig{Bucket: "idempotency", TTL: 30 * time.Minute}
+	// The backing stream CreateKeyValue makes for want.
+	live := nats.StreamConfig{Name: "KV_idempotency", MaxAge: 30 * time.Minute, MaxBytes: -1, MaxMsgsPerSubject: 1}
+
+	if drifts := diffBucket(&live, want); len(drifts) != 0 {
+		t.Errorf("identical bucket: drift = %+v, want none", drifts)
+	}
+	if drifts := diffBucket(nil, want); len(drifts) != 1 || drifts[0].Action != DriftCreate {
+		t.Errorf("missing bucket: drift = %+v, want create", drifts)
+	}
+
+	old := live
+	old.MaxAge = 24 * time.Hour
+	drifts := diffBucket(&old, want)
+	if len(drifts) != 1 || drifts[0].Field != "ttl" || drifts[0].Action != DriftUpdate ||
+		drifts[0].Live != "24h0m0s" || drifts[0].Want != "30m0s" {
+		t.Errorf("ttl drift = %+v, want an update from 24h to 30m", drifts)
+	}
+
+	mem := live
+	mem.Storage = nats.MemoryStorage
+	if drifts := diffBucket(&mem, want); len(drifts) != 1 || drifts[0].Action != DriftImmutable {
+		t.Errorf("storage drift = %+v, want immutable", drifts)
+	}
+}
+
+func TestDiffConsumer(t *testing.T) {
+	want := DefaultPullConsumerConfig("HA_EVENTS", "engine_rules", "ha.events.>")
+	// The server replaces AckWait with the first BackOff step.
+	live := nats.ConsumerConfig{
+		Durable:       "engine_rules",
+		FilterSubject: "ha.events.>",
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       want.BackOff[0],
+		MaxDeliver:    want.MaxDeliver,
+		MaxAckPending: want.MaxAckPending,
+		BackOff:       want.BackOff,
+	}
+	if drifts := diffConsumer(&live, want); len(drifts) != 0 {
+		t.Errorf("identical consumer: drift = %+v, want none", drifts)
+	}
+
+	edited := live
+	edited.MaxAckPending = 1000
+	edited.AckPolicy = nats.AckAllPolicy
+	drifts := diffConsumer(&edited, want)
+	if len(drifts) != 2 ||
+		drifts[0].Field != "max_ack_pending" || drifts[0].Action != DriftUpdate ||
+		drifts[1].Field != "ack_policy" || drifts[1].Action != DriftImmutable {
+		t.Errorf("drift = %+v, want a max_ack_pending update and an immutable ack_policy", drifts)
+	}
+
+	// A family entry without a filter leaves each instance's filter alone.
+	want.FilterSubject = ""
+	edited = live
+	edited.FilterSubject = "ha.events.person.alice"
+	if drifts := diffConsumer(&edited, want); len(drifts) != 0 {
+		t.Errorf("family filter: drift = %+v, want none", drifts)
+	}
+	if got := patchConsumer(edited, want).FilterSubject; got != "ha.events.person.alice" {
+		t.Errorf("patchConsumer filter = %q, want the live filter kept", got)
+	}
+
+	// A list of filters replaces a single one, in any order.
+	want.FilterSubjects = []string{"ha.events.person.>", "ha.events.light.>"}
+	drifts = diffConsumer(&live, want)
+	if len(drifts) != 1 || drifts[0].Field != "filter_subjects" || drifts[0].Action != DriftUpdate {
+		t.Errorf("drift = %+v, want a filter_subjects update", drifts)
+	}
+	patched := patchConsumer(live, want)
+	if patched.FilterSubject != "" || len(patched.FilterSubjects) != 2 {
+		t.Errorf("patchConsumer filters = %q %q, want only the list", patched.FilterSubject, patched.FilterSubjects)
+	}
+	reordered := live
+	reordered.FilterSubject, reordered.FilterSubjects = "", []string{"ha.events.light.>", "ha.events.person.>"}
+	if drifts := diffConsumer(&reordered, want); len(drifts) != 0 {
+		t.Errorf("reordered filters: drift = %+v, want none", drifts)
+	}
+}
+
+func TestPatchStream_KeepsUndeclaredFields(t *testing.T) {
+	live := nats.StreamConfig{Name: "S", Subjects: []string{"s.>"}, Duplicates: 2 * time.Minute, Description: "d", MaxAge: time.Hour}
+	want := nats.StreamConfig{Name: "S", Subjects: []string{"s.>"}, MaxAge: 2 * time.Hour}
+	got := patchStream(live, want)
+	if got.MaxAge != 2*time.Hour || got.Duplicates != 2*time.Minute || got.Description != "d" {
+		t.Errorf("patchStream = %+v, want the new max age and the live duplicates/description", got)
+	}
+
+	// A declared duplicate window is reconciled like the other limits.
+	want.Duplicates = time.Hour
+	if drifts := diffStream(&live, want); len(drifts) != 2 || drifts[1].Field != "duplicates" {
+		t.Errorf("drift = %+v, want max_age and duplicates updates", drifts)
+	}
+	if got := patchStream(live, want); got.Duplicates != time.Hour {
+		t.Errorf("patchStream duplicates = %v, want 1h", got.Duplicates)
+	}
+}
+
+// fakeJS is an in-memory topologyAdmin.
+type fakeJS struct {
+	streams   map[string]nats.StreamConfig
+	consumers map[string]map[string]nats.ConsumerConfig // stream → durable → config
+	calls     []string
+}
+
+func newFakeJS() *fakeJS {
+	return &fakeJS{streams: map[string]nats.StreamConfig{}, consumers: map[string]map[string]nats.ConsumerConfig{}}
+}
+
+func (f *fakeJS) StreamInfo(stream string, _ ...nats.JSOpt) (*nats.StreamInfo, error) {
+	cfg, ok := f.streams[stream]
+	if !ok {
+		return nil, nats.ErrStreamNotFound
+	}
+	return &nats.StreamInfo{Config: cfg}, nil
+}
+
+func (f *fakeJS) StreamNames(_ ...nats.JSOpt) <-chan string {
+	ch := make(chan string, len(f.streams))
+	for name := range f.streams {
+		ch <- name
+	}
+	close(ch)
+	return ch
+}
+
+func (f *fakeJS) AddStream(cfg *nats.StreamConfig, _ ...nats.JSOpt) (*nats.StreamInfo, error) {
+	f.calls = append(f.calls, "add stream "+cfg.Name)
+	f.streams[cfg.Name] = *cfg
+	return &nats.StreamInfo{Config: *cfg}, nil
+}
+
+func (f *fakeJS) UpdateStream(cfg *nats.StreamConfig, _ ...nats.JSOpt) (*nats.StreamInfo, error) {
+	f.calls = append(f.calls, "update stream "+cfg.Name)
+	f.streams[cfg.Name] = *cfg
+	return &nats.StreamInfo{Config: *cfg}, nil
+}
+
+func (f *fakeJS) CreateKeyValue(cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	f.calls = append(f.calls, "create bucket "+cfg.Bucket)
+	f.streams[kvStreamName(cfg.Bucket)] = nats.StreamConfig{Name: kvStreamName(cfg.Bucket), MaxAge: cfg.TTL, MaxBytes: -1, MaxMsgsPerSubject: kvHistory(*cfg)}
+	return nil, nil
+}
+
+func (f *fakeJS) ConsumerInfo(stream, name string, _ ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	if _, ok := f.streams[stream]; !ok {
+		return nil, nats.ErrStreamNotFound
+	}
+	cfg, ok := f.consumers[stream][name]
+	if !ok {
+		return nil, nats.ErrConsumerNotFound
+	}
+	return &nats.ConsumerInfo{Stream: stream, Name: name, Config: cfg}, nil
+}
+
+func (f *fakeJS) ConsumersInfo(stream string, _ ...nats.JSOpt) <-chan *nats.ConsumerInfo {
+	ch := make(chan *nats.ConsumerInfo, len(f.consumers[stream]))
+	for name, cfg := range f.consumers[stream] {
+		ch <- &nats.ConsumerInfo{Stream: stream, Name: name, Config: cfg}
+	}
+	close(ch)
+	return ch
+}
+
+func (f *fakeJS) AddConsumer(stream string, cfg *nats.ConsumerConfig, _ ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	f.calls = append(f.calls, "add consumer "+stream+"/"+cfg.Durable)
+	f.putConsumer(stream, *cfg)
+	return &nats.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: *cfg}, nil
+}
+
+func (f *fakeJS) UpdateConsumer(stream string, cfg *nats.ConsumerConfig, _ ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	f.calls = append(f.calls, "update consumer "+stream+"/"+cfg.Durable)
+	f.putConsumer(stream, *cfg)
+	return &nats.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: *cfg}, nil
+}
+
+// putConsumer stores cfg the way the server would report it.
+func (f *fakeJS) putConsumer(stream string, cfg nats.ConsumerConfig) {
+	if len(cfg.BackOff) > 0 {
+		cfg.AckWait = cfg.BackOff[0]
+	}
+	if f.consumers[stream] == nil {
+		f.consumers[stream] = map[string]nats.ConsumerConfig{}
+	}
+	f.consumers[stream][cfg.Durable] = cfg
+}
+
+func testTopology() Topology {
+	return Topology{
+		Streams: []nats.StreamConfig{
+			{Name: "EVENTS", Subjects: []string{"events.>"}, MaxAge: time.Hour, MaxBytes: 1 << 20},
+		},
+		Buckets: []nats.KeyValueConfig{{Bucket: "state", TTL: time.Hour}},
+		Consumers: []PullConsumerConfig{
+			DefaultPullConsumerConfig("EVENTS", "app_*", ""),
+			DefaultPullConsumerConfig("EVENTS", "sink", "events.>"),
+		},
+	}
+}
+
+func TestPlan_EmptyServer(t *testing.T) {
+	js := newFakeJS()
+	drifts, err := Plan(js, testTopology())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Drift{
+		{Action: DriftCreate, Kind: KindStream, Name: "EVENTS"},
+		{Action: DriftCreate, Kind: KindBucket, Name: "state"},
+		{Action: DriftCreate, Kind: KindConsumer, Name: "EVENTS/sink"},
+	}
+	if !slices.Equal(drifts, want) {
+		t.Errorf("Plan = %+v, want %+v", drifts, want)
+	}
+	if len(js.calls) != 0 {
+		t.Errorf("Plan changed the server: %v", js.calls)
+	}
+}
+
+func TestApply_ConvergesAndReportsWhatItCannotFix(t *testing.T) {
+	js := newFakeJS()
+	top := testTopology()
+	if _, err := Apply(js, top); err != nil {
+		t.Fatal(err)
+	}
+	// A family instance created by its service, an operator's consumer, a stray
+	// stream and bucket, and drift: a shorter bucket TTL and a memory-backed stream.
+	app := DefaultPullConsumerConfig("EVENTS", "app_one", "events.one")
+	js.putConsumer("EVENTS", *consumerConfig(app))
+	js.putConsumer("EVENTS", nats.ConsumerConfig{Durable: "debug", AckPolicy: nats.AckExplicitPolicy})
+	js.streams["OTHER"] = nats.StreamConfig{Name: "OTHER"}
+	js.streams["KV_other"] = nats.StreamConfig{Name: "KV_other"}
+	kv := js.streams["KV_state"]
+	kv.MaxAge = time.Minute
+	js.streams["KV_state"] = kv
+	s := js.streams["EVENTS"]
+	s.Storage = nats.MemoryStorage
+	js.streams["EVENTS"] = s
+	app.MaxAckPending = 1
+	js.putConsumer("EVENTS", *consumerConfig(app))
+	js.calls = nil
+
+	drifts, err := Apply(js, top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Drift{
+		{Action: DriftImmutable, Kind: KindStream, Name: "EVENTS", Field: "storage", Live: "Memory", Want: "File"},
+		{Action: DriftUpdate, Kind: KindBucket, Name: "state", Field: "ttl", Live: "1m0s", Want: "1h0m0s"},
+		{Action: DriftUpdate, Kind: KindConsumer, Name: "EVENTS/app_one", Field: "max_ack_pending", Live: "1", Want: "128"},
+		{Action: DriftUnmanaged, Kind: KindConsumer, Name: "EVENTS/debug"},
+		{Action: DriftUnmanaged, Kind: KindStream, Name: "OTHER"},
+		{Action: DriftUnmanaged, Kind: KindBucket, Name: "other"},
+	}
+	if !slices.Equal(drifts, want) {
+		t.Errorf("Apply drift =\n%+v\nwant\n%+v", drifts, want)
+	}
+	wantCalls := []string{"update stream KV_state", "update consumer EVENTS/app_one"}
+	if !slices.Equal(js.calls, wantCalls) {
+		t.Errorf("calls = %v, want %v", js.calls, wantCalls)
+	}
+	if got := js.consumers["EVENTS"]["app_one"].FilterSubject; got != "events.one" {
+		t.Errorf("app_one filter = %q, want the instance's own filter kept", got)
+	}
+
+	drifts, err = Plan(js, top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(drifts, Drift.Fixable) {
+		t.Errorf("Plan after Apply = %+v, want nothing left to fix", drifts)
+	}
+}
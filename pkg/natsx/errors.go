@@ -3,6 +3,7 @@ package natsx
 import "errors"
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrInvalidClass = errors.New("invalid class")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrInvalidClass   = errors.New("invalid class")
+	ErrInvalidPattern = errors.New("invalid subject pattern")
 )
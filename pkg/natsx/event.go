@@ -0,0 +1,42 @@
+package natsx
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// NewEventMsg returns evt as a binary-mode CloudEvent message on subject: attributes
+// in ce-* headers, data as the body (schemas.EncodeBinary). The active span's trace
+// context is injected as in PublishWithContext.
+func NewEventMsg(ctx context.Context, subject string, evt schemas.CloudEvent) (*nats.Msg, error) {
+	m := nats.NewMsg(subject)
+	body, err := schemas.EncodeBinary(evt, m.Header)
+	if err != nil {
+		return nil, err
+	}
+	m.Data = body
+	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(m.Header))
+	return m, nil
+}
+
+// PublishEvent publishes evt on subject in binary content mode. Every consumer accepts
+// both modes (EventData), so a producer can switch to it without a coordinated deploy
+// once its consumers run a release that reads binary mode.
+func PublishEvent(ctx context.Context, pub MsgPublisher, subject string, evt schemas.CloudEvent) error {
+	m, err := NewEventMsg(ctx, subject, evt)
+	if err != nil {
+		return err
+	}
+	return publishMsg(ctx, pub, m)
+}
+
+// EventData returns msg's CloudEvent as structured-mode JSON whichever content mode it
+// was published in, for handlers that decode the envelope from bytes. Structured
+// messages are returned without copying.
+func EventData(msg *nats.Msg) ([]byte, error) {
+	return schemas.StructuredJSON(msg.Header, msg.Data)
+}
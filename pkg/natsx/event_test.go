@@ -0,0 +1,49 @@
+//go:build fast
+
+package natsx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+func TestNewEventMsg_BinaryMode(t *testing.T) {
+	evt := schemas.CloudEvent{ID: "e1", Type: schemas.CommandTypeNotify, CorrelationID: "corr-1",
+		Data: map[string]any{"device": "phone"}}
+	m, err := NewEventMsg(context.Background(), "ruby_engine.commands.notify.phone", evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get(schemas.HeaderID); got != "e1" {
+		t.Errorf("%s = %q", schemas.HeaderID, got)
+	}
+	if got := m.Header.Get(nats.MsgIdHdr); got != "" {
+		t.Errorf("Nats-Msg-Id = %q, want it left to the publisher", got)
+	}
+	if string(m.Data) != `{"device":"phone"}` {
+		t.Errorf("Data = %s, want the event data alone", m.Data)
+	}
+
+	data, err := EventData(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := schemas.ValidateJSON(data)
+	if err != nil {
+		t.Fatalf("EventData = %s: %v", data, err)
+	}
+	if got.ID != "e1" || got.CorrelationID != "corr-1" || got.Data["device"] != "phone" {
+		t.Errorf("EventData = %s", data)
+	}
+}
+
+func TestEventData_Structured(t *testing.T) {
+	m := &nats.Msg{Subject: "ha.events.x", Data: []byte(`{"id":"e1"}`)}
+	if data, err := EventData(m); err != nil || string(data) != `{"id":"e1"}` {
+		t.Errorf("EventData = %s, %v; want the structured body unchanged", data, err)
+	}
+}
@@ -21,11 +21,18 @@ const meterName = "github.com/primaryrutabaga/ruby-core/pkg/natsx"
 var tracer = otel.Tracer(meterName)
 
 // Outcome labels for ruby_core_messages_processed_total. Each consumer loop maps its
-// terminal branch (ack / nak / dedup) to one of these.
+// terminal branch (ack / nak / dedup) to one of these. OutcomeFiltered marks a message
+// acked unprocessed because the consumer's handler does not want its subject.
+// OutcomeInFlight marks a message deferred because another worker holds its
+// idempotency claim (ADR-0025). OutcomeRejected marks an event acked after failing
+// schema validation and being routed to the REJECTED stream (ADR-0014).
 const (
 	OutcomeSuccess   = "success"
 	OutcomeFailure   = "failure"
 	OutcomeDuplicate = "duplicate"
+	OutcomeFiltered  = "filtered"
+	OutcomeInFlight  = "in_flight"
+	OutcomeRejected  = "rejected"
 )
 
 // natsHeaderCarrier adapts nats.Header to the W3C TextMapCarrier interface so trace
@@ -52,24 +59,30 @@ type MsgPublisher interface {
 // PublishWithContext publishes data to subject, injecting the active span's W3C trace
 // context into the message headers so the consumer can continue the same trace. Use this
 // in place of nc.Publish on any cross-service hop that should appear as one connected
-// trace (PLAN-0009).
+// trace (PLAN-0009). With an AckedPublisher the publish is bounded by ctx.
 func PublishWithContext(ctx context.Context, pub MsgPublisher, subject string, data []byte) error {
 	msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
 	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(msg.Header))
-	return pub.PublishMsg(msg)
+	return publishMsg(ctx, pub, msg)
 }
 
 // MsgInstruments holds the OTel metric instruments shared by every JetStream consumer
 // loop: a processed counter and a processing-duration histogram, both labeled by
-// service / stream / consumer / outcome. Construct one per service (after otel.Init) and
-// pass it into each loop. The instruments degrade to no-op when no OTLP endpoint is
+// service / stream / consumer / outcome, and gauges of each WorkerPool's current worker
+// limit, fetch batch and consumer pending count. Construct one per service (after
+// otel.Init) and pass it into each loop. The instruments degrade to no-op when no OTLP endpoint is
 // configured (the global MeterProvider is then a no-op), so the dev path needs no special
 // handling. A nil *MsgInstruments is also safe — Observe just runs the work without a
 // span or metrics, which keeps direct-constructed consumers in tests instrument-free.
 type MsgInstruments struct {
 	service   string
+	meter     metric.Meter
 	processed metric.Int64Counter
 	duration  metric.Float64Histogram
+
+	workerLimit metric.Int64ObservableGauge
+	fetchBatch  metric.Int64ObservableGauge
+	pending     metric.Int64ObservableGauge
 }
 
 // NewMsgInstruments registers the shared message instruments under the global
@@ -93,7 +106,61 @@ func NewMsgInstruments(service string) (*MsgInstruments, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &MsgInstruments{service: service, processed: processed, duration: duration}, nil
+	workerLimit, err := m.Int64ObservableGauge(
+		"ruby_core_consumer_worker_limit",
+		metric.WithDescription("Current worker limit of a consumer's adaptive worker pool; below its WorkerCount while backpressure is engaged"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	fetchBatch, err := m.Int64ObservableGauge(
+		"ruby_core_consumer_fetch_batch",
+		metric.WithDescription("Current number of messages a consumer requests per fetch"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := m.Int64ObservableGauge(
+		"ruby_core_consumer_pending",
+		metric.WithDescription("Undelivered messages of a consumer, as last read by its adaptive worker pool"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &MsgInstruments{
+		service:     service,
+		meter:       m,
+		processed:   processed,
+		duration:    duration,
+		workerLimit: workerLimit,
+		fetchBatch:  fetchBatch,
+		pending:     pending,
+	}, nil
+}
+
+// observeLimits reports conc's worker limit, fetch batch and pending count on the
+// consumer gauges until the returned func is called. A nil receiver reports nothing.
+func (mi *MsgInstruments) observeLimits(stream, consumer string, conc *concurrency) (unregister func()) {
+	if mi == nil || mi.meter == nil {
+		return func() {}
+	}
+	attrs := metric.WithAttributes(
+		attribute.String("service", mi.service),
+		attribute.String("stream", stream),
+		attribute.String("consumer", consumer),
+	)
+	reg, err := mi.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(mi.workerLimit, int64(conc.lim.size()), attrs)
+		o.ObserveInt64(mi.fetchBatch, int64(conc.batch()), attrs)
+		if p := conc.lastPending(); p >= 0 {
+			o.ObserveInt64(mi.pending, p, attrs)
+		}
+		return nil
+	}, mi.workerLimit, mi.fetchBatch, mi.pending)
+	if err != nil {
+		return func() {}
+	}
+	return func() { _ = reg.Unregister() }
 }
 
 // Observe extracts any W3C trace context from msg's headers, opens a child nats.consume
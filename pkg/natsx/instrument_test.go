@@ -168,3 +168,63 @@ func TestNewDedupCounter_Increments(t *testing.T) {
 		t.Errorf("ruby_core_idempotency_dedup_total = %d (found=%v), want 2", got, ok)
 	}
 }
+
+// gaugeValue returns the int64 gauge value of the named metric in rm, and whether it
+// was found.
+func gaugeValue(rm metricdata.ResourceMetrics, name string) (int64, bool) {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			g, ok := m.Data.(metricdata.Gauge[int64])
+			if !ok || len(g.DataPoints) == 0 {
+				return 0, false
+			}
+			return g.DataPoints[0].Value, true
+		}
+	}
+	return 0, false
+}
+
+// observeLimits reports a pool's current limits on the consumer gauges until
+// unregistered.
+func TestObserveLimits_ReportsGauges(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	otel.SetMeterProvider(mp)
+	t.Cleanup(func() { otel.SetMeterProvider(noopmetric.NewMeterProvider()) })
+
+	mi, err := NewMsgInstruments("engine")
+	if err != nil {
+		t.Fatalf("NewMsgInstruments: %v", err)
+	}
+	conc := newConcurrency(2, 10, 5)
+	conc.lim.setLimit(3)
+	conc.adjust(42)
+	unregister := mi.observeLimits("HA_EVENTS", "engine_processor", conc)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	limit := int64(conc.lim.size())
+	if got, ok := gaugeValue(rm, "ruby_core_consumer_worker_limit"); !ok || got != limit {
+		t.Errorf("ruby_core_consumer_worker_limit = %d (found=%v), want %d", got, ok, limit)
+	}
+	if got, ok := gaugeValue(rm, "ruby_core_consumer_fetch_batch"); !ok || got != min(limit, 5) {
+		t.Errorf("ruby_core_consumer_fetch_batch = %d (found=%v), want %d", got, ok, min(limit, 5))
+	}
+	if got, ok := gaugeValue(rm, "ruby_core_consumer_pending"); !ok || got != 42 {
+		t.Errorf("ruby_core_consumer_pending = %d (found=%v), want 42", got, ok)
+	}
+
+	unregister()
+	rm = metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if _, ok := gaugeValue(rm, "ruby_core_consumer_worker_limit"); ok {
+		t.Error("ruby_core_consumer_worker_limit still reported after unregister")
+	}
+}
@@ -1,9 +1,7 @@
 package natsx
 
 import (
-	"errors"
 	"fmt"
-	"time"
 
 	"github.com/nats-io/nats.go"
 )
@@ -18,14 +16,26 @@ import (
 //	──────────────────  ──────────  ──────────  ───────────────────────────────────────────
 //	KVBucketIdempotency engine      —           Processed event IDs; dedup across restarts
 //	KVBucketConfig      engine      gateway     Compiled rule config (passlist + critical entities)
-//	KVBucketPresence    presence+engine  —       Presence state: presence svc writes key "{personID}" (raw string);
-//	                                            engine presence_notify writes key "{type}.{id}" (JSON {state,updated_at})
+//	KVBucketPresence    presence    engine      Presence state: presence svc writes key "{personID}" (raw string);
+//	                                            legacy "{type}.{id}" keys from presence_notify are read, not written
 //	KVBucketGatewayState gateway    —           Last-seen CloudEvent timestamp per HA entity (reconciler)
+//	KVBucketGatewayCommands gateway —           Claimed and run ha_service command IDs; a redelivered
+//	                                            command is not written to HA twice
+//	KVBucketRules       engine      —           Last-seen event data per rule trigger entity (rules processor)
+//	{processor}         engine      any         Per-processor state for each processor.StateOwner, named after
+//	                                            the processor (e.g. rules, presence_notify) and stamped with its
+//	                                            owner; opened by services/engine/state, not by this package
+//	schedules           engine      —           Durable timers registered by engine processors and schedule rules
+//	                                            (services/engine/scheduler)
+//
+// Every bucket's TTL, size and history are declared in Manifest.
 const (
-	KVBucketIdempotency  = "idempotency"
-	KVBucketConfig       = "config"
-	KVBucketPresence     = "presence"
-	KVBucketGatewayState = "gateway_state"
+	KVBucketIdempotency     = "idempotency"
+	KVBucketConfig          = "config"
+	KVBucketPresence        = "presence"
+	KVBucketGatewayState    = "gateway_state"
+	KVBucketGatewayCommands = "gateway_commands"
+	KVBucketRules           = "rules"
 )
 
 // KV key names published to KVBucketConfig by the engine after loading rules.
@@ -37,38 +47,42 @@ const (
 // EnsureConfigKV creates or binds the config KV bucket.
 // Owned by the engine; the gateway reads from it.
 func EnsureConfigKV(js nats.JetStreamContext) (nats.KeyValue, error) {
-	return ensureKV(js, KVBucketConfig, 0)
+	return ensureKV(js, KVBucketConfig)
 }
 
 // EnsurePresenceKV creates or binds the presence KV bucket.
-// Owned by the engine's presence_notify processor.
+// Owned by the presence service; presence_notify only reads its legacy keys.
 func EnsurePresenceKV(js nats.JetStreamContext) (nats.KeyValue, error) {
-	return ensureKV(js, KVBucketPresence, 0)
+	return ensureKV(js, KVBucketPresence)
 }
 
 // EnsureGatewayStateKV creates or binds the gateway_state KV bucket.
 // Owned by the gateway reconciler.
 func EnsureGatewayStateKV(js nats.JetStreamContext) (nats.KeyValue, error) {
-	return ensureKV(js, KVBucketGatewayState, 0)
+	return ensureKV(js, KVBucketGatewayState)
 }
 
-// ensureKV creates a KV bucket if it does not already exist. Idempotent.
-// A zero ttl means no per-key TTL (keys persist until explicitly deleted or the
-// bucket is destroyed).
-func ensureKV(js nats.JetStreamContext, bucket string, ttl time.Duration) (nats.KeyValue, error) {
-	kv, err := js.KeyValue(bucket)
-	if err == nil {
-		return kv, nil
+// EnsureGatewayCommandsKV creates or binds the gateway_commands KV bucket.
+// Owned by the gateway's ha_service command consumer.
+func EnsureGatewayCommandsKV(js nats.JetStreamContext) (nats.KeyValue, error) {
+	return ensureKV(js, KVBucketGatewayCommands)
+}
+
+// ensureKV creates the named manifest bucket if it does not already exist, or
+// reconciles its TTL, size and history if it does, and binds it. Idempotent.
+func ensureKV(js nats.JetStreamContext, bucket string) (nats.KeyValue, error) {
+	want, ok := Manifest().Bucket(bucket)
+	if !ok {
+		return nil, fmt.Errorf("kv: bucket %q is not in the topology manifest", bucket)
 	}
-	if !errors.Is(err, nats.ErrBucketNotFound) {
-		return nil, fmt.Errorf("kv: bind %q: %w", bucket, err)
+	drifts, err := reconcileBucket(js, want, true)
+	if err != nil {
+		return nil, err
 	}
-	kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
-		Bucket: bucket,
-		TTL:    ttl,
-	})
+	warnImmutable(drifts)
+	kv, err := js.KeyValue(bucket)
 	if err != nil {
-		return nil, fmt.Errorf("kv: create %q: %w", bucket, err)
+		return nil, fmt.Errorf("kv: bind %q: %w", bucket, err)
 	}
 	return kv, nil
 }
@@ -0,0 +1,207 @@
+package natsx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// JSPublisher is the subset of nats.JetStreamContext used to publish to a stream and
+// wait for its ack, synchronously or asynchronously.
+type JSPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+	PublishMsgAsync(m *nats.Msg, opts ...nats.PubOpt) (nats.PubAckFuture, error)
+}
+
+// contextPublisher is a MsgPublisher that can bound a publish by a context.
+// PublishWithContext, PublishEvent and PublishRejected use it when pub has it.
+type contextPublisher interface {
+	PublishMsgContext(ctx context.Context, m *nats.Msg) error
+}
+
+// publishMsg publishes m through pub, bounded by ctx when pub supports it.
+func publishMsg(ctx context.Context, pub MsgPublisher, m *nats.Msg) error {
+	if cp, ok := pub.(contextPublisher); ok {
+		return cp.PublishMsgContext(ctx, m)
+	}
+	return pub.PublishMsg(m)
+}
+
+// AckedPublisher publishes through JetStream and waits for the stream's ack, unlike a
+// core NATS publish, which is lost silently when no stream stores it. Every message
+// carries a Nats-Msg-Id — the CloudEvent id unless the caller set one — so the stream
+// drops a retried publish it already stored. A publish that times out is retried after
+// each DefaultPublishBackOff step.
+//
+// AckedPublisher is a MsgPublisher, so it replaces a *nats.Conn wherever events are
+// published to a stream. Subjects no stream captures (gateway.health) stay on core NATS.
+type AckedPublisher struct {
+	js      JSPublisher
+	ackWait time.Duration
+	backoff []time.Duration
+}
+
+// NewAckedPublisher returns an AckedPublisher on js with the config package defaults.
+func NewAckedPublisher(js JSPublisher) *AckedPublisher {
+	return &AckedPublisher{
+		js:      js,
+		ackWait: config.DefaultPublishAckWait,
+		backoff: config.DefaultPublishBackOff,
+	}
+}
+
+// PublishMsg publishes m and waits for its ack, retrying on timeout.
+func (p *AckedPublisher) PublishMsg(m *nats.Msg) error {
+	return p.PublishMsgContext(context.Background(), m)
+}
+
+// PublishMsgContext is PublishMsg bounded by ctx: it stops retrying once ctx is done.
+func (p *AckedPublisher) PublishMsgContext(ctx context.Context, m *nats.Msg) error {
+	setMsgID(m)
+	for attempt := 0; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, p.ackWait)
+		_, err := p.js.PublishMsg(m, nats.Context(actx))
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) || ctx.Err() != nil || attempt == len(p.backoff) {
+			return fmt.Errorf("natsx: publish %s: %w", m.Subject, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("natsx: publish %s: %w", m.Subject, err)
+		case <-time.After(p.backoff[attempt]):
+		}
+	}
+}
+
+// Batch returns a Batch that publishes through p's stream connection.
+func (p *AckedPublisher) Batch() *Batch {
+	return &Batch{
+		pub:    p,
+		window: make(chan struct{}, config.DefaultPublishPendingWindow),
+	}
+}
+
+// retryable reports whether a publish failed for want of an ack (the stream was slow,
+// or leaderless for a moment) rather than being refused.
+func retryable(err error) bool {
+	return errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, nats.ErrNoStreamResponse)
+}
+
+// setMsgID sets m's Nats-Msg-Id to its CloudEvent id, from the ce-id header of a
+// binary-mode event or the id member of a structured one, unless it has one already.
+// A payload without an id is published without one.
+func setMsgID(m *nats.Msg) {
+	if m.Header == nil {
+		m.Header = nats.Header{}
+	}
+	if m.Header.Get(nats.MsgIdHdr) != "" {
+		return
+	}
+	id := m.Header.Get(schemas.HeaderID)
+	if id == "" && !schemas.IsBinary(m.Header) {
+		var envelope struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(m.Data, &envelope) == nil {
+			id = envelope.ID
+		}
+	}
+	if id != "" {
+		m.Header.Set(nats.MsgIdHdr, id)
+	}
+}
+
+// Batch publishes asynchronously: PublishMsg returns once the message is sent, with at
+// most DefaultPublishPendingWindow messages awaiting their acks (PublishMsg blocks
+// while the window is full). Wait waits for every ack and republishes, synchronously
+// and with retries, any message whose ack failed or did not arrive within the ack
+// wait; Nats-Msg-Id makes that safe. Use it for bursts, such as the gateway publishing
+// every reconciled entity at once. A Batch is safe for concurrent use until Wait.
+type Batch struct {
+	pub    *AckedPublisher
+	window chan struct{}
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	failed []*nats.Msg
+}
+
+// PublishMsg sends m without waiting for its ack.
+func (b *Batch) PublishMsg(m *nats.Msg) error {
+	return b.PublishMsgContext(context.Background(), m)
+}
+
+// PublishMsgContext sends m without waiting for its ack, waiting for room in the
+// pending window until ctx is done.
+func (b *Batch) PublishMsgContext(ctx context.Context, m *nats.Msg) error {
+	setMsgID(m)
+	select {
+	case b.window <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("natsx: publish %s: %w", m.Subject, ctx.Err())
+	}
+	f, err := b.pub.js.PublishMsgAsync(m)
+	if err != nil {
+		<-b.window
+		return fmt.Errorf("natsx: publish %s: %w", m.Subject, err)
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.window }()
+		t := time.NewTimer(b.pub.ackWait)
+		defer t.Stop()
+		select {
+		case <-f.Ok():
+			return
+		case <-f.Err():
+		case <-t.C:
+		}
+		b.mu.Lock()
+		b.failed = append(b.failed, m)
+		b.mu.Unlock()
+	}()
+	return nil
+}
+
+// Wait blocks until every message published so far is acked, republishing those whose
+// async ack failed, and returns the errors of any that still could not be published.
+func (b *Batch) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("natsx: wait for publish acks: %w", ctx.Err())
+	}
+
+	b.mu.Lock()
+	failed := b.failed
+	b.failed = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for _, m := range failed {
+		retry := &nats.Msg{Subject: m.Subject, Header: m.Header, Data: m.Data}
+		if err := b.pub.PublishMsgContext(ctx, retry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+//go:build integration
+
+package natsx_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// TestAckedPublisher_DedupsRetriedPublish verifies that publishing the same CloudEvent
+// twice — as a retry after a lost ack does — stores it once, and that a subject no
+// stream captures is an error instead of a silent loss.
+func TestAckedPublisher_DedupsRetriedPublish(t *testing.T) {
+	nc := startNATS(t)
+	js := ensureStream(t, nc)
+	pub := natsx.NewAckedPublisher(js)
+	ctx := context.Background()
+
+	body := []byte(`{"specversion":"1.0","id":"evt-1","type":"test"}`)
+	for range 2 {
+		if err := natsx.PublishWithContext(ctx, pub, "test.events.a", body); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	info, err := js.StreamInfo("TEST_STREAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream holds %d messages, want the duplicate dropped", info.State.Msgs)
+	}
+
+	if err := natsx.PublishWithContext(ctx, pub, "nostream.events.a", body); err == nil {
+		t.Error("publish to a subject no stream captures succeeded")
+	}
+}
+
+// TestBatch_PublishesAll verifies every message published through a Batch is stored
+// once Wait returns.
+func TestBatch_PublishesAll(t *testing.T) {
+	nc := startNATS(t)
+	js := ensureStream(t, nc)
+	batch := natsx.NewAckedPublisher(js).Batch()
+	ctx := context.Background()
+
+	const n = 500 // more than the pending window
+	for i := range n {
+		body := fmt.Appendf(nil, `{"specversion":"1.0","id":"evt-%d","type":"test"}`, i)
+		if err := natsx.PublishWithContext(ctx, batch, "test.events.batch", body); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	if err := batch.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	info, err := js.StreamInfo("TEST_STREAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != n {
+		t.Errorf("stream holds %d messages, want %d", info.State.Msgs, n)
+	}
+}
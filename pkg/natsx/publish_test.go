@@ -0,0 +1,140 @@
+//go:build fast
+
+package natsx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// fakeStreamPublisher answers publishes with the queued errors, then with acks.
+type fakeStreamPublisher struct {
+	mu    sync.Mutex
+	errs  []error
+	msgs  []*nats.Msg
+	async []*nats.Msg
+}
+
+func (f *fakeStreamPublisher) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append(f.msgs, m)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &nats.PubAck{Stream: "TEST"}, nil
+}
+
+func (f *fakeStreamPublisher) PublishMsgAsync(m *nats.Msg, _ ...nats.PubOpt) (nats.PubAckFuture, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.async = append(f.async, m)
+	fut := &fakeFuture{ok: make(chan *nats.PubAck, 1), err: make(chan error, 1), msg: m}
+	if len(f.errs) > 0 {
+		fut.err <- f.errs[0]
+		f.errs = f.errs[1:]
+	} else {
+		fut.ok <- &nats.PubAck{Stream: "TEST"}
+	}
+	return fut, nil
+}
+
+type fakeFuture struct {
+	ok  chan *nats.PubAck
+	err chan error
+	msg *nats.Msg
+}
+
+func (f *fakeFuture) Ok() <-chan *nats.PubAck { return f.ok }
+func (f *fakeFuture) Err() <-chan error       { return f.err }
+func (f *fakeFuture) Msg() *nats.Msg          { return f.msg }
+
+func testPublisher(js JSPublisher) *AckedPublisher {
+	return &AckedPublisher{js: js, ackWait: time.Second, backoff: []time.Duration{time.Millisecond, time.Millisecond}}
+}
+
+func TestAckedPublisher_SetsMsgIDFromCloudEvent(t *testing.T) {
+	js := &fakeStreamPublisher{}
+	p := testPublisher(js)
+
+	if err := PublishWithContext(context.Background(), p, "ha.events.a", []byte(`{"id":"evt-1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	binary, err := NewEventMsg(context.Background(), "ha.events.b", schemas.CloudEvent{ID: "evt-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishMsg(binary); err != nil {
+		t.Fatal(err)
+	}
+	explicit := nats.NewMsg("ha.events.c")
+	explicit.Header.Set(nats.MsgIdHdr, "fire-1")
+	explicit.Data = []byte(`{"id":"evt-3"}`)
+	if err := p.PublishMsg(explicit); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishMsg(&nats.Msg{Subject: "ha.events.d", Data: []byte("not-json")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"evt-1", "evt-2", "fire-1", ""} {
+		if got := js.msgs[i].Header.Get(nats.MsgIdHdr); got != want {
+			t.Errorf("%s: Nats-Msg-Id = %q, want %q", js.msgs[i].Subject, got, want)
+		}
+	}
+}
+
+func TestAckedPublisher_RetriesTimeouts(t *testing.T) {
+	js := &fakeStreamPublisher{errs: []error{nats.ErrTimeout, context.DeadlineExceeded}}
+	if err := testPublisher(js).PublishMsg(&nats.Msg{Subject: "s", Data: []byte(`{"id":"e"}`)}); err != nil {
+		t.Fatalf("PublishMsg = %v, want success on the third attempt", err)
+	}
+	if len(js.msgs) != 3 {
+		t.Errorf("attempts = %d, want 3", len(js.msgs))
+	}
+}
+
+func TestAckedPublisher_GivesUp(t *testing.T) {
+	js := &fakeStreamPublisher{errs: []error{nats.ErrTimeout, nats.ErrTimeout, nats.ErrTimeout}}
+	err := testPublisher(js).PublishMsg(&nats.Msg{Subject: "s"})
+	if !errors.Is(err, nats.ErrTimeout) || len(js.msgs) != 3 {
+		t.Errorf("PublishMsg = %v after %d attempts, want ErrTimeout after 3", err, len(js.msgs))
+	}
+
+	refused := errors.New("nats: maximum bytes exceeded")
+	js = &fakeStreamPublisher{errs: []error{refused}}
+	err = testPublisher(js).PublishMsg(&nats.Msg{Subject: "s"})
+	if !errors.Is(err, refused) || len(js.msgs) != 1 {
+		t.Errorf("PublishMsg = %v after %d attempts, want the refusal without a retry", err, len(js.msgs))
+	}
+}
+
+func TestBatch_RepublishesFailedAcks(t *testing.T) {
+	js := &fakeStreamPublisher{errs: []error{nats.ErrNoStreamResponse}}
+	p := testPublisher(js)
+	b := &Batch{pub: p, window: make(chan struct{}, 2)}
+
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := b.PublishMsg(&nats.Msg{Subject: "s", Data: []byte(`{"id":"` + id + `"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+	if len(js.async) != 3 {
+		t.Errorf("async publishes = %d, want 3", len(js.async))
+	}
+	if len(js.msgs) != 1 || js.msgs[0].Header.Get(nats.MsgIdHdr) != "e1" {
+		t.Errorf("republished %v, want e1 once", js.msgs)
+	}
+}
@@ -0,0 +1,60 @@
+package natsx
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+)
+
+// RejectedStream is the JetStream stream that captures events rejected by schema
+// validation on rejected.> (ADR-0014). See EnsureRejectedStream.
+const RejectedStream = "REJECTED"
+
+// RejectedSubjectPrefix prefixes the subject of a rejected event: an event published
+// on ha.events.ada.diaper_logged is rejected to rejected.ha.events.ada.diaper_logged,
+// so the REJECTED stream can be filtered the same way as the stream it came from.
+const RejectedSubjectPrefix = "rejected."
+
+// Headers set on every rejected event, describing who rejected it and why.
+const (
+	// HeaderRejectedBy names what rejected the event: the publishing service at
+	// publish time (e.g. "ruby_gateway"), or the durable consumer at consume time.
+	HeaderRejectedBy = "Ruby-Rejected-By"
+	// HeaderRejectedReason is the validation error, flattened to one line and
+	// truncated like HeaderDLQError.
+	HeaderRejectedReason = "Ruby-Rejected-Reason"
+)
+
+// RejectedSubject returns the subject an event published on subject is rejected to.
+func RejectedSubject(subject string) string {
+	return RejectedSubjectPrefix + subject
+}
+
+// RejectedMsg builds the message that routes an invalid event to the REJECTED stream:
+// data unchanged on RejectedSubject(subject), carrying orig's headers and the
+// HeaderRejected* headers. Keeping orig's Nats-Msg-Id lets the stream dedup the same
+// event rejected again on redelivery, or by several consumers.
+func RejectedMsg(subject string, orig nats.Header, data []byte, by string, reason error) *nats.Msg {
+	m := nats.NewMsg(RejectedSubject(subject))
+	m.Data = data
+	for k, vs := range orig {
+		m.Header[k] = append([]string(nil), vs...)
+	}
+	m.Header.Set(HeaderRejectedBy, by)
+	msg := "invalid event"
+	if reason != nil {
+		msg = reason.Error()
+	}
+	m.Header.Set(HeaderRejectedReason, DLQErrorHeader(msg))
+	return m
+}
+
+// PublishRejected publishes an event that failed validation before it was published
+// (e.g. by the gateway) to the REJECTED stream, injecting the active span's trace
+// context like PublishWithContext.
+func PublishRejected(ctx context.Context, pub MsgPublisher, subject string, data []byte, by string, reason error) error {
+	m := RejectedMsg(subject, nil, data, by, reason)
+	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(m.Header))
+	return publishMsg(ctx, pub, m)
+}
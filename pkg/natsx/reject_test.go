@@ -0,0 +1,33 @@
+//go:build fast
+
+package natsx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestRejectedMsg(t *testing.T) {
+	orig := nats.Header{}
+	orig.Set(nats.MsgIdHdr, "evt-1")
+	m := RejectedMsg("ha.events.ada.diaper_deleted", orig, []byte(`{}`), "engine_ada",
+		errors.New("invalid event:\nmissing id"))
+
+	if m.Subject != "rejected.ha.events.ada.diaper_deleted" {
+		t.Errorf("Subject = %q", m.Subject)
+	}
+	if got := m.Header.Get(nats.MsgIdHdr); got != "evt-1" {
+		t.Errorf("Nats-Msg-Id = %q, want the original so redeliveries dedup", got)
+	}
+	if got := m.Header.Get(HeaderRejectedBy); got != "engine_ada" {
+		t.Errorf("%s = %q", HeaderRejectedBy, got)
+	}
+	if got := m.Header.Get(HeaderRejectedReason); got != "invalid event: missing id" {
+		t.Errorf("%s = %q, want the reason on one line", HeaderRejectedReason, got)
+	}
+	if orig.Get(HeaderRejectedBy) != "" {
+		t.Error("RejectedMsg modified the original headers")
+	}
+}
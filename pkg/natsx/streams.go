@@ -1,13 +1,9 @@
 package natsx
 
 import (
-	"errors"
 	"fmt"
-	"time"
 
 	"github.com/nats-io/nats.go"
-
-	"github.com/primaryrutabaga/ruby-core/pkg/config"
 )
 
 // EnsureHAEventsStream creates or reconciles the HA_EVENTS JetStream stream.
@@ -17,31 +13,26 @@ import (
 // ceiling so this high-volume firehose can never exhaust the JetStream store — its prior
 // unbounded retention filled the account and starved the discard=new KV buckets.
 func EnsureHAEventsStream(js nats.JetStreamContext) error {
-	return ensureStream(js, &nats.StreamConfig{
-		Name:      "HA_EVENTS",
-		Subjects:  []string{"ha.events.>"},
-		Storage:   nats.FileStorage,
-		Retention: nats.LimitsPolicy,
-		MaxAge:    config.DefaultHAEventsMaxAge,
-		MaxBytes:  config.MaxBytesHAEvents,
-	})
+	return ensureStream(js, "HA_EVENTS")
 }
 
-// EnsureDLQStream creates the DLQ JetStream stream if it does not already exist.
+// EnsureDLQStream creates or reconciles the DLQ JetStream stream.
 // The wildcard subjects dlq.> capture dead-lettered messages from all consumers (ADR-0022).
 // Messages are retained for DefaultDLQMaxAge (7 days) for manual inspection and reprocessing.
 func EnsureDLQStream(js nats.JetStreamContext) error {
-	return ensureStream(js, &nats.StreamConfig{
-		Name:      "DLQ",
-		Subjects:  []string{"dlq.>"},
-		Storage:   nats.FileStorage,
-		Retention: nats.LimitsPolicy,
-		MaxAge:    config.DefaultDLQMaxAge,
-		MaxBytes:  config.MaxBytesDLQ,
-	})
+	return ensureStream(js, DLQStream)
+}
+
+// EnsureRejectedStream creates or reconciles the REJECTED JetStream stream.
+// The wildcard subjects rejected.> capture events that failed schema validation at
+// publish or consume time (ADR-0014). Messages are retained for DefaultRejectedMaxAge
+// (7 days) for inspection, and deduplicated by Nats-Msg-Id for
+// DefaultRejectedDuplicates.
+func EnsureRejectedStream(js nats.JetStreamContext) error {
+	return ensureStream(js, RejectedStream)
 }
 
-// EnsureAuditStream creates the AUDIT_EVENTS JetStream stream if it does not already exist.
+// EnsureAuditStream creates or reconciles the AUDIT_EVENTS JetStream stream.
 // The stream captures all audit.> subjects published by any service (ADR-0019).
 // Messages are retained for DefaultAuditMaxAge (72h) to survive a prolonged audit-sink outage.
 //
@@ -54,74 +45,47 @@ func EnsureDLQStream(js nats.JetStreamContext) error {
 // The inversion is necessary: a leading-wildcard filter *.audit.> overlaps with the
 // reserved dlq.> namespace, causing NATS to reject the stream. Using audit.> avoids this.
 func EnsureAuditStream(js nats.JetStreamContext) error {
-	return ensureStream(js, &nats.StreamConfig{
-		Name:      "AUDIT_EVENTS",
-		Subjects:  []string{"audit.>"},
-		Storage:   nats.FileStorage,
-		Retention: nats.LimitsPolicy,
-		MaxAge:    config.DefaultAuditMaxAge,
-		MaxBytes:  config.MaxBytesAudit,
-	})
+	return ensureStream(js, "AUDIT_EVENTS")
 }
 
-// EnsureCommandsStream creates the COMMANDS JetStream stream if it does not already exist.
+// EnsureCommandsStream creates or reconciles the COMMANDS JetStream stream.
 // The stream captures all ruby_engine.commands.> subjects published by the engine.
 // Messages are retained for 1 hour — stale notifications are not worth replaying.
 func EnsureCommandsStream(js nats.JetStreamContext) error {
-	return ensureStream(js, &nats.StreamConfig{
-		Name:      "COMMANDS",
-		Subjects:  []string{"ruby_engine.commands.>"},
-		Storage:   nats.FileStorage,
-		Retention: nats.LimitsPolicy,
-		MaxAge:    config.DefaultCommandsMaxAge,
-		MaxBytes:  config.MaxBytesCommands,
-	})
+	return ensureStream(js, "COMMANDS")
 }
 
-// EnsurePresenceStream creates the PRESENCE JetStream stream if it does not already exist.
+// EnsurePresenceStream creates or reconciles the PRESENCE JetStream stream.
 // The stream captures all ruby_presence.events.> subjects published by the presence service.
 // Messages are retained for 24 hours.
 func EnsurePresenceStream(js nats.JetStreamContext) error {
-	return ensureStream(js, &nats.StreamConfig{
-		Name:      "PRESENCE",
-		Subjects:  []string{"ruby_presence.events.>"},
-		Storage:   nats.FileStorage,
-		Retention: nats.LimitsPolicy,
-		MaxAge:    24 * time.Hour,
-		MaxBytes:  config.MaxBytesPresence,
-	})
+	return ensureStream(js, "PRESENCE")
+}
+
+// EnsureSchedulesStream creates or reconciles the SCHEDULES JetStream stream.
+// The stream captures the ruby_engine.events.schedule.> events published by the engine
+// scheduler when a durable timer comes due. Messages are retained for DefaultSchedulesMaxAge,
+// within which the scheduler can tell that a firing was already published.
+func EnsureSchedulesStream(js nats.JetStreamContext) error {
+	return ensureStream(js, "SCHEDULES")
 }
 
-// ensureStream creates a stream if absent, or reconciles its mutable retention limits
-// if it already exists with drifted config. Idempotent. Reconciliation is what lets a
-// limit change in code (e.g. a new MaxAge/MaxBytes) actually take effect on an existing
-// deployment instead of being silently ignored — the gap that let HA_EVENTS grow
-// unbounded after its limits were assumed but never applied (ADR-0034).
-func ensureStream(js nats.JetStreamContext, cfg *nats.StreamConfig) error {
-	info, err := js.StreamInfo(cfg.Name)
-	if errors.Is(err, nats.ErrStreamNotFound) {
-		if _, err = js.AddStream(cfg); err != nil {
-			return fmt.Errorf("add stream %q: %w", cfg.Name, err)
-		}
-		return nil
+// ensureStream creates the named manifest stream if absent, or reconciles its subjects
+// and retention limits if it already exists with drifted config. Idempotent.
+// Reconciliation is what lets a limit change in the manifest (e.g. a new
+// MaxAge/MaxBytes) actually take effect on an existing deployment instead of being
+// silently ignored — the gap that let HA_EVENTS grow unbounded after its limits were
+// assumed but never applied (ADR-0034). Drift in fields the server cannot update
+// (storage, retention policy) is logged, not fixed.
+func ensureStream(js topologyAdmin, name string) error {
+	want, ok := Manifest().Stream(name)
+	if !ok {
+		return fmt.Errorf("natsx: stream %q is not in the topology manifest", name)
 	}
+	drifts, err := reconcileStream(js, want, true)
 	if err != nil {
-		return fmt.Errorf("stream info %q: %w", cfg.Name, err)
-	}
-	// Only retention limits are reconciled — these are safe to UpdateStream. Immutable
-	// fields (name, storage, retention policy, subjects) are never changed here.
-	if streamLimitsDrifted(&info.Config, cfg) {
-		if _, err = js.UpdateStream(cfg); err != nil {
-			return fmt.Errorf("update stream %q: %w", cfg.Name, err)
-		}
+		return err
 	}
+	warnImmutable(drifts)
 	return nil
 }
-
-// streamLimitsDrifted reports whether the live stream's mutable retention limits differ
-// from the desired config. Pure, so the reconcile trigger is unit-testable.
-func streamLimitsDrifted(existing, desired *nats.StreamConfig) bool {
-	return existing.MaxAge != desired.MaxAge ||
-		existing.MaxBytes != desired.MaxBytes ||
-		existing.MaxMsgs != desired.MaxMsgs
-}
@@ -100,3 +100,85 @@ func BuildDLQSubject(streamName, consumerName string) (string, error) {
 	}
 	return fmt.Sprintf("dlq.%s.%s", streamName, consumerName), nil
 }
+
+// ValidateSubjectPattern checks that pattern is a well-formed NATS subscription
+// subject: non-empty dot-separated tokens without whitespace, where "*" and ">"
+// only appear as whole tokens and ">" only as the last one. Token case is not
+// checked, so patterns may address subjects outside the ADR-0027 convention.
+func ValidateSubjectPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern: %w", ErrInvalidPattern)
+	}
+	tokens := strings.Split(pattern, ".")
+	for i, tok := range tokens {
+		switch {
+		case tok == "":
+			return fmt.Errorf("%q has an empty token: %w", pattern, ErrInvalidPattern)
+		case tok == ">" && i != len(tokens)-1:
+			return fmt.Errorf("%q: \">\" must be the last token: %w", pattern, ErrInvalidPattern)
+		case tok == "*" || tok == ">":
+		case strings.ContainsAny(tok, "*> \t\r\n"):
+			return fmt.Errorf("%q: token %q mixes wildcards or whitespace with text: %w", pattern, tok, ErrInvalidPattern)
+		}
+	}
+	return nil
+}
+
+// MatchSubject reports whether subject matches pattern under NATS wildcard
+// rules: "*" matches exactly one token in any position and a trailing ">"
+// matches one or more remaining tokens. pattern is assumed to be valid (see
+// ValidateSubjectPattern).
+func MatchSubject(pattern, subject string) bool {
+	for {
+		ptok, prest, pmore := strings.Cut(pattern, ".")
+		if ptok == ">" {
+			return subject != ""
+		}
+		stok, srest, smore := strings.Cut(subject, ".")
+		if stok == "" || (ptok != "*" && ptok != stok) {
+			return false
+		}
+		if !pmore || !smore {
+			return pmore == smore
+		}
+		pattern, subject = prest, srest
+	}
+}
+
+// SubjectSubsetOf reports whether every subject matched by pattern is also matched by
+// of. Both are assumed to be valid (see ValidateSubjectPattern).
+func SubjectSubsetOf(pattern, of string) bool {
+	for {
+		otok, orest, omore := strings.Cut(of, ".")
+		if otok == ">" {
+			return pattern != ""
+		}
+		ptok, prest, pmore := strings.Cut(pattern, ".")
+		if ptok == "" || ptok == ">" || (otok != "*" && otok != ptok) {
+			return false
+		}
+		if !pmore || !omore {
+			return pmore == omore
+		}
+		pattern, of = prest, orest
+	}
+}
+
+// SubjectsOverlap reports whether some subject is matched by both a and b. Both are
+// assumed to be valid (see ValidateSubjectPattern).
+func SubjectsOverlap(a, b string) bool {
+	for {
+		atok, arest, amore := strings.Cut(a, ".")
+		btok, brest, bmore := strings.Cut(b, ".")
+		if atok == ">" || btok == ">" {
+			return true
+		}
+		if atok != btok && atok != "*" && btok != "*" {
+			return false
+		}
+		if !amore || !bmore {
+			return amore == bmore
+		}
+		a, b = arest, brest
+	}
+}
@@ -3,6 +3,7 @@
 package natsx
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -102,3 +103,119 @@ func TestBuildDLQSubject(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSubjectPattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"ha.events.person.wife", true},
+		{"ha.events.>", true},
+		{"ha.*.person.*", true},
+		{"*.events.>", true},
+		{">", true},
+		{"ha.events.input_number.Ada_Threshold", true},
+		{"", false},
+		{"ha..events", false},
+		{"ha.events.", false},
+		{"ha.>.person", false},
+		{"ha.events.person*", false},
+		{"ha.events.>x", false},
+		{"ha.events.person wife", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			err := ValidateSubjectPattern(tc.pattern)
+			if tc.valid && err != nil {
+				t.Errorf("ValidateSubjectPattern(%q) unexpected error = %v", tc.pattern, err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidPattern) {
+				t.Errorf("ValidateSubjectPattern(%q) error = %v, want %v", tc.pattern, err, ErrInvalidPattern)
+			}
+		})
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{"ha.events.person.wife", "ha.events.person.wife", true},
+		{"ha.events.person.wife", "ha.events.person.husband", false},
+		{"ha.events.person", "ha.events.person.wife", false},
+		{"ha.events.person.wife", "ha.events.person", false},
+		{"ha.events.>", "ha.events.person.wife", true},
+		{"ha.events.>", "ha.events", false},
+		{"ha.events.>", "ha.eventsx.person", false},
+		{">", "ha", true},
+		{"ha.*.person.*", "ha.events.person.wife", true},
+		{"ha.*.person.*", "ha.events.person", false},
+		{"*.events.>", "ruby_presence.events.state.wife", true},
+		{"ha.*", "ha.events.person", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+"/"+tc.subject, func(t *testing.T) {
+			if got := MatchSubject(tc.pattern, tc.subject); got != tc.want {
+				t.Errorf("MatchSubject(%q, %q) = %v, want %v", tc.pattern, tc.subject, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubjectSubsetOf(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		of      string
+		want    bool
+	}{
+		{"ha.events.person.>", "ha.events.>", true},
+		{"ha.events.>", "ha.events.>", true},
+		{"ha.events.>", "ha.events.person.>", false},
+		{"ha.events.person", "ha.events.>", true},
+		{"ha.events", "ha.events.>", false},
+		{"ha.events.person.*", "ha.events.>", true},
+		{"ha.events.*", "ha.events.person", false},
+		{"ha.events.person", "ha.events.*", true},
+		{"ha.*.person", "ha.events.*", false},
+		{"ha.events.>", "ruby_presence.events.>", false},
+		{"ha.events.person", "ha.events.person.alice", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+"/"+tc.of, func(t *testing.T) {
+			if got := SubjectSubsetOf(tc.pattern, tc.of); got != tc.want {
+				t.Errorf("SubjectSubsetOf(%q, %q) = %v, want %v", tc.pattern, tc.of, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubjectsOverlap(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want bool
+	}{
+		{"ha.events.person.>", "ha.events.>", true},
+		{"ha.events.person.>", "ha.events.light.>", false},
+		{"ha.events.*.alice", "ha.events.person.*", true},
+		{"ha.events.person", "ha.events.person.>", false},
+		{"ha.events.person.alice", "ha.events.person.alice", true},
+		{"ha.events.*", "ha.events.person.alice", false},
+		{">", "ruby_presence.events.state", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+"/"+tc.b, func(t *testing.T) {
+			if got := SubjectsOverlap(tc.a, tc.b); got != tc.want {
+				t.Errorf("SubjectsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+			if got := SubjectsOverlap(tc.b, tc.a); got != tc.want {
+				t.Errorf("SubjectsOverlap(%q, %q) = %v, want %v", tc.b, tc.a, got, tc.want)
+			}
+		})
+	}
+}
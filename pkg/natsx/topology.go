@@ -0,0 +1,186 @@
+package natsx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+)
+
+// Topology is a declarative manifest of JetStream streams, KV buckets and durable
+// consumers. Services reconcile their part of it at boot (Ensure*Stream, Ensure*KV,
+// EnsurePullConsumer); cmd/topology diffs all of it against a live server.
+type Topology struct {
+	Streams []nats.StreamConfig
+	Buckets []nats.KeyValueConfig
+	// Consumers lists durable pull consumers. A Durable ending in "*" declares a
+	// family whose instances are named at runtime (e.g. engine_* has one consumer per
+	// engine processor); an empty FilterSubject leaves each instance's filter to the
+	// service that creates it.
+	Consumers []PullConsumerConfig
+}
+
+// Manifest returns the topology Ruby Core runs on. It is the single place stream
+// limits, bucket TTLs and consumer delivery settings are declared; change them here
+// and every service reconciles on its next start (ADR-0034).
+func Manifest() Topology {
+	// The engine narrows each engine_* instance to its processor's subscriptions
+	// within filter with FilterSubjects.
+	engineConsumer := func(stream, filter string) PullConsumerConfig {
+		return DefaultPullConsumerConfig(stream, "engine_*", filter)
+	}
+	presenceConsumer := DefaultPullConsumerConfig("HA_EVENTS", "presence_*", "")
+	// One worker: presence transitions must apply in the order the phone reported them.
+	presenceConsumer.WorkerCount, presenceConsumer.MinWorkerCount, presenceConsumer.FetchBatch = 1, 1, 1
+	// Notify commands are low volume: a small pool that idles at one worker.
+	notifierConsumer := DefaultPullConsumerConfig("COMMANDS", "notifier_processor", "ruby_engine.commands.notify.>")
+	notifierConsumer.MaxAckPending = config.DefaultCommandsMaxAckPending
+	notifierConsumer.WorkerCount, notifierConsumer.MinWorkerCount = config.DefaultCommandsWorkerCount, 1
+	notifierConsumer.FetchBatch = config.DefaultCommandsFetchBatch
+	// HA service calls share the notifier's bounds: a burst is a scene of a few lights.
+	gatewayCommandsConsumer := notifierConsumer
+	gatewayCommandsConsumer.Durable, gatewayCommandsConsumer.FilterSubject = "gateway_ha_service", "ruby_engine.commands.ha_service.>"
+
+	return Topology{
+		Streams: []nats.StreamConfig{
+			{
+				Name:      "HA_EVENTS",
+				Subjects:  []string{"ha.events.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    config.DefaultHAEventsMaxAge,
+				MaxBytes:  config.MaxBytesHAEvents,
+			},
+			{
+				Name:      DLQStream,
+				Subjects:  []string{"dlq.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    config.DefaultDLQMaxAge,
+				MaxBytes:  config.MaxBytesDLQ,
+			},
+			{
+				Name:       RejectedStream,
+				Subjects:   []string{RejectedSubjectPrefix + ">"},
+				Storage:    nats.FileStorage,
+				Retention:  nats.LimitsPolicy,
+				MaxAge:     config.DefaultRejectedMaxAge,
+				MaxBytes:   config.MaxBytesRejected,
+				Duplicates: config.DefaultRejectedDuplicates,
+			},
+			{
+				Name:      "AUDIT_EVENTS",
+				Subjects:  []string{"audit.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    config.DefaultAuditMaxAge,
+				MaxBytes:  config.MaxBytesAudit,
+			},
+			{
+				Name:      "COMMANDS",
+				Subjects:  []string{"ruby_engine.commands.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    config.DefaultCommandsMaxAge,
+				MaxBytes:  config.MaxBytesCommands,
+			},
+			{
+				Name:      "PRESENCE",
+				Subjects:  []string{"ruby_presence.events.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    24 * time.Hour,
+				MaxBytes:  config.MaxBytesPresence,
+			},
+			{
+				Name:      "SCHEDULES",
+				Subjects:  []string{"ruby_engine.events.schedule.>"},
+				Storage:   nats.FileStorage,
+				Retention: nats.LimitsPolicy,
+				MaxAge:    config.DefaultSchedulesMaxAge,
+				MaxBytes:  config.MaxBytesSchedules,
+			},
+		},
+		Buckets: []nats.KeyValueConfig{
+			{Bucket: KVBucketIdempotency, TTL: config.DefaultIdempotencyTTL},
+			{Bucket: "calendar_idempotency", TTL: 24 * time.Hour}, // calendar processor's dedup of reminders
+			{Bucket: KVBucketConfig},
+			{Bucket: KVBucketPresence},
+			{Bucket: KVBucketGatewayState},
+			// The gateway's ha_service command claims: each outlives its command in COMMANDS.
+			{Bucket: KVBucketGatewayCommands, TTL: config.DefaultCommandsMaxAge},
+			{Bucket: KVBucketRules},     // rules processor state (services/engine/state)
+			{Bucket: "presence_notify"}, // presence_notify processor state
+			{Bucket: "schedules"},       // engine scheduler timers
+		},
+		Consumers: []PullConsumerConfig{
+			engineConsumer("HA_EVENTS", "ha.events.>"),
+			engineConsumer("PRESENCE", "ruby_presence.events.>"),
+			engineConsumer("SCHEDULES", "ruby_engine.events.schedule.>"),
+			presenceConsumer,
+			notifierConsumer,
+			gatewayCommandsConsumer,
+			{
+				Stream:         "AUDIT_EVENTS",
+				Durable:        "audit_sink_consumer",
+				FilterSubject:  "audit.>",
+				MaxDeliver:     config.DefaultMaxDeliver,
+				MaxAckPending:  config.DefaultAuditSinkMaxAckPending,
+				AckWait:        config.DefaultAckWait,
+				BackOff:        config.DefaultBackOff,
+				WorkerCount:    config.DefaultAuditSinkWorkerCount,
+				MinWorkerCount: 1,
+				FetchBatch:     config.DefaultAuditSinkFetchBatch,
+			},
+		},
+	}
+}
+
+// Stream returns the manifest entry for the named stream.
+func (t Topology) Stream(name string) (nats.StreamConfig, bool) {
+	for _, s := range t.Streams {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nats.StreamConfig{}, false
+}
+
+// Bucket returns the manifest entry for the named KV bucket.
+func (t Topology) Bucket(name string) (nats.KeyValueConfig, bool) {
+	for _, b := range t.Buckets {
+		if b.Bucket == name {
+			return b, true
+		}
+	}
+	return nats.KeyValueConfig{}, false
+}
+
+// Consumer returns the config for durable on stream, taken from its exact manifest
+// entry or from the family it belongs to. The returned Durable is always durable.
+func (t Topology) Consumer(stream, durable string) (PullConsumerConfig, error) {
+	for _, c := range t.Consumers {
+		if c.Stream == stream && durableMatches(c.Durable, durable) {
+			c.Durable = durable
+			return c, nil
+		}
+	}
+	return PullConsumerConfig{}, fmt.Errorf("natsx: consumer %s/%s is not in the topology manifest", stream, durable)
+}
+
+// durableMatches reports whether name is pattern or, for a family pattern ending in
+// "*", an instance of it.
+func durableMatches(pattern, name string) bool {
+	if prefix, family := strings.CutSuffix(pattern, "*"); family {
+		return strings.HasPrefix(name, prefix) && len(name) > len(prefix)
+	}
+	return pattern == name
+}
+
+// isFamily reports whether durable names a consumer family rather than one consumer.
+func isFamily(durable string) bool {
+	return strings.HasSuffix(durable, "*")
+}
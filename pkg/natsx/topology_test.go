@@ -0,0 +1,91 @@
+//go:build fast
+
+package natsx
+
+import (
+	"testing"
+)
+
+func TestManifest_Consistent(t *testing.T) {
+	m := Manifest()
+	streams := make(map[string]bool)
+	for _, s := range m.Streams {
+		if streams[s.Name] {
+			t.Errorf("stream %q declared twice", s.Name)
+		}
+		streams[s.Name] = true
+		if len(s.Subjects) == 0 || s.MaxAge <= 0 || s.MaxBytes <= 0 {
+			t.Errorf("stream %q must declare subjects and age and byte limits (ADR-0034)", s.Name)
+		}
+	}
+	buckets := make(map[string]bool)
+	for _, b := range m.Buckets {
+		if buckets[b.Bucket] {
+			t.Errorf("bucket %q declared twice", b.Bucket)
+		}
+		buckets[b.Bucket] = true
+	}
+	for _, c := range m.Consumers {
+		if !streams[c.Stream] {
+			t.Errorf("consumer %s/%s is on a stream the manifest does not declare", c.Stream, c.Durable)
+		}
+		if c.FetchBatch > c.WorkerCount {
+			t.Errorf("consumer %s/%s: FetchBatch (%d) exceeds WorkerCount (%d)", c.Stream, c.Durable, c.FetchBatch, c.WorkerCount)
+		}
+		if c.MinWorkerCount > c.WorkerCount {
+			t.Errorf("consumer %s/%s: MinWorkerCount (%d) exceeds WorkerCount (%d)", c.Stream, c.Durable, c.MinWorkerCount, c.WorkerCount)
+		}
+		if !isFamily(c.Durable) && c.FilterSubject == "" {
+			t.Errorf("consumer %s/%s must declare its filter subject", c.Stream, c.Durable)
+		}
+	}
+}
+
+func TestTopologyConsumer(t *testing.T) {
+	m := Manifest()
+
+	cfg, err := m.Consumer("HA_EVENTS", "engine_rules")
+	if err != nil {
+		t.Fatalf("engine family instance: %v", err)
+	}
+	if cfg.Durable != "engine_rules" || cfg.FilterSubject != "ha.events.>" {
+		t.Errorf("engine_rules = %s %q, want the instance name and the family's filter", cfg.Durable, cfg.FilterSubject)
+	}
+
+	cfg, err = m.Consumer("HA_EVENTS", "presence_alice")
+	if err != nil {
+		t.Fatalf("presence family instance: %v", err)
+	}
+	if cfg.WorkerCount != 1 || cfg.FetchBatch != 1 {
+		t.Errorf("presence consumer pool = %d/%d, want 1/1 so transitions apply in order", cfg.WorkerCount, cfg.FetchBatch)
+	}
+
+	if _, err := m.Consumer("AUDIT_EVENTS", "audit_sink_consumer"); err != nil {
+		t.Errorf("exact entry: %v", err)
+	}
+	for _, c := range []struct{ stream, durable string }{
+		{"HA_EVENTS", "engine_"},            // family prefix alone is not an instance
+		{"COMMANDS", "engine_rules"},        // family is not declared on COMMANDS
+		{"COMMANDS", "notifier_processor2"}, // exact entries match exactly
+	} {
+		if _, err := m.Consumer(c.stream, c.durable); err == nil {
+			t.Errorf("Consumer(%s, %s) matched, want an error", c.stream, c.durable)
+		}
+	}
+}
+
+func TestTopologyLookups(t *testing.T) {
+	m := Manifest()
+	if s, ok := m.Stream(DLQStream); !ok || s.Subjects[0] != "dlq.>" {
+		t.Errorf("Stream(DLQ) = %+v, %v", s, ok)
+	}
+	if _, ok := m.Stream("NOPE"); ok {
+		t.Error("Stream(NOPE) found")
+	}
+	if b, ok := m.Bucket(KVBucketIdempotency); !ok || b.TTL <= 0 {
+		t.Errorf("Bucket(idempotency) = %+v, %v; want a TTL", b, ok)
+	}
+	if _, ok := m.Bucket("nope"); ok {
+		t.Error("Bucket(nope) found")
+	}
+}
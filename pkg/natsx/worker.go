@@ -0,0 +1,376 @@
+package natsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+)
+
+// FetchMaxWait bounds each Fetch call of a WorkerPool. It is also the longest a
+// shutdown waits for an idle pool to notice ctx cancellation.
+const FetchMaxWait = 2 * time.Second
+
+// DrainTimeout is how long WorkerPool.Run waits for in-flight handlers after ctx is
+// cancelled before cancelling their context. It stays under Docker's 10s stop grace
+// period so handlers get to ack before the container is killed.
+const DrainTimeout = 5 * time.Second
+
+// Decision is what a WorkerPool does with a message once its Handler returns.
+type Decision int
+
+const (
+	// DecisionAck acknowledges the message.
+	DecisionAck Decision = iota
+	// DecisionNak asks for redelivery after the consumer's BackOff delay for this
+	// delivery (or Result.Delay, when set).
+	DecisionNak
+	// DecisionTerm stops redelivery: the payload is poison and no retry can succeed.
+	// Terminated messages do not reach MaxDeliver, so they are not dead-lettered.
+	DecisionTerm
+)
+
+// Result is a Handler's verdict on one message.
+type Result struct {
+	Decision Decision
+	// Outcome labels ruby_core_messages_processed_total (Outcome*). Empty means
+	// OutcomeSuccess for an ack and OutcomeFailure otherwise.
+	Outcome string
+	// Delay overrides the BackOff delay of a DecisionNak.
+	Delay time.Duration
+	// Err is why the message was naked or terminated. A nak records it in the
+	// pool's FailureLog for the DLQ envelope.
+	Err error
+}
+
+// Ack returns a Result that acknowledges the message as processed.
+func Ack() Result { return Result{Decision: DecisionAck} }
+
+// Nak returns a Result that redelivers the message on the BackOff schedule.
+func Nak(err error) Result { return Result{Decision: DecisionNak, Err: err} }
+
+// Term returns a Result that terminates a poison message.
+func Term(err error) Result { return Result{Decision: DecisionTerm, Err: err} }
+
+// ErrPoison marks a processing error as permanent: ResultOf terminates the message
+// instead of redelivering it. Wrap it for payloads no retry can fix (e.g. malformed JSON).
+var ErrPoison = errors.New("poison message")
+
+// ResultOf maps a process error to a Result: nil acks, an error wrapping ErrPoison
+// terminates, and any other error naks for redelivery.
+func ResultOf(err error) Result {
+	switch {
+	case err == nil:
+		return Ack()
+	case errors.Is(err, ErrPoison):
+		return Term(err)
+	default:
+		return Nak(err)
+	}
+}
+
+// Handler processes one message. It must not ack, nak or term the message itself —
+// the WorkerPool settles it from the returned Result. ctx carries the nats.consume
+// span and outlives the pool's ctx by up to DrainTimeout on shutdown.
+type Handler func(ctx context.Context, msg *nats.Msg) Result
+
+// fetcher is the subset of *nats.Subscription used by WorkerPool.
+type fetcher interface {
+	Fetch(batch int, opts ...nats.PullOpt) ([]*nats.Msg, error)
+}
+
+// consumerInfoer is implemented by *nats.Subscription; an adaptive WorkerPool reads
+// the consumer's pending count through it.
+type consumerInfoer interface {
+	ConsumerInfo() (*nats.ConsumerInfo, error)
+}
+
+// WorkerPool runs a Handler over a durable pull consumer with a bounded number of
+// workers (ADR-0024). It survives NATS bounces (#18), skips DLQ replays addressed to
+// another consumer, settles each message from the handler's Result, sends in-progress heartbeats while a handler outlives half of
+// AckWait, and on shutdown drains in-flight workers before Run returns.
+//
+// With MinWorkerCount below WorkerCount, the worker limit and fetch batch adapt
+// within those bounds to the handlers' latency and error rate and the consumer's
+// pending count (see concurrency); the current values are exported as gauges.
+type WorkerPool struct {
+	sub        fetcher
+	handler    Handler
+	stream     string
+	consumer   string
+	workers    int // upper bound
+	minWorkers int // lower bound; 0 fixes the pool at workers
+	batch      int // upper bound
+	adaptEvery time.Duration
+	backOff    []time.Duration
+	heartbeat  time.Duration // in-progress interval; 0 disables
+	drain      time.Duration // DrainTimeout; shortened in tests
+	instr      *MsgInstruments
+	failures   *FailureLog
+	log        *slog.Logger
+}
+
+// NewWorkerPool returns a WorkerPool that fetches from sub and runs handler on each
+// message. cfg supplies the pool bounds (MinWorkerCount, WorkerCount, FetchBatch),
+// the nak schedule (BackOff), the heartbeat interval (half the AckWait the server
+// applies: BackOff[0] when a BackOff is set) and the Stream/Durable metric labels. instr and failures may be nil.
+func NewWorkerPool(sub *nats.Subscription, cfg PullConsumerConfig, handler Handler, instr *MsgInstruments, failures *FailureLog, log *slog.Logger) (*WorkerPool, error) {
+	if cfg.FetchBatch > cfg.WorkerCount {
+		return nil, fmt.Errorf("natsx: FetchBatch (%d) must not exceed WorkerCount (%d)",
+			cfg.FetchBatch, cfg.WorkerCount)
+	}
+	if cfg.MinWorkerCount > cfg.WorkerCount {
+		return nil, fmt.Errorf("natsx: MinWorkerCount (%d) must not exceed WorkerCount (%d)",
+			cfg.MinWorkerCount, cfg.WorkerCount)
+	}
+	return &WorkerPool{
+		sub:        sub,
+		handler:    handler,
+		stream:     cfg.Stream,
+		consumer:   cfg.Durable,
+		workers:    cfg.WorkerCount,
+		minWorkers: cfg.MinWorkerCount,
+		batch:      cfg.FetchBatch,
+		adaptEvery: config.DefaultAdaptInterval,
+		backOff:    cfg.BackOff,
+		heartbeat:  effectiveAckWait(cfg) / 2,
+		drain:      DrainTimeout,
+		instr:      instr,
+		failures:   failures,
+		log:        log,
+	}, nil
+}
+
+// logger returns p.log if set, otherwise slog.Default().
+func (p *WorkerPool) logger() *slog.Logger {
+	if p.log != nil {
+		return p.log
+	}
+	return slog.Default()
+}
+
+// Run fetches and dispatches messages until ctx is cancelled, then waits for
+// in-flight workers to settle their messages before returning. Handlers keep running
+// for up to DrainTimeout after cancellation; past that their context is cancelled
+// and Run waits for them to return. Fetch errors never end Run (#18).
+func (p *WorkerPool) Run(ctx context.Context) error {
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	conc := newConcurrency(p.minWorkers, p.workers, p.batch)
+	defer p.instr.observeLimits(p.stream, p.consumer, conc)()
+	if conc.adaptive() {
+		go p.adaptLoop(ctx, conc)
+	}
+
+	var wg sync.WaitGroup
+	p.fetchLoop(ctx, conc.batch, func(msg *nats.Msg) bool {
+		if !conc.lim.tryAcquire() {
+			conc.waited()
+			if !conc.lim.acquire(ctx) {
+				return false
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conc.lim.release()
+			p.work(workCtx, msg, conc)
+		}()
+		return true
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(p.drain):
+		p.logger().Warn("natsx: consumer: drain timed out, cancelling in-flight handlers",
+			slog.String("consumer", p.consumer))
+		cancelWork()
+		<-drained
+	}
+	return nil
+}
+
+// adaptLoop re-evaluates conc's worker limit every adaptEvery until ctx is cancelled.
+func (p *WorkerPool) adaptLoop(ctx context.Context, conc *concurrency) {
+	every := p.adaptEvery
+	if every <= 0 {
+		every = config.DefaultAdaptInterval
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		pending := p.pending()
+		if from, to := conc.adjust(pending); from != to {
+			p.logger().Debug("natsx: consumer: worker limit adjusted",
+				slog.String("consumer", p.consumer),
+				slog.Int("from", from),
+				slog.Int("to", to),
+				slog.Int64("pending", pending))
+		}
+	}
+}
+
+// pending returns the consumer's count of undelivered messages, or -1 if it cannot
+// be read.
+func (p *WorkerPool) pending() int64 {
+	ci, ok := p.sub.(consumerInfoer)
+	if !ok {
+		return -1
+	}
+	info, err := ci.ConsumerInfo()
+	if err != nil {
+		return -1
+	}
+	return int64(info.NumPending)
+}
+
+// fetchLoop fetches batches of batch() messages until ctx is cancelled, passing each
+// message to dispatch. It returns when ctx is cancelled or dispatch refuses a message.
+func (p *WorkerPool) fetchLoop(ctx context.Context, batch func() int, dispatch func(*nats.Msg) bool) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		msgs, err := p.sub.Fetch(batch(), nats.MaxWait(FetchMaxWait))
+		if err != nil {
+			// Survive a NATS bounce: only ctx cancellation exits the loop; every other
+			// fetch error is transient (nats.go reconnects underneath) so we log, back
+			// off, and resume (#18).
+			switch ClassifyFetchErr(err, ctx.Err()) {
+			case FetchStop:
+				return
+			case FetchBackoff:
+				p.logger().Warn("natsx: consumer: transient fetch error, retrying",
+					slog.String("consumer", p.consumer),
+					slog.String("error", err.Error()))
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(FetchRetryBackoff):
+				}
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			if !dispatch(msg) {
+				return
+			}
+		}
+	}
+}
+
+// work runs the handler on msg inside the shared instruments, records its latency
+// and verdict with conc, and settles the message. A DLQ replay meant for another
+// consumer is acked without running the handler: only the consumer that
+// dead-lettered it processes it again.
+func (p *WorkerPool) work(ctx context.Context, msg *nats.Msg, conc *concurrency) {
+	p.instr.Observe(ctx, msg, p.stream, p.consumer, func(sctx context.Context) string {
+		if c := msg.Header.Get(HeaderDLQConsumer); c != "" && c != p.consumer {
+			return p.settle(msg, Result{Decision: DecisionAck, Outcome: OutcomeFiltered})
+		}
+		stop := p.startHeartbeat(msg)
+		start := time.Now()
+		res := p.handler(sctx, msg)
+		conc.observe(time.Since(start), res.Decision != DecisionAck)
+		stop()
+		return p.settle(msg, res)
+	})
+}
+
+// startHeartbeat sends in-progress acks for msg every p.heartbeat until the returned
+// stop func is called, so a handler running longer than AckWait keeps its message.
+func (p *WorkerPool) startHeartbeat(msg *nats.Msg) (stop func()) {
+	if p.heartbeat <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(p.heartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				_ = msg.InProgress()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// settle acks, naks or terms msg as res decides and returns the outcome label.
+func (p *WorkerPool) settle(msg *nats.Msg, res Result) string {
+	var numDelivered uint64
+	if meta, err := msg.Metadata(); err == nil {
+		numDelivered = meta.NumDelivered
+	}
+	switch res.Decision {
+	case DecisionAck:
+		p.failures.ForgetMsg(msg)
+		_ = msg.Ack()
+	case DecisionNak:
+		p.failures.RecordMsg(msg, res.Err)
+		if d := nakDelayFor(res, p.backOff, numDelivered); d > 0 {
+			_ = msg.NakWithDelay(d)
+		} else {
+			_ = msg.Nak() // final attempt: no delay, let the max-delivery advisory fire promptly
+		}
+	case DecisionTerm:
+		p.failures.ForgetMsg(msg)
+		attrs := []any{slog.String("consumer", p.consumer), slog.String("subject", msg.Subject)}
+		if res.Err != nil {
+			attrs = append(attrs, slog.String("error", res.Err.Error()))
+		}
+		p.logger().Error("natsx: consumer: terminating poison message", attrs...)
+		_ = msg.Term()
+	}
+	return outcomeOf(res)
+}
+
+// nakDelayFor is res.Delay when set, otherwise the BackOff delay for numDelivered.
+func nakDelayFor(res Result, backOff []time.Duration, numDelivered uint64) time.Duration {
+	if res.Delay > 0 {
+		return res.Delay
+	}
+	return NakDelay(backOff, numDelivered)
+}
+
+// outcomeOf returns res.Outcome, defaulted from its decision.
+func outcomeOf(res Result) string {
+	switch {
+	case res.Outcome != "":
+		return res.Outcome
+	case res.Decision == DecisionAck:
+		return OutcomeSuccess
+	default:
+		return OutcomeFailure
+	}
+}
+
+// NakDelay returns the delay to use with NakWithDelay for the given delivery number.
+// When numDelivered exceeds the backOff schedule (i.e. final attempt), returns 0
+// so the server fires the max-delivery advisory without an extra wait.
+func NakDelay(backOff []time.Duration, numDelivered uint64) time.Duration {
+	if len(backOff) == 0 || numDelivered == 0 || numDelivered > uint64(len(backOff)) {
+		return 0
+	}
+	return backOff[numDelivered-1]
+}
@@ -0,0 +1,248 @@
+//go:build fast
+
+package natsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fakeFetcher hands out queued messages, then behaves like an idle pull consumer.
+type fakeFetcher struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (f *fakeFetcher) Fetch(batch int, _ ...nats.PullOpt) ([]*nats.Msg, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.msgs) == 0 {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nats.ErrTimeout
+	}
+	n := min(batch, len(f.msgs))
+	out := f.msgs[:n]
+	f.msgs = f.msgs[n:]
+	return out, nil
+}
+
+func newTestPool(msgs int, workers int, h Handler) *WorkerPool {
+	f := &fakeFetcher{}
+	for i := range msgs {
+		f.msgs = append(f.msgs, &nats.Msg{Subject: fmt.Sprintf("test.%d", i)})
+	}
+	return &WorkerPool{sub: f, handler: h, workers: workers, batch: workers, drain: time.Second}
+}
+
+func TestNewWorkerPool_FetchBatchExceedsWorkers(t *testing.T) {
+	cfg := DefaultPullConsumerConfig("S", "c", "s.>")
+	cfg.FetchBatch = cfg.WorkerCount + 1
+	if _, err := NewWorkerPool(nil, cfg, nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error when FetchBatch > WorkerCount, got nil")
+	}
+}
+
+func TestNewWorkerPool_MinWorkersExceedsWorkers(t *testing.T) {
+	cfg := DefaultPullConsumerConfig("S", "c", "s.>")
+	cfg.MinWorkerCount = cfg.WorkerCount + 1
+	if _, err := NewWorkerPool(nil, cfg, nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error when MinWorkerCount > WorkerCount, got nil")
+	}
+}
+
+// TestNewWorkerPool_HeartbeatWithinBackOff verifies in-progress heartbeats are paced
+// by the AckWait the server applies, BackOff[0] when a BackOff is set, rather than the
+// configured AckWait, which would let a slow handler's message be redelivered.
+func TestNewWorkerPool_HeartbeatWithinBackOff(t *testing.T) {
+	cfg := DefaultPullConsumerConfig("S", "c", "s.>")
+	p, err := NewWorkerPool(nil, cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cfg.BackOff[0] / 2; p.heartbeat != want {
+		t.Errorf("heartbeat = %v, want %v (BackOff[0]/2)", p.heartbeat, want)
+	}
+
+	cfg.BackOff = nil
+	p, err = NewWorkerPool(nil, cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cfg.AckWait / 2; p.heartbeat != want {
+		t.Errorf("heartbeat without BackOff = %v, want %v (AckWait/2)", p.heartbeat, want)
+	}
+}
+
+func TestWorkerPool_BoundsConcurrency(t *testing.T) {
+	var running, peak, handled atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTestPool(12, 3, func(context.Context, *nats.Msg) Result {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		if handled.Add(1) == 12 {
+			cancel()
+		}
+		return Ack()
+	})
+
+	if err := p.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := handled.Load(); got != 12 {
+		t.Errorf("handled %d messages, want 12", got)
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("%d handlers ran at once, want at most 3 workers", got)
+	}
+}
+
+// TestWorkerPool_DLQReplayOnlyForItsConsumer verifies two consumers of one stream,
+// handed the same DLQ replay, run only the handler of the consumer that dead-lettered
+// it; the other acks it unprocessed. Both handle an ordinary message.
+func TestWorkerPool_DLQReplayOnlyForItsConsumer(t *testing.T) {
+	replay := nats.NewMsg("ha.events.light.porch")
+	replay.Header.Set(HeaderDLQConsumer, "engine_rules")
+	plain := nats.NewMsg("ha.events.light.porch")
+
+	ran := map[string]int{}
+	for _, consumer := range []string{"engine_rules", "engine_presence"} {
+		p := &WorkerPool{consumer: consumer, handler: func(context.Context, *nats.Msg) Result {
+			ran[consumer]++
+			return Ack()
+		}}
+		conc := newConcurrency(1, 1, 1)
+		p.work(context.Background(), replay, conc)
+		p.work(context.Background(), plain, conc)
+	}
+	if ran["engine_rules"] != 2 || ran["engine_presence"] != 1 {
+		t.Errorf("handler runs = %v, want engine_rules 2 (the replay and the message), engine_presence 1", ran)
+	}
+}
+
+func TestWorkerPool_RunDrainsInFlightWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var finished atomic.Bool
+	p := newTestPool(1, 1, func(hctx context.Context, _ *nats.Msg) Result {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		if hctx.Err() != nil {
+			t.Error("handler context cancelled during the drain, want it to outlive Run's ctx")
+		}
+		finished.Store(true)
+		return Ack()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		_ = p.Run(ctx)
+		close(done)
+	}()
+	<-started
+	cancel()
+	<-done
+	if !finished.Load() {
+		t.Error("Run returned while a handler was still in flight")
+	}
+}
+
+func TestWorkerPool_DrainTimeoutCancelsHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	p := newTestPool(1, 1, func(hctx context.Context, _ *nats.Msg) Result {
+		close(started)
+		<-hctx.Done() // a handler that only stops when cancelled
+		return Nak(hctx.Err())
+	})
+	p.drain = 20 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		_ = p.Run(ctx)
+		close(done)
+	}()
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the drain timeout")
+	}
+}
+
+func TestResultOf(t *testing.T) {
+	poison := fmt.Errorf("%w: unmarshal: bad json", ErrPoison)
+	cases := []struct {
+		err  error
+		want Decision
+	}{
+		{nil, DecisionAck},
+		{errors.New("ha unreachable"), DecisionNak},
+		{poison, DecisionTerm},
+	}
+	for _, tc := range cases {
+		if got := ResultOf(tc.err); got.Decision != tc.want || !errors.Is(got.Err, tc.err) {
+			t.Errorf("ResultOf(%v) = %+v, want decision %d carrying the error", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestOutcomeOf(t *testing.T) {
+	cases := []struct {
+		res  Result
+		want string
+	}{
+		{Ack(), OutcomeSuccess},
+		{Nak(errors.New("x")), OutcomeFailure},
+		{Term(errors.New("x")), OutcomeFailure},
+		{Result{Decision: DecisionAck, Outcome: OutcomeDuplicate}, OutcomeDuplicate},
+		{Result{Decision: DecisionNak, Outcome: OutcomeInFlight}, OutcomeInFlight},
+	}
+	for _, tc := range cases {
+		if got := outcomeOf(tc.res); got != tc.want {
+			t.Errorf("outcomeOf(%+v) = %q, want %q", tc.res, got, tc.want)
+		}
+	}
+}
+
+func TestNakDelay(t *testing.T) {
+	backOff := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	cases := []struct {
+		numDelivered uint64
+		want         time.Duration
+	}{
+		{1, 1 * time.Second}, // first attempt → 1s delay before retry
+		{2, 2 * time.Second}, // second attempt → 2s
+		{3, 4 * time.Second}, // third → 4s
+		{4, 8 * time.Second}, // fourth → 8s
+		{5, 0},               // fifth (final, MaxDeliver=5) → no delay, advisory fires promptly
+		{6, 0},               // beyond schedule → no delay
+	}
+	for _, tc := range cases {
+		got := NakDelay(backOff, tc.numDelivered)
+		if got != tc.want {
+			t.Errorf("NakDelay(backOff, %d) = %v, want %v", tc.numDelivered, got, tc.want)
+		}
+	}
+	if d := NakDelay(nil, 3); d != 0 {
+		t.Errorf("NakDelay(nil, 3) = %v, want 0", d)
+	}
+	if d := nakDelayFor(Result{Delay: 30 * time.Second}, backOff, 1); d != 30*time.Second {
+		t.Errorf("nakDelayFor with an explicit Delay = %v, want 30s", d)
+	}
+}
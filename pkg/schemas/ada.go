@@ -61,7 +61,7 @@ const (
 // AdaDeleteData is the payload for every ada.<area>.delete event — a single id.
 // Also used by ada.medication.delete and ada.medication.routine.delete.
 type AdaDeleteData struct {
-	ID       string `json:"id"`
+	ID       string `json:"id" schema:"required"`
 	LoggedBy string `json:"logged_by,omitempty"`
 }
 
@@ -70,7 +70,7 @@ type AdaDeleteData struct {
 // routine). min_interval_hours and max_per_24h are nullable, so pointers distinguish
 // "no limit" from zero.
 type AdaMedicationUpsertData struct {
-	ID               string   `json:"id"`
+	ID               string   `json:"id" schema:"required"`
 	Name             string   `json:"name"`
 	Route            string   `json:"route"`        // oral|drops|topical|suppository
 	MeasureUnit      string   `json:"measure_unit"` // mL|mg|drops|supp
@@ -172,7 +172,7 @@ type AdaEmergencyReorderData struct {
 // Breast timing is in seconds; bottle amounts are in oz. The source is re-derived
 // from which fields are non-zero, exactly as a fresh submission would be.
 type AdaFeedingUpdateData struct {
-	ID           string  `json:"id"`
+	ID           string  `json:"id" schema:"required"`
 	StartTime    string  `json:"start_time"`
 	LeftBreastS  int     `json:"left_breast_s,omitempty"`
 	RightBreastS int     `json:"right_breast_s,omitempty"`
@@ -183,7 +183,7 @@ type AdaFeedingUpdateData struct {
 
 // AdaDiaperUpdateData replaces a diaper event by id. Type ∈ wet | dirty | mixed.
 type AdaDiaperUpdateData struct {
-	ID        string `json:"id"`
+	ID        string `json:"id" schema:"required"`
 	Type      string `json:"type"`
 	Timestamp string `json:"timestamp"`
 	LoggedBy  string `json:"logged_by,omitempty"`
@@ -191,7 +191,7 @@ type AdaDiaperUpdateData struct {
 
 // AdaSleepUpdateData replaces a sleep session by id. SleepType ∈ nap | night.
 type AdaSleepUpdateData struct {
-	ID        string `json:"id"`
+	ID        string `json:"id" schema:"required"`
 	StartTime string `json:"start_time"`
 	EndTime   string `json:"end_time"`
 	DurationS int    `json:"duration_s"`
@@ -201,7 +201,7 @@ type AdaSleepUpdateData struct {
 
 // AdaTummyUpdateData replaces a tummy time session by id.
 type AdaTummyUpdateData struct {
-	ID        string `json:"id"`
+	ID        string `json:"id" schema:"required"`
 	StartTime string `json:"start_time"`
 	EndTime   string `json:"end_time"`
 	DurationS int    `json:"duration_s"`
@@ -211,7 +211,7 @@ type AdaTummyUpdateData struct {
 // AdaGrowthUpdateData replaces a growth measurement by id. Optional metric fields
 // use pointers — nil means "not present" (cleared). Percentiles are recomputed.
 type AdaGrowthUpdateData struct {
-	ID                  string   `json:"id"`
+	ID                  string   `json:"id" schema:"required"`
 	WeightOz            *float64 `json:"weight_oz,omitempty"`
 	LengthIn            *float64 `json:"length_in,omitempty"`
 	HeadCircumferenceIn *float64 `json:"head_circumference_in,omitempty"`
@@ -0,0 +1,143 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CloudEvents binary content mode (CloudEvents NATS protocol binding): the context
+// attributes travel as ce-* message headers and the body is the event data alone,
+// so a consumer can read the id and correlation extensions without parsing the
+// body. Structured mode (the whole CloudEvent as the JSON body) remains valid;
+// consumers accept both.
+const (
+	HeaderSpecVersion   = "ce-specversion"
+	HeaderID            = "ce-id"
+	HeaderSource        = "ce-source"
+	HeaderType          = "ce-type"
+	HeaderTime          = "ce-time"
+	HeaderDataSchema    = "ce-dataschema"
+	HeaderSubject       = "ce-subject"
+	HeaderCorrelationID = "ce-" + ExtensionCorrelationID
+	HeaderCausationID   = "ce-" + ExtensionCausationID
+
+	// HeaderContentType is the media type of a binary-mode body. Data is always JSON.
+	HeaderContentType = "content-type"
+	ContentTypeJSON   = "application/json"
+)
+
+// Headers is the message header access binary mode needs. nats.Header implements it;
+// keeping it an interface keeps this package free of the NATS client.
+type Headers interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// IsBinary reports whether h carries a binary-mode CloudEvent. A nil h does not.
+func IsBinary(h Headers) bool {
+	return h != nil && h.Get(HeaderSpecVersion) != ""
+}
+
+// EncodeBinary writes e's attributes to h as ce-* headers and returns the body: e.Data
+// as JSON, or nil when e has no data. Empty optional attributes are omitted.
+func EncodeBinary(e CloudEvent, h Headers) ([]byte, error) {
+	for _, a := range []struct{ key, value string }{
+		{HeaderSpecVersion, e.SpecVersion},
+		{HeaderID, e.ID},
+		{HeaderSource, e.Source},
+		{HeaderType, e.Type},
+		{HeaderTime, e.Time},
+		{HeaderDataSchema, e.DataSchema},
+		{HeaderSubject, e.Subject},
+		{HeaderCorrelationID, e.CorrelationID},
+		{HeaderCausationID, e.CausationID},
+	} {
+		if a.value != "" {
+			h.Set(a.key, a.value)
+		}
+	}
+	if e.SpecVersion == "" {
+		h.Set(HeaderSpecVersion, CloudEventsSpecVersion)
+	}
+	if e.Data == nil {
+		return nil, nil
+	}
+	h.Set(HeaderContentType, ContentTypeJSON)
+	body, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("schemas: marshal CloudEvent data: %w", err)
+	}
+	return body, nil
+}
+
+// DecodeEvent decodes a CloudEvent from a message in either content mode.
+func DecodeEvent(h Headers, body []byte) (CloudEvent, error) {
+	var e CloudEvent
+	if !IsBinary(h) {
+		err := json.Unmarshal(body, &e)
+		return e, err
+	}
+	e = binaryAttributes(h)
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &e.Data); err != nil {
+			return CloudEvent{}, fmt.Errorf("schemas: binary CloudEvent data: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// StructuredJSON returns a message's CloudEvent in structured mode. A structured-mode
+// body is returned as is; a binary-mode message is rebuilt into an envelope around
+// its body without decoding the data, so code written against structured events can
+// consume both modes. A binary body that is not JSON is an ErrInvalidEvent.
+func StructuredJSON(h Headers, body []byte) ([]byte, error) {
+	if !IsBinary(h) {
+		return body, nil
+	}
+	if ct := h.Get(HeaderContentType); ct != "" && ct != ContentTypeJSON {
+		return nil, fmt.Errorf("%w: binary CloudEvent has unsupported content-type %q", ErrInvalidEvent, ct)
+	}
+	env := struct {
+		CloudEvent
+		Data json.RawMessage `json:"data,omitempty"`
+	}{CloudEvent: binaryAttributes(h)}
+	if len(body) > 0 {
+		if !json.Valid(body) {
+			return nil, fmt.Errorf("%w: binary CloudEvent data is not JSON", ErrInvalidEvent)
+		}
+		env.Data = body
+	}
+	return json.Marshal(env)
+}
+
+// ValidateBinary validates a binary-mode CloudEvent as Validate does, reading its
+// type, id and dataschema from the ce-* headers. The body is decoded only when
+// the type has a registered schema, and then straight into that schema.
+func ValidateBinary(h Headers, body []byte) error {
+	eventType := h.Get(HeaderType)
+	if !Registered(eventType) {
+		return nil
+	}
+	if ct := h.Get(HeaderContentType); ct != "" && ct != ContentTypeJSON {
+		return fmt.Errorf("%w: binary CloudEvent has unsupported content-type %q", ErrInvalidEvent, ct)
+	}
+	if len(body) == 0 {
+		body = []byte("null")
+	}
+	return validateData(eventType, h.Get(HeaderID), h.Get(HeaderDataSchema), body)
+}
+
+// binaryAttributes reads the context attributes of a binary-mode CloudEvent.
+func binaryAttributes(h Headers) CloudEvent {
+	return CloudEvent{
+		SpecVersion:   h.Get(HeaderSpecVersion),
+		ID:            h.Get(HeaderID),
+		Source:        h.Get(HeaderSource),
+		Type:          h.Get(HeaderType),
+		Time:          h.Get(HeaderTime),
+		DataSchema:    h.Get(HeaderDataSchema),
+		Subject:       h.Get(HeaderSubject),
+		CorrelationID: h.Get(HeaderCorrelationID),
+		CausationID:   h.Get(HeaderCausationID),
+	}
+}
@@ -0,0 +1,136 @@
+//go:build fast
+
+package schemas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	in := CloudEvent{
+		ID:            "e1",
+		Source:        CommandSource,
+		Type:          CommandTypeNotify,
+		Time:          "2026-10-17T08:00:00Z",
+		DataSchema:    "1.0",
+		CorrelationID: "corr-1",
+		CausationID:   "cause-1",
+		Data:          map[string]any{"title": "Door", "device": "phone"},
+	}
+	h := http.Header{}
+	body, err := EncodeBinary(in, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsBinary(h) || h.Get(HeaderSpecVersion) != CloudEventsSpecVersion {
+		t.Errorf("%s = %q, want the default spec version", HeaderSpecVersion, h.Get(HeaderSpecVersion))
+	}
+	if h.Get(HeaderContentType) != ContentTypeJSON {
+		t.Errorf("%s = %q", HeaderContentType, h.Get(HeaderContentType))
+	}
+	if h.Get(HeaderSubject) != "" {
+		t.Errorf("%s = %q, want empty attributes omitted", HeaderSubject, h.Get(HeaderSubject))
+	}
+	if string(body) != `{"device":"phone","title":"Door"}` {
+		t.Errorf("body = %s, want the data alone", body)
+	}
+
+	out, err := DecodeEvent(h, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.SpecVersion = CloudEventsSpecVersion
+	if out.ID != in.ID || out.Type != in.Type || out.CorrelationID != in.CorrelationID ||
+		out.CausationID != in.CausationID || out.Data["title"] != "Door" {
+		t.Errorf("DecodeEvent = %+v, want %+v", out, in)
+	}
+}
+
+func TestStructuredJSON(t *testing.T) {
+	structured := []byte(`{"specversion":"1.0","id":"e1"}`)
+	if got, err := StructuredJSON(nil, structured); err != nil || string(got) != string(structured) {
+		t.Errorf("structured = %s, %v; want the body unchanged", got, err)
+	}
+
+	h := http.Header{}
+	body, _ := EncodeBinary(CloudEvent{ID: "e2", Type: AdaEventDiaperDelete, Data: map[string]any{"id": "d1"}}, h)
+	got, err := StructuredJSON(h, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evt CloudEvent
+	if err := json.Unmarshal(got, &evt); err != nil {
+		t.Fatal(err)
+	}
+	if evt.ID != "e2" || evt.Type != AdaEventDiaperDelete || evt.Data["id"] != "d1" {
+		t.Errorf("envelope = %s", got)
+	}
+	if _, err := ValidateJSON(got); err != nil {
+		t.Errorf("rebuilt envelope did not validate: %v", err)
+	}
+
+	noData := http.Header{}
+	noData.Set(HeaderSpecVersion, CloudEventsSpecVersion)
+	noData.Set(HeaderID, "e3")
+	got, err = StructuredJSON(noData, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["data"]; ok || fields["id"] != "e3" {
+		t.Errorf("no data = %s, want id e3 and no data member", got)
+	}
+}
+
+func TestStructuredJSON_Invalid(t *testing.T) {
+	h := http.Header{}
+	h.Set(HeaderSpecVersion, CloudEventsSpecVersion)
+	if _, err := StructuredJSON(h, []byte("not-json")); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("non-JSON body: err = %v, want ErrInvalidEvent", err)
+	}
+	h.Set(HeaderContentType, "application/cbor")
+	if _, err := StructuredJSON(h, []byte("{}")); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("cbor body: err = %v, want ErrInvalidEvent", err)
+	}
+}
+
+func TestValidateBinary(t *testing.T) {
+	binary := func(typ string, body string) (http.Header, []byte) {
+		h := http.Header{}
+		h.Set(HeaderSpecVersion, CloudEventsSpecVersion)
+		h.Set(HeaderID, "e1")
+		h.Set(HeaderType, typ)
+		return h, []byte(body)
+	}
+	cases := []struct {
+		name  string
+		typ   string
+		body  string
+		valid bool
+	}{
+		{"valid", CommandTypeNotify, `{"device":"pixel"}`, true},
+		{"missing required field", CommandTypeNotify, `{"title":"x"}`, false},
+		{"no body with required field", CommandTypeNotify, ``, false},
+		{"wrong field type", AdaEventSleepEnded, `{"duration_s":"an hour"}`, false},
+		{"body not JSON", CommandTypeNotify, `not json`, false},
+		{"unregistered type body not read", "ha.events.light.kitchen", `not json`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, body := binary(tc.typ, tc.body)
+			err := ValidateBinary(h, body)
+			if tc.valid && err != nil {
+				t.Errorf("ValidateBinary = %v, want nil", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidEvent) {
+				t.Errorf("ValidateBinary = %v, want ErrInvalidEvent", err)
+			}
+		})
+	}
+}
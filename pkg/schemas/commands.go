@@ -0,0 +1,85 @@
+package schemas
+
+import "time"
+
+// Command CloudEvents are published by the engine on
+// ruby_engine.commands.{action}.{id}, one per executed rule action. Their
+// correlationid/causationid link each command to the event that triggered it,
+// so the audit trail connects cause and effect.
+const (
+	// CommandSource is the CloudEvents "source" of every engine command.
+	CommandSource = "ruby_engine"
+
+	// CommandSubjectPrefix prefixes every command subject; the COMMANDS stream
+	// captures CommandSubjectPrefix + ">".
+	CommandSubjectPrefix = "ruby_engine.commands."
+
+	CommandTypeNotify    = "command.notify"
+	CommandTypeHAService = "command.ha_service"
+	CommandTypePublish   = "command.publish"
+	CommandTypeKVSet     = "command.kv_set"
+	CommandTypeDelay     = "command.delay"
+)
+
+// CommandNotifyData is the payload of a command.notify command: a push notification
+// for the notifier to deliver to one mobile_app device. Rule names the rule (or
+// processor) that sent it.
+type CommandNotifyData struct {
+	Rule    string `json:"rule,omitempty"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Device  string `json:"device" schema:"required"`
+}
+
+// CommandHAServiceData is the payload of a command.ha_service command: a Home
+// Assistant service call for the gateway to run over its WebSocket. EntityID is
+// shorthand for an entity_id in Target; Target may also name device_id or area_id.
+// ServiceData is passed to the service unchanged.
+type CommandHAServiceData struct {
+	Rule        string         `json:"rule,omitempty"`
+	Domain      string         `json:"domain" schema:"required"`
+	Service     string         `json:"service" schema:"required"`
+	EntityID    string         `json:"entity_id,omitempty"`
+	Target      map[string]any `json:"target,omitempty"`
+	ServiceData map[string]any `json:"service_data,omitempty"`
+}
+
+// HAServiceResult is the type and subject of the event the gateway publishes for each
+// command.ha_service it runs. Its causationid is the command's id and its
+// correlationid the command's, so the result joins the chain that sent the command.
+const HAServiceResult = "ha.events.ha_service.result"
+
+// HAServiceResultData is the payload of an HAServiceResult event. Error and
+// ErrorCode carry Home Assistant's refusal when Success is false, or the gateway's
+// own: "expired" for a command too old to run, "result_unknown" for a call HA did
+// not answer and may have run.
+type HAServiceResultData struct {
+	CommandID string `json:"command_id" schema:"required"`
+	Rule      string `json:"rule,omitempty"`
+	Domain    string `json:"domain" schema:"required"`
+	Service   string `json:"service" schema:"required"`
+	EntityID  string `json:"entity_id,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// NewCommand constructs a command CloudEvent of type typ caused by cause.
+// The correlation ID is inherited from cause (falling back to its ID when the
+// cause starts a new chain); the causation ID is cause's own ID.
+func NewCommand(id, typ string, cause CloudEvent, data map[string]any) CloudEvent {
+	corrID := cause.CorrelationID
+	if corrID == "" {
+		corrID = cause.ID
+	}
+	return CloudEvent{
+		SpecVersion:   CloudEventsSpecVersion,
+		ID:            id,
+		Source:        CommandSource,
+		Type:          typ,
+		Time:          time.Now().UTC().Format(time.RFC3339),
+		CorrelationID: corrID,
+		CausationID:   cause.ID,
+		Data:          data,
+	}
+}
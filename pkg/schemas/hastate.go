@@ -0,0 +1,43 @@
+package schemas
+
+import "reflect"
+
+// DataOldState is the member of an HA state event's data (ha.events.{domain}.{entity})
+// carrying the entity's previous state, projected like the event itself: "state",
+// "last_changed" and the passlisted attributes. It is absent when HA reported no
+// previous state (a new entity) and on reconciled events, whose previous state the
+// gateway never saw.
+const DataOldState = "old_state"
+
+// OldState returns the previous state carried by an HA state event, if any.
+func OldState(evt CloudEvent) (map[string]any, bool) {
+	old, ok := evt.Data[DataOldState].(map[string]any)
+	return old, ok
+}
+
+// Transition is the change an HA state event made to one member of its data.
+type Transition struct {
+	From, To any // From is nil when the previous state lacked the member
+	// Known is false when the event carries no previous state: whether the member
+	// changed cannot be told from the event alone.
+	Known bool
+}
+
+// Changed reports whether the event is known to have changed the member.
+func (t Transition) Changed() bool {
+	return t.Known && !reflect.DeepEqual(t.From, t.To)
+}
+
+// StateTransition returns the change evt made to field ("state" when empty), read
+// from the event's own previous state, so a processor can detect transitions
+// without keeping a copy of each entity's last state.
+func StateTransition(evt CloudEvent, field string) Transition {
+	if field == "" {
+		field = "state"
+	}
+	t := Transition{To: evt.Data[field]}
+	if old, ok := OldState(evt); ok {
+		t.From, t.Known = old[field], true
+	}
+	return t
+}
@@ -0,0 +1,30 @@
+//go:build fast
+
+package schemas
+
+import "testing"
+
+func TestStateTransition(t *testing.T) {
+	evt := func(data map[string]any) CloudEvent { return CloudEvent{Data: data} }
+	cases := []struct {
+		name         string
+		evt          CloudEvent
+		field        string
+		known, moved bool
+		from         any
+	}{
+		{"state changed", evt(map[string]any{"state": "home", DataOldState: map[string]any{"state": "not_home"}}), "", true, true, "not_home"},
+		{"attribute-only update", evt(map[string]any{"state": "home", DataOldState: map[string]any{"state": "home"}}), "state", true, false, "home"},
+		{"attribute changed", evt(map[string]any{"brightness": 255.0, DataOldState: map[string]any{"brightness": 128.0}}), "brightness", true, true, 128.0},
+		{"attribute appeared", evt(map[string]any{"brightness": 255.0, DataOldState: map[string]any{}}), "brightness", true, true, nil},
+		{"no previous state", evt(map[string]any{"state": "home"}), "", false, false, nil},
+		{"reconciled", evt(map[string]any{"state": "home", "reconciled": true}), "", false, false, nil},
+	}
+	for _, tc := range cases {
+		tr := StateTransition(tc.evt, tc.field)
+		if tr.Known != tc.known || tr.Changed() != tc.moved || tr.From != tc.from {
+			t.Errorf("%s: %+v, Changed() = %v; want known %v, changed %v, from %v",
+				tc.name, tr, tr.Changed(), tc.known, tc.moved, tc.from)
+		}
+	}
+}
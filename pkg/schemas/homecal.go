@@ -76,7 +76,7 @@ type CalendarDeleteData struct {
 // (create when ID absent, else update). Local overlay only (Slice D).
 type ChildcareProviderUpsertData struct {
 	ID           string  `json:"id,omitempty"`
-	DisplayName  string  `json:"display_name"`
+	DisplayName  string  `json:"display_name" schema:"required"`
 	PersonID     *string `json:"person_id,omitempty"`
 	Relationship *string `json:"relationship,omitempty"`
 	Archived     bool    `json:"archived,omitempty"`
@@ -85,7 +85,7 @@ type ChildcareProviderUpsertData struct {
 // ChildcareProviderDeleteData is the payload of ruby_home.childcare.provider.delete
 // (delete = archive, preserving frequency history).
 type ChildcareProviderDeleteData struct {
-	ID string `json:"id"`
+	ID string `json:"id" schema:"required"`
 }
 
 // DirectoryPersonUpsertData is the payload of ruby_home.directory.person.upsert
@@ -94,7 +94,7 @@ type ChildcareProviderDeleteData struct {
 // person object, so omitted fields are written as their zero/empty value.
 type DirectoryPersonUpsertData struct {
 	ID               string `json:"id,omitempty"`
-	DisplayName      string `json:"display_name"`
+	DisplayName      string `json:"display_name" schema:"required"`
 	Kind             string `json:"kind,omitempty"`                // "person" (default) | "group"
 	Email            string `json:"email,omitempty"`               // primary address; reconciles attendees
 	Family           string `json:"family,omitempty"`              // optional family grouping
@@ -105,5 +105,5 @@ type DirectoryPersonUpsertData struct {
 // DirectoryPersonDeleteData is the payload of ruby_home.directory.person.delete
 // (delete = deactivate; the row is retained so historical associations resolve).
 type DirectoryPersonDeleteData struct {
-	ID string `json:"id"`
+	ID string `json:"id" schema:"required"`
 }
@@ -0,0 +1,182 @@
+package schemas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidEvent marks a CloudEvent whose data does not match the schema registered
+// for its type and dataschema version. No retry can fix it, so consumers route it to
+// rejected.{subject} instead of redelivering it.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// schemaKey identifies one registered payload: a CloudEvent type at one dataschema
+// MAJOR version. MINOR versions are additive (ADR-0014) and share their major's struct.
+type schemaKey struct {
+	eventType string
+	major     int
+}
+
+// registry maps each registered CloudEvent type and dataschema MAJOR version to the Go
+// struct that defines its data (ADR-0014: the struct is the schema). A breaking change
+// registers the new struct under the next major alongside the old one.
+var registry = make(map[schemaKey]reflect.Type)
+
+func init() {
+	for eventType, proto := range map[string]any{
+		AdaEventFeedingEnded:            AdaFeedingEndedData{},
+		AdaEventFeedingLogged:           AdaFeedingLoggedData{},
+		AdaEventFeedingSupplemented:     AdaFeedingSupplementData{},
+		AdaEventFeedingLoggedPast:       AdaFeedingLoggedPastData{},
+		AdaEventFeedingClaimed:          AdaFeedingClaimedData{},
+		AdaEventDiaperLogged:            AdaDiaperLoggedData{},
+		AdaEventSleepStarted:            AdaSleepStartedData{},
+		AdaEventSleepEnded:              AdaSleepEndedData{},
+		AdaEventSleepLogged:             AdaSleepLoggedData{},
+		AdaEventTummyEnded:              AdaTummyEndedData{},
+		AdaEventTummyLogged:             AdaTummyLoggedData{},
+		AdaEventGrowthLogged:            AdaGrowthLoggedData{},
+		AdaEventBorn:                    AdaBornData{},
+		AdaEventUsersSynced:             AdaUsersSyncedData{},
+		AdaEventCaretakerUpdate:         AdaCaretakerUpdateData{},
+		AdaEventAddChannel:              AdaAddChannelData{},
+		AdaEventRemoveChannel:           AdaRemoveChannelData{},
+		AdaEventTummyTarget:             AdaTummyTargetData{},
+		AdaEventBedtimeConfig:           AdaBedtimeConfigData{},
+		AdaEventTrendsQuery:             AdaTrendsQueryData{},
+		AdaEventFeedingUpdate:           AdaFeedingUpdateData{},
+		AdaEventFeedingDelete:           AdaDeleteData{},
+		AdaEventDiaperUpdate:            AdaDiaperUpdateData{},
+		AdaEventDiaperDelete:            AdaDeleteData{},
+		AdaEventSleepUpdate:             AdaSleepUpdateData{},
+		AdaEventSleepDelete:             AdaDeleteData{},
+		AdaEventTummyUpdate:             AdaTummyUpdateData{},
+		AdaEventTummyDelete:             AdaDeleteData{},
+		AdaEventGrowthUpdate:            AdaGrowthUpdateData{},
+		AdaEventGrowthDelete:            AdaDeleteData{},
+		AdaEventMedicationUpsert:        AdaMedicationUpsertData{},
+		AdaEventMedicationDelete:        AdaDeleteData{},
+		AdaEventMedicationRoutineUpsert: AdaMedicationRoutineUpsertData{},
+		AdaEventMedicationRoutineDelete: AdaDeleteData{},
+		AdaEventMedicationGiven:         AdaMedicationEventData{},
+		AdaEventMedicationSkipped:       AdaMedicationEventData{},
+		AdaEventMedicationSeriesStart:   AdaMedicationSeriesStartData{},
+		AdaEventMedicationSeriesEnd:     AdaMedicationSeriesEndData{},
+		AdaEventMedicationEventUpdate:   AdaMedicationEventUpdateData{},
+		AdaEventMedicationEventDelete:   AdaDeleteData{},
+		AdaEventEmergencyRowUpsert:      AdaEmergencyRowUpsertData{},
+		AdaEventEmergencyRowDelete:      AdaDeleteData{},
+		AdaEventEmergencyReorder:        AdaEmergencyReorderData{},
+
+		HomeEventCalendarUpsert:          CalendarUpsertData{},
+		HomeEventCalendarDelete:          CalendarDeleteData{},
+		HomeEventChildcareProviderUpsert: ChildcareProviderUpsertData{},
+		HomeEventChildcareProviderDelete: ChildcareProviderDeleteData{},
+		HomeEventDirectoryPersonUpsert:   DirectoryPersonUpsertData{},
+		HomeEventDirectoryPersonDelete:   DirectoryPersonDeleteData{},
+
+		CommandTypeNotify:    CommandNotifyData{},
+		CommandTypeHAService: CommandHAServiceData{},
+		HAServiceResult:      HAServiceResultData{},
+	} {
+		registry[schemaKey{eventType, 1}] = reflect.TypeOf(proto)
+	}
+}
+
+// Registered reports whether eventType has a data schema at any version.
+func Registered(eventType string) bool {
+	for k := range registry {
+		if k.eventType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DataSchemaMajor returns the MAJOR version of a dataschema attribute ("1.0" → 1,
+// "2.1" → 2). An absent dataschema is read as 1.x: the first contracts were published
+// before producers set it.
+func DataSchemaMajor(dataschema string) (int, error) {
+	if dataschema == "" {
+		return 1, nil
+	}
+	majorStr, _, _ := strings.Cut(dataschema, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 1 {
+		return 0, fmt.Errorf("dataschema %q is not a MAJOR.MINOR version", dataschema)
+	}
+	return major, nil
+}
+
+// Validate checks evt's data against the schema registered for its type and dataschema
+// MAJOR version: every field must decode into the registered struct's type and every
+// field tagged `schema:"required"` must be present and non-zero. Unknown fields are
+// ignored, as ADR-0014 requires of consumers. Events of unregistered types pass
+// unchecked. A failure wraps ErrInvalidEvent.
+func Validate(evt CloudEvent) error {
+	if !Registered(evt.Type) {
+		return nil
+	}
+	b, err := json.Marshal(evt.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %s data: %v", ErrInvalidEvent, evt.Type, err)
+	}
+	return validateData(evt.Type, evt.ID, evt.DataSchema, b)
+}
+
+// validateData checks the JSON data of an event of the registered eventType, as
+// Validate describes.
+func validateData(eventType, id, dataschema string, data []byte) error {
+	if id == "" {
+		return fmt.Errorf("%w: %s has no id", ErrInvalidEvent, eventType)
+	}
+	major, err := DataSchemaMajor(dataschema)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEvent, eventType, err)
+	}
+	t, ok := registry[schemaKey{eventType, major}]
+	if !ok {
+		return fmt.Errorf("%w: %s has no schema for dataschema %s", ErrInvalidEvent, eventType, dataschema)
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return fmt.Errorf("%w: %s data: %v", ErrInvalidEvent, eventType, err)
+	}
+	if field := missingRequired(v.Elem()); field != "" {
+		return fmt.Errorf("%w: %s data: missing required field %q", ErrInvalidEvent, eventType, field)
+	}
+	return nil
+}
+
+// ValidateJSON decodes a CloudEvent and validates it. A payload that is not a
+// CloudEvent at all is invalid too.
+func ValidateJSON(data []byte) (CloudEvent, error) {
+	var evt CloudEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return CloudEvent{}, fmt.Errorf("%w: not a CloudEvent: %v", ErrInvalidEvent, err)
+	}
+	return evt, Validate(evt)
+}
+
+// missingRequired returns the JSON name of the first `schema:"required"` field of v
+// that holds its zero value, or "" if there is none.
+func missingRequired(v reflect.Value) string {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Tag.Get("schema") != "required" || !v.Field(i).IsZero() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		return name
+	}
+	return ""
+}
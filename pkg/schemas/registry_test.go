@@ -0,0 +1,71 @@
+//go:build fast
+
+package schemas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	event := func(typ, dataschema string, data map[string]any) CloudEvent {
+		return CloudEvent{SpecVersion: CloudEventsSpecVersion, ID: "e1", Type: typ, DataSchema: dataschema, Data: data}
+	}
+	cases := []struct {
+		name  string
+		evt   CloudEvent
+		valid bool
+	}{
+		{"valid", event(AdaEventSleepEnded, "1.0", map[string]any{"end_time": "2026-05-01T07:00:00Z", "duration_s": 3600}), true},
+		{"unknown fields ignored", event(AdaEventDiaperLogged, "1.0", map[string]any{"event": "ada.diaper.log", "type": "wet", "extra": true}), true},
+		{"minor version accepted", event(AdaEventDiaperLogged, "1.3", map[string]any{"type": "wet"}), true},
+		{"absent dataschema read as 1.x", event(AdaEventDiaperLogged, "", map[string]any{"type": "wet"}), true},
+		{"unregistered type unchecked", event("ha.events.light.kitchen", "", map[string]any{"state": 1}), true},
+		{"wrong field type", event(AdaEventSleepEnded, "1.0", map[string]any{"duration_s": "an hour"}), false},
+		{"missing required id", event(AdaEventFeedingDelete, "1.0", map[string]any{"logged_by": "x"}), false},
+		{"no data with required field", event(CommandTypeNotify, "", nil), false},
+		{"empty required string", event(CommandTypeNotify, "", map[string]any{"device": ""}), false},
+		{"ha_service command", event(CommandTypeHAService, "", map[string]any{"domain": "light", "service": "turn_on", "target": map[string]any{"area_id": "porch"}}), true},
+		{"ha_service without service", event(CommandTypeHAService, "", map[string]any{"domain": "light"}), false},
+		{"unknown major version", event(AdaEventDiaperLogged, "2.0", map[string]any{"type": "wet"}), false},
+		{"malformed dataschema", event(AdaEventDiaperLogged, "v1", map[string]any{"type": "wet"}), false},
+		{"registered type without id", CloudEvent{Type: AdaEventDiaperLogged, Data: map[string]any{"type": "wet"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.evt)
+			if tc.valid && err != nil {
+				t.Errorf("Validate = %v, want nil", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidEvent) {
+				t.Errorf("Validate = %v, want ErrInvalidEvent", err)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	if _, err := ValidateJSON([]byte("not json")); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("malformed payload: %v, want ErrInvalidEvent", err)
+	}
+	evt, err := ValidateJSON([]byte(`{"id":"c1","type":"command.notify","data":{"device":"pixel"}}`))
+	if err != nil || evt.ID != "c1" {
+		t.Errorf("ValidateJSON = %+v, %v; want the decoded event", evt, err)
+	}
+}
+
+// TestRegistry_Structs guards the registry itself: every entry must be a struct so
+// Validate can decode into it and read its required tags.
+func TestRegistry_Structs(t *testing.T) {
+	for k, typ := range registry {
+		if typ.Kind() != reflect.Struct {
+			t.Errorf("%s v%d registered as %s, want a struct", k.eventType, k.major, typ.Kind())
+		}
+	}
+	for _, typ := range []string{AdaEventMedicationGiven, HomeEventCalendarUpsert, CommandTypeNotify, CommandTypeHAService, HAServiceResult} {
+		if !Registered(typ) {
+			t.Errorf("%s is not registered", typ)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package schemas
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 const (
 	RulesSchemaVersionV1 = "1.0"
 
@@ -7,9 +13,67 @@ const (
 	// Required params: "title", "message", "device" (HA mobile_app device name).
 	ActionTypeNotify = "notify"
 
+	// ActionTypeHAService calls a Home Assistant service.
+	// Required params: "service" ("{domain}.{service}", e.g. "light.turn_on").
+	// Optional params: "entity_id". "data" is passed through as service data.
+	ActionTypeHAService = "ha_service"
+
+	// ActionTypePublish publishes a CloudEvent on ruby_engine.events.{subject}.
+	// Required params: "subject" (one or more dot-separated subject tokens),
+	// "type" (CloudEvents type). "data" becomes the event's data.
+	ActionTypePublish = "publish"
+
+	// ActionTypeKVSet writes a value to a key in the rules processor's KV bucket.
+	// Required params: "key" (dot-separated subject tokens), "value".
+	ActionTypeKVSet = "kv_set"
+
+	// ActionTypeDelay postpones the actions that follow it in the same list.
+	// Required params: "duration" (Go duration, e.g. "5m").
+	ActionTypeDelay = "delay"
+
+	// ActionTypeSequence runs "steps" in order; each step may set on_error.
+	ActionTypeSequence = "sequence"
+
+	// OnErrorStop (the default) abandons the remaining actions when an action
+	// fails; OnErrorContinue logs the failure and proceeds with the next one.
+	OnErrorStop     = "stop"
+	OnErrorContinue = "continue"
+
 	// ConditionTypeStateTransition matches when an entity transitions to a specific state value.
 	// Required fields: "field" (attribute name, typically "state"), "value" (target state string).
 	ConditionTypeStateTransition = "state_transition"
+
+	// ConditionTypeNumericState matches when a numeric field lies within a range.
+	// Required fields: "field"; at least one of "above" (exclusive lower bound) and
+	// "below" (exclusive upper bound).
+	ConditionTypeNumericState = "numeric_state"
+
+	// ConditionTypeAttribute matches on the current value of a field.
+	// Required fields: "field"; exactly one of "value" (string equality) or
+	// "pattern" (RE2 regular expression, unanchored).
+	ConditionTypeAttribute = "attribute"
+
+	// ConditionTypeStateFor matches when a field has held a value for at least a
+	// duration, measured from the stored time the value was first observed.
+	// Required fields: "field", "value", "for" (Go duration, e.g. "10m").
+	// Evaluated on event arrival only: it fires on the first event at or after the
+	// duration has elapsed, not when the duration elapses.
+	ConditionTypeStateFor = "state_for"
+
+	// ConditionTypeTimeOfDay matches when the event time falls in a local-time window.
+	// Fields: "after" and/or "before" as "HH:MM" (after inclusive, before exclusive).
+	// A window with after > before wraps midnight (e.g. after 22:00, before 06:00).
+	ConditionTypeTimeOfDay = "time_of_day"
+
+	// ConditionTypeDayOfWeek matches when the event's local weekday is listed.
+	// Required fields: "weekdays" (three-letter lowercase names, e.g. [mon, tue]).
+	ConditionTypeDayOfWeek = "day_of_week"
+
+	// ConditionTypeAll, ConditionTypeAny and ConditionTypeNot compose the nested
+	// "conditions" list: all must hold, at least one must hold, or none may hold.
+	ConditionTypeAll = "all"
+	ConditionTypeAny = "any"
+	ConditionTypeNot = "not"
 )
 
 // RuleFile represents the top-level YAML file.
@@ -34,20 +98,60 @@ type Rule struct {
 // The engine's config loader aggregates these into a passlist published to NATS KV so
 // the gateway can perform lean projection (ADR-0009): only listed attributes are
 // forwarded in the CloudEvent payload; all others are dropped.
+//
+// A trigger with Source TriggerSourceSchedule is time-based instead: Cron is a
+// five-field cron expression in the engine's time zone and ID names the
+// schedule; Type and Attributes are unused.
 type Trigger struct {
 	Source     string   `yaml:"source"`
 	Type       string   `yaml:"type"`
 	ID         string   `yaml:"id,omitempty"`
 	Attributes []string `yaml:"attributes,omitempty"`
+	Cron       string   `yaml:"cron,omitempty"`
 }
 
+// Condition is a predicate over the triggering event and the entity's prior
+// state. Which fields apply depends on Type; see the ConditionType* constants.
 type Condition struct {
-	Type  string `yaml:"type"`
-	Field string `yaml:"field,omitempty"`
-	Value string `yaml:"value,omitempty"`
+	Type       string      `yaml:"type"`
+	Field      string      `yaml:"field,omitempty"`
+	Value      string      `yaml:"value,omitempty"`
+	Above      *float64    `yaml:"above,omitempty"`
+	Below      *float64    `yaml:"below,omitempty"`
+	Pattern    string      `yaml:"pattern,omitempty"`
+	For        string      `yaml:"for,omitempty"`
+	After      string      `yaml:"after,omitempty"`
+	Before     string      `yaml:"before,omitempty"`
+	Weekdays   []string    `yaml:"weekdays,omitempty"`
+	Conditions []Condition `yaml:"conditions,omitempty"`
 }
 
+// Action is one step executed when a rule fires. Which fields apply depends on
+// Type; see the ActionType* constants. OnError applies to this action's
+// failure within its enclosing list.
 type Action struct {
-	Type   string            `yaml:"type"`
-	Params map[string]string `yaml:"params,omitempty"`
+	Type    string            `yaml:"type"`
+	Params  map[string]string `yaml:"params,omitempty"`
+	Data    map[string]any    `yaml:"data,omitempty"`
+	Steps   []Action          `yaml:"steps,omitempty"`
+	OnError string            `yaml:"on_error,omitempty"`
+}
+
+// ParseTimeOfDay parses an "HH:MM" wall-clock time into its offset from midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time of day %q: want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseWeekday parses a three-letter weekday name ("mon" … "sun"), case-insensitively.
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("weekday %q: want one of sun, mon, tue, wed, thu, fri, sat", s)
 }
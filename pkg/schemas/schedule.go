@@ -0,0 +1,25 @@
+package schemas
+
+// Schedule CloudEvents are published by the engine scheduler on
+// ruby_engine.events.schedule.{owner}.{id} when a persisted timer comes due.
+// They are consumed through the same durable consumers as every other engine
+// event, so they are deduplicated, audited and dead-lettered like any other.
+const (
+	// ScheduleSource is the CloudEvents "source" of every schedule event.
+	ScheduleSource = "ruby_engine"
+
+	// ScheduleSubjectPrefix prefixes every schedule subject; the SCHEDULES
+	// stream captures ScheduleSubjectPrefix + ">".
+	ScheduleSubjectPrefix = "ruby_engine.events.schedule."
+
+	// ScheduleEventFired is the type of the event published when a schedule
+	// comes due. Its subject is "{owner}.{id}" and its time is the scheduled
+	// (not the actual) fire time. Data carries the schedule's own data plus
+	// "schedule_id" and "scheduled_at".
+	ScheduleEventFired = "schedule.fired"
+
+	// TriggerSourceSchedule is the rule trigger source for time-based rules.
+	// Such a trigger names its schedule in "id" and sets "cron"; the rule fires
+	// on ruby_engine.events.schedule.rules.{id}.
+	TriggerSourceSchedule = "schedule"
+)
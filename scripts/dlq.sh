@@ -0,0 +1,44 @@
+#!/usr/bin/env bash
+# dlq.sh — run the DLQ inspection/replay/purge command (cmd/dlq) against a ruby-core
+# NATS server as the admin user. It sets the pkg/boot environment the command reads
+# (admin NKEY seed path + direct-PKI client cert, the same material
+# scripts/nats-admin.sh uses) and passes all arguments through.
+#
+# Usage:  ENV=<dev|staging|prod> scripts/dlq.sh <list|view|replay|purge> [flags]
+# Example: ENV=prod scripts/dlq.sh list --stream HA_EVENTS
+#          ENV=prod scripts/dlq.sh replay 12 13
+#
+# Requires: go, and the admin AppRole material on the host
+# (/opt/foundation/vault/...-ruby-core-admin). Read VAULT_TOKEN from
+# deploy/prod/.env if not already set. See docs/runbooks/dlq.md.
+
+set -euo pipefail
+
+DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+REPO_ROOT="$(cd "${DIR}/.." && pwd)"
+
+case "${ENV:-}" in
+    prod)    NATS_URL="tls://127.0.0.1:4223"; VAULT_SECRET_PREFIX="secret/data/ruby-core" ;;
+    staging) NATS_URL="tls://127.0.0.1:4224"; VAULT_SECRET_PREFIX="secret/data/ruby-core/staging" ;;
+    dev)     NATS_URL="tls://127.0.0.1:4222"; VAULT_SECRET_PREFIX="secret/data/ruby-core/dev" ;;
+    "")      echo "error: ENV is required (dev | staging | prod)" >&2; exit 2 ;;
+    *)       echo "error: unknown ENV='${ENV}'" >&2; exit 2 ;;
+esac
+
+export VAULT_ADDR="${VAULT_ADDR:-https://127.0.0.1:8200}"
+export VAULT_CACERT="${VAULT_CACERT:-/opt/foundation/vault/tls/vault-ca.crt}"
+if [[ -z "${VAULT_TOKEN:-}" ]]; then
+    VAULT_TOKEN="$(grep '^VAULT_TOKEN=' "${REPO_ROOT}/deploy/prod/.env" | head -1 | cut -d= -f2-)"
+fi
+export VAULT_TOKEN
+
+export NATS_URL
+export NATS_REQUIRE_MTLS=true
+export VAULT_NKEY_PATH="${VAULT_SECRET_PREFIX}/nats/admin"
+export VAULT_PKI_ROLE=ruby-core-admin
+export VAULT_PKI_TTL=1h
+export VAULT_ROLE_ID_PATH="${ADMIN_ROLE_ID_PATH:-/opt/foundation/vault/role-id-foundation-agent-ruby-core-admin}"
+export VAULT_SECRET_ID_PATH="${ADMIN_SECRET_ID_PATH:-/opt/foundation/vault/.secret-id-foundation-agent-ruby-core-admin}"
+
+cd "${REPO_ROOT}"
+exec go run ./cmd/dlq "$@"
@@ -180,6 +180,42 @@ PUBKEY_NAVI_DEV=$(fetch_pubkey navi-dev)
 PUBKEY_NAVI_STAGING=$(fetch_pubkey navi-staging)
 PUBKEY_NAVI_PROD=$(fetch_pubkey navi-prod)
 
+# The API's NKEY is optional: it exists only where read-only DLQ inspection is
+# enabled (DLQ_API_ENABLED, see docs/runbooks/api-deploy-provisioning.md). Without
+# it the api user is left out of auth.conf instead of failing the init container.
+API_USER=""
+if vault kv get "${NKEY_BASE}/api" >/dev/null 2>&1; then
+    PUBKEY_API=$(fetch_pubkey api)
+    API_USER=$(cat <<EOF
+    # API service (optional)
+    # Responsibilities: Read-only DLQ inspection for GET /v1/dlq/messages (ADR-0022, ADR-0040)
+    # Principle of least privilege: stream info + message get on DLQ only; no consumers,
+    #   no deletes, no publish to business subjects
+    #   publish  \$JS.API.STREAM.INFO.DLQ    — DLQ sequence range
+    #   publish  \$JS.API.STREAM.MSG.GET.DLQ — Read one DLQ message by sequence
+    #   publish  audit.ruby_api.>            — Audit trail of DLQ reads (ADR-0019)
+    #   subscribe _INBOX.>                   — Reply-to subjects for JetStream API responses
+    {
+      nkey: "${PUBKEY_API}"
+      permissions: {
+        publish: {
+          allow: [
+            "\$JS.API.STREAM.INFO.DLQ",
+            "\$JS.API.STREAM.MSG.GET.DLQ",
+            "audit.ruby_api.>"
+          ]
+        }
+        subscribe: {
+          allow: [
+            "_INBOX.>"
+          ]
+        }
+      }
+    },
+EOF
+)
+fi
+
 echo "[nats-init] Generating auth.conf..."
 
 cat > "${TMP_DIR}/auth.conf" <<EOF
@@ -208,8 +244,15 @@ authorization {
     #   publish  \$JS.API.>         — JetStream API (KV create/bind, stream queries)
     #   publish  \$JS.ACK.>         — Message acknowledgements
     #   publish  \$KV.gateway_state.> — Gateway reconciler state (single-writer, ADR-0002)
+    #   publish  rejected.ha.events.> — Write events that fail schema validation (ADR-0014)
     #   subscribe _INBOX.>          — Reply-to subjects for JetStream API responses
     #   subscribe \$KV.config.>     — Read compiled rule config (passlist + critical entities)
+    # HA actuation (gateway_ha_service consumer on COMMANDS):
+    #   subscribe ruby_engine.commands.ha_service.> — ha_service commands, run as call_service
+    #   publish  \$KV.gateway_commands.> — claims on ha_service command IDs (single-writer, ADR-0002)
+    #   publish  dlq.commands.gateway_ha_service — dead-lettered commands (ADR-0022)
+    #   publish  rejected.ruby_engine.commands.ha_service.> — commands that fail validation (ADR-0014)
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.COMMANDS.gateway_ha_service
     {
       nkey: "${PUBKEY_GATEWAY}"
       permissions: {
@@ -221,14 +264,19 @@ authorization {
             "gateway.health",
             "\$JS.API.>",
             "\$JS.ACK.>",
-            "\$KV.gateway_state.>"
+            "\$KV.gateway_state.>",
+            "\$KV.gateway_commands.>",
+            "rejected.ha.events.>",
+            "dlq.commands.gateway_ha_service",
+            "rejected.ruby_engine.commands.ha_service.>"
           ]
         }
         subscribe: {
           allow: [
-            "ruby_engine.commands.>",
+            "ruby_engine.commands.ha_service.>",
             "_INBOX.>",
-            "\$KV.config.>"
+            "\$KV.config.>",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.COMMANDS.gateway_ha_service"
           ]
         }
       }
@@ -241,12 +289,25 @@ authorization {
     #   publish  \$JS.ACK.>     — Message acknowledgements to JetStream
     #   publish  \$KV.idempotency.> — Idempotency KV bucket (single-writer, ADR-0002)
     #   publish  dlq.>          — DLQ forwarder routes dead-lettered messages (ADR-0022)
+    #   publish  rejected.>     — Events that fail schema validation at consume time (ADR-0014)
     #   subscribe _INBOX.>      — Reply-to subjects for JetStream API responses
-    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.engine_processor
-    #                           — max-delivery advisory triggers DLQ routing (ADR-0022)
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.*
+    #                           — max-delivery advisory triggers DLQ routing (ADR-0022);
+    #                             one engine_{processor} consumer per processor
     # Phase 5 additions:
     #   publish  \$KV.config.>    — Compiled rule config for gateway (single-writer, ADR-0002)
     #   publish  \$KV.presence.>  — Presence processor state (single-writer, ADR-0002)
+    #   publish  \$KV.rules.>     — Rules processor trigger-entity state (single-writer, ADR-0002)
+    #   publish  \$KV.presence_notify.> — presence_notify state bucket (single-writer, ADR-0002)
+    # Scheduler additions:
+    #   publish  \$KV.schedules.>  — Durable timers registered by processors and rules (single-writer, ADR-0002)
+    #   publish  ruby_engine.events.> — Schedule firings (schedule.>, captured by the SCHEDULES
+    #                           stream) and rule publish actions (core NATS). Rule validation
+    #                           reserves schedule.> for the scheduler.
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.SCHEDULES.*
+    #                           — max-delivery advisory for the SCHEDULES consumers (ADR-0022)
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.PRESENCE.*
+    #                           — max-delivery advisory for the PRESENCE consumers (ADR-0022)
     {
       nkey: "${PUBKEY_ENGINE}"
       permissions: {
@@ -260,7 +321,12 @@ authorization {
             "\$KV.idempotency.>",
             "\$KV.config.>",
             "\$KV.presence.>",
-            "dlq.>"
+            "\$KV.rules.>",
+            "\$KV.presence_notify.>",
+            "\$KV.schedules.>",
+            "ruby_engine.events.>",
+            "dlq.>",
+            "rejected.>"
           ]
         }
         subscribe: {
@@ -268,7 +334,9 @@ authorization {
             "ha.events.>",
             "gateway.health",
             "_INBOX.>",
-            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.engine_processor"
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.*",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.SCHEDULES.*",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.PRESENCE.*"
           ]
         }
       }
@@ -279,6 +347,11 @@ authorization {
     # Phase 5 additions:
     #   publish  \$JS.API.>         — JetStream API (consumer create/fetch/bind)
     #   publish  \$JS.ACK.>         — Message acknowledgements
+    # DLQ forwarding (ADR-0022):
+    #   publish  dlq.commands.notifier_processor — dead-lettered notify commands
+    # Schema validation (ADR-0014):
+    #   publish  rejected.ruby_engine.commands.notify.> — notify commands that fail validation
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.COMMANDS.notifier_processor
     {
       nkey: "${PUBKEY_NOTIFIER}"
       permissions: {
@@ -287,13 +360,16 @@ authorization {
             "audit.ruby_notifier.>",
             "ruby_notifier.metrics.>",
             "\$JS.API.>",
-            "\$JS.ACK.>"
+            "\$JS.ACK.>",
+            "dlq.commands.notifier_processor",
+            "rejected.ruby_engine.commands.notify.>"
           ]
         }
         subscribe: {
           allow: [
             "ruby_engine.commands.notify.>",
-            "_INBOX.>"
+            "_INBOX.>",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.COMMANDS.notifier_processor"
           ]
         }
       }
@@ -302,6 +378,9 @@ authorization {
     # Presence service
     # Responsibilities: Multi-source presence fusion; subscribes to HA phone events,
     #   publishes clean presence state events and audit trail.
+    # DLQ forwarding (ADR-0022), one presence_{person} consumer per instance:
+    #   publish  dlq.ha_events.>    — dead-lettered phone events
+    #   subscribe \$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.*
     {
       nkey: "${PUBKEY_PRESENCE}"
       permissions: {
@@ -313,7 +392,8 @@ authorization {
             "\$JS.API.>",
             "\$JS.ACK.>",
             "\$KV.presence.>",
-            "_INBOX.>"
+            "_INBOX.>",
+            "dlq.ha_events.>"
           ]
         }
         subscribe: {
@@ -321,7 +401,8 @@ authorization {
             "ha.events.phone.>",
             "_INBOX.>",
             "\$JS.API.>",
-            "\$JS.ACK.>"
+            "\$JS.ACK.>",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.HA_EVENTS.*"
           ]
         }
       }
@@ -330,18 +411,21 @@ authorization {
     # Audit-Sink service
     # Responsibilities: Consume all *.audit.> events from AUDIT_EVENTS stream, archive to NDJSON file
     # Principle of least privilege: no publish to business subjects; only JetStream API/ACK
+    #   and its own DLQ subject + max-delivery advisory (ADR-0022)
     {
       nkey: "${PUBKEY_AUDIT_SINK}"
       permissions: {
         publish: {
           allow: [
             "\$JS.API.>",
-            "\$JS.ACK.>"
+            "\$JS.ACK.>",
+            "dlq.audit_events.audit_sink_consumer"
           ]
         }
         subscribe: {
           allow: [
-            "_INBOX.>"
+            "_INBOX.>",
+            "\$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.AUDIT_EVENTS.audit_sink_consumer"
           ]
         }
       }
@@ -386,6 +470,8 @@ authorization {
       }
     },
 
+${API_USER}
+
     # Admin/operator account (for debugging and maintenance)
     {
       nkey: "${PUBKEY_ADMIN}"
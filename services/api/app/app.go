@@ -24,8 +24,9 @@ type App struct {
 }
 
 // New builds the read API over a read-only Postgres pool and the in-app bearer token.
-func New(pool *pgxpool.Pool, bearerToken string, log *slog.Logger) (*App, error) {
-	svc := handlers.New(pool, log, "api")
+// dlq serves the DLQ inspection endpoints; nil leaves them answering 503.
+func New(pool *pgxpool.Pool, dlq handlers.DLQReader, bearerToken string, log *slog.Logger) (*App, error) {
+	svc := handlers.New(pool, dlq, log, "api")
 	auth := newTokenAuth(bearerToken)
 
 	oasServer, err := oas.NewServer(svc, auth)
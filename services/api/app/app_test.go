@@ -4,14 +4,19 @@ package app
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
 
 	apispec "github.com/primaryrutabaga/ruby-core/api"
+	"github.com/primaryrutabaga/ruby-core/pkg/dlq"
 )
 
 const testToken = "test-bearer-token-0123456789"
@@ -19,7 +24,8 @@ const testToken = "test-bearer-token-0123456789"
 func newTestHandler(t *testing.T) http.Handler {
 	t.Helper()
 	// pool is nil: the only endpoint in this slice (/ping) performs no DB I/O.
-	a, err := New(nil, testToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	// dlq is nil: DLQ inspection is disabled and its endpoints answer 503.
+	a, err := New(nil, nil, testToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
 	if err != nil {
 		t.Fatalf("app.New: %v", err)
 	}
@@ -110,3 +116,102 @@ func TestOpenAPISpec_ServesEmbeddedBundle(t *testing.T) {
 		t.Fatal("/openapi.yaml body does not match embedded bundle")
 	}
 }
+
+// fakeDLQ serves a fixed set of DLQ messages.
+type fakeDLQ struct{ msgs []dlq.Message }
+
+func (f fakeDLQ) List(filter dlq.Filter) ([]dlq.Message, error) {
+	var out []dlq.Message
+	for _, m := range f.msgs {
+		if filter.Match(m) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeDLQ) Get(seq uint64) (dlq.Message, error) {
+	for _, m := range f.msgs {
+		if m.Seq == seq {
+			return m, nil
+		}
+	}
+	return dlq.Message{}, dlq.ErrNotFound
+}
+
+func newDLQTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	at := time.Now().Add(-time.Hour)
+	a, err := New(nil, fakeDLQ{msgs: []dlq.Message{
+		{Seq: 7, Subject: "dlq.ha_events.engine_processor", Stream: "HA_EVENTS", Consumer: "engine_processor",
+			OrigSubject: "ha.events.light.kitchen", OrigSeq: 409, Deliveries: 5, Time: at, Data: []byte(`{"id":"evt-1"}`),
+			FirstFailure: at.Add(-15 * time.Second), LastFailure: at, LastError: "rules: kitchen: timeout",
+			OrigHeader: nats.Header{nats.MsgIdHdr: {"evt-1"}}},
+		{Seq: 8, Subject: "dlq.schedules.engine_schedules", Stream: "schedules", Consumer: "engine_schedules", Time: at},
+	}}, testToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("app.New: %v", err)
+	}
+	return a.Handler()
+}
+
+func TestDLQ_Disabled_503Problem(t *testing.T) {
+	for _, path := range []string{"/v1/dlq/messages", "/v1/dlq/messages/1"} {
+		rec := do(t, newTestHandler(t), http.MethodGet, path, testToken)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s status = %d, want 503 (body=%s)", path, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, "problem+json") {
+			t.Fatalf("%s content-type = %q, want application/problem+json", path, ct)
+		}
+	}
+}
+
+func TestDLQ_ListFiltersAndOmitsPayload(t *testing.T) {
+	rec := do(t, newDLQTestHandler(t), http.MethodGet, "/v1/dlq/messages?stream=ha_events", testToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want 200 (body=%s)", rec.Code, rec.Body.String())
+	}
+	var body []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(body) != 1 || body[0]["seq"] != float64(7) || body[0]["original_subject"] != "ha.events.light.kitchen" ||
+		body[0]["deliveries"] != float64(5) || body[0]["last_error"] != "rules: kitchen: timeout" || body[0]["first_failed_at"] == nil {
+		t.Fatalf("list body = %v, want only seq 7 with its original subject, deliveries and failure", body)
+	}
+	if age, _ := body[0]["age_seconds"].(float64); age < 3600 {
+		t.Errorf("age_seconds = %v, want at least 3600", body[0]["age_seconds"])
+	}
+	if _, ok := body[0]["payload"]; ok {
+		t.Error("list returned a payload")
+	}
+	if _, ok := body[0]["original_headers"]; ok {
+		t.Error("list returned the original headers")
+	}
+}
+
+func TestDLQ_GetReturnsHeadersAndPayloadOr404(t *testing.T) {
+	h := newDLQTestHandler(t)
+	rec := do(t, h, http.MethodGet, "/v1/dlq/messages/7", testToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, want 200 (body=%s)", rec.Code, rec.Body.String())
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode get: %v", err)
+	}
+	if body["payload"] != `{"id":"evt-1"}` {
+		t.Errorf("payload = %v, want the stored payload", body["payload"])
+	}
+	if h, _ := body["original_headers"].(map[string]any); fmt.Sprint(h[nats.MsgIdHdr]) != "[evt-1]" {
+		t.Errorf("original_headers = %v, want the stored Nats-Msg-Id", body["original_headers"])
+	}
+
+	if rec := do(t, h, http.MethodGet, "/v1/dlq/messages/99", testToken); rec.Code != http.StatusNotFound {
+		t.Errorf("get missing status = %d, want 404 (body=%s)", rec.Code, rec.Body.String())
+	}
+	if rec := do(t, h, http.MethodGet, "/v1/dlq/messages/0", testToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("get seq 0 status = %d, want 400 (body=%s)", rec.Code, rec.Body.String())
+	}
+}
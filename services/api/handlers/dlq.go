@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/dlq"
+	"github.com/primaryrutabaga/ruby-core/services/api/oas"
+)
+
+// defaultDLQLimit is the page size of ListDLQMessages when no limit is given.
+const defaultDLQLimit = 100
+
+const dlqDisabled = "DLQ inspection is not enabled on this deployment."
+
+// ListDLQMessages returns the dead-lettered messages matching the stream and
+// consumer filters, oldest first, without their payloads.
+func (s *Service) ListDLQMessages(_ context.Context, params oas.ListDLQMessagesParams) (oas.ListDLQMessagesRes, error) {
+	if s.dlq == nil {
+		return nil, unavailable(dlqDisabled)
+	}
+	msgs, err := s.dlq.List(dlq.Filter{
+		Stream:   params.Stream.Or(""),
+		Consumer: params.Consumer.Or(""),
+		Limit:    int(params.Limit.Or(defaultDLQLimit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make(oas.ListDLQMessagesOKApplicationJSON, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, toAPIDLQMessage(m, now))
+	}
+	return &out, nil
+}
+
+// GetDLQMessage returns one dead-lettered message with its original headers and payload.
+func (s *Service) GetDLQMessage(_ context.Context, params oas.GetDLQMessageParams) (oas.GetDLQMessageRes, error) {
+	if s.dlq == nil {
+		return nil, unavailable(dlqDisabled)
+	}
+	m, err := s.dlq.Get(uint64(params.Seq)) //nolint:gosec // G115: the spec bounds seq to >= 1
+	if errors.Is(err, dlq.ErrNotFound) {
+		return nil, notFound("No DLQ message is stored at this sequence.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := toAPIDLQMessage(m, time.Now())
+	if m.OrigHeader != nil {
+		out.OriginalHeaders = oas.NewOptDLQMessageOriginalHeaders(oas.DLQMessageOriginalHeaders(m.OrigHeader))
+	}
+	out.Payload = oas.NewOptString(string(m.Data))
+	return &out, nil
+}
+
+// toAPIDLQMessage maps a DLQ message to its API shape, leaving out the original
+// headers and payload.
+func toAPIDLQMessage(m dlq.Message, now time.Time) oas.DLQMessage {
+	out := oas.DLQMessage{
+		Seq:            int64(m.Seq), //nolint:gosec // G115: stream sequences stay far below 2^63
+		Subject:        m.Subject,
+		Stream:         m.Stream,
+		Consumer:       m.Consumer,
+		DeadLetteredAt: m.Time.UTC(),
+		AgeSeconds:     int64(m.Age(now) / time.Second),
+	}
+	if m.OrigSubject != "" {
+		out.OriginalSubject = oas.NewOptString(m.OrigSubject)
+	}
+	if m.OrigSeq != 0 {
+		out.OriginalSeq = oas.NewOptInt64(int64(m.OrigSeq)) //nolint:gosec // G115: stream sequences stay far below 2^63
+	}
+	if m.Deliveries != 0 {
+		out.Deliveries = oas.NewOptInt32(int32(m.Deliveries)) //nolint:gosec // G115: delivery counts are bounded by MaxDeliver
+	}
+	if !m.FirstFailure.IsZero() {
+		out.FirstFailedAt = oas.NewOptDateTime(m.FirstFailure.UTC())
+	}
+	if !m.LastFailure.IsZero() {
+		out.LastFailedAt = oas.NewOptDateTime(m.LastFailure.UTC())
+	}
+	if m.LastError != "" {
+		out.LastError = oas.NewOptString(m.LastError)
+	}
+	return out
+}
@@ -21,6 +21,14 @@ func (e *apiError) Error() string { return e.detail }
 // badRequest returns a handler error that NewError renders as a 400 Problem.
 func badRequest(detail string) error { return &apiError{status: http.StatusBadRequest, detail: detail} }
 
+// notFound returns a handler error that NewError renders as a 404 Problem.
+func notFound(detail string) error { return &apiError{status: http.StatusNotFound, detail: detail} }
+
+// unavailable returns a handler error that NewError renders as a 503 Problem.
+func unavailable(detail string) error {
+	return &apiError{status: http.StatusServiceUnavailable, detail: detail}
+}
+
 // NewError maps any error returned by a handler or by the security/decoding layer
 // to an RFC 9457 Problem Details response (ADR-0041). ogen calls this for the
 // operation's `default` response. Explicit apiErrors keep their status; security
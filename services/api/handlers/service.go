@@ -8,18 +8,29 @@ import (
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/dlq"
 )
 
+// DLQReader is the read side of dlq.Client served by the DLQ endpoints. The API
+// never replays or purges (ADR-0040); those stay with the dlq command.
+type DLQReader interface {
+	List(f dlq.Filter) ([]dlq.Message, error)
+	Get(seq uint64) (dlq.Message, error)
+}
+
 // Service implements oas.Handler. It holds the read-only Postgres pool that domain
 // handlers query; the API never writes (ADR-0040).
 type Service struct {
 	pool    *pgxpool.Pool
+	dlq     DLQReader // nil when DLQ inspection is not enabled
 	log     *slog.Logger
 	service string
 }
 
-// New constructs the read-API handler. service is the short service name reported
-// by the liveness endpoint.
-func New(pool *pgxpool.Pool, log *slog.Logger, service string) *Service {
-	return &Service{pool: pool, log: log, service: service}
+// New constructs the read-API handler. dlq may be nil, in which case the DLQ
+// endpoints answer 503. service is the short service name reported by the
+// liveness endpoint.
+func New(pool *pgxpool.Pool, dlq DLQReader, log *slog.Logger, service string) *Service {
+	return &Service{pool: pool, dlq: dlq, log: log, service: service}
 }
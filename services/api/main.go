@@ -5,13 +5,17 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/audit"
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/dlq"
 	"github.com/primaryrutabaga/ruby-core/pkg/logging"
 	"github.com/primaryrutabaga/ruby-core/services/api/app"
+	"github.com/primaryrutabaga/ruby-core/services/api/handlers"
 )
 
 var (
@@ -58,15 +62,41 @@ func main() {
 	}
 	logger.Info("vault: fetched api bearer token", slog.String("path", tokenPath))
 
-	application, err := app.New(pool, bearer, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Optional read-only DLQ inspection (ADR-0022). Off unless DLQ_API_ENABLED is set:
+	// it is the API's only NATS dependency and needs its own NKEY at VAULT_NKEY_PATH.
+	var dlqReader handlers.DLQReader
+	if enabled, _ := strconv.ParseBool(os.Getenv("DLQ_API_ENABLED")); enabled {
+		seed, err := boot.FetchNATSSeed(cfg.VaultAddr, cfg.VaultToken, cfg.VaultNKEYPath)
+		if err != nil {
+			logger.Error("vault: fetch NATS seed failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		nc, err := boot.BootstrapNATSTLS(ctx, cfg, "ruby-core-api", seed)
+		if err != nil {
+			logger.Error("nats: connect failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer nc.Close()
+		js, err := nc.JetStream()
+		if err != nil {
+			logger.Error("nats: jetstream context failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		auditPub := audit.NewPublisher(nc, "ruby_api", logger)
+		defer auditPub.Close()
+		dlqReader = dlq.New(js, auditPub)
+		logger.Info("dlq: inspection enabled")
+	}
+
+	application, err := app.New(pool, dlqReader, bearer, logger)
 	if err != nil {
 		logger.Error("api: init failed", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
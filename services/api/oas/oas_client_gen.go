@@ -29,6 +29,13 @@ func trimTrailingSlashes(u *url.URL) {
 
 // Invoker invokes operations described by OpenAPI v3 specification.
 type Invoker interface {
+	// GetDLQMessage invokes getDLQMessage operation.
+	//
+	// Returns one message in the DLQ stream with its original headers and payload. Read-only (ADR-0040);
+	// every call is recorded as an audit event.
+	//
+	// GET /dlq/messages/{seq}
+	GetDLQMessage(ctx context.Context, params GetDLQMessageParams) (GetDLQMessageRes, error)
 	// ListCalendarEvents invokes listCalendarEvents operation.
 	//
 	// Returns a flat, sorted list of calendar event instances whose time overlaps the requested
@@ -55,6 +62,15 @@ type Invoker interface {
 	//
 	// GET /childcare/providers
 	ListChildcareProviders(ctx context.Context) (ListChildcareProvidersRes, error)
+	// ListDLQMessages invokes listDLQMessages operation.
+	//
+	// Returns the messages in the DLQ stream, oldest first, with their original subject, stream sequence,
+	// delivery count and age. Payloads are omitted; fetch one with `getDLQMessage`. Read-only: replay and
+	// purge are operator actions taken with the `dlq` command (ADR-0040). Every call is recorded as an
+	// audit event.
+	//
+	// GET /dlq/messages
+	ListDLQMessages(ctx context.Context, params ListDLQMessagesParams) (ListDLQMessagesRes, error)
 	// ListDirectoryPeople invokes listDirectoryPeople operation.
 	//
 	// Returns the active people and groups in the household directory — the roster that feeds the "FOR"
@@ -114,6 +130,138 @@ func (c *Client) requestURL(ctx context.Context) *url.URL {
 	return u
 }
 
+// GetDLQMessage invokes getDLQMessage operation.
+//
+// Returns one message in the DLQ stream with its original headers and payload. Read-only (ADR-0040);
+// every call is recorded as an audit event.
+//
+// GET /dlq/messages/{seq}
+func (c *Client) GetDLQMessage(ctx context.Context, params GetDLQMessageParams) (GetDLQMessageRes, error) {
+	res, err := c.sendGetDLQMessage(ctx, params)
+	return res, err
+}
+
+func (c *Client) sendGetDLQMessage(ctx context.Context, params GetDLQMessageParams) (res GetDLQMessageRes, err error) {
+	otelAttrs := []attribute.KeyValue{
+		otelogen.OperationID("getDLQMessage"),
+		semconv.HTTPRequestMethodKey.String("GET"),
+		semconv.URLTemplateKey.String("/dlq/messages/{seq}"),
+	}
+	otelAttrs = append(otelAttrs, c.cfg.Attributes...)
+
+	// Run stopwatch.
+	startTime := time.Now()
+	defer func() {
+		// Use floating point division here for higher precision (instead of Millisecond method).
+		elapsedDuration := time.Since(startTime)
+		c.duration.Record(ctx, float64(elapsedDuration)/float64(time.Millisecond), metric.WithAttributes(otelAttrs...))
+	}()
+
+	// Increment request counter.
+	c.requests.Add(ctx, 1, metric.WithAttributes(otelAttrs...))
+
+	// Start a span for this request.
+	ctx, span := c.cfg.Tracer.Start(ctx, GetDLQMessageOperation,
+		trace.WithAttributes(otelAttrs...),
+		clientSpanKind,
+	)
+	// Track stage for error reporting.
+	var stage string
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, stage)
+			c.errors.Add(ctx, 1, metric.WithAttributes(otelAttrs...))
+		}
+		span.End()
+	}()
+
+	stage = "BuildURL"
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [2]string
+	pathParts[0] = "/dlq/messages/"
+	{
+		// Encode "seq" parameter.
+		e := uri.NewPathEncoder(uri.PathEncoderConfig{
+			Param:   "seq",
+			Style:   uri.PathStyleSimple,
+			Explode: false,
+		})
+		if err := func() error {
+			return e.EncodeValue(conv.Int64ToString(params.Seq))
+		}(); err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		encoded, err := e.Result()
+		if err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		pathParts[1] = encoded
+	}
+	uri.AddPathParts(u, pathParts[:]...)
+
+	stage = "EncodeRequest"
+	r, err := ht.NewRequest(ctx, "GET", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+
+	{
+		type bitset = [1]uint8
+		var satisfied bitset
+		{
+			stage = "Security:BearerAuth"
+			switch err := c.securityBearerAuth(ctx, GetDLQMessageOperation, r); {
+			case err == nil: // if NO error
+				satisfied[0] |= 1 << 0
+			case errors.Is(err, ogenerrors.ErrSkipClientSecurity):
+				// Skip this security.
+			default:
+				return res, errors.Wrap(err, "security \"BearerAuth\"")
+			}
+		}
+
+		if ok := func() bool {
+		nextRequirement:
+			for _, requirement := range []bitset{
+				{0b00000001},
+			} {
+				for i, mask := range requirement {
+					if satisfied[i]&mask != mask {
+						continue nextRequirement
+					}
+				}
+				return true
+			}
+			return false
+		}(); !ok {
+			return res, ogenerrors.ErrSecurityRequirementIsNotSatisfied
+		}
+	}
+
+	stage = "SendRequest"
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	stage = "DecodeResponse"
+	result, err := decodeGetDLQMessageResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
 // ListCalendarEvents invokes listCalendarEvents operation.
 //
 // Returns a flat, sorted list of calendar event instances whose time overlaps the requested
@@ -493,6 +641,177 @@ func (c *Client) sendListChildcareProviders(ctx context.Context) (res ListChildc
 	return result, nil
 }
 
+// ListDLQMessages invokes listDLQMessages operation.
+//
+// Returns the messages in the DLQ stream, oldest first, with their original subject, stream sequence,
+// delivery count and age. Payloads are omitted; fetch one with `getDLQMessage`. Read-only: replay and
+// purge are operator actions taken with the `dlq` command (ADR-0040). Every call is recorded as an
+// audit event.
+//
+// GET /dlq/messages
+func (c *Client) ListDLQMessages(ctx context.Context, params ListDLQMessagesParams) (ListDLQMessagesRes, error) {
+	res, err := c.sendListDLQMessages(ctx, params)
+	return res, err
+}
+
+func (c *Client) sendListDLQMessages(ctx context.Context, params ListDLQMessagesParams) (res ListDLQMessagesRes, err error) {
+	otelAttrs := []attribute.KeyValue{
+		otelogen.OperationID("listDLQMessages"),
+		semconv.HTTPRequestMethodKey.String("GET"),
+		semconv.URLTemplateKey.String("/dlq/messages"),
+	}
+	otelAttrs = append(otelAttrs, c.cfg.Attributes...)
+
+	// Run stopwatch.
+	startTime := time.Now()
+	defer func() {
+		// Use floating point division here for higher precision (instead of Millisecond method).
+		elapsedDuration := time.Since(startTime)
+		c.duration.Record(ctx, float64(elapsedDuration)/float64(time.Millisecond), metric.WithAttributes(otelAttrs...))
+	}()
+
+	// Increment request counter.
+	c.requests.Add(ctx, 1, metric.WithAttributes(otelAttrs...))
+
+	// Start a span for this request.
+	ctx, span := c.cfg.Tracer.Start(ctx, ListDLQMessagesOperation,
+		trace.WithAttributes(otelAttrs...),
+		clientSpanKind,
+	)
+	// Track stage for error reporting.
+	var stage string
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, stage)
+			c.errors.Add(ctx, 1, metric.WithAttributes(otelAttrs...))
+		}
+		span.End()
+	}()
+
+	stage = "BuildURL"
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [1]string
+	pathParts[0] = "/dlq/messages"
+	uri.AddPathParts(u, pathParts[:]...)
+
+	stage = "EncodeQueryParams"
+	q := uri.NewQueryEncoder()
+	{
+		// Encode "stream" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "stream",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Stream.Get(); ok {
+				return e.EncodeValue(conv.StringToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "consumer" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "consumer",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Consumer.Get(); ok {
+				return e.EncodeValue(conv.StringToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "limit" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "limit",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Limit.Get(); ok {
+				return e.EncodeValue(conv.Int32ToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	u.RawQuery = q.Values().Encode()
+
+	stage = "EncodeRequest"
+	r, err := ht.NewRequest(ctx, "GET", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+
+	{
+		type bitset = [1]uint8
+		var satisfied bitset
+		{
+			stage = "Security:BearerAuth"
+			switch err := c.securityBearerAuth(ctx, ListDLQMessagesOperation, r); {
+			case err == nil: // if NO error
+				satisfied[0] |= 1 << 0
+			case errors.Is(err, ogenerrors.ErrSkipClientSecurity):
+				// Skip this security.
+			default:
+				return res, errors.Wrap(err, "security \"BearerAuth\"")
+			}
+		}
+
+		if ok := func() bool {
+		nextRequirement:
+			for _, requirement := range []bitset{
+				{0b00000001},
+			} {
+				for i, mask := range requirement {
+					if satisfied[i]&mask != mask {
+						continue nextRequirement
+					}
+				}
+				return true
+			}
+			return false
+		}(); !ok {
+			return res, ogenerrors.ErrSecurityRequirementIsNotSatisfied
+		}
+	}
+
+	stage = "SendRequest"
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	stage = "DecodeResponse"
+	result, err := decodeListDLQMessagesResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
 // ListDirectoryPeople invokes listDirectoryPeople operation.
 //
 // Returns the active people and groups in the household directory — the roster that feeds the "FOR"
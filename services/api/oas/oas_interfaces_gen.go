@@ -1,6 +1,10 @@
 // Code generated by ogen, DO NOT EDIT.
 package oas
 
+type GetDLQMessageRes interface {
+	getDLQMessageRes()
+}
+
 type ListCalendarEventsRes interface {
 	listCalendarEventsRes()
 }
@@ -13,6 +17,10 @@ type ListChildcareProvidersRes interface {
 	listChildcareProvidersRes()
 }
 
+type ListDLQMessagesRes interface {
+	listDLQMessagesRes()
+}
+
 type ListDirectoryPeopleRes interface {
 	listDirectoryPeopleRes()
 }
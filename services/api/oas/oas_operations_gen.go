@@ -6,9 +6,11 @@ package oas
 type OperationName = string
 
 const (
+	GetDLQMessageOperation                    OperationName = "GetDLQMessage"
 	ListCalendarEventsOperation               OperationName = "ListCalendarEvents"
 	ListChildcareProviderSuggestionsOperation OperationName = "ListChildcareProviderSuggestions"
 	ListChildcareProvidersOperation           OperationName = "ListChildcareProviders"
+	ListDLQMessagesOperation                  OperationName = "ListDLQMessages"
 	ListDirectoryPeopleOperation              OperationName = "ListDirectoryPeople"
 	PingOperation                             OperationName = "Ping"
 )
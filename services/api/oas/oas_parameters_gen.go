@@ -4,14 +4,101 @@ package oas
 
 import (
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/go-faster/errors"
 	"github.com/ogen-go/ogen/conv"
 	"github.com/ogen-go/ogen/middleware"
 	"github.com/ogen-go/ogen/ogenerrors"
 	"github.com/ogen-go/ogen/uri"
+	"github.com/ogen-go/ogen/validate"
 )
 
+// GetDLQMessageParams is parameters of getDLQMessage operation.
+type GetDLQMessageParams struct {
+	// The message's sequence in the DLQ stream.
+	Seq int64
+}
+
+func unpackGetDLQMessageParams(packed middleware.Parameters) (params GetDLQMessageParams) {
+	{
+		key := middleware.ParameterKey{
+			Name: "seq",
+			In:   "path",
+		}
+		params.Seq = packed[key].(int64)
+	}
+	return params
+}
+
+func decodeGetDLQMessageParams(args [1]string, argsEscaped bool, r *http.Request) (params GetDLQMessageParams, _ error) {
+	// Decode path: seq.
+	if err := func() error {
+		param := args[0]
+		if argsEscaped {
+			unescaped, err := url.PathUnescape(args[0])
+			if err != nil {
+				return errors.Wrap(err, "unescape path")
+			}
+			param = unescaped
+		}
+		if len(param) > 0 {
+			d := uri.NewPathDecoder(uri.PathDecoderConfig{
+				Param:   "seq",
+				Value:   param,
+				Style:   uri.PathStyleSimple,
+				Explode: false,
+			})
+
+			if err := func() error {
+				val, err := d.DecodeValue()
+				if err != nil {
+					return err
+				}
+
+				c, err := conv.ToInt64(val)
+				if err != nil {
+					return err
+				}
+
+				params.Seq = c
+				return nil
+			}(); err != nil {
+				return err
+			}
+			if err := func() error {
+				if err := (validate.Int{
+					MinSet:        true,
+					Min:           1,
+					MaxSet:        false,
+					Max:           0,
+					MinExclusive:  false,
+					MaxExclusive:  false,
+					MultipleOfSet: false,
+					MultipleOf:    0,
+					Pattern:       nil,
+				}).Validate(int64(params.Seq)); err != nil {
+					return errors.Wrap(err, "int")
+				}
+				return nil
+			}(); err != nil {
+				return err
+			}
+		} else {
+			return validate.ErrFieldRequired
+		}
+		return nil
+	}(); err != nil {
+		return params, &ogenerrors.DecodeParamError{
+			Name: "seq",
+			In:   "path",
+			Err:  err,
+		}
+	}
+	return params, nil
+}
+
 // ListCalendarEventsParams is parameters of listCalendarEvents operation.
 type ListCalendarEventsParams struct {
 	// Inclusive start of the window, as an RFC 3339 timestamp.
@@ -115,3 +202,202 @@ func decodeListCalendarEventsParams(args [0]string, argsEscaped bool, r *http.Re
 	}
 	return params, nil
 }
+
+// ListDLQMessagesParams is parameters of listDLQMessages operation.
+type ListDLQMessagesParams struct {
+	// Only messages consumed from this stream (case-insensitive), e.g. `HA_EVENTS`.
+	Stream OptString `json:",omitempty,omitzero"`
+	// Only messages dead-lettered by this consumer, e.g. `engine_processor`.
+	Consumer OptString `json:",omitempty,omitzero"`
+	// The maximum number of messages to return.
+	Limit OptInt32 `json:",omitempty,omitzero"`
+}
+
+func unpackListDLQMessagesParams(packed middleware.Parameters) (params ListDLQMessagesParams) {
+	{
+		key := middleware.ParameterKey{
+			Name: "stream",
+			In:   "query",
+		}
+		if v, ok := packed[key]; ok {
+			params.Stream = v.(OptString)
+		}
+	}
+	{
+		key := middleware.ParameterKey{
+			Name: "consumer",
+			In:   "query",
+		}
+		if v, ok := packed[key]; ok {
+			params.Consumer = v.(OptString)
+		}
+	}
+	{
+		key := middleware.ParameterKey{
+			Name: "limit",
+			In:   "query",
+		}
+		if v, ok := packed[key]; ok {
+			params.Limit = v.(OptInt32)
+		}
+	}
+	return params
+}
+
+func decodeListDLQMessagesParams(args [0]string, argsEscaped bool, r *http.Request) (params ListDLQMessagesParams, _ error) {
+	q := uri.NewQueryDecoder(r.URL.Query())
+	// Decode query: stream.
+	if err := func() error {
+		cfg := uri.QueryParameterDecodingConfig{
+			Name:    "stream",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.HasParam(cfg); err == nil {
+			if err := q.DecodeParam(cfg, func(d uri.Decoder) error {
+				var paramsDotStreamVal string
+				if err := func() error {
+					val, err := d.DecodeValue()
+					if err != nil {
+						return err
+					}
+
+					c, err := conv.ToString(val)
+					if err != nil {
+						return err
+					}
+
+					paramsDotStreamVal = c
+					return nil
+				}(); err != nil {
+					return err
+				}
+				params.Stream.SetTo(paramsDotStreamVal)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return params, &ogenerrors.DecodeParamError{
+			Name: "stream",
+			In:   "query",
+			Err:  err,
+		}
+	}
+	// Decode query: consumer.
+	if err := func() error {
+		cfg := uri.QueryParameterDecodingConfig{
+			Name:    "consumer",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.HasParam(cfg); err == nil {
+			if err := q.DecodeParam(cfg, func(d uri.Decoder) error {
+				var paramsDotConsumerVal string
+				if err := func() error {
+					val, err := d.DecodeValue()
+					if err != nil {
+						return err
+					}
+
+					c, err := conv.ToString(val)
+					if err != nil {
+						return err
+					}
+
+					paramsDotConsumerVal = c
+					return nil
+				}(); err != nil {
+					return err
+				}
+				params.Consumer.SetTo(paramsDotConsumerVal)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return params, &ogenerrors.DecodeParamError{
+			Name: "consumer",
+			In:   "query",
+			Err:  err,
+		}
+	}
+	// Set default value for query: limit.
+	{
+		val := int32(100)
+		params.Limit.SetTo(val)
+	}
+	// Decode query: limit.
+	if err := func() error {
+		cfg := uri.QueryParameterDecodingConfig{
+			Name:    "limit",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.HasParam(cfg); err == nil {
+			if err := q.DecodeParam(cfg, func(d uri.Decoder) error {
+				var paramsDotLimitVal int32
+				if err := func() error {
+					val, err := d.DecodeValue()
+					if err != nil {
+						return err
+					}
+
+					c, err := conv.ToInt32(val)
+					if err != nil {
+						return err
+					}
+
+					paramsDotLimitVal = c
+					return nil
+				}(); err != nil {
+					return err
+				}
+				params.Limit.SetTo(paramsDotLimitVal)
+				return nil
+			}); err != nil {
+				return err
+			}
+			if err := func() error {
+				if value, ok := params.Limit.Get(); ok {
+					if err := func() error {
+						if err := (validate.Int{
+							MinSet:        true,
+							Min:           1,
+							MaxSet:        true,
+							Max:           1000,
+							MinExclusive:  false,
+							MaxExclusive:  false,
+							MultipleOfSet: false,
+							MultipleOf:    0,
+							Pattern:       nil,
+						}).Validate(int64(value)); err != nil {
+							return errors.Wrap(err, "int")
+						}
+						return nil
+					}(); err != nil {
+						return err
+					}
+				}
+				return nil
+			}(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return params, &ogenerrors.DecodeParamError{
+			Name: "limit",
+			In:   "query",
+			Err:  err,
+		}
+	}
+	return params, nil
+}
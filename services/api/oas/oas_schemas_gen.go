@@ -269,6 +269,210 @@ func (s *CalendarInstanceAttendeesItem) SetResponseStatus(val OptString) {
 	s.ResponseStatus = val
 }
 
+// A message dead-lettered to the DLQ stream after exhausting its consumer's MaxDeliver (ADR-0022). The
+// original subject, headers, stream sequence, delivery count and failure details are recorded by the
+// DLQ forwarder; messages dead-lettered before it recorded them carry only the stream and consumer
+// named by their DLQ subject.
+// Ref: #/components/schemas/DLQMessage
+type DLQMessage struct {
+	// The message's sequence in the DLQ stream; identifies it to the `dlq` command for replay and purge.
+	Seq int64 `json:"seq"`
+	// The DLQ subject, `dlq.{stream}.{consumer}`.
+	Subject string `json:"subject"`
+	// The stream the message was consumed from.
+	Stream string `json:"stream"`
+	// The durable consumer that exhausted MaxDeliver.
+	Consumer string `json:"consumer"`
+	// The subject the message was originally published on, when recorded.
+	OriginalSubject OptString `json:"original_subject"`
+	// The message's sequence in its original stream, when recorded.
+	OriginalSeq OptInt64 `json:"original_seq"`
+	// Delivery attempts before the message was dead-lettered, when recorded.
+	Deliveries OptInt32 `json:"deliveries"`
+	// When the consumer first failed to process the message, when recorded.
+	FirstFailedAt OptDateTime `json:"first_failed_at"`
+	// When the consumer last failed to process the message, when recorded.
+	LastFailedAt OptDateTime `json:"last_failed_at"`
+	// The error of the last failed attempt, on one line and truncated to 1024 bytes, when recorded.
+	LastError OptString `json:"last_error"`
+	// When the message was dead-lettered, as an RFC 3339 timestamp.
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+	// Seconds since the message was dead-lettered.
+	AgeSeconds int64 `json:"age_seconds"`
+	// The original message's headers, including its `Nats-Msg-Id` and W3C trace context, when recorded.
+	// Returned only by `getDLQMessage`.
+	OriginalHeaders OptDLQMessageOriginalHeaders `json:"original_headers"`
+	// The message payload as text. Returned only by `getDLQMessage`.
+	Payload OptString `json:"payload"`
+}
+
+// GetSeq returns the value of Seq.
+func (s *DLQMessage) GetSeq() int64 {
+	return s.Seq
+}
+
+// GetSubject returns the value of Subject.
+func (s *DLQMessage) GetSubject() string {
+	return s.Subject
+}
+
+// GetStream returns the value of Stream.
+func (s *DLQMessage) GetStream() string {
+	return s.Stream
+}
+
+// GetConsumer returns the value of Consumer.
+func (s *DLQMessage) GetConsumer() string {
+	return s.Consumer
+}
+
+// GetOriginalSubject returns the value of OriginalSubject.
+func (s *DLQMessage) GetOriginalSubject() OptString {
+	return s.OriginalSubject
+}
+
+// GetOriginalSeq returns the value of OriginalSeq.
+func (s *DLQMessage) GetOriginalSeq() OptInt64 {
+	return s.OriginalSeq
+}
+
+// GetDeliveries returns the value of Deliveries.
+func (s *DLQMessage) GetDeliveries() OptInt32 {
+	return s.Deliveries
+}
+
+// GetFirstFailedAt returns the value of FirstFailedAt.
+func (s *DLQMessage) GetFirstFailedAt() OptDateTime {
+	return s.FirstFailedAt
+}
+
+// GetLastFailedAt returns the value of LastFailedAt.
+func (s *DLQMessage) GetLastFailedAt() OptDateTime {
+	return s.LastFailedAt
+}
+
+// GetLastError returns the value of LastError.
+func (s *DLQMessage) GetLastError() OptString {
+	return s.LastError
+}
+
+// GetDeadLetteredAt returns the value of DeadLetteredAt.
+func (s *DLQMessage) GetDeadLetteredAt() time.Time {
+	return s.DeadLetteredAt
+}
+
+// GetAgeSeconds returns the value of AgeSeconds.
+func (s *DLQMessage) GetAgeSeconds() int64 {
+	return s.AgeSeconds
+}
+
+// GetOriginalHeaders returns the value of OriginalHeaders.
+func (s *DLQMessage) GetOriginalHeaders() OptDLQMessageOriginalHeaders {
+	return s.OriginalHeaders
+}
+
+// GetPayload returns the value of Payload.
+func (s *DLQMessage) GetPayload() OptString {
+	return s.Payload
+}
+
+// SetSeq sets the value of Seq.
+func (s *DLQMessage) SetSeq(val int64) {
+	s.Seq = val
+}
+
+// SetSubject sets the value of Subject.
+func (s *DLQMessage) SetSubject(val string) {
+	s.Subject = val
+}
+
+// SetStream sets the value of Stream.
+func (s *DLQMessage) SetStream(val string) {
+	s.Stream = val
+}
+
+// SetConsumer sets the value of Consumer.
+func (s *DLQMessage) SetConsumer(val string) {
+	s.Consumer = val
+}
+
+// SetOriginalSubject sets the value of OriginalSubject.
+func (s *DLQMessage) SetOriginalSubject(val OptString) {
+	s.OriginalSubject = val
+}
+
+// SetOriginalSeq sets the value of OriginalSeq.
+func (s *DLQMessage) SetOriginalSeq(val OptInt64) {
+	s.OriginalSeq = val
+}
+
+// SetDeliveries sets the value of Deliveries.
+func (s *DLQMessage) SetDeliveries(val OptInt32) {
+	s.Deliveries = val
+}
+
+// SetFirstFailedAt sets the value of FirstFailedAt.
+func (s *DLQMessage) SetFirstFailedAt(val OptDateTime) {
+	s.FirstFailedAt = val
+}
+
+// SetLastFailedAt sets the value of LastFailedAt.
+func (s *DLQMessage) SetLastFailedAt(val OptDateTime) {
+	s.LastFailedAt = val
+}
+
+// SetLastError sets the value of LastError.
+func (s *DLQMessage) SetLastError(val OptString) {
+	s.LastError = val
+}
+
+// SetDeadLetteredAt sets the value of DeadLetteredAt.
+func (s *DLQMessage) SetDeadLetteredAt(val time.Time) {
+	s.DeadLetteredAt = val
+}
+
+// SetAgeSeconds sets the value of AgeSeconds.
+func (s *DLQMessage) SetAgeSeconds(val int64) {
+	s.AgeSeconds = val
+}
+
+// SetOriginalHeaders sets the value of OriginalHeaders.
+func (s *DLQMessage) SetOriginalHeaders(val OptDLQMessageOriginalHeaders) {
+	s.OriginalHeaders = val
+}
+
+// SetPayload sets the value of Payload.
+func (s *DLQMessage) SetPayload(val OptString) {
+	s.Payload = val
+}
+
+func (*DLQMessage) getDLQMessageRes() {}
+
+// The original message's headers, including its `Nats-Msg-Id` and W3C trace context, when recorded.
+// Returned only by `getDLQMessage`.
+type DLQMessageOriginalHeaders map[string][]string
+
+func (s *DLQMessageOriginalHeaders) init() DLQMessageOriginalHeaders {
+	m := *s
+	if m == nil {
+		m = map[string][]string{}
+		*s = m
+	}
+	return m
+}
+
+type GetDLQMessageNotFound Problem
+
+func (*GetDLQMessageNotFound) getDLQMessageRes() {}
+
+type GetDLQMessageServiceUnavailable Problem
+
+func (*GetDLQMessageServiceUnavailable) getDLQMessageRes() {}
+
+type GetDLQMessageUnauthorized Problem
+
+func (*GetDLQMessageUnauthorized) getDLQMessageRes() {}
+
 type ListCalendarEventsBadRequest Problem
 
 func (*ListCalendarEventsBadRequest) listCalendarEventsRes() {}
@@ -289,10 +493,68 @@ type ListChildcareProvidersOKApplicationJSON []Provider
 
 func (*ListChildcareProvidersOKApplicationJSON) listChildcareProvidersRes() {}
 
+type ListDLQMessagesOKApplicationJSON []DLQMessage
+
+func (*ListDLQMessagesOKApplicationJSON) listDLQMessagesRes() {}
+
+type ListDLQMessagesServiceUnavailable Problem
+
+func (*ListDLQMessagesServiceUnavailable) listDLQMessagesRes() {}
+
+type ListDLQMessagesUnauthorized Problem
+
+func (*ListDLQMessagesUnauthorized) listDLQMessagesRes() {}
+
 type ListDirectoryPeopleOKApplicationJSON []Person
 
 func (*ListDirectoryPeopleOKApplicationJSON) listDirectoryPeopleRes() {}
 
+// NewOptDLQMessageOriginalHeaders returns new OptDLQMessageOriginalHeaders with value set to v.
+func NewOptDLQMessageOriginalHeaders(v DLQMessageOriginalHeaders) OptDLQMessageOriginalHeaders {
+	return OptDLQMessageOriginalHeaders{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDLQMessageOriginalHeaders is optional DLQMessageOriginalHeaders.
+type OptDLQMessageOriginalHeaders struct {
+	Value DLQMessageOriginalHeaders
+	Set   bool
+}
+
+// IsSet returns true if OptDLQMessageOriginalHeaders was set.
+func (o OptDLQMessageOriginalHeaders) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDLQMessageOriginalHeaders) Reset() {
+	var v DLQMessageOriginalHeaders
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDLQMessageOriginalHeaders) SetTo(v DLQMessageOriginalHeaders) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDLQMessageOriginalHeaders) Get() (v DLQMessageOriginalHeaders, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDLQMessageOriginalHeaders) Or(d DLQMessageOriginalHeaders) DLQMessageOriginalHeaders {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
 // NewOptDateTime returns new OptDateTime with value set to v.
 func NewOptDateTime(v time.Time) OptDateTime {
 	return OptDateTime{
@@ -339,6 +601,98 @@ func (o OptDateTime) Or(d time.Time) time.Time {
 	return d
 }
 
+// NewOptInt32 returns new OptInt32 with value set to v.
+func NewOptInt32(v int32) OptInt32 {
+	return OptInt32{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt32 is optional int32.
+type OptInt32 struct {
+	Value int32
+	Set   bool
+}
+
+// IsSet returns true if OptInt32 was set.
+func (o OptInt32) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt32) Reset() {
+	var v int32
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt32) SetTo(v int32) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt32) Get() (v int32, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt32) Or(d int32) int32 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt64 returns new OptInt64 with value set to v.
+func NewOptInt64(v int64) OptInt64 {
+	return OptInt64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt64 is optional int64.
+type OptInt64 struct {
+	Value int64
+	Set   bool
+}
+
+// IsSet returns true if OptInt64 was set.
+func (o OptInt64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt64) Reset() {
+	var v int64
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt64) SetTo(v int64) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt64) Get() (v int64, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt64) Or(d int64) int64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
 // NewOptString returns new OptString with value set to v.
 func NewOptString(v string) OptString {
 	return OptString{
@@ -36,9 +36,11 @@ func findAuthorization(h http.Header, prefix string) (string, bool) {
 
 // operationRolesBearerAuth is a private map storing roles per operation.
 var operationRolesBearerAuth = map[string][]string{
+	GetDLQMessageOperation:                    []string{},
 	ListCalendarEventsOperation:               []string{},
 	ListChildcareProviderSuggestionsOperation: []string{},
 	ListChildcareProvidersOperation:           []string{},
+	ListDLQMessagesOperation:                  []string{},
 	ListDirectoryPeopleOperation:              []string{},
 	PingOperation:                             []string{},
 }
@@ -8,6 +8,13 @@ import (
 
 // Handler handles operations described by OpenAPI v3 specification.
 type Handler interface {
+	// GetDLQMessage implements getDLQMessage operation.
+	//
+	// Returns one message in the DLQ stream with its original headers and payload. Read-only (ADR-0040);
+	// every call is recorded as an audit event.
+	//
+	// GET /dlq/messages/{seq}
+	GetDLQMessage(ctx context.Context, params GetDLQMessageParams) (GetDLQMessageRes, error)
 	// ListCalendarEvents implements listCalendarEvents operation.
 	//
 	// Returns a flat, sorted list of calendar event instances whose time overlaps the requested
@@ -34,6 +41,15 @@ type Handler interface {
 	//
 	// GET /childcare/providers
 	ListChildcareProviders(ctx context.Context) (ListChildcareProvidersRes, error)
+	// ListDLQMessages implements listDLQMessages operation.
+	//
+	// Returns the messages in the DLQ stream, oldest first, with their original subject, stream sequence,
+	// delivery count and age. Payloads are omitted; fetch one with `getDLQMessage`. Read-only: replay and
+	// purge are operator actions taken with the `dlq` command (ADR-0040). Every call is recorded as an
+	// audit event.
+	//
+	// GET /dlq/messages
+	ListDLQMessages(ctx context.Context, params ListDLQMessagesParams) (ListDLQMessagesRes, error)
 	// ListDirectoryPeople implements listDirectoryPeople operation.
 	//
 	// Returns the active people and groups in the household directory — the roster that feeds the "FOR"
@@ -13,6 +13,16 @@ type UnimplementedHandler struct{}
 
 var _ Handler = UnimplementedHandler{}
 
+// GetDLQMessage implements getDLQMessage operation.
+//
+// Returns one message in the DLQ stream with its original headers and payload. Read-only (ADR-0040);
+// every call is recorded as an audit event.
+//
+// GET /dlq/messages/{seq}
+func (UnimplementedHandler) GetDLQMessage(ctx context.Context, params GetDLQMessageParams) (r GetDLQMessageRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // ListCalendarEvents implements listCalendarEvents operation.
 //
 // Returns a flat, sorted list of calendar event instances whose time overlaps the requested
@@ -48,6 +58,18 @@ func (UnimplementedHandler) ListChildcareProviders(ctx context.Context) (r ListC
 	return r, ht.ErrNotImplemented
 }
 
+// ListDLQMessages implements listDLQMessages operation.
+//
+// Returns the messages in the DLQ stream, oldest first, with their original subject, stream sequence,
+// delivery count and age. Payloads are omitted; fetch one with `getDLQMessage`. Read-only: replay and
+// purge are operator actions taken with the `dlq` command (ADR-0040). Every call is recorded as an
+// audit event.
+//
+// GET /dlq/messages
+func (UnimplementedHandler) ListDLQMessages(ctx context.Context, params ListDLQMessagesParams) (r ListDLQMessagesRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // ListDirectoryPeople implements listDirectoryPeople operation.
 //
 // Returns the active people and groups in the household directory — the roster that feeds the "FOR"
@@ -13,7 +13,6 @@ import (
 	"github.com/nats-io/nats.go"
 
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
-	"github.com/primaryrutabaga/ruby-core/pkg/config"
 	"github.com/primaryrutabaga/ruby-core/pkg/logging"
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	rubyotel "github.com/primaryrutabaga/ruby-core/pkg/otel"
@@ -92,16 +91,10 @@ func main() {
 	logger.Info("nats: AUDIT_EVENTS stream ready")
 
 	// Set up pull consumer on AUDIT_EVENTS.
-	consumerCfg := natsx.PullConsumerConfig{
-		Stream:        "AUDIT_EVENTS",
-		Durable:       "audit_sink_consumer",
-		FilterSubject: "audit.>",
-		MaxDeliver:    config.DefaultMaxDeliver,
-		MaxAckPending: config.DefaultAuditSinkMaxAckPending,
-		AckWait:       config.DefaultAckWait,
-		BackOff:       config.DefaultBackOff,
-		WorkerCount:   config.DefaultAuditSinkWorkerCount,
-		FetchBatch:    config.DefaultAuditSinkFetchBatch,
+	consumerCfg, err := natsx.Manifest().Consumer("AUDIT_EVENTS", "audit_sink_consumer")
+	if err != nil {
+		logger.Error("nats: consumer config failed", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 	sub, err := natsx.EnsurePullConsumer(js, consumerCfg)
 	if err != nil {
@@ -113,6 +106,19 @@ func main() {
 		slog.Int("max_ack_pending", consumerCfg.MaxAckPending),
 	)
 
+	// The sink acks every message it handles, so only a message whose acks keep
+	// timing out (AckWait) reaches MaxDeliver; dead-letter it instead of dropping
+	// the audit record (ADR-0022). There are no processing errors to record.
+	if err := natsx.EnsureDLQStream(js); err != nil {
+		logger.Error("nats: ensure DLQ stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	dlqFwd, err := natsx.NewDLQForwarder(nc, js, consumerCfg.Stream, consumerCfg.Durable, nil, logger)
+	if err != nil {
+		logger.Error("dlq forwarder init failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	// Open NDJSON writer.
 	dataDir := envOrDefault("AUDIT_DATA_DIR", defaultAuditDataDir)
 	if err := os.MkdirAll(dataDir, 0o750); err != nil {
@@ -148,69 +154,27 @@ func main() {
 		logger.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
 	}
 
-	if err := runFetchLoop(ctx, sub, writer, consumerCfg, msgInstr, logger); err != nil {
-		logger.Error("fetch loop exited with error", slog.String("error", err.Error()))
+	pool, err := natsx.NewWorkerPool(sub, consumerCfg, func(_ context.Context, msg *nats.Msg) natsx.Result {
+		return handleAuditMsg(msg, writer, logger)
+	}, msgInstr, nil, logger)
+	if err != nil {
+		logger.Error("worker pool init failed", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
+
+	go func() { _ = dlqFwd.Run(ctx) }()
+	_ = pool.Run(ctx)
 	logger.Info("audit-sink stopped")
 	if natsLost.Load() {
 		os.Exit(1)
 	}
 }
 
-// runFetchLoop is the main message processing loop for the audit-sink.
-// It fetches batches of audit events and writes each to the NDJSON file.
-// Write failures are logged but do not cause a NAK — audit-sink always ACKs to
-// avoid retry loops on persistent filesystem errors.
-func runFetchLoop(ctx context.Context, sub *nats.Subscription, writer *NDJSONWriter, cfg natsx.PullConsumerConfig, instr *natsx.MsgInstruments, logger *slog.Logger) error {
-	sem := make(chan struct{}, cfg.WorkerCount)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
-
-		msgs, err := sub.Fetch(cfg.FetchBatch, nats.MaxWait(2*time.Second))
-		if err != nil {
-			// Survive a NATS bounce (#18): exit only on ctx cancellation; treat every
-			// other fetch error as transient and resume after nats.go reconnects.
-			switch natsx.ClassifyFetchErr(err, ctx.Err()) {
-			case natsx.FetchStop:
-				return nil
-			case natsx.FetchBackoff:
-				logger.Warn("audit-sink: transient fetch error, retrying",
-					slog.String("error", err.Error()))
-				select {
-				case <-ctx.Done():
-					return nil
-				case <-time.After(natsx.FetchRetryBackoff):
-				}
-			}
-			continue
-		}
-
-		for _, msg := range msgs {
-			select {
-			case sem <- struct{}{}:
-			case <-ctx.Done():
-				return nil
-			}
-			go func(m *nats.Msg) {
-				defer func() { <-sem }()
-				instr.Observe(ctx, m, cfg.Stream, cfg.Durable, func(_ context.Context) string {
-					return handleAuditMsg(m, writer, logger)
-				})
-			}(msg)
-		}
-	}
-}
-
 // handleAuditMsg writes the message payload to the NDJSON file and ACKs.
 // Write failures are logged but the message is still ACKed to prevent retry loops
 // on persistent filesystem errors (disk full, etc.). The AUDIT_EVENTS stream
 // retains messages for 72h as a recovery window.
-func handleAuditMsg(msg *nats.Msg, writer *NDJSONWriter, logger *slog.Logger) string {
+func handleAuditMsg(msg *nats.Msg, writer *NDJSONWriter, logger *slog.Logger) natsx.Result {
 	outcome := natsx.OutcomeSuccess
 	if err := writer.Write(msg.Data); err != nil {
 		logger.Warn("audit-sink: write failed, acking to avoid retry loop",
@@ -224,8 +188,7 @@ func handleAuditMsg(msg *nats.Msg, writer *NDJSONWriter, logger *slog.Logger) st
 			slog.Int("bytes", len(msg.Data)),
 		)
 	}
-	_ = msg.Ack()
-	return outcome
+	return natsx.Result{Decision: natsx.DecisionAck, Outcome: outcome}
 }
 
 func envOrDefault(key, fallback string) string {
@@ -1,10 +1,11 @@
 // Package config loads and validates YAML automation rule files for the engine
 // service (ADR-0006). The loader reads *.yaml files from RULES_DIR, validates
-// the schema version, and compiles them into a CompiledConfig that the engine
-// and gateway consume via NATS KV.
+// the schema version and every rule's conditions and actions, and compiles
+// them into a CompiledConfig that the engine and gateway consume via NATS KV.
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -42,14 +43,20 @@ type CompiledConfig struct {
 // validates each file's schema version, and returns a CompiledConfig derived
 // from all rules.
 //
-// Returns an error if RULES_DIR is empty, any file fails to parse, or any
-// file has an unrecognised schemaVersion. Callers must treat errors as fatal.
+// Returns an error if RULES_DIR is empty, any file fails to parse, any file
+// has an unrecognised schemaVersion, or any rule fails ValidateRule. An error
+// is fatal at engine start; on a hot reload it is logged and the previously
+// loaded rules stay in effect.
 func Load() (*CompiledConfig, error) {
-	dir := os.Getenv("RULES_DIR")
-	if dir == "" {
-		dir = defaultRulesDir
+	return LoadDir(Dir())
+}
+
+// Dir returns the rules directory Load reads: RULES_DIR, or "configs/rules".
+func Dir() string {
+	if dir := os.Getenv("RULES_DIR"); dir != "" {
+		return dir
 	}
-	return LoadDir(dir)
+	return defaultRulesDir
 }
 
 // LoadDir is the testable core of Load; it accepts an explicit directory path.
@@ -70,7 +77,7 @@ func LoadDir(dir string) (*CompiledConfig, error) {
 	attrSeen := make(map[string]map[string]struct{}) // domain → attribute set
 
 	for _, path := range paths {
-		rf, err := parseFile(path)
+		rf, err := ParseFile(path)
 		if err != nil {
 			return nil, err
 		}
@@ -82,15 +89,20 @@ func LoadDir(dir string) (*CompiledConfig, error) {
 	return cfg, nil
 }
 
-// parseFile reads and validates a single rule file.
-func parseFile(path string) (*schemas.RuleFile, error) {
+// ParseFile reads and validates a single rule file. Every invalid rule is
+// reported, each prefixed with the file and the line the rule starts on.
+func ParseFile(path string) (*schemas.RuleFile, error) {
 	data, err := os.ReadFile(path) //nolint:gosec // path comes from filepath.Glob on a trusted directory
 	if err != nil {
 		return nil, fmt.Errorf("config: read %q: %w", path, err)
 	}
 
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config: parse %q: %w", path, err)
+	}
 	var rf schemas.RuleFile
-	if err := yaml.Unmarshal(data, &rf); err != nil {
+	if err := doc.Decode(&rf); err != nil {
 		return nil, fmt.Errorf("config: parse %q: %w", path, err)
 	}
 
@@ -103,10 +115,40 @@ func parseFile(path string) (*schemas.RuleFile, error) {
 	if len(rf.Rules) == 0 {
 		return nil, fmt.Errorf("config: %q: no rules defined", path)
 	}
+	lines := ruleLines(&doc)
+	var errs []error
+	for i, rule := range rf.Rules {
+		if err := ValidateRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("config: %q: line %d: %w", path, lines[i], err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
 
 	return &rf, nil
 }
 
+// ruleLines returns the line each entry of the top-level "rules" sequence
+// starts on, indexed like RuleFile.Rules.
+func ruleLines(doc *yaml.Node) []int {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "rules" {
+			continue
+		}
+		var lines []int
+		for _, n := range root.Content[i+1].Content {
+			lines = append(lines, n.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
 // mergeExplicit adds top-level passlist and critical_entities from a RuleFile into
 // the compiled config, deduplicating against already-seen entries from rule triggers.
 func mergeExplicit(
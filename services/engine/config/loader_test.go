@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
@@ -323,3 +324,40 @@ rules:
 		t.Errorf("expected passlist to contain sensor.state, got %v", cfg.Passlist)
 	}
 }
+
+func TestParseFile_ReportsEveryInvalidRuleWithLine(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "bad.yaml", `schemaVersion: "1.0"
+rules:
+  - name: no_device
+    trigger: {source: ha, type: person, id: wife}
+    actions:
+      - type: notify
+        params: {title: T, message: M}
+  - name: ok
+    trigger: {source: ha, type: person, id: wife}
+    actions:
+      - type: notify
+        params: {title: T, message: M, device: d}
+  - name: bad_condition
+    trigger: {source: ha, type: sensor, id: temp}
+    conditions:
+      - type: bogus
+    actions:
+      - type: notify
+        params: {title: T, message: M, device: d}
+`)
+
+	_, err := config.ParseFile(filepath.Join(dir, "bad.yaml"))
+	if err == nil {
+		t.Fatal("expected validation errors, got nil")
+	}
+	for _, want := range []string{`line 3: rule "no_device"`, `line 13: rule "bad_condition"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `"ok"`) {
+		t.Errorf("valid rule reported: %v", err)
+	}
+}
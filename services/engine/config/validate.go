@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+)
+
+// ValidateRule checks that a schedule trigger and every condition and action
+// in rule are well formed for their type. It is applied to each rule at load
+// time so a malformed rule fails the boot rather than silently never firing.
+func ValidateRule(rule schemas.Rule) error {
+	if rule.Name == "" {
+		return errors.New("rule has no name")
+	}
+	if err := validateTrigger(rule.Trigger); err != nil {
+		return fmt.Errorf("rule %q: trigger: %w", rule.Name, err)
+	}
+	for i, c := range rule.Conditions {
+		if err := validateCondition(c); err != nil {
+			return fmt.Errorf("rule %q: conditions[%d]: %w", rule.Name, i, err)
+		}
+	}
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("rule %q: at least one action is required", rule.Name)
+	}
+	for i, a := range rule.Actions {
+		if err := validateAction(a); err != nil {
+			return fmt.Errorf("rule %q: actions[%d]: %w", rule.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// validateTrigger checks the fields of a schedule trigger: an id naming the
+// schedule and a cron expression. Other triggers must not set cron.
+func validateTrigger(t schemas.Trigger) error {
+	if t.Source != schemas.TriggerSourceSchedule {
+		if t.Cron != "" {
+			return fmt.Errorf("cron applies only to source %q", schemas.TriggerSourceSchedule)
+		}
+		return nil
+	}
+	if !natsx.IsValidToken(t.ID) {
+		return fmt.Errorf("%s: id %q: %w", t.Source, t.ID, natsx.ErrInvalidToken)
+	}
+	if _, err := scheduler.ParseCron(t.Cron); err != nil {
+		return fmt.Errorf("%s: %w", t.Source, err)
+	}
+	return nil
+}
+
+// validateCondition checks the fields required by c.Type, recursing into
+// composite conditions.
+func validateCondition(c schemas.Condition) error {
+	switch c.Type {
+	case schemas.ConditionTypeStateTransition:
+		if c.Value == "" {
+			return fmt.Errorf("%s: value is required", c.Type)
+		}
+
+	case schemas.ConditionTypeNumericState:
+		if c.Field == "" {
+			return fmt.Errorf("%s: field is required", c.Type)
+		}
+		if c.Above == nil && c.Below == nil {
+			return fmt.Errorf("%s: at least one of above or below is required", c.Type)
+		}
+		if c.Above != nil && c.Below != nil && *c.Above >= *c.Below {
+			return fmt.Errorf("%s: above (%g) must be less than below (%g)", c.Type, *c.Above, *c.Below)
+		}
+
+	case schemas.ConditionTypeAttribute:
+		if c.Field == "" {
+			return fmt.Errorf("%s: field is required", c.Type)
+		}
+		if (c.Value == "") == (c.Pattern == "") {
+			return fmt.Errorf("%s: exactly one of value or pattern is required", c.Type)
+		}
+		if c.Pattern != "" {
+			if _, err := regexp.Compile(c.Pattern); err != nil {
+				return fmt.Errorf("%s: pattern: %w", c.Type, err)
+			}
+		}
+
+	case schemas.ConditionTypeStateFor:
+		if c.Field == "" || c.Value == "" {
+			return fmt.Errorf("%s: field and value are required", c.Type)
+		}
+		d, err := time.ParseDuration(c.For)
+		if err != nil {
+			return fmt.Errorf("%s: for: %w", c.Type, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%s: for must be positive, got %q", c.Type, c.For)
+		}
+
+	case schemas.ConditionTypeTimeOfDay:
+		if c.After == "" && c.Before == "" {
+			return fmt.Errorf("%s: at least one of after or before is required", c.Type)
+		}
+		for _, s := range []string{c.After, c.Before} {
+			if s == "" {
+				continue
+			}
+			if _, err := schemas.ParseTimeOfDay(s); err != nil {
+				return fmt.Errorf("%s: %w", c.Type, err)
+			}
+		}
+
+	case schemas.ConditionTypeDayOfWeek:
+		if len(c.Weekdays) == 0 {
+			return fmt.Errorf("%s: weekdays is required", c.Type)
+		}
+		for _, s := range c.Weekdays {
+			if _, err := schemas.ParseWeekday(s); err != nil {
+				return fmt.Errorf("%s: %w", c.Type, err)
+			}
+		}
+
+	case schemas.ConditionTypeAll, schemas.ConditionTypeAny, schemas.ConditionTypeNot:
+		if len(c.Conditions) == 0 {
+			return fmt.Errorf("%s: conditions is required", c.Type)
+		}
+		for i, sub := range c.Conditions {
+			if err := validateCondition(sub); err != nil {
+				return fmt.Errorf("%s.conditions[%d]: %w", c.Type, i, err)
+			}
+		}
+
+	case "":
+		return errors.New("type is required")
+	default:
+		return fmt.Errorf("unknown condition type %q", c.Type)
+	}
+	return nil
+}
+
+// validateAction checks the params required by a.Type, recursing into
+// sequence steps.
+func validateAction(a schemas.Action) error {
+	switch a.OnError {
+	case "", schemas.OnErrorStop, schemas.OnErrorContinue:
+	default:
+		return fmt.Errorf("%s: on_error must be %q or %q, got %q", a.Type, schemas.OnErrorStop, schemas.OnErrorContinue, a.OnError)
+	}
+
+	switch a.Type {
+	case schemas.ActionTypeNotify:
+		for _, k := range []string{"title", "message", "device"} {
+			if a.Params[k] == "" {
+				return fmt.Errorf("%s: params.%s is required", a.Type, k)
+			}
+		}
+
+	case schemas.ActionTypeHAService:
+		domain, service, ok := strings.Cut(a.Params["service"], ".")
+		if !ok || domain == "" || service == "" {
+			return fmt.Errorf("%s: params.service must be \"{domain}.{service}\", got %q", a.Type, a.Params["service"])
+		}
+
+	case schemas.ActionTypePublish:
+		if err := validateTokens(a.Params["subject"]); err != nil {
+			return fmt.Errorf("%s: params.subject: %w", a.Type, err)
+		}
+		if first, _, _ := strings.Cut(a.Params["subject"], "."); first == "schedule" {
+			return fmt.Errorf("%s: params.subject: schedule.> is reserved for the scheduler", a.Type)
+		}
+		if a.Params["type"] == "" {
+			return fmt.Errorf("%s: params.type is required", a.Type)
+		}
+
+	case schemas.ActionTypeKVSet:
+		if err := validateTokens(a.Params["key"]); err != nil {
+			return fmt.Errorf("%s: params.key: %w", a.Type, err)
+		}
+		if _, ok := a.Params["value"]; !ok {
+			return fmt.Errorf("%s: params.value is required", a.Type)
+		}
+
+	case schemas.ActionTypeDelay:
+		d, err := time.ParseDuration(a.Params["duration"])
+		if err != nil {
+			return fmt.Errorf("%s: params.duration: %w", a.Type, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%s: params.duration must be positive, got %q", a.Type, a.Params["duration"])
+		}
+
+	case schemas.ActionTypeSequence:
+		if len(a.Steps) == 0 {
+			return fmt.Errorf("%s: steps is required", a.Type)
+		}
+		for i, step := range a.Steps {
+			if err := validateAction(step); err != nil {
+				return fmt.Errorf("%s.steps[%d]: %w", a.Type, i, err)
+			}
+		}
+
+	case "":
+		return errors.New("type is required")
+	default:
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+	return nil
+}
+
+// validateTokens checks that s is one or more dot-separated ADR-0027 subject tokens.
+func validateTokens(s string) error {
+	if s == "" {
+		return errors.New("is required")
+	}
+	for tok := range strings.SplitSeq(s, ".") {
+		if !natsx.IsValidToken(tok) {
+			return fmt.Errorf("token %q: %w", tok, natsx.ErrInvalidToken)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+//go:build fast
+
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func ruleWith(conds ...schemas.Condition) schemas.Rule {
+	return schemas.Rule{
+		Name:       "r",
+		Trigger:    schemas.Trigger{Source: "ha", Type: "sensor", ID: "temp"},
+		Conditions: conds,
+		Actions: []schemas.Action{{Type: schemas.ActionTypeNotify, Params: map[string]string{
+			"title": "T", "message": "M", "device": "d",
+		}}},
+	}
+}
+
+func TestValidateRule_Conditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    schemas.Condition
+		wantErr string // empty means valid
+	}{
+		{"state_transition", schemas.Condition{Type: "state_transition", Field: "state", Value: "on"}, ""},
+		{"state_transition no value", schemas.Condition{Type: "state_transition", Field: "state"}, "value is required"},
+		{"numeric above", schemas.Condition{Type: "numeric_state", Field: "state", Above: ptr(20)}, ""},
+		{"numeric range", schemas.Condition{Type: "numeric_state", Field: "state", Above: ptr(20), Below: ptr(25)}, ""},
+		{"numeric no bounds", schemas.Condition{Type: "numeric_state", Field: "state"}, "above or below"},
+		{"numeric inverted", schemas.Condition{Type: "numeric_state", Field: "state", Above: ptr(30), Below: ptr(25)}, "must be less than"},
+		{"attribute value", schemas.Condition{Type: "attribute", Field: "mode", Value: "heat"}, ""},
+		{"attribute pattern", schemas.Condition{Type: "attribute", Field: "mode", Pattern: "^(heat|cool)$"}, ""},
+		{"attribute both", schemas.Condition{Type: "attribute", Field: "mode", Value: "a", Pattern: "a"}, "exactly one"},
+		{"attribute bad regex", schemas.Condition{Type: "attribute", Field: "mode", Pattern: "("}, "pattern"},
+		{"state_for", schemas.Condition{Type: "state_for", Field: "state", Value: "on", For: "10m"}, ""},
+		{"state_for bad duration", schemas.Condition{Type: "state_for", Field: "state", Value: "on", For: "ten"}, "for"},
+		{"state_for zero", schemas.Condition{Type: "state_for", Field: "state", Value: "on", For: "0s"}, "positive"},
+		{"time_of_day wrap", schemas.Condition{Type: "time_of_day", After: "22:00", Before: "06:00"}, ""},
+		{"time_of_day empty", schemas.Condition{Type: "time_of_day"}, "after or before"},
+		{"time_of_day bad", schemas.Condition{Type: "time_of_day", After: "25:00"}, "HH:MM"},
+		{"day_of_week", schemas.Condition{Type: "day_of_week", Weekdays: []string{"mon", "Fri"}}, ""},
+		{"day_of_week bad", schemas.Condition{Type: "day_of_week", Weekdays: []string{"monday"}}, "weekday"},
+		{"any nested", schemas.Condition{Type: "any", Conditions: []schemas.Condition{
+			{Type: "attribute", Field: "mode", Value: "heat"},
+			{Type: "not", Conditions: []schemas.Condition{{Type: "day_of_week", Weekdays: []string{"sun"}}}},
+		}}, ""},
+		{"all empty", schemas.Condition{Type: "all"}, "conditions is required"},
+		{"nested invalid", schemas.Condition{Type: "not", Conditions: []schemas.Condition{{Type: "numeric_state", Field: "x"}}}, "not.conditions[0]"},
+		{"unknown type", schemas.Condition{Type: "bogus"}, "unknown condition type"},
+		{"missing type", schemas.Condition{}, "type is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ValidateRule(ruleWith(tt.cond))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("expected error containing %q, got nil", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRule_Actions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  schemas.Action
+		wantErr string // empty means valid
+	}{
+		{"notify missing device", schemas.Action{Type: "notify", Params: map[string]string{"title": "T", "message": "M"}}, "params.device"},
+		{"ha_service", schemas.Action{Type: "ha_service", Params: map[string]string{"service": "light.turn_on", "entity_id": "light.porch"}, Data: map[string]any{"brightness": 128}}, ""},
+		{"ha_service bad service", schemas.Action{Type: "ha_service", Params: map[string]string{"service": "turn_on"}}, "{domain}.{service}"},
+		{"publish", schemas.Action{Type: "publish", Params: map[string]string{"subject": "house.night_mode", "type": "house.night_mode"}}, ""},
+		{"publish bad subject", schemas.Action{Type: "publish", Params: map[string]string{"subject": "House.Mode", "type": "t"}}, "params.subject"},
+		{"publish no type", schemas.Action{Type: "publish", Params: map[string]string{"subject": "house"}}, "params.type"},
+		{"publish reserved subject", schemas.Action{Type: "publish", Params: map[string]string{"subject": "schedule.rules.x", "type": "t"}}, "reserved"},
+		{"kv_set", schemas.Action{Type: "kv_set", Params: map[string]string{"key": "night_mode", "value": "on"}}, ""},
+		{"kv_set no value", schemas.Action{Type: "kv_set", Params: map[string]string{"key": "night_mode"}}, "params.value"},
+		{"delay", schemas.Action{Type: "delay", Params: map[string]string{"duration": "5m"}}, ""},
+		{"delay bad", schemas.Action{Type: "delay", Params: map[string]string{"duration": "-1s"}}, "positive"},
+		{"sequence", schemas.Action{Type: "sequence", Steps: []schemas.Action{
+			{Type: "kv_set", Params: map[string]string{"key": "a", "value": "1"}, OnError: "continue"},
+			{Type: "delay", Params: map[string]string{"duration": "1s"}},
+		}}, ""},
+		{"sequence empty", schemas.Action{Type: "sequence"}, "steps is required"},
+		{"sequence bad step", schemas.Action{Type: "sequence", Steps: []schemas.Action{{Type: "delay"}}}, "sequence.steps[0]"},
+		{"bad on_error", schemas.Action{Type: "kv_set", Params: map[string]string{"key": "a", "value": "1"}, OnError: "retry"}, "on_error"},
+		{"unknown type", schemas.Action{Type: "bogus"}, "unknown action type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ruleWith()
+			r.Actions = []schemas.Action{tt.action}
+			err := config.ValidateRule(r)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("expected error containing %q, got nil", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	r := ruleWith()
+	r.Actions = nil
+	if err := config.ValidateRule(r); err == nil {
+		t.Error("expected error for rule without actions")
+	}
+}
+
+func TestValidateRule_Trigger(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger schemas.Trigger
+		wantErr string // empty means valid
+	}{
+		{"ha", schemas.Trigger{Source: "ha", Type: "sensor", ID: "temp"}, ""},
+		{"ha with cron", schemas.Trigger{Source: "ha", Type: "sensor", Cron: "0 7 * * *"}, "cron applies only"},
+		{"schedule", schemas.Trigger{Source: "schedule", ID: "morning", Cron: "0 7 * * mon-fri"}, ""},
+		{"schedule no id", schemas.Trigger{Source: "schedule", Cron: "0 7 * * *"}, "id"},
+		{"schedule bad cron", schemas.Trigger{Source: "schedule", ID: "morning", Cron: "7am"}, "want 5 fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ruleWith()
+			r.Trigger = tt.trigger
+			err := config.ValidateRule(r)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("expected error containing %q, got nil", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadDir_InvalidConditionFailsLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "bad.yaml", `
+schemaVersion: "1.0"
+rules:
+  - name: too_hot
+    trigger:
+      source: ha
+      type: sensor
+      id: temp
+    conditions:
+      - type: numeric_state
+        field: state
+    actions:
+      - type: notify
+        params:
+          title: T
+          message: M
+          device: d
+`)
+
+	_, err := config.LoadDir(dir)
+	if err == nil {
+		t.Fatal("expected error for numeric_state without bounds, got nil")
+	}
+	if !strings.Contains(err.Error(), "too_hot") {
+		t.Errorf("error should name the rule, got: %v", err)
+	}
+}
+
+func TestLoadDir_RichConditionsParsed(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "rich.yaml", `
+schemaVersion: "1.0"
+rules:
+  - name: too_hot_at_night
+    trigger:
+      source: ha
+      type: sensor
+      id: nursery_temp
+    conditions:
+      - type: numeric_state
+        field: state
+        above: 24.5
+      - type: any
+        conditions:
+          - type: time_of_day
+            after: "22:00"
+            before: "06:00"
+          - type: day_of_week
+            weekdays: [sat, sun]
+    actions:
+      - type: notify
+        params:
+          title: T
+          message: M
+          device: d
+`)
+
+	cfg, err := config.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conds := cfg.Rules[0].Conditions
+	if len(conds) != 2 {
+		t.Fatalf("want 2 conditions, got %d", len(conds))
+	}
+	if conds[0].Above == nil || *conds[0].Above != 24.5 {
+		t.Errorf("numeric_state above not parsed: %+v", conds[0])
+	}
+	if len(conds[1].Conditions) != 2 || conds[1].Conditions[1].Weekdays[1] != "sun" {
+		t.Errorf("nested conditions not parsed: %+v", conds[1])
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls RULES_DIR for changes.
+const DefaultWatchInterval = 10 * time.Second
+
+// Watch polls dir every interval and calls fn with the result of LoadDir each
+// time the set of *.yaml files or their contents change. A change is loaded
+// once it has been stable for a full interval, so a file caught mid-write is not
+// reported as invalid. A load error is passed to fn with a nil config; the
+// caller keeps its last good config. Watch blocks until ctx is cancelled.
+//
+// Polling a content digest rather than using inotify keeps Watch dependency-free
+// and also catches the atomic symlink swaps used by mounted config volumes,
+// which do not always produce events on the watched directory.
+func Watch(ctx context.Context, dir string, interval time.Duration, fn func(*CompiledConfig, error)) {
+	last, _ := digest(dir)
+	pending := last
+	failing := false
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sum, err := digest(dir)
+		if err != nil {
+			if !failing {
+				fn(nil, err) // report once per outage rather than every tick
+			}
+			failing = true
+			continue
+		}
+		failing = false
+		if sum != pending {
+			pending = sum // changed since the last tick: wait for it to settle
+			continue
+		}
+		if sum == last {
+			continue
+		}
+		last = sum
+		fn(LoadDir(dir))
+	}
+}
+
+// digest hashes the names and contents of the *.yaml files in dir.
+func digest(dir string) ([sha256.Size]byte, error) {
+	pattern := filepath.Join(dir, "*.yaml")
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("config: glob %q: %w", pattern, err)
+	}
+	h := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path) //nolint:gosec // path comes from filepath.Glob on a trusted directory
+		if err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("config: read %q: %w", path, err)
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(path), len(data))
+		h.Write(data)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
@@ -0,0 +1,102 @@
+//go:build fast
+
+package config_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+)
+
+const watchRule = `
+schemaVersion: "1.0"
+rules:
+  - name: %s
+    trigger:
+      source: ha
+      type: person
+      id: %s
+    actions:
+      - type: notify
+        params:
+          title: "T"
+          message: "M"
+          device: d
+`
+
+type loadResult struct {
+	cfg *config.CompiledConfig
+	err error
+}
+
+// startWatch runs config.Watch on dir with a short interval and returns the
+// channel its callbacks are delivered on.
+func startWatch(t *testing.T, dir string) <-chan loadResult {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan loadResult, 8)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		config.Watch(ctx, dir, 5*time.Millisecond, func(cfg *config.CompiledConfig, err error) {
+			ch <- loadResult{cfg, err}
+		})
+	}()
+	t.Cleanup(func() { cancel(); <-done })
+	// Let Watch take its baseline digest before the test edits files.
+	time.Sleep(20 * time.Millisecond)
+	return ch
+}
+
+func next(t *testing.T, ch <-chan loadResult) loadResult {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not report a change")
+		return loadResult{}
+	}
+}
+
+func TestWatch_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "a.yaml", fmt.Sprintf(watchRule, "a", "wife"))
+	ch := startWatch(t, dir)
+
+	// Unchanged files produce no callback.
+	select {
+	case r := <-ch:
+		t.Fatalf("unexpected callback without a change: %+v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeYAML(t, dir, "b.yaml", fmt.Sprintf(watchRule, "b", "husband"))
+	r := next(t, ch)
+	if r.err != nil {
+		t.Fatalf("reload error: %v", r.err)
+	}
+	if len(r.cfg.Rules) != 2 || len(r.cfg.CriticalEntities) != 2 {
+		t.Errorf("reloaded config = %d rules / %v, want 2 rules", len(r.cfg.Rules), r.cfg.CriticalEntities)
+	}
+}
+
+func TestWatch_ReportsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "a.yaml", fmt.Sprintf(watchRule, "a", "wife"))
+	ch := startWatch(t, dir)
+
+	writeYAML(t, dir, "a.yaml", "schemaVersion: v9\nrules: []\n")
+	if r := next(t, ch); r.err == nil || r.cfg != nil {
+		t.Errorf("expected a load error and nil config, got %+v", r)
+	}
+
+	// Fixing the file is picked up as another change.
+	writeYAML(t, dir, "a.yaml", fmt.Sprintf(watchRule, "a", "wife"))
+	if r := next(t, ch); r.err != nil {
+		t.Errorf("expected the fixed file to load, got %v", r.err)
+	}
+}
@@ -5,16 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
 	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
 // handleResult describes the outcome of processing a single message.
@@ -24,8 +24,14 @@ const (
 	resultAck  handleResult = iota // message processed successfully
 	resultNak                      // processing failed; redeliver with backoff
 	resultSkip                     // duplicate message; ack without processing
+	resultBusy                     // another worker holds the event's claim; redeliver after the lease
 )
 
+// rejectPublisher is the subset of nats.JetStreamContext used to route rejected events.
+type rejectPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
 // Recorder is the interface implemented by audit.Publisher.
 // Defining it here keeps the consumer decoupled from the audit package (ADR-0019).
 // Inject a NoopRecorder in tests; inject *audit.Publisher in production.
@@ -49,6 +55,32 @@ type Consumer struct {
 	batchSize int
 	backOff   []time.Duration // NAK delay schedule; mirrors consumer BackOff config
 
+	// minWorkers, when set below workerN, lets the worker pool adapt its concurrency
+	// between the two (set by main from the manifest's MinWorkerCount).
+	minWorkers int
+
+	// Per-processor delivery (set by main). accepts, when set, filters the stream
+	// down to the subjects the consumer's processor subscribes to; other messages,
+	// which the consumer's FilterSubjects let through only while they catch up with
+	// a reload, are acked without an idempotency check. idScope prefixes idempotency
+	// keys so consumers sharing the store track the same event independently.
+	accepts func(subject string) bool
+	idScope string
+	// legacyThrough is the last stream sequence the legacy shared consumer
+	// delivered (see legacyStartSeq). An event up to it that the legacy consumer
+	// completed under its bare ID is a duplicate.
+	legacyThrough uint64
+
+	// failures remembers why each unacked message last failed, for the consumer's
+	// natsx.DLQForwarder (nil-safe).
+	failures *natsx.FailureLog
+
+	// rejects, when set, enables schema validation (ADR-0014): an event that fails
+	// schemas.ValidateJSON (schemas.ValidateBinary in binary mode) is published to
+	// rejected.{subject} and acked instead of reaching the processor and naking
+	// until it is dead-lettered.
+	rejects rejectPublisher
+
 	// Observability (set by main after otel.Init; all nil-safe). stream/consumerName
 	// label the metrics; instruments records processed-count + duration; dedup counts
 	// idempotency discards (#137).
@@ -94,96 +126,90 @@ func NewConsumer(
 		workerN:   workerN,
 		batchSize: batchSize,
 		backOff:   backOff,
+		failures:  natsx.NewFailureLog(),
 	}, nil
 }
 
-// nakDelay returns the delay to use with NakWithDelay for the given delivery number.
-// When numDelivered exceeds the backOff schedule (i.e. final attempt), returns 0
-// so the server fires the max-delivery advisory without an extra wait.
-func nakDelay(backOff []time.Duration, numDelivered uint64) time.Duration {
-	if len(backOff) == 0 || numDelivered == 0 || numDelivered > uint64(len(backOff)) {
-		return 0
-	}
-	return backOff[numDelivered-1]
-}
-
-// Run starts the fetch loop, dispatching messages to a fixed worker pool.
-// It returns when ctx is cancelled or a non-recoverable fetch error occurs.
+// Run processes messages on a natsx.WorkerPool until ctx is cancelled, then waits
+// for in-flight messages to settle before returning.
 func (c *Consumer) Run(ctx context.Context) error {
-	sem := make(chan struct{}, c.workerN)
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
-
-		msgs, err := c.sub.Fetch(c.batchSize, nats.MaxWait(2*time.Second))
-		if err != nil {
-			// Survive a NATS bounce: only ctx cancellation exits the loop; every other
-			// fetch error is transient (nats.go reconnects underneath) so we log, back
-			// off, and resume rather than returning a fatal error that wedged the
-			// process (#18).
-			switch natsx.ClassifyFetchErr(err, ctx.Err()) {
-			case natsx.FetchStop:
-				return nil
-			case natsx.FetchBackoff:
-				c.logger().Warn("engine: transient fetch error, retrying",
-					slog.String("error", err.Error()))
-				select {
-				case <-ctx.Done():
-					return nil
-				case <-time.After(natsx.FetchRetryBackoff):
-				}
-			}
-			continue
-		}
-
-		for _, msg := range msgs {
-			select {
-			case sem <- struct{}{}:
-			case <-ctx.Done():
-				return nil
-			}
-			go func(m *nats.Msg) {
-				defer func() { <-sem }()
-				c.instruments.Observe(ctx, m, c.stream, c.consumerName, func(sctx context.Context) string {
-					return c.handle(sctx, m)
-				})
-			}(msg)
-		}
+	pool, err := natsx.NewWorkerPool(c.sub, natsx.PullConsumerConfig{
+		Stream:         c.stream,
+		Durable:        c.consumerName,
+		AckWait:        config.DefaultAckWait,
+		BackOff:        c.backOff,
+		WorkerCount:    c.workerN,
+		MinWorkerCount: c.minWorkers,
+		FetchBatch:     c.batchSize,
+	}, c.handle, c.instruments, c.failures, c.log)
+	if err != nil {
+		return err
 	}
+	return pool.Run(ctx)
 }
 
-// handle processes a single message: checks idempotency, calls process, then acks/naks.
-// Structured log entries include correlationid and causationid from the CloudEvent payload.
-// It returns the outcome label (natsx.Outcome*) for metric recording by the caller.
-func (c *Consumer) handle(ctx context.Context, msg *nats.Msg) string {
+// handle processes a single message: claims its event ID, calls process, and returns
+// how the worker pool should settle it. Structured log entries include correlationid
+// and causationid from the CloudEvent, read from the ce-* headers of a binary-mode
+// event. A binary-mode body is only decoded by schema validation, and only when
+// its ce-type has a registered schema.
+func (c *Consumer) handle(ctx context.Context, msg *nats.Msg) natsx.Result {
 	meta, err := msg.Metadata()
 	if err != nil {
 		c.logger().Error("engine: metadata error", slog.String("error", err.Error()))
-		_ = msg.Nak()
-		return natsx.OutcomeFailure
+		return natsx.Nak(err)
 	}
 
-	eventID := extractEventID(msg.Header, msg.Data, meta)
-	correlationID, causationID := extractCorrelationFields(msg.Data)
+	if c.accepts != nil && !c.accepts(msg.Subject) {
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeFiltered}
+	}
 
-	result, err := c.decide(ctx, msg.Subject, eventID, msg.Data)
+	// Processors decode structured-mode envelopes; a binary-mode event is rebuilt
+	// into one around its data, and validated from its headers and body.
+	data, err := natsx.EventData(msg)
+	if err == nil && c.rejects != nil {
+		if schemas.IsBinary(msg.Header) {
+			err = schemas.ValidateBinary(msg.Header, msg.Data)
+		} else {
+			_, err = schemas.ValidateJSON(data)
+		}
+	}
+	if err != nil {
+		if c.rejects == nil {
+			return natsx.Term(err)
+		}
+		return c.reject(msg, extractEventID(msg.Header, data, meta), err)
+	}
+
+	rawID := extractEventID(msg.Header, data, meta)
+	eventID := c.idempotencyKey(rawID)
+	correlationID, causationID := extractCorrelationFields(msg.Header, data)
+
+	var result handleResult
+	var cause error
+	switch legacy, lerr := c.legacyClaim(meta.Sequence.Stream, rawID); {
+	case lerr != nil:
+		err = lerr
+	case legacy == idempotency.Done:
+		result = resultSkip
+	case legacy == idempotency.InFlight:
+		result, cause = resultBusy, fmt.Errorf("idempotency: %s is claimed by the legacy consumer", rawID)
+	default:
+		result, cause, err = c.decide(ctx, msg.Subject, eventID, data)
+	}
 	if err != nil {
 		c.logger().Error("engine: decide error",
 			slog.String("eventid", eventID),
 			slog.String("correlationid", correlationID),
 			slog.String("error", err.Error()),
 		)
-		_ = msg.Nak()
-		return natsx.OutcomeFailure
+		return natsx.Nak(err)
 	}
 
 	switch result {
 	case resultAck:
-		if err := c.idStore.Mark(eventID); err != nil {
-			c.logger().Warn("engine: idempotency mark error",
+		if err := c.idStore.Complete(eventID); err != nil {
+			c.logger().Warn("engine: idempotency complete error",
 				slog.String("eventid", eventID),
 				slog.String("correlationid", correlationID),
 				slog.String("error", err.Error()),
@@ -195,75 +221,153 @@ func (c *Consumer) handle(ctx context.Context, msg *nats.Msg) string {
 			slog.String("correlationid", correlationID),
 			slog.String("subject", msg.Subject),
 		)
-		_ = msg.Ack()
-		return natsx.OutcomeSuccess
+		return natsx.Ack()
 
 	case resultNak:
 		c.audit.Record(correlationID, causationID, "event.failed", msg.Subject, "failure")
-		if d := nakDelay(c.backOff, meta.NumDelivered); d > 0 {
-			_ = msg.NakWithDelay(d)
-		} else {
-			_ = msg.Nak() // final attempt: no delay, let advisory fire promptly
-		}
-		return natsx.OutcomeFailure
+		return natsx.Nak(cause)
 
 	case resultSkip:
 		c.audit.Record(correlationID, causationID, "event.discarded", msg.Subject, "duplicate")
 		if c.dedup != nil {
 			c.dedup.Add(context.Background(), 1, metric.WithAttributes(attribute.String("service", "engine")))
 		}
-		_ = msg.Ack() // ack duplicates to remove from pending
-		return natsx.OutcomeDuplicate
+		// Ack duplicates to remove from pending.
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeDuplicate}
+
+	case resultBusy:
+		// Not a processing failure, but the nak records it so a message that runs
+		// out of deliveries while claimed elsewhere says why in the DLQ.
+		return natsx.Result{Decision: natsx.DecisionNak, Outcome: natsx.OutcomeInFlight,
+			Delay: config.DefaultIdempotencyLease, Err: cause}
 	}
 
-	return natsx.OutcomeFailure // unreachable: decide returns one of the three results
+	return natsx.Nak(fmt.Errorf("engine: unknown decide result %d", result)) // unreachable
 }
 
-// decide evaluates idempotency and calls the process function.
+// reject routes an event that failed schema validation to the REJECTED stream and
+// acks it: no redelivery can make it valid. The rejection's Nats-Msg-Id is the
+// event's unscoped eventID, so the stream keeps one rejection however many
+// processor consumers reject the event. If the rejection cannot be published the
+// message is naked so the event is not lost.
+func (c *Consumer) reject(msg *nats.Msg, eventID string, reason error) natsx.Result {
+	correlationID, causationID := extractCorrelationFields(msg.Header, msg.Data)
+	rej := natsx.RejectedMsg(msg.Subject, msg.Header, msg.Data, c.consumerName, reason)
+	rej.Header.Set(nats.MsgIdHdr, eventID)
+	if _, err := c.rejects.PublishMsg(rej); err != nil {
+		c.logger().Error("engine: publish rejected event failed",
+			slog.String("subject", msg.Subject),
+			slog.String("correlationid", correlationID),
+			slog.String("error", err.Error()),
+		)
+		return natsx.Nak(fmt.Errorf("publish rejected event: %w", err))
+	}
+	c.audit.Record(correlationID, causationID, "event.rejected", msg.Subject, "rejected")
+	c.logger().Warn("engine: event rejected",
+		slog.String("subject", msg.Subject),
+		slog.String("consumer", c.consumerName),
+		slog.String("correlationid", correlationID),
+		slog.String("reason", reason.Error()),
+	)
+	return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeRejected}
+}
+
+// decide claims eventID and calls the process function.
 // It is a pure decision function, separated from NATS types to enable unit testing.
-func (c *Consumer) decide(ctx context.Context, subject, eventID string, data []byte) (handleResult, error) {
-	seen, err := c.idStore.Seen(eventID)
+// cause is the processing error behind a resultNak (or the held claim behind a
+// resultBusy); err reports a failure to decide.
+func (c *Consumer) decide(ctx context.Context, subject, eventID string, data []byte) (result handleResult, cause, err error) {
+	claim, err := c.idStore.Claim(eventID)
 	if err != nil {
-		return resultNak, fmt.Errorf("idempotency check: %w", err)
+		return resultNak, nil, fmt.Errorf("idempotency claim: %w", err)
 	}
-	if seen {
+	switch claim {
+	case idempotency.Done:
 		c.logger().Info("engine: duplicate event, discarding",
 			slog.String("eventid", eventID),
 		)
-		return resultSkip, nil
+		return resultSkip, nil, nil
+	case idempotency.InFlight:
+		c.logger().Info("engine: event claimed by another worker, deferring",
+			slog.String("eventid", eventID),
+		)
+		return resultBusy, fmt.Errorf("idempotency: %s is claimed by another worker", eventID), nil
 	}
 	if err := c.process(ctx, subject, data); err != nil {
 		c.logger().Warn("engine: process error, will nak",
 			slog.String("eventid", eventID),
 			slog.String("error", err.Error()),
 		)
-		return resultNak, nil
+		if rerr := c.idStore.Release(eventID); rerr != nil {
+			c.logger().Warn("engine: idempotency release error",
+				slog.String("eventid", eventID),
+				slog.String("error", rerr.Error()),
+			)
+		}
+		return resultNak, err, nil
+	}
+	return resultAck, nil, nil
+}
+
+// legacyClaim checks the bare key the legacy shared consumer recorded eventID
+// under, for an event at stream sequence seq it may have delivered. It returns
+// Claimed, having released the claim, when the legacy consumer did not settle
+// the event, or for any event past legacyThrough without touching the store.
+func (c *Consumer) legacyClaim(seq uint64, eventID string) (idempotency.ClaimResult, error) {
+	if c.idScope == "" || seq > c.legacyThrough {
+		return idempotency.Claimed, nil
+	}
+	claim, err := c.idStore.Claim(eventID)
+	if err != nil {
+		return 0, fmt.Errorf("idempotency legacy claim: %w", err)
+	}
+	if claim == idempotency.Claimed {
+		if err := c.idStore.Release(eventID); err != nil {
+			return 0, fmt.Errorf("idempotency legacy release: %w", err)
+		}
+	}
+	return claim, nil
+}
+
+// idempotencyKey scopes eventID to the consumer when idScope is set.
+func (c *Consumer) idempotencyKey(eventID string) string {
+	if c.idScope == "" {
+		return eventID
 	}
-	return resultAck, nil
+	return c.idScope + "." + eventID
 }
 
 // extractEventID derives a stable event identifier for idempotency tracking (ADR-0025).
-// Priority: Nats-Msg-Id header → CloudEvent id field → stream sequence fallback.
+// Priority: Nats-Msg-Id header → binary-mode ce-id header → CloudEvent id field →
+// stream sequence fallback.
 func extractEventID(headers nats.Header, data []byte, meta *nats.MsgMetadata) string {
 	// 1. Nats-Msg-Id header (set by publishers using NATS dedup header)
 	if id := headers.Get("Nats-Msg-Id"); id != "" {
 		return id
 	}
-	// 2. CloudEvent id field (fast single-field unmarshal; small payloads in practice)
+	// 2. ce-id header of a binary-mode CloudEvent (no body parse)
+	if id := headers.Get(schemas.HeaderID); id != "" {
+		return id
+	}
+	// 3. CloudEvent id field (fast single-field unmarshal; small payloads in practice)
 	var ce struct {
 		ID string `json:"id"`
 	}
 	if err := json.Unmarshal(data, &ce); err == nil && ce.ID != "" {
 		return ce.ID
 	}
-	// 3. Stream sequence — always unique within the stream (handles at-least-once redelivery)
+	// 4. Stream sequence — always unique within the stream (handles at-least-once redelivery)
 	return fmt.Sprintf("%s.%d", meta.Stream, meta.Sequence.Stream)
 }
 
 // extractCorrelationFields extracts the correlationid and causationid extensions from
-// a CloudEvent payload in a single JSON pass. Returns empty strings for non-CloudEvent
-// or malformed payloads; callers must treat empty as "unavailable" rather than an error.
-func extractCorrelationFields(data []byte) (correlationID, causationID string) {
+// the ce-* headers of a binary-mode CloudEvent, or else from a structured payload in a
+// single JSON pass. Returns empty strings for non-CloudEvent or malformed payloads;
+// callers must treat empty as "unavailable" rather than an error.
+func extractCorrelationFields(headers nats.Header, data []byte) (correlationID, causationID string) {
+	if schemas.IsBinary(headers) {
+		return headers.Get(schemas.HeaderCorrelationID), headers.Get(schemas.HeaderCausationID)
+	}
 	var ce struct {
 		CorrelationID string `json:"correlationid"`
 		CausationID   string `json:"causationid"`
@@ -271,132 +375,3 @@ func extractCorrelationFields(data []byte) (correlationID, causationID string) {
 	_ = json.Unmarshal(data, &ce)
 	return ce.CorrelationID, ce.CausationID
 }
-
-// ---------------------------------------------------------------------------
-// DLQForwarder — routes dead-lettered messages to the DLQ stream (ADR-0022)
-// ---------------------------------------------------------------------------
-
-// maxDeliverAdvisory is the payload emitted by the NATS server when a consumer
-// exceeds MaxDeliver attempts for a message.
-type maxDeliverAdvisory struct {
-	Stream     string `json:"stream"`
-	Consumer   string `json:"consumer"`
-	StreamSeq  uint64 `json:"stream_seq"`
-	Deliveries int    `json:"deliveries"`
-}
-
-// DLQForwarder subscribes to the NATS server max-delivery advisory for a specific
-// consumer and republishes the original payload to the DLQ stream (ADR-0022).
-//
-// Why advisory-based: ConsumerConfig has no Republish field in nats.go v1.48.0.
-// StreamConfig.RePublish copies ALL messages, not just dead-lettered ones.
-// The advisory is the only server-side signal for max-delivery exhaustion.
-type DLQForwarder struct {
-	js        nats.JetStreamContext
-	sub       *nats.Subscription
-	msgCh     chan *nats.Msg
-	log       *slog.Logger
-	stream    string              // e.g. "HA_EVENTS"
-	consumer  string              // e.g. "engine_processor"
-	forwarded metric.Int64Counter // ruby_core_dlq_forwarded_total{stream}
-}
-
-// logger returns f.log if set, otherwise slog.Default().
-func (f *DLQForwarder) logger() *slog.Logger {
-	if f.log != nil {
-		return f.log
-	}
-	return slog.Default()
-}
-
-// NewDLQForwarder subscribes to the max-delivery advisory for the given stream/consumer
-// and returns a DLQForwarder ready to be started with Run.
-func NewDLQForwarder(nc *nats.Conn, js nats.JetStreamContext, stream, consumer string, log *slog.Logger) (*DLQForwarder, error) {
-	advisorySubj := fmt.Sprintf("$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.%s.%s", stream, consumer)
-	msgCh := make(chan *nats.Msg, 64)
-	sub, err := nc.ChanSubscribe(advisorySubj, msgCh)
-	if err != nil {
-		return nil, fmt.Errorf("engine: dlq forwarder subscribe %q: %w", advisorySubj, err)
-	}
-	forwarded, _ := otel.Meter("github.com/primaryrutabaga/ruby-core/services/engine").Int64Counter(
-		"ruby_core_dlq_forwarded_total",
-		metric.WithDescription("Messages routed to the DLQ stream after max-delivery exhaustion"),
-	)
-	return &DLQForwarder{
-		js:        js,
-		sub:       sub,
-		msgCh:     msgCh,
-		log:       log,
-		stream:    stream,
-		consumer:  consumer,
-		forwarded: forwarded,
-	}, nil
-}
-
-// Run processes advisory messages until ctx is cancelled.
-func (f *DLQForwarder) Run(ctx context.Context) error {
-	for {
-		select {
-		case msg, ok := <-f.msgCh:
-			if !ok {
-				return nil
-			}
-			f.handleAdvisory(msg)
-		case <-ctx.Done():
-			_ = f.sub.Unsubscribe()
-			return nil
-		}
-	}
-}
-
-// handleAdvisory fetches the original message by sequence and publishes it to the DLQ stream.
-// Failures are logged but non-fatal — the consumer has already moved on.
-//
-// Risk: if HA_EVENTS retention has evicted the message before the advisory fires, the
-// DLQ routing silently drops. Mitigated by EnsureHAEventsStream setting no MaxAge.
-func (f *DLQForwarder) handleAdvisory(msg *nats.Msg) {
-	var adv maxDeliverAdvisory
-	if err := json.Unmarshal(msg.Data, &adv); err != nil {
-		f.logger().Error("engine: dlq: unmarshal advisory", slog.String("error", err.Error()))
-		return
-	}
-
-	orig, err := f.js.GetMsg(f.stream, adv.StreamSeq)
-	if err != nil {
-		f.logger().Error("engine: dlq: get original message, dropped from DLQ",
-			slog.String("stream", f.stream),
-			slog.Uint64("seq", adv.StreamSeq),
-			slog.String("error", err.Error()),
-		)
-		return
-	}
-
-	// BuildDLQSubject requires lowercase tokens (ADR-0027).
-	dlqSubj, err := natsx.BuildDLQSubject(strings.ToLower(f.stream), f.consumer)
-	if err != nil {
-		f.logger().Error("engine: dlq: build subject",
-			slog.String("stream", f.stream),
-			slog.String("consumer", f.consumer),
-			slog.String("error", err.Error()),
-		)
-		return
-	}
-
-	if _, err := f.js.Publish(dlqSubj, orig.Data); err != nil {
-		f.logger().Error("engine: dlq: publish failed",
-			slog.Uint64("seq", adv.StreamSeq),
-			slog.String("subject", dlqSubj),
-			slog.String("error", err.Error()),
-		)
-		return
-	}
-	if f.forwarded != nil {
-		f.forwarded.Add(context.Background(), 1, metric.WithAttributes(attribute.String("stream", f.stream)))
-	}
-	f.logger().Info("engine: dlq: routed",
-		slog.String("stream", f.stream),
-		slog.Uint64("seq", adv.StreamSeq),
-		slog.String("subject", dlqSubj),
-		slog.Int("deliveries", adv.Deliveries),
-	)
-}
@@ -4,12 +4,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"testing"
-	"time"
 
 	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
 // ---------------------------------------------------------------------------
@@ -17,26 +19,38 @@ import (
 // ---------------------------------------------------------------------------
 
 type mockStore struct {
-	seen    map[string]bool
-	seeErr  error
-	markErr error
-	marked  []string
+	seen        map[string]bool // completed ids
+	inFlight    map[string]bool // ids claimed by another worker
+	claimErr    error
+	completeErr error
+	completed   []string
+	released    []string
 }
 
 func newMockStore() *mockStore {
-	return &mockStore{seen: make(map[string]bool)}
+	return &mockStore{seen: make(map[string]bool), inFlight: make(map[string]bool)}
 }
 
-func (m *mockStore) Seen(id string) (bool, error) {
-	if m.seeErr != nil {
-		return false, m.seeErr
+func (m *mockStore) Claim(id string) (idempotency.ClaimResult, error) {
+	switch {
+	case m.claimErr != nil:
+		return 0, m.claimErr
+	case m.seen[id]:
+		return idempotency.Done, nil
+	case m.inFlight[id]:
+		return idempotency.InFlight, nil
 	}
-	return m.seen[id], nil
+	return idempotency.Claimed, nil
+}
+
+func (m *mockStore) Complete(id string) error {
+	m.completed = append(m.completed, id)
+	return m.completeErr
 }
 
-func (m *mockStore) Mark(id string) error {
-	m.marked = append(m.marked, id)
-	return m.markErr
+func (m *mockStore) Release(id string) error {
+	m.released = append(m.released, id)
+	return nil
 }
 
 func (m *mockStore) Close() error { return nil }
@@ -51,7 +65,7 @@ func TestDecide_Success(t *testing.T) {
 		process: func(_ context.Context, _ string, _ []byte) error { return nil },
 	}
 
-	result, err := c.decide(context.Background(), "ha.events.person.wife", "evt-001", []byte("data"))
+	result, _, err := c.decide(context.Background(), "ha.events.person.wife", "evt-001", []byte("data"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -61,18 +75,25 @@ func TestDecide_Success(t *testing.T) {
 }
 
 func TestDecide_ProcessFailure(t *testing.T) {
+	store := newMockStore()
 	c := &Consumer{
-		idStore: newMockStore(),
+		idStore: store,
 		process: func(_ context.Context, _ string, _ []byte) error { return errors.New("transient error") },
 	}
 
-	result, err := c.decide(context.Background(), "ha.events.person.wife", "evt-002", []byte("data"))
+	result, cause, err := c.decide(context.Background(), "ha.events.person.wife", "evt-002", []byte("data"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if result != resultNak {
 		t.Errorf("result = %d, want resultNak (%d)", result, resultNak)
 	}
+	if cause == nil || cause.Error() != "transient error" {
+		t.Errorf("cause = %v, want the process error", cause)
+	}
+	if len(store.released) != 1 || store.released[0] != "evt-002" {
+		t.Errorf("released = %v, want the claim on evt-002 released for the retry", store.released)
+	}
 }
 
 func TestDecide_Duplicate(t *testing.T) {
@@ -88,7 +109,7 @@ func TestDecide_Duplicate(t *testing.T) {
 		},
 	}
 
-	result, err := c.decide(context.Background(), "ha.events.person.wife", "evt-003", []byte("data"))
+	result, _, err := c.decide(context.Background(), "ha.events.person.wife", "evt-003", []byte("data"))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -100,15 +121,46 @@ func TestDecide_Duplicate(t *testing.T) {
 	}
 }
 
+func TestDecide_ClaimedElsewhere(t *testing.T) {
+	store := newMockStore()
+	store.inFlight["evt-005"] = true
+
+	processed := false
+	c := &Consumer{
+		idStore: store,
+		process: func(_ context.Context, _ string, _ []byte) error {
+			processed = true
+			return nil
+		},
+	}
+
+	result, cause, err := c.decide(context.Background(), "ha.events.person.wife", "evt-005", []byte("data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != resultBusy {
+		t.Errorf("result = %d, want resultBusy (%d)", result, resultBusy)
+	}
+	if cause == nil {
+		t.Error("cause = nil, want the held claim recorded for the DLQ")
+	}
+	if processed {
+		t.Error("process() was called while another worker holds the claim, want it deferred")
+	}
+	if len(store.released) != 0 {
+		t.Errorf("released = %v, want another worker's claim left alone", store.released)
+	}
+}
+
 func TestDecide_IdempotencyCheckError(t *testing.T) {
 	store := newMockStore()
-	store.seeErr = errors.New("kv unreachable")
+	store.claimErr = errors.New("kv unreachable")
 	c := &Consumer{
 		idStore: store,
 		process: func(_ context.Context, _ string, _ []byte) error { return nil },
 	}
 
-	result, err := c.decide(context.Background(), "ha.events.person.wife", "evt-004", []byte("data"))
+	result, _, err := c.decide(context.Background(), "ha.events.person.wife", "evt-004", []byte("data"))
 	if err == nil {
 		t.Fatal("expected error from idempotency failure, got nil")
 	}
@@ -117,10 +169,135 @@ func TestDecide_IdempotencyCheckError(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Consumer.reject tests
+// ---------------------------------------------------------------------------
+
+type fakeRejects struct {
+	msgs []*nats.Msg
+	err  error
+}
+
+func (f *fakeRejects) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.msgs = append(f.msgs, m)
+	return &nats.PubAck{}, f.err
+}
+
+func TestReject_RoutesAndAcks(t *testing.T) {
+	rejects := &fakeRejects{}
+	rec := &stubRecorder{}
+	c := &Consumer{rejects: rejects, audit: rec, consumerName: "engine_ada"}
+	msg := &nats.Msg{Subject: "ha.events.ada.diaper_deleted", Data: []byte(`{"id":"e1","type":"ha.events.ada.diaper_deleted","data":{}}`)}
+
+	_, verr := schemas.ValidateJSON(msg.Data)
+	if verr == nil {
+		t.Fatal("delete without an id validated")
+	}
+	res := c.reject(msg, "e1", verr)
+	if res.Decision != natsx.DecisionAck || res.Outcome != natsx.OutcomeRejected {
+		t.Errorf("result = %+v, want an ack labelled rejected", res)
+	}
+	if len(rejects.msgs) != 1 || rejects.msgs[0].Subject != "rejected.ha.events.ada.diaper_deleted" {
+		t.Fatalf("published %v, want one message on rejected.ha.events.ada.diaper_deleted", rejects.msgs)
+	}
+	if by := rejects.msgs[0].Header.Get(natsx.HeaderRejectedBy); by != "engine_ada" {
+		t.Errorf("%s = %q, want the consumer", natsx.HeaderRejectedBy, by)
+	}
+	if len(rec.records) != 1 || rec.records[0].action != "event.rejected" {
+		t.Errorf("audit = %+v, want one event.rejected record", rec.records)
+	}
+}
+
+// Every processor consumer rejects a malformed event; the shared Nats-Msg-Id lets
+// the REJECTED stream keep one of them.
+func TestReject_OneMsgIDAcrossConsumers(t *testing.T) {
+	rejects := &fakeRejects{}
+	data := []byte(`{"id":"e1","type":"ha.events.ada.diaper_deleted","data":{}}`)
+	meta := &nats.MsgMetadata{Stream: "HA_EVENTS", Sequence: nats.SequencePair{Stream: 7}}
+	for _, name := range []string{"engine_ada", "engine_rules"} {
+		c := &Consumer{rejects: rejects, audit: NoopRecorder{}, consumerName: name, idScope: name}
+		msg := &nats.Msg{Subject: "ha.events.ada.diaper_deleted", Data: data}
+		c.reject(msg, extractEventID(msg.Header, msg.Data, meta), schemas.ErrInvalidEvent)
+	}
+	if len(rejects.msgs) != 2 {
+		t.Fatalf("published %d rejections, want 2", len(rejects.msgs))
+	}
+	for _, m := range rejects.msgs {
+		if id := m.Header.Get(nats.MsgIdHdr); id != "e1" {
+			t.Errorf("%s = %q, want the event id e1 from every consumer", nats.MsgIdHdr, id)
+		}
+	}
+}
+
+func TestReject_PublishFailureNaks(t *testing.T) {
+	c := &Consumer{rejects: &fakeRejects{err: errors.New("no responders")}, audit: NoopRecorder{}}
+	res := c.reject(&nats.Msg{Subject: "ha.events.ada.diaper_deleted"}, "e1", schemas.ErrInvalidEvent)
+	if res.Decision != natsx.DecisionNak {
+		t.Errorf("decision = %v, want a nak so the event is not lost", res.Decision)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // extractEventID tests
 // ---------------------------------------------------------------------------
 
+func TestIdempotencyKey_ScopedPerConsumer(t *testing.T) {
+	store := newMockStore()
+	store.seen["engine_ada.evt-001"] = true
+	calls := 0
+	process := func(_ context.Context, _ string, _ []byte) error { calls++; return nil }
+	ada := &Consumer{idStore: store, process: process, idScope: "engine_ada"}
+	rules := &Consumer{idStore: store, process: process, idScope: "engine_rules"}
+
+	if got := ada.idempotencyKey("evt-001"); got != "engine_ada.evt-001" {
+		t.Errorf("idempotencyKey = %q, want engine_ada.evt-001", got)
+	}
+	if got := (&Consumer{}).idempotencyKey("evt-001"); got != "evt-001" {
+		t.Errorf("unscoped idempotencyKey = %q, want evt-001", got)
+	}
+
+	// An event already handled by one processor is still new to another.
+	if r, _, _ := ada.decide(context.Background(), "s", ada.idempotencyKey("evt-001"), nil); r != resultSkip {
+		t.Errorf("ada result = %d, want resultSkip", r)
+	}
+	if r, _, _ := rules.decide(context.Background(), "s", rules.idempotencyKey("evt-001"), nil); r != resultAck || calls != 1 {
+		t.Errorf("rules result = %d (calls %d), want resultAck and one call", r, calls)
+	}
+}
+
+// An event in the legacy window is a duplicate if the legacy shared consumer
+// completed it under its bare ID, even though the scoped key is new.
+func TestLegacyClaim_Window(t *testing.T) {
+	store := newMockStore()
+	store.seen["evt-done"] = true
+	store.inFlight["evt-busy"] = true
+	c := &Consumer{idStore: store, idScope: "engine_rules", legacyThrough: 45}
+
+	cases := []struct {
+		seq  uint64
+		id   string
+		want idempotency.ClaimResult
+	}{
+		{44, "evt-done", idempotency.Done},
+		{45, "evt-busy", idempotency.InFlight},
+		{45, "evt-new", idempotency.Claimed},
+		{46, "evt-done", idempotency.Claimed}, // past the window: not checked
+	}
+	for _, tc := range cases {
+		if got, err := c.legacyClaim(tc.seq, tc.id); err != nil || got != tc.want {
+			t.Errorf("legacyClaim(%d, %s) = %v, %v; want %v, nil", tc.seq, tc.id, got, err, tc.want)
+		}
+	}
+	if len(store.released) != 1 || store.released[0] != "evt-new" {
+		t.Errorf("released = %v, want the claim on evt-new only", store.released)
+	}
+
+	store.claimErr = errors.New("kv down")
+	if _, err := c.legacyClaim(44, "evt-done"); err == nil {
+		t.Error("legacyClaim with a failing store: expected error, got nil")
+	}
+}
+
 func TestExtractEventID_NatsMsgIdHeader(t *testing.T) {
 	headers := nats.Header{}
 	headers.Set("Nats-Msg-Id", "header-id-123")
@@ -173,6 +350,49 @@ func TestExtractEventID_FallbackOnEmptyCloudEventID(t *testing.T) {
 	}
 }
 
+func TestExtractEventID_BinaryModeHeader(t *testing.T) {
+	headers := nats.Header{}
+	headers.Set(schemas.HeaderSpecVersion, schemas.CloudEventsSpecVersion)
+	headers.Set(schemas.HeaderID, "ce-bin-1")
+	meta := &nats.MsgMetadata{
+		Stream:   "HA_EVENTS",
+		Sequence: nats.SequencePair{Stream: 3},
+	}
+
+	// The body of a binary-mode event is the data alone and has no id.
+	id := extractEventID(headers, []byte(`{"id":"data-field"}`), meta)
+	if id != "ce-bin-1" {
+		t.Errorf("id = %q, want %q (ce-id header)", id, "ce-bin-1")
+	}
+}
+
+func TestExtractCorrelationFields(t *testing.T) {
+	binary := nats.Header{}
+	binary.Set(schemas.HeaderSpecVersion, schemas.CloudEventsSpecVersion)
+	binary.Set(schemas.HeaderCorrelationID, "corr-1")
+	binary.Set(schemas.HeaderCausationID, "cause-1")
+
+	cases := []struct {
+		name        string
+		headers     nats.Header
+		data        string
+		corr, cause string
+	}{
+		{"structured", nil, `{"id":"e1","correlationid":"corr-2","causationid":"cause-2"}`, "corr-2", "cause-2"},
+		// The body is not parsed: it need not even be JSON.
+		{"binary", binary, `not-json`, "corr-1", "cause-1"},
+		{"malformed", nil, `not-json`, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			corr, cause := extractCorrelationFields(tc.headers, []byte(tc.data))
+			if corr != tc.corr || cause != tc.cause {
+				t.Errorf("got (%q, %q), want (%q, %q)", corr, cause, tc.corr, tc.cause)
+			}
+		})
+	}
+}
+
 // ---------------------------------------------------------------------------
 // NewConsumer validation tests
 // ---------------------------------------------------------------------------
@@ -193,65 +413,3 @@ func TestNewConsumer_ValidConfig(t *testing.T) {
 		t.Fatal("expected non-nil Consumer")
 	}
 }
-
-// ---------------------------------------------------------------------------
-// nakDelay tests
-// ---------------------------------------------------------------------------
-
-func TestNakDelay(t *testing.T) {
-	backOff := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
-	cases := []struct {
-		numDelivered uint64
-		want         time.Duration
-	}{
-		{1, 1 * time.Second}, // first attempt → 1s delay before retry
-		{2, 2 * time.Second}, // second attempt → 2s
-		{3, 4 * time.Second}, // third → 4s
-		{4, 8 * time.Second}, // fourth → 8s
-		{5, 0},               // fifth (final, MaxDeliver=5) → no delay, advisory fires promptly
-		{6, 0},               // beyond schedule → no delay
-	}
-	for _, tc := range cases {
-		got := nakDelay(backOff, tc.numDelivered)
-		if got != tc.want {
-			t.Errorf("nakDelay(backOff, %d) = %v, want %v", tc.numDelivered, got, tc.want)
-		}
-	}
-}
-
-func TestNakDelay_EmptyBackOff(t *testing.T) {
-	if d := nakDelay(nil, 3); d != 0 {
-		t.Errorf("nakDelay(nil, 3) = %v, want 0", d)
-	}
-}
-
-// ---------------------------------------------------------------------------
-// maxDeliverAdvisory parsing test
-// ---------------------------------------------------------------------------
-
-func TestMaxDeliverAdvisory_Parse(t *testing.T) {
-	payload := `{
-		"type": "io.nats.jetstream.advisory.v1.max_deliver",
-		"id": "some-id",
-		"timestamp": "2026-02-23T10:00:00Z",
-		"stream": "HA_EVENTS",
-		"consumer": "engine_processor",
-		"stream_seq": 42,
-		"consumer_seq": 42,
-		"deliveries": 5
-	}`
-
-	var adv maxDeliverAdvisory
-	if err := json.Unmarshal([]byte(payload), &adv); err != nil {
-		t.Fatalf("unmarshal advisory: %v", err)
-	}
-	if adv.Stream != "HA_EVENTS" {
-		t.Errorf("Stream = %q, want %q", adv.Stream, "HA_EVENTS")
-	}
-	if adv.StreamSeq != 42 {
-		t.Errorf("StreamSeq = %d, want 42", adv.StreamSeq)
-	}
-	if adv.Deliveries != 5 {
-		t.Errorf("Deliveries = %d, want 5", adv.Deliveries)
-	}
-}
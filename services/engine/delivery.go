@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// engineStream is a JetStream stream the engine consumes. Every registered
+// processor gets its own durable pull consumer on it, configured by the engine_*
+// entry for the stream in natsx.Manifest and filtered to the processor's
+// subscriptions (see processorFilters), so a processor that fails an event is
+// retried and, once MaxDeliver is exhausted, dead-lettered by its own
+// natsx.DLQForwarder (ADR-0022) without re-running the side effects of the others.
+type engineStream struct {
+	name string
+	// legacy is the durable that fanned the stream out to every processor before
+	// per-processor consumers, if any; see legacyStartSeq.
+	legacy string
+}
+
+var engineStreams = []engineStream{
+	{name: "HA_EVENTS", legacy: "engine_processor"},
+	{name: "PRESENCE", legacy: "engine_presence_processor"},
+	{name: "SCHEDULES"},
+}
+
+// processorConsumerName is the durable name of processor's consumer. It is the
+// same on every stream and also scopes the consumer's idempotency keys.
+func processorConsumerName(processor string) string {
+	return "engine_" + processor
+}
+
+// idleFilterToken replaces the last token of a stream's subject to filter a
+// processor that subscribes to nothing on the stream: an empty FilterSubjects
+// would deliver it everything.
+const idleFilterToken = "_none"
+
+// processorFilters returns the FilterSubjects of a processor's consumer on a
+// stream whose engine consumers take bound (the manifest's FilterSubject), so
+// JetStream delivers the processor only the events it subscribes to. A
+// subscription within bound is kept, one reaching past it is cut to bound, and
+// one covered by another is dropped; if two still overlap, which JetStream
+// rejects, the consumer takes all of bound and Consumer.accepts filters instead.
+func processorFilters(bound string, subs []string) []string {
+	var filters []string
+	for _, sub := range subs {
+		switch {
+		case natsx.SubjectSubsetOf(sub, bound):
+			filters = append(filters, sub)
+		case natsx.SubjectsOverlap(sub, bound):
+			filters = append(filters, bound)
+		}
+	}
+	slices.Sort(filters)
+	filters = slices.Compact(filters)
+	filters = slices.DeleteFunc(slices.Clone(filters), func(f string) bool {
+		return slices.ContainsFunc(filters, func(g string) bool {
+			return g != f && natsx.SubjectSubsetOf(f, g)
+		})
+	})
+	for i, f := range filters {
+		for _, g := range filters[i+1:] {
+			if natsx.SubjectsOverlap(f, g) {
+				return []string{bound}
+			}
+		}
+	}
+	if len(filters) == 0 {
+		return []string{bound[:strings.LastIndex(bound, ".")+1] + idleFilterToken}
+	}
+	return filters
+}
+
+// consumerAdmin is the subset of nats.JetStreamContext used to migrate off the
+// legacy shared consumers.
+type consumerAdmin interface {
+	ConsumerInfo(stream, name string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error)
+	DeleteConsumer(stream, consumer string, opts ...nats.JSOpt) error
+}
+
+// legacyStartSeq returns the stream sequence at which newly created processor
+// consumers should start so that switching from the shared legacy consumer
+// neither replays the stream's history nor skips unacked events: one past the
+// legacy consumer's ack floor. Events from start through the last one the legacy
+// consumer delivered may have been acked out of order above the floor, under
+// their bare event IDs; the processor consumers check those keys for events up
+// to through (see Consumer.legacyThrough). Both are 0 when there is no legacy
+// consumer, i.e. on a fresh install or once the migration has completed; new
+// consumers then start with the next published event (DeliverNew), so a
+// processor added or renamed later does not replay the stream's history.
+func legacyStartSeq(js consumerAdmin, s engineStream) (start, through uint64, err error) {
+	if s.legacy == "" {
+		return 0, 0, nil
+	}
+	info, err := js.ConsumerInfo(s.name, s.legacy)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("engine: legacy consumer info %q: %w", s.legacy, err)
+	}
+	return info.AckFloor.Stream + 1, info.Delivered.Stream, nil
+}
+
+// retireLegacyConsumer deletes the stream's legacy consumer once every consumer
+// in names has acked through, the last event the legacy consumer delivered.
+// Until then the legacy consumer is kept, so that a restart still knows which
+// events to check under their legacy keys. It reports whether the legacy
+// consumer is gone; missing is not an error.
+func retireLegacyConsumer(js consumerAdmin, s engineStream, through uint64, names []string) (bool, error) {
+	if s.legacy == "" {
+		return true, nil
+	}
+	for _, name := range names {
+		info, err := js.ConsumerInfo(s.name, name)
+		if err != nil {
+			return false, fmt.Errorf("engine: consumer info %q: %w", name, err)
+		}
+		if info.AckFloor.Stream < through {
+			return false, nil
+		}
+	}
+	err := js.DeleteConsumer(s.name, s.legacy)
+	if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		return false, fmt.Errorf("engine: delete legacy consumer %q: %w", s.legacy, err)
+	}
+	return true, nil
+}
+
+// filteredConsumer is a processor consumer whose FilterSubjects follow the
+// subscriptions of its route; see processorFilters.
+type filteredConsumer struct {
+	cfg   natsx.PullConsumerConfig
+	bound string
+	route ProcessorRoute
+}
+
+// syncConsumerFilters updates the FilterSubjects of consumers to their
+// processors' current subscriptions, after a rule reload changed them. A
+// consumer whose filters are unchanged is left alone. Until a consumer is
+// updated its Consumer.accepts drops the events its processor no longer takes;
+// events published before the update on a subject it newly takes are not
+// delivered to it.
+func syncConsumerFilters(js nats.JetStreamContext, consumers []filteredConsumer, log *slog.Logger) {
+	for _, c := range consumers {
+		c.cfg.FilterSubjects = processorFilters(c.bound, c.route.Subscriptions())
+		if err := natsx.UpdatePullConsumer(js, c.cfg); err != nil {
+			log.Error("nats: update consumer filter failed",
+				slog.String("consumer", c.cfg.Durable),
+				slog.String("stream", c.cfg.Stream),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build fast
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// fakeConsumerAdmin is a consumerAdmin backed by a map of consumer name → info.
+type fakeConsumerAdmin struct {
+	consumers map[string]*nats.ConsumerInfo
+	infoErr   error
+	deleted   []string
+}
+
+func (f *fakeConsumerAdmin) ConsumerInfo(_, name string, _ ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	if f.infoErr != nil {
+		return nil, f.infoErr
+	}
+	info, ok := f.consumers[name]
+	if !ok {
+		return nil, nats.ErrConsumerNotFound
+	}
+	return info, nil
+}
+
+func (f *fakeConsumerAdmin) DeleteConsumer(_, name string, _ ...nats.JSOpt) error {
+	if _, ok := f.consumers[name]; !ok {
+		return nats.ErrConsumerNotFound
+	}
+	delete(f.consumers, name)
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+func TestLegacyStartSeq_ResumesAfterAckFloor(t *testing.T) {
+	stream := engineStreams[0]
+	js := &fakeConsumerAdmin{consumers: map[string]*nats.ConsumerInfo{
+		stream.legacy:  {AckFloor: nats.SequenceInfo{Stream: 41}, Delivered: nats.SequenceInfo{Stream: 41}},
+		"engine_rules": {AckFloor: nats.SequenceInfo{Stream: 41}},
+	}}
+
+	start, through, err := legacyStartSeq(js, stream)
+	if err != nil || start != 42 || through != 41 {
+		t.Fatalf("legacyStartSeq = %d, %d, %v; want 42, 41, nil", start, through, err)
+	}
+	if retired, err := retireLegacyConsumer(js, stream, through, []string{"engine_rules"}); err != nil || !retired {
+		t.Fatalf("retireLegacyConsumer = %v, %v; want true, nil", retired, err)
+	}
+	if len(js.deleted) != 1 || js.deleted[0] != stream.legacy {
+		t.Errorf("deleted = %v, want [%s]", js.deleted, stream.legacy)
+	}
+
+	// Once retired there is no ack floor to resume from (new consumers deliver
+	// only new events) and retiring is a no-op.
+	if start, through, err := legacyStartSeq(js, stream); err != nil || start != 0 || through != 0 {
+		t.Errorf("after retire: legacyStartSeq = %d, %d, %v; want 0, 0, nil", start, through, err)
+	}
+	if retired, err := retireLegacyConsumer(js, stream, 0, []string{"engine_rules"}); err != nil || !retired {
+		t.Errorf("retire twice = %v, %v; want true, nil", retired, err)
+	}
+}
+
+// Events the legacy consumer acked out of order above its floor keep it until
+// every processor consumer has acked past them, so a restart still checks their
+// legacy keys.
+func TestRetireLegacyConsumer_KeptUntilWindowAcked(t *testing.T) {
+	stream := engineStreams[0]
+	js := &fakeConsumerAdmin{consumers: map[string]*nats.ConsumerInfo{
+		stream.legacy:     {AckFloor: nats.SequenceInfo{Stream: 41}, Delivered: nats.SequenceInfo{Stream: 45}},
+		"engine_rules":    {AckFloor: nats.SequenceInfo{Stream: 45}},
+		"engine_calendar": {AckFloor: nats.SequenceInfo{Stream: 43}},
+	}}
+
+	start, through, err := legacyStartSeq(js, stream)
+	if err != nil || start != 42 || through != 45 {
+		t.Fatalf("legacyStartSeq = %d, %d, %v; want 42, 45, nil", start, through, err)
+	}
+	names := []string{"engine_rules", "engine_calendar"}
+	if retired, err := retireLegacyConsumer(js, stream, through, names); err != nil || retired || len(js.deleted) != 0 {
+		t.Fatalf("retireLegacyConsumer = %v, %v, deleted %v; want false, nil, none", retired, err, js.deleted)
+	}
+
+	js.consumers["engine_calendar"].AckFloor.Stream = 46
+	if retired, err := retireLegacyConsumer(js, stream, through, names); err != nil || !retired {
+		t.Errorf("after ack: retireLegacyConsumer = %v, %v; want true, nil", retired, err)
+	}
+}
+
+func TestLegacyStartSeq_InfoError(t *testing.T) {
+	js := &fakeConsumerAdmin{infoErr: errors.New("timeout")}
+	if _, _, err := legacyStartSeq(js, engineStreams[0]); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestLegacyStartSeq_StreamWithoutLegacyConsumer(t *testing.T) {
+	js := &fakeConsumerAdmin{infoErr: errors.New("must not be called")}
+	s := engineStream{name: "SCHEDULES"}
+	if start, through, err := legacyStartSeq(js, s); err != nil || start != 0 || through != 0 {
+		t.Errorf("legacyStartSeq = %d, %d, %v; want 0, 0, nil", start, through, err)
+	}
+	if retired, err := retireLegacyConsumer(js, s, 0, []string{"engine_rules"}); err != nil || !retired || len(js.deleted) != 0 {
+		t.Errorf("retireLegacyConsumer = %v, %v, deleted %v; want true, nil, none", retired, err, js.deleted)
+	}
+}
+
+func TestEngineStreams_InManifest(t *testing.T) {
+	m := natsx.Manifest()
+	for _, s := range engineStreams {
+		cfg, err := m.Consumer(s.name, processorConsumerName("rules"))
+		if err != nil {
+			t.Errorf("%s: %v", s.name, err)
+			continue
+		}
+		if cfg.FilterSubject == "" {
+			t.Errorf("%s: engine consumers need a filter subject", s.name)
+		}
+	}
+}
+
+func TestProcessorFilters(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bound string
+		subs  []string
+		want  []string
+	}{
+		{"within the stream", "ha.events.>",
+			[]string{"ha.events.person.>", "ha.events.device_tracker.>", "ruby_presence.events.state.>"},
+			[]string{"ha.events.device_tracker.>", "ha.events.person.>"}},
+		{"covered subscription dropped", "ha.events.>",
+			[]string{"ha.events.ada.>", "ha.events.ada.feeding", "ha.events.input_number.x"},
+			[]string{"ha.events.ada.>", "ha.events.input_number.x"}},
+		{"broader subscription cut to the stream", "ha.events.>",
+			[]string{"*.events.>"},
+			[]string{"ha.events.>"}},
+		{"overlapping subscriptions take the stream", "ha.events.>",
+			[]string{"ha.events.*.alice", "ha.events.person.*"},
+			[]string{"ha.events.>"}},
+		{"nothing on the stream", "ruby_engine.events.schedule.>",
+			[]string{"ha.events.person.>"},
+			[]string{"ruby_engine.events.schedule._none"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := processorFilters(tc.bound, tc.subs); !slices.Equal(got, tc.want) {
+				t.Errorf("processorFilters = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
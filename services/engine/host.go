@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
+	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nats-io/nats.go"
@@ -14,8 +17,11 @@ import (
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
 )
 
 // tracer opens engine spans; delegates to the global provider installed by otel.Init.
@@ -26,6 +32,9 @@ var tracer = otel.Tracer("github.com/primaryrutabaga/ruby-core/services/engine")
 // to the matching NATS subject.
 type ProcessorHost struct {
 	processors []processor.Processor
+	scheduler  *scheduler.Scheduler
+	router     atomic.Pointer[subjectRouter] // built by Initialize, rebuilt by Reload
+	ruleCfg    *config.CompiledConfig        // the config the processors run; Reload rolls back to it
 	log        *slog.Logger
 }
 
@@ -40,6 +49,12 @@ func (h *ProcessorHost) Register(p processor.Processor) {
 	h.processors = append(h.processors, p)
 }
 
+// SetScheduler makes s the source of every processor's Config.Schedules. Must
+// be called before Initialize; without it Config.Schedules is nil.
+func (h *ProcessorHost) SetScheduler(s *scheduler.Scheduler) {
+	h.scheduler = s
+}
+
 // RequiresStorage reports whether any registered processor implements
 // StatefulProcessor and returns true from RequiresStorage. Used by main.go
 // to determine whether to boot the Postgres connection pool before Initialize.
@@ -55,6 +70,9 @@ func (h *ProcessorHost) RequiresStorage() bool {
 // Initialize calls Initialize on every registered processor with the provided
 // config and resources. pool and ha are passed through to Config and are non-nil
 // only when at least one StatefulProcessor is registered (see RequiresStorage).
+// Each StateOwner additionally receives its own state bucket as Config.State,
+// and with a scheduler set (SetScheduler) every processor receives its own
+// Config.Schedules.
 //
 // Coupling note: HA config (ha) is currently fetched unconditionally whenever
 // any stateful processor is registered, even if a given processor only needs
@@ -65,52 +83,210 @@ func (h *ProcessorHost) RequiresStorage() bool {
 // there is a second stateful processor to drive the design.
 func (h *ProcessorHost) Initialize(ruleCfg *config.CompiledConfig, nc *nats.Conn, js nats.JetStreamContext, pool *pgxpool.Pool, ha *boot.HAConfig) error {
 	cfg := processor.Config{RuleCfg: ruleCfg, NC: nc, JS: js, Pool: pool, HA: ha}
+	if js != nil {
+		cfg.Commands = natsx.NewAckedPublisher(js)
+	}
+	openState := func(p processor.Processor, ttl time.Duration) (state.Store, error) {
+		if js == nil {
+			return nil, errors.New("owns state but JetStream is nil")
+		}
+		return state.Open(js, p.Name(), stateOwner(p), ttl)
+	}
+	return h.initialize(cfg, openState, processor.Processor.Initialize)
+}
+
+// InitializeOffline initializes every registered processor without NATS, for
+// the replay command: each must implement processor.OfflineProcessor and
+// publishes to pub, and each StateOwner receives the store returned by
+// openState in place of its KV bucket. pool and ha are passed through as in
+// Initialize.
+func (h *ProcessorHost) InitializeOffline(ruleCfg *config.CompiledConfig, pub natsx.MsgPublisher, pool *pgxpool.Pool, ha *boot.HAConfig, openState func(p processor.Processor, ttl time.Duration) (state.Store, error)) error {
+	cfg := processor.Config{RuleCfg: ruleCfg, Pool: pool, HA: ha}
+	return h.initialize(cfg, openState, func(p processor.Processor, pcfg processor.Config) error {
+		op, ok := p.(processor.OfflineProcessor)
+		if !ok {
+			return fmt.Errorf("processor %s cannot run offline", p.Name())
+		}
+		return op.InitializeOffline(pcfg, pub)
+	})
+}
+
+// initialize validates the registered processors, calls init on each with its
+// Config and builds the subject router.
+func (h *ProcessorHost) initialize(cfg processor.Config, openState func(processor.Processor, time.Duration) (state.Store, error), init func(processor.Processor, processor.Config) error) error {
+	names := make(map[string]bool, len(h.processors))
+	for _, p := range h.processors {
+		if !natsx.IsValidToken(p.Name()) {
+			return fmt.Errorf("host: processor %T name %q: %w", p, p.Name(), natsx.ErrInvalidToken)
+		}
+		if names[p.Name()] {
+			return fmt.Errorf("host: processor name %q registered twice", p.Name())
+		}
+		names[p.Name()] = true
+	}
 	for _, p := range h.processors {
 		if sp, ok := p.(processor.StatefulProcessor); ok && sp.RequiresStorage() {
 			if cfg.Pool == nil {
 				return fmt.Errorf("host: processor %T requires storage but Config.Pool is nil", p)
 			}
 		}
-		if err := p.Initialize(cfg); err != nil {
+		pcfg := cfg
+		if so, ok := p.(processor.StateOwner); ok {
+			st, err := openState(p, so.StateTTL())
+			if err != nil {
+				return fmt.Errorf("host: processor %s state: %w", p.Name(), err)
+			}
+			pcfg.State = st
+		}
+		if h.scheduler != nil {
+			pcfg.Schedules = h.scheduler.For(p.Name())
+		}
+		if err := init(p, pcfg); err != nil {
 			return fmt.Errorf("host: processor init: %w", err)
 		}
 	}
+	// Subscriptions are read after Initialize: the rules processor derives its
+	// patterns from the compiled config it receives there.
+	router, err := newSubjectRouter(h.processors)
+	if err != nil {
+		return fmt.Errorf("host: subscriptions: %w", err)
+	}
+	h.router.Store(router)
+	h.ruleCfg = cfg.RuleCfg
+	return nil
+}
+
+// stateOwner is the owner stamped on p's state bucket.
+func stateOwner(p processor.Processor) string {
+	return "ruby_engine/" + p.Name()
+}
+
+// Reload hands a recompiled rule config to every registered processor that
+// implements processor.Reloader, then rebuilds the subject router from the
+// resulting subscriptions. It stops at the first processor that fails, and
+// when one fails or the new subscriptions are malformed, the processors
+// already reloaded are rolled back to the previous config and the previous
+// router is kept: the processors never run different configs.
+func (h *ProcessorHost) Reload(ruleCfg *config.CompiledConfig) error {
+	var reloaded []processor.Processor
+	var err error
+	for _, p := range h.processors {
+		r, ok := p.(processor.Reloader)
+		if !ok {
+			continue
+		}
+		if rerr := r.Reload(ruleCfg); rerr != nil {
+			err = fmt.Errorf("host: processor %s reload: %w", p.Name(), rerr)
+			break
+		}
+		reloaded = append(reloaded, p)
+	}
+	var router *subjectRouter
+	if err == nil {
+		if router, err = newSubjectRouter(h.processors); err != nil {
+			err = fmt.Errorf("host: subscriptions: %w", err)
+		}
+	}
+	if err != nil {
+		h.rollback(reloaded)
+		return err
+	}
+	h.router.Store(router)
+	h.ruleCfg = ruleCfg
 	return nil
 }
 
+// rollback reloads procs, in reverse order, with the config they ran before a
+// failed Reload. A processor that cannot go back is logged and left as it is.
+func (h *ProcessorHost) rollback(procs []processor.Processor) {
+	for _, p := range slices.Backward(procs) {
+		if err := p.(processor.Reloader).Reload(h.ruleCfg); err != nil {
+			h.log.Error("host: processor rollback failed",
+				slog.String("processor", p.Name()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
 // Process routes an event to every processor whose Subscriptions list matches
-// the given subject. A processor subscription matches if the subject equals the
-// pattern exactly, or if the pattern ends with ".>" and the subject has that prefix.
+// the given subject under NATS wildcard rules ("*" for one token in any
+// position, a trailing ">" for the rest), calling each processor at most once.
 // Errors from individual processors are logged but do not prevent other processors
 // from running; the error from the first failing processor is returned to the caller
 // so the consumer can NAK the message.
+//
+// The engine's consumers deliver through Routes instead, so that a failure is
+// retried for the failing processor only; Process serves callers that want the
+// whole fan-out in one call.
 func (h *ProcessorHost) Process(ctx context.Context, subject string, data []byte) error {
+	return h.dispatch(ctx, subject, data, h.router.Load().match(subject))
+}
+
+// ProcessorRoute delivers events to a single registered processor. Each route
+// backs its own durable consumer (see main.go), so retries, backoff, DLQ routing
+// and metrics are scoped to the processor that failed.
+type ProcessorRoute struct {
+	host *ProcessorHost
+	idx  int
+}
+
+// Routes returns one route per registered processor, in registration order.
+func (h *ProcessorHost) Routes() []ProcessorRoute {
+	routes := make([]ProcessorRoute, len(h.processors))
+	for i := range h.processors {
+		routes[i] = ProcessorRoute{host: h, idx: i}
+	}
+	return routes
+}
+
+// Name is the name of the route's processor.
+func (r ProcessorRoute) Name() string {
+	return r.host.processors[r.idx].Name()
+}
+
+// Accepts reports whether the route's processor currently subscribes to subject.
+func (r ProcessorRoute) Accepts(subject string) bool {
+	return slices.Contains(r.host.router.Load().match(subject), r.idx)
+}
+
+// Subscriptions returns the subject patterns the route's processor currently
+// subscribes to.
+func (r ProcessorRoute) Subscriptions() []string {
+	return r.host.processors[r.idx].Subscriptions()
+}
+
+// Process delivers an event to the route's processor if it subscribes to
+// subject, returning the processor's error.
+func (r ProcessorRoute) Process(ctx context.Context, subject string, data []byte) error {
+	if !r.Accepts(subject) {
+		return nil
+	}
+	return r.host.dispatch(ctx, subject, data, []int{r.idx})
+}
+
+// dispatch calls ProcessEvent on each processor in matched, in order.
+func (h *ProcessorHost) dispatch(ctx context.Context, subject string, data []byte, matched []int) error {
 	ctx, span := tracer.Start(ctx, "engine.process",
 		trace.WithAttributes(attribute.String("subject", subject)))
 	defer span.End()
 
 	var firstErr error
-	matched := false
-
-	for _, p := range h.processors {
-		for _, pattern := range p.Subscriptions() {
-			if matchesSubject(pattern, subject) {
-				matched = true
-				if err := p.ProcessEvent(ctx, subject, data); err != nil {
-					h.log.Warn("host: processor error",
-						slog.String("subject", subject),
-						slog.String("error", err.Error()),
-					)
-					if firstErr == nil {
-						firstErr = err
-					}
-				}
-				break // a processor is called at most once per event
+	for _, i := range matched {
+		p := h.processors[i]
+		if err := p.ProcessEvent(ctx, subject, data); err != nil {
+			h.log.Warn("host: processor error",
+				slog.String("processor", p.Name()),
+				slog.String("subject", subject),
+				slog.String("error", err.Error()),
+			)
+			if firstErr == nil {
+				firstErr = err
 			}
 		}
 	}
 
-	if !matched {
+	if len(matched) == 0 {
 		h.log.Debug("host: no processor matched subject", slog.String("subject", subject))
 	}
 	if firstErr != nil {
@@ -125,17 +301,3 @@ func (h *ProcessorHost) Shutdown() {
 		h.processors[i].Shutdown()
 	}
 }
-
-// matchesSubject reports whether pattern matches subject following NATS wildcard rules.
-// Only the trailing ".>" wildcard is supported here; ".*" single-token wildcards are not
-// needed by any current processor and are left for a future ADR if required.
-func matchesSubject(pattern, subject string) bool {
-	if pattern == subject {
-		return true
-	}
-	if strings.HasSuffix(pattern, ".>") {
-		prefix := strings.TrimSuffix(pattern, ">")
-		return strings.HasPrefix(subject, prefix)
-	}
-	return false
-}
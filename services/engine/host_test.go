@@ -5,21 +5,31 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
+	"time"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
 )
 
 // mockProcessor is a test double for processor.Processor.
 type mockProcessor struct {
+	name       string // defaults to "mock"
 	subs       []string
 	events     []string // subjects received by ProcessEvent
 	initErr    error
 	processErr error
 }
 
+func (m *mockProcessor) Name() string {
+	if m.name == "" {
+		return "mock"
+	}
+	return m.name
+}
 func (m *mockProcessor) Initialize(_ processor.Config) error { return m.initErr }
 func (m *mockProcessor) Subscriptions() []string             { return m.subs }
 func (m *mockProcessor) ProcessEvent(_ context.Context, subject string, _ []byte) error {
@@ -28,13 +38,28 @@ func (m *mockProcessor) ProcessEvent(_ context.Context, subject string, _ []byte
 }
 func (m *mockProcessor) Shutdown() {}
 
+// mockReloader is a mockProcessor that also implements processor.Reloader.
+type mockReloader struct {
+	mockProcessor
+	reloaded  *config.CompiledConfig
+	reloadErr error
+}
+
+func (m *mockReloader) Reload(cfg *config.CompiledConfig) error {
+	m.reloaded = cfg
+	return m.reloadErr
+}
+
 // Compile-time check: mockProcessor satisfies processor.Processor.
 var _ processor.Processor = (*mockProcessor)(nil)
 
 func newHost(t *testing.T, procs ...*mockProcessor) *ProcessorHost {
 	t.Helper()
 	h := NewProcessorHost(slog.Default())
-	for _, p := range procs {
+	for i, p := range procs {
+		if p.name == "" {
+			p.name = fmt.Sprintf("mock%d", i)
+		}
 		h.Register(p)
 	}
 	// nil NC/JS is fine for tests: mock processors don't use them.
@@ -134,3 +159,162 @@ func TestHost_InitializeError(t *testing.T) {
 		t.Fatal("expected error from processor init, got nil")
 	}
 }
+
+func TestHost_ReloadReachesOnlyReloaders(t *testing.T) {
+	plain := &mockProcessor{name: "plain"}
+	a := &mockReloader{mockProcessor: mockProcessor{name: "a"}}
+	b := &mockReloader{mockProcessor: mockProcessor{name: "b"}}
+	h := NewProcessorHost(slog.Default())
+	h.Register(plain)
+	h.Register(a)
+	h.Register(b)
+
+	cfg := &config.CompiledConfig{}
+	if err := h.Reload(cfg); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if a.reloaded != cfg || b.reloaded != cfg {
+		t.Error("every reloader must receive the new config")
+	}
+}
+
+// TestHost_ReloadRollsBackOnFailure verifies a failing processor stops the reload
+// and the processors reloaded before it return to the config they ran.
+func TestHost_ReloadRollsBackOnFailure(t *testing.T) {
+	before := &mockReloader{mockProcessor: mockProcessor{name: "before"}}
+	failing := &mockReloader{mockProcessor: mockProcessor{name: "failing"}, reloadErr: errors.New("bad rules")}
+	after := &mockReloader{mockProcessor: mockProcessor{name: "after"}}
+	h := NewProcessorHost(slog.Default())
+	h.Register(before)
+	h.Register(failing)
+	h.Register(after)
+	initial := &config.CompiledConfig{}
+	if err := h.Initialize(initial, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	if err := h.Reload(&config.CompiledConfig{CriticalEntities: []string{"person.sam"}}); !errors.Is(err, failing.reloadErr) {
+		t.Errorf("error = %v, want %v", err, failing.reloadErr)
+	}
+	if before.reloaded != initial {
+		t.Error("a processor reloaded before the failure was not rolled back")
+	}
+	if after.reloaded != nil {
+		t.Error("a processor after the failure was reloaded")
+	}
+}
+
+func TestHost_RoutesSingleTokenWildcard(t *testing.T) {
+	p := &mockProcessor{subs: []string{"ha.events.*.wife", "*.events.state.>"}}
+	h := newHost(t, p)
+
+	for _, subject := range []string{
+		"ha.events.person.wife",
+		"ruby_presence.events.state.wife",
+		"ha.events.person.husband",
+		"ha.events.person.wife.extra",
+	} {
+		if err := h.Process(context.Background(), subject, []byte("{}")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := []string{"ha.events.person.wife", "ruby_presence.events.state.wife"}
+	if len(p.events) != len(want) || p.events[0] != want[0] || p.events[1] != want[1] {
+		t.Errorf("events = %v, want %v", p.events, want)
+	}
+}
+
+func TestHost_InitializeRejectsMalformedPattern(t *testing.T) {
+	for _, pattern := range []string{"ha.>.person", "ha..events", "ha.events.person*"} {
+		h := NewProcessorHost(slog.Default())
+		h.Register(&mockProcessor{subs: []string{pattern}})
+		err := h.Initialize(&config.CompiledConfig{}, nil, nil, nil, nil)
+		if !errors.Is(err, natsx.ErrInvalidPattern) {
+			t.Errorf("%q: error = %v, want %v", pattern, err, natsx.ErrInvalidPattern)
+		}
+	}
+}
+
+func TestHost_ReloadRebuildsRouter(t *testing.T) {
+	p := &mockReloader{mockProcessor: mockProcessor{subs: []string{"ha.events.light.>"}}}
+	h := NewProcessorHost(slog.Default())
+	h.Register(p)
+	if err := h.Initialize(&config.CompiledConfig{}, nil, nil, nil, nil); err != nil {
+		t.Fatalf("host.Initialize: %v", err)
+	}
+
+	p.subs = []string{"ha.events.person.>"}
+	if err := h.Reload(&config.CompiledConfig{}); err != nil {
+		t.Fatalf("host.Reload: %v", err)
+	}
+	_ = h.Process(context.Background(), "ha.events.light.kitchen", []byte("{}"))
+	_ = h.Process(context.Background(), "ha.events.person.wife", []byte("{}"))
+	if len(p.events) != 1 || p.events[0] != "ha.events.person.wife" {
+		t.Errorf("events = %v, want [ha.events.person.wife]", p.events)
+	}
+
+	// A malformed reload keeps the previous router.
+	p.subs = []string{"ha.>.bad"}
+	if err := h.Reload(&config.CompiledConfig{}); !errors.Is(err, natsx.ErrInvalidPattern) {
+		t.Fatalf("error = %v, want %v", err, natsx.ErrInvalidPattern)
+	}
+	_ = h.Process(context.Background(), "ha.events.person.wife", []byte("{}"))
+	if len(p.events) != 2 {
+		t.Errorf("events = %v, want the previous router to keep routing", p.events)
+	}
+}
+
+func TestHost_RouteIsolatesProcessorFailure(t *testing.T) {
+	failing := &mockProcessor{subs: []string{"ha.events.>"}, processErr: errors.New("calendar down")}
+	ok := &mockProcessor{subs: []string{"ha.events.person.>"}}
+	other := &mockProcessor{subs: []string{"ha.events.light.>"}}
+	h := newHost(t, failing, ok, other)
+
+	routes := h.Routes()
+	if len(routes) != 3 || routes[1].Name() != ok.name {
+		t.Fatalf("routes = %v, want one per processor in registration order", routes)
+	}
+	if err := routes[0].Process(context.Background(), "ha.events.person.wife", []byte("{}")); !errors.Is(err, failing.processErr) {
+		t.Errorf("failing route error = %v, want %v", err, failing.processErr)
+	}
+	if err := routes[1].Process(context.Background(), "ha.events.person.wife", []byte("{}")); err != nil {
+		t.Errorf("ok route error = %v, want nil", err)
+	}
+	if len(ok.events) != 1 || len(failing.events) != 1 {
+		t.Errorf("each route must reach only its own processor: ok=%v failing=%v", ok.events, failing.events)
+	}
+	if routes[2].Accepts("ha.events.person.wife") {
+		t.Error("route accepts a subject its processor does not subscribe to")
+	}
+	if err := routes[2].Process(context.Background(), "ha.events.person.wife", []byte("{}")); err != nil || len(other.events) != 0 {
+		t.Errorf("unsubscribed route: err=%v events=%v", err, other.events)
+	}
+}
+
+func TestHost_InitializeRejectsBadNames(t *testing.T) {
+	for name, procs := range map[string][]*mockProcessor{
+		"invalid token": {{name: "Bad-Name"}},
+		"duplicate":     {{name: "dup"}, {name: "dup"}},
+	} {
+		h := NewProcessorHost(slog.Default())
+		for _, p := range procs {
+			h.Register(p)
+		}
+		if err := h.Initialize(&config.CompiledConfig{}, nil, nil, nil, nil); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+// mockStateOwner is a mockProcessor that owns a state bucket.
+type mockStateOwner struct{ mockProcessor }
+
+func (m *mockStateOwner) StateTTL() time.Duration { return 0 }
+
+func TestHost_StateOwnerRequiresJetStream(t *testing.T) {
+	h := NewProcessorHost(slog.Default())
+	h.Register(&mockStateOwner{})
+	if err := h.Initialize(&config.CompiledConfig{}, nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error for a state owner without JetStream, got nil")
+	}
+}
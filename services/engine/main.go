@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"log/slog"
 	"os"
@@ -29,6 +28,9 @@ import (
 	adastore "github.com/primaryrutabaga/ruby-core/services/engine/processors/ada/store"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processors/calendar"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processors/presence_notify"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
 )
 
 var (
@@ -37,6 +39,10 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+
 	logger := logging.NewLogger("engine")
 	// Set as the process default so that package-level slog calls (e.g. in pkg/boot,
 	// pkg/idempotency) also emit structured JSON without needing a logger parameter.
@@ -109,6 +115,12 @@ func main() {
 	}
 	logger.Info("nats: DLQ stream ready")
 
+	if err := natsx.EnsureRejectedStream(js); err != nil {
+		logger.Error("nats: ensure REJECTED stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	logger.Info("nats: REJECTED stream ready")
+
 	// --- Phase 4: Audit stream ---
 
 	if err := natsx.EnsureAuditStream(js); err != nil {
@@ -129,6 +141,12 @@ func main() {
 	}
 	logger.Info("nats: PRESENCE stream ready")
 
+	if err := natsx.EnsureSchedulesStream(js); err != nil {
+		logger.Error("nats: ensure SCHEDULES stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	logger.Info("nats: SCHEDULES stream ready")
+
 	// --- Phase 3: Idempotency ---
 
 	kv, err := idempotency.CreateOrBindKVBucket(js, "idempotency", config.DefaultIdempotencyTTL)
@@ -136,7 +154,7 @@ func main() {
 		logger.Error("nats: idempotency KV bucket failed", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
-	idStore := idempotency.NewHybridStore(kv, config.DefaultIdempotencyTTL, "engine")
+	idStore := idempotency.NewHybridStore(kv, config.DefaultIdempotencyTTL, config.DefaultIdempotencyLease, "engine")
 	defer func() { _ = idStore.Close() }()
 	logger.Info("idempotency: hybrid store ready", slog.Duration("ttl", config.DefaultIdempotencyTTL))
 
@@ -184,43 +202,27 @@ func main() {
 	}
 	logger.Info("nats: config KV bucket ready")
 
-	passlistJSON, err := json.Marshal(ruleCfg.Passlist)
-	if err != nil {
-		logger.Error("config: marshal passlist", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
-	criticalJSON, err := json.Marshal(ruleCfg.CriticalEntities)
-	if err != nil {
-		logger.Error("config: marshal critical entities", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
-	if _, err := configKV.Put(natsx.KVKeyConfigPasslist, passlistJSON); err != nil {
-		logger.Error("nats: publish passlist to config KV", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
-	if _, err := configKV.Put(natsx.KVKeyConfigCriticalEntities, criticalJSON); err != nil {
-		logger.Error("nats: publish critical entities to config KV", slog.String("error", err.Error()))
+	if err := publishEngineConfig(configKV, ruleCfg); err != nil {
+		logger.Error("nats: publish engine config to config KV", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 	logger.Info("config: passlist and critical entities published to NATS KV")
 
-	// --- Consumer ---
-
-	consumerCfg := natsx.DefaultPullConsumerConfig("HA_EVENTS", "engine_processor", "ha.events.>")
-	sub, err := natsx.EnsurePullConsumer(js, consumerCfg)
+	// Durable timers: processors and rules register schedules in the schedules
+	// KV bucket; the scheduler publishes each firing on the SCHEDULES stream.
+	scheduleStore, err := state.Open(js, scheduler.Bucket, "ruby_engine/scheduler", 0)
 	if err != nil {
-		logger.Error("nats: ensure pull consumer failed", slog.String("error", err.Error()))
+		logger.Error("nats: schedules KV bucket failed", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
-	logger.Info(
-		"nats: pull consumer ready",
-		slog.String("consumer", "engine_processor"),
-		slog.Int("max_ack_pending", consumerCfg.MaxAckPending),
-		slog.Duration("ack_wait", consumerCfg.AckWait),
-	)
+	sched := scheduler.New(scheduleStore, js, logger)
+	logger.Info("scheduler: schedules KV bucket ready")
 
 	host := NewProcessorHost(logger)
+	host.SetScheduler(sched)
 	host.Register(presence_notify.New(logger))
+	// rules evaluates every YAML rule not already handled by presence_notify.
+	host.Register(rules.New(logger, presence_notify.Handles))
 	host.Register(ada.New(logger))
 	host.Register(calendar.New(logger))
 
@@ -291,58 +293,103 @@ func main() {
 	}
 	defer host.Shutdown()
 
-	processFn := host.Process
-	if forceFail {
-		logger.Warn("ENGINE_FORCE_FAIL is set — all events will be rejected; do not use in production")
-		processFn = forceFailProcess
-	}
+	// --- Consumers: one durable per processor per stream ---
+	// Each processor acks, retries and dead-letters events on its own consumer, so a
+	// transient failure in one processor does not re-run the side effects of the
+	// others. Each consumer's FilterSubjects follow its processor's subscriptions, so
+	// JetStream only delivers the events the processor takes. All consumers share one
+	// set of OTel instruments (nil-safe), labeled per-consumer at record time.
 
-	consumer, err := NewConsumer(sub, idStore, processFn, consumerCfg.WorkerCount, consumerCfg.FetchBatch, consumerCfg.BackOff, logger, auditPub)
+	msgInstr, err := natsx.NewMsgInstruments("engine")
 	if err != nil {
-		logger.Error("consumer init failed", slog.String("error", err.Error()))
-		os.Exit(1)
+		logger.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
 	}
-
-	dlqFwd, err := NewDLQForwarder(nc, js, "HA_EVENTS", "engine_processor", logger)
+	dedupCtr, err := natsx.NewDedupCounter()
 	if err != nil {
-		logger.Error("dlq forwarder init failed", slog.String("error", err.Error()))
-		os.Exit(1)
+		logger.Warn("otel: dedup counter unavailable", slog.String("error", err.Error()))
+	}
+	if forceFail {
+		logger.Warn("ENGINE_FORCE_FAIL is set — all events will be rejected; do not use in production")
 	}
 
-	// --- PRESENCE consumer (shares processor host) ---
+	var consumers []*Consumer
+	var dlqFwds []*natsx.DLQForwarder
+	var filtered []filteredConsumer
+	topology := natsx.Manifest()
+	for _, stream := range engineStreams {
+		startSeq, legacyThrough, err := legacyStartSeq(js, stream)
+		if err != nil {
+			logger.Error("nats: inspect legacy consumer failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
 
-	presenceCfg := natsx.DefaultPullConsumerConfig("PRESENCE", "engine_presence_processor", "ruby_presence.events.>")
-	presenceSub, err := natsx.EnsurePullConsumer(js, presenceCfg)
-	if err != nil {
-		logger.Error("nats: ensure presence pull consumer failed", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
-	logger.Info(
-		"nats: presence pull consumer ready",
-		slog.String("consumer", "engine_presence_processor"),
-	)
+		var names []string
+		for _, route := range host.Routes() {
+			name := processorConsumerName(route.Name())
+			consumerCfg, err := topology.Consumer(stream.name, name)
+			if err != nil {
+				logger.Error("nats: consumer config failed", slog.String("consumer", name), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			bound := consumerCfg.FilterSubject
+			consumerCfg.FilterSubjects = processorFilters(bound, route.Subscriptions())
+			consumerCfg.OptStartSeq, consumerCfg.DeliverNew = startSeq, true
+			sub, err := natsx.EnsurePullConsumer(js, consumerCfg)
+			if err != nil {
+				logger.Error("nats: ensure pull consumer failed", slog.String("consumer", name), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
 
-	presenceConsumer, err := NewConsumer(presenceSub, idStore, processFn, presenceCfg.WorkerCount, presenceCfg.FetchBatch, presenceCfg.BackOff, logger, auditPub)
-	if err != nil {
-		logger.Error("presence consumer init failed", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
+			processFn := route.Process
+			if forceFail {
+				processFn = forceFailProcess
+			}
+			consumer, err := NewConsumer(sub, idStore, processFn, consumerCfg.WorkerCount, consumerCfg.FetchBatch, consumerCfg.BackOff, logger, auditPub)
+			if err != nil {
+				logger.Error("consumer init failed", slog.String("consumer", name), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			consumer.stream, consumer.consumerName = stream.name, name
+			consumer.minWorkers = consumerCfg.MinWorkerCount
+			consumer.instruments, consumer.dedup = msgInstr, dedupCtr
+			consumer.accepts, consumer.idScope = route.Accepts, name
+			consumer.legacyThrough = legacyThrough
+			consumer.rejects = js
+			consumers = append(consumers, consumer)
+			names = append(names, name)
+			filtered = append(filtered, filteredConsumer{cfg: consumerCfg, bound: bound, route: route})
+
+			dlqFwd, err := natsx.NewDLQForwarder(nc, js, stream.name, name, consumer.failures, logger)
+			if err != nil {
+				logger.Error("dlq forwarder init failed", slog.String("consumer", name), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			dlqFwds = append(dlqFwds, dlqFwd)
+
+			logger.Info(
+				"nats: pull consumer ready",
+				slog.String("stream", stream.name),
+				slog.String("consumer", name),
+				slog.Uint64("start_seq", startSeq),
+				slog.Any("filter_subjects", consumerCfg.FilterSubjects),
+				slog.Int("max_ack_pending", consumerCfg.MaxAckPending),
+				slog.Duration("ack_wait", consumerCfg.AckWait),
+				slog.Int("min_workers", consumerCfg.MinWorkerCount),
+				slog.Int("max_workers", consumerCfg.WorkerCount),
+			)
+		}
 
-	// --- Observability: attach OTel message instruments to both consumers ---
-	// nil instruments/counter are safe (no-op); both consumers share one set, labeled
-	// per-consumer at record time.
-	msgInstr, err := natsx.NewMsgInstruments("engine")
-	if err != nil {
-		logger.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
-	}
-	dedupCtr, err := natsx.NewDedupCounter()
-	if err != nil {
-		logger.Warn("otel: dedup counter unavailable", slog.String("error", err.Error()))
+		retired, err := retireLegacyConsumer(js, stream, legacyThrough, names)
+		switch {
+		case err != nil:
+			logger.Warn("nats: legacy consumer not removed", slog.String("error", err.Error()))
+		case !retired:
+			logger.Info("nats: legacy consumer kept until processor consumers ack its last delivery",
+				slog.String("stream", stream.name),
+				slog.Uint64("through_seq", legacyThrough),
+			)
+		}
 	}
-	consumer.stream, consumer.consumerName = "HA_EVENTS", "engine_processor"
-	consumer.instruments, consumer.dedup = msgInstr, dedupCtr
-	presenceConsumer.stream, presenceConsumer.consumerName = "PRESENCE", "engine_presence_processor"
-	presenceConsumer.instruments, presenceConsumer.dedup = msgInstr, dedupCtr
 
 	// --- Graceful shutdown ---
 
@@ -355,32 +402,39 @@ func main() {
 		cancel()
 	}()
 
-	var wg sync.WaitGroup
-	wg.Add(3)
+	// Hot reload: rule-file changes are recompiled, handed to the processors and
+	// then republished to the config KV; invalid files keep the last good config.
+	reloader := &configReloader{dir: engineconfig.Dir(), current: ruleCfg, kv: configKV, host: host, audit: auditPub, log: logger}
+	reloader.reloaded = func() { syncConsumerFilters(js, filtered, logger) }
 
-	go func() {
-		defer wg.Done()
-		if err := consumer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			logger.Error("consumer exited with error", slog.String("error", err.Error()))
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if err := dlqFwd.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			logger.Error("dlq forwarder exited with error", slog.String("error", err.Error()))
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if err := presenceConsumer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			logger.Error("presence consumer exited with error", slog.String("error", err.Error()))
+	var wg sync.WaitGroup
+	for _, c := range consumers {
+		wg.Go(func() {
+			if err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("consumer exited with error", slog.String("consumer", c.consumerName), slog.String("error", err.Error()))
+			}
+		})
+	}
+	for _, f := range dlqFwds {
+		wg.Go(func() {
+			if err := f.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("dlq forwarder exited with error", slog.String("consumer", f.Consumer()), slog.String("error", err.Error()))
+			}
+		})
+	}
+	wg.Go(func() {
+		engineconfig.Watch(ctx, reloader.dir, engineconfig.DefaultWatchInterval, reloader.apply)
+	})
+	wg.Go(func() {
+		if err := sched.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("scheduler exited with error", slog.String("error", err.Error()))
 		}
-	}()
+	})
 
 	logger.Info(
-		"consumer and DLQ forwarder started",
-		slog.Int("workers", consumerCfg.WorkerCount),
-		slog.Int("batch", consumerCfg.FetchBatch),
+		"consumers and DLQ forwarders started",
+		slog.Int("consumers", len(consumers)),
+		slog.Int("dlq_forwarders", len(dlqFwds)),
 	)
 	wg.Wait()
 	logger.Info("engine stopped")
@@ -4,17 +4,24 @@
 //
 // Processors are self-contained; cross-processor communication MUST go via the
 // NATS bus (ADR-0007). Each processor is the sole writer to its NATS KV keyspace
-// (ADR-0002). See pkg/natsx/kv.go for the canonical KV bucket reference.
+// (ADR-0002); a StateOwner receives its keyspace as Config.State. See
+// pkg/natsx/kv.go for the canonical KV bucket reference. Time-based behavior
+// uses the durable timers in Config.Schedules rather than goroutines of its own,
+// so it survives restarts and is delivered like any other event.
 package processor
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nats-io/nats.go"
 
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
 )
 
 // Config bundles the resources passed to every processor at Initialize time.
@@ -25,6 +32,10 @@ type Config struct {
 	NC *nats.Conn
 	// JS is the JetStream context used for KV bucket access.
 	JS nats.JetStreamContext
+	// Commands publishes to the COMMANDS stream through JetStream, waiting for the
+	// ack and deduplicating retries by CloudEvent id (natsx.AckedPublisher). Use it
+	// via CommandPublisher. It is nil when the host runs without JetStream.
+	Commands natsx.MsgPublisher
 	// Pool is the shared PostgreSQL connection pool.
 	// It is non-nil only when at least one StatefulProcessor is registered.
 	// Stateless processors must not access Pool; it may be nil.
@@ -33,21 +44,45 @@ type Config struct {
 	// sensor state to HA. It is non-nil only when stateful processors are
 	// registered. Fetched in main.go via boot.FetchHAConfig.
 	HA *boot.HAConfig
+	// State is the processor's own durable state, a KV bucket named after the
+	// processor and stamped with it as owner (ADR-0002). It is non-nil only for a
+	// StateOwner; tests may pass a state.Memory.
+	State state.Store
+	// Schedules registers the processor's durable one-shot and cron timers
+	// with the engine scheduler. They fire as CloudEvents on
+	// Schedules.Subscription(), which the processor must list in Subscriptions
+	// to receive them. It is nil when the host runs without a scheduler.
+	Schedules *scheduler.Schedules
+}
+
+// CommandPublisher returns the publisher for commands on ruby_engine.commands.>:
+// Commands, or NC when the host runs without JetStream.
+func (c Config) CommandPublisher() natsx.MsgPublisher {
+	if c.Commands != nil {
+		return c.Commands
+	}
+	return c.NC
 }
 
 // Processor is the interface all logical processors must satisfy.
 //
+//   - Name returns a stable, unique subject token (e.g. "ada") identifying the
+//     processor. The engine derives the processor's durable consumer names and
+//     idempotency scope from it, so changing it replays the stream's history.
 //   - Initialize is called once at startup; it receives Config containing the
 //     compiled rule config and NATS/JetStream access. Returning an error causes
 //     the engine to exit.
-//   - Subscriptions returns the NATS subject patterns this processor handles.
-//     The host routes each incoming event to every processor whose subscription
-//     list contains a matching pattern.
+//   - Subscriptions returns the NATS subject patterns this processor handles,
+//     with full NATS wildcards ("*" in any position, ">" as the last token). It
+//     is read after Initialize and after each Reload; a malformed pattern fails
+//     the call. The host routes each incoming event to every processor whose
+//     subscription list contains a matching pattern.
 //   - ProcessEvent is called for each routed event. It must return an error only
 //     for transient failures; the caller treats errors as NAK signals.
 //   - Shutdown is called when the engine is shutting down. Implementations should
 //     release resources and return promptly.
 type Processor interface {
+	Name() string
 	Initialize(cfg Config) error
 	Subscriptions() []string
 	ProcessEvent(ctx context.Context, subject string, data []byte) error
@@ -61,3 +96,34 @@ type StatefulProcessor interface {
 	Processor
 	RequiresStorage() bool
 }
+
+// StateOwner is a Processor that keeps durable state in its own KV bucket. The
+// ProcessorHost opens the bucket (named after Name) before Initialize and passes
+// it as Config.State; opening fails if another owner has stamped the bucket.
+// StateTTL is the bucket's per-key TTL, fixed when the bucket is created; zero
+// keeps keys until deleted.
+type StateOwner interface {
+	Processor
+	StateTTL() time.Duration
+}
+
+// OfflineProcessor is a Processor that can run without a NATS connection,
+// publishing through pub instead of Config.NC (which is nil). The engine's
+// replay command and cmd/rules-lint use it to evaluate recorded events with
+// in-memory state; Config.JS is nil as well.
+type OfflineProcessor interface {
+	Processor
+	InitializeOffline(cfg Config, pub natsx.MsgPublisher) error
+}
+
+// Reloader is a Processor that can adopt a recompiled rule config while the
+// engine runs. When RULES_DIR changes and the new files load cleanly, the host
+// calls Reload on every registered Reloader; processors that do not implement it
+// keep the config they were initialized with until the next restart.
+//
+// Reload may be called concurrently with ProcessEvent and Subscriptions, so
+// implementations must swap their derived state atomically.
+type Reloader interface {
+	Processor
+	Reload(cfg *config.CompiledConfig) error
+}
@@ -1,6 +1,7 @@
 // Package ada implements the Ada baby tracking stateful processor (ADR-0029).
 // It persists feeding, diaper, sleep, and tummy time events to PostgreSQL and
-// pushes derived sensor state to Home Assistant after each event.
+// pushes derived sensor state to Home Assistant after each event. The feeding
+// alert is a durable engine schedule, so it survives engine restarts.
 package ada
 
 import (
@@ -11,7 +12,6 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -23,10 +23,12 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
 	adaha "github.com/primaryrutabaga/ruby-core/services/engine/processors/ada/ha"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processors/ada/store"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
 )
 
 const (
@@ -37,6 +39,19 @@ const (
 	cfgKeyNextFeedingTarget = "next_feeding_target"
 	cfgKeyFeedingClaimedBy  = "feeding_claimed_by" // current claimer name, "" when unclaimed
 
+	// scheduleFeedingAlert is the engine schedule that fires the feeding alert
+	// at next_feeding_target.
+	scheduleFeedingAlert = "feeding_alert"
+
+	// scheduleBedtimeBoundary fires daily at bedtime_hhmm to refresh the daily
+	// aggregates.
+	scheduleBedtimeBoundary = "bedtime_boundary"
+
+	// scheduleSafetyNet fires every minute for the medication reconcile, the
+	// sleep session timer and the 4-hour full sensor refresh (runSafetyNet).
+	scheduleSafetyNet = "safety_net"
+	safetyNetCron     = "* * * * *"
+
 	// ada_config keys — bedtime boundary
 	cfgKeyBedtimeHHMM     = "bedtime_hhmm"      // e.g. "19:00"
 	cfgKeyDaytimeHHMM     = "daytime_hhmm"      // e.g. "07:00"
@@ -92,7 +107,7 @@ var adaBoundaryCrossingsTotal, _ = otel.Meter("github.com/primaryrutabaga/ruby-c
 // Processor implements processor.StatefulProcessor for Ada baby tracking.
 // It persists feeding, diaper, sleep, and tummy time events to Postgres
 // and pushes derived sensor state to Home Assistant.
-// Daily aggregate refresh is driven by a bedtime boundary ticker (not midnight).
+// Daily aggregate refresh is driven by a bedtime boundary schedule (not midnight).
 type Processor struct {
 	q               *store.Queries
 	pool            *pgxpool.Pool // for multi-statement transactions (feeding edit)
@@ -100,23 +115,27 @@ type Processor struct {
 	lastHAConnected bool
 	healthSub       *nats.Subscription
 	log             *slog.Logger
-	stopCh          chan struct{}
-	lastFullRefresh time.Time // time of last full sensor restore (for 4h safety net)
-	alertMu         sync.Mutex
-	alertTimer      *time.Timer
+	lastFullRefresh time.Time            // time of last full sensor restore (for 4h safety net)
+	schedules       *scheduler.Schedules // feeding alert, bedtime boundary, safety net
 	// born is true once Ada's birth profile exists. While false (pre-birth), every
 	// event is forced test=true; the first ada.born clears the test slate (ADR-0035).
 	born atomic.Bool
 }
 
-// compile-time interface check
-var _ processor.StatefulProcessor = (*Processor)(nil)
+// compile-time interface checks
+var (
+	_ processor.StatefulProcessor = (*Processor)(nil)
+	_ processor.OfflineProcessor  = (*Processor)(nil)
+)
 
 // New returns a Processor. Initialize must be called before use.
 func New(log *slog.Logger) *Processor {
 	return &Processor{log: log}
 }
 
+// Name identifies the processor's consumers and idempotency scope.
+func (p *Processor) Name() string { return "ada" }
+
 func (p *Processor) RequiresStorage() bool { return true }
 
 func (p *Processor) Subscriptions() []string {
@@ -124,6 +143,8 @@ func (p *Processor) Subscriptions() []string {
 		"ha.events.ada.>",
 		// React to feed interval changes made in the HA dashboard.
 		"ha.events.input_number.ada_alert_threshold_h",
+		// The feeding alert, bedtime boundary and safety net schedules.
+		"ruby_engine.events.schedule.ada.>",
 		// Note: gateway.health is a bare NATS publish (not on HA_EVENTS JetStream)
 		// and is handled via a bare nc.Subscribe in Initialize, not listed here.
 		// Note: ha.events.ada.> covers growth_logged — no additional entry needed.
@@ -133,17 +154,9 @@ func (p *Processor) Subscriptions() []string {
 // Initialize wires the processor: creates the query client, HA push client,
 // bare gateway.health subscription, and restores sensor state from Postgres.
 func (p *Processor) Initialize(cfg processor.Config) error {
-	p.q = store.New(cfg.Pool)
-	p.pool = cfg.Pool
-
-	// Determine born state from the profile so pre-birth events are forced test=true
-	// (ADR-0035). Any error (incl. no rows) is treated as not-born.
-	if _, err := p.q.GetProfile(context.Background()); err == nil {
-		p.born.Store(true)
+	if err := p.InitializeOffline(cfg, cfg.NC); err != nil {
+		return err
 	}
-	p.ha = adaha.NewClient(cfg.HA.URL, cfg.HA.Token, p.log)
-	// Assume HA is connected at startup; gateway.health will correct if not.
-	p.lastHAConnected = true
 
 	// gateway.health is a bare NATS publish — not captured by HA_EVENTS JetStream.
 	// Subscribe directly on the connection; torn down in Shutdown.
@@ -155,6 +168,30 @@ func (p *Processor) Initialize(cfg processor.Config) error {
 		return fmt.Errorf("ada: subscribe gateway.health: %w", err)
 	}
 
+	p.log.Info("ada: processor initialized")
+	return nil
+}
+
+// InitializeOffline does everything Initialize does except subscribe to
+// gateway.health, for replaying recorded events against a scratch database.
+// The processor publishes nothing on NATS, so pub is unused.
+func (p *Processor) InitializeOffline(cfg processor.Config, _ natsx.MsgPublisher) error {
+	if cfg.Schedules == nil {
+		return errors.New("ada: Config.Schedules is nil")
+	}
+	p.schedules = cfg.Schedules
+	p.q = store.New(cfg.Pool)
+	p.pool = cfg.Pool
+
+	// Determine born state from the profile so pre-birth events are forced test=true
+	// (ADR-0035). Any error (incl. no rows) is treated as not-born.
+	if _, err := p.q.GetProfile(context.Background()); err == nil {
+		p.born.Store(true)
+	}
+	p.ha = adaha.NewClient(cfg.HA.URL, cfg.HA.Token, p.log)
+	// Assume HA is connected at startup; gateway.health will correct if not.
+	p.lastHAConnected = true
+
 	ctx := context.Background()
 
 	// Restore sensor state from Postgres so HA sensors are current immediately
@@ -165,30 +202,18 @@ func (p *Processor) Initialize(cfg processor.Config) error {
 	p.seedDefaultConfig(ctx)
 
 	p.lastFullRefresh = time.Now()
-	p.stopCh = make(chan struct{})
 
-	// Start the bedtime boundary ticker — fires once per day at bedtime.
-	p.startBoundaryTicker(ctx)
-
-	p.log.Info("ada: processor initialized")
-	return nil
+	// Register the bedtime boundary and safety net schedules.
+	return p.registerSchedules(ctx)
 }
 
-// Shutdown unsubscribes the bare gateway.health subscription and stops the
-// background ticker goroutine. The pool and HA client are owned by the engine
-// and must not be closed here.
+// Shutdown unsubscribes the bare gateway.health subscription. The pool and HA
+// client are owned by the engine and must not be closed here; the schedules
+// stay registered.
 func (p *Processor) Shutdown() {
-	p.alertMu.Lock()
-	if p.alertTimer != nil {
-		p.alertTimer.Stop()
-	}
-	p.alertMu.Unlock()
 	if p.healthSub != nil {
 		_ = p.healthSub.Unsubscribe()
 	}
-	if p.stopCh != nil {
-		close(p.stopCh)
-	}
 	p.log.Info("ada: processor shut down")
 }
 
@@ -290,6 +315,8 @@ func (p *Processor) ProcessEvent(_ context.Context, subject string, data []byte)
 		return p.handleEmergencyReorder(ctx, evt)
 	case "ha.events.input_number.ada_alert_threshold_h":
 		return p.handleThresholdChange(ctx, evt)
+	case schemas.ScheduleEventFired:
+		return p.handleScheduleFired(ctx, evt)
 	default:
 		p.log.Warn("ada: unknown event type", slog.String("subject", subject), slog.String("type", evt.Type))
 		return nil // ACK unknown events silently
@@ -1014,7 +1041,8 @@ func (p *Processor) handleBedtimeConfig(ctx context.Context, evt schemas.CloudEv
 
 	p.pushTodayBoundary(ctx)
 	p.pushDailyAggregates(ctx)
-	return nil
+	// Move the boundary schedule to the new bedtime.
+	return p.registerSchedules(ctx)
 }
 
 // ── Threshold change handler ─────────────────────────────────────────────────
@@ -1232,7 +1260,7 @@ func (p *Processor) pushAll(ctx context.Context, pushes []struct{ id, state stri
 // ── Sensor restore / periodic refresh ────────────────────────────────────────
 
 // refreshAllSensors re-pushes the complete Ada sensor set from Postgres.
-// Called on engine startup, HA reconnect, and the 4-hour safety-net ticker tick.
+// Called on engine startup, HA reconnect, and the 4-hour safety-net refresh.
 func (p *Processor) refreshAllSensors(ctx context.Context) {
 	p.pushLastEventSensors(ctx)
 	p.pushDailyAggregates(ctx)
@@ -1528,59 +1556,52 @@ func categorizeSleep(startTime time.Time, bedtimeHHMM, daytimeHHMM string, grace
 	return "nap"
 }
 
-// ── Background boundary ticker ────────────────────────────────────────────────
+// ── Bedtime boundary and safety net schedules ────────────────────────────────
 
-// startBoundaryTicker fires once per bedtime boundary crossing. On each crossing
-// it refreshes daily aggregates and increments the Prometheus counter.
-// It also runs a 4-hour safety-net full refresh inside the same goroutine via
-// a secondary ticker, so we don't lose the safety net from the old onTick.
-func (p *Processor) startBoundaryTicker(ctx context.Context) {
-	go func() {
-		safetyTicker := time.NewTicker(60 * time.Second)
-		defer safetyTicker.Stop()
+// registerSchedules registers the bedtime boundary at the configured bedtime and
+// the safety net with the engine scheduler. Registering unchanged schedules again
+// keeps their pending runs.
+func (p *Processor) registerSchedules(ctx context.Context) error {
+	bedtimeHHMM := "19:00"
+	if row, err := p.q.GetConfig(ctx, cfgKeyBedtimeHHMM); err == nil {
+		bedtimeHHMM = row.Value
+	}
+	if err := p.schedules.Cron(scheduleBedtimeBoundary, boundaryCron(bedtimeHHMM), nil); err != nil {
+		return fmt.Errorf("ada: schedule bedtime boundary: %w", err)
+	}
+	if err := p.schedules.Cron(scheduleSafetyNet, safetyNetCron, nil); err != nil {
+		return fmt.Errorf("ada: schedule safety net: %w", err)
+	}
+	return nil
+}
 
-		for {
-			// Compute time until next bedtime boundary.
-			bedtimeHHMM := "19:00"
-			if row, err := p.q.GetConfig(ctx, cfgKeyBedtimeHHMM); err == nil {
-				bedtimeHHMM = row.Value
-			}
-			// Next crossing = most recent boundary + 24h
-			nextBoundary := computeTodayBoundary(bedtimeHHMM).Add(24 * time.Hour)
-			delay := time.Until(nextBoundary)
-
-			boundaryTimer := time.NewTimer(delay)
-
-			// Inner loop: run safety-net ticks until the boundary fires.
-		inner:
-			for {
-				select {
-				case <-ctx.Done():
-					boundaryTimer.Stop()
-					return
-				case <-p.stopCh:
-					boundaryTimer.Stop()
-					return
-				case <-boundaryTimer.C:
-					p.log.Info("ada: bedtime boundary crossed — refreshing daily aggregates")
-					adaBoundaryCrossingsTotal.Add(ctx, 1)
-					p.pushTodayBoundary(ctx)
-					p.pushDailyAggregates(ctx)
-					break inner
-				case <-safetyTicker.C:
-					p.runSafetyNet(ctx)
-				}
-			}
-		}
-	}()
+// boundaryCron is the daily cron expression for a bedtime "HH:MM", falling back to
+// 19:00 like computeTodayBoundary when it does not parse.
+func boundaryCron(bedtimeHHMM string) string {
+	hh, mm, ok := strings.Cut(bedtimeHHMM, ":")
+	hour, errH := strconv.Atoi(hh)
+	minute, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return "0 19 * * *"
+	}
+	return fmt.Sprintf("%d %d * * *", minute, hour)
+}
+
+// onBedtimeBoundary refreshes the daily aggregates when the bedtime boundary
+// schedule fires.
+func (p *Processor) onBedtimeBoundary(ctx context.Context) {
+	p.log.Info("ada: bedtime boundary crossed — refreshing daily aggregates")
+	adaBoundaryCrossingsTotal.Add(ctx, 1)
+	p.pushTodayBoundary(ctx)
+	p.pushDailyAggregates(ctx)
 }
 
 // runSafetyNet implements the 4-hour safety-net full sensor refresh and the
-// sleep session elapsed-time push (formerly in onTick).
+// sleep session elapsed-time push, each time the safety net schedule fires.
 func (p *Processor) runSafetyNet(ctx context.Context) {
 	// Medication reconcile: emit missed, auto-complete routines, expire watches,
 	// and remind on due — the time-edge transitions that must fire with the app
-	// closed (ADR-0038). Runs every tick, independent of the 4-hour refresh below.
+	// closed (ADR-0038). Runs on every firing, independent of the 4-hour refresh below.
 	medCtx, medCancel := context.WithTimeout(ctx, 30*time.Second)
 	p.reconcileMedications(medCtx)
 	medCancel()
@@ -1602,10 +1623,10 @@ func (p *Processor) runSafetyNet(ctx context.Context) {
 	if err == nil {
 		if haErr := p.ha.PushState(innerCtx, sensorSleepSessionMin,
 			strconv.Itoa(sleepElapsedMin(active.Time)), nil); haErr != nil {
-			p.log.Warn("ada: ticker push sleep_session_min", slog.String("error", haErr.Error()))
+			p.log.Warn("ada: safety net push sleep_session_min", slog.String("error", haErr.Error()))
 		}
 	} else if !errors.Is(err, pgx.ErrNoRows) {
-		p.log.Warn("ada: ticker query active sleep session", slog.String("error", err.Error()))
+		p.log.Warn("ada: safety net query active sleep session", slog.String("error", err.Error()))
 	}
 }
 
@@ -1838,25 +1859,26 @@ func (p *Processor) pushTummyHistory(ctx context.Context) {
 
 // ── Feeding alert dispatch ────────────────────────────────────────────────────
 
-// setFeedingAlertTimer arms (or re-arms) a one-shot timer to fire
-// dispatchFeedingAlert at nextTarget. Safe to call concurrently.
+// setFeedingAlertTimer schedules (or reschedules) the feeding alert for
+// nextTarget, or cancels it if nextTarget has already passed. Errors are logged:
+// the next feeding or safety-net refresh registers the alert again.
 func (p *Processor) setFeedingAlertTimer(lastFeedingTime, nextTarget time.Time) {
-	p.alertMu.Lock()
-	defer p.alertMu.Unlock()
-	if p.alertTimer != nil {
-		p.alertTimer.Stop()
+	var err error
+	if time.Now().Before(nextTarget) {
+		err = p.schedules.At(scheduleFeedingAlert, nextTarget, map[string]any{
+			"last_feeding_time": lastFeedingTime.UTC().Format(time.RFC3339),
+		})
+	} else {
+		err = p.schedules.Cancel(scheduleFeedingAlert)
 	}
-	delay := time.Until(nextTarget)
-	if delay <= 0 {
-		return
+	if err != nil {
+		p.log.Warn("ada: schedule feeding alert", slog.String("error", err.Error()))
 	}
-	p.alertTimer = time.AfterFunc(delay, func() {
-		p.dispatchFeedingAlert(context.Background(), lastFeedingTime)
-	})
 }
 
-// restoreAlertTimer re-arms the feeding alert on engine restart if
-// next_feeding_target is still in the future.
+// restoreAlertTimer re-registers the feeding alert if next_feeding_target is
+// still in the future. Re-registering an unchanged alert is a no-op, and an
+// alert that came due while the engine was down is fired by the scheduler.
 func (p *Processor) restoreAlertTimer(ctx context.Context) {
 	cfg, err := p.q.GetConfig(ctx, cfgKeyNextFeedingTarget)
 	if err != nil {
@@ -1875,8 +1897,47 @@ func (p *Processor) restoreAlertTimer(ctx context.Context) {
 		slog.Duration("fires_in", time.Until(target)))
 }
 
+// handleScheduleFired runs the work of the schedule that fired.
+func (p *Processor) handleScheduleFired(ctx context.Context, evt schemas.CloudEvent) error {
+	switch id, _ := evt.Data["schedule_id"].(string); id {
+	case scheduleFeedingAlert:
+		return p.handleFeedingAlert(ctx, evt)
+	case scheduleBedtimeBoundary:
+		p.onBedtimeBoundary(ctx)
+	case scheduleSafetyNet:
+		p.runSafetyNet(ctx)
+	default:
+		p.log.Warn("ada: unknown schedule", slog.String("schedule_id", id))
+	}
+	return nil
+}
+
+// handleFeedingAlert dispatches the feeding alert when its schedule fires,
+// unless a later feeding has moved next_feeding_target since it was scheduled.
+func (p *Processor) handleFeedingAlert(ctx context.Context, evt schemas.CloudEvent) error {
+	scheduledStr, _ := evt.Data["scheduled_at"].(string)
+	lastStr, _ := evt.Data["last_feeding_time"].(string)
+	scheduledAt, err1 := time.Parse(time.RFC3339, scheduledStr)
+	last, err2 := time.Parse(time.RFC3339, lastStr)
+	if err1 != nil || err2 != nil {
+		p.log.Warn("ada: malformed feeding alert", slog.String("event_id", evt.ID))
+		return nil
+	}
+
+	cfg, err := p.q.GetConfig(ctx, cfgKeyNextFeedingTarget)
+	if err != nil {
+		return fmt.Errorf("ada: get next_feeding_target: %w", err)
+	}
+	// next_feeding_target is stored to the second.
+	if target, err := time.Parse(time.RFC3339, cfg.Value); err != nil || !target.Equal(scheduledAt.Truncate(time.Second)) {
+		p.log.Debug("ada: stale feeding alert skipped", slog.String("scheduled_at", scheduledStr))
+		return nil
+	}
+	p.dispatchFeedingAlert(ctx, last)
+	return nil
+}
+
 // dispatchFeedingAlert sends a push notification to all active caretakers.
-// Called by the alert timer goroutine — must not block indefinitely.
 func (p *Processor) dispatchFeedingAlert(ctx context.Context, lastFeedingTime time.Time) {
 	channels, err := p.q.GetActivePeopleWithChannels(ctx)
 	if err != nil {
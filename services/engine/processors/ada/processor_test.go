@@ -310,6 +310,24 @@ func TestComputeTodayBoundary_InvalidFormat(t *testing.T) {
 	}
 }
 
+// ── boundaryCron ──────────────────────────────────────────────────────────────
+
+func TestBoundaryCron(t *testing.T) {
+	cases := map[string]string{
+		"19:00":      "0 19 * * *",
+		"20:30":      "30 20 * * *",
+		"07:05":      "5 7 * * *",
+		"":           "0 19 * * *", // default bedtime
+		"not-a-time": "0 19 * * *",
+		"24:00":      "0 19 * * *",
+	}
+	for in, want := range cases {
+		if got := boundaryCron(in); got != want {
+			t.Errorf("boundaryCron(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 // ── sleepElapsedMin ───────────────────────────────────────────────────────────
 
 func TestSleepElapsedMin_Now(t *testing.T) {
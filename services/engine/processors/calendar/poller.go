@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	"time"
 
 	"github.com/jackc/pgx/v5"
 	calendarv3 "google.golang.org/api/calendar/v3"
@@ -12,27 +11,11 @@ import (
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/store"
 )
 
-// runPoller drives incremental sync on a timer until ctx is cancelled. A future
-// Google watch/push can replace the timer by calling syncOnce on the same path
-// (ADR-0042).
-func (p *Processor) runPoller(ctx context.Context) {
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
-
-	p.syncOnce(ctx) // sync immediately on startup
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			p.syncOnce(ctx)
-		}
-	}
-}
-
 // syncOnce runs one incremental sync pass: page through changes since the stored
 // sync token, upsert each into the mirror, and persist the new token. A 410 (token
-// expired) triggers exactly one full resync from scratch.
+// expired) triggers exactly one full resync from scratch. The sync schedule calls
+// it once a minute; a future Google watch/push can call it on the same path
+// (ADR-0042).
 func (p *Processor) syncOnce(ctx context.Context) {
 	syncToken := p.storedSyncToken(ctx)
 	pageToken := ""
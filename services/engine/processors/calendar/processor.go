@@ -1,6 +1,6 @@
 // Package calendar implements the engine-side calendar processor (ROADMAP-0012,
 // ADR-0042): the single ingress for calendar writes (write-through to Google +
-// the local mirror) and the owner of the incremental sync poller. Google is the
+// the local mirror) and the owner of the incremental sync poll. Google is the
 // system of record; ruby-core holds the durable mirror and the local overlay.
 //
 // All Google access is gated behind CALENDAR_SYNC_ENABLED. Only the environment
@@ -11,6 +11,7 @@ package calendar
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -22,10 +23,12 @@ import (
 
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/store"
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
 	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processors/calendar/gcal"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
 
 	"github.com/nats-io/nats.go"
 )
@@ -33,7 +36,11 @@ import (
 const (
 	idempotencyBucket = "calendar_idempotency"
 	idempotencyTTL    = 24 * time.Hour
-	pollInterval      = 60 * time.Second
+
+	// Schedules registered with the engine scheduler while sync is enabled.
+	scheduleSync      = "sync"
+	scheduleReminders = "reminders"
+	everyMinuteCron   = "* * * * *"
 )
 
 // calStore is the subset of store.Queries the processor uses. Abstracting it lets
@@ -78,9 +85,13 @@ type Processor struct {
 
 	idStore     idempotency.Store
 	syncEnabled bool
+	schedules   *scheduler.Schedules
+
+	// syncMu and remindMu skip a firing while the previous pass is still running.
+	syncMu   sync.Mutex
+	remindMu sync.Mutex
 
 	cancel context.CancelFunc
-	wg     sync.WaitGroup
 }
 
 // New constructs the calendar processor.
@@ -89,10 +100,14 @@ func New(log *slog.Logger) *Processor { return &Processor{log: log} }
 // RequiresStorage signals the engine to boot Postgres and run migrations.
 func (p *Processor) RequiresStorage() bool { return true }
 
+// Name identifies the processor's consumers and idempotency scope.
+func (p *Processor) Name() string { return "calendar" }
+
 // Subscriptions are the calendar + overlay write subjects routed in via the gateway
-// (Slices B/D).
+// (Slices B/D), plus the sync and reminder schedules.
 func (p *Processor) Subscriptions() []string {
 	return []string{
+		"ruby_engine.events.schedule.calendar.>",
 		schemas.HomeEventCalendarUpsert,
 		schemas.HomeEventCalendarDelete,
 		schemas.HomeEventChildcareProviderUpsert,
@@ -103,8 +118,13 @@ func (p *Processor) Subscriptions() []string {
 }
 
 // Initialize wires storage + NATS, and — when sync is enabled — connects Google
-// and starts the sync poller. Migrations are owned by the engine (see main.go).
+// and registers the sync and reminder schedules. Migrations are owned by the
+// engine (see main.go).
 func (p *Processor) Initialize(cfg processor.Config) error {
+	if cfg.Schedules == nil {
+		return errors.New("calendar: Config.Schedules is nil")
+	}
+	p.schedules = cfg.Schedules
 	p.pool = cfg.Pool
 	p.q = store.New(cfg.Pool)
 	p.nc = cfg.NC
@@ -114,11 +134,11 @@ func (p *Processor) Initialize(cfg processor.Config) error {
 	if err != nil {
 		return fmt.Errorf("calendar: idempotency kv: %w", err)
 	}
-	p.idStore = idempotency.NewHybridStore(kv, idempotencyTTL, "engine")
+	p.idStore = idempotency.NewHybridStore(kv, idempotencyTTL, config.DefaultIdempotencyLease, "engine")
 
 	if !p.syncEnabled {
 		p.log.Warn("calendar: sync disabled (CALENDAR_SYNC_ENABLED != true) — no Google connection; write events ignored")
-		return nil
+		return p.cancelSchedules()
 	}
 
 	gcfg, err := boot.FetchGoogleConfig(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), os.Getenv("VAULT_GOOGLE_PATH"))
@@ -144,8 +164,10 @@ func (p *Processor) Initialize(cfg processor.Config) error {
 		p.ha = newHAPusher("", "") // HA disabled → sensor pushes are no-ops
 	}
 
-	p.wg.Go(func() { p.runPoller(ctx) })
-	p.wg.Go(func() { p.runReminders(ctx) })
+	if err := p.registerSchedules(); err != nil {
+		cancel()
+		return err
+	}
 
 	p.log.Info("calendar: sync enabled",
 		slog.String("calendar_id", p.calendarID),
@@ -165,12 +187,34 @@ func reminderLeadFromEnv() time.Duration {
 	return 10 * time.Minute
 }
 
-// Shutdown cancels the poller and releases resources.
+// registerSchedules registers the once-a-minute sync and reminder passes.
+// Registering them again on restart keeps their pending runs.
+func (p *Processor) registerSchedules() error {
+	for _, id := range []string{scheduleSync, scheduleReminders} {
+		if err := p.schedules.Cron(id, everyMinuteCron, nil); err != nil {
+			return fmt.Errorf("calendar: schedule %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// cancelSchedules removes the sync and reminder schedules, so an environment
+// that turns sync off stops receiving their firings.
+func (p *Processor) cancelSchedules() error {
+	for _, id := range []string{scheduleSync, scheduleReminders} {
+		if err := p.schedules.Cancel(id); err != nil {
+			return fmt.Errorf("calendar: cancel schedule %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Shutdown cancels the Google client context and releases resources. The
+// schedules stay registered.
 func (p *Processor) Shutdown() {
 	if p.cancel != nil {
 		p.cancel()
 	}
-	p.wg.Wait()
 	if p.idStore != nil {
 		_ = p.idStore.Close()
 	}
@@ -189,6 +233,11 @@ func (p *Processor) ProcessEvent(ctx context.Context, subject string, data []byt
 		return nil // ack malformed
 	}
 
+	if evt.Type == schemas.ScheduleEventFired {
+		p.handleScheduleFired(ctx, evt)
+		return nil
+	}
+
 	switch subject {
 	case schemas.HomeEventCalendarUpsert:
 		return p.handleUpsert(ctx, &evt)
@@ -207,6 +256,28 @@ func (p *Processor) ProcessEvent(ctx context.Context, subject string, data []byt
 	}
 }
 
+// handleScheduleFired runs the sync or reminder pass whose schedule fired. A
+// firing that arrives while the previous pass is still running is skipped; the
+// next minute's firing picks up the work.
+func (p *Processor) handleScheduleFired(ctx context.Context, evt schemas.CloudEvent) {
+	switch id, _ := evt.Data["schedule_id"].(string); id {
+	case scheduleSync:
+		if !p.syncMu.TryLock() {
+			return
+		}
+		defer p.syncMu.Unlock()
+		p.syncOnce(ctx)
+	case scheduleReminders:
+		if !p.remindMu.TryLock() {
+			return
+		}
+		defer p.remindMu.Unlock()
+		p.tickReminders(ctx)
+	default:
+		p.log.Warn("calendar: unknown schedule", slog.String("schedule_id", id))
+	}
+}
+
 // decodeData re-marshals the CloudEvent data map into a typed payload.
 func decodeData(data map[string]any, out any) error {
 	b, err := json.Marshal(data)
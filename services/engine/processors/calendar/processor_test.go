@@ -17,6 +17,8 @@ import (
 
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/expand"
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/store"
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processors/calendar/gcal"
 )
@@ -239,11 +241,24 @@ func (s *fakeStore) ListOverrides(_ context.Context) ([]*store.CalendarEvent, er
 	return nil, nil
 }
 
+// fakeIDStore tracks claims in memory: seen[id] is false while id is claimed and true
+// once it is completed.
 type fakeIDStore struct{ seen map[string]bool }
 
-func (f *fakeIDStore) Seen(id string) (bool, error) { return f.seen[id], nil }
-func (f *fakeIDStore) Mark(id string) error         { f.seen[id] = true; return nil }
-func (f *fakeIDStore) Close() error                 { return nil }
+func (f *fakeIDStore) Claim(id string) (idempotency.ClaimResult, error) {
+	if done, ok := f.seen[id]; ok {
+		if done {
+			return idempotency.Done, nil
+		}
+		return idempotency.InFlight, nil
+	}
+	f.seen[id] = false
+	return idempotency.Claimed, nil
+}
+
+func (f *fakeIDStore) Complete(id string) error { f.seen[id] = true; return nil }
+func (f *fakeIDStore) Release(id string) error  { delete(f.seen, id); return nil }
+func (f *fakeIDStore) Close() error             { return nil }
 
 func newTestProcessor() (*Processor, *fakeGCal, *fakeStore) {
 	g := newFakeGCal()
@@ -496,6 +511,51 @@ func TestSyncOnce_UpsertsAndPersistsToken(t *testing.T) {
 	}
 }
 
+// scheduleFired is the engine scheduler's firing event for one of the calendar
+// schedules, as the consumer delivers it.
+func scheduleFired(t *testing.T, id string) []byte {
+	t.Helper()
+	b, err := json.Marshal(schemas.CloudEvent{
+		ID:   "fire-" + id,
+		Type: schemas.ScheduleEventFired,
+		Data: map[string]any{"schedule_id": id, "scheduled_at": "2026-06-26T09:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("marshal firing: %v", err)
+	}
+	return b
+}
+
+// The sync schedule's firing runs one sync pass; a firing that arrives while a
+// pass is still running is skipped rather than syncing concurrently.
+func TestProcessEvent_SyncScheduleRunsSyncOnce(t *testing.T) {
+	p, g, st := newTestProcessor()
+	g.listResults = []*gcal.ListResult{{
+		Events:        []*calendarv3.Event{googleTimed("g1", `"e1"`)},
+		NextSyncToken: "tok2",
+	}}
+	subject := "ruby_engine.events.schedule.calendar." + scheduleSync
+
+	p.syncMu.Lock()
+	if err := p.ProcessEvent(context.Background(), subject, scheduleFired(t, scheduleSync)); err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	p.syncMu.Unlock()
+	if st.sync != nil {
+		t.Fatalf("sync ran while a pass was in progress: %+v", st.sync)
+	}
+
+	if err := p.ProcessEvent(context.Background(), subject, scheduleFired(t, scheduleSync)); err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if _, ok := st.events["g1"]; !ok {
+		t.Error("synced event not mirrored")
+	}
+	if st.sync == nil || st.sync.SyncToken.String != "tok2" {
+		t.Errorf("sync token not persisted: %+v", st.sync)
+	}
+}
+
 func TestSyncOnce_EchoSkipsSameEtag(t *testing.T) {
 	p, g, st := newTestProcessor()
 	st.events["g1"] = &store.CalendarEvent{GoogleEventID: "g1", Etag: "e1"} // already mirrored
@@ -748,3 +808,10 @@ func TestHandleUpsert_ThisAndFollowingIsIgnored(t *testing.T) {
 			g.patches, g.inserts, g.updates, st.upserts)
 	}
 }
+
+func TestIdempotencyBucketInManifest(t *testing.T) {
+	b, ok := natsx.Manifest().Bucket(idempotencyBucket)
+	if !ok || b.TTL != idempotencyTTL {
+		t.Errorf("manifest bucket %q = %+v, %v; want TTL %v", idempotencyBucket, b, ok, idempotencyTTL)
+	}
+}
@@ -9,31 +9,16 @@ import (
 	rubycal "github.com/primaryrutabaga/ruby-core/pkg/calendar"
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/expand"
 	"github.com/primaryrutabaga/ruby-core/pkg/calendar/store"
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
 const (
-	reminderInterval  = 60 * time.Second
+	reminderInterval  = 60 * time.Second // cadence of the reminders schedule
 	reminderLookahead = 36 * time.Hour
 	statusSensor      = "sensor.ruby_home_calendar_status"
 )
 
-// runReminders ticks reminder evaluation until ctx is cancelled.
-func (p *Processor) runReminders(ctx context.Context) {
-	ticker := time.NewTicker(reminderInterval)
-	defer ticker.Stop()
-
-	p.tickReminders(ctx)
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			p.tickReminders(ctx)
-		}
-	}
-}
-
 // tickReminders computes reminders over the mirror — ruby-core owns reminder policy
 // and ignores Google's per-event reminder overrides (ADR-0042). Each due reminder
 // fires once (deduped) on NATS, and the always-on status sensor is refreshed with
@@ -92,10 +77,11 @@ func statusState(next *expand.Instance, active bool) string {
 }
 
 // fireReminder publishes calendar.reminder.due for one occurrence, deduped by
-// event id + occurrence start so it fires exactly once.
+// event id + occurrence start and claimed first so it fires exactly once, even when
+// two engines tick over the same occurrence.
 func (p *Processor) fireReminder(in expand.Instance, row *store.CalendarEvent) {
 	key := "reminder:" + in.GoogleEventID + ":" + in.Start.UTC().Format(time.RFC3339)
-	if seen, err := p.idStore.Seen(key); err != nil || seen {
+	if claim, err := p.idStore.Claim(key); err != nil || claim != idempotency.Claimed {
 		return
 	}
 
@@ -122,14 +108,16 @@ func (p *Processor) fireReminder(in expand.Instance, row *store.CalendarEvent) {
 	b, err := json.Marshal(evt)
 	if err != nil {
 		p.log.Warn("calendar: marshal reminder failed", slog.String("error", err.Error()))
+		p.releaseReminder(key)
 		return
 	}
 	if err := p.nc.Publish(schemas.HomeEventCalendarReminderDue, b); err != nil {
 		p.log.Warn("calendar: publish reminder failed", slog.String("error", err.Error()))
+		p.releaseReminder(key)
 		return
 	}
-	if err := p.idStore.Mark(key); err != nil {
-		p.log.Warn("calendar: mark reminder failed", slog.String("error", err.Error()))
+	if err := p.idStore.Complete(key); err != nil {
+		p.log.Warn("calendar: complete reminder failed", slog.String("error", err.Error()))
 	}
 	p.log.Info("calendar: reminder due",
 		slog.String("google_event_id", in.GoogleEventID),
@@ -137,6 +125,13 @@ func (p *Processor) fireReminder(in expand.Instance, row *store.CalendarEvent) {
 	)
 }
 
+// releaseReminder gives up the claim on an unsent reminder so the next tick retries it.
+func (p *Processor) releaseReminder(key string) {
+	if err := p.idStore.Release(key); err != nil {
+		p.log.Warn("calendar: release reminder failed", slog.String("error", err.Error()))
+	}
+}
+
 // pushStatus refreshes sensor.ruby_home_calendar_status: state is reminder|upcoming|idle,
 // with the next event summary/start and the active-reminder flag as attributes.
 func (p *Processor) pushStatus(ctx context.Context, next *expand.Instance, rows map[string]*store.CalendarEvent, active bool) {
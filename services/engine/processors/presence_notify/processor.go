@@ -2,9 +2,12 @@
 // when a tracked person arrives home or leaves, and publishes a notification
 // command for the notifier service to dispatch as a push notification.
 //
-// State ownership: this processor is the sole writer of the "presence" NATS KV
-// bucket (ADR-0002). Each key is the HA entity ID (e.g. "person.wife"); the value
-// is a JSON-encoded presenceState struct.
+// State ownership: this processor owns the "presence_notify" state bucket
+// (ADR-0002), which the host opens for it as Config.State. Each key is the HA
+// entity ID (e.g. "person.wife"); the value is a JSON-encoded presenceState
+// struct. Earlier releases kept the same keys in the "presence" bucket, which the
+// presence service also writes; an entity with no state of its own is read from
+// there once, so upgrading does not re-announce everyone's current presence.
 //
 // NATS subjects:
 //
@@ -16,51 +19,21 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/nats-io/nats.go"
-
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
 )
 
-// kvStore is a narrow interface over nats.KeyValue, used for dependency
-// injection in tests without requiring a live NATS server.
-type kvStore interface {
-	Get(key string) ([]byte, error)
-	Put(key string, val []byte) error
-}
-
-// natsPub is a narrow interface over *nats.Conn for publishing, allowing
-// test injection without a live NATS connection. PublishMsg carries headers so the
-// notify command can propagate W3C trace context to the notifier (PLAN-0009).
-type natsPub interface {
-	Publish(subject string, data []byte) error
-	PublishMsg(*nats.Msg) error
-}
-
-// kvAdapter adapts nats.KeyValue to the kvStore interface.
-type kvAdapter struct{ kv nats.KeyValue }
-
-func (a *kvAdapter) Get(key string) ([]byte, error) {
-	entry, err := a.kv.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return entry.Value(), nil
-}
-
-func (a *kvAdapter) Put(key string, val []byte) error {
-	_, err := a.kv.Put(key, val)
-	return err
-}
-
-// presenceState is persisted to the "presence" KV bucket for each watched entity.
+// presenceState is persisted to the state bucket for each watched entity.
 type presenceState struct {
 	State     string    `json:"state"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -75,6 +48,7 @@ type ruleTarget struct {
 }
 
 type notifyParams struct {
+	rule    string
 	title   string
 	message string
 	device  string
@@ -82,14 +56,20 @@ type notifyParams struct {
 
 // Processor implements processor.Processor for presence-based notifications.
 type Processor struct {
+	mu      sync.RWMutex // guards targets, which Reload replaces
 	targets []ruleTarget
-	kv      kvStore
-	nc      natsPub
+	states  state.Typed[presenceState]
+	legacy  state.Reader // "presence" bucket; nil when absent or offline
+	nc      natsx.MsgPublisher
 	log     *slog.Logger
 }
 
-// compile-time interface check
-var _ processor.Processor = (*Processor)(nil)
+// compile-time interface checks
+var (
+	_ processor.Reloader         = (*Processor)(nil)
+	_ processor.StateOwner       = (*Processor)(nil)
+	_ processor.OfflineProcessor = (*Processor)(nil)
+)
 
 // New returns a new Processor. Register it with the ProcessorHost before Initialize.
 func New(log *slog.Logger) *Processor {
@@ -99,17 +79,22 @@ func New(log *slog.Logger) *Processor {
 	return &Processor{log: log}
 }
 
-// Initialize resolves rule targets from the compiled config and binds the
-// presence KV bucket. Returns an error if NATS/JetStream access fails.
-func (p *Processor) Initialize(cfg processor.Config) error {
-	p.nc = cfg.NC
-	p.targets = buildTargets(cfg.RuleCfg)
+// StateTTL keeps presence state until it is overwritten.
+func (p *Processor) StateTTL() time.Duration { return 0 }
 
-	kv, err := natsx.EnsurePresenceKV(cfg.JS)
+// Initialize resolves rule targets from the compiled config, adopts the state
+// bucket the host opened for the processor and binds the legacy presence bucket
+// for reads.
+func (p *Processor) Initialize(cfg processor.Config) error {
+	if err := p.InitializeOffline(cfg, cfg.CommandPublisher()); err != nil {
+		return err
+	}
+	legacy, err := state.OpenReader(cfg.JS, natsx.KVBucketPresence)
 	if err != nil {
-		return fmt.Errorf("presence_notify: ensure KV: %w", err)
+		p.log.Info("presence_notify: no legacy presence bucket to read from", slog.String("error", err.Error()))
+	} else {
+		p.legacy = legacy
 	}
-	p.kv = &kvAdapter{kv}
 
 	p.log.Info("presence_notify: initialized",
 		slog.Int("targets", len(p.targets)),
@@ -117,15 +102,39 @@ func (p *Processor) Initialize(cfg processor.Config) error {
 	return nil
 }
 
-// InitializeForTest is a test seam that injects stub dependencies directly.
-// Only call from tests; do not use in production code.
-func (p *Processor) InitializeForTest(cfg processor.Config, kv kvStore, nc natsPub) error {
-	p.kv = kv
+// InitializeOffline resolves rule targets from cfg against cfg.State and a
+// caller-supplied publisher instead of a NATS connection, for offline
+// evaluation such as the cmd/rules-lint dry run.
+func (p *Processor) InitializeOffline(cfg processor.Config, nc natsx.MsgPublisher) error {
+	if cfg.State == nil {
+		return errors.New("presence_notify: Config.State is nil")
+	}
+	p.states = state.NewTyped[presenceState](cfg.State)
 	p.nc = nc
 	p.targets = buildTargets(cfg.RuleCfg)
 	return nil
 }
 
+// InitializeForTest is a test seam that injects stub dependencies directly.
+// Only call from tests; do not use in production code.
+func (p *Processor) InitializeForTest(cfg processor.Config, nc natsx.MsgPublisher) error {
+	return p.InitializeOffline(cfg, nc)
+}
+
+// Reload rebuilds the rule targets from a recompiled config. The presence KV
+// state is kept, so an entity already at its target state does not re-notify.
+func (p *Processor) Reload(cfg *config.CompiledConfig) error {
+	targets := buildTargets(cfg)
+	p.mu.Lock()
+	p.targets = targets
+	p.mu.Unlock()
+	p.log.Info("presence_notify: reloaded", slog.Int("targets", len(targets)))
+	return nil
+}
+
+// Name identifies the processor's consumers and idempotency scope.
+func (p *Processor) Name() string { return "presence_notify" }
+
 // Subscriptions returns the NATS subjects this processor handles.
 func (p *Processor) Subscriptions() []string {
 	return []string{
@@ -163,7 +172,15 @@ func (p *Processor) ProcessEvent(ctx context.Context, subject string, data []byt
 		return nil // entity not watched by any rule
 	}
 
-	prev, err := p.loadState(entityID)
+	// An HA event carrying its previous state shows an attribute-only update
+	// (e.g. a new GPS fix) without a KV read. Presence service events carry none,
+	// so the stored state below still decides for them, and still guards against
+	// announcing one transition twice.
+	if tr := schemas.StateTransition(evt, "state"); tr.Known && !tr.Changed() {
+		return nil // no transition
+	}
+
+	prev, rev, err := p.loadState(entityID)
 	if err != nil {
 		return fmt.Errorf("presence_notify: load state %q: %w", entityID, err)
 	}
@@ -172,8 +189,11 @@ func (p *Processor) ProcessEvent(ctx context.Context, subject string, data []byt
 		return nil // no transition
 	}
 
-	// Persist new state before publishing to avoid re-notifying on restart.
-	if err := p.saveState(entityID, newState); err != nil {
+	// Persist new state before publishing to avoid re-notifying on restart. The
+	// write is conditional on the revision read above, so two workers handling
+	// events for the same entity cannot both announce one transition: the loser
+	// gets state.ErrConflict and is redelivered.
+	if err := p.saveState(entityID, newState, rev); err != nil {
 		return fmt.Errorf("presence_notify: save state %q: %w", entityID, err)
 	}
 
@@ -191,6 +211,8 @@ func (p *Processor) Shutdown() {}
 // --- internal helpers ---
 
 func (p *Processor) targetFor(entityID string) *ruleTarget {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	for i := range p.targets {
 		if p.targets[i].entityID == entityID {
 			return &p.targets[i]
@@ -199,58 +221,59 @@ func (p *Processor) targetFor(entityID string) *ruleTarget {
 	return nil
 }
 
-func (p *Processor) loadState(entityID string) (*presenceState, error) {
-	data, err := p.kv.Get(entityID)
+// loadState returns the last recorded state of entityID and its revision in
+// the state bucket (0 if it has none there, e.g. when read from the legacy
+// bucket), or nil if the entity has never been seen.
+func (p *Processor) loadState(entityID string) (*presenceState, uint64, error) {
+	st, rev, err := p.states.Get(entityID)
+	if err == nil {
+		return &st, rev, nil
+	}
+	if !errors.Is(err, state.ErrNotFound) {
+		return nil, 0, err
+	}
+	if p.legacy == nil {
+		return nil, 0, nil
+	}
+	e, err := p.legacy.Get(entityID)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil, 0, nil
+	}
 	if err != nil {
-		if isNotFound(err) {
-			return nil, nil
-		}
-		return nil, err
+		return nil, 0, err
 	}
-	var st presenceState
-	if err := json.Unmarshal(data, &st); err != nil {
-		return nil, err
+	if err := json.Unmarshal(e.Value, &st); err != nil {
+		return nil, 0, nil // a presence service value, not ours: treat as unseen
 	}
-	return &st, nil
+	return &st, 0, nil
 }
 
-func (p *Processor) saveState(entityID, state string) error {
-	st := presenceState{State: state, UpdatedAt: time.Now().UTC()}
-	data, err := json.Marshal(st)
-	if err != nil {
+// saveState records newState for entityID, expecting the entity to be at
+// revision rev (0: not yet in the state bucket).
+func (p *Processor) saveState(entityID, newState string, rev uint64) error {
+	st := presenceState{State: newState, UpdatedAt: time.Now().UTC()}
+	if rev == 0 {
+		_, err := p.states.Create(entityID, st)
 		return err
 	}
-	return p.kv.Put(entityID, data)
+	_, err := p.states.Update(entityID, st, rev)
+	return err
 }
 
 func (p *Processor) publishNotify(ctx context.Context, cause schemas.CloudEvent, params notifyParams) error {
-	corrID := cause.CorrelationID
-	if corrID == "" {
-		corrID = cause.ID
-	}
-	evtID := newID()
-
-	cmd := schemas.CloudEvent{
-		SpecVersion:   schemas.CloudEventsSpecVersion,
-		ID:            evtID,
-		Source:        "ruby_engine",
-		Type:          "command.notify",
-		Time:          time.Now().UTC().Format(time.RFC3339),
-		CorrelationID: corrID,
-		CausationID:   cause.ID,
-		Data: map[string]any{
-			"title":   params.title,
-			"message": params.message,
-			"device":  params.device,
-		},
-	}
+	cmd := schemas.NewCommand(newID(), schemas.CommandTypeNotify, cause, map[string]any{
+		"rule":    params.rule,
+		"title":   params.title,
+		"message": params.message,
+		"device":  params.device,
+	})
 
 	data, err := json.Marshal(cmd)
 	if err != nil {
 		return fmt.Errorf("presence_notify: marshal notify command: %w", err)
 	}
 
-	subj := "ruby_engine.commands.notify." + evtID
+	subj := schemas.CommandSubjectPrefix + schemas.ActionTypeNotify + "." + cmd.ID
 	if err := natsx.PublishWithContext(ctx, p.nc, subj, data); err != nil {
 		return fmt.Errorf("presence_notify: publish notify command: %w", err)
 	}
@@ -258,14 +281,39 @@ func (p *Processor) publishNotify(ctx context.Context, cause schemas.CloudEvent,
 	p.log.Info("presence_notify: notification dispatched",
 		slog.String("subject", subj),
 		slog.String("title", params.title),
-		slog.String("correlationid", corrID),
+		slog.String("correlationid", cmd.CorrelationID),
 	)
 	return nil
 }
 
+// Handles reports whether rule has the shape this processor acts on: an "ha"
+// person/device_tracker or "ruby_presence" trigger with an ID, a single "notify"
+// action, and a single "state_transition" condition on the "state" field. Rules
+// with any further conditions or actions are left to the generic rules
+// processor, which skips the rules Handles accepts so none is acted on twice.
+func Handles(rule schemas.Rule) bool {
+	t := rule.Trigger
+	if t.ID == "" {
+		return false
+	}
+	switch t.Source {
+	case "ha":
+		if t.Type != "person" && t.Type != "device_tracker" {
+			return false
+		}
+	case "ruby_presence":
+		// any type is valid (e.g. "state")
+	default:
+		return false
+	}
+	if len(rule.Conditions) != 1 || len(rule.Actions) != 1 {
+		return false
+	}
+	return notifyAction(rule) != nil && targetState(rule) != ""
+}
+
 // buildTargets derives ruleTargets from the raw rules in cfg.
-// Only rules with a "notify" action and a "state_transition" condition are included.
-// Supported trigger sources: "ha" (person/device_tracker), "ruby_presence" (state).
+// Only rules accepted by Handles are included.
 func buildTargets(cfg *config.CompiledConfig) []ruleTarget {
 	if cfg == nil {
 		return nil
@@ -275,58 +323,21 @@ func buildTargets(cfg *config.CompiledConfig) []ruleTarget {
 	var targets []ruleTarget
 
 	for _, rule := range cfg.Rules {
-		t := rule.Trigger
-		if t.ID == "" {
+		if !Handles(rule) {
 			continue
 		}
 
-		switch t.Source {
-		case "ha":
-			if t.Type != "person" && t.Type != "device_tracker" {
-				continue
-			}
-		case "ruby_presence":
-			// any type is valid (e.g. "state")
-		default:
-			continue
-		}
-
-		entityID := t.Type + "." + t.ID
-
-		var np *notifyParams
-		for _, action := range rule.Actions {
-			if action.Type != schemas.ActionTypeNotify {
-				continue
-			}
-			np = &notifyParams{
-				title:   action.Params["title"],
-				message: action.Params["message"],
-				device:  action.Params["device"],
-			}
-			break
-		}
-		if np == nil {
-			continue
-		}
-
-		targetState := ""
-		for _, cond := range rule.Conditions {
-			if cond.Type == schemas.ConditionTypeStateTransition && cond.Field == "state" {
-				targetState = cond.Value
-				break
-			}
-		}
-		if targetState == "" {
-			continue
-		}
+		entityID := rule.Trigger.Type + "." + rule.Trigger.ID
+		np := notifyAction(rule)
+		state := targetState(rule)
 
 		if idx, ok := index[entityID]; ok {
-			targets[idx].notifications[targetState] = *np
+			targets[idx].notifications[state] = *np
 		} else {
 			index[entityID] = len(targets)
 			targets = append(targets, ruleTarget{
 				entityID:      entityID,
-				notifications: map[string]notifyParams{targetState: *np},
+				notifications: map[string]notifyParams{state: *np},
 			})
 		}
 	}
@@ -334,9 +345,31 @@ func buildTargets(cfg *config.CompiledConfig) []ruleTarget {
 	return targets
 }
 
-// isNotFound reports whether a KV error indicates a missing key.
-func isNotFound(err error) bool {
-	return err == nats.ErrKeyNotFound || strings.Contains(err.Error(), "not found")
+// notifyAction returns the params of the rule's first "notify" action, or nil.
+func notifyAction(rule schemas.Rule) *notifyParams {
+	for _, action := range rule.Actions {
+		if action.Type != schemas.ActionTypeNotify {
+			continue
+		}
+		return &notifyParams{
+			rule:    rule.Name,
+			title:   action.Params["title"],
+			message: action.Params["message"],
+			device:  action.Params["device"],
+		}
+	}
+	return nil
+}
+
+// targetState returns the value of the rule's first "state_transition"
+// condition on the "state" field, or "" if there is none.
+func targetState(rule schemas.Rule) string {
+	for _, cond := range rule.Conditions {
+		if cond.Type == schemas.ConditionTypeStateTransition && cond.Field == "state" {
+			return cond.Value
+		}
+	}
+	return ""
 }
 
 // entityIDFromSubject derives the entity ID from a NATS subject.
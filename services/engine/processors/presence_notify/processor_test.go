@@ -5,7 +5,6 @@ package presence_notify_test
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -16,30 +15,15 @@ import (
 	"github.com/primaryrutabaga/ruby-core/services/engine/config"
 	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
 	pn "github.com/primaryrutabaga/ruby-core/services/engine/processors/presence_notify"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
 )
 
 // ---------------------------------------------------------------------------
 // Stub KV store for testing without a live NATS server
 // ---------------------------------------------------------------------------
 
-type stubKV struct {
-	data map[string][]byte
-}
-
-func newStubKV() *stubKV { return &stubKV{data: make(map[string][]byte)} }
-
-func (s *stubKV) Get(key string) ([]byte, error) {
-	v, ok := s.data[key]
-	if !ok {
-		return nil, fmt.Errorf("not found")
-	}
-	return v, nil
-}
-
-func (s *stubKV) Put(key string, val []byte) error {
-	s.data[key] = val
-	return nil
-}
+// newStubKV returns an empty in-memory state store.
+func newStubKV() *state.Memory { return state.NewMemory(0) }
 
 // ---------------------------------------------------------------------------
 // Stub NATS publisher
@@ -138,10 +122,10 @@ func minimalConfig() *config.CompiledConfig {
 
 // newTestProcessor creates a Processor with injected stub dependencies.
 // We use the exported constructor + InitializeWithDeps test seam.
-func newTestProcessor(t *testing.T, kv *stubKV, nc *stubNC, cfg *config.CompiledConfig) *pn.Processor {
+func newTestProcessor(t *testing.T, kv *state.Memory, nc *stubNC, cfg *config.CompiledConfig) *pn.Processor {
 	t.Helper()
 	p := pn.New(nil)
-	if err := p.InitializeForTest(processor.Config{RuleCfg: cfg}, kv, nc); err != nil {
+	if err := p.InitializeForTest(processor.Config{RuleCfg: cfg, State: kv}, nc); err != nil {
 		t.Fatalf("processor.InitializeForTest: %v", err)
 	}
 	return p
@@ -179,7 +163,7 @@ func TestDeparture_PublishesNotification(t *testing.T) {
 		State     string    `json:"state"`
 		UpdatedAt time.Time `json:"updated_at"`
 	}{"home", time.Now().UTC()})
-	kv.data["person.wife"] = state
+	_, _ = kv.Put("person.wife", state)
 
 	p := newTestProcessor(t, kv, nc, minimalConfig())
 
@@ -207,7 +191,7 @@ func TestSameState_NoNotification(t *testing.T) {
 		State     string    `json:"state"`
 		UpdatedAt time.Time `json:"updated_at"`
 	}{"home", time.Now().UTC()})
-	kv.data["person.wife"] = state
+	_, _ = kv.Put("person.wife", state)
 
 	p := newTestProcessor(t, kv, nc, minimalConfig())
 
@@ -220,6 +204,29 @@ func TestSameState_NoNotification(t *testing.T) {
 	}
 }
 
+// TestAttributeOnlyUpdate_NoNotification verifies an HA event whose previous state
+// has the same state is no transition, even for an entity never seen before.
+func TestAttributeOnlyUpdate_NoNotification(t *testing.T) {
+	kv := newStubKV()
+	nc := &stubNC{}
+	p := newTestProcessor(t, kv, nc, minimalConfig())
+
+	var evt schemas.CloudEvent
+	_ = json.Unmarshal(stateEvent("person.wife", "home"), &evt)
+	evt.Data[schemas.DataOldState] = map[string]any{"state": "home"}
+	data, _ := json.Marshal(evt)
+
+	if err := p.ProcessEvent(context.Background(), "ha.events.person.wife", data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nc.msgs) != 0 {
+		t.Errorf("expected 0 notifications for an attribute-only update, got %d", len(nc.msgs))
+	}
+	if _, err := kv.Get("person.wife"); err == nil {
+		t.Error("state persisted for an event that was no transition")
+	}
+}
+
 func TestUnwatchedEntity_NoNotification(t *testing.T) {
 	kv := newStubKV()
 	nc := &stubNC{}
@@ -306,3 +313,28 @@ func TestCorrelationID_PropagatedToCommand(t *testing.T) {
 		t.Errorf("causationID = %q, want %q", cmd.CausationID, "cause-id")
 	}
 }
+
+func TestHandles_MatchesOnlyPresenceNotifyRules(t *testing.T) {
+	rs := minimalConfig().Rules
+	if !pn.Handles(rs[0]) {
+		t.Errorf("Handles(%q) = false, want true", rs[0].Name)
+	}
+
+	other := rs[0]
+	other.Trigger.Type = "binary_sensor"
+	if pn.Handles(other) {
+		t.Error("Handles should reject an ha trigger that is not person/device_tracker")
+	}
+
+	noCond := rs[0]
+	noCond.Conditions = nil
+	if pn.Handles(noCond) {
+		t.Error("Handles should reject a rule without a state_transition condition")
+	}
+
+	extra := rs[0]
+	extra.Conditions = append(extra.Conditions, schemas.Condition{Type: schemas.ConditionTypeTimeOfDay, After: "22:00"})
+	if pn.Handles(extra) {
+		t.Error("Handles should leave rules with further conditions to the rules processor")
+	}
+}
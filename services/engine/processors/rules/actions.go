@@ -0,0 +1,269 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// eventSubjectPrefix prefixes subjects written by the publish action.
+const eventSubjectPrefix = "ruby_engine.events."
+
+// varKeyPrefix namespaces kv_set keys in the rules bucket so they cannot
+// collide with the per-entity state keys ("{source}.{type}.{id}").
+const varKeyPrefix = "vars."
+
+// delaySchedulePrefix starts the ids of the schedules that resume the actions
+// after a delay; schedule triggers may not use it.
+const delaySchedulePrefix = "delay_"
+
+// delaySubjectPrefix prefixes the subjects on which delay schedules fire.
+const delaySubjectPrefix = schemas.ScheduleSubjectPrefix + "rules." + delaySchedulePrefix
+
+// actionNamespace derives the ids of the commands and events an action emits.
+var actionNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("ruby_engine/rules"))
+
+// actionID derives the id of what the action at path emits when rule fires for
+// cause. A redelivered event re-runs the actions that ran before the failure;
+// their commands keep their ids, so the stream drops them as duplicates
+// (Nats-Msg-Id) instead of acting twice.
+func actionID(cause schemas.CloudEvent, rule, path string) string {
+	return uuid.NewSHA1(actionNamespace, []byte(cause.ID+"/"+rule+"/"+path)).String()
+}
+
+// runActions executes actions in order on behalf of rule. A failing action
+// abandons the rest of the list unless its on_error is "continue". A delay
+// hands the remainder of its list to a schedule and returns immediately, so a
+// delay inside a sequence postpones only that sequence's later steps. at is
+// when the list started: the triggering event's time, or the time the delay
+// before it came due.
+//
+// actions[i] is addressed as prefix followed by first+i ("2.0" is the first
+// step of the rule's third action), which keeps the addresses of the actions
+// after a delay stable.
+func (p *Processor) runActions(ctx context.Context, rule string, cause schemas.CloudEvent, at time.Time, prefix string, first int, actions []schemas.Action) error {
+	for i, a := range actions {
+		path := prefix + strconv.Itoa(first+i)
+		var err error
+		switch a.Type {
+		case schemas.ActionTypeNotify:
+			err = p.notify(ctx, rule, cause, path, a)
+		case schemas.ActionTypeHAService:
+			err = p.haService(ctx, rule, cause, path, a)
+		case schemas.ActionTypePublish:
+			err = p.publish(ctx, rule, cause, path, a)
+		case schemas.ActionTypeKVSet:
+			err = p.kvSet(ctx, rule, cause, path, a)
+		case schemas.ActionTypeSequence:
+			err = p.runActions(ctx, rule, cause, at, path+".", 0, a.Steps)
+		case schemas.ActionTypeDelay:
+			err = p.delay(ctx, rule, cause, at, path, a, len(actions)-i-1)
+			if err == nil {
+				return nil // the remaining actions run when the delay elapses
+			}
+		default:
+			err = fmt.Errorf("unsupported action type %q", a.Type)
+		}
+		if err == nil {
+			continue
+		}
+		if a.OnError == schemas.OnErrorContinue {
+			p.log.Warn("rules: action failed, continuing",
+				slog.String("rule", rule),
+				slog.String("action", path),
+				slog.String("type", a.Type),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		return fmt.Errorf("action %s (%s): %w", path, a.Type, err)
+	}
+	return nil
+}
+
+func (p *Processor) notify(ctx context.Context, rule string, cause schemas.CloudEvent, path string, a schemas.Action) error {
+	return p.emit(ctx, actionID(cause, rule, path), schemas.ActionTypeNotify, schemas.CommandTypeNotify, cause, map[string]any{
+		"rule":    rule,
+		"title":   a.Params["title"],
+		"message": a.Params["message"],
+		"device":  a.Params["device"],
+	})
+}
+
+func (p *Processor) haService(ctx context.Context, rule string, cause schemas.CloudEvent, path string, a schemas.Action) error {
+	domain, service, _ := strings.Cut(a.Params["service"], ".")
+	data := map[string]any{
+		"rule":         rule,
+		"domain":       domain,
+		"service":      service,
+		"service_data": a.Data,
+	}
+	if id := a.Params["entity_id"]; id != "" {
+		data["entity_id"] = id
+	}
+	return p.emit(ctx, actionID(cause, rule, path), schemas.ActionTypeHAService, schemas.CommandTypeHAService, cause, data)
+}
+
+// publish writes the rule-defined CloudEvent to ruby_engine.events.{subject}
+// and then records the command.
+func (p *Processor) publish(ctx context.Context, rule string, cause schemas.CloudEvent, path string, a schemas.Action) error {
+	evt := schemas.NewCommand(actionID(cause, rule, path+"/event"), a.Params["type"], cause, a.Data)
+	subj := eventSubjectPrefix + a.Params["subject"]
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if err := natsx.PublishWithContext(ctx, p.events, subj, b); err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+	return p.emit(ctx, actionID(cause, rule, path), schemas.ActionTypePublish, schemas.CommandTypePublish, cause, map[string]any{
+		"rule":     rule,
+		"subject":  subj,
+		"event_id": evt.ID,
+	})
+}
+
+// kvSet writes the value to vars.{key} in the rules bucket and then records
+// the command.
+func (p *Processor) kvSet(ctx context.Context, rule string, cause schemas.CloudEvent, path string, a schemas.Action) error {
+	key := varKeyPrefix + a.Params["key"]
+	if _, err := p.store.Put(key, []byte(a.Params["value"])); err != nil {
+		return fmt.Errorf("put %q: %w", key, err)
+	}
+	return p.emit(ctx, actionID(cause, rule, path), schemas.ActionTypeKVSet, schemas.CommandTypeKVSet, cause, map[string]any{
+		"rule":   rule,
+		"bucket": natsx.KVBucketRules,
+		"key":    key,
+		"value":  a.Params["value"],
+	})
+}
+
+// delay records the command and registers a one-shot schedule that resumes
+// the remaining actions of its list when the duration after at has elapsed
+// (resumeDelay). The schedule id and fire time derive from the cause and the
+// delay's path, so a redelivered event registers the same schedule again
+// rather than a second one. Without a scheduler (tests, rules-lint without
+// one) the remaining actions are dropped.
+func (p *Processor) delay(ctx context.Context, rule string, cause schemas.CloudEvent, at time.Time, path string, a schemas.Action, remaining int) error {
+	d, err := time.ParseDuration(a.Params["duration"])
+	if err != nil {
+		return err
+	}
+	if err := p.emit(ctx, actionID(cause, rule, path), schemas.ActionTypeDelay, schemas.CommandTypeDelay, cause, map[string]any{
+		"rule":      rule,
+		"duration":  d.String(),
+		"remaining": remaining,
+	}); err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return nil
+	}
+	if p.sched == nil {
+		p.log.Warn("rules: no scheduler, delayed actions dropped",
+			slog.String("rule", rule),
+			slog.Int("remaining", remaining),
+		)
+		return nil
+	}
+
+	b, err := json.Marshal(cause)
+	if err != nil {
+		return fmt.Errorf("marshal cause: %w", err)
+	}
+	id := delaySchedulePrefix + strings.ReplaceAll(actionID(cause, rule, path), "-", "")
+	return p.sched.At(id, at.Add(d), map[string]any{
+		"rule":  rule,
+		"path":  path,
+		"cause": string(b),
+	})
+}
+
+// resumeDelay runs the actions after the delay whose schedule fired, as the
+// rule now defines them. If the rule was removed, or no longer has a delay at
+// that path, the remaining actions are dropped.
+func (p *Processor) resumeDelay(ctx context.Context, fired schemas.CloudEvent) error {
+	rule, _ := fired.Data["rule"].(string)
+	path, _ := fired.Data["path"].(string)
+	rawCause, _ := fired.Data["cause"].(string)
+	scheduledAt, _ := fired.Data["scheduled_at"].(string)
+
+	var cause schemas.CloudEvent
+	if err := json.Unmarshal([]byte(rawCause), &cause); err != nil {
+		p.log.Warn("rules: malformed delay schedule",
+			slog.String("rule", rule),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, scheduledAt)
+	if err != nil {
+		at = time.Now()
+	}
+
+	actions, prefix, first, ok := p.afterDelay(rule, path)
+	if !ok {
+		p.log.Warn("rules: rule changed, delayed actions dropped",
+			slog.String("rule", rule),
+			slog.String("action", path),
+		)
+		return nil
+	}
+	if err := p.runActions(ctx, rule, cause, at.In(time.Local), prefix, first, actions); err != nil {
+		return fmt.Errorf("rules: rule %q: %w", rule, err)
+	}
+	return nil
+}
+
+// afterDelay returns the actions following the delay at path in the named
+// rule, with their address prefix and first index, or ok=false if the rule
+// has no delay there.
+func (p *Processor) afterDelay(rule, path string) (actions []schemas.Action, prefix string, first int, ok bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, r := range p.rules {
+		if r.rule.Name != rule {
+			continue
+		}
+		list := r.rule.Actions
+		tokens := strings.Split(path, ".")
+		for n, tok := range tokens {
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(list) {
+				return nil, "", 0, false
+			}
+			if n < len(tokens)-1 {
+				list = list[i].Steps
+				continue
+			}
+			if list[i].Type != schemas.ActionTypeDelay {
+				return nil, "", 0, false
+			}
+			return list[i+1:], path[:len(path)-len(tok)], i + 1, true
+		}
+	}
+	return nil, "", 0, false
+}
+
+// emit publishes a command CloudEvent with id on ruby_engine.commands.{action}.{id}.
+func (p *Processor) emit(ctx context.Context, id, action, typ string, cause schemas.CloudEvent, data map[string]any) error {
+	cmd := schemas.NewCommand(id, typ, cause, data)
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return fmt.Errorf("marshal %s command: %w", action, err)
+	}
+	subj := schemas.CommandSubjectPrefix + action + "." + cmd.ID
+	if err := natsx.PublishWithContext(ctx, p.nc, subj, b); err != nil {
+		return fmt.Errorf("publish %s command: %w", action, err)
+	}
+	return nil
+}
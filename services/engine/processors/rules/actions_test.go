@@ -0,0 +1,347 @@
+//go:build fast
+
+package rules_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// runRule fires a single condition-less rule with actions once and returns the
+// ProcessEvent error; published messages are recorded on nc.
+func runRule(t *testing.T, kv *stubKV, nc *stubNC, actions ...schemas.Action) error {
+	t.Helper()
+	p := newTestProcessor(t, kv, nc, nil, schemas.Rule{
+		Name:    "r",
+		Trigger: schemas.Trigger{Source: "ha", Type: "sensor", ID: "x"},
+		Actions: actions,
+	})
+	t.Cleanup(p.Shutdown)
+	if len(p.Subscriptions()) != 1 {
+		t.Fatalf("rule was not compiled: %+v", actions)
+	}
+	return p.ProcessEvent(context.Background(), "ha.events.sensor.x", event("cause", map[string]any{"state": "1"}))
+}
+
+// decode unmarshals a published CloudEvent.
+func decode(t *testing.T, m published) schemas.CloudEvent {
+	t.Helper()
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(m.data, &evt); err != nil {
+		t.Fatalf("unmarshal %s: %v", m.subject, err)
+	}
+	return evt
+}
+
+func TestHAService_EmitsCommand(t *testing.T) {
+	nc := &stubNC{}
+	err := runRule(t, newStubKV(), nc, schemas.Action{
+		Type:   schemas.ActionTypeHAService,
+		Params: map[string]string{"service": "light.turn_on", "entity_id": "light.porch"},
+		Data:   map[string]any{"brightness": 128},
+	})
+	if err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+
+	msgs := nc.snapshot()
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0].subject, "ruby_engine.commands.ha_service.") {
+		t.Fatalf("expected one ha_service command, got %+v", msgs)
+	}
+	cmd := decode(t, msgs[0])
+	if cmd.Type != schemas.CommandTypeHAService {
+		t.Errorf("type = %q, want %q", cmd.Type, schemas.CommandTypeHAService)
+	}
+	if cmd.Data["domain"] != "light" || cmd.Data["service"] != "turn_on" || cmd.Data["entity_id"] != "light.porch" {
+		t.Errorf("unexpected data: %v", cmd.Data)
+	}
+	if cmd.CorrelationID != "corr-cause" || cmd.CausationID != "cause" {
+		t.Errorf("correlation/causation = %q/%q, want corr-cause/cause", cmd.CorrelationID, cmd.CausationID)
+	}
+}
+
+func TestPublish_EmitsEventAndCommand(t *testing.T) {
+	nc := &stubNC{}
+	err := runRule(t, newStubKV(), nc, schemas.Action{
+		Type:   schemas.ActionTypePublish,
+		Params: map[string]string{"subject": "house.night_mode", "type": "house.night_mode.entered"},
+		Data:   map[string]any{"reason": "bedtime"},
+	})
+	if err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+
+	msgs := nc.snapshot()
+	if len(msgs) != 2 {
+		t.Fatalf("expected event + command, got %d messages", len(msgs))
+	}
+	if msgs[0].subject != "ruby_engine.events.house.night_mode" {
+		t.Errorf("event subject = %q", msgs[0].subject)
+	}
+	evt := decode(t, msgs[0])
+	if evt.Type != "house.night_mode.entered" || evt.CausationID != "cause" {
+		t.Errorf("unexpected event: %+v", evt)
+	}
+	cmd := decode(t, msgs[1])
+	if cmd.Type != schemas.CommandTypePublish || cmd.Data["event_id"] != evt.ID {
+		t.Errorf("command does not reference the published event: %+v", cmd)
+	}
+}
+
+func TestKVSet_WritesProcessorOwnedKey(t *testing.T) {
+	kv := newStubKV()
+	nc := &stubNC{}
+	err := runRule(t, kv, nc, schemas.Action{
+		Type:   schemas.ActionTypeKVSet,
+		Params: map[string]string{"key": "night_mode", "value": "on"},
+	})
+	if err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if got := kv.value("vars.night_mode"); got != "on" {
+		t.Errorf("vars.night_mode = %q, want %q", got, "on")
+	}
+	if msgs := nc.snapshot(); len(msgs) != 1 || !strings.HasPrefix(msgs[0].subject, "ruby_engine.commands.kv_set.") {
+		t.Errorf("expected one kv_set command, got %+v", msgs)
+	}
+}
+
+func TestSequence_OnErrorPolicy(t *testing.T) {
+	kv := newStubKV()
+	kv.putErr = errors.New("kv down")
+
+	// on_error: continue — the failing step is skipped and the next runs.
+	nc := &stubNC{}
+	err := runRule(t, kv, nc, schemas.Action{
+		Type: schemas.ActionTypeSequence,
+		Steps: []schemas.Action{
+			{Type: schemas.ActionTypeKVSet, Params: map[string]string{"key": "a", "value": "1"}, OnError: schemas.OnErrorContinue},
+			notifyAction("after"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessEvent with on_error continue: %v", err)
+	}
+	if got := titles(t, nc); len(got) != 1 {
+		t.Errorf("expected the step after the failure to run, got %v", got)
+	}
+
+	// Default (stop) — the failure aborts the rule and is returned for redelivery.
+	nc = &stubNC{}
+	err = runRule(t, kv, nc, schemas.Action{
+		Type: schemas.ActionTypeSequence,
+		Steps: []schemas.Action{
+			{Type: schemas.ActionTypeKVSet, Params: map[string]string{"key": "a", "value": "1"}},
+			notifyAction("after"),
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "kv down") {
+		t.Errorf("expected kv failure to be returned, got %v", err)
+	}
+	if msgs := nc.snapshot(); len(msgs) != 0 {
+		t.Errorf("expected no commands after a stopping failure, got %d", len(msgs))
+	}
+}
+
+// TestRedelivery_FiresWithoutOldState verifies that an event without old_state,
+// redelivered after its first action failed, still sees the entity's previous
+// state and fires its state_transition rule: the state is saved only once the
+// actions have run.
+func TestRedelivery_FiresWithoutOldState(t *testing.T) {
+	kv := newStubKV()
+	nc := &stubNC{seen: map[string]bool{}}
+	p := newTestProcessor(t, kv, nc, nil, schemas.Rule{
+		Name:    "door_opened",
+		Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+		Conditions: []schemas.Condition{
+			{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"},
+		},
+		Actions: []schemas.Action{
+			{Type: schemas.ActionTypeKVSet, Params: map[string]string{"key": "door", "value": "open"}},
+			notifyAction("Door opened"),
+		},
+	})
+	t.Cleanup(p.Shutdown)
+
+	ctx := context.Background()
+	subj := "ha.events.binary_sensor.front_door"
+	if err := p.ProcessEvent(ctx, subj, event("e0", map[string]any{"state": "off"})); err != nil {
+		t.Fatalf("ProcessEvent(off): %v", err)
+	}
+	data := event("e1", map[string]any{"state": "on"})
+	kv.putErr = errors.New("kv down")
+	if err := p.ProcessEvent(ctx, subj, data); err == nil {
+		t.Fatal("expected the kv_set failure to be returned for redelivery")
+	}
+	kv.putErr = nil
+	if err := p.ProcessEvent(ctx, subj, data); err != nil {
+		t.Fatalf("redelivered ProcessEvent: %v", err)
+	}
+
+	if got := kv.value("vars.door"); got != "open" {
+		t.Errorf("vars.door = %q, want open", got)
+	}
+	if got := notifyTitles(t, nc); !slices.Equal(got, []string{"Door opened"}) {
+		t.Errorf("notifications = %v, want [Door opened]", got)
+	}
+}
+
+// TestRedelivery_RepeatedActionsDeduplicated verifies that an event redelivered
+// after its second action failed re-emits the first action's command under the
+// same id, so the stream drops it and the notification is sent once.
+func TestRedelivery_RepeatedActionsDeduplicated(t *testing.T) {
+	kv := newStubKV()
+	kv.putErr = errors.New("kv down")
+	nc := &stubNC{seen: map[string]bool{}}
+	p := newTestProcessor(t, kv, nc, nil, schemas.Rule{
+		Name:    "door_opened",
+		Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+		Conditions: []schemas.Condition{
+			{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"},
+		},
+		Actions: []schemas.Action{
+			notifyAction("Door opened"),
+			{Type: schemas.ActionTypeKVSet, Params: map[string]string{"key": "door", "value": "open"}},
+		},
+	})
+	t.Cleanup(p.Shutdown)
+
+	ctx := context.Background()
+	subj := "ha.events.binary_sensor.front_door"
+	data := event("e1", map[string]any{"state": "on", schemas.DataOldState: map[string]any{"state": "off"}})
+	if err := p.ProcessEvent(ctx, subj, data); err == nil {
+		t.Fatal("expected the kv_set failure to be returned for redelivery")
+	}
+	kv.putErr = nil
+	if err := p.ProcessEvent(ctx, subj, data); err != nil {
+		t.Fatalf("redelivered ProcessEvent: %v", err)
+	}
+
+	var notify, kvSet int
+	for _, m := range nc.snapshot() {
+		switch {
+		case strings.HasPrefix(m.subject, "ruby_engine.commands.notify."):
+			notify++
+		case strings.HasPrefix(m.subject, "ruby_engine.commands.kv_set."):
+			kvSet++
+		}
+	}
+	if notify != 1 || kvSet != 1 {
+		t.Errorf("stored notify/kv_set commands = %d/%d, want 1/1", notify, kvSet)
+	}
+	if got := kv.value("vars.door"); got != "open" {
+		t.Errorf("vars.door = %q, want open", got)
+	}
+}
+
+// TestDelay_ResumesFromSchedule verifies that a delay registers a one-shot
+// schedule, that a redelivery registers the same one, that it survives a
+// reload, and that its firing runs only the remaining steps of its sequence.
+func TestDelay_ResumesFromSchedule(t *testing.T) {
+	nc := &stubNC{seen: map[string]bool{}}
+	schedules := scheduler.New(state.NewMemory(0), nil, nil).For("rules")
+	rule := schemas.Rule{
+		Name:    "r",
+		Trigger: schemas.Trigger{Source: "ha", Type: "sensor", ID: "x"},
+		Actions: []schemas.Action{
+			{Type: schemas.ActionTypeSequence, Steps: []schemas.Action{
+				notifyAction("first"),
+				{Type: schemas.ActionTypeDelay, Params: map[string]string{"duration": "10m"}},
+				notifyAction("second"),
+			}},
+			notifyAction("after sequence"),
+		},
+	}
+	p := rules.New(nil, nil)
+	cfg := processor.Config{RuleCfg: &config.CompiledConfig{Rules: []schemas.Rule{rule}}, State: newStubKV(), Schedules: schedules}
+	if err := p.InitializeForTest(cfg, nc); err != nil {
+		t.Fatalf("InitializeForTest: %v", err)
+	}
+	if subs := p.Subscriptions(); !slices.Contains(subs, "ruby_engine.events.schedule.rules.>") {
+		t.Errorf("Subscriptions() = %v, want the delay schedules", subs)
+	}
+
+	ctx := context.Background()
+	data := event("cause", map[string]any{"state": "1"})
+	for range 2 { // the second pass is a redelivery
+		if err := p.ProcessEvent(ctx, "ha.events.sensor.x", data); err != nil {
+			t.Fatalf("ProcessEvent: %v", err)
+		}
+	}
+	if got := notifyTitles(t, nc); !slices.Equal(got, []string{"first", "after sequence"}) {
+		t.Fatalf("before the delay = %v", got)
+	}
+	if err := p.Reload(cfg.RuleCfg); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	scheds, err := schedules.List()
+	if err != nil || len(scheds) != 1 || !strings.HasPrefix(scheds[0].ID, "delay_") {
+		t.Fatalf("schedules = %+v, %v; want one delay schedule", scheds, err)
+	}
+	var cause schemas.CloudEvent
+	_ = json.Unmarshal(data, &cause)
+	if want := eventTimeOf(t, cause).Add(10 * time.Minute); !scheds[0].Next.Equal(want) {
+		t.Errorf("delay fires at %v, want %v", scheds[0].Next, want)
+	}
+
+	fired, _ := json.Marshal(scheduler.FiredEvent(scheds[0]))
+	if err := p.ProcessEvent(ctx, "ruby_engine.events.schedule.rules."+scheds[0].ID, fired); err != nil {
+		t.Fatalf("ProcessEvent(firing): %v", err)
+	}
+	msgs := nc.snapshot()
+	if last := decode(t, msgs[len(msgs)-1]); last.CausationID != "cause" {
+		t.Errorf("resumed action causation = %q, want the original event", last.CausationID)
+	}
+	if got := notifyTitles(t, nc); !slices.Equal(got, []string{"first", "after sequence", "second"}) {
+		t.Errorf("notifications = %v, want only the second step after the firing", got)
+	}
+}
+
+func TestDelay_DroppedWithoutScheduler(t *testing.T) {
+	nc := &stubNC{}
+	err := runRule(t, newStubKV(), nc,
+		schemas.Action{Type: schemas.ActionTypeDelay, Params: map[string]string{"duration": "1h"}},
+		notifyAction("never"),
+	)
+	if err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if msgs := nc.snapshot(); len(msgs) != 1 || !strings.HasPrefix(msgs[0].subject, "ruby_engine.commands.delay.") {
+		t.Errorf("expected only the delay command, got %+v", msgs)
+	}
+}
+
+// notifyTitles returns the titles of the notify commands published on nc.
+func notifyTitles(t *testing.T, nc *stubNC) []string {
+	t.Helper()
+	var out []string
+	for _, m := range nc.snapshot() {
+		if strings.HasPrefix(m.subject, "ruby_engine.commands.notify.") {
+			out = append(out, fmt.Sprint(decode(t, m).Data["title"]))
+		}
+	}
+	return out
+}
+
+// eventTimeOf parses the time of evt.
+func eventTimeOf(t *testing.T, evt schemas.CloudEvent) time.Time {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, evt.Time)
+	if err != nil {
+		t.Fatalf("event time %q: %v", evt.Time, err)
+	}
+	return at
+}
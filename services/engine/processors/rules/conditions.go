@@ -0,0 +1,358 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// stateForSchedulePrefix starts the ids of the schedules that check a rule
+// when a state_for duration elapses; schedule triggers may not use it.
+const stateForSchedulePrefix = "statefor_"
+
+// stateForSubjectPrefix prefixes the subjects on which state_for schedules fire.
+const stateForSubjectPrefix = schemas.ScheduleSubjectPrefix + "rules." + stateForSchedulePrefix
+
+// condition is a schemas.Condition with its string-encoded fields parsed once
+// at compile time so evaluation never fails.
+type condition struct {
+	schemas.Condition
+	re        *regexp.Regexp
+	dur       time.Duration
+	after     time.Duration
+	before    time.Duration
+	weekdays  [7]bool
+	subs      []condition
+	hasAfter  bool
+	hasBefore bool
+}
+
+// evalContext is everything a condition may inspect: the triggering event,
+// the entity state before it (nil on first sight), the state after it, and
+// the local time the event occurred.
+type evalContext struct {
+	evt  schemas.CloudEvent
+	prev *entityState
+	next *entityState
+	now  time.Time
+}
+
+// compileConditions parses a rule's conditions. The config loader has already
+// validated them (config.ValidateRule); errors here guard the test seam.
+func compileConditions(conds []schemas.Condition) ([]condition, error) {
+	out := make([]condition, 0, len(conds))
+	for i, c := range conds {
+		cc, err := compileCondition(c)
+		if err != nil {
+			return nil, fmt.Errorf("conditions[%d]: %w", i, err)
+		}
+		out = append(out, cc)
+	}
+	return out, nil
+}
+
+func compileCondition(c schemas.Condition) (condition, error) {
+	cc := condition{Condition: c}
+	var err error
+	switch c.Type {
+	case schemas.ConditionTypeStateTransition, schemas.ConditionTypeNumericState:
+	case schemas.ConditionTypeAttribute:
+		if c.Pattern != "" {
+			cc.re, err = regexp.Compile(c.Pattern)
+		}
+	case schemas.ConditionTypeStateFor:
+		cc.dur, err = time.ParseDuration(c.For)
+	case schemas.ConditionTypeTimeOfDay:
+		if c.After != "" {
+			cc.hasAfter = true
+			if cc.after, err = schemas.ParseTimeOfDay(c.After); err != nil {
+				break
+			}
+		}
+		if c.Before != "" {
+			cc.hasBefore = true
+			cc.before, err = schemas.ParseTimeOfDay(c.Before)
+		}
+	case schemas.ConditionTypeDayOfWeek:
+		for _, s := range c.Weekdays {
+			var d time.Weekday
+			if d, err = schemas.ParseWeekday(s); err != nil {
+				break
+			}
+			cc.weekdays[d] = true
+		}
+	case schemas.ConditionTypeAll, schemas.ConditionTypeAny, schemas.ConditionTypeNot:
+		cc.subs, err = compileConditions(c.Conditions)
+	default:
+		err = fmt.Errorf("unsupported condition type %q", c.Type)
+	}
+	return cc, err
+}
+
+// allHold reports whether every condition holds. An empty list holds.
+func allHold(conds []condition, ec *evalContext) bool {
+	for i := range conds {
+		if !conds[i].holds(ec) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *condition) holds(ec *evalContext) bool {
+	switch c.Type {
+	case schemas.ConditionTypeStateTransition:
+		field := c.Field
+		if field == "" {
+			field = "state"
+		}
+		cur, ok := ec.evt.Data[field]
+		if !ok || valueString(cur) != c.Value {
+			return false
+		}
+		// An HA event carrying its previous state says itself whether it is a
+		// transition; the stored state covers events without one (reconciled
+		// events, other sources).
+		if tr := schemas.StateTransition(ec.evt, field); tr.Known {
+			return tr.From == nil || valueString(tr.From) != c.Value
+		}
+		if ec.prev == nil {
+			return true
+		}
+		old, ok := ec.prev.Data[field]
+		return !ok || valueString(old) != c.Value
+
+	case schemas.ConditionTypeNumericState:
+		v, ok := numeric(ec.evt.Data[c.Field])
+		if !ok {
+			return false
+		}
+		return (c.Above == nil || v > *c.Above) && (c.Below == nil || v < *c.Below)
+
+	case schemas.ConditionTypeAttribute:
+		cur, ok := ec.evt.Data[c.Field]
+		if !ok {
+			return false
+		}
+		if c.re != nil {
+			return c.re.MatchString(valueString(cur))
+		}
+		return valueString(cur) == c.Value
+
+	case schemas.ConditionTypeStateFor:
+		cur, ok := ec.evt.Data[c.Field]
+		if !ok || valueString(cur) != c.Value {
+			return false
+		}
+		since, ok := ec.next.Since[c.Field]
+		return ok && ec.now.Sub(since) >= c.dur
+
+	case schemas.ConditionTypeTimeOfDay:
+		// Wall-clock time, not time since midnight, which is an hour off on
+		// DST transition days.
+		h, m, s := ec.now.Clock()
+		tod := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+		switch {
+		case c.hasAfter && c.hasBefore && c.after > c.before:
+			return tod >= c.after || tod < c.before // window wraps midnight
+		default:
+			return (!c.hasAfter || tod >= c.after) && (!c.hasBefore || tod < c.before)
+		}
+
+	case schemas.ConditionTypeDayOfWeek:
+		return c.weekdays[ec.now.Weekday()]
+
+	case schemas.ConditionTypeAll:
+		return allHold(c.subs, ec)
+
+	case schemas.ConditionTypeAny:
+		for i := range c.subs {
+			if c.subs[i].holds(ec) {
+				return true
+			}
+		}
+		return false
+
+	case schemas.ConditionTypeNot:
+		for i := range c.subs {
+			if c.subs[i].holds(ec) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// numeric converts a decoded JSON value to float64. HA reports most sensor
+// states as strings, so numeric strings are accepted; "unavailable" and
+// "unknown" are not numbers and never match.
+func numeric(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	case bool, nil:
+		return 0, false
+	default:
+		f, err := strconv.ParseFloat(fmt.Sprint(t), 64)
+		return f, err == nil
+	}
+}
+
+// nextState derives the state to persist after observing data at now. Since
+// records when each field first took its current value, carried forward from
+// prev while the value is unchanged; this backs state_for. The previous state an
+// HA event carries describes the event, not the entity, and is not persisted.
+func nextState(prev *entityState, data map[string]any, now time.Time) *entityState {
+	if _, ok := data[schemas.DataOldState]; ok {
+		data = maps.Clone(data)
+		delete(data, schemas.DataOldState)
+	}
+	next := &entityState{Data: data, Since: make(map[string]time.Time, len(data)), UpdatedAt: now.UTC()}
+	for field, v := range data {
+		next.Since[field] = now.UTC()
+		if prev == nil {
+			continue
+		}
+		old, ok := prev.Data[field]
+		if !ok || valueString(old) != valueString(v) {
+			continue
+		}
+		if since, ok := prev.Since[field]; ok {
+			next.Since[field] = since
+		} else if !prev.UpdatedAt.IsZero() {
+			next.Since[field] = prev.UpdatedAt
+		}
+	}
+	return next
+}
+
+// eventTime returns the event's time in the engine's local zone (TZ), falling
+// back to the current time when the event carries none or it is unparsable.
+func eventTime(evt schemas.CloudEvent) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, evt.Time); err == nil {
+		return t.In(time.Local)
+	}
+	return time.Now()
+}
+
+// stateForConditions returns the state_for conditions among conds and their
+// subconditions, in order.
+func stateForConditions(conds []condition) []*condition {
+	var out []*condition
+	for i := range conds {
+		if conds[i].Type == schemas.ConditionTypeStateFor {
+			out = append(out, &conds[i])
+		}
+		out = append(out, stateForConditions(conds[i].subs)...)
+	}
+	return out
+}
+
+// stateForID is the id of the schedule that checks rule for the entity under
+// key when its n-th state_for condition's duration elapses.
+func stateForID(rule, key string, n int) string {
+	id := uuid.NewSHA1(actionNamespace, []byte(rule+"/"+key+"/"+strconv.Itoa(n)))
+	return stateForSchedulePrefix + strings.ReplaceAll(id.String(), "-", "")
+}
+
+// armStateFor keeps a one-shot schedule per state_for condition of the matched
+// rules, so a rule is checked when a duration elapses even if no other event
+// arrives (checkStateFor). An event on which the field takes the value
+// registers it, due the duration after the value was first seen; one on which
+// the field leaves the value cancels it. Registering an unchanged schedule is a
+// no-op, so later events and redeliveries keep it. Without a scheduler state_for
+// is checked only when events arrive.
+func (p *Processor) armStateFor(subject, key string, matched []compiledRule, ec *evalContext) error {
+	if p.sched == nil {
+		return nil
+	}
+	for _, r := range matched {
+		for n, c := range r.stateFor {
+			id := stateForID(r.rule.Name, key, n)
+			cur, ok := ec.evt.Data[c.Field]
+			if ok && valueString(cur) == c.Value {
+				due := ec.next.Since[c.Field].Add(c.dur)
+				if !due.After(ec.now) {
+					continue // already elapsed: this event was the check
+				}
+				if err := p.sched.At(id, due, map[string]any{"rule": r.rule.Name, "subject": subject}); err != nil {
+					return err
+				}
+				continue
+			}
+			if ec.prev != nil && valueString(ec.prev.Data[c.Field]) == c.Value {
+				if err := p.sched.Cancel(id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkStateFor evaluates the rule whose state_for schedule fired against the
+// entity's stored state at the time the schedule came due, and runs its
+// actions, on behalf of the firing, if its conditions all hold. A value that
+// changed since the schedule was registered no longer holds; a rule that was
+// removed is skipped.
+func (p *Processor) checkStateFor(ctx context.Context, fired schemas.CloudEvent) error {
+	name, _ := fired.Data["rule"].(string)
+	subject, _ := fired.Data["subject"].(string)
+	scheduledAt, _ := fired.Data["scheduled_at"].(string)
+	at, err := time.Parse(time.RFC3339Nano, scheduledAt)
+	if err != nil {
+		at = time.Now()
+	}
+
+	var rule *compiledRule
+	for _, r := range p.matching(subject) {
+		if r.rule.Name == name {
+			rule = &r
+			break
+		}
+	}
+	if rule == nil {
+		p.log.Info("rules: rule changed, state_for check dropped", slog.String("rule", name))
+		return nil
+	}
+	key := stateKey(subject)
+	st, err := p.loadState(key)
+	if err != nil {
+		return fmt.Errorf("rules: load state %q: %w", key, err)
+	}
+	if st == nil {
+		return nil
+	}
+	ec := &evalContext{evt: schemas.CloudEvent{Data: st.Data}, prev: st, next: st, now: at.In(time.Local)}
+	if !allHold(rule.conds, ec) {
+		return nil
+	}
+	if err := p.runActions(ctx, name, fired, ec.now, "", 0, rule.rule.Actions); err != nil {
+		return fmt.Errorf("rules: rule %q: %w", name, err)
+	}
+	if p.fired != nil {
+		p.fired.Add(ctx, 1, metric.WithAttributes(attribute.String("rule", name)))
+	}
+	p.log.Info("rules: rules fired",
+		slog.String("subject", subject),
+		slog.String("event_id", fired.ID),
+		slog.Any("rules", []string{name}),
+		slog.String("correlationid", fired.CorrelationID),
+	)
+	return nil
+}
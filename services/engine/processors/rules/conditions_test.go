@@ -0,0 +1,267 @@
+//go:build fast
+
+package rules_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+// ev is one event in a fires sequence.
+type ev struct {
+	at   time.Time
+	data map[string]any
+}
+
+// eventAt builds a CloudEvent carrying data with the given event time.
+func eventAt(id string, at time.Time, data map[string]any) []byte {
+	evt := schemas.CloudEvent{
+		SpecVersion:   "1.0",
+		ID:            id,
+		Source:        "ha",
+		Type:          "state_changed",
+		Time:          at.Format(time.RFC3339),
+		CorrelationID: "corr-" + id,
+		Data:          data,
+	}
+	b, _ := json.Marshal(evt)
+	return b
+}
+
+// fires reports whether a single-rule processor with conds fires for each
+// (time, data) event in turn, sharing KV state across events.
+func fires(t *testing.T, conds []schemas.Condition, events ...ev) []bool {
+	t.Helper()
+	nc := &stubNC{}
+	p := newTestProcessor(t, newStubKV(), nc, nil, schemas.Rule{
+		Name:       "r",
+		Trigger:    schemas.Trigger{Source: "ha", Type: "sensor", ID: "x"},
+		Conditions: conds,
+		Actions:    []schemas.Action{notifyAction("fired")},
+	})
+	if len(p.Subscriptions()) != 1 {
+		t.Fatalf("rule was not compiled: %+v", conds)
+	}
+	out := make([]bool, 0, len(events))
+	for i, e := range events {
+		before := len(nc.msgs)
+		if err := p.ProcessEvent(context.Background(), "ha.events.sensor.x", eventAt(fmt.Sprint("e", i), e.at, e.data)); err != nil {
+			t.Fatalf("ProcessEvent #%d: %v", i, err)
+		}
+		out = append(out, len(nc.msgs) > before)
+	}
+	return out
+}
+
+func TestNumericState(t *testing.T) {
+	now := time.Now()
+	got := fires(t, []schemas.Condition{{Type: "numeric_state", Field: "state", Above: ptr(20), Below: ptr(25)}},
+		ev{now, map[string]any{"state": "21.5"}},
+		ev{now, map[string]any{"state": "25"}},
+		ev{now, map[string]any{"state": 22.0}},
+		ev{now, map[string]any{"state": "unavailable"}},
+	)
+	want := []bool{true, false, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAttribute_ValueAndPattern(t *testing.T) {
+	now := time.Now()
+	got := fires(t, []schemas.Condition{
+		{Type: "attribute", Field: "hvac_mode", Pattern: "^(heat|cool)$"},
+		{Type: "attribute", Field: "preset", Value: "away"},
+	},
+		ev{now, map[string]any{"hvac_mode": "heat", "preset": "away"}},
+		ev{now, map[string]any{"hvac_mode": "heat_cool", "preset": "away"}},
+		ev{now, map[string]any{"hvac_mode": "cool", "preset": "home"}},
+	)
+	want := []bool{true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStateFor_UsesStoredSinceTime(t *testing.T) {
+	t0 := time.Now().Add(-time.Hour)
+	got := fires(t, []schemas.Condition{{Type: "state_for", Field: "state", Value: "on", For: "10m"}},
+		ev{t0, map[string]any{"state": "on"}},
+		ev{t0.Add(5 * time.Minute), map[string]any{"state": "on"}},
+		ev{t0.Add(11 * time.Minute), map[string]any{"state": "on"}},
+		ev{t0.Add(12 * time.Minute), map[string]any{"state": "off"}},
+		ev{t0.Add(13 * time.Minute), map[string]any{"state": "on"}},
+		ev{t0.Add(20 * time.Minute), map[string]any{"state": "on"}},
+	)
+	want := []bool{false, false, true, false, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestStateFor_CheckedWhenDurationElapses verifies that a state_for rule fires
+// from its schedule when no second event arrives, that the schedule is
+// cancelled when the value changes, and that a stale firing does not fire.
+func TestStateFor_CheckedWhenDurationElapses(t *testing.T) {
+	nc := &stubNC{}
+	schedules := scheduler.New(state.NewMemory(0), nil, nil).For("rules")
+	rule := schemas.Rule{
+		Name:       "r",
+		Trigger:    schemas.Trigger{Source: "ha", Type: "sensor", ID: "x"},
+		Conditions: []schemas.Condition{{Type: "state_for", Field: "state", Value: "on", For: "10m"}},
+		Actions:    []schemas.Action{notifyAction("fired")},
+	}
+	p := rules.New(nil, nil)
+	cfg := processor.Config{RuleCfg: &config.CompiledConfig{Rules: []schemas.Rule{rule}}, State: newStubKV(), Schedules: schedules}
+	if err := p.InitializeForTest(cfg, nc); err != nil {
+		t.Fatalf("InitializeForTest: %v", err)
+	}
+	if subs := p.Subscriptions(); !slices.Contains(subs, "ruby_engine.events.schedule.rules.>") {
+		t.Errorf("Subscriptions() = %v, want the state_for schedules", subs)
+	}
+
+	ctx := context.Background()
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	process := func(id string, at time.Time, value string) {
+		t.Helper()
+		if err := p.ProcessEvent(ctx, "ha.events.sensor.x", eventAt(id, at, map[string]any{"state": value})); err != nil {
+			t.Fatalf("ProcessEvent(%s): %v", id, err)
+		}
+	}
+	process("e0", t0, "on")
+	process("e1", t0.Add(5*time.Minute), "on") // same value: the schedule is kept
+	scheds, err := schedules.List()
+	if err != nil || len(scheds) != 1 || !strings.HasPrefix(scheds[0].ID, "statefor_") {
+		t.Fatalf("schedules = %+v, %v; want one state_for schedule", scheds, err)
+	}
+	if want := t0.Add(10 * time.Minute); !scheds[0].Next.Equal(want) {
+		t.Errorf("state_for check at %v, want %v", scheds[0].Next, want)
+	}
+	if len(nc.snapshot()) != 0 {
+		t.Fatal("fired before the duration elapsed")
+	}
+
+	fire := func(sched scheduler.Schedule) {
+		t.Helper()
+		fired, _ := json.Marshal(scheduler.FiredEvent(sched))
+		if err := p.ProcessEvent(ctx, "ruby_engine.events.schedule.rules."+sched.ID, fired); err != nil {
+			t.Fatalf("ProcessEvent(firing): %v", err)
+		}
+	}
+	fire(scheds[0])
+	if got := notifyTitles(t, nc); !slices.Equal(got, []string{"fired"}) {
+		t.Fatalf("notifications = %v, want the rule fired by its schedule", got)
+	}
+
+	// Leaving the value cancels the check; a firing already in flight finds the
+	// value changed and does not fire.
+	process("e2", t0.Add(20*time.Minute), "on")
+	process("e3", t0.Add(21*time.Minute), "off")
+	if scheds, err := schedules.List(); err != nil || len(scheds) != 0 {
+		t.Errorf("schedules after the change = %+v, %v; want none", scheds, err)
+	}
+	stale := scheds[0]
+	stale.Next = t0.Add(30 * time.Minute)
+	before := len(nc.snapshot())
+	fire(stale)
+	if got := len(nc.snapshot()); got != before {
+		t.Errorf("a stale firing published %d messages, want none", got-before)
+	}
+}
+
+func TestTimeOfDay_WrapsMidnight(t *testing.T) {
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local)
+	got := fires(t, []schemas.Condition{{Type: "time_of_day", After: "22:00", Before: "06:00"}},
+		ev{day.Add(23 * time.Hour), map[string]any{"state": "1"}},
+		ev{day.Add(5*time.Hour + 59*time.Minute), map[string]any{"state": "1"}},
+		ev{day.Add(6 * time.Hour), map[string]any{"state": "1"}},
+		ev{day.Add(12 * time.Hour), map[string]any{"state": "1"}},
+	)
+	want := []bool{true, true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTimeOfDay_DSTTransitionDays(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	local := time.Local
+	time.Local = ny
+	t.Cleanup(func() { time.Local = local })
+
+	// 2026-03-08 springs forward (23h day), 2026-11-01 falls back (25h day).
+	got := fires(t, []schemas.Condition{{Type: "time_of_day", After: "10:00", Before: "11:00"}},
+		ev{time.Date(2026, 3, 8, 10, 30, 0, 0, ny), map[string]any{"state": "1"}},
+		ev{time.Date(2026, 3, 8, 9, 30, 0, 0, ny), map[string]any{"state": "1"}},
+		ev{time.Date(2026, 11, 1, 10, 30, 0, 0, ny), map[string]any{"state": "1"}},
+		ev{time.Date(2026, 11, 1, 11, 30, 0, 0, ny), map[string]any{"state": "1"}},
+	)
+	want := []bool{true, false, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDayOfWeek(t *testing.T) {
+	sat := time.Date(2026, 3, 7, 12, 0, 0, 0, time.Local) // Saturday
+	got := fires(t, []schemas.Condition{{Type: "day_of_week", Weekdays: []string{"sat", "sun"}}},
+		ev{sat, map[string]any{"state": "1"}},
+		ev{sat.AddDate(0, 0, 2), map[string]any{"state": "1"}},
+	)
+	if !got[0] || got[1] {
+		t.Errorf("fired = %v, want [true false]", got)
+	}
+}
+
+func TestComposition_AnyAllNot(t *testing.T) {
+	now := time.Now()
+	conds := []schemas.Condition{{
+		Type: "any",
+		Conditions: []schemas.Condition{
+			{Type: "attribute", Field: "a", Value: "1"},
+			{Type: "all", Conditions: []schemas.Condition{
+				{Type: "attribute", Field: "b", Value: "1"},
+				{Type: "not", Conditions: []schemas.Condition{{Type: "attribute", Field: "c", Value: "1"}}},
+			}},
+		},
+	}}
+	got := fires(t, conds,
+		ev{now, map[string]any{"a": "1"}},
+		ev{now, map[string]any{"b": "1"}},
+		ev{now, map[string]any{"b": "1", "c": "1"}},
+		ev{now, map[string]any{"c": "1"}},
+	)
+	want := []bool{true, true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: fired = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
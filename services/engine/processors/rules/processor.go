@@ -0,0 +1,450 @@
+// Package rules implements a Logical Processor (ADR-0007) that evaluates every
+// schemas.Rule loaded from RULES_DIR generically: a rule's Trigger becomes a NATS
+// subscription, all of its Conditions must hold for the triggering event, and
+// its Actions are executed in order. Adding an automation is a YAML change
+// rather than a new Go processor.
+//
+// Rules whose shape is owned by a dedicated processor (e.g. presence_notify's
+// person/device_tracker notify rules) are excluded via the claimed predicate
+// passed to New, so no rule is acted on twice.
+//
+// State ownership: this processor owns the "rules" state bucket (ADR-0002), which
+// the host opens for it as Config.State. Each key is the trigger entity
+// "{source}.{type}.{id}"; the value is the JSON-encoded entityState last observed
+// for it, used to detect transitions. Keys under "vars." are written by the
+// kv_set action.
+//
+// A trigger with source "schedule" fires on a cron expression instead of an
+// event: the processor registers each such trigger with the engine scheduler
+// under its id, and cancels schedules whose rules are removed on reload. A
+// delay action registers a one-shot "delay_{hash}" schedule that resumes the
+// actions after it, so pending steps survive an engine restart. Likewise, a
+// state_for condition registers a one-shot "statefor_{hash}" schedule when its
+// field takes the value, so the rule is checked when the duration elapses even
+// if no other event arrives.
+//
+// NATS subjects:
+//
+//	Subscribes: {source}.events.{type}.{id} per rule ({source}.events.{type}.> when id is omitted)
+//	            ruby_engine.events.schedule.rules.{id} per schedule trigger
+//	            ruby_engine.events.schedule.rules.> when a rule has a delay or state_for
+//	Publishes:  ruby_engine.commands.{action}.{eventID} (one command per executed action)
+//	            ruby_engine.events.{subject} (publish action)
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// entityState is persisted to the "rules" state bucket for each trigger entity.
+// Since records, per data field, when the field first took its current value.
+type entityState struct {
+	Data      map[string]any       `json:"data"`
+	Since     map[string]time.Time `json:"since,omitempty"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// compiledRule is a rule accepted by compile together with the subject
+// pattern derived from its trigger and its parsed conditions.
+type compiledRule struct {
+	rule     schemas.Rule
+	subject  string
+	conds    []condition
+	stateFor []*condition // the state_for conditions among conds
+}
+
+// Processor implements processor.Processor for YAML-defined rules.
+type Processor struct {
+	claimed func(schemas.Rule) bool
+	mu      sync.RWMutex // guards rules, which Reload replaces
+	rules   []compiledRule
+	store   state.Store
+	states  state.Typed[entityState] // JSON view of store
+	nc      natsx.MsgPublisher       // commands (ruby_engine.commands.>)
+	events  natsx.MsgPublisher       // publish actions (ruby_engine.events.>), no stream
+	sched   *scheduler.Schedules     // nil disables schedule triggers and delays
+	log     *slog.Logger
+	fired   metric.Int64Counter
+}
+
+// compile-time interface checks
+var (
+	_ processor.Reloader         = (*Processor)(nil)
+	_ processor.StateOwner       = (*Processor)(nil)
+	_ processor.OfflineProcessor = (*Processor)(nil)
+)
+
+// New returns a new Processor. claimed reports rules handled by a dedicated
+// processor; those are skipped. A nil claimed evaluates every rule.
+// Register it with the ProcessorHost before Initialize.
+func New(log *slog.Logger, claimed func(schemas.Rule) bool) *Processor {
+	if log == nil {
+		log = slog.Default()
+	}
+	p := &Processor{claimed: claimed, log: log}
+
+	meter := otel.Meter("github.com/primaryrutabaga/ruby-core/services/engine")
+	fired, err := meter.Int64Counter("ruby_core_rules_fired_total",
+		metric.WithDescription("Rules whose conditions held and whose actions ran, by rule name"),
+	)
+	if err != nil {
+		log.Warn("rules: otel counter unavailable", slog.String("error", err.Error()))
+	}
+	p.fired = fired
+	return p
+}
+
+// StateTTL keeps entity state and vars until they are overwritten.
+func (p *Processor) StateTTL() time.Duration { return 0 }
+
+// Initialize compiles the rules from cfg and adopts the state bucket the host
+// opened for the processor.
+func (p *Processor) Initialize(cfg processor.Config) error {
+	if err := p.InitializeOffline(cfg, cfg.CommandPublisher()); err != nil {
+		return err
+	}
+	p.events = cfg.NC
+	p.log.Info("rules: initialized", slog.Int("rules", len(p.rules)))
+	return nil
+}
+
+// InitializeOffline compiles the rules from cfg against cfg.State and a
+// caller-supplied publisher instead of a NATS connection, for offline
+// evaluation such as the cmd/rules-lint dry run.
+func (p *Processor) InitializeOffline(cfg processor.Config, nc natsx.MsgPublisher) error {
+	if cfg.State == nil {
+		return errors.New("rules: Config.State is nil")
+	}
+	p.store = cfg.State
+	p.states = state.NewTyped[entityState](cfg.State)
+	p.nc, p.events = nc, nc
+	p.sched = cfg.Schedules
+	rules := p.compile(cfg.RuleCfg)
+	if err := p.syncSchedules(rules); err != nil {
+		return err
+	}
+	p.rules = rules
+	return nil
+}
+
+// InitializeForTest is a test seam that injects stub dependencies directly.
+// Only call from tests; do not use in production code.
+func (p *Processor) InitializeForTest(cfg processor.Config, nc natsx.MsgPublisher) error {
+	return p.InitializeOffline(cfg, nc)
+}
+
+// Reload recompiles the rules from a recompiled config. Per-entity state in the
+// KV bucket is kept, so transitions are still measured against the last event
+// seen before the reload. Pending delays resume with the rule as reloaded. If
+// the schedules cannot be updated the previous rules are kept.
+func (p *Processor) Reload(cfg *config.CompiledConfig) error {
+	rules := p.compile(cfg)
+	if err := p.syncSchedules(rules); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.rules = rules
+	p.mu.Unlock()
+	p.log.Info("rules: reloaded", slog.Int("rules", len(rules)))
+	return nil
+}
+
+// Name identifies the processor's consumers and idempotency scope.
+func (p *Processor) Name() string { return "rules" }
+
+// Subscriptions returns the distinct subject patterns derived from the
+// triggers of all compiled rules, plus the processor's schedules when a rule
+// has a delay to resume or a state_for to check.
+func (p *Processor) Subscriptions() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := make(map[string]bool, len(p.rules))
+	var subs []string
+	timers := false
+	for _, r := range p.rules {
+		timers = timers || hasDelay(r.rule.Actions) || len(r.stateFor) > 0
+		if seen[r.subject] {
+			continue
+		}
+		seen[r.subject] = true
+		subs = append(subs, r.subject)
+	}
+	if timers && p.sched != nil {
+		subs = append(subs, p.sched.Subscription())
+	}
+	return subs
+}
+
+// ProcessEvent evaluates every rule whose trigger matches subject against the
+// event and the entity's previously observed state, then runs the actions of
+// each rule whose conditions all hold.
+func (p *Processor) ProcessEvent(ctx context.Context, subject string, data []byte) error {
+	delayed := strings.HasPrefix(subject, delaySubjectPrefix)
+	stateFor := strings.HasPrefix(subject, stateForSubjectPrefix)
+	matched := p.matching(subject)
+	if len(matched) == 0 && !delayed && !stateFor {
+		return nil
+	}
+
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		p.log.Warn("rules: unmarshal event",
+			slog.String("subject", subject),
+			slog.String("error", err.Error()),
+		)
+		return nil // malformed payload: ack and move on, do not NAK
+	}
+	if delayed {
+		return p.resumeDelay(ctx, evt)
+	}
+	if stateFor {
+		return p.checkStateFor(ctx, evt)
+	}
+
+	key := stateKey(subject)
+	prev, err := p.loadState(key)
+	if err != nil {
+		return fmt.Errorf("rules: load state %q: %w", key, err)
+	}
+
+	now := eventTime(evt)
+	ec := &evalContext{evt: evt, prev: prev, next: nextState(prev, evt.Data, now), now: now}
+
+	var fired []schemas.Rule
+	for _, r := range matched {
+		if allHold(r.conds, ec) {
+			fired = append(fired, r.rule)
+		}
+	}
+
+	names := make([]string, 0, len(fired))
+	for _, rule := range fired {
+		if err := p.runActions(ctx, rule.Name, evt, now, "", 0, rule.Actions); err != nil {
+			return fmt.Errorf("rules: rule %q: %w", rule.Name, err)
+		}
+		if p.fired != nil {
+			p.fired.Add(ctx, 1, metric.WithAttributes(attribute.String("rule", rule.Name)))
+		}
+		names = append(names, rule.Name)
+	}
+
+	if err := p.armStateFor(subject, key, matched, ec); err != nil {
+		return fmt.Errorf("rules: state_for schedules %q: %w", key, err)
+	}
+
+	// Persist the observed state only once every action has run: a redelivered
+	// event then sees the same previous state and fires the same rules, even one
+	// without old_state. The actions it repeats keep their command ids
+	// (actionID), so the stream drops them.
+	if err := p.saveState(key, ec.next); err != nil {
+		return fmt.Errorf("rules: save state %q: %w", key, err)
+	}
+	if len(fired) == 0 {
+		return nil
+	}
+
+	p.log.Info("rules: rules fired",
+		slog.String("subject", subject),
+		slog.String("event_id", evt.ID),
+		slog.Any("rules", names),
+		slog.String("correlationid", evt.CorrelationID),
+	)
+	return nil
+}
+
+// Shutdown is a no-op: pending delays are schedules and resume after a
+// restart. The KV bucket and NATS connection are owned by the engine.
+func (p *Processor) Shutdown() {}
+
+// --- internal helpers ---
+
+// compile selects the rules this processor evaluates. Claimed rules are
+// skipped silently; rules with an unusable trigger or that fail validation are
+// skipped with a warning so they are never half-applied. Rules may share a
+// schedule id only if they agree on its cron expression.
+func (p *Processor) compile(cfg *config.CompiledConfig) []compiledRule {
+	if cfg == nil {
+		return nil
+	}
+	var out []compiledRule
+	crons := make(map[string]string) // schedule id → cron
+	for _, rule := range cfg.Rules {
+		if p.claimed != nil && p.claimed(rule) {
+			continue
+		}
+		subject, err := TriggerSubject(rule.Trigger)
+		if err == nil {
+			err = config.ValidateRule(rule)
+		}
+		if err == nil && rule.Trigger.Source == schemas.TriggerSourceSchedule {
+			err = p.checkSchedule(rule.Trigger, crons)
+		}
+		var conds []condition
+		if err == nil {
+			conds, err = compileConditions(rule.Conditions)
+		}
+		if err != nil {
+			p.log.Warn("rules: rule skipped",
+				slog.String("rule", rule.Name),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		out = append(out, compiledRule{rule: rule, subject: subject, conds: conds, stateFor: stateForConditions(conds)})
+	}
+	return out
+}
+
+// checkSchedule reports whether a schedule trigger can be registered: the
+// processor has a scheduler and no earlier rule gave the id another cron.
+func (p *Processor) checkSchedule(t schemas.Trigger, crons map[string]string) error {
+	if p.sched == nil {
+		return errors.New("schedule triggers need the engine scheduler")
+	}
+	if strings.HasPrefix(t.ID, delaySchedulePrefix) {
+		return fmt.Errorf("schedule ids starting with %q are reserved for delays", delaySchedulePrefix)
+	}
+	if strings.HasPrefix(t.ID, stateForSchedulePrefix) {
+		return fmt.Errorf("schedule ids starting with %q are reserved for state_for checks", stateForSchedulePrefix)
+	}
+	if prev, ok := crons[t.ID]; ok && prev != t.Cron {
+		return fmt.Errorf("schedule %q is already defined with cron %q", t.ID, prev)
+	}
+	crons[t.ID] = t.Cron
+	return nil
+}
+
+// syncSchedules registers the schedule of every schedule trigger in rules and
+// cancels the processor's other schedules, except pending delays and state_for
+// checks. Registering an unchanged schedule keeps its pending run.
+func (p *Processor) syncSchedules(rules []compiledRule) error {
+	if p.sched == nil {
+		return nil
+	}
+	want := make(map[string]bool)
+	for _, r := range rules {
+		t := r.rule.Trigger
+		if t.Source != schemas.TriggerSourceSchedule || want[t.ID] {
+			continue
+		}
+		want[t.ID] = true
+		if err := p.sched.Cron(t.ID, t.Cron, nil); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+	}
+	existing, err := p.sched.List()
+	if err != nil {
+		return fmt.Errorf("rules: list schedules: %w", err)
+	}
+	for _, s := range existing {
+		if want[s.ID] || strings.HasPrefix(s.ID, delaySchedulePrefix) || strings.HasPrefix(s.ID, stateForSchedulePrefix) {
+			continue
+		}
+		if err := p.sched.Cancel(s.ID); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+	}
+	return nil
+}
+
+// hasDelay reports whether actions, or the steps of a sequence among them,
+// include a delay.
+func hasDelay(actions []schemas.Action) bool {
+	for _, a := range actions {
+		if a.Type == schemas.ActionTypeDelay || hasDelay(a.Steps) {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the compiled rules whose trigger subject matches subject.
+func (p *Processor) matching(subject string) []compiledRule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var out []compiledRule
+	for _, r := range p.rules {
+		if natsx.MatchSubject(r.subject, subject) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (p *Processor) loadState(key string) (*entityState, error) {
+	st, _, err := p.states.Get(key)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (p *Processor) saveState(key string, st *entityState) error {
+	_, err := p.states.Put(key, *st)
+	return err
+}
+
+// TriggerSubject derives the subscription pattern for a trigger:
+// "{source}.events.{type}.{id}", or "{source}.events.{type}.>" without an id.
+// A schedule trigger fires on "ruby_engine.events.schedule.rules.{id}".
+func TriggerSubject(t schemas.Trigger) (string, error) {
+	if t.Source == schemas.TriggerSourceSchedule {
+		if !natsx.IsValidToken(t.ID) {
+			return "", fmt.Errorf("schedule id %q: %w", t.ID, natsx.ErrInvalidToken)
+		}
+		return schemas.ScheduleSubjectPrefix + "rules." + t.ID, nil
+	}
+	tokens := []string{t.Source, t.Type}
+	if t.ID != "" {
+		tokens = append(tokens, t.ID)
+	}
+	for _, tok := range tokens {
+		if !natsx.IsValidToken(tok) {
+			return "", fmt.Errorf("trigger token %q: %w", tok, natsx.ErrInvalidToken)
+		}
+	}
+	subj := t.Source + ".events." + t.Type
+	if t.ID == "" {
+		return subj + ".>", nil
+	}
+	return subj + "." + t.ID, nil
+}
+
+// stateKey derives the KV key for the entity addressed by subject by dropping
+// the ".events" class token: "ha.events.sensor.temp" → "ha.sensor.temp".
+func stateKey(subject string) string {
+	return strings.Replace(subject, ".events.", ".", 1)
+}
+
+// valueString renders a decoded JSON value for comparison with a YAML string.
+func valueString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
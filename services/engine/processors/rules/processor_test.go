@@ -0,0 +1,392 @@
+//go:build fast
+
+package rules_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// ---------------------------------------------------------------------------
+// Stubs
+// ---------------------------------------------------------------------------
+
+// stubKV is an in-memory state store whose Put can be made to fail.
+type stubKV struct {
+	*state.Memory
+	putErr error // returned by Put for keys under "vars."
+}
+
+func newStubKV() *stubKV { return &stubKV{Memory: state.NewMemory(0)} }
+
+func (s *stubKV) Put(key string, val []byte) (uint64, error) {
+	if s.putErr != nil && strings.HasPrefix(key, "vars.") {
+		return 0, s.putErr
+	}
+	return s.Memory.Put(key, val)
+}
+
+// value returns the stored value of key, or "" if it is absent.
+func (s *stubKV) value(key string) string {
+	e, err := s.Get(key)
+	if err != nil {
+		return ""
+	}
+	return string(e.Value)
+}
+
+type published struct {
+	subject string
+	data    []byte
+}
+
+// stubNC records published messages. It is safe for concurrent use.
+type stubNC struct {
+	mu   sync.Mutex
+	msgs []published
+	seen map[string]bool // non-nil: drop repeated CloudEvent ids, as a stream's duplicate window does
+}
+
+func (s *stubNC) PublishMsg(m *nats.Msg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen != nil {
+		var evt schemas.CloudEvent
+		if err := json.Unmarshal(m.Data, &evt); err == nil {
+			if s.seen[evt.ID] {
+				return nil
+			}
+			s.seen[evt.ID] = true
+		}
+	}
+	s.msgs = append(s.msgs, published{m.Subject, m.Data})
+	return nil
+}
+
+// snapshot returns a copy of the messages published so far.
+func (s *stubNC) snapshot() []published {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.msgs)
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+// event builds a minimal CloudEvent carrying data.
+func event(id string, data map[string]any) []byte {
+	evt := schemas.CloudEvent{
+		SpecVersion:   "1.0",
+		ID:            id,
+		Source:        "ha",
+		Type:          "state_changed",
+		Time:          time.Now().UTC().Format(time.RFC3339),
+		CorrelationID: "corr-" + id,
+		CausationID:   "corr-" + id,
+		Data:          data,
+	}
+	b, _ := json.Marshal(evt)
+	return b
+}
+
+func notifyAction(title string) schemas.Action {
+	return schemas.Action{Type: schemas.ActionTypeNotify, Params: map[string]string{
+		"title":   title,
+		"message": "m",
+		"device":  "mobile_app_phone",
+	}}
+}
+
+func newTestProcessor(t *testing.T, kv *stubKV, nc *stubNC, claimed func(schemas.Rule) bool, rs ...schemas.Rule) *rules.Processor {
+	t.Helper()
+	p := rules.New(nil, claimed)
+	if err := p.InitializeForTest(processor.Config{RuleCfg: &config.CompiledConfig{Rules: rs}, State: kv}, nc); err != nil {
+		t.Fatalf("InitializeForTest: %v", err)
+	}
+	return p
+}
+
+func titles(t *testing.T, nc *stubNC) []string {
+	t.Helper()
+	var out []string
+	for _, m := range nc.snapshot() {
+		if !strings.HasPrefix(m.subject, "ruby_engine.commands.notify.") {
+			t.Errorf("subject %q does not start with ruby_engine.commands.notify.", m.subject)
+		}
+		var cmd schemas.CloudEvent
+		if err := json.Unmarshal(m.data, &cmd); err != nil {
+			t.Fatalf("unmarshal command: %v", err)
+		}
+		out = append(out, fmt.Sprint(cmd.Data["title"]))
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestSubscriptions_DerivedFromTriggers(t *testing.T) {
+	p := newTestProcessor(t, newStubKV(), &stubNC{}, nil,
+		schemas.Rule{Name: "a", Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"}, Actions: []schemas.Action{notifyAction("a")}},
+		schemas.Rule{Name: "b", Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"}, Actions: []schemas.Action{notifyAction("b")}},
+		schemas.Rule{Name: "c", Trigger: schemas.Trigger{Source: "ha", Type: "sensor"}, Actions: []schemas.Action{notifyAction("c")}},
+	)
+
+	got := p.Subscriptions()
+	want := []string{"ha.events.binary_sensor.front_door", "ha.events.sensor.>"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Subscriptions() = %v, want %v", got, want)
+	}
+}
+
+func TestReload_ReplacesRules(t *testing.T) {
+	nc := &stubNC{}
+	p := newTestProcessor(t, newStubKV(), nc, nil,
+		schemas.Rule{Name: "old", Trigger: schemas.Trigger{Source: "ha", Type: "sensor", ID: "a"}, Actions: []schemas.Action{notifyAction("old")}},
+	)
+
+	err := p.Reload(&config.CompiledConfig{Rules: []schemas.Rule{
+		{Name: "new", Trigger: schemas.Trigger{Source: "ha", Type: "sensor", ID: "b"}, Actions: []schemas.Action{notifyAction("new")}},
+	}})
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := p.Subscriptions(); !slices.Equal(got, []string{"ha.events.sensor.b"}) {
+		t.Errorf("Subscriptions() after reload = %v", got)
+	}
+
+	ctx := context.Background()
+	for _, subj := range []string{"ha.events.sensor.a", "ha.events.sensor.b"} {
+		if err := p.ProcessEvent(ctx, subj, event(subj, map[string]any{"state": "1"})); err != nil {
+			t.Fatalf("ProcessEvent %s: %v", subj, err)
+		}
+	}
+	if got := titles(t, nc); !slices.Equal(got, []string{"new"}) {
+		t.Errorf("fired = %v, want only the reloaded rule", got)
+	}
+}
+
+func TestStateTransition_FiresOnceOnChange(t *testing.T) {
+	kv := newStubKV()
+	nc := &stubNC{}
+	p := newTestProcessor(t, kv, nc, nil, schemas.Rule{
+		Name:    "door_opened",
+		Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+		Conditions: []schemas.Condition{
+			{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"},
+		},
+		Actions: []schemas.Action{notifyAction("Door opened")},
+	})
+
+	ctx := context.Background()
+	subj := "ha.events.binary_sensor.front_door"
+	for i, state := range []string{"on", "on", "off", "on"} {
+		if err := p.ProcessEvent(ctx, subj, event(fmt.Sprint("e", i), map[string]any{"state": state})); err != nil {
+			t.Fatalf("ProcessEvent #%d: %v", i, err)
+		}
+	}
+
+	if got := titles(t, nc); len(got) != 2 {
+		t.Errorf("expected 2 notifications (on, off→on), got %d: %v", len(got), got)
+	}
+	if kv.value("ha.binary_sensor.front_door") == "" {
+		t.Error("expected state persisted under ha.binary_sensor.front_door")
+	}
+}
+
+// TestStateTransition_ReadsEventOldState verifies an event carrying its previous
+// state decides the transition itself, over the stored state, and that the
+// previous state is not persisted.
+func TestStateTransition_ReadsEventOldState(t *testing.T) {
+	kv := newStubKV()
+	nc := &stubNC{}
+	p := newTestProcessor(t, kv, nc, nil, schemas.Rule{
+		Name:    "door_opened",
+		Trigger: schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+		Conditions: []schemas.Condition{
+			{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"},
+		},
+		Actions: []schemas.Action{notifyAction("Door opened")},
+	})
+
+	ctx := context.Background()
+	subj := "ha.events.binary_sensor.front_door"
+	for i, data := range []map[string]any{
+		{"state": "on", schemas.DataOldState: map[string]any{"state": "off"}}, // fires
+		{"state": "on", schemas.DataOldState: map[string]any{"state": "off"}}, // fires: the off event was missed
+		{"state": "on", schemas.DataOldState: map[string]any{"state": "on"}},  // attribute-only update
+		{"state": "on", "reconciled": true},                                   // no previous state: stored state is on
+	} {
+		if err := p.ProcessEvent(ctx, subj, event(fmt.Sprint("e", i), data)); err != nil {
+			t.Fatalf("ProcessEvent #%d: %v", i, err)
+		}
+	}
+
+	if got := titles(t, nc); len(got) != 2 {
+		t.Errorf("expected 2 notifications, got %d: %v", len(got), got)
+	}
+	if v := kv.value("ha.binary_sensor.front_door"); strings.Contains(v, schemas.DataOldState) {
+		t.Errorf("persisted state includes %s: %s", schemas.DataOldState, v)
+	}
+}
+
+func TestNoConditions_FiresOnEveryEvent(t *testing.T) {
+	nc := &stubNC{}
+	p := newTestProcessor(t, newStubKV(), nc, nil, schemas.Rule{
+		Name:    "any_sensor",
+		Trigger: schemas.Trigger{Source: "ha", Type: "sensor"},
+		Actions: []schemas.Action{notifyAction("Sensor changed")},
+	})
+
+	ctx := context.Background()
+	_ = p.ProcessEvent(ctx, "ha.events.sensor.temp", event("1", map[string]any{"state": "20"}))
+	_ = p.ProcessEvent(ctx, "ha.events.sensor.humidity", event("2", map[string]any{"state": "40"}))
+	_ = p.ProcessEvent(ctx, "ha.events.light.kitchen", event("3", map[string]any{"state": "on"}))
+
+	if got := titles(t, nc); len(got) != 2 {
+		t.Errorf("expected 2 notifications, got %d: %v", len(got), got)
+	}
+}
+
+func TestClaimedRule_NotEvaluated(t *testing.T) {
+	nc := &stubNC{}
+	claimed := func(r schemas.Rule) bool { return r.Name == "claimed" }
+	p := newTestProcessor(t, newStubKV(), nc, claimed,
+		schemas.Rule{Name: "claimed", Trigger: schemas.Trigger{Source: "ha", Type: "person", ID: "wife"}, Actions: []schemas.Action{notifyAction("claimed")}},
+	)
+
+	if subs := p.Subscriptions(); len(subs) != 0 {
+		t.Errorf("expected no subscriptions for claimed rule, got %v", subs)
+	}
+	_ = p.ProcessEvent(context.Background(), "ha.events.person.wife", event("1", map[string]any{"state": "home"}))
+	if len(nc.msgs) != 0 {
+		t.Errorf("expected 0 notifications for claimed rule, got %d", len(nc.msgs))
+	}
+}
+
+func TestUnsupportedType_RuleSkipped(t *testing.T) {
+	nc := &stubNC{}
+	p := newTestProcessor(t, newStubKV(), nc, nil,
+		schemas.Rule{
+			Name:       "bad_condition",
+			Trigger:    schemas.Trigger{Source: "ha", Type: "sensor", ID: "a"},
+			Conditions: []schemas.Condition{{Type: "bogus"}},
+			Actions:    []schemas.Action{notifyAction("a")},
+		},
+		schemas.Rule{
+			Name:    "bad_action",
+			Trigger: schemas.Trigger{Source: "ha", Type: "sensor", ID: "b"},
+			Actions: []schemas.Action{{Type: "bogus"}},
+		},
+	)
+
+	if subs := p.Subscriptions(); len(subs) != 0 {
+		t.Errorf("expected unsupported rules to be skipped, got subscriptions %v", subs)
+	}
+}
+
+func TestMalformedEvent_AckedNotErrored(t *testing.T) {
+	p := newTestProcessor(t, newStubKV(), &stubNC{}, nil, schemas.Rule{
+		Name:    "any_sensor",
+		Trigger: schemas.Trigger{Source: "ha", Type: "sensor"},
+		Actions: []schemas.Action{notifyAction("x")},
+	})
+
+	if err := p.ProcessEvent(context.Background(), "ha.events.sensor.temp", []byte("not-json")); err != nil {
+		t.Errorf("malformed payload should not return error, got: %v", err)
+	}
+}
+
+func TestCorrelation_PropagatedToCommand(t *testing.T) {
+	nc := &stubNC{}
+	p := newTestProcessor(t, newStubKV(), nc, nil, schemas.Rule{
+		Name:    "any_sensor",
+		Trigger: schemas.Trigger{Source: "ha", Type: "sensor"},
+		Actions: []schemas.Action{notifyAction("x")},
+	})
+
+	if err := p.ProcessEvent(context.Background(), "ha.events.sensor.temp", event("cause", map[string]any{"state": "1"})); err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if len(nc.msgs) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(nc.msgs))
+	}
+	var cmd schemas.CloudEvent
+	if err := json.Unmarshal(nc.msgs[0].data, &cmd); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if cmd.CorrelationID != "corr-cause" || cmd.CausationID != "cause" {
+		t.Errorf("correlation/causation = %q/%q, want corr-cause/cause", cmd.CorrelationID, cmd.CausationID)
+	}
+}
+
+func TestScheduleTrigger_RegisteredAndCancelledOnReload(t *testing.T) {
+	nc := &stubNC{}
+	schedules := scheduler.New(state.NewMemory(0), nil, nil).For("rules")
+	morning := schemas.Rule{
+		Name:    "morning",
+		Trigger: schemas.Trigger{Source: "schedule", ID: "morning", Cron: "0 7 * * *"},
+		Actions: []schemas.Action{notifyAction("Good morning")},
+	}
+	clash := schemas.Rule{
+		Name:    "clash",
+		Trigger: schemas.Trigger{Source: "schedule", ID: "morning", Cron: "0 8 * * *"},
+		Actions: []schemas.Action{notifyAction("clash")},
+	}
+	p := rules.New(nil, nil)
+	cfg := processor.Config{RuleCfg: &config.CompiledConfig{Rules: []schemas.Rule{morning, clash}}, State: newStubKV(), Schedules: schedules}
+	if err := p.InitializeForTest(cfg, nc); err != nil {
+		t.Fatalf("InitializeForTest: %v", err)
+	}
+
+	if got := p.Subscriptions(); !slices.Equal(got, []string{"ruby_engine.events.schedule.rules.morning"}) {
+		t.Errorf("Subscriptions() = %v", got)
+	}
+	scheds, err := schedules.List()
+	if err != nil || len(scheds) != 1 || scheds[0].Cron != "0 7 * * *" {
+		t.Fatalf("schedules = %+v, %v; want morning at 0 7 * * * only", scheds, err)
+	}
+
+	subj := "ruby_engine.events.schedule.rules.morning"
+	if err := p.ProcessEvent(context.Background(), subj, event("fired", map[string]any{"schedule_id": "morning"})); err != nil {
+		t.Fatalf("ProcessEvent: %v", err)
+	}
+	if got := titles(t, nc); !slices.Equal(got, []string{"Good morning"}) {
+		t.Errorf("fired = %v, want the schedule rule only", got)
+	}
+
+	if err := p.Reload(&config.CompiledConfig{}); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if scheds, _ := schedules.List(); len(scheds) != 0 {
+		t.Errorf("schedules after removing the rule = %+v", scheds)
+	}
+}
+
+func TestScheduleTrigger_SkippedWithoutScheduler(t *testing.T) {
+	p := newTestProcessor(t, newStubKV(), &stubNC{}, nil, schemas.Rule{
+		Name:    "morning",
+		Trigger: schemas.Trigger{Source: "schedule", ID: "morning", Cron: "0 7 * * *"},
+		Actions: []schemas.Action{notifyAction("x")},
+	})
+	if subs := p.Subscriptions(); len(subs) != 0 {
+		t.Errorf("expected the schedule rule to be skipped, got subscriptions %v", subs)
+	}
+}
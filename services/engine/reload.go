@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	engineconfig "github.com/primaryrutabaga/ruby-core/services/engine/config"
+)
+
+// configPutter is the subset of nats.KeyValue used to publish compiled config.
+type configPutter interface {
+	Put(key string, value []byte) (uint64, error)
+}
+
+// publishEngineConfig writes the passlist and critical entities derived from
+// cfg to the config KV bucket, where the gateway watches them (ADR-0008/0009).
+func publishEngineConfig(kv configPutter, cfg *engineconfig.CompiledConfig) error {
+	passlistJSON, err := json.Marshal(cfg.Passlist)
+	if err != nil {
+		return fmt.Errorf("marshal passlist: %w", err)
+	}
+	criticalJSON, err := json.Marshal(cfg.CriticalEntities)
+	if err != nil {
+		return fmt.Errorf("marshal critical entities: %w", err)
+	}
+	if _, err := kv.Put(natsx.KVKeyConfigPasslist, passlistJSON); err != nil {
+		return fmt.Errorf("put passlist: %w", err)
+	}
+	if _, err := kv.Put(natsx.KVKeyConfigCriticalEntities, criticalJSON); err != nil {
+		return fmt.Errorf("put critical entities: %w", err)
+	}
+	return nil
+}
+
+// reloadTarget is implemented by ProcessorHost.
+type reloadTarget interface {
+	Reload(cfg *engineconfig.CompiledConfig) error
+}
+
+// detailsRecorder is the subset of audit.Publisher used to audit reloads.
+type detailsRecorder interface {
+	RecordDetails(correlationID, causationID, action, natsSubject, outcome string, details map[string]any)
+}
+
+// configSubjects is the subject space of the config KV bucket a reload writes.
+const configSubjects = "$KV." + natsx.KVBucketConfig + ".>"
+
+// configReloader applies a rule-file change reported by engineconfig.Watch to
+// the running engine: it hands the new rules to the processors and, once they
+// all took them, republishes the gateway config. A failed load, reload or
+// publish leaves the last good config in place. Every outcome is audited as
+// config.reloaded, with the rules directory, the error and the config's counts
+// as details.
+type configReloader struct {
+	dir     string
+	current *engineconfig.CompiledConfig // last config applied to processors and KV
+	kv      configPutter
+	host    reloadTarget
+	audit   detailsRecorder
+	log     *slog.Logger
+	// reloaded, when set, is called after a config has been applied, to follow
+	// it outside the processors (main updates the consumer filters).
+	reloaded func()
+}
+
+// apply is the engineconfig.Watch callback.
+func (r *configReloader) apply(cfg *engineconfig.CompiledConfig, err error) {
+	if err == nil {
+		err = r.host.Reload(cfg)
+		if err == nil {
+			if err = publishEngineConfig(r.kv, cfg); err != nil {
+				err = fmt.Errorf("publish config: %w", err)
+				r.rollback()
+			}
+		}
+	}
+
+	details := map[string]any{"dir": r.dir}
+	if cfg != nil {
+		details["rules"] = len(cfg.Rules)
+		details["critical_entities"] = len(cfg.CriticalEntities)
+		details["passlist_domains"] = len(cfg.Passlist)
+	}
+	if err != nil {
+		r.log.Error("config: reload rejected — keeping last good config",
+			slog.String("dir", r.dir),
+			slog.String("error", err.Error()),
+		)
+		details["error"] = err.Error()
+		r.audit.RecordDetails("", "", "config.reloaded", configSubjects, "failure", details)
+		return
+	}
+
+	r.current = cfg
+	if r.reloaded != nil {
+		r.reloaded()
+	}
+	r.log.Info("config: rules reloaded",
+		slog.String("dir", r.dir),
+		slog.Int("rules", len(cfg.Rules)),
+		slog.Int("critical_entities", len(cfg.CriticalEntities)),
+		slog.Int("passlist_domains", len(cfg.Passlist)),
+	)
+	r.audit.RecordDetails("", "", "config.reloaded", configSubjects, "success", details)
+}
+
+// rollback returns the processors, and the gateway config if it was partly
+// written, to the last good config after a new one reloaded the processors
+// but could not be published.
+func (r *configReloader) rollback() {
+	if r.current == nil {
+		return
+	}
+	if err := r.host.Reload(r.current); err != nil {
+		r.log.Error("config: processor rollback failed", slog.String("error", err.Error()))
+	}
+	if err := publishEngineConfig(r.kv, r.current); err != nil {
+		r.log.Warn("config: gateway config not restored", slog.String("error", err.Error()))
+	}
+}
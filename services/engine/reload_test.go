@@ -0,0 +1,158 @@
+//go:build fast
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+)
+
+type stubConfigKV struct {
+	data   map[string]string
+	putErr error
+}
+
+func (s *stubConfigKV) Put(key string, value []byte) (uint64, error) {
+	if s.putErr != nil {
+		return 0, s.putErr
+	}
+	s.data[key] = string(value)
+	return uint64(len(s.data)), nil
+}
+
+type stubReloadHost struct {
+	got []*config.CompiledConfig // every config Reload received
+	err error
+}
+
+func (s *stubReloadHost) Reload(cfg *config.CompiledConfig) error {
+	s.got = append(s.got, cfg)
+	return s.err
+}
+
+type recordedAudit struct {
+	action, subject, outcome string
+	details                  map[string]any
+}
+
+type stubRecorder struct{ records []recordedAudit }
+
+func (s *stubRecorder) Record(_, _, action, subject, outcome string) {
+	s.RecordDetails("", "", action, subject, outcome, nil)
+}
+
+func (s *stubRecorder) RecordDetails(_, _, action, subject, outcome string, details map[string]any) {
+	s.records = append(s.records, recordedAudit{action, subject, outcome, details})
+}
+
+func newReloader() (*configReloader, *stubConfigKV, *stubReloadHost, *stubRecorder) {
+	kv := &stubConfigKV{data: make(map[string]string)}
+	host := &stubReloadHost{}
+	rec := &stubRecorder{}
+	r := &configReloader{dir: "rules", current: &config.CompiledConfig{}, kv: kv, host: host, audit: rec, log: slog.Default()}
+	return r, kv, host, rec
+}
+
+func TestConfigReloader_AppliesValidConfig(t *testing.T) {
+	r, kv, host, rec := newReloader()
+	cfg := &config.CompiledConfig{
+		Rules:            []schemas.Rule{{Name: "r"}},
+		Passlist:         map[string][]string{"person": {"state"}},
+		CriticalEntities: []string{"person.wife"},
+	}
+
+	r.apply(cfg, nil)
+
+	if kv.data[natsx.KVKeyConfigPasslist] != `{"person":["state"]}` {
+		t.Errorf("passlist = %q", kv.data[natsx.KVKeyConfigPasslist])
+	}
+	if kv.data[natsx.KVKeyConfigCriticalEntities] != `["person.wife"]` {
+		t.Errorf("critical entities = %q", kv.data[natsx.KVKeyConfigCriticalEntities])
+	}
+	if len(host.got) != 1 || host.got[0] != cfg {
+		t.Error("processors were not reloaded with the new config")
+	}
+	if r.current != cfg {
+		t.Error("the applied config did not become the last good one")
+	}
+	if len(rec.records) != 1 {
+		t.Fatalf("audit = %+v, want one record", rec.records)
+	}
+	got := rec.records[0]
+	if got.action != "config.reloaded" || got.outcome != "success" || got.subject != "$KV.config.>" {
+		t.Errorf("audit = %+v, want config.reloaded success on $KV.config.>", got)
+	}
+	if got.details["dir"] != "rules" || got.details["rules"] != 1 || got.details["critical_entities"] != 1 {
+		t.Errorf("audit details = %v", got.details)
+	}
+}
+
+func TestConfigReloader_KeepsLastGoodConfigOnError(t *testing.T) {
+	r, kv, host, rec := newReloader()
+
+	r.apply(nil, errors.New("config: parse \"a.yaml\": bad"))
+
+	if len(kv.data) != 0 || len(host.got) != 0 {
+		t.Error("a failed load must not republish or reload")
+	}
+	if len(rec.records) != 1 || rec.records[0].outcome != "failure" || rec.records[0].details["error"] == nil {
+		t.Errorf("audit = %+v, want one config.reloaded failure with its error", rec.records)
+	}
+}
+
+func TestConfigReloader_ProcessorFailureSkipsPublish(t *testing.T) {
+	r, kv, host, rec := newReloader()
+	host.err = errors.New("bad rules")
+
+	r.apply(&config.CompiledConfig{}, nil)
+
+	if len(kv.data) != 0 {
+		t.Error("the gateway config must not be published for rules the processors refused")
+	}
+	if len(rec.records) != 1 || rec.records[0].outcome != "failure" {
+		t.Errorf("audit = %+v, want one failure", rec.records)
+	}
+}
+
+func TestConfigReloader_PublishFailureRollsBackProcessors(t *testing.T) {
+	r, kv, host, rec := newReloader()
+	last := r.current
+	kv.putErr = errors.New("kv down")
+	cfg := &config.CompiledConfig{}
+
+	r.apply(cfg, nil)
+
+	if len(host.got) != 2 || host.got[0] != cfg || host.got[1] != last {
+		t.Error("processors must return to the last good config when it cannot be published")
+	}
+	if r.current != last {
+		t.Error("an unpublished config became the last good one")
+	}
+	if len(rec.records) != 1 || rec.records[0].outcome != "failure" {
+		t.Errorf("audit = %+v, want one failure", rec.records)
+	}
+}
+
+func TestConfigReloader_ReloadedHookOnlyOnSuccess(t *testing.T) {
+	r, _, host, _ := newReloader()
+	calls := 0
+	r.reloaded = func() { calls++ }
+
+	host.err = errors.New("bad rules")
+	r.apply(&config.CompiledConfig{}, nil)
+	r.apply(nil, errors.New("config: parse \"a.yaml\": bad"))
+	if calls != 0 {
+		t.Fatalf("reloaded called %d times for rejected configs, want 0", calls)
+	}
+
+	host.err = nil
+	r.apply(&config.CompiledConfig{}, nil)
+	if calls != 1 {
+		t.Errorf("reloaded called %d times, want 1 after the config applied", calls)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/boot"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	engineconfig "github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/ada"
+	adastore "github.com/primaryrutabaga/ruby-core/services/engine/processors/ada/store"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/presence_notify"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processors/rules"
+	"github.com/primaryrutabaga/ruby-core/services/engine/replay"
+	"github.com/primaryrutabaga/ruby-core/services/engine/scheduler"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+const replayUsage = `usage: engine replay [flags]
+
+Feeds recorded events through the engine's processors and prints what they
+would have done: the messages they would publish, their state and schedule
+writes and their Home Assistant calls. Nothing is published or written outside
+the process, so the output of two builds over the same events can be diffed.
+
+Events come from an NDJSON file (--events) or from a time range of the engine's
+JetStream streams (--from/--to), which needs the engine's NATS environment.
+
+`
+
+// runReplay implements "engine replay" and returns the process exit code.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(fs.Output(), replayUsage)
+		fs.PrintDefaults()
+	}
+	var opts replayOptions
+	fs.StringVar(&opts.events, "events", "", "NDJSON file of recorded events to replay")
+	fs.StringVar(&opts.from, "from", "", "start of the stream range to replay (RFC 3339)")
+	fs.StringVar(&opts.to, "to", "", "end of the stream range to replay (RFC 3339; default now)")
+	fs.StringVar(&opts.streams, "streams", strings.Join(engineStreamNames(), ","), "comma-separated streams to read with --from")
+	fs.StringVar(&opts.record, "record", "", "also write the events read to this NDJSON file, for replaying later with --events")
+	fs.StringVar(&opts.rulesDir, "rules", engineconfig.Dir(), "directory of *.yaml rule files")
+	fs.StringVar(&opts.scratchPG, "scratch-pg", "", "DSN of a throwaway Postgres database; it is migrated and the ada processor is replayed against it")
+	verbose := fs.Bool("v", false, "log processor output to stderr")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (opts.events == "") == (opts.from == "") {
+		_, _ = fmt.Fprintln(os.Stderr, "engine replay: exactly one of --events and --from is required")
+		fs.Usage()
+		return 2
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if *verbose {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	slog.SetDefault(logger)
+
+	if err := opts.run(logger, os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "engine replay: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// replayOptions are the flags of "engine replay".
+type replayOptions struct {
+	events    string
+	from, to  string
+	streams   string
+	record    string
+	rulesDir  string
+	scratchPG string
+}
+
+func (o replayOptions) run(logger *slog.Logger, w io.Writer) error {
+	var events []replay.Event
+	var err error
+	if o.events != "" {
+		events, err = replay.ReadFile(o.events)
+	} else {
+		events, err = readStreamRange(o.from, o.to, o.streams)
+	}
+	if err != nil {
+		return err
+	}
+	if o.record != "" {
+		if err := recordEvents(o.record, events); err != nil {
+			return fmt.Errorf("record: %w", err)
+		}
+	}
+
+	ruleCfg, err := engineconfig.LoadDir(o.rulesDir)
+	if err != nil {
+		return err
+	}
+
+	rec := replay.NewRecorder()
+	var pool *pgxpool.Pool
+	var ha *boot.HAConfig
+	if o.scratchPG != "" {
+		ctx := context.Background()
+		if err := adastore.MigrateUp(ctx, o.scratchPG); err != nil {
+			return fmt.Errorf("scratch postgres: %w", err)
+		}
+		if pool, err = pgxpool.New(ctx, o.scratchPG); err != nil {
+			return fmt.Errorf("scratch postgres: %w", err)
+		}
+		defer pool.Close()
+
+		srv := httptest.NewServer(rec)
+		defer srv.Close()
+		ha = &boot.HAConfig{URL: srv.URL, Token: "replay"}
+	}
+
+	host, err := newReplayHost(logger, ruleCfg, pool, ha, rec)
+	if err != nil {
+		return err
+	}
+	defer host.Shutdown()
+	replayEvents(context.Background(), host, rec, events, w)
+	return nil
+}
+
+// engineStreamNames lists the streams the engine consumes, the default
+// --streams.
+func engineStreamNames() []string {
+	names := make([]string, len(engineStreams))
+	for i, s := range engineStreams {
+		names[i] = s.name
+	}
+	return names
+}
+
+// readStreamRange connects to NATS the way the engine does and reads the
+// events stored in streams between from and to.
+func readStreamRange(fromStr, toStr, streams string) ([]replay.Event, error) {
+	from, err := time.Parse(time.RFC3339, fromStr)
+	if err != nil {
+		return nil, fmt.Errorf("--from: %w", err)
+	}
+	to := time.Now()
+	if toStr != "" {
+		if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+			return nil, fmt.Errorf("--to: %w", err)
+		}
+	}
+	if to.Before(from) {
+		return nil, errors.New("--to is before --from")
+	}
+
+	cfg := boot.LoadConfig("engine")
+	seed, err := boot.FetchNATSSeed(cfg.VaultAddr, cfg.VaultToken, cfg.VaultNKEYPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: fetch NATS seed: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nc, err := boot.BootstrapNATSTLS(ctx, cfg, "ruby-core-engine-replay", seed)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("nats: jetstream context: %w", err)
+	}
+	return replay.ReadStreams(js, strings.Split(streams, ","), from, to)
+}
+
+func recordEvents(path string, events []replay.Event) error {
+	f, err := os.Create(path) //nolint:gosec // path is an operator-supplied CLI argument
+	if err != nil {
+		return err
+	}
+	if err := replay.Write(f, events); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newReplayHost builds a ProcessorHost whose processors publish to rec, keep
+// their state in memory and register schedules that never fire. The ada
+// processor is included only with a pool, and pushes to the Home Assistant at
+// ha. The calendar processor is left out: it needs JetStream for its
+// idempotency bucket.
+func newReplayHost(logger *slog.Logger, ruleCfg *engineconfig.CompiledConfig, pool *pgxpool.Pool, ha *boot.HAConfig, rec *replay.Recorder) (*ProcessorHost, error) {
+	host := NewProcessorHost(logger)
+	host.SetScheduler(scheduler.New(rec.Store(scheduler.Bucket, state.NewMemory(0)), nil, logger))
+	host.Register(presence_notify.New(logger))
+	host.Register(rules.New(logger, presence_notify.Handles))
+
+	if pool != nil {
+		host.Register(ada.New(logger))
+	}
+
+	openState := func(p processor.Processor, ttl time.Duration) (state.Store, error) {
+		return rec.Store(p.Name(), state.NewMemory(ttl)), nil
+	}
+	if err := host.InitializeOffline(ruleCfg, rec, pool, ha, openState); err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+// replayEvents feeds events through host in order and writes the effects
+// recorded for each to w, followed by a summary. Effects of initialization are
+// listed first; events with no effects and no error are omitted.
+func replayEvents(ctx context.Context, host *ProcessorHost, rec *replay.Recorder, events []replay.Event, w io.Writer) {
+	if lines := rec.Take(); len(lines) > 0 {
+		_, _ = fmt.Fprintln(w, "init")
+		writeEffects(w, lines)
+	}
+
+	var nEffects, nWith, nErrs int
+	for i, e := range events {
+		err := host.Process(ctx, e.Subject, e.Data)
+		lines := rec.Take()
+		if len(lines) == 0 && err == nil {
+			continue
+		}
+		nWith++
+		nEffects += len(lines)
+		_, _ = fmt.Fprintf(w, "#%d %s id=%s\n", i+1, e.Subject, eventID(e.Data))
+		writeEffects(w, lines)
+		if err != nil {
+			nErrs++
+			_, _ = fmt.Fprintf(w, "  error: %v\n", err)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "replay: %d event(s), %d with effects, %d effect(s), %d error(s)\n", len(events), nWith, nEffects, nErrs)
+}
+
+func writeEffects(w io.Writer, lines []string) {
+	for _, l := range lines {
+		_, _ = fmt.Fprintf(w, "  %s\n", l)
+	}
+}
+
+// eventID returns the CloudEvent ID of data, or "?" if it has none.
+func eventID(data []byte) string {
+	var evt schemas.CloudEvent
+	if json.Unmarshal(data, &evt) != nil || evt.ID == "" {
+		return "?"
+	}
+	return evt.ID
+}
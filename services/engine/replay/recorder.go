@@ -0,0 +1,156 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// timestampRE matches the RFC 3339 timestamps that Take masks.
+var timestampRE = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// Recorder captures the side effects of processors under replay: messages
+// published through PublishMsg, writes to stores wrapped by Store, and calls
+// to the Home Assistant API it serves as an http.Handler. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	lines   []string
+	ids     []string // IDs of the CloudEvents published since the last Take
+	started time.Time
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{started: time.Now().Truncate(time.Second)}
+}
+
+func (r *Recorder) add(line, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+	if id != "" {
+		r.ids = append(r.ids, id)
+	}
+}
+
+// Take returns the effects recorded since the last call, one line each, in the
+// order they happened. So that two runs over the same events print the same
+// lines, the IDs of published CloudEvents are replaced by <id1>, <id2>, ... in
+// publication order, and timestamps no earlier than the Recorder's creation
+// (taken from the wall clock rather than from the events) by <now>.
+func (r *Recorder) Take() []string {
+	r.mu.Lock()
+	lines, ids := r.lines, r.ids
+	r.lines, r.ids = nil, nil
+	r.mu.Unlock()
+
+	pairs := make([]string, 0, 2*len(ids))
+	for i, id := range ids {
+		pairs = append(pairs, id, fmt.Sprintf("<id%d>", i+1))
+	}
+	masker := strings.NewReplacer(pairs...)
+	for i, line := range lines {
+		line = masker.Replace(line)
+		lines[i] = timestampRE.ReplaceAllStringFunc(line, func(ts string) string {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil && !t.Before(r.started) {
+				return "<now>"
+			}
+			return ts
+		})
+	}
+	return lines
+}
+
+// PublishMsg records m as "publish {subject} {type} {data}", for a CloudEvent in
+// either content mode; a payload that is not a CloudEvent is recorded as is.
+func (r *Recorder) PublishMsg(m *nats.Msg) error {
+	evt, err := schemas.DecodeEvent(m.Header, m.Data)
+	if err != nil || evt.Type == "" {
+		r.add(fmt.Sprintf("publish %s %s", m.Subject, m.Data), "")
+		return nil
+	}
+	data, _ := json.Marshal(evt.Data)
+	r.add(fmt.Sprintf("publish %s %s %s", m.Subject, evt.Type, data), evt.ID)
+	return nil
+}
+
+// Publish records a message published without headers.
+func (r *Recorder) Publish(subject string, data []byte) error {
+	return r.PublishMsg(&nats.Msg{Subject: subject, Data: data})
+}
+
+// ServeHTTP records a Home Assistant API call as "ha {method} {path} {body}"
+// and answers it with an empty JSON object.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.add(strings.TrimSpace(fmt.Sprintf("ha %s %s %s", req.Method, req.URL.Path, body)), "")
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("{}"))
+}
+
+// Store returns st with every successful write recorded as
+// "kv {bucket} {op} {key} {value}".
+func (r *Recorder) Store(bucket string, st state.Store) state.Store {
+	return &recordingStore{Store: st, bucket: bucket, rec: r}
+}
+
+type recordingStore struct {
+	state.Store
+	bucket string
+	rec    *Recorder
+}
+
+func (s *recordingStore) record(op, key string, value []byte) {
+	s.rec.add(strings.TrimSpace(fmt.Sprintf("kv %s %s %s %s", s.bucket, op, key, value)), "")
+}
+
+func (s *recordingStore) Put(key string, value []byte) (uint64, error) {
+	rev, err := s.Store.Put(key, value)
+	if err == nil {
+		s.record("put", key, value)
+	}
+	return rev, err
+}
+
+func (s *recordingStore) Create(key string, value []byte) (uint64, error) {
+	rev, err := s.Store.Create(key, value)
+	if err == nil {
+		s.record("create", key, value)
+	}
+	return rev, err
+}
+
+func (s *recordingStore) Update(key string, value []byte, revision uint64) (uint64, error) {
+	rev, err := s.Store.Update(key, value, revision)
+	if err == nil {
+		s.record("update", key, value)
+	}
+	return rev, err
+}
+
+func (s *recordingStore) Delete(key string) error {
+	err := s.Store.Delete(key)
+	if err == nil {
+		s.record("delete", key, nil)
+	}
+	return err
+}
+
+func (s *recordingStore) CompareAndDelete(key string, revision uint64) error {
+	err := s.Store.CompareAndDelete(key, revision)
+	if err == nil {
+		s.record("delete", key, nil)
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+// Package replay feeds recorded engine input back through processors and
+// records what they would have done, so a processor change can be checked
+// against real traffic before it is deployed. It reads events from a time range
+// of the engine's JetStream streams or from an NDJSON recording, and its
+// Recorder captures the messages, state writes and Home Assistant calls the
+// processors make in place of NATS, the KV buckets and HA.
+//
+// The engine's "replay" command (services/engine/replay.go) wires these to a
+// ProcessorHost; cmd/rules-lint reads the same NDJSON format.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// Event is one recorded message: a CloudEvent and the NATS subject it was
+// published on. Stream, Seq and Time are set for events read from JetStream.
+type Event struct {
+	Subject string
+	Data    []byte
+	Stream  string
+	Seq     uint64
+	Time    time.Time
+}
+
+// envelope is the NDJSON form of an Event that carries its subject.
+type envelope struct {
+	Subject string          `json:"subject"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// ParseLine decodes one NDJSON line: an envelope {"subject": ..., "event": ...},
+// or a bare HA CloudEvent, whose subject is derived from its "subject" (entity
+// ID) as ha.events.{domain}.{name}.
+func ParseLine(line []byte) (Event, error) {
+	var env envelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		return Event{}, err
+	}
+	if env.Event != nil {
+		if env.Subject == "" {
+			return Event{}, errors.New(`envelope has no "subject"`)
+		}
+		return Event{Subject: env.Subject, Data: env.Event}, nil
+	}
+
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(line, &evt); err != nil {
+		return Event{}, err
+	}
+	if evt.Type == schemas.AuditEventType {
+		return Event{}, errors.New("audit record: audit events carry no payload to replay; record the streams with --record instead")
+	}
+	if evt.Source != "ha" || !strings.Contains(evt.Subject, ".") {
+		return Event{}, errors.New(`cannot derive a NATS subject; wrap the event as {"subject": ..., "event": ...}`)
+	}
+	return Event{Subject: "ha.events." + evt.Subject, Data: line}, nil
+}
+
+// Read parses every non-blank NDJSON line in r. Errors name the line.
+func Read(r io.Reader) ([]Event, error) {
+	var out []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		evt, err := ParseLine(sc.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		// The scanner reuses its buffer.
+		evt.Data = append([]byte(nil), evt.Data...)
+		out = append(out, evt)
+	}
+	return out, sc.Err()
+}
+
+// ReadFile reads an NDJSON recording from path.
+func ReadFile(path string) ([]Event, error) {
+	f, err := os.Open(path) //nolint:gosec // path is an operator-supplied CLI argument
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	events, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return events, nil
+}
+
+// Write writes events to w as NDJSON envelopes, the form Read accepts.
+func Write(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(envelope{Subject: e.Subject, Event: e.Data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
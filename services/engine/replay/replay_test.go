@@ -0,0 +1,119 @@
+//go:build fast
+
+package replay
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+func TestParseLine_Forms(t *testing.T) {
+	env, err := ParseLine([]byte(`{"subject":"ruby_presence.events.state.alice","event":{"id":"1"}}`))
+	if err != nil || env.Subject != "ruby_presence.events.state.alice" || string(env.Data) != `{"id":"1"}` {
+		t.Errorf("envelope: got %+v, %v", env, err)
+	}
+
+	bare := `{"id":"2","source":"ha","type":"state_changed","subject":"person.alice","data":{"state":"home"}}`
+	ha, err := ParseLine([]byte(bare))
+	if err != nil || ha.Subject != "ha.events.person.alice" || string(ha.Data) != bare {
+		t.Errorf("bare HA event: got %+v, %v", ha, err)
+	}
+
+	for line, want := range map[string]string{
+		`{"subject":"","event":{}}`: `no "subject"`,
+		`{"id":"3","source":"ruby_engine","type":"dev.rubycore.audit.v1","data":{}}`: "audit events carry no payload",
+		`{"id":"4","source":"ruby_presence","type":"state"}`:                         "cannot derive a NATS subject",
+		`not json`: "invalid character",
+	} {
+		if _, err := ParseLine([]byte(line)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseLine(%s) error = %v, want it to mention %q", line, err, want)
+		}
+	}
+}
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	events := []Event{
+		{Subject: "ha.events.person.alice", Data: []byte(`{"id":"1","source":"ha"}`)},
+		{Subject: "ruby_engine.events.schedule.rules.morning", Data: []byte(`{"id":"2"}`)},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, events); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	buf.WriteString("\n")
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("Read returned %d events, want %d", len(got), len(events))
+	}
+	for i := range events {
+		if got[i].Subject != events[i].Subject || string(got[i].Data) != string(events[i].Data) {
+			t.Errorf("event %d = %s %s, want %s %s", i, got[i].Subject, got[i].Data, events[i].Subject, events[i].Data)
+		}
+	}
+
+	_, err = Read(strings.NewReader("{\"subject\":\"a\",\"event\":{}}\n\n{}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected a line 3 error, got %v", err)
+	}
+}
+
+func TestRecorder_NormalizesIDsAndRunTimes(t *testing.T) {
+	rec := NewRecorder()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_ = rec.Publish("ruby_engine.commands.notify.abc-123", []byte(
+		`{"id":"abc-123","source":"ruby_engine","type":"command.notify","time":"`+now+`","data":{"at":"`+now+`","since":"2020-01-01T00:00:00Z"}}`))
+	_ = rec.Publish("ruby_engine.commands.notify.def-456", []byte(`{"id":"def-456","type":"command.notify","data":{"parent":"abc-123"}}`))
+	_ = rec.Publish("raw", []byte("not a cloudevent"))
+
+	got := rec.Take()
+	want := []string{
+		`publish ruby_engine.commands.notify.<id1> command.notify {"at":"<now>","since":"2020-01-01T00:00:00Z"}`,
+		`publish ruby_engine.commands.notify.<id2> command.notify {"parent":"<id1>"}`,
+		`publish raw not a cloudevent`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Take:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if again := rec.Take(); len(again) != 0 {
+		t.Errorf("second Take returned %v, want nothing", again)
+	}
+}
+
+func TestRecorder_StoreAndHTTP(t *testing.T) {
+	rec := NewRecorder()
+	st := rec.Store("rules", state.NewMemory(0))
+	rev, _ := st.Put("light.kitchen", []byte(`"on"`))
+	_, _ = st.Update("light.kitchen", []byte(`"off"`), rev)
+	if _, err := st.Create("light.kitchen", []byte(`"x"`)); err == nil {
+		t.Error("Create of an existing key succeeded")
+	}
+	_ = st.Delete("light.kitchen")
+
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/api/states/sensor.ada", "application/json", strings.NewReader(`{"state":"1"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	want := []string{
+		`kv rules put light.kitchen "on"`,
+		`kv rules update light.kitchen "off"`,
+		`kv rules delete light.kitchen`,
+		`ha POST /api/states/sensor.ada {"state":"1"}`,
+	}
+	if got := rec.Take(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Take:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,65 @@
+//go:build fast
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/config"
+	"github.com/primaryrutabaga/ruby-core/services/engine/replay"
+)
+
+func TestReplay_ReportsEffectsPerEvent(t *testing.T) {
+	cfg := &config.CompiledConfig{Rules: []schemas.Rule{{
+		Name:       "door_opened",
+		Trigger:    schemas.Trigger{Source: "ha", Type: "binary_sensor", ID: "front_door"},
+		Conditions: []schemas.Condition{{Type: schemas.ConditionTypeStateTransition, Field: "state", Value: "on"}},
+		Actions: []schemas.Action{{Type: schemas.ActionTypeNotify, Params: map[string]string{
+			"title": "Door opened", "message": "m", "device": "d",
+		}}},
+	}}}
+	events, err := replay.Read(strings.NewReader(strings.Join([]string{
+		`{"id":"1","source":"ha","type":"state_changed","subject":"binary_sensor.front_door","data":{"state":"on"}}`,
+		`{"id":"2","source":"ha","type":"state_changed","subject":"binary_sensor.front_door","data":{"state":"on"}}`,
+		`{"id":"3","source":"ha","type":"state_changed","subject":"light.hall","data":{"state":"on"}}`,
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	run := func() string {
+		quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+		rec := replay.NewRecorder()
+		host, err := newReplayHost(quiet, cfg, nil, nil, rec)
+		if err != nil {
+			t.Fatalf("newReplayHost: %v", err)
+		}
+		defer host.Shutdown()
+		var out bytes.Buffer
+		replayEvents(context.Background(), host, rec, events, &out)
+		return out.String()
+	}
+
+	got := run()
+	for _, want := range []string{
+		"#1 ha.events.binary_sensor.front_door id=1\n",
+		`command.notify {"device":"d","message":"m","rule":"door_opened","title":"Door opened"}`,
+		"replay: 3 event(s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "light.hall") {
+		t.Errorf("event no processor acted on was reported:\n%s", got)
+	}
+	if again := run(); again != got {
+		t.Errorf("two runs over the same events differ:\n%s\n---\n%s", got, again)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+)
+
+// routeNode is one token position in the subject trie. Literal tokens and "*"
+// descend to child nodes; a ">" pattern is recorded on the node it follows.
+type routeNode struct {
+	literal map[string]*routeNode
+	star    *routeNode
+	leaf    []int // processors whose pattern ends at this node
+	tail    []int // processors whose pattern continues with ">" here
+}
+
+// subjectRouter maps a subject to the indices of the processors subscribed to
+// it. It is built once from the processors' Subscriptions, so matching walks at
+// most one literal and one "*" branch per token regardless of how many
+// processors or patterns are registered.
+type subjectRouter struct {
+	root routeNode
+}
+
+// newSubjectRouter indexes the Subscriptions of procs by position. Every
+// pattern is validated with natsx.ValidateSubjectPattern; the first malformed
+// one is returned as an error naming its processor.
+func newSubjectRouter(procs []processor.Processor) (*subjectRouter, error) {
+	r := &subjectRouter{}
+	for i, p := range procs {
+		for _, pattern := range p.Subscriptions() {
+			if err := natsx.ValidateSubjectPattern(pattern); err != nil {
+				return nil, fmt.Errorf("processor %q: %w", p.Name(), err)
+			}
+			r.add(pattern, i)
+		}
+	}
+	return r, nil
+}
+
+func (r *subjectRouter) add(pattern string, proc int) {
+	n := &r.root
+	for tok := range strings.SplitSeq(pattern, ".") {
+		switch tok {
+		case ">":
+			n.tail = appendOnce(n.tail, proc)
+			return
+		case "*":
+			if n.star == nil {
+				n.star = &routeNode{}
+			}
+			n = n.star
+		default:
+			next := n.literal[tok]
+			if next == nil {
+				if n.literal == nil {
+					n.literal = make(map[string]*routeNode)
+				}
+				next = &routeNode{}
+				n.literal[tok] = next
+			}
+			n = next
+		}
+	}
+	n.leaf = appendOnce(n.leaf, proc)
+}
+
+// match returns the indices of the processors with at least one pattern
+// matching subject, each once and in registration order.
+func (r *subjectRouter) match(subject string) []int {
+	if r == nil || subject == "" {
+		return nil
+	}
+	out := r.root.collect(subject, nil)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// collect appends the processors matching subject, the part of the original
+// subject still to be consumed below n.
+func (n *routeNode) collect(subject string, out []int) []int {
+	out = append(out, n.tail...)
+	tok, rest, more := strings.Cut(subject, ".")
+	if tok == "" {
+		return out
+	}
+	for _, next := range [2]*routeNode{n.literal[tok], n.star} {
+		switch {
+		case next == nil:
+		case more:
+			out = next.collect(rest, out)
+		default:
+			out = append(out, next.leaf...)
+		}
+	}
+	return out
+}
+
+func appendOnce(s []int, v int) []int {
+	if slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
@@ -0,0 +1,36 @@
+//go:build fast
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/primaryrutabaga/ruby-core/services/engine/processor"
+)
+
+func TestSubjectRouter_Match(t *testing.T) {
+	procs := []processor.Processor{
+		&mockProcessor{subs: []string{"ha.events.person.>", "ha.events.person.wife"}},
+		&mockProcessor{subs: []string{"ha.*.person.*"}},
+		&mockProcessor{subs: []string{">"}},
+		&mockProcessor{subs: []string{"ruby_presence.events.state.>"}},
+	}
+	r, err := newSubjectRouter(procs)
+	if err != nil {
+		t.Fatalf("newSubjectRouter: %v", err)
+	}
+
+	for subject, want := range map[string][]int{
+		"ha.events.person.wife":           {0, 1, 2},
+		"ha.events.person.wife.extra":     {0, 2},
+		"ha.commands.person.wife":         {1, 2},
+		"ha.events.person":                {2},
+		"ruby_presence.events.state.wife": {2, 3},
+		"":                                nil,
+	} {
+		if got := r.match(subject); !slices.Equal(got, want) {
+			t.Errorf("match(%q) = %v, want %v", subject, got, want)
+		}
+	}
+}
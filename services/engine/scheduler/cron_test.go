@@ -0,0 +1,67 @@
+//go:build fast
+
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	// Thursday 2026-10-15 08:59:30 UTC.
+	from := time.Date(2026, 10, 15, 8, 59, 30, 0, time.UTC)
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * mon-fri", time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{"30 7 * * sat,sun", time.Date(2026, 10, 17, 7, 30, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matching day fires (the 20th or a Friday).
+		{"0 6 20 * fri", time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)},
+		{"10/20 * * * *", time.Date(2026, 10, 15, 9, 10, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		cr, err := ParseCron(c.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", c.expr, err)
+			continue
+		}
+		if got := cr.Next(from); !got.Equal(c.want) {
+			t.Errorf("%q: Next = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for expr, want := range map[string]string{
+		"* * * *":      "want 5 fields",
+		"60 * * * *":   "minute",
+		"* * * foo *":  "month",
+		"5-1 * * * *":  "backwards",
+		"*/0 * * * *":  "bad step",
+		"0 0 30 feb *": "never fires",
+	} {
+		_, err := ParseCron(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseCron(%q) error = %v, want it to mention %q", expr, err, want)
+		}
+	}
+}
+
+func TestCron_NextSkipsNonexistentLocalTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	c, _ := ParseCron("30 2 * * *")
+	// 2026-03-08 02:30 does not exist in New York (clocks jump 02:00 → 03:00).
+	got := c.Next(time.Date(2026, 3, 8, 0, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
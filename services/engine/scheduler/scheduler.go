@@ -0,0 +1,274 @@
+// Package scheduler is the engine's durable timer service. Processors and rules
+// register one-shot and cron schedules, which are persisted in the "schedules"
+// KV bucket; the Scheduler watches the bucket and, when a schedule comes due,
+// publishes a schemas.ScheduleEventFired CloudEvent on
+// ruby_engine.events.schedule.{owner}.{id}. The event reaches its processor
+// through the SCHEDULES stream and the processor's own durable consumer, so it
+// is deduplicated, audited, retried and dead-lettered like any other event.
+//
+// Schedules survive restarts: a schedule that came due while the engine was
+// down fires once when it starts (missed cron runs are coalesced), and every
+// firing of a schedule has a deterministic event ID derived from its key and
+// scheduled time. A firing is only recorded in KV after it is published, and
+// before publishing the Scheduler checks the stream for that ID, so a crash
+// between the two neither loses the firing nor publishes it twice.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+const (
+	// Bucket is the KV bucket holding every schedule, keyed "{owner}.{id}".
+	Bucket = "schedules"
+	// Stream is the JetStream stream capturing schedule events.
+	Stream = "SCHEDULES"
+
+	// retryDelay is how long a schedule whose firing failed waits before the
+	// next attempt.
+	retryDelay = 10 * time.Second
+)
+
+// fireNamespace scopes the deterministic IDs of schedule events.
+var fireNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("ruby_engine/scheduler"))
+
+// Schedule is a persisted timer. A schedule without Cron fires once at Next
+// and is then removed; a cron schedule fires at Next and is advanced to the
+// following match.
+type Schedule struct {
+	Owner string         `json:"owner"`
+	ID    string         `json:"id"`
+	Cron  string         `json:"cron,omitempty"`
+	Next  time.Time      `json:"next"`
+	Data  map[string]any `json:"data,omitempty"`
+}
+
+// Key is the schedule's KV key and the tail of its subject.
+func (s Schedule) Key() string { return s.Owner + "." + s.ID }
+
+// Subject is the subject the schedule fires on.
+func (s Schedule) Subject() string { return schemas.ScheduleSubjectPrefix + s.Key() }
+
+// Publisher is the subset of nats.JetStreamContext used to publish firings and
+// to check whether a firing was already published.
+type Publisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+	GetLastMsg(name, subject string, opts ...nats.JSOpt) (*nats.RawStreamMsg, error)
+}
+
+// Scheduler fires the schedules stored in a state.Store.
+type Scheduler struct {
+	store state.Store
+	pub   Publisher
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+// New returns a Scheduler over store, which is normally the Bucket opened
+// with state.Open. pub may be nil when the Scheduler is only used to hand out
+// Schedules (e.g. in tests and offline tools) and Run is never called.
+func New(store state.Store, pub Publisher, log *slog.Logger) *Scheduler {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Scheduler{store: store, pub: pub, log: log, now: time.Now}
+}
+
+// For returns owner's view of the scheduler. owner is a processor name.
+func (s *Scheduler) For(owner string) *Schedules {
+	return &Schedules{owner: owner, schedules: state.NewTyped[Schedule](s.store), now: s.now}
+}
+
+// pending is a schedule known to Run, with the revision it was read at.
+type pending struct {
+	sched    Schedule
+	revision uint64
+	due      time.Time // Next, or later after a failed attempt
+}
+
+// Run fires schedules as they come due until ctx is done. It follows the
+// bucket through a watch, so schedules registered, changed or cancelled by any
+// engine instance take effect immediately.
+func (s *Scheduler) Run(ctx context.Context) error {
+	updates, err := s.store.Watch(ctx, ">")
+	if err != nil {
+		return fmt.Errorf("scheduler: watch: %w", err)
+	}
+	due := make(map[string]*pending)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		s.arm(timer, due)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-updates:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return errors.New("scheduler: watch ended")
+			}
+			s.track(due, e)
+		case <-timer.C:
+			now := s.now()
+			for key, p := range due {
+				if !p.due.After(now) {
+					s.fire(due, key, p, now)
+				}
+			}
+		}
+	}
+}
+
+// track applies a watched bucket change to due.
+func (s *Scheduler) track(due map[string]*pending, e state.Entry) {
+	if e.Deleted {
+		delete(due, e.Key)
+		return
+	}
+	var sched Schedule
+	if err := json.Unmarshal(e.Value, &sched); err != nil {
+		s.log.Warn("scheduler: undecodable schedule ignored",
+			slog.String("key", e.Key),
+			slog.String("error", err.Error()),
+		)
+		delete(due, e.Key)
+		return
+	}
+	due[e.Key] = &pending{sched: sched, revision: e.Revision, due: sched.Next}
+}
+
+// arm resets timer to the earliest due time, or stops it if nothing is due.
+func (s *Scheduler) arm(timer *time.Timer, due map[string]*pending) {
+	var next time.Time
+	for _, p := range due {
+		if next.IsZero() || p.due.Before(next) {
+			next = p.due
+		}
+	}
+	if next.IsZero() {
+		timer.Stop()
+		return
+	}
+	timer.Reset(max(next.Sub(s.now()), 0))
+}
+
+// fire publishes one firing of p and then advances (cron) or removes
+// (one-shot) the schedule with a compare-and-set on the revision it was read
+// at, so a schedule changed meanwhile is left to the watch. A failed publish is
+// retried after retryDelay.
+func (s *Scheduler) fire(due map[string]*pending, key string, p *pending, now time.Time) {
+	log := s.log.With(slog.String("schedule", key), slog.Time("scheduled_at", p.sched.Next))
+	if err := s.publish(p.sched); err != nil {
+		log.Warn("scheduler: fire failed, will retry", slog.String("error", err.Error()))
+		p.due = now.Add(retryDelay)
+		return
+	}
+	delete(due, key)
+
+	if p.sched.Cron == "" {
+		if err := s.store.CompareAndDelete(key, p.revision); err != nil && !errors.Is(err, state.ErrConflict) {
+			// Try again later; the stream check in publish keeps the firing
+			// from repeating.
+			log.Warn("scheduler: remove fired schedule, will retry", slog.String("error", err.Error()))
+			p.due = now.Add(retryDelay)
+			due[key] = p
+			return // the retry logs the firing
+		}
+		log.Info("scheduler: fired")
+		return
+	}
+
+	next, err := nextRun(p.sched.Cron, p.sched.Next, now)
+	if err != nil {
+		log.Error("scheduler: cron schedule stopped", slog.String("error", err.Error()))
+		return
+	}
+	advanced := p.sched
+	advanced.Next = next
+	b, err := json.Marshal(advanced)
+	if err == nil {
+		_, err = s.store.Update(key, b, p.revision)
+	}
+	if err != nil && !errors.Is(err, state.ErrConflict) {
+		// Keep the advanced schedule in memory at the revision read, so the
+		// next run still fires and retries the update.
+		log.Warn("scheduler: advance cron schedule", slog.String("error", err.Error()))
+		due[key] = &pending{sched: advanced, revision: p.revision, due: next}
+	}
+	log.Info("scheduler: fired", slog.Time("next", next))
+}
+
+// nextRun is the first match of expr after both the fired time and now, so
+// runs missed while the engine was down are coalesced into the one just fired.
+func nextRun(expr string, fired, now time.Time) (time.Time, error) {
+	c, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	from := fired
+	if now.After(from) {
+		from = now
+	}
+	return c.Next(from.In(time.Local)), nil
+}
+
+// publish sends the firing event for sched unless the stream already holds it.
+func (s *Scheduler) publish(sched Schedule) error {
+	evt := FiredEvent(sched)
+	last, err := s.pub.GetLastMsg(Stream, sched.Subject())
+	switch {
+	case err == nil && last.Header.Get(nats.MsgIdHdr) == evt.ID:
+		return nil // published before a restart; only the KV update was lost
+	case err != nil && !errors.Is(err, nats.ErrMsgNotFound):
+		return fmt.Errorf("check stream: %w", err)
+	}
+
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(sched.Subject())
+	msg.Header.Set(nats.MsgIdHdr, evt.ID)
+	msg.Data = data
+	if _, err := s.pub.PublishMsg(msg); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// FiredEvent is the CloudEvent published when sched fires at its Next time.
+// Its ID depends only on the schedule's key and Next, so every attempt to
+// publish the same firing carries the same ID.
+func FiredEvent(sched Schedule) schemas.CloudEvent {
+	at := sched.Next.UTC().Format(time.RFC3339Nano)
+	id := uuid.NewSHA1(fireNamespace, []byte(sched.Key()+"@"+at)).String()
+	data := make(map[string]any, len(sched.Data)+2)
+	maps.Copy(data, sched.Data)
+	data["schedule_id"] = sched.ID
+	data["scheduled_at"] = at
+	return schemas.CloudEvent{
+		SpecVersion:   schemas.CloudEventsSpecVersion,
+		ID:            id,
+		Source:        schemas.ScheduleSource,
+		Type:          schemas.ScheduleEventFired,
+		Time:          at,
+		Subject:       sched.Key(),
+		CorrelationID: id,
+		Data:          data,
+	}
+}
@@ -0,0 +1,228 @@
+//go:build fast
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// fakeStream records published messages and answers GetLastMsg from them.
+type fakeStream struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+	fail bool
+}
+
+func (f *fakeStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errors.New("nats: timeout")
+	}
+	f.msgs = append(f.msgs, m)
+	return &nats.PubAck{Stream: Stream}, nil
+}
+
+func (f *fakeStream) GetLastMsg(_, subject string, _ ...nats.JSOpt) (*nats.RawStreamMsg, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.msgs) - 1; i >= 0; i-- {
+		if m := f.msgs[i]; m.Subject == subject {
+			return &nats.RawStreamMsg{Subject: m.Subject, Header: m.Header, Data: m.Data}, nil
+		}
+	}
+	return nil, nats.ErrMsgNotFound
+}
+
+func (f *fakeStream) published() []*nats.Msg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*nats.Msg(nil), f.msgs...)
+}
+
+// run starts s.Run and returns a func that stops it.
+func run(t *testing.T, s *Scheduler) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	return func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_FiresOneShotAndRemovesIt(t *testing.T) {
+	store := state.NewMemory(0)
+	pub := &fakeStream{}
+	s := New(store, pub, nil)
+	ada := s.For("ada")
+
+	at := time.Now().Add(20 * time.Millisecond)
+	if err := ada.At("feeding_alert", at, map[string]any{"last_feeding_time": "2026-10-16T06:00:00Z"}); err != nil {
+		t.Fatalf("At: %v", err)
+	}
+	stop := run(t, s)
+	defer stop()
+
+	waitFor(t, "firing", func() bool { return len(pub.published()) == 1 })
+	waitFor(t, "removal", func() bool {
+		_, err := store.Get("ada.feeding_alert")
+		return errors.Is(err, state.ErrNotFound)
+	})
+
+	m := pub.published()[0]
+	if m.Subject != "ruby_engine.events.schedule.ada.feeding_alert" {
+		t.Errorf("subject = %q", m.Subject)
+	}
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(m.Data, &evt); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if evt.Type != schemas.ScheduleEventFired || evt.Data["schedule_id"] != "feeding_alert" ||
+		evt.Data["last_feeding_time"] != "2026-10-16T06:00:00Z" {
+		t.Errorf("event = %+v", evt)
+	}
+	if got := m.Header.Get(nats.MsgIdHdr); got != evt.ID {
+		t.Errorf("Nats-Msg-Id = %q, want event ID %q", got, evt.ID)
+	}
+}
+
+func TestScheduler_DoesNotRepublishAfterLostUpdate(t *testing.T) {
+	store := state.NewMemory(0)
+	pub := &fakeStream{}
+	s := New(store, pub, nil)
+
+	// The firing was published, but the engine stopped before removing it.
+	sched := Schedule{Owner: "ada", ID: "feeding_alert", Next: time.Now().Add(-time.Minute).UTC()}
+	if err := s.For("ada").At(sched.ID, sched.Next, nil); err != nil {
+		t.Fatalf("At: %v", err)
+	}
+	if err := s.publish(sched); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	stop := run(t, s)
+	defer stop()
+	waitFor(t, "removal", func() bool {
+		_, err := store.Get("ada.feeding_alert")
+		return errors.Is(err, state.ErrNotFound)
+	})
+	if n := len(pub.published()); n != 1 {
+		t.Errorf("published %d messages, want 1", n)
+	}
+}
+
+func TestScheduler_CoalescesMissedCronRuns(t *testing.T) {
+	store := state.NewMemory(0)
+	pub := &fakeStream{}
+	s := New(store, pub, nil)
+
+	// Registered long ago; several runs were missed while the engine was down.
+	missed := time.Now().Add(-3 * time.Hour).Truncate(time.Hour).UTC()
+	b, _ := json.Marshal(Schedule{Owner: "rules", ID: "hourly", Cron: "0 * * * *", Next: missed})
+	if _, err := store.Put("rules.hourly", b); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	stop := run(t, s)
+	defer stop()
+	waitFor(t, "advance", func() bool {
+		scheds, _ := s.For("rules").List()
+		return len(scheds) == 1 && scheds[0].Next.After(time.Now())
+	})
+	if n := len(pub.published()); n != 1 {
+		t.Errorf("published %d messages for the missed runs, want 1", n)
+	}
+}
+
+func TestScheduler_RetriesFailedPublish(t *testing.T) {
+	store := state.NewMemory(0)
+	pub := &fakeStream{fail: true}
+	s := New(store, pub, nil)
+	if err := s.For("ada").At("x", time.Now(), nil); err != nil {
+		t.Fatalf("At: %v", err)
+	}
+
+	stop := run(t, s)
+	defer stop()
+	time.Sleep(30 * time.Millisecond)
+	if _, err := store.Get("ada.x"); err != nil {
+		t.Errorf("schedule removed although its publish failed: %v", err)
+	}
+}
+
+func TestSchedules_ReregisteringKeepsPendingRun(t *testing.T) {
+	store := state.NewMemory(0)
+	rules := New(store, nil, nil).For("rules")
+
+	if err := rules.Cron("morning", "0 7 * * *", nil); err != nil {
+		t.Fatalf("Cron: %v", err)
+	}
+	// Simulate a run that came due while the engine was down.
+	e, _ := store.Get("rules.morning")
+	var sched Schedule
+	_ = json.Unmarshal(e.Value, &sched)
+	sched.Next = time.Now().Add(-time.Hour).UTC()
+	b, _ := json.Marshal(sched)
+	_, _ = store.Put("rules.morning", b)
+
+	if err := rules.Cron("morning", "0 7 * * *", nil); err != nil {
+		t.Fatalf("re-register: %v", err)
+	}
+	scheds, _ := rules.List()
+	if len(scheds) != 1 || !scheds[0].Next.Equal(sched.Next) {
+		t.Errorf("re-registering moved the pending run: %+v", scheds)
+	}
+
+	if err := rules.Cron("morning", "0 8 * * *", nil); err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	scheds, _ = rules.List()
+	if len(scheds) != 1 || !scheds[0].Next.After(time.Now()) {
+		t.Errorf("changed expression kept the old run: %+v", scheds)
+	}
+
+	if err := rules.Cancel("morning"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if scheds, _ := rules.List(); len(scheds) != 0 {
+		t.Errorf("List after Cancel = %+v", scheds)
+	}
+	if err := rules.At("bad id", time.Now(), nil); err == nil {
+		t.Error("At accepted an id that is not a subject token")
+	}
+}
+
+func TestFiredEvent_IDIsDeterministic(t *testing.T) {
+	at := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	a := FiredEvent(Schedule{Owner: "rules", ID: "morning", Next: at})
+	b := FiredEvent(Schedule{Owner: "rules", ID: "morning", Next: at})
+	c := FiredEvent(Schedule{Owner: "rules", ID: "morning", Next: at.Add(24 * time.Hour)})
+	if a.ID != b.ID || a.ID == c.ID {
+		t.Errorf("IDs: same firing %q/%q, next firing %q", a.ID, b.ID, c.ID)
+	}
+}
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+	"github.com/primaryrutabaga/ruby-core/services/engine/state"
+)
+
+// Schedules is one processor's view of the scheduler: every schedule it
+// registers is keyed and fires under the processor's name, on
+// ruby_engine.events.schedule.{owner}.{id}, so the processor must subscribe to
+// Subscription to receive them. Registration is idempotent: registering an
+// unchanged schedule again keeps its pending run, so processors can re-register
+// their schedules on every Initialize or Reload without skipping a run that is
+// due.
+type Schedules struct {
+	owner     string
+	schedules state.Typed[Schedule]
+	now       func() time.Time
+}
+
+// Subscription is the subject pattern on which the owner's schedules fire.
+func (s *Schedules) Subscription() string {
+	return schemas.ScheduleSubjectPrefix + s.owner + ".>"
+}
+
+// At registers a one-shot schedule that fires at t (immediately if t has
+// passed), replacing any schedule with the same id.
+func (s *Schedules) At(id string, t time.Time, data map[string]any) error {
+	return s.register(Schedule{Owner: s.owner, ID: id, Next: t.UTC(), Data: data})
+}
+
+// Cron registers a schedule that fires at every match of expr in the engine's
+// local time zone (TZ), replacing any schedule with the same id.
+func (s *Schedules) Cron(id, expr string, data map[string]any) error {
+	c, err := ParseCron(expr)
+	if err != nil {
+		return fmt.Errorf("scheduler: %s.%s: %w", s.owner, id, err)
+	}
+	next := c.Next(s.now().In(time.Local))
+	return s.register(Schedule{Owner: s.owner, ID: id, Cron: expr, Next: next.UTC(), Data: data})
+}
+
+// Cancel removes the schedule with the given id. Cancelling an absent schedule
+// is not an error.
+func (s *Schedules) Cancel(id string) error {
+	if !natsx.IsValidToken(id) {
+		return fmt.Errorf("scheduler: schedule id %q: %w", id, natsx.ErrInvalidToken)
+	}
+	return s.schedules.Store.Delete(s.owner + "." + id)
+}
+
+// List returns the owner's registered schedules.
+func (s *Schedules) List() ([]Schedule, error) {
+	entries, err := s.schedules.Store.List(s.owner + ".>")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Schedule, 0, len(entries))
+	for _, e := range entries {
+		var sched Schedule
+		if err := json.Unmarshal(e.Value, &sched); err != nil {
+			return nil, fmt.Errorf("scheduler: decode %q: %w", e.Key, err)
+		}
+		out = append(out, sched)
+	}
+	return out, nil
+}
+
+// register writes sched unless an equivalent schedule is already stored: a
+// one-shot at the same time, or a cron schedule with the same expression, with
+// the same data.
+func (s *Schedules) register(sched Schedule) error {
+	if !natsx.IsValidToken(sched.ID) {
+		return fmt.Errorf("scheduler: schedule id %q: %w", sched.ID, natsx.ErrInvalidToken)
+	}
+	if sched.Next.IsZero() {
+		return fmt.Errorf("scheduler: %s: no fire time", sched.Key())
+	}
+	existing, _, err := s.schedules.Get(sched.Key())
+	switch {
+	case err == nil && equivalent(existing, sched):
+		return nil
+	case err != nil && !errors.Is(err, state.ErrNotFound):
+		return fmt.Errorf("scheduler: %s: %w", sched.Key(), err)
+	}
+	if _, err := s.schedules.Put(sched.Key(), sched); err != nil {
+		return fmt.Errorf("scheduler: %s: %w", sched.Key(), err)
+	}
+	return nil
+}
+
+// equivalent reports whether registering b over a would change nothing but a
+// cron schedule's pending run time.
+func equivalent(a, b Schedule) bool {
+	if a.Cron != b.Cron || (a.Cron == "" && !a.Next.Equal(b.Next)) {
+		return false
+	}
+	// Compare data as JSON, the form in which a was stored.
+	ad, aerr := json.Marshal(a.Data)
+	bd, berr := json.Marshal(b.Data)
+	return aerr == nil && berr == nil && bytes.Equal(ad, bd)
+}
@@ -0,0 +1,234 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// ownerPrefix introduces the owner recorded in a state bucket's description.
+const ownerPrefix = "owner: "
+
+// bucketAdmin is the subset of nats.JetStreamContext used to read and stamp
+// the owner of a KV bucket's backing stream.
+type bucketAdmin interface {
+	StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	UpdateStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+}
+
+// Open creates or binds the KV bucket and returns it as owner's Store. A new
+// bucket is created with the given per-key ttl (zero keeps keys until deleted)
+// and stamped with owner; an existing unstamped bucket is claimed for owner.
+// A bucket stamped with a different owner is refused with ErrNotOwner, which
+// enforces the ADR-0002 single-writer rule at runtime.
+//
+// As with every KV bucket, ttl is fixed at creation: binding an existing bucket
+// with a different ttl logs a warning and keeps the bucket's value.
+func Open(js nats.JetStreamContext, bucket, owner string, ttl time.Duration) (Store, error) {
+	kv, err := js.KeyValue(bucket)
+	switch {
+	case err == nil:
+		if err := claim(js, bucket, owner); err != nil {
+			return nil, err
+		}
+		if st, serr := kv.Status(); serr == nil && st.TTL() != ttl {
+			slog.Warn("state: kv bucket TTL differs from configured value — delete+recreate the bucket to apply",
+				slog.String("bucket", bucket),
+				slog.Duration("bucket_ttl", st.TTL()),
+				slog.Duration("configured_ttl", ttl),
+			)
+		}
+	case errors.Is(err, nats.ErrBucketNotFound):
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:      bucket,
+			Description: ownerPrefix + owner,
+			TTL:         ttl,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("state: create %q: %w", bucket, err)
+		}
+	default:
+		return nil, fmt.Errorf("state: bind %q: %w", bucket, err)
+	}
+	return &kvStore{kv: kv}, nil
+}
+
+// OpenReader binds an existing KV bucket read-only, e.g. to read another
+// processor's state or a bucket written by another service.
+func OpenReader(js nats.JetStreamContext, bucket string) (Reader, error) {
+	kv, err := js.KeyValue(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("state: bind %q: %w", bucket, err)
+	}
+	return &kvStore{kv: kv}, nil
+}
+
+// claim checks the owner recorded on bucket, stamping owner if there is none.
+func claim(js bucketAdmin, bucket, owner string) error {
+	stream := "KV_" + bucket
+	info, err := js.StreamInfo(stream)
+	if err != nil {
+		return fmt.Errorf("state: stream info %q: %w", stream, err)
+	}
+	current, stamped := strings.CutPrefix(info.Config.Description, ownerPrefix)
+	if stamped {
+		if current != owner {
+			return fmt.Errorf("%w: %q is owned by %q, not %q", ErrNotOwner, bucket, current, owner)
+		}
+		return nil
+	}
+	cfg := info.Config
+	cfg.Description = ownerPrefix + owner
+	if _, err := js.UpdateStream(&cfg); err != nil {
+		return fmt.Errorf("state: claim %q for %q: %w", bucket, owner, err)
+	}
+	return nil
+}
+
+// kvStore is a Store over a NATS KV bucket.
+type kvStore struct {
+	kv nats.KeyValue
+}
+
+func (s *kvStore) Get(key string) (Entry, error) {
+	if err := checkKey(key); err != nil {
+		return Entry{}, err
+	}
+	e, err := s.kv.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) || errors.Is(err, nats.ErrKeyDeleted) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("state: get %q: %w", key, err)
+	}
+	return Entry{Key: key, Value: e.Value(), Revision: e.Revision()}, nil
+}
+
+func (s *kvStore) Put(key string, value []byte) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Put(key, value)
+	if err != nil {
+		return 0, fmt.Errorf("state: put %q: %w", key, err)
+	}
+	return rev, nil
+}
+
+func (s *kvStore) Create(key string, value []byte) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Create(key, value)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return 0, fmt.Errorf("%w: %q exists", ErrConflict, key)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("state: create %q: %w", key, err)
+	}
+	return rev, nil
+}
+
+func (s *kvStore) Update(key string, value []byte, revision uint64) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	rev, err := s.kv.Update(key, value, revision)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return 0, fmt.Errorf("%w: %q is not at revision %d", ErrConflict, key, revision)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("state: update %q: %w", key, err)
+	}
+	return rev, nil
+}
+
+func (s *kvStore) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := s.kv.Delete(key); err != nil {
+		return fmt.Errorf("state: delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *kvStore) CompareAndDelete(key string, revision uint64) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	err := s.kv.Delete(key, nats.LastRevision(revision))
+	if errors.Is(err, nats.ErrKeyExists) {
+		return fmt.Errorf("%w: %q is not at revision %d", ErrConflict, key, revision)
+	}
+	if err != nil {
+		return fmt.Errorf("state: delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *kvStore) List(keys string) ([]Entry, error) {
+	if err := natsx.ValidateSubjectPattern(keys); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	w, err := s.kv.Watch(keys, nats.IgnoreDeletes())
+	if err != nil {
+		return nil, fmt.Errorf("state: list %q: %w", keys, err)
+	}
+	defer func() { _ = w.Stop() }()
+	var out []Entry
+	for e := range w.Updates() {
+		if e == nil {
+			break // end of initial values
+		}
+		out = append(out, Entry{Key: e.Key(), Value: e.Value(), Revision: e.Revision()})
+	}
+	return out, nil
+}
+
+func (s *kvStore) Watch(ctx context.Context, keys string) (<-chan Entry, error) {
+	if err := natsx.ValidateSubjectPattern(keys); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	w, err := s.kv.Watch(keys, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("state: watch %q: %w", keys, err)
+	}
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		defer func() { _ = w.Stop() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-w.Updates():
+				if !ok {
+					return
+				}
+				if e == nil {
+					continue // end of initial values
+				}
+				entry := Entry{
+					Key:      e.Key(),
+					Value:    e.Value(),
+					Revision: e.Revision(),
+					Deleted:  e.Operation() != nats.KeyValuePut,
+				}
+				select {
+				case out <- entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
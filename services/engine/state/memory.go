@@ -0,0 +1,227 @@
+package state
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// Memory is an in-process Store with the same revision, CAS, TTL and watch
+// semantics as a NATS KV bucket. Revisions increase across all keys, as a
+// bucket's stream sequence does. The zero value is not usable; call NewMemory.
+type Memory struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	rev      uint64
+	entries  map[string]memEntry
+	watchers []*memWatcher
+}
+
+type memEntry struct {
+	value    []byte
+	revision uint64
+	expires  time.Time // zero: never
+}
+
+type memWatcher struct {
+	keys    string
+	pending []Entry
+	signal  chan struct{}
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns an empty Memory whose keys expire ttl after their last
+// write (zero keeps them until deleted).
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{ttl: ttl, entries: make(map[string]memEntry)}
+}
+
+func (m *Memory) Get(key string) (Entry, error) {
+	if err := checkKey(key); err != nil {
+		return Entry{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.live(key)
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return Entry{Key: key, Value: slices.Clone(e.value), Revision: e.revision}, nil
+}
+
+func (m *Memory) Put(key string, value []byte) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.write(key, value), nil
+}
+
+func (m *Memory) Create(key string, value []byte) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.live(key); ok {
+		return 0, fmt.Errorf("%w: %q exists", ErrConflict, key)
+	}
+	return m.write(key, value), nil
+}
+
+func (m *Memory) Update(key string, value []byte, revision uint64) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.live(key)
+	if !ok || e.revision != revision {
+		return 0, fmt.Errorf("%w: %q is not at revision %d", ErrConflict, key, revision)
+	}
+	return m.write(key, value), nil
+}
+
+func (m *Memory) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.live(key); !ok {
+		return nil
+	}
+	delete(m.entries, key)
+	m.rev++
+	m.notify(Entry{Key: key, Revision: m.rev, Deleted: true})
+	return nil
+}
+
+func (m *Memory) CompareAndDelete(key string, revision uint64) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.live(key)
+	if !ok || e.revision != revision {
+		return fmt.Errorf("%w: %q is not at revision %d", ErrConflict, key, revision)
+	}
+	delete(m.entries, key)
+	m.rev++
+	m.notify(Entry{Key: key, Revision: m.rev, Deleted: true})
+	return nil
+}
+
+func (m *Memory) List(keys string) ([]Entry, error) {
+	if err := natsx.ValidateSubjectPattern(keys); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Entry
+	for k := range m.entries {
+		if e, ok := m.live(k); ok && natsx.MatchSubject(keys, k) {
+			out = append(out, Entry{Key: k, Value: slices.Clone(e.value), Revision: e.revision})
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) Watch(ctx context.Context, keys string) (<-chan Entry, error) {
+	if err := natsx.ValidateSubjectPattern(keys); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	w := &memWatcher{keys: keys, signal: make(chan struct{}, 1)}
+
+	m.mu.Lock()
+	var names []string
+	for k := range m.entries {
+		if _, ok := m.live(k); ok && natsx.MatchSubject(keys, k) {
+			names = append(names, k)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Compare(m.entries[a].revision, m.entries[b].revision)
+	})
+	for _, k := range names {
+		e := m.entries[k]
+		w.pending = append(w.pending, Entry{Key: k, Value: slices.Clone(e.value), Revision: e.revision})
+	}
+	m.watchers = append(m.watchers, w)
+	m.mu.Unlock()
+	w.signal <- struct{}{}
+
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		defer m.unwatch(w)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.signal:
+			}
+			m.mu.Lock()
+			batch := w.pending
+			w.pending = nil
+			m.mu.Unlock()
+			for _, e := range batch {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// live returns key's entry if it exists and has not expired. Callers hold mu.
+func (m *Memory) live(key string) (memEntry, bool) {
+	e, ok := m.entries[key]
+	if !ok || (!e.expires.IsZero() && !time.Now().Before(e.expires)) {
+		return memEntry{}, false
+	}
+	return e, true
+}
+
+// write stores value under a new revision and notifies watchers. Callers hold mu.
+func (m *Memory) write(key string, value []byte) uint64 {
+	m.rev++
+	e := memEntry{value: slices.Clone(value), revision: m.rev}
+	if m.ttl > 0 {
+		e.expires = time.Now().Add(m.ttl)
+	}
+	m.entries[key] = e
+	m.notify(Entry{Key: key, Value: slices.Clone(value), Revision: m.rev})
+	return m.rev
+}
+
+// notify queues e for every watcher whose pattern matches. Callers hold mu.
+func (m *Memory) notify(e Entry) {
+	for _, w := range m.watchers {
+		if !natsx.MatchSubject(w.keys, e.Key) {
+			continue
+		}
+		w.pending = append(w.pending, e)
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (m *Memory) unwatch(w *memWatcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchers = slices.DeleteFunc(m.watchers, func(x *memWatcher) bool { return x == w })
+}
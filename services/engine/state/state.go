@@ -0,0 +1,133 @@
+// Package state gives each engine processor a namespaced, durable key-value
+// store backed by its own NATS KV bucket (ADR-0002). The host opens a processor's
+// store before Initialize and passes it as processor.Config.State; the bucket is
+// stamped with its owner so no other processor or service can open it for
+// writing. Other components read it through OpenReader.
+//
+// Memory is an in-process implementation with the same semantics, for unit
+// tests and offline tools that must not touch NATS.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned by Get for a key that is absent, deleted or expired.
+	ErrNotFound = errors.New("state: key not found")
+	// ErrConflict is returned by Create when the key exists and by Update when
+	// the key's current revision is not the expected one.
+	ErrConflict = errors.New("state: revision conflict")
+	// ErrNotOwner is returned by Open when the bucket is owned by someone else.
+	ErrNotOwner = errors.New("state: bucket has another owner")
+	// ErrInvalidKey is returned for keys NATS KV cannot store.
+	ErrInvalidKey = errors.New("state: invalid key")
+)
+
+// Entry is one revision of a key.
+type Entry struct {
+	Key      string
+	Value    []byte
+	Revision uint64
+	// Deleted marks a delete, purge or expiry seen by a watcher.
+	Deleted bool
+}
+
+// Reader is the read side of a processor's state, available to any component.
+type Reader interface {
+	// Get returns the latest revision of key, or ErrNotFound.
+	Get(key string) (Entry, error)
+	// Watch sends the current value of every key matching the NATS wildcard
+	// pattern keys, then each later change, until ctx is done; the channel is
+	// closed when the watch ends.
+	Watch(ctx context.Context, keys string) (<-chan Entry, error)
+	// List returns the latest revision of every key matching the NATS wildcard
+	// pattern keys, in no particular order.
+	List(keys string) ([]Entry, error)
+}
+
+// Store is a processor's own state. Only the owning processor holds one.
+type Store interface {
+	Reader
+	// Put writes value unconditionally and returns the new revision.
+	Put(key string, value []byte) (uint64, error)
+	// Create writes value only if key does not exist, else ErrConflict.
+	Create(key string, value []byte) (uint64, error)
+	// Update writes value only if key is at revision, else ErrConflict.
+	Update(key string, value []byte, revision uint64) (uint64, error)
+	// Delete removes key. Deleting an absent key is not an error.
+	Delete(key string) error
+	// CompareAndDelete removes key only if it is at revision, else ErrConflict.
+	CompareAndDelete(key string, revision uint64) error
+}
+
+// Typed is a Store view that JSON-encodes values of type T.
+type Typed[T any] struct {
+	Store Store
+}
+
+// NewTyped returns a JSON view of s.
+func NewTyped[T any](s Store) Typed[T] {
+	return Typed[T]{Store: s}
+}
+
+// Get decodes the latest value of key and returns it with its revision.
+func (t Typed[T]) Get(key string) (T, uint64, error) {
+	var v T
+	e, err := t.Store.Get(key)
+	if err != nil {
+		return v, 0, err
+	}
+	if err := json.Unmarshal(e.Value, &v); err != nil {
+		return v, 0, fmt.Errorf("state: decode %q: %w", key, err)
+	}
+	return v, e.Revision, nil
+}
+
+// Put encodes and writes v unconditionally.
+func (t Typed[T]) Put(key string, v T) (uint64, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("state: encode %q: %w", key, err)
+	}
+	return t.Store.Put(key, b)
+}
+
+// Create encodes and writes v only if key does not exist.
+func (t Typed[T]) Create(key string, v T) (uint64, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("state: encode %q: %w", key, err)
+	}
+	return t.Store.Create(key, b)
+}
+
+// Update encodes and writes v only if key is at revision.
+func (t Typed[T]) Update(key string, v T, revision uint64) (uint64, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("state: encode %q: %w", key, err)
+	}
+	return t.Store.Update(key, b, revision)
+}
+
+// checkKey applies the NATS KV key rules: letters, digits and "-/_=." only,
+// not starting or ending with ".". A wildcard pattern is not a key.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-/_=.", r):
+		default:
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+//go:build fast
+
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestMemory_CreateUpdateConflicts(t *testing.T) {
+	m := NewMemory(0)
+
+	rev, err := m.Create("person.wife", []byte("home"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := m.Create("person.wife", []byte("away")); !errors.Is(err, ErrConflict) {
+		t.Errorf("second Create error = %v, want ErrConflict", err)
+	}
+	next, err := m.Update("person.wife", []byte("not_home"), rev)
+	if err != nil {
+		t.Fatalf("Update at current revision: %v", err)
+	}
+	if _, err := m.Update("person.wife", []byte("home"), rev); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale Update error = %v, want ErrConflict", err)
+	}
+
+	e, err := m.Get("person.wife")
+	if err != nil || string(e.Value) != "not_home" || e.Revision != next {
+		t.Errorf("Get = %+v, %v; want not_home at revision %d", e, err, next)
+	}
+
+	if err := m.Delete("person.wife"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := m.Get("person.wife"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Create("person.wife", []byte("home")); err != nil {
+		t.Errorf("Create after Delete: %v", err)
+	}
+}
+
+func TestMemory_ListAndCompareAndDelete(t *testing.T) {
+	m := NewMemory(0)
+	rev, _ := m.Put("rules.morning", []byte("a"))
+	_, _ = m.Put("rules.evening", []byte("b"))
+	_, _ = m.Put("ada.feeding_alert", []byte("c"))
+
+	got, err := m.List("rules.>")
+	if err != nil || len(got) != 2 {
+		t.Fatalf("List(rules.>) = %v, %v; want 2 entries", got, err)
+	}
+
+	if err := m.CompareAndDelete("rules.morning", rev+10); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale CompareAndDelete error = %v, want ErrConflict", err)
+	}
+	if err := m.CompareAndDelete("rules.morning", rev); err != nil {
+		t.Fatalf("CompareAndDelete at current revision: %v", err)
+	}
+	if got, _ := m.List("rules.>"); len(got) != 1 || got[0].Key != "rules.evening" {
+		t.Errorf("List after delete = %v, want only rules.evening", got)
+	}
+}
+
+func TestMemory_TTLExpiresKeys(t *testing.T) {
+	m := NewMemory(20 * time.Millisecond)
+	if _, err := m.Put("k", []byte("v")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := m.Get("k"); err != nil {
+		t.Fatalf("Get before expiry: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := m.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after expiry error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemory_WatchSendsCurrentThenChanges(t *testing.T) {
+	m := NewMemory(0)
+	_, _ = m.Put("ha.sensor.a", []byte("1"))
+	_, _ = m.Put("vars.night_mode", []byte("on"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := m.Watch(ctx, "ha.>")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	_, _ = m.Put("ha.sensor.b", []byte("2"))
+	_ = m.Delete("ha.sensor.a")
+	_, _ = m.Put("vars.other", []byte("x"))
+
+	want := []Entry{
+		{Key: "ha.sensor.a", Value: []byte("1")},
+		{Key: "ha.sensor.b", Value: []byte("2")},
+		{Key: "ha.sensor.a", Deleted: true},
+	}
+	for _, w := range want {
+		select {
+		case got := <-ch:
+			if got.Key != w.Key || string(got.Value) != string(w.Value) || got.Deleted != w.Deleted {
+				t.Errorf("entry = %+v, want %+v", got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %+v", w)
+		}
+	}
+
+	cancel()
+	for range ch {
+		// drain until the watch closes the channel
+	}
+}
+
+func TestTyped_RoundTrip(t *testing.T) {
+	type presence struct {
+		State string `json:"state"`
+	}
+	s := NewTyped[presence](NewMemory(0))
+
+	rev, err := s.Create("person.wife", presence{State: "home"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, gotRev, err := s.Get("person.wife")
+	if err != nil || got.State != "home" || gotRev != rev {
+		t.Errorf("Get = %+v, %d, %v; want home at %d", got, gotRev, err, rev)
+	}
+	if _, err := s.Update("person.wife", presence{State: "away"}, rev+1); !errors.Is(err, ErrConflict) {
+		t.Errorf("Update at wrong revision error = %v, want ErrConflict", err)
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	for key, valid := range map[string]bool{
+		"ha.sensor.temp":  true,
+		"vars.night_mode": true,
+		"a/b=c-d":         true,
+		"":                false,
+		".leading":        false,
+		"trailing.":       false,
+		"ha.*":            false,
+		"has space":       false,
+	} {
+		if err := checkKey(key); (err == nil) != valid {
+			t.Errorf("checkKey(%q) = %v, want valid=%v", key, err, valid)
+		}
+	}
+}
+
+// fakeBucketAdmin records the description of a single KV stream.
+type fakeBucketAdmin struct {
+	description string
+	updated     bool
+}
+
+func (f *fakeBucketAdmin) StreamInfo(stream string, _ ...nats.JSOpt) (*nats.StreamInfo, error) {
+	return &nats.StreamInfo{Config: nats.StreamConfig{Name: stream, Description: f.description}}, nil
+}
+
+func (f *fakeBucketAdmin) UpdateStream(cfg *nats.StreamConfig, _ ...nats.JSOpt) (*nats.StreamInfo, error) {
+	f.description = cfg.Description
+	f.updated = true
+	return &nats.StreamInfo{Config: *cfg}, nil
+}
+
+func TestClaim_StampsUnownedAndRefusesOthers(t *testing.T) {
+	js := &fakeBucketAdmin{}
+	if err := claim(js, "rules", "ruby_engine/rules"); err != nil {
+		t.Fatalf("claim unowned bucket: %v", err)
+	}
+	if !js.updated || js.description != "owner: ruby_engine/rules" {
+		t.Errorf("description = %q (updated %v), want the bucket stamped", js.description, js.updated)
+	}
+
+	js.updated = false
+	if err := claim(js, "rules", "ruby_engine/rules"); err != nil || js.updated {
+		t.Errorf("reclaim by owner: err=%v updated=%v, want no-op", err, js.updated)
+	}
+	if err := claim(js, "rules", "ruby_presence/rules"); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("claim by another owner error = %v, want ErrNotOwner", err)
+	}
+}
@@ -8,24 +8,25 @@ import (
 	"log/slog"
 	"net/http"
 
-	goNats "github.com/nats-io/nats.go"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 )
 
 // Handler publishes Ada dashboard actions as CloudEvents to HA_EVENTS.
 type Handler struct {
-	nc  *goNats.Conn
+	pub natsx.MsgPublisher
 	log *slog.Logger
 }
 
-// New returns a Handler that publishes to the given NATS connection.
-func New(nc *goNats.Conn, log *slog.Logger) *Handler {
-	return &Handler{nc: nc, log: log}
+// New returns a Handler that publishes through pub, a natsx.AckedPublisher in
+// production so a 202 means HA_EVENTS stored the event.
+func New(pub natsx.MsgPublisher, log *slog.Logger) *Handler {
+	return &Handler{pub: pub, log: log}
 }
 
 // ServeHTTP handles POST /ada/events.
 // Decodes the request body, routes by the "event" field, wraps in a CloudEvent,
 // and publishes to the appropriate ha.events.ada.* subject.
-// Returns 202 Accepted on success; the payload is fire-and-forget once published.
+// Returns 202 Accepted once the stream has acknowledged the event.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -39,7 +40,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := Publish(r.Context(), h.nc, raw, h.log); err != nil {
+	if err := Publish(r.Context(), h.pub, raw, h.log); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
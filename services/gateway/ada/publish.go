@@ -8,8 +8,6 @@ import (
 	"log/slog"
 	"time"
 
-	goNats "github.com/nats-io/nats.go"
-
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
@@ -70,7 +68,7 @@ var eventRoutes = map[string]string{
 // Publish wraps payload in a CloudEvent and publishes to the appropriate
 // ha.events.ada.* NATS subject. Used by both the HTTP handler and the
 // gateway WebSocket ada_event handler.
-func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *slog.Logger) error {
+func Publish(ctx context.Context, pub natsx.MsgPublisher, payload map[string]any, log *slog.Logger) error {
 	eventType, _ := payload["event"].(string)
 	subject, ok := eventRoutes[eventType]
 	if !ok {
@@ -96,7 +94,12 @@ func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *
 		return fmt.Errorf("ada: marshal CloudEvent: %w", err)
 	}
 
-	if err := natsx.PublishWithContext(ctx, nc, subject, b); err != nil {
+	if err := schemas.Validate(evt); err != nil {
+		reject(ctx, pub, subject, b, err, log)
+		return fmt.Errorf("ada: %w", err)
+	}
+
+	if err := natsx.PublishWithContext(ctx, pub, subject, b); err != nil {
 		return fmt.Errorf("ada: publish %s: %w", subject, err)
 	}
 
@@ -113,7 +116,7 @@ func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *
 // after querying HA — not routed through eventRoutes.
 // availableServices is the full list of mobile_app_* notify service names
 // discovered from HA, forwarded so the engine can populate the device picker.
-func PublishUsersSynced(ctx context.Context, nc *goNats.Conn, users []schemas.AdaHAUser, availableServices []string, log *slog.Logger) error {
+func PublishUsersSynced(ctx context.Context, pub natsx.MsgPublisher, users []schemas.AdaHAUser, availableServices []string, log *slog.Logger) error {
 	subject := schemas.AdaEventUsersSynced
 	id := newID()
 	evt := schemas.CloudEvent{
@@ -134,13 +137,29 @@ func PublishUsersSynced(ctx context.Context, nc *goNats.Conn, users []schemas.Ad
 	if err != nil {
 		return fmt.Errorf("ada: marshal users_synced: %w", err)
 	}
-	if err := natsx.PublishWithContext(ctx, nc, subject, b); err != nil {
+	if err := natsx.PublishWithContext(ctx, pub, subject, b); err != nil {
 		return fmt.Errorf("ada: publish users_synced: %w", err)
 	}
 	log.Info("ada: users_synced published", slog.Int("count", len(users)))
 	return nil
 }
 
+// reject routes an event that failed schema validation to rejected.{subject} instead
+// of publishing it (ADR-0014). A failed rejection is only logged: the caller already
+// reports the validation error to Home Assistant.
+func reject(ctx context.Context, pub natsx.MsgPublisher, subject string, data []byte, reason error, log *slog.Logger) {
+	log.Warn("ada: event rejected",
+		slog.String("subject", subject),
+		slog.String("reason", reason.Error()),
+	)
+	if err := natsx.PublishRejected(ctx, pub, subject, data, "ruby_gateway", reason); err != nil {
+		log.Error("ada: publish rejected event failed",
+			slog.String("subject", subject),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
 func newID() string {
 	var b [8]byte
 	_, _ = rand.Read(b[:])
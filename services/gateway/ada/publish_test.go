@@ -3,8 +3,14 @@
 package ada
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
 	"testing"
 
+	"github.com/nats-io/nats.go"
+
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
@@ -53,3 +59,25 @@ func TestEventRoutesEditDelete(t *testing.T) {
 		}
 	}
 }
+
+// recordingPublisher records published messages in place of a JetStream publisher.
+type recordingPublisher struct{ msgs []*nats.Msg }
+
+func (r *recordingPublisher) PublishMsg(m *nats.Msg) error {
+	r.msgs = append(r.msgs, m)
+	return nil
+}
+
+// TestPublishInvalidEvent verifies a delete without an id is refused with
+// ErrInvalidEvent instead of being published for the engine to nak (ADR-0014).
+func TestPublishInvalidEvent(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pub := &recordingPublisher{}
+	err := Publish(context.Background(), pub, map[string]any{"event": "ada.diaper.delete"}, log)
+	if !errors.Is(err, schemas.ErrInvalidEvent) {
+		t.Fatalf("Publish = %v, want ErrInvalidEvent", err)
+	}
+	if len(pub.msgs) != 1 || pub.msgs[0].Subject != "rejected."+schemas.AdaEventDiaperDelete {
+		t.Errorf("published %v, want only the rejection", pub.msgs)
+	}
+}
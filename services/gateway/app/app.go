@@ -1,5 +1,6 @@
 // Package app wires together the gateway's components: HA WebSocket client,
-// Normalizer, Reconciler, NATS publisher, and health heartbeat.
+// Normalizer, Reconciler, NATS publisher, ha_service command consumer, and health
+// heartbeat.
 package app
 
 import (
@@ -7,10 +8,12 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	goNats "github.com/nats-io/nats.go"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/audit"
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/services/gateway/ada"
 	"github.com/primaryrutabaga/ruby-core/services/gateway/ha"
@@ -22,22 +25,30 @@ const healthInterval = 15 * time.Second
 // App holds all gateway runtime components.
 type App struct {
 	nc        *goNats.Conn
+	js        goNats.JetStreamContext
+	pub       *natsx.AckedPublisher // acked, deduplicated event publishes
+	norm      *ha.Normalizer
 	client    *ha.Client
 	publisher *gatewayNats.Publisher
+	commands  *commandRunner   // nil in degraded mode
+	rec       *audit.Publisher // audit.ruby_gateway.>
 	log       *slog.Logger
 }
 
 // New builds the gateway App by reading compiled config from the engine's KV
-// bucket, then constructing the Normalizer, Reconciler, HA client, and NATS
-// publisher.
+// bucket, then constructing the Normalizer, Reconciler, HA client, NATS
+// publisher, and the consumer of ha_service commands.
 //
 // haURL and haToken are the Home Assistant base URL and long-lived access
-// token. nc is an established NATS connection.
+// token. reconcileDomains opts in to full-state reconciliation of those entity
+// domains on each reconnect; nil leaves it off. nc is an established NATS
+// connection.
 //
 // If the config KV entry is not yet present (engine hasn't published yet),
 // the gateway starts with a nil passlist (pass-all) and an empty critical
-// entity list (no reconciliation). This is the safe V0 default.
-func New(haURL, haToken string, nc *goNats.Conn, log *slog.Logger) (*App, error) {
+// entity list (no reconciliation). This is the safe V0 default. Run keeps both
+// current as the engine republishes them (see watchEngineConfig).
+func New(haURL, haToken string, reconcileDomains []string, nc *goNats.Conn, log *slog.Logger) (*App, error) {
 	js, err := nc.JetStream()
 	if err != nil {
 		return nil, err
@@ -54,17 +65,27 @@ func New(haURL, haToken string, nc *goNats.Conn, log *slog.Logger) (*App, error)
 
 	// ── components ──────────────────────────────────────────────────────────
 	norm := ha.NewNormalizer(passlist)
-	publisher := gatewayNats.New(nc)
+	pub := natsx.NewAckedPublisher(js)
+	publisher := gatewayNats.New(nc, pub)
+
+	rec := audit.NewPublisher(nc, "ruby_gateway", log)
 
 	var client *ha.Client
+	var commands *commandRunner
 	if haURL != "" {
-		reconciler := ha.NewReconciler(haURL, haToken, stateKV, norm, publisher, log)
-		client = ha.NewClient(haURL, haToken, nc, norm, publisher, stateKV, critEntities, reconciler, log)
+		reconciler := ha.NewReconciler(haURL, haToken, reconcileDomains, stateKV, norm, publisher, log)
+		client = ha.NewClient(haURL, haToken, pub, norm, publisher, stateKV, critEntities, reconciler, log)
+		// Commands are only consumed where they can run: a degraded gateway leaves
+		// them in the COMMANDS stream.
+		if commands, err = newCommandRunner(nc, js, client, pub, rec, log); err != nil {
+			rec.Close()
+			return nil, err
+		}
 	} else {
 		log.Warn("gateway: no HA URL configured — WebSocket client disabled (degraded mode)")
 	}
 
-	return &App{nc: nc, client: client, publisher: publisher, log: log}, nil
+	return &App{nc: nc, js: js, pub: pub, norm: norm, client: client, publisher: publisher, commands: commands, rec: rec, log: log}, nil
 }
 
 // Run starts the HTTP server, HA WebSocket client loop, and health heartbeat
@@ -75,13 +96,26 @@ func New(haURL, haToken string, nc *goNats.Conn, log *slog.Logger) (*App, error)
 // must go through Traefik (ADR-0020).
 func (a *App) Run(ctx context.Context, httpAddr string) {
 	go a.runHealthBeat(ctx)
+	go a.watchEngineConfig(ctx)
 	go a.runHTTP(ctx, httpAddr)
+
+	var wg sync.WaitGroup
+	if a.commands != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.commands.Run(ctx)
+		}()
+	}
 	if a.client != nil {
 		a.client.Run(ctx) // blocks until ctx cancelled
 	} else {
 		// Degraded mode: no HA client. Block on ctx cancellation only.
 		<-ctx.Done()
 	}
+	// In-flight commands settle before their audit events are flushed.
+	wg.Wait()
+	a.rec.Close()
 }
 
 // runHTTP starts a minimal HTTP server exposing GET /health for Traefik and
@@ -93,7 +127,7 @@ func (a *App) runHTTP(ctx context.Context, addr string) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
-	mux.Handle("/ada/events", ada.New(a.nc, a.log))
+	mux.Handle("/ada/events", ada.New(a.pub, a.log))
 
 	srv := &http.Server{
 		Addr:         addr,
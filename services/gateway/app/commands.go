@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	goNats "github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/audit"
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/services/gateway/ha"
+)
+
+// commandsConsumer is the durable consumer through which the gateway runs the
+// engine's ha_service commands.
+const commandsConsumer = "gateway_ha_service"
+
+// commandRunner consumes ha_service commands from the COMMANDS stream and runs them
+// through the HA client (ha.CommandHandler), dead-lettering those that exhaust
+// MaxDeliver (ADR-0022).
+type commandRunner struct {
+	pool   *natsx.WorkerPool
+	dlqFwd *natsx.DLQForwarder
+	ids    idempotency.Store
+}
+
+// newCommandRunner ensures the streams, the durable consumer and the command claims
+// bucket the command path needs and builds its worker pool. The streams are normally created by the engine;
+// ensuring them here lets the gateway start first.
+func newCommandRunner(nc *goNats.Conn, js goNats.JetStreamContext, client *ha.Client, pub natsx.MsgPublisher, rec *audit.Publisher, log *slog.Logger) (*commandRunner, error) {
+	for _, ensure := range []func(goNats.JetStreamContext) error{
+		natsx.EnsureCommandsStream,
+		natsx.EnsureDLQStream,
+		natsx.EnsureRejectedStream,
+	} {
+		if err := ensure(js); err != nil {
+			return nil, fmt.Errorf("gateway: ensure stream: %w", err)
+		}
+	}
+	cfg, err := natsx.Manifest().Consumer("COMMANDS", commandsConsumer)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := natsx.EnsurePullConsumer(js, cfg)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := natsx.EnsureGatewayCommandsKV(js)
+	if err != nil {
+		return nil, err
+	}
+	ids := idempotency.NewHybridStore(claims, config.DefaultCommandsMaxAge, config.DefaultIdempotencyLease, "gateway")
+
+	msgInstr, err := natsx.NewMsgInstruments("gateway")
+	if err != nil {
+		log.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
+	}
+	failures := natsx.NewFailureLog()
+	dlqFwd, err := natsx.NewDLQForwarder(nc, js, cfg.Stream, cfg.Durable, failures, log)
+	if err != nil {
+		return nil, err
+	}
+	h := ha.NewCommandHandler(client, ids, pub, rec, cfg.Durable, log)
+	pool, err := natsx.NewWorkerPool(sub, cfg, h.Handle, msgInstr, failures, log)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("nats: pull consumer ready",
+		slog.String("consumer", cfg.Durable),
+		slog.String("filter", cfg.FilterSubject),
+	)
+	return &commandRunner{pool: pool, dlqFwd: dlqFwd, ids: ids}, nil
+}
+
+// Run consumes commands until ctx is cancelled, then waits for in-flight calls.
+func (r *commandRunner) Run(ctx context.Context) {
+	go func() { _ = r.dlqFwd.Run(ctx) }()
+	_ = r.pool.Run(ctx)
+	_ = r.ids.Close()
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	goNats "github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+)
+
+// configWatchRetry is how long watchEngineConfig waits before re-binding the
+// config KV bucket when it is absent or its watcher stops.
+const configWatchRetry = 30 * time.Second
+
+// watchEngineConfig keeps the Normalizer passlist and the client's critical
+// entities in step with the engine's config KV bucket, which the engine
+// republishes whenever its rule files are reloaded. A value that fails to parse
+// is logged and ignored, leaving the previous config in place. It blocks until
+// ctx is cancelled.
+func (a *App) watchEngineConfig(ctx context.Context) {
+	for {
+		if err := a.watchEngineConfigOnce(ctx); err != nil {
+			a.log.Info("gateway: config KV watch unavailable; retrying",
+				slog.String("bucket", natsx.KVBucketConfig),
+				slog.String("error", err.Error()),
+			)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(configWatchRetry):
+		}
+	}
+}
+
+// watchEngineConfigOnce watches the config.engine.* keys until ctx is cancelled
+// or the watcher closes. The watcher replays the current values first, so any
+// update missed between loadEngineConfig and the watch is applied too.
+func (a *App) watchEngineConfigOnce(ctx context.Context) error {
+	kv, err := a.js.KeyValue(natsx.KVBucketConfig)
+	if err != nil {
+		return err
+	}
+	w, err := kv.Watch("config.engine.*", goNats.Context(ctx))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry, ok := <-w.Updates():
+			if !ok {
+				return nil
+			}
+			if entry == nil || entry.Operation() != goNats.KeyValuePut {
+				continue // nil marks the end of the initial values
+			}
+			a.applyEngineConfig(ctx, entry.Key(), entry.Value())
+		}
+	}
+}
+
+// applyEngineConfig applies one config KV value to the running components.
+func (a *App) applyEngineConfig(ctx context.Context, key string, value []byte) {
+	switch key {
+	case natsx.KVKeyConfigPasslist:
+		var passlist map[string][]string
+		if err := json.Unmarshal(value, &passlist); err != nil {
+			a.log.Warn("gateway: parse passlist JSON failed; keeping current passlist",
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		a.norm.SetPasslist(passlist)
+		a.log.Info("gateway: passlist updated", slog.Int("passlist_domains", len(passlist)))
+
+	case natsx.KVKeyConfigCriticalEntities:
+		var critEntities []string
+		if err := json.Unmarshal(value, &critEntities); err != nil {
+			a.log.Warn("gateway: parse critical_entities JSON failed; keeping current list",
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		if a.client != nil {
+			a.client.SetCriticalEntities(ctx, critEntities)
+		}
+		a.log.Info("gateway: critical entities updated", slog.Int("critical_entities", len(critEntities)))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 	"github.com/primaryrutabaga/ruby-core/services/gateway/ada"
 	gatewayNats "github.com/primaryrutabaga/ruby-core/services/gateway/nats"
@@ -34,6 +36,10 @@ type haWSMessage struct {
 	EventType   string   `json:"event_type,omitempty"`
 	Success     bool     `json:"success,omitempty"`
 	Event       *haEvent `json:"event,omitempty"`
+	Error       *haError `json:"error,omitempty"`
+
+	// Result is the result member of a command's response, kept raw for the caller.
+	Result json.RawMessage `json:"result,omitempty"`
 }
 
 // haEvent wraps a HA WebSocket event. Data is left as raw JSON so the
@@ -46,15 +52,17 @@ type haEvent struct {
 // haEventData is the data field of a state_changed event.
 type haEventData struct {
 	EntityID string         `json:"entity_id"`
+	OldState *haEntityState `json:"old_state"`
 	NewState *haEntityState `json:"new_state"`
 }
 
-// haEntityState is the new_state field of a state_changed event.
+// haEntityState is the old_state or new_state field of a state_changed event.
 type haEntityState struct {
 	EntityID    string         `json:"entity_id"`
 	State       string         `json:"state"`
 	Attributes  map[string]any `json:"attributes"`
 	LastChanged string         `json:"last_changed"`
+	LastUpdated string         `json:"last_updated"`
 }
 
 // haUserEntry is one HA user account from the config/auth/list WebSocket response.
@@ -69,18 +77,20 @@ type haUserEntry struct {
 // Client connects to the Home Assistant WebSocket API, subscribes to
 // state_changed and ada_event events, normalises state_changed events via the
 // Normalizer, and publishes CloudEvents to NATS. On each successful reconnect
-// it triggers the Reconciler (ADR-0008 targeted reconciliation).
+// it triggers the Reconciler (ADR-0008 targeted reconciliation, and full
+// reconciliation of the allowlisted domains when configured).
 type Client struct {
 	haURL        string
 	haToken      string
-	nc           *goNats.Conn
+	pub          natsx.MsgPublisher // acked JetStream publishes of ada and ruby_home events
 	norm         *Normalizer
 	publisher    *gatewayNats.Publisher
 	stateKV      goNats.KeyValue
-	critEntities []string
+	critEntities atomic.Pointer[[]string] // replaced by SetCriticalEntities
 	reconciler   *Reconciler
 	log          *slog.Logger
 	haConnected  atomic.Bool
+	session      atomic.Pointer[session] // the live connection, for commands; nil while down
 	httpClient   *http.Client
 	reconnects   metric.Int64Counter // ruby_core_ha_websocket_reconnects_total
 }
@@ -88,7 +98,7 @@ type Client struct {
 // NewClient creates a Client.
 func NewClient(
 	haURL, haToken string,
-	nc *goNats.Conn,
+	pub natsx.MsgPublisher,
 	norm *Normalizer,
 	publisher *gatewayNats.Publisher,
 	stateKV goNats.KeyValue,
@@ -100,18 +110,39 @@ func NewClient(
 		"ruby_core_ha_websocket_reconnects_total",
 		metric.WithDescription("Successful Home Assistant WebSocket connection establishments (includes the initial connect)"),
 	)
-	return &Client{
-		haURL:        haURL,
-		haToken:      haToken,
-		nc:           nc,
-		norm:         norm,
-		publisher:    publisher,
-		stateKV:      stateKV,
-		critEntities: critEntities,
-		reconciler:   reconciler,
-		log:          log,
-		httpClient:   &http.Client{Timeout: 10 * time.Second},
-		reconnects:   reconnects,
+	c := &Client{
+		haURL:      haURL,
+		haToken:    haToken,
+		pub:        pub,
+		norm:       norm,
+		publisher:  publisher,
+		stateKV:    stateKV,
+		reconciler: reconciler,
+		log:        log,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		reconnects: reconnects,
+	}
+	c.critEntities.Store(&critEntities)
+	return c
+}
+
+// SetCriticalEntities replaces the entity list reconciled on each reconnect,
+// e.g. after the engine republishes its compiled config. Entities not in the
+// previous list are reconciled immediately if the WebSocket is connected, so a
+// newly critical entity does not wait for the next reconnect.
+func (c *Client) SetCriticalEntities(ctx context.Context, critEntities []string) {
+	prev := c.critEntities.Swap(&critEntities)
+	if !c.Connected() {
+		return
+	}
+	var added []string
+	for _, id := range critEntities {
+		if prev == nil || !slices.Contains(*prev, id) {
+			added = append(added, id)
+		}
+	}
+	if len(added) > 0 {
+		go c.reconciler.Run(ctx, nil, added)
 	}
 }
 
@@ -154,8 +185,9 @@ func (c *Client) Run(ctx context.Context) {
 	}
 }
 
-// runOnce opens one WebSocket session: authenticates, subscribes to
-// state_changed events, and loops over incoming messages.
+// runOnce opens one WebSocket session: authenticates, starts the session's reader,
+// subscribes to the HA events the gateway ingests, and handles events in order until
+// the connection fails or ctx is cancelled.
 func (c *Client) runOnce(ctx context.Context) error {
 	wsURL := haWSURL(c.haURL)
 	c.log.Info("ha websocket: connecting", slog.String("url", wsURL))
@@ -168,7 +200,8 @@ func (c *Client) runOnce(ctx context.Context) error {
 	defer func() { _ = conn.Close() }()
 
 	// ── auth flow ───────────────────────────────────────────────────────────
-	// HA sends auth_required first.
+	// HA sends auth_required first. Auth precedes any ID-tagged message, so it is
+	// read inline before the session's reader starts.
 	var authReq haWSMessage
 	if err := conn.ReadJSON(&authReq); err != nil {
 		return fmt.Errorf("read auth_required: %w", err)
@@ -194,61 +227,41 @@ func (c *Client) runOnce(ctx context.Context) error {
 		return fmt.Errorf("ha websocket: unexpected auth response type %q", authResp.Type)
 	}
 
-	// ── subscribe to events ────────────────────────────────────────────────
-	// IDs must be unique per connection; 1–3 are used by the three subscriptions.
-	// msgID is incremented for any subsequent command (e.g. config/auth/list).
-	const subID = 1          // state_changed
-	const adaSubID = 2       // ada_event (Phase 3b dashboard write path)
-	const homeEventSubID = 3 // ruby_home_event (ROADMAP-0012 domain-neutral write path)
-	msgID := 4               // next available ID for on-demand commands
-
-	if err := conn.WriteJSON(haWSMessage{
-		ID:        subID,
-		Type:      "subscribe_events",
-		EventType: "state_changed",
-	}); err != nil {
-		return fmt.Errorf("write subscribe_events state_changed: %w", err)
-	}
-	var subResp haWSMessage
-	if err := conn.ReadJSON(&subResp); err != nil {
-		return fmt.Errorf("read subscribe state_changed result: %w", err)
-	}
-	if !subResp.Success {
-		return fmt.Errorf("ha websocket: subscribe state_changed rejected")
-	}
-	c.log.Info("ha websocket: subscribed to state_changed")
-
-	if err := conn.WriteJSON(haWSMessage{
-		ID:        adaSubID,
-		Type:      "subscribe_events",
-		EventType: "ada_event",
-	}); err != nil {
-		return fmt.Errorf("write subscribe_events ada_event: %w", err)
-	}
-	var adaSubResp haWSMessage
-	if err := conn.ReadJSON(&adaSubResp); err != nil {
-		return fmt.Errorf("read subscribe ada_event result: %w", err)
-	}
-	if !adaSubResp.Success {
-		return fmt.Errorf("ha websocket: subscribe ada_event rejected")
-	}
-	c.log.Info("ha websocket: subscribed to ada_event")
+	// ── session ─────────────────────────────────────────────────────────────
+	// From here one reader owns the connection: command results go to their callers
+	// (Call, from any goroutine) and events are queued for the event loop, which
+	// runs each subscription's handler in arrival order.
+	sess := newSession(conn)
+	events := make(chan haWSMessage, eventQueueSize)
+	go sess.read(events)
+	loopDone := make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		for msg := range events {
+			c.dispatch(ctx, sess, msg)
+		}
+	}()
+	c.session.Store(sess)
+	defer func() {
+		c.session.Store(nil)
+		_ = conn.Close() // stops the reader, which ends the event loop
+		<-loopDone
+	}()
 
-	if err := conn.WriteJSON(haWSMessage{
-		ID:        homeEventSubID,
-		Type:      "subscribe_events",
-		EventType: "ruby_home_event",
-	}); err != nil {
-		return fmt.Errorf("write subscribe_events ruby_home_event: %w", err)
-	}
-	var homeSubResp haWSMessage
-	if err := conn.ReadJSON(&homeSubResp); err != nil {
-		return fmt.Errorf("read subscribe ruby_home_event result: %w", err)
-	}
-	if !homeSubResp.Success {
-		return fmt.Errorf("ha websocket: subscribe ruby_home_event rejected")
+	// ── subscribe to events ────────────────────────────────────────────────
+	for _, sub := range []struct {
+		eventType string
+		handle    eventHandler
+	}{
+		{"state_changed", c.handleStateChanged},
+		{"ada_event", c.handleAdaEvent},            // Phase 3b dashboard write path
+		{"ruby_home_event", c.handleRubyHomeEvent}, // ROADMAP-0012 domain-neutral write path
+	} {
+		if err := sess.subscribe(ctx, sub.eventType, sub.handle); err != nil {
+			return fmt.Errorf("subscribe %s: %w", sub.eventType, err)
+		}
+		c.log.Info("ha websocket: subscribed to " + sub.eventType)
 	}
-	c.log.Info("ha websocket: subscribed to ruby_home_event")
 
 	// Mark connected only after all subscriptions are confirmed — the health
 	// heartbeat reads this flag to publish ha_connected, which the engine watches
@@ -258,48 +271,39 @@ func (c *Client) runOnce(ctx context.Context) error {
 		c.reconnects.Add(ctx, 1)
 	}
 
-	// Trigger targeted reconciliation after a successful reconnect (ADR-0008).
-	go c.reconciler.Run(ctx, c.critEntities)
+	// Trigger reconciliation after a successful reconnect (ADR-0008). It runs after
+	// the subscriptions, so a change HA makes during get_states arrives live.
+	go c.reconciler.Run(ctx, c, *c.critEntities.Load())
 
-	// ── event loop ─────────────────────────────────────────────────────────
-	for {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-sess.done:
 		if ctx.Err() != nil {
 			return nil
 		}
-		var msg haWSMessage
-		if err := conn.ReadJSON(&msg); err != nil {
-			if ctx.Err() != nil {
-				return nil
-			}
-			return fmt.Errorf("read event: %w", err)
-		}
-		if msg.Type != "event" || msg.Event == nil {
-			continue
-		}
-		if err := c.handleEvent(ctx, conn, &msgID, msg.Event); err != nil {
-			c.log.Warn("ha websocket: handle event error",
-				slog.String("error", err.Error()),
-			)
-		}
+		return fmt.Errorf("read event: %w", sess.err)
 	}
 }
 
-// handleEvent routes an incoming HA WebSocket event by event_type. It opens the root
-// ha.ingest span for the event; the span context flows to the NATS publish so the engine
-// (and notifier) continue the same distributed trace (PLAN-0009).
-func (c *Client) handleEvent(ctx context.Context, conn *websocket.Conn, nextID *int, ev *haEvent) error {
+// dispatch runs the handler of the subscription msg's event belongs to. It opens the
+// root ha.ingest span for the event; the span context flows to the NATS publish so the
+// engine (and notifier) continue the same distributed trace (PLAN-0009).
+func (c *Client) dispatch(ctx context.Context, sess *session, msg haWSMessage) {
+	handle := sess.handler(msg.ID)
+	if handle == nil {
+		return
+	}
 	ctx, span := tracer.Start(ctx, "ha.ingest",
 		trace.WithSpanKind(trace.SpanKindProducer),
-		trace.WithAttributes(attribute.String("ha.event_type", ev.EventType)))
+		trace.WithAttributes(attribute.String("ha.event_type", msg.Event.EventType)))
 	defer span.End()
 
-	switch ev.EventType {
-	case "ada_event":
-		return c.handleAdaEvent(ctx, conn, nextID, ev)
-	case "ruby_home_event":
-		return c.handleRubyHomeEvent(ctx, ev)
-	default:
-		return c.handleStateChanged(ctx, ev)
+	if err := handle(ctx, msg.Event); err != nil {
+		c.log.Warn("ha websocket: handle event error",
+			slog.String("event_type", msg.Event.EventType),
+			slog.String("error", err.Error()),
+		)
 	}
 }
 
@@ -317,7 +321,7 @@ func (c *Client) handleRubyHomeEvent(ctx context.Context, ev *haEvent) error {
 	if wrapper.Payload == nil {
 		return fmt.Errorf("ha: ruby_home_event missing payload field")
 	}
-	return rubyhome.Publish(ctx, c.nc, wrapper.Payload, c.log)
+	return rubyhome.Publish(ctx, c.pub, wrapper.Payload, c.log)
 }
 
 // handleStateChanged processes a state_changed event from HA.
@@ -341,7 +345,17 @@ func (c *Client) handleStateChanged(ctx context.Context, ev *haEvent) error {
 	}
 
 	filtered := c.norm.Apply(domain, ns.Attributes)
-	if err := c.publisher.PublishHAEvent(ctx, ns.EntityID, ns.State, filtered, ns.LastChanged); err != nil {
+	// The previous state goes through the same projection, so a processor compares
+	// like with like when it reads a transition from the event.
+	var old *gatewayNats.PrevState
+	if prev := data.OldState; prev != nil {
+		old = &gatewayNats.PrevState{
+			State:       prev.State,
+			Attributes:  c.norm.Apply(domain, prev.Attributes),
+			LastChanged: prev.LastChanged,
+		}
+	}
+	if err := c.publisher.PublishHAEvent(ctx, ns.EntityID, ns.State, filtered, ns.LastChanged, ns.LastUpdated, old); err != nil {
 		return fmt.Errorf("publish event for %s: %w", ns.EntityID, err)
 	}
 
@@ -361,9 +375,10 @@ func (c *Client) handleStateChanged(ctx context.Context, ev *haEvent) error {
 //
 //	{"payload": {"event": "ada.diaper.log", "type": "dirty", ...}}
 //
-// ada.sync_users is intercepted here and handled inline via the open WebSocket
-// connection — it does not go through the standard eventRoutes publish path.
-func (c *Client) handleAdaEvent(ctx context.Context, conn *websocket.Conn, nextID *int, ev *haEvent) error {
+// ada.sync_users is intercepted here and answered from a WebSocket command on its
+// own goroutine, so the event loop keeps handling events meanwhile — it does not go
+// through the standard eventRoutes publish path.
+func (c *Client) handleAdaEvent(ctx context.Context, ev *haEvent) error {
 	var wrapper struct {
 		Payload map[string]any `json:"payload"`
 	}
@@ -376,54 +391,29 @@ func (c *Client) handleAdaEvent(ctx context.Context, conn *websocket.Conn, nextI
 
 	eventType, _ := wrapper.Payload["event"].(string)
 	if eventType == "ada.sync_users" {
-		id := *nextID
-		*nextID++
-		return c.syncUsers(ctx, conn, id)
+		go func() {
+			if err := c.syncUsers(ctx); err != nil {
+				c.log.Warn("ha: sync users failed", slog.String("error", err.Error()))
+			}
+		}()
+		return nil
 	}
 
-	return ada.Publish(ctx, c.nc, wrapper.Payload, c.log)
+	return ada.Publish(ctx, c.pub, wrapper.Payload, c.log)
 }
 
 // syncUsers queries HA for all active users via the config/auth/list WebSocket
 // command, discovers Companion app notify services via the REST API, and
 // publishes ha.events.ada.users_synced to NATS.
-func (c *Client) syncUsers(ctx context.Context, conn *websocket.Conn, id int) error {
-	if err := conn.WriteJSON(map[string]any{
-		"id":   id,
-		"type": "config/auth/list",
-	}); err != nil {
-		return fmt.Errorf("ha: write config/auth/list: %w", err)
-	}
-
-	// The WebSocket is live — HA may send event messages before the command
-	// result arrives. Loop until we get the message with our command ID.
-	var result struct {
-		ID      int  `json:"id"`
-		Success bool `json:"success"`
-		Error   *struct {
-			Message string `json:"message"`
-		} `json:"error"`
-		Result json.RawMessage `json:"result"` // HA returns a flat array, not an object
-	}
-	for {
-		if err := conn.ReadJSON(&result); err != nil {
-			return fmt.Errorf("ha: read config/auth/list result: %w", err)
-		}
-		if result.ID == id {
-			break
-		}
-	}
-	if !result.Success {
-		msg := "unknown error"
-		if result.Error != nil {
-			msg = result.Error.Message
-		}
-		return fmt.Errorf("ha: config/auth/list failed: %s", msg)
+func (c *Client) syncUsers(ctx context.Context) error {
+	result, err := c.Call(ctx, "config/auth/list", nil)
+	if err != nil {
+		return fmt.Errorf("ha: config/auth/list: %w", err)
 	}
 
-	// HA returns result as a flat array of user objects.
+	// HA returns result as a flat array of user objects, not an object.
 	var haUsers []haUserEntry
-	if err := json.Unmarshal(result.Result, &haUsers); err != nil {
+	if err := json.Unmarshal(result, &haUsers); err != nil {
 		return fmt.Errorf("ha: unmarshal config/auth/list users: %w", err)
 	}
 
@@ -445,7 +435,7 @@ func (c *Client) syncUsers(ctx context.Context, conn *websocket.Conn, id int) er
 		})
 	}
 
-	return ada.PublishUsersSynced(ctx, c.nc, users, availableServices, c.log)
+	return ada.PublishUsersSynced(ctx, c.pub, users, availableServices, c.log)
 }
 
 // fetchMobileAppServices queries GET /api/services and returns all mobile_app_*
@@ -0,0 +1,116 @@
+//go:build fast
+
+package ha
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// fakeHA serves an HA WebSocket that authenticates any token and confirms every
+// subscription. Once ada_event is subscribed it sends an ada.sync_users request, and
+// it answers config/auth/list only after sending another ada_event, so the event
+// loop must handle events while syncUsers waits on its command.
+func fakeHA(t *testing.T) *httptest.Server {
+	t.Helper()
+	var upgrader websocket.Upgrader
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/services", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "[]")
+	})
+	mux.HandleFunc("/api/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var auth map[string]any
+		if conn.WriteJSON(map[string]any{"type": "auth_required"}) != nil ||
+			conn.ReadJSON(&auth) != nil ||
+			conn.WriteJSON(map[string]any{"type": "auth_ok"}) != nil {
+			return
+		}
+		var adaSub any
+		adaEvent := func(payload map[string]any) map[string]any {
+			return map[string]any{"id": adaSub, "type": "event",
+				"event": map[string]any{"event_type": "ada_event", "data": map[string]any{"payload": payload}}}
+		}
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var out []map[string]any
+			switch {
+			case cmd["type"] == "subscribe_events" && cmd["event_type"] == "ada_event":
+				adaSub = cmd["id"]
+				out = append(result(cmd, "", nil), adaEvent(map[string]any{"event": "ada.sync_users"}))
+			case cmd["type"] == "config/auth/list":
+				users := []map[string]any{{"id": "u1", "name": "Sam", "username": "sam", "is_active": true}}
+				out = append([]map[string]any{adaEvent(map[string]any{"event": "ada.diaper.delete", "id": "d1"})},
+					result(cmd, "", users)...)
+			default:
+				out = result(cmd, "", nil)
+			}
+			for _, msg := range out {
+				if err := conn.WriteJSON(msg); err != nil {
+					return
+				}
+			}
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestRunOnce_SyncUsersDoesNotStallEvents verifies ada.sync_users is answered over
+// the multiplexed session while events keep flowing: the ada_event HA sends ahead of
+// the config/auth/list result is published, and so is the users_synced reply.
+func TestRunOnce_SyncUsersDoesNotStallEvents(t *testing.T) {
+	srv := fakeHA(t)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	norm := NewNormalizer(nil)
+	pub := &recordingPublisher{}
+	c := NewClient(srv.URL, "token", pub, norm, nil, nil, nil,
+		NewReconciler(srv.URL, "token", nil, nil, norm, nil, log), log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.runOnce(ctx) }()
+
+	want := []string{schemas.AdaEventDiaperDelete, schemas.AdaEventUsersSynced}
+	published := func() []string { return slices.Sorted(slices.Values(pub.subjects())) }
+	deadline := time.After(2 * time.Second)
+	for !slices.Equal(published(), want) {
+		select {
+		case <-deadline:
+			t.Fatalf("published %v, want %v", pub.subjects(), want)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runOnce after cancel = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("runOnce did not return after cancel")
+	}
+	if _, err := c.Call(context.Background(), "get_states", nil); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Call after runOnce returned = %v, want ErrNotConnected", err)
+	}
+}
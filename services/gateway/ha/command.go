@@ -0,0 +1,297 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/config"
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+const (
+	// serviceCallTimeout bounds one call_service round trip. The worker pool's
+	// in-progress heartbeats keep the command from being redelivered while the call
+	// runs; a call that times out is answered with a result_unknown result.
+	serviceCallTimeout = 10 * time.Second
+
+	// resultUnknownCode is the error code of the result of a call written to HA that
+	// got no answer: HA may or may not have run it.
+	resultUnknownCode = "result_unknown"
+
+	// commandMaxAge is the oldest command the gateway still runs. A command that
+	// waited longer in the COMMANDS stream (the gateway was down, or HA was) is
+	// answered with an expired result instead: switching a light minutes after the
+	// rule fired is worse than not switching it.
+	commandMaxAge = 5 * time.Minute
+)
+
+// serviceCaller is implemented by *Client.
+type serviceCaller interface {
+	CallService(ctx context.Context, domain, service string, target, data map[string]any) error
+}
+
+// recorder is implemented by *audit.Publisher.
+type recorder interface {
+	Record(correlationID, causationID, action, natsSubject, outcome string)
+}
+
+// CommandHandler runs the engine's command.ha_service commands
+// (ruby_engine.commands.ha_service.>) as call_service over the HA WebSocket, and
+// answers each with an HAServiceResult event on ha.events.ha_service.result that
+// carries HA's verdict and the command's correlation. Every call is audited as
+// ha_service_called.
+//
+// Each command is claimed by its id in the idempotency store before it is written to
+// HA, so a redelivered command runs at most once. A refused call (*CommandError) is
+// acked: retrying cannot change HA's answer. A call that could not be written
+// (WebSocket down) is naked for redelivery, and publishes no result until it is. A
+// call written but not answered (timeout, disconnect) may have run, so it is not
+// retried: it is acked with a result_unknown result.
+type CommandHandler struct {
+	caller   serviceCaller
+	ids      idempotency.Store  // claims on command ids
+	pub      natsx.MsgPublisher // results and rejected commands
+	rec      recorder
+	consumer string
+	log      *slog.Logger
+	now      func() time.Time
+}
+
+// NewCommandHandler returns a CommandHandler that calls services through client,
+// claims commands in ids and publishes results and rejections through pub on behalf
+// of the durable consumer.
+func NewCommandHandler(client *Client, ids idempotency.Store, pub natsx.MsgPublisher, rec recorder, consumer string, log *slog.Logger) *CommandHandler {
+	return &CommandHandler{
+		caller:   client,
+		ids:      ids,
+		pub:      pub,
+		rec:      rec,
+		consumer: consumer,
+		log:      log,
+		now:      time.Now,
+	}
+}
+
+// Handle is the natsx.Handler for the gateway's ha_service command consumer.
+// Commands arrive in either CloudEvents content mode.
+func (h *CommandHandler) Handle(ctx context.Context, msg *nats.Msg) natsx.Result {
+	data, err := natsx.EventData(msg)
+	var cmd schemas.CloudEvent
+	if err == nil {
+		cmd, err = schemas.ValidateJSON(data)
+	}
+	if err == nil && cmd.Type != schemas.CommandTypeHAService {
+		err = fmt.Errorf("%w: %s is not a %s command", schemas.ErrInvalidEvent, cmd.Type, schemas.CommandTypeHAService)
+	}
+	var d schemas.CommandHAServiceData
+	if err == nil {
+		err = decodeData(cmd.Data, &d)
+	}
+	if err != nil {
+		return h.reject(ctx, msg, err)
+	}
+
+	if sent, err := time.Parse(time.RFC3339, cmd.Time); err == nil && h.now().Sub(sent) > commandMaxAge {
+		h.finish(ctx, msg.Subject, cmd, d, &CommandError{
+			Type:    "call_service",
+			Code:    "expired",
+			Message: fmt.Sprintf("command older than %s", commandMaxAge),
+		})
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeFailure}
+	}
+
+	switch claim, err := h.ids.Claim(cmd.ID); {
+	case err != nil:
+		return natsx.Nak(fmt.Errorf("ha: claim command %s: %w", cmd.ID, err))
+	case claim == idempotency.Done:
+		h.log.Info("ha: command already run, discarding", slog.String("command_id", cmd.ID))
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeDuplicate}
+	case claim == idempotency.InFlight:
+		return natsx.Result{Decision: natsx.DecisionNak, Outcome: natsx.OutcomeInFlight,
+			Delay: config.DefaultIdempotencyLease, Err: fmt.Errorf("ha: command %s is claimed by another worker", cmd.ID)}
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, serviceCallTimeout)
+	err = h.caller.CallService(cctx, d.Domain, d.Service, serviceTarget(d), d.ServiceData)
+	cancel()
+
+	var refused *CommandError
+	switch {
+	case err == nil:
+		h.finish(ctx, msg.Subject, cmd, d, nil)
+		return natsx.Ack()
+	case errors.As(err, &refused):
+		h.finish(ctx, msg.Subject, cmd, d, refused)
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeFailure}
+	case errors.Is(err, ErrNoResult):
+		h.finish(ctx, msg.Subject, cmd, d, &CommandError{Type: "call_service", Code: resultUnknownCode, Message: err.Error()})
+		return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeFailure}
+	default:
+		h.log.Warn("ha: call_service failed, naking",
+			slog.String("subject", msg.Subject),
+			slog.String("service", d.Domain+"."+d.Service),
+			slog.String("correlationid", cmd.CorrelationID),
+			slog.String("error", err.Error()),
+		)
+		if rerr := h.ids.Release(cmd.ID); rerr != nil {
+			h.log.Warn("ha: release command claim failed",
+				slog.String("command_id", cmd.ID),
+				slog.String("error", rerr.Error()),
+			)
+		}
+		return natsx.Nak(err)
+	}
+}
+
+// finish publishes the result of cmd, audits it and records its id as done, so a
+// redelivery is discarded. refused is nil for a call HA accepted. A result that cannot be published
+// is logged, not retried: the service has already run, and redelivering the command
+// would run it again.
+func (h *CommandHandler) finish(ctx context.Context, subject string, cmd schemas.CloudEvent, d schemas.CommandHAServiceData, refused *CommandError) {
+	outcome := "success"
+	result := schemas.HAServiceResultData{
+		CommandID: cmd.ID,
+		Rule:      d.Rule,
+		Domain:    d.Domain,
+		Service:   d.Service,
+		EntityID:  d.EntityID,
+		Success:   refused == nil,
+	}
+	if refused != nil {
+		outcome = "failure"
+		result.Error, result.ErrorCode = refused.Message, refused.Code
+		h.log.Warn("ha: call_service failed",
+			slog.String("service", d.Domain+"."+d.Service),
+			slog.String("correlationid", cmd.CorrelationID),
+			slog.String("code", refused.Code),
+			slog.String("error", refused.Message),
+		)
+	} else {
+		h.log.Info("ha: call_service done",
+			slog.String("service", d.Domain+"."+d.Service),
+			slog.String("entity_id", d.EntityID),
+			slog.String("correlationid", cmd.CorrelationID),
+		)
+	}
+	h.rec.Record(correlationOf(cmd), cmd.ID, "ha_service_called", subject, outcome)
+
+	if err := h.publishResult(ctx, cmd, result); err != nil {
+		h.log.Error("ha: publish call_service result failed",
+			slog.String("command_id", cmd.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+	if err := h.ids.Complete(cmd.ID); err != nil {
+		h.log.Error("ha: record command as run failed",
+			slog.String("command_id", cmd.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// publishResult publishes result as the HAServiceResult event caused by cmd. Its id
+// is derived from the command's, so a result published twice for one command is
+// dropped by the stream.
+func (h *CommandHandler) publishResult(ctx context.Context, cmd schemas.CloudEvent, result schemas.HAServiceResultData) error {
+	data, err := toMap(result)
+	if err != nil {
+		return err
+	}
+	subject := result.EntityID
+	if subject == "" {
+		subject = result.Domain + "." + result.Service
+	}
+	evt := schemas.CloudEvent{
+		SpecVersion:   schemas.CloudEventsSpecVersion,
+		ID:            cmd.ID + ".result",
+		Source:        "ruby_gateway",
+		Type:          schemas.HAServiceResult,
+		Time:          h.now().UTC().Format(time.RFC3339),
+		DataSchema:    schemas.CloudEventDataSchemaVersionV1,
+		Subject:       subject,
+		CorrelationID: correlationOf(cmd),
+		CausationID:   cmd.ID,
+		Data:          data,
+	}
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("ha: marshal call_service result: %w", err)
+	}
+	return natsx.PublishWithContext(ctx, h.pub, schemas.HAServiceResult, b)
+}
+
+// reject routes a command that failed schema validation to the REJECTED stream and
+// acks it (ADR-0014). If the rejection cannot be published the command is naked so
+// it is not lost.
+func (h *CommandHandler) reject(ctx context.Context, msg *nats.Msg, reason error) natsx.Result {
+	rej := natsx.RejectedMsg(msg.Subject, msg.Header, msg.Data, h.consumer, reason)
+	if err := h.pub.PublishMsg(rej); err != nil {
+		h.log.Error("ha: publish rejected command failed",
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+		return natsx.Nak(fmt.Errorf("ha: publish rejected command: %w", err))
+	}
+	h.log.Warn("ha: command rejected",
+		slog.String("subject", msg.Subject),
+		slog.String("reason", reason.Error()),
+	)
+	return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeRejected}
+}
+
+// serviceTarget is the call_service target of d: its Target, with EntityID added as
+// entity_id unless Target names entities itself.
+func serviceTarget(d schemas.CommandHAServiceData) map[string]any {
+	if d.EntityID == "" {
+		return d.Target
+	}
+	target := make(map[string]any, len(d.Target)+1)
+	for k, v := range d.Target {
+		target[k] = v
+	}
+	if _, ok := target["entity_id"]; !ok {
+		target["entity_id"] = d.EntityID
+	}
+	return target
+}
+
+// correlationOf is cmd's correlation ID, or its own ID when it starts a chain.
+func correlationOf(cmd schemas.CloudEvent) string {
+	if cmd.CorrelationID != "" {
+		return cmd.CorrelationID
+	}
+	return cmd.ID
+}
+
+// decodeData decodes a CloudEvent's data member into v.
+func decodeData(data map[string]any, v any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", schemas.ErrInvalidEvent, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %v", schemas.ErrInvalidEvent, err)
+	}
+	return nil
+}
+
+// toMap converts a data struct to the map form CloudEvent.Data holds.
+func toMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("ha: marshal event data: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("ha: event data: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,315 @@
+//go:build fast
+
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/idempotency"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// fakeCaller records the last call_service and answers with err.
+type fakeCaller struct {
+	err             error
+	calls           int
+	domain, service string
+	target, data    map[string]any
+}
+
+func (f *fakeCaller) CallService(_ context.Context, domain, service string, target, data map[string]any) error {
+	f.calls++
+	f.domain, f.service, f.target, f.data = domain, service, target, data
+	return f.err
+}
+
+// recordingPublisher records published messages in place of a JetStream publisher.
+type recordingPublisher struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (r *recordingPublisher) PublishMsg(m *nats.Msg) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, m)
+	return nil
+}
+
+// subjects returns the subjects published so far, in order.
+func (r *recordingPublisher) subjects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subjects := make([]string, len(r.msgs))
+	for i, m := range r.msgs {
+		subjects[i] = m.Subject
+	}
+	return subjects
+}
+
+// fakeClaims is an in-memory idempotency.Store.
+type fakeClaims struct {
+	done, inFlight map[string]bool
+	released       []string
+}
+
+func newFakeClaims() *fakeClaims {
+	return &fakeClaims{done: map[string]bool{}, inFlight: map[string]bool{}}
+}
+
+func (f *fakeClaims) Claim(id string) (idempotency.ClaimResult, error) {
+	switch {
+	case f.done[id]:
+		return idempotency.Done, nil
+	case f.inFlight[id]:
+		return idempotency.InFlight, nil
+	}
+	return idempotency.Claimed, nil
+}
+
+func (f *fakeClaims) Complete(id string) error {
+	f.done[id] = true
+	return nil
+}
+
+func (f *fakeClaims) Release(id string) error {
+	f.released = append(f.released, id)
+	return nil
+}
+
+func (f *fakeClaims) Close() error { return nil }
+
+// auditRecord is one recorded audit event.
+type auditRecord struct{ correlationID, causationID, action, outcome string }
+
+type fakeRecorder struct{ records []auditRecord }
+
+func (f *fakeRecorder) Record(correlationID, causationID, action, _, outcome string) {
+	f.records = append(f.records, auditRecord{correlationID, causationID, action, outcome})
+}
+
+var commandNow = time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+
+func newTestCommandHandler(caller serviceCaller) (*CommandHandler, *recordingPublisher, *fakeRecorder) {
+	pub, rec := &recordingPublisher{}, &fakeRecorder{}
+	return &CommandHandler{
+		caller:   caller,
+		ids:      newFakeClaims(),
+		pub:      pub,
+		rec:      rec,
+		consumer: "gateway_ha_service",
+		log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		now:      func() time.Time { return commandNow },
+	}, pub, rec
+}
+
+func commandMsg(t *testing.T, sent time.Time, data map[string]any) *nats.Msg {
+	t.Helper()
+	cmd := schemas.NewCommand("cmd1", schemas.CommandTypeHAService, schemas.CloudEvent{ID: "evt1", CorrelationID: "corr1"}, data)
+	cmd.Time = sent.Format(time.RFC3339)
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &nats.Msg{Subject: "ruby_engine.commands.ha_service.cmd1", Data: b}
+}
+
+// resultOf decodes the single HAServiceResult event published through pub.
+func resultOf(t *testing.T, pub *recordingPublisher) (schemas.CloudEvent, schemas.HAServiceResultData) {
+	t.Helper()
+	if len(pub.msgs) != 1 || pub.msgs[0].Subject != schemas.HAServiceResult {
+		t.Fatalf("expected one message on %s, got %d", schemas.HAServiceResult, len(pub.msgs))
+	}
+	var evt schemas.CloudEvent
+	if err := json.Unmarshal(pub.msgs[0].Data, &evt); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	var d schemas.HAServiceResultData
+	if err := decodeData(evt.Data, &d); err != nil {
+		t.Fatalf("decode result data: %v", err)
+	}
+	return evt, d
+}
+
+func TestCommandHandler_CallsService(t *testing.T) {
+	caller := &fakeCaller{}
+	h, pub, rec := newTestCommandHandler(caller)
+	res := h.Handle(context.Background(), commandMsg(t, commandNow, map[string]any{
+		"rule":         "porch_light",
+		"domain":       "light",
+		"service":      "turn_on",
+		"entity_id":    "light.porch",
+		"service_data": map[string]any{"brightness": 128},
+	}))
+
+	if res.Decision != natsx.DecisionAck {
+		t.Fatalf("decision = %v, want ack", res.Decision)
+	}
+	if caller.domain != "light" || caller.service != "turn_on" {
+		t.Errorf("called %s.%s, want light.turn_on", caller.domain, caller.service)
+	}
+	if caller.target["entity_id"] != "light.porch" || caller.data["brightness"] != float64(128) {
+		t.Errorf("target = %v, data = %v", caller.target, caller.data)
+	}
+
+	evt, d := resultOf(t, pub)
+	if !d.Success || d.CommandID != "cmd1" || d.Rule != "porch_light" {
+		t.Errorf("result data = %+v, want success for cmd1", d)
+	}
+	if evt.CausationID != "cmd1" || evt.CorrelationID != "corr1" || evt.Subject != "light.porch" {
+		t.Errorf("result causation/correlation/subject = %q/%q/%q, want cmd1/corr1/light.porch",
+			evt.CausationID, evt.CorrelationID, evt.Subject)
+	}
+	if err := schemas.Validate(evt); err != nil {
+		t.Errorf("result does not match its schema: %v", err)
+	}
+	if len(rec.records) != 1 || rec.records[0] != (auditRecord{"corr1", "cmd1", "ha_service_called", "success"}) {
+		t.Errorf("audit = %+v", rec.records)
+	}
+}
+
+func TestCommandHandler_RefusedCallIsAckedWithFailure(t *testing.T) {
+	h, pub, rec := newTestCommandHandler(&fakeCaller{err: &CommandError{Type: "call_service", Code: "service_not_found", Message: "Service light.explode not found."}})
+	res := h.Handle(context.Background(), commandMsg(t, commandNow, map[string]any{"domain": "light", "service": "explode"}))
+
+	if res.Decision != natsx.DecisionAck || res.Outcome != natsx.OutcomeFailure {
+		t.Fatalf("result = %+v, want ack with failure outcome", res)
+	}
+	_, d := resultOf(t, pub)
+	if d.Success || d.ErrorCode != "service_not_found" || d.Error != "Service light.explode not found." {
+		t.Errorf("result data = %+v, want HA's refusal", d)
+	}
+	if len(rec.records) != 1 || rec.records[0].outcome != "failure" {
+		t.Errorf("audit = %+v, want one failure", rec.records)
+	}
+}
+
+func TestCommandHandler_UnreachableHANaks(t *testing.T) {
+	for _, err := range []error{ErrNotConnected, errors.New("ha: write call_service: broken pipe")} {
+		h, pub, _ := newTestCommandHandler(&fakeCaller{err: err})
+		res := h.Handle(context.Background(), commandMsg(t, commandNow, map[string]any{"domain": "lock", "service": "lock"}))
+		if res.Decision != natsx.DecisionNak || !errors.Is(res.Err, err) {
+			t.Errorf("%v: result = %+v, want nak", err, res)
+		}
+		if len(pub.msgs) != 0 {
+			t.Errorf("%v: published %d messages, want no result until HA answers", err, len(pub.msgs))
+		}
+		if ids := h.ids.(*fakeClaims); len(ids.released) != 1 || ids.done["cmd1"] {
+			t.Errorf("%v: released %v, done %v, want the claim released for the retry", err, ids.released, ids.done)
+		}
+	}
+}
+
+// TestCommandHandler_UnansweredCallNotRetried verifies a call written to HA but not
+// answered is acked with a result_unknown result rather than naked: HA may have run
+// it, and a retry could run it twice.
+func TestCommandHandler_UnansweredCallNotRetried(t *testing.T) {
+	for _, cause := range []error{context.DeadlineExceeded, ErrNotConnected} {
+		err := fmt.Errorf("%w for call_service: %w", ErrNoResult, cause)
+		h, pub, rec := newTestCommandHandler(&fakeCaller{err: err})
+		res := h.Handle(context.Background(), commandMsg(t, commandNow, map[string]any{"domain": "lock", "service": "unlock"}))
+
+		if res.Decision != natsx.DecisionAck || res.Outcome != natsx.OutcomeFailure {
+			t.Errorf("%v: result = %+v, want ack with failure outcome", cause, res)
+		}
+		if _, d := resultOf(t, pub); d.Success || d.ErrorCode != resultUnknownCode {
+			t.Errorf("%v: result data = %+v, want %s", cause, d, resultUnknownCode)
+		}
+		if len(rec.records) != 1 || rec.records[0].outcome != "failure" {
+			t.Errorf("%v: audit = %+v, want one failure", cause, rec.records)
+		}
+		if !h.ids.(*fakeClaims).done["cmd1"] {
+			t.Errorf("%v: command not recorded as done", cause)
+		}
+	}
+}
+
+// TestCommandHandler_RedeliveredCommandRunsOnce verifies a command redelivered after
+// it ran (e.g. its ack was lost) is discarded by its claim, and one claimed by
+// another worker is deferred; neither calls HA again.
+func TestCommandHandler_RedeliveredCommandRunsOnce(t *testing.T) {
+	caller := &fakeCaller{}
+	h, pub, _ := newTestCommandHandler(caller)
+	msg := commandMsg(t, commandNow, map[string]any{"domain": "cover", "service": "open_cover"})
+
+	h.Handle(context.Background(), msg)
+	res := h.Handle(context.Background(), msg)
+	if res.Decision != natsx.DecisionAck || res.Outcome != natsx.OutcomeDuplicate {
+		t.Errorf("redelivery result = %+v, want ack as duplicate", res)
+	}
+
+	ids := h.ids.(*fakeClaims)
+	delete(ids.done, "cmd1")
+	ids.inFlight["cmd1"] = true
+	if res := h.Handle(context.Background(), msg); res.Decision != natsx.DecisionNak || res.Outcome != natsx.OutcomeInFlight {
+		t.Errorf("in-flight result = %+v, want nak as in flight", res)
+	}
+
+	if caller.calls != 1 || len(pub.msgs) != 1 {
+		t.Errorf("HA called %d times with %d results, want the command run and answered once", caller.calls, len(pub.msgs))
+	}
+}
+
+func TestCommandHandler_ExpiredCommandNotRun(t *testing.T) {
+	caller := &fakeCaller{}
+	h, pub, _ := newTestCommandHandler(caller)
+	res := h.Handle(context.Background(), commandMsg(t, commandNow.Add(-commandMaxAge-time.Second), map[string]any{"domain": "scene", "service": "turn_on"}))
+
+	if res.Decision != natsx.DecisionAck || caller.calls != 0 {
+		t.Fatalf("result = %+v after %d calls, want ack without calling HA", res, caller.calls)
+	}
+	if _, d := resultOf(t, pub); d.Success || d.ErrorCode != "expired" {
+		t.Errorf("result data = %+v, want expired failure", d)
+	}
+}
+
+func TestCommandHandler_InvalidCommandRejected(t *testing.T) {
+	caller := &fakeCaller{}
+	h, pub, _ := newTestCommandHandler(caller)
+	res := h.Handle(context.Background(), commandMsg(t, commandNow, map[string]any{"domain": "light"}))
+
+	if res.Decision != natsx.DecisionAck || res.Outcome != natsx.OutcomeRejected || caller.calls != 0 {
+		t.Fatalf("result = %+v after %d calls, want rejected without calling HA", res, caller.calls)
+	}
+	if len(pub.msgs) != 1 || pub.msgs[0].Subject != "rejected.ruby_engine.commands.ha_service.cmd1" {
+		t.Fatalf("expected the command on its rejected subject, got %d messages", len(pub.msgs))
+	}
+	if by := pub.msgs[0].Header.Get(natsx.HeaderRejectedBy); by != "gateway_ha_service" {
+		t.Errorf("%s = %q, want gateway_ha_service", natsx.HeaderRejectedBy, by)
+	}
+}
+
+func TestServiceTarget(t *testing.T) {
+	cases := []struct {
+		name string
+		d    schemas.CommandHAServiceData
+		want map[string]any
+	}{
+		{"none", schemas.CommandHAServiceData{}, nil},
+		{"entity only", schemas.CommandHAServiceData{EntityID: "light.porch"}, map[string]any{"entity_id": "light.porch"}},
+		{"area and entity", schemas.CommandHAServiceData{EntityID: "light.porch", Target: map[string]any{"area_id": "garden"}},
+			map[string]any{"entity_id": "light.porch", "area_id": "garden"}},
+		{"target entity wins", schemas.CommandHAServiceData{EntityID: "light.porch", Target: map[string]any{"entity_id": []any{"light.a", "light.b"}}},
+			map[string]any{"entity_id": []any{"light.a", "light.b"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := json.Marshal(serviceTarget(tc.d))
+			want, _ := json.Marshal(tc.want)
+			if string(got) != string(want) {
+				t.Errorf("serviceTarget = %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@
 // components for the Ruby Core gateway service.
 package ha
 
+import "sync"
+
 // Normalizer applies lean projection to HA entity attributes, dropping any
 // attribute not in the passlist for the given entity domain (ADR-0009).
 //
@@ -10,6 +12,7 @@ package ha
 // means all attributes are passed through (safe default for V0 operation when
 // the engine config has not yet been published to KV).
 type Normalizer struct {
+	mu       sync.RWMutex // guards passlist, which SetPasslist replaces
 	passlist map[string]map[string]struct{}
 }
 
@@ -17,15 +20,25 @@ type Normalizer struct {
 // config KV bucket. passlist maps entity domain → allowed attribute names.
 // Passing nil is safe: all attributes are passed through.
 func NewNormalizer(passlist map[string][]string) *Normalizer {
-	n := &Normalizer{passlist: make(map[string]map[string]struct{})}
+	n := &Normalizer{}
+	n.SetPasslist(passlist)
+	return n
+}
+
+// SetPasslist replaces the passlist, e.g. after the engine republishes its
+// compiled config. Safe to call concurrently with Apply.
+func (n *Normalizer) SetPasslist(passlist map[string][]string) {
+	sets := make(map[string]map[string]struct{}, len(passlist))
 	for domain, attrs := range passlist {
 		set := make(map[string]struct{}, len(attrs))
 		for _, a := range attrs {
 			set[a] = struct{}{}
 		}
-		n.passlist[domain] = set
+		sets[domain] = set
 	}
-	return n
+	n.mu.Lock()
+	n.passlist = sets
+	n.mu.Unlock()
 }
 
 // Apply filters attrs to only those allowed for the given entity domain.
@@ -33,7 +46,9 @@ func NewNormalizer(passlist map[string][]string) *Normalizer {
 // and most automations require it). If no passlist entry exists for the domain,
 // all attributes are passed through.
 func (n *Normalizer) Apply(domain string, attrs map[string]any) map[string]any {
+	n.mu.RLock()
 	allowed, hasList := n.passlist[domain]
+	n.mu.RUnlock()
 	if !hasList || len(allowed) == 0 {
 		return attrs // no filter configured: pass all through
 	}
@@ -68,3 +68,16 @@ func TestNormalizer_UnknownDomainPassesAll(t *testing.T) {
 		t.Errorf("unknown domain: expected 2 attrs, got %d: %v", len(got), got)
 	}
 }
+
+func TestNormalizer_SetPasslistReplacesFilter(t *testing.T) {
+	n := ha.NewNormalizer(map[string][]string{"person": {"battery"}})
+	n.SetPasslist(map[string][]string{"person": {"gps"}})
+
+	got := n.Apply("person", map[string]any{"state": "home", "battery": 82, "gps": "x"})
+	if _, ok := got["battery"]; ok {
+		t.Errorf("battery should be dropped after the passlist changed: %v", got)
+	}
+	if got["gps"] != "x" || got["state"] != "home" {
+		t.Errorf("got %v, want state and gps", got)
+	}
+}
@@ -3,6 +3,7 @@ package ha
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -20,99 +21,207 @@ type haStateResponse struct {
 	State       string         `json:"state"`
 	Attributes  map[string]any `json:"attributes"`
 	LastChanged string         `json:"last_changed"` // RFC3339 UTC
+	LastUpdated string         `json:"last_updated"` // RFC3339 UTC
 }
 
-// Reconciler fetches fresh state from HA for each critical entity and publishes
-// an update if the HA state is newer than the last CloudEvent stored in the
-// gateway_state KV bucket (ADR-0008).
+// Reconciler republishes the state of entities that changed while the HA WebSocket
+// was down: those whose HA last_changed is newer than the last CloudEvent stored in
+// the gateway_state KV bucket (ADR-0008). Critical entities are fetched one by one
+// over REST; with a domain allowlist, every entity of those domains is also checked
+// from a single get_states over the WebSocket (full reconciliation).
 type Reconciler struct {
-	haURL     string
-	haToken   string
-	stateKV   nats.KeyValue
-	norm      *Normalizer
-	publisher *gatewayNats.Publisher
-	log       *slog.Logger
-	client    *http.Client
+	haURL       string
+	haToken     string
+	fullDomains map[string]bool // empty: full reconciliation off
+	stateKV     nats.KeyValue
+	norm        *Normalizer
+	publisher   *gatewayNats.Publisher
+	log         *slog.Logger
+	client      *http.Client
 }
 
-// NewReconciler creates a Reconciler.
+// wsCaller issues HA WebSocket commands; *Client implements it.
+type wsCaller interface {
+	Call(ctx context.Context, cmdType string, fields map[string]any) (json.RawMessage, error)
+}
+
+// drift is an entity whose HA state is newer than its gateway_state entry, and the
+// entry's revision (0 when it has none), so the entry is only advanced if no live
+// event wrote it meanwhile.
+type drift struct {
+	state    haStateResponse
+	revision uint64
+}
+
+// NewReconciler creates a Reconciler. fullDomains opts in to full reconciliation of
+// the entities in those domains (e.g. "person", "light"); nil leaves it off.
 func NewReconciler(
 	haURL, haToken string,
+	fullDomains []string,
 	stateKV nats.KeyValue,
 	norm *Normalizer,
 	publisher *gatewayNats.Publisher,
 	log *slog.Logger,
 ) *Reconciler {
+	domains := make(map[string]bool, len(fullDomains))
+	for _, d := range fullDomains {
+		domains[d] = true
+	}
 	return &Reconciler{
-		haURL:     haURL,
-		haToken:   haToken,
-		stateKV:   stateKV,
-		norm:      norm,
-		publisher: publisher,
-		log:       log,
-		client:    &http.Client{Timeout: 10 * time.Second},
+		haURL:       haURL,
+		haToken:     haToken,
+		fullDomains: domains,
+		stateKV:     stateKV,
+		norm:        norm,
+		publisher:   publisher,
+		log:         log,
+		client:      &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
-// Run performs targeted reconciliation for each entity in criticalEntities.
-// It is called once after the HA WebSocket client successfully reconnects.
-func (r *Reconciler) Run(ctx context.Context, criticalEntities []string) {
-	if len(criticalEntities) == 0 {
-		r.log.Info("reconciler: no critical entities configured, skipping")
+// Run reconciles the allowlisted domains through ws, then each entity in
+// criticalEntities that full reconciliation did not cover. It is called once after
+// the HA WebSocket client successfully reconnects. A nil ws reconciles only
+// criticalEntities; a failed get_states leaves them to the REST path.
+func (r *Reconciler) Run(ctx context.Context, ws wsCaller, criticalEntities []string) {
+	full := ws != nil && len(r.fullDomains) > 0
+	if len(criticalEntities) == 0 && !full {
+		r.log.Info("reconciler: no critical entities or domains configured, skipping")
 		return
 	}
-	r.log.Info("reconciler: starting", slog.Int("entities", len(criticalEntities)))
+	r.log.Info("reconciler: starting",
+		slog.Int("entities", len(criticalEntities)),
+		slog.Bool("full", full),
+	)
 
+	// Updates are published as one batch: each is sent as soon as it is found, and
+	// the acks are awaited together at the end.
+	batch := r.publisher.Batch()
+	var drifted []drift
+	covered := make(map[string]bool)
+	if full {
+		states, err := r.fetchAllStates(ctx, ws)
+		if err != nil {
+			r.log.Warn("reconciler: full reconciliation failed, reconciling critical entities only",
+				slog.String("error", err.Error()))
+		}
+		for _, st := range states {
+			domain, _, err := SplitEntityID(st.EntityID)
+			if err != nil || !r.fullDomains[domain] {
+				continue
+			}
+			covered[st.EntityID] = true
+			drifted = r.reconcileState(ctx, batch, st, drifted)
+		}
+		r.log.Info("reconciler: full reconciliation checked entities", slog.Int("entities", len(covered)))
+	}
 	for _, entityID := range criticalEntities {
 		if ctx.Err() != nil {
-			return
+			break
+		}
+		if covered[entityID] {
+			continue
 		}
-		if err := r.reconcileOne(ctx, entityID); err != nil {
+		haState, err := r.fetchHAState(entityID)
+		if err != nil {
 			r.log.Warn("reconciler: entity reconcile failed",
 				slog.String("entity_id", entityID),
-				slog.String("error", err.Error()),
+				slog.String("error", fmt.Errorf("fetch HA state: %w", err).Error()),
 			)
+			continue
 		}
+		drifted = r.reconcileState(ctx, batch, *haState, drifted)
 	}
-	r.log.Info("reconciler: complete")
+	if err := batch.Wait(ctx); err != nil {
+		r.log.Warn("reconciler: publish updates failed", slog.String("error", err.Error()))
+		return
+	}
+	r.recordLastSeen(drifted)
+	r.log.Info("reconciler: complete", slog.Int("published", len(drifted)))
 }
 
-// reconcileOne reconciles a single entity.
-func (r *Reconciler) reconcileOne(ctx context.Context, entityID string) error {
-	haState, err := r.fetchHAState(entityID)
+// reconcileState publishes st to batch, flagged as reconciled, if HA's state is
+// newer than the gateway_state entry (or there is none), and returns drifted with
+// it appended. Failures are logged and leave drifted unchanged.
+func (r *Reconciler) reconcileState(ctx context.Context, batch *gatewayNats.Batch, st haStateResponse, drifted []drift) []drift {
+	d, ok, err := r.checkDrift(st)
+	if err == nil && ok {
+		// HA is newer (or we have no record): publish an update.
+		r.log.Info("reconciler: publishing update for entity",
+			slog.String("entity_id", st.EntityID),
+			slog.String("ha_last_changed", st.LastChanged),
+		)
+		domain, _, _ := SplitEntityID(st.EntityID) // checked by checkDrift
+		filtered := r.norm.Apply(domain, st.Attributes)
+		err = batch.PublishReconciledHAEvent(ctx, st.EntityID, st.State, filtered, st.LastChanged, st.LastUpdated)
+		if err == nil {
+			return append(drifted, d)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("fetch HA state: %w", err)
+		r.log.Warn("reconciler: entity reconcile failed",
+			slog.String("entity_id", st.EntityID),
+			slog.String("error", err.Error()),
+		)
 	}
+	return drifted
+}
 
-	haTime, err := ParseUTC(haState.LastChanged)
+// checkDrift reports whether st is newer than the entity's gateway_state entry.
+func (r *Reconciler) checkDrift(st haStateResponse) (drift, bool, error) {
+	if _, _, err := SplitEntityID(st.EntityID); err != nil {
+		return drift{}, false, err
+	}
+	haTime, err := ParseUTC(st.LastChanged)
 	if err != nil {
-		return fmt.Errorf("parse HA last_changed: %w", err)
+		return drift{}, false, fmt.Errorf("parse HA last_changed: %w", err)
 	}
-
-	kvTime, err := r.loadLastSeen(entityID)
+	kvTime, rev, err := r.loadLastSeen(st.EntityID)
 	if err != nil {
-		return fmt.Errorf("load KV timestamp: %w", err)
+		return drift{}, false, fmt.Errorf("load KV timestamp: %w", err)
 	}
-
 	if kvTime != nil && !haTime.After(*kvTime) {
 		r.log.Debug("reconciler: entity up to date, skipping",
-			slog.String("entity_id", entityID),
+			slog.String("entity_id", st.EntityID),
 		)
-		return nil
+		return drift{}, false, nil
 	}
+	return drift{state: st, revision: rev}, true, nil
+}
 
-	// HA is newer (or we have no record): publish an update.
-	r.log.Info("reconciler: publishing update for entity",
-		slog.String("entity_id", entityID),
-		slog.String("ha_last_changed", haState.LastChanged),
-	)
+// recordLastSeen advances the gateway_state entry of each published entity to the
+// state it was reconciled to. An entry a live state_changed event wrote since it was
+// read already holds a newer timestamp and is left alone.
+func (r *Reconciler) recordLastSeen(drifted []drift) {
+	for _, d := range drifted {
+		value := []byte(d.state.LastChanged)
+		var err error
+		if d.revision == 0 {
+			_, err = r.stateKV.Create(d.state.EntityID, value)
+		} else {
+			_, err = r.stateKV.Update(d.state.EntityID, value, d.revision)
+		}
+		if err != nil && !errors.Is(err, nats.ErrKeyExists) {
+			r.log.Warn("reconciler: stateKV update failed",
+				slog.String("entity_id", d.state.EntityID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
 
-	domain, _, err := SplitEntityID(entityID)
+// fetchAllStates issues get_states over the HA WebSocket and returns every entity's
+// current state.
+func (r *Reconciler) fetchAllStates(ctx context.Context, ws wsCaller) ([]haStateResponse, error) {
+	result, err := ws.Call(ctx, "get_states", nil)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("get_states: %w", err)
+	}
+	var states []haStateResponse
+	if err := json.Unmarshal(result, &states); err != nil {
+		return nil, fmt.Errorf("unmarshal get_states result: %w", err)
 	}
-	filtered := r.norm.Apply(domain, haState.Attributes)
-	return r.publisher.PublishHAEvent(ctx, entityID, haState.State, filtered, haState.LastChanged)
+	return states, nil
 }
 
 // fetchHAState calls the HA REST API to retrieve the current state of an entity.
@@ -148,20 +257,21 @@ func (r *Reconciler) fetchHAState(entityID string) (*haStateResponse, error) {
 }
 
 // loadLastSeen retrieves the timestamp of the last CloudEvent published for
-// an entity from the gateway_state KV bucket. Returns nil if no entry exists.
-func (r *Reconciler) loadLastSeen(entityID string) (*time.Time, error) {
+// an entity from the gateway_state KV bucket, and the entry's revision. Returns nil
+// and revision 0 if no entry exists.
+func (r *Reconciler) loadLastSeen(entityID string) (*time.Time, uint64, error) {
 	entry, err := r.stateKV.Get(entityID)
 	if err != nil {
 		if err == nats.ErrKeyNotFound {
-			return nil, nil
+			return nil, 0, nil
 		}
-		return nil, err
+		return nil, 0, err
 	}
 	t, err := ParseUTC(string(entry.Value()))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return &t, nil
+	return &t, entry.Revision(), nil
 }
 
 // ParseUTC parses a time string as RFC3339, normalising to UTC.
@@ -3,10 +3,24 @@
 package ha_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/services/gateway/ha"
+	gatewayNats "github.com/primaryrutabaga/ruby-core/services/gateway/nats"
 )
 
 func TestParseUTC_RFC3339(t *testing.T) {
@@ -78,3 +92,200 @@ func TestSplitEntityID_Invalid(t *testing.T) {
 		t.Fatal("expected error for entity ID without dot, got nil")
 	}
 }
+
+// fakeStream acks every async publish and records the message.
+type fakeStream struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (f *fakeStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append(f.msgs, m)
+	return &nats.PubAck{Stream: "HA_EVENTS"}, nil
+}
+
+func (f *fakeStream) PublishMsgAsync(m *nats.Msg, opts ...nats.PubOpt) (nats.PubAckFuture, error) {
+	pa, _ := f.PublishMsg(m, opts...)
+	fut := &ackedFuture{ok: make(chan *nats.PubAck, 1), msg: m}
+	fut.ok <- pa
+	return fut, nil
+}
+
+type ackedFuture struct {
+	ok  chan *nats.PubAck
+	msg *nats.Msg
+}
+
+func (f *ackedFuture) Ok() <-chan *nats.PubAck { return f.ok }
+func (f *ackedFuture) Err() <-chan error       { return nil }
+func (f *ackedFuture) Msg() *nats.Msg          { return f.msg }
+
+// published returns the data of each published event by its subject's entity.
+func (f *fakeStream) published(t *testing.T) map[string]map[string]any {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]map[string]any)
+	for _, m := range f.msgs {
+		var evt struct {
+			Subject string         `json:"subject"`
+			Data    map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(m.Data, &evt); err != nil {
+			t.Fatalf("decode %s: %v", m.Subject, err)
+		}
+		out[evt.Subject] = evt.Data
+	}
+	return out
+}
+
+// fakeStateKV is a gateway_state bucket holding value/revision pairs. Update honours
+// the expected revision as JetStream does.
+type fakeStateKV struct {
+	nats.KeyValue
+	entries map[string]fakeEntry
+}
+
+type fakeEntry struct {
+	nats.KeyValueEntry
+	value    string
+	revision uint64
+}
+
+func (e fakeEntry) Value() []byte    { return []byte(e.value) }
+func (e fakeEntry) Revision() uint64 { return e.revision }
+
+func (kv *fakeStateKV) Get(key string) (nats.KeyValueEntry, error) {
+	e, ok := kv.entries[key]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+	return e, nil
+}
+
+func (kv *fakeStateKV) Create(key string, value []byte) (uint64, error) {
+	return kv.Update(key, value, 0)
+}
+
+func (kv *fakeStateKV) Update(key string, value []byte, last uint64) (uint64, error) {
+	if kv.entries[key].revision != last {
+		return 0, nats.ErrKeyExists
+	}
+	kv.entries[key] = fakeEntry{value: string(value), revision: last + 100}
+	return last + 100, nil
+}
+
+// statesCaller answers get_states with states, or fails with err.
+type statesCaller struct {
+	states []map[string]any
+	err    error
+}
+
+func (c statesCaller) Call(_ context.Context, cmdType string, _ map[string]any) (json.RawMessage, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if cmdType != "get_states" {
+		return nil, errors.New("unexpected command " + cmdType)
+	}
+	return json.Marshal(c.states)
+}
+
+func haState(entityID, state, lastChanged string) map[string]any {
+	return map[string]any{"entity_id": entityID, "state": state, "last_changed": lastChanged,
+		"last_updated": lastChanged, "attributes": map[string]any{"friendly_name": entityID}}
+}
+
+// restStates serves /api/states/{entity_id} from states and records which entities
+// were fetched.
+func restStates(t *testing.T, states ...map[string]any) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var fetched []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, st := range states {
+			if r.URL.Path == "/api/states/"+st["entity_id"].(string) {
+				mu.Lock()
+				fetched = append(fetched, st["entity_id"].(string))
+				mu.Unlock()
+				_ = json.NewEncoder(w).Encode(st)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetched
+}
+
+const (
+	seen  = "2026-10-17T08:00:00Z"
+	newer = "2026-10-17T09:00:00Z"
+)
+
+// TestReconciler_FullReconciliation verifies a full run publishes, flagged as
+// reconciled, every allowlisted entity newer in HA than in gateway_state, fetches
+// only uncovered critical entities over REST, and advances gateway_state.
+func TestReconciler_FullReconciliation(t *testing.T) {
+	kv := &fakeStateKV{entries: map[string]fakeEntry{
+		"light.porch":   {value: seen, revision: 5},
+		"light.kitchen": {value: seen, revision: 6},
+	}}
+	srv, fetched := restStates(t, haState("switch.pump", "on", newer))
+	stream := &fakeStream{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := ha.NewReconciler(srv.URL, "token", []string{"light", "person"}, kv,
+		ha.NewNormalizer(nil), gatewayNats.New(nil, natsx.NewAckedPublisher(stream)), log)
+
+	ws := statesCaller{states: []map[string]any{
+		haState("light.porch", "on", newer),   // drifted
+		haState("light.kitchen", "off", seen), // up to date
+		haState("person.sam", "home", newer),  // never seen
+		haState("sensor.temp", "21", newer),   // outside the allowlist
+	}}
+	r.Run(context.Background(), ws, []string{"light.porch", "switch.pump"})
+
+	got := stream.published(t)
+	want := []string{"light.porch", "person.sam", "switch.pump"}
+	if keys := slices.Sorted(maps.Keys(got)); !slices.Equal(keys, want) {
+		t.Fatalf("published %v, want %v", keys, want)
+	}
+	for id, data := range got {
+		if data["reconciled"] != true || data["last_changed"] != newer {
+			t.Errorf("%s data = %v, want reconciled at %s", id, data, newer)
+		}
+	}
+	if !slices.Equal(*fetched, []string{"switch.pump"}) {
+		t.Errorf("fetched over REST %v, want only the uncovered critical switch.pump", *fetched)
+	}
+	for _, id := range want {
+		if kv.entries[id].value != newer {
+			t.Errorf("gateway_state %s = %q, want %s", id, kv.entries[id].value, newer)
+		}
+	}
+	if kv.entries["light.kitchen"].revision != 6 {
+		t.Error("gateway_state light.kitchen rewritten though up to date")
+	}
+}
+
+// TestReconciler_GetStatesFailsFallsBackToCritical verifies a failed get_states
+// still reconciles the critical entities over REST.
+func TestReconciler_GetStatesFailsFallsBackToCritical(t *testing.T) {
+	kv := &fakeStateKV{entries: map[string]fakeEntry{}}
+	srv, fetched := restStates(t, haState("light.porch", "on", newer))
+	stream := &fakeStream{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := ha.NewReconciler(srv.URL, "token", []string{"light"}, kv,
+		ha.NewNormalizer(nil), gatewayNats.New(nil, natsx.NewAckedPublisher(stream)), log)
+
+	r.Run(context.Background(), statesCaller{err: ha.ErrNotConnected}, []string{"light.porch"})
+
+	if !slices.Equal(*fetched, []string{"light.porch"}) {
+		t.Errorf("fetched over REST %v, want light.porch", *fetched)
+	}
+	if got := stream.published(t); got["light.porch"]["reconciled"] != true {
+		t.Errorf("published %v, want light.porch reconciled", got)
+	}
+}
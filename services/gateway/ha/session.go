@@ -0,0 +1,266 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// callTimeout bounds a WebSocket command whose context has no deadline of its own.
+	callTimeout = 10 * time.Second
+
+	// eventQueueSize is how many events the reader buffers for the event loop. A full
+	// queue stalls the reader, and with it command results, until the loop catches up.
+	eventQueueSize = 256
+)
+
+// ErrNotConnected is returned by commands issued while the HA WebSocket is down, or
+// cut off by a disconnect. The command can be retried once the client reconnects.
+var ErrNotConnected = errors.New("ha: websocket not connected")
+
+// ErrNoResult is wrapped by the error of a command that was written to HA but got no
+// result, because the wait timed out or the connection dropped. HA may have run it,
+// so retrying could run it twice.
+var ErrNoResult = errors.New("ha: no result")
+
+// CommandError is Home Assistant refusing a WebSocket command, e.g. call_service for
+// an unknown service or an invalid target. Retrying the same command cannot succeed.
+type CommandError struct {
+	Type    string // the command's type, e.g. "call_service"
+	Code    string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("ha: %s failed: %s (%s)", e.Type, e.Message, e.Code)
+}
+
+// haError is the error member of a failed HA WebSocket result.
+type haError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// eventHandler handles one event of a subscription.
+type eventHandler func(ctx context.Context, ev *haEvent) error
+
+// session is one authenticated HA WebSocket connection, multiplexed by message ID.
+// A single reader goroutine (read) routes each command result to the caller waiting
+// on its ID and queues each event, tagged with its subscription's ID, for the event
+// loop, so any number of goroutines can issue commands without the loop losing
+// events to them.
+type session struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int // HA requires IDs to increase per connection
+	pending  map[int]chan haWSMessage
+	handlers map[int]eventHandler // by subscription ID
+	closed   bool
+
+	done chan struct{} // closed when read returns
+	err  error         // why read returned; set before done is closed
+}
+
+func newSession(conn *websocket.Conn) *session {
+	return &session{
+		conn:     conn,
+		nextID:   1,
+		pending:  make(map[int]chan haWSMessage),
+		handlers: make(map[int]eventHandler),
+		done:     make(chan struct{}),
+	}
+}
+
+// read reads the connection until it fails, delivering results and sending events
+// to events, which it closes on return. Waiting commands then fail with
+// ErrNotConnected.
+func (s *session) read(events chan<- haWSMessage) {
+	defer close(s.done)
+	defer close(events)
+	defer s.close()
+	for {
+		var msg haWSMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			s.err = err
+			return
+		}
+		switch msg.Type {
+		case "result":
+			s.deliver(msg)
+		case "event":
+			if msg.Event != nil {
+				events <- msg
+			}
+		}
+	}
+}
+
+// send writes a command of type typ with fields and a fresh ID, and waits for its
+// result until ctx is done or the session closes. A non-nil h is registered as the
+// handler for events carrying the command's ID before the command is written, so a
+// subscription's first event cannot arrive unhandled.
+func (s *session) send(ctx context.Context, typ string, fields map[string]any, h eventHandler) (haWSMessage, error) {
+	ch := make(chan haWSMessage, 1)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return haWSMessage{}, ErrNotConnected
+	}
+	id := s.nextID
+	s.nextID++
+	s.pending[id] = ch
+	if h != nil {
+		s.handlers[id] = h
+	}
+	s.mu.Unlock()
+	defer s.forget(id)
+
+	cmd := maps.Clone(fields)
+	if cmd == nil {
+		cmd = make(map[string]any, 2)
+	}
+	cmd["id"], cmd["type"] = id, typ
+	s.writeMu.Lock()
+	err := s.conn.WriteJSON(cmd)
+	s.writeMu.Unlock()
+	if err != nil {
+		s.unsubscribe(id)
+		return haWSMessage{}, fmt.Errorf("ha: write %s: %w", typ, err)
+	}
+
+	select {
+	case res, ok := <-ch:
+		if !ok {
+			return haWSMessage{}, fmt.Errorf("%w for %s: %w", ErrNoResult, typ, ErrNotConnected)
+		}
+		if !res.Success {
+			s.unsubscribe(id)
+		}
+		return res, nil
+	case <-ctx.Done():
+		s.unsubscribe(id)
+		return haWSMessage{}, fmt.Errorf("%w for %s: %w", ErrNoResult, typ, ctx.Err())
+	}
+}
+
+// call sends a command and returns its result member, or a *CommandError if HA
+// refused it. Without a deadline on ctx it waits at most callTimeout.
+func (s *session) call(ctx context.Context, typ string, fields map[string]any) (json.RawMessage, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callTimeout)
+		defer cancel()
+	}
+	res, err := s.send(ctx, typ, fields, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Success {
+		return nil, commandError(typ, res)
+	}
+	return res.Result, nil
+}
+
+// subscribe subscribes to HA events of eventType, passing each to h.
+func (s *session) subscribe(ctx context.Context, eventType string, h eventHandler) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callTimeout)
+		defer cancel()
+	}
+	res, err := s.send(ctx, "subscribe_events", map[string]any{"event_type": eventType}, h)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return commandError("subscribe_events "+eventType, res)
+	}
+	return nil
+}
+
+// handler returns the handler of the subscription with ID id, or nil.
+func (s *session) handler(id int) eventHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handlers[id]
+}
+
+func (s *session) forget(id int) {
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
+}
+
+func (s *session) unsubscribe(id int) {
+	s.mu.Lock()
+	delete(s.handlers, id)
+	s.mu.Unlock()
+}
+
+// deliver hands a result to the caller waiting on its ID, if one still is.
+func (s *session) deliver(msg haWSMessage) {
+	s.mu.Lock()
+	ch, ok := s.pending[msg.ID]
+	delete(s.pending, msg.ID)
+	s.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// close fails every waiting command with ErrNotConnected and refuses new ones.
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for id, ch := range s.pending {
+		close(ch)
+		delete(s.pending, id)
+	}
+}
+
+func commandError(typ string, res haWSMessage) *CommandError {
+	e := &CommandError{Type: typ, Code: "unknown_error", Message: "unknown error"}
+	if res.Error != nil {
+		e.Code, e.Message = res.Error.Code, res.Error.Message
+	}
+	return e
+}
+
+// Call issues a WebSocket command of type cmdType (e.g. "get_states",
+// "config/auth/list") with fields as its members and returns the result member of
+// HA's response. It is safe for concurrent use and never consumes events. Without a
+// deadline on ctx it waits at most 10s. It returns ErrNotConnected while the
+// WebSocket is down, an error wrapping ErrNoResult when the command was written but
+// not answered, and a *CommandError when HA refuses the command.
+func (c *Client) Call(ctx context.Context, cmdType string, fields map[string]any) (json.RawMessage, error) {
+	s := c.session.Load()
+	if s == nil {
+		return nil, ErrNotConnected
+	}
+	return s.call(ctx, cmdType, fields)
+}
+
+// CallService runs HA's call_service: service in domain, on target (entity_id,
+// device_id or area_id), with data as the service data. target and data may be nil.
+// Errors are as for Call.
+func (c *Client) CallService(ctx context.Context, domain, service string, target, data map[string]any) error {
+	fields := map[string]any{"domain": domain, "service": service}
+	if len(target) > 0 {
+		fields["target"] = target
+	}
+	if len(data) > 0 {
+		fields["service_data"] = data
+	}
+	_, err := c.Call(ctx, "call_service", fields)
+	return err
+}
@@ -0,0 +1,188 @@
+//go:build fast
+
+package ha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// haServer accepts one WebSocket and answers each command it reads with the messages
+// reply returns, in order.
+func haServer(t *testing.T, reply func(cmd map[string]any) []map[string]any) string {
+	t.Helper()
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			for _, msg := range reply(cmd) {
+				if err := conn.WriteJSON(msg); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// result is HA's reply to cmd, successful when errCode is empty.
+func result(cmd map[string]any, errCode string, res any) []map[string]any {
+	msg := map[string]any{"id": cmd["id"], "type": "result", "success": errCode == ""}
+	if errCode != "" {
+		msg["error"] = map[string]any{"code": errCode, "message": "refused"}
+	}
+	if res != nil {
+		msg["result"] = res
+	}
+	return []map[string]any{msg}
+}
+
+// dialSession dials url and starts a session's reader, returning the session and
+// the events it queues.
+func dialSession(t *testing.T, url string) (*session, <-chan haWSMessage) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	sess := newSession(conn)
+	events := make(chan haWSMessage, eventQueueSize)
+	go sess.read(events)
+	return sess, events
+}
+
+// connectSession returns a Client on a session to url whose events are dropped.
+func connectSession(t *testing.T, url string) *Client {
+	t.Helper()
+	sess, events := dialSession(t, url)
+	go func() {
+		for range events {
+		}
+	}()
+	c := &Client{}
+	c.session.Store(sess)
+	return c
+}
+
+func TestCallService_Success(t *testing.T) {
+	var got map[string]any
+	url := haServer(t, func(cmd map[string]any) []map[string]any {
+		got = cmd
+		return result(cmd, "", nil)
+	})
+	c := connectSession(t, url)
+
+	err := c.CallService(context.Background(), "light", "turn_on",
+		map[string]any{"entity_id": "light.porch"}, map[string]any{"brightness": 128})
+	if err != nil {
+		t.Fatalf("CallService: %v", err)
+	}
+	if got["type"] != "call_service" || got["domain"] != "light" || got["service"] != "turn_on" || got["id"] != float64(1) {
+		t.Errorf("command = %v", got)
+	}
+	if got["target"].(map[string]any)["entity_id"] != "light.porch" || got["service_data"].(map[string]any)["brightness"] != float64(128) {
+		t.Errorf("command target/service_data = %v/%v", got["target"], got["service_data"])
+	}
+}
+
+func TestCallService_Refused(t *testing.T) {
+	url := haServer(t, func(cmd map[string]any) []map[string]any {
+		return result(cmd, "service_not_found", nil)
+	})
+	c := connectSession(t, url)
+
+	var ce *CommandError
+	err := c.CallService(context.Background(), "light", "explode", nil, nil)
+	if !errors.As(err, &ce) || ce.Code != "service_not_found" || ce.Type != "call_service" {
+		t.Fatalf("CallService = %v, want a call_service service_not_found CommandError", err)
+	}
+}
+
+func TestCallService_NotConnected(t *testing.T) {
+	if err := (&Client{}).CallService(context.Background(), "light", "turn_on", nil, nil); !errors.Is(err, ErrNotConnected) || errors.Is(err, ErrNoResult) {
+		t.Errorf("CallService without a session = %v, want ErrNotConnected before the write", err)
+	}
+}
+
+func TestCallService_TimesOutAndFailsOnDisconnect(t *testing.T) {
+	url := haServer(t, func(map[string]any) []map[string]any { return nil }) // never answers
+	c := connectSession(t, url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.CallService(ctx, "light", "turn_on", nil, nil); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrNoResult) {
+		t.Errorf("unanswered CallService = %v, want DeadlineExceeded after the write", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.CallService(context.Background(), "light", "turn_on", nil, nil) }()
+	time.Sleep(20 * time.Millisecond)
+	c.session.Load().close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrNotConnected) || !errors.Is(err, ErrNoResult) {
+			t.Errorf("CallService across a disconnect = %v, want ErrNotConnected after the write", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CallService still waiting after the session closed")
+	}
+}
+
+// TestSession_EventsDuringCall verifies an event HA sends while a command awaits its
+// result is queued for the event loop under its subscription's handler, and the
+// command still gets its result.
+func TestSession_EventsDuringCall(t *testing.T) {
+	var subID any
+	url := haServer(t, func(cmd map[string]any) []map[string]any {
+		if cmd["type"] == "subscribe_events" {
+			subID = cmd["id"]
+			return result(cmd, "", nil)
+		}
+		event := map[string]any{"id": subID, "type": "event", "event": map[string]any{"event_type": "state_changed"}}
+		return append([]map[string]any{event}, result(cmd, "", []string{"sun.sun"})...)
+	})
+	sess, events := dialSession(t, url)
+
+	var handled string
+	if err := sess.subscribe(context.Background(), "state_changed", func(_ context.Context, ev *haEvent) error {
+		handled = ev.EventType
+		return nil
+	}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	res, err := sess.call(context.Background(), "get_states", nil)
+	if err != nil || string(res) != `["sun.sun"]` {
+		t.Fatalf("call = %s, %v; want the result despite the event ahead of it", res, err)
+	}
+	select {
+	case msg := <-events:
+		h := sess.handler(msg.ID)
+		if h == nil {
+			t.Fatalf("no handler for subscription %d", msg.ID)
+		}
+		_ = h(context.Background(), msg.Event)
+		if handled != "state_changed" {
+			t.Errorf("handled %q, want state_changed", handled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event was not queued")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -99,7 +100,21 @@ func main() {
 		}
 	}
 
-	gateway, err := app.New(haCfg.URL, haCfg.Token, nc, logger)
+	// Full-state reconciliation is opt-in: HA_RECONCILE_DOMAINS lists the entity
+	// domains (comma-separated, e.g. "person,light,binary_sensor") whose drifted
+	// entities are republished from one get_states after each reconnect. Critical
+	// entities are reconciled either way.
+	var reconcileDomains []string
+	for _, d := range strings.Split(os.Getenv("HA_RECONCILE_DOMAINS"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			reconcileDomains = append(reconcileDomains, d)
+		}
+	}
+	if len(reconcileDomains) > 0 {
+		logger.Info("full-state reconciliation enabled", slog.Any("domains", reconcileDomains))
+	}
+
+	gateway, err := app.New(haCfg.URL, haCfg.Token, reconcileDomains, nc, logger)
 	if err != nil {
 		logger.Error("gateway: init failed", slog.String("error", err.Error()))
 		os.Exit(1)
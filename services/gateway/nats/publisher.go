@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	goNats "github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,37 +18,85 @@ import (
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
-// Publisher wraps a NATS connection and publishes HA events and gateway
-// health heartbeats as CloudEvents.
+// stateNamespace scopes the deterministic IDs of HA state events.
+var stateNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("ruby_gateway/ha_state"))
+
+// Publisher publishes HA events and gateway health heartbeats as CloudEvents. HA
+// events go through JetStream and are acknowledged by HA_EVENTS; heartbeats, which
+// no stream captures, are core NATS publishes.
 type Publisher struct {
 	nc             *goNats.Conn
+	pub            *natsx.AckedPublisher
 	eventsReceived metric.Int64Counter // ruby_core_ha_events_received_total{entity_domain}
 }
 
-// New returns a Publisher for the given NATS connection. It registers the
-// ha-events-received counter under the global MeterProvider (no-op without otel.Init).
-func New(nc *goNats.Conn) *Publisher {
+// New returns a Publisher that publishes HA events through pub and heartbeats on nc.
+// It registers the ha-events-received counter under the global MeterProvider (no-op
+// without otel.Init).
+func New(nc *goNats.Conn, pub *natsx.AckedPublisher) *Publisher {
 	eventsReceived, _ := otel.Meter("github.com/primaryrutabaga/ruby-core/services/gateway").Int64Counter(
 		"ruby_core_ha_events_received_total",
 		metric.WithDescription("Home Assistant state_changed events ingested and published, by entity domain"),
 	)
-	return &Publisher{nc: nc, eventsReceived: eventsReceived}
+	return &Publisher{nc: nc, pub: pub, eventsReceived: eventsReceived}
+}
+
+// PrevState is an entity's state before a state_changed event, lean-projected like
+// the new state.
+type PrevState struct {
+	State       string
+	Attributes  map[string]any // filtered attribute map (post lean projection)
+	LastChanged string
 }
 
 // PublishHAEvent publishes a HA state_changed event as a CloudEvent to
-// ha.events.{domain}.{entityName}.
+// ha.events.{domain}.{entityName}. Its id, and so its Nats-Msg-Id, is stateEventID:
+// the same HA state published twice — live and by the reconciler, or again after a
+// gateway restart — is one event to the stream and to the engine's idempotency store.
 //
 //   - entityID: the HA entity ID, e.g. "person.wife"
 //   - state:    the new entity state string, e.g. "home"
 //   - attrs:    the filtered attribute map (post lean projection)
 //   - lastChanged: the HA last_changed timestamp (RFC3339 UTC)
-func (p *Publisher) PublishHAEvent(ctx context.Context, entityID, state string, attrs map[string]any, lastChanged string) error {
+//   - lastUpdated: the HA last_updated timestamp (RFC3339 UTC), part of the id only
+//   - old:      the previous state, published as data.old_state
+//     (schemas.DataOldState); nil when HA reported none
+func (p *Publisher) PublishHAEvent(ctx context.Context, entityID, state string, attrs map[string]any, lastChanged, lastUpdated string, old *PrevState) error {
+	return p.publishHAEvent(ctx, p.pub, entityID, state, attrs, lastChanged, lastUpdated, old, false)
+}
+
+// Batch returns a Batch for publishing a burst of HA events without waiting for each
+// ack in turn.
+func (p *Publisher) Batch() *Batch {
+	return &Batch{p: p, b: p.pub.Batch()}
+}
+
+// Batch publishes HA events asynchronously within natsx.Batch's pending window.
+type Batch struct {
+	p *Publisher
+	b *natsx.Batch
+}
+
+// PublishReconciledHAEvent is Publisher.PublishHAEvent without waiting for the ack,
+// for a state the reconciler caught up on rather than one HA pushed: its data carries
+// "reconciled": true, so processors can tell a catch-up from a live transition.
+func (b *Batch) PublishReconciledHAEvent(ctx context.Context, entityID, state string, attrs map[string]any, lastChanged, lastUpdated string) error {
+	return b.p.publishHAEvent(ctx, b.b, entityID, state, attrs, lastChanged, lastUpdated, nil, true)
+}
+
+// Wait returns once every event in the batch is acknowledged, or with the errors of
+// those that could not be published.
+func (b *Batch) Wait(ctx context.Context) error {
+	return b.b.Wait(ctx)
+}
+
+func (p *Publisher) publishHAEvent(ctx context.Context, pub natsx.MsgPublisher, entityID, state string, attrs map[string]any, lastChanged, lastUpdated string, old *PrevState, reconciled bool) error {
 	domain, entityName, err := splitEntityID(entityID)
 	if err != nil {
 		return err
 	}
 
-	id := newID()
+	id := stateEventID(entityID, state, lastChanged, lastUpdated)
 	evt := schemas.CloudEvent{
 		SpecVersion:   schemas.CloudEventsSpecVersion,
 		ID:            id,
@@ -61,12 +110,13 @@ func (p *Publisher) PublishHAEvent(ctx context.Context, entityID, state string,
 	}
 
 	// Merge state into attrs for a complete data payload.
-	data := make(map[string]any, len(attrs)+1)
-	for k, v := range attrs {
-		data[k] = v
+	data := stateData(state, attrs, lastChanged)
+	if old != nil {
+		data[schemas.DataOldState] = stateData(old.State, old.Attributes, old.LastChanged)
+	}
+	if reconciled {
+		data["reconciled"] = true
 	}
-	data["state"] = state
-	data["last_changed"] = lastChanged
 	evt.Data = data
 
 	payload, err := json.Marshal(evt)
@@ -75,7 +125,7 @@ func (p *Publisher) PublishHAEvent(ctx context.Context, entityID, state string,
 	}
 
 	subject := fmt.Sprintf("ha.events.%s.%s", domain, entityName)
-	if err := natsx.PublishWithContext(ctx, p.nc, subject, payload); err != nil {
+	if err := natsx.PublishWithContext(ctx, pub, subject, payload); err != nil {
 		return err
 	}
 	if p.eventsReceived != nil {
@@ -84,6 +134,37 @@ func (p *Publisher) PublishHAEvent(ctx context.Context, entityID, state string,
 	return nil
 }
 
+// stateData merges state and last_changed into attrs for a complete data payload.
+func stateData(state string, attrs map[string]any, lastChanged string) map[string]any {
+	data := make(map[string]any, len(attrs)+4)
+	for k, v := range attrs {
+		data[k] = v
+	}
+	data["state"] = state
+	data["last_changed"] = lastChanged
+	return data
+}
+
+// stateEventID derives the CloudEvent id of an HA state from the state itself: the
+// entity, its last_changed and last_updated (normalised to UTC) and its state. HA keeps
+// last_changed on an attribute-only update but moves last_updated, so an update that
+// bounces an attribute back to an earlier value (A→B→A) is not mistaken for the first
+// A. The attributes are left out: they are published after the lean projection, and a
+// passlist reload must not give the same HA state a new id.
+func stateEventID(entityID, state, lastChanged, lastUpdated string) string {
+	key := entityID + "@" + utcTime(lastChanged) + "/" + utcTime(lastUpdated) + "=" + state
+	return uuid.NewSHA1(stateNamespace, []byte(key)).String()
+}
+
+// utcTime normalises an RFC 3339 timestamp to UTC, so HA's "+00:00" and "Z" forms of
+// one instant are equal. A value that does not parse is returned unchanged.
+func utcTime(s string) string {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return s
+}
+
 // PublishHealth publishes a gateway health heartbeat CloudEvent to the
 // "gateway.health" NATS subject every tick from the caller's goroutine.
 func (p *Publisher) PublishHealth(haConnected bool) error {
@@ -0,0 +1,186 @@
+//go:build fast
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+
+	goNats "github.com/nats-io/nats.go"
+
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
+	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
+)
+
+// fakeStream acks every publish and records the message.
+type fakeStream struct {
+	mu   sync.Mutex
+	msgs []*goNats.Msg
+}
+
+func (f *fakeStream) PublishMsg(m *goNats.Msg, _ ...goNats.PubOpt) (*goNats.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append(f.msgs, m)
+	return &goNats.PubAck{Stream: "HA_EVENTS"}, nil
+}
+
+func (f *fakeStream) PublishMsgAsync(m *goNats.Msg, opts ...goNats.PubOpt) (goNats.PubAckFuture, error) {
+	pa, _ := f.PublishMsg(m, opts...)
+	fut := &ackedFuture{ok: make(chan *goNats.PubAck, 1), msg: m}
+	fut.ok <- pa
+	return fut, nil
+}
+
+type ackedFuture struct {
+	ok  chan *goNats.PubAck
+	msg *goNats.Msg
+}
+
+func (f *ackedFuture) Ok() <-chan *goNats.PubAck { return f.ok }
+func (f *ackedFuture) Err() <-chan error         { return nil }
+func (f *ackedFuture) Msg() *goNats.Msg          { return f.msg }
+
+// TestPublishHAEvent_DeterministicID verifies the live and reconciled publish of one
+// HA state carry the same CloudEvent id and Nats-Msg-Id, though only the live one
+// knows the previous state.
+func TestPublishHAEvent_DeterministicID(t *testing.T) {
+	stream := &fakeStream{}
+	p := New(nil, natsx.NewAckedPublisher(stream))
+	ctx := context.Background()
+	attrs := map[string]any{"brightness": float64(128)}
+
+	old := &PrevState{State: "off", LastChanged: "2026-10-17T08:00:00Z"}
+	if err := p.PublishHAEvent(ctx, "light.porch", "on", attrs, "2026-10-17T09:00:00.5+00:00", "2026-10-17T09:00:00.5+00:00", old); err != nil {
+		t.Fatal(err)
+	}
+	b := p.Batch()
+	if err := b.PublishReconciledHAEvent(ctx, "light.porch", "on", attrs, "2026-10-17T09:00:00.5Z", "2026-10-17T09:00:00.5Z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.msgs) != 2 {
+		t.Fatalf("published %d messages, want 2", len(stream.msgs))
+	}
+	var ids []string
+	for _, m := range stream.msgs {
+		var evt struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(m.Data, &evt); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Header.Get(goNats.MsgIdHdr); got != evt.ID {
+			t.Errorf("Nats-Msg-Id = %q, want the CloudEvent id %q", got, evt.ID)
+		}
+		ids = append(ids, evt.ID)
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("ids = %v, want one id for the same HA state", ids)
+	}
+}
+
+// TestPublishHAEvent_OldState verifies the previous state is published as
+// data.old_state in the shape of the event's own data, and omitted when unknown.
+func TestPublishHAEvent_OldState(t *testing.T) {
+	stream := &fakeStream{}
+	p := New(nil, natsx.NewAckedPublisher(stream))
+	ctx := context.Background()
+
+	old := &PrevState{State: "not_home", Attributes: map[string]any{"source": "gps"}, LastChanged: "2026-10-17T08:00:00Z"}
+	if err := p.PublishHAEvent(ctx, "person.sam", "home", map[string]any{"source": "wifi"}, "2026-10-17T09:00:00Z", "2026-10-17T09:00:00Z", old); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishHAEvent(ctx, "person.alex", "home", nil, "2026-10-17T09:00:00Z", "2026-10-17T09:00:00Z", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []schemas.CloudEvent
+	for _, m := range stream.msgs {
+		var evt schemas.CloudEvent
+		if err := json.Unmarshal(m.Data, &evt); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, evt)
+	}
+	got, ok := schemas.OldState(events[0])
+	want := map[string]any{"state": "not_home", "source": "gps", "last_changed": "2026-10-17T08:00:00Z"}
+	if !ok || !maps.Equal(got, want) {
+		t.Errorf("old_state = %v, want %v", got, want)
+	}
+	if tr := schemas.StateTransition(events[0], "source"); !tr.Changed() || tr.From != "gps" || tr.To != "wifi" {
+		t.Errorf("source transition = %+v, want gps → wifi", tr)
+	}
+	if _, ok := schemas.OldState(events[1]); ok {
+		t.Errorf("old_state published without a previous state: %v", events[1].Data)
+	}
+}
+
+func TestStateEventID(t *testing.T) {
+	const at = "2026-10-17T09:00:00Z"
+	base := stateEventID("light.porch", "on", at, at)
+	if got := stateEventID("light.porch", "on", "2026-10-17T11:00:00+02:00", "2026-10-17T09:00:00+00:00"); got != base {
+		t.Errorf("same instants in other offsets: id %s, want %s", got, base)
+	}
+	for name, id := range map[string]string{
+		"entity":       stateEventID("light.kitchen", "on", at, at),
+		"state":        stateEventID("light.porch", "off", at, at),
+		"last_changed": stateEventID("light.porch", "on", "2026-10-17T09:00:01Z", at),
+		"last_updated": stateEventID("light.porch", "on", at, "2026-10-17T09:00:01Z"),
+	} {
+		if id == base {
+			t.Errorf("a different %s gives the same id", name)
+		}
+	}
+}
+
+// TestPublishHAEvent_PassListReload verifies the id of a state does not follow its
+// projected attributes: republished after a passlist reload, it is the same event.
+func TestPublishHAEvent_PassListReload(t *testing.T) {
+	stream := &fakeStream{}
+	p := New(nil, natsx.NewAckedPublisher(stream))
+	ctx := context.Background()
+
+	const at = "2026-10-17T09:00:00Z"
+	for _, attrs := range []map[string]any{{"brightness": 128.0}, {"brightness": 128.0, "color_temp": 300.0}} {
+		if err := p.PublishHAEvent(ctx, "light.porch", "on", attrs, at, at, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if a, b := stream.msgs[0].Header.Get(goNats.MsgIdHdr), stream.msgs[1].Header.Get(goNats.MsgIdHdr); a != b {
+		t.Errorf("Nats-Msg-Ids %s and %s, want one id for one HA state", a, b)
+	}
+}
+
+// TestPublishHAEvent_AttributeBounce verifies an attribute that changes and changes
+// back (A→B→A) under one last_changed publishes three distinct events, so the stream
+// does not drop the third as a duplicate of the first.
+func TestPublishHAEvent_AttributeBounce(t *testing.T) {
+	stream := &fakeStream{}
+	p := New(nil, natsx.NewAckedPublisher(stream))
+	ctx := context.Background()
+
+	const changed = "2026-10-17T09:00:00Z"
+	for i, brightness := range []float64{128, 255, 128} {
+		updated := fmt.Sprintf("2026-10-17T09:0%d:00Z", i)
+		if err := p.PublishHAEvent(ctx, "light.porch", "on", map[string]any{"brightness": brightness}, changed, updated, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, m := range stream.msgs {
+		seen[m.Header.Get(goNats.MsgIdHdr)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("published %d distinct Nats-Msg-Ids, want 3", len(seen))
+	}
+}
@@ -15,8 +15,6 @@ import (
 	"log/slog"
 	"time"
 
-	goNats "github.com/nats-io/nats.go"
-
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
@@ -36,7 +34,7 @@ var eventRoutes = map[string]string{
 // Publish wraps payload in a CloudEvent and publishes it to the ha.events.* subject
 // derived from the payload "event" string. An unknown event is logged and returns
 // an error without publishing.
-func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *slog.Logger) error {
+func Publish(ctx context.Context, pub natsx.MsgPublisher, payload map[string]any, log *slog.Logger) error {
 	eventType, _ := payload["event"].(string)
 	subject, ok := eventRoutes[eventType]
 	if !ok {
@@ -64,7 +62,12 @@ func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *
 		return fmt.Errorf("ruby_home: marshal CloudEvent: %w", err)
 	}
 
-	if err := natsx.PublishWithContext(ctx, nc, subject, b); err != nil {
+	if err := schemas.Validate(evt); err != nil {
+		reject(ctx, pub, subject, b, err, log)
+		return fmt.Errorf("ruby_home: %w", err)
+	}
+
+	if err := natsx.PublishWithContext(ctx, pub, subject, b); err != nil {
 		return fmt.Errorf("ruby_home: publish %s: %w", subject, err)
 	}
 
@@ -76,6 +79,22 @@ func Publish(ctx context.Context, nc *goNats.Conn, payload map[string]any, log *
 	return nil
 }
 
+// reject routes an event that failed schema validation to rejected.{subject} instead
+// of publishing it (ADR-0014). A failed rejection is only logged: the caller already
+// reports the validation error to Home Assistant.
+func reject(ctx context.Context, pub natsx.MsgPublisher, subject string, data []byte, reason error, log *slog.Logger) {
+	log.Warn("ruby_home: event rejected",
+		slog.String("subject", subject),
+		slog.String("reason", reason.Error()),
+	)
+	if err := natsx.PublishRejected(ctx, pub, subject, data, "ruby_gateway", reason); err != nil {
+		log.Error("ruby_home: publish rejected event failed",
+			slog.String("subject", subject),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
 func newID() string {
 	var b [8]byte
 	_, _ = rand.Read(b[:])
@@ -5,6 +5,7 @@ package rubyhome_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"testing"
@@ -129,3 +130,49 @@ func assertCloudEventStored(t *testing.T, js natsgo.JetStreamContext, subject st
 		t.Errorf("CloudEvent.Source = %q, want ruby_gateway", evt.Source)
 	}
 }
+
+// TestInvalidEventRejected proves a write event that fails schema validation never
+// reaches HA_EVENTS: it is stored on rejected.{subject} with the reason (ADR-0014).
+func TestInvalidEventRejected(t *testing.T) {
+	nc := startNATS(t)
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("JetStream: %v", err)
+	}
+	if err := natsx.EnsureHAEventsStream(js); err != nil {
+		t.Fatalf("EnsureHAEventsStream: %v", err)
+	}
+	if err := natsx.EnsureRejectedStream(js); err != nil {
+		t.Fatalf("EnsureRejectedStream: %v", err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	err = rubyhome.Publish(context.Background(), nc, map[string]any{
+		"event": "ruby_home.directory.person.delete",
+	}, log)
+	if !errors.Is(err, schemas.ErrInvalidEvent) {
+		t.Fatalf("rubyhome.Publish = %v, want ErrInvalidEvent", err)
+	}
+
+	subject := schemas.HomeEventDirectoryPersonDelete
+	var msg *natsgo.RawStreamMsg
+	for range 20 {
+		msg, err = js.GetLastMsg(natsx.RejectedStream, natsx.RejectedSubject(subject))
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("no rejected message stored for %q: %v", subject, err)
+	}
+	if by := msg.Header.Get(natsx.HeaderRejectedBy); by != "ruby_gateway" {
+		t.Errorf("%s = %q, want ruby_gateway", natsx.HeaderRejectedBy, by)
+	}
+	if msg.Header.Get(natsx.HeaderRejectedReason) == "" {
+		t.Errorf("%s not set", natsx.HeaderRejectedReason)
+	}
+	if _, err := js.GetLastMsg("HA_EVENTS", subject); err == nil {
+		t.Errorf("invalid event was published to HA_EVENTS")
+	}
+}
@@ -4,11 +4,14 @@ package rubyhome
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"strings"
 	"testing"
 
+	"github.com/nats-io/nats.go"
+
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
@@ -72,6 +75,33 @@ func TestPublishMissingEventField(t *testing.T) {
 	}
 }
 
+// failingPublisher fails every publish, as a JetStream publisher does when no stream
+// acknowledges the message.
+type failingPublisher struct{ subjects []string }
+
+func (f *failingPublisher) PublishMsg(m *nats.Msg) error {
+	f.subjects = append(f.subjects, m.Subject)
+	return nats.ErrNoStreamResponse
+}
+
+// TestPublishInvalidEvent verifies a payload that fails its schema is not published
+// and reports ErrInvalidEvent (the rejection publish fails too, which is only logged).
+func TestPublishInvalidEvent(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pub := &failingPublisher{}
+	err := Publish(context.Background(), pub, map[string]any{
+		"event":        "ruby_home.childcare.provider.upsert",
+		"display_name": 42,
+	}, log)
+	if !errors.Is(err, schemas.ErrInvalidEvent) {
+		t.Fatalf("Publish = %v, want ErrInvalidEvent", err)
+	}
+	want := natsx.RejectedSubject(schemas.HomeEventChildcareProviderUpsert)
+	if len(pub.subjects) != 1 || pub.subjects[0] != want {
+		t.Errorf("published %v, want only %s", pub.subjects, want)
+	}
+}
+
 // TestEnsureCalendarIdempotencyKey covers the #138 fix: a calendar create with no
 // idempotency_key gets a deterministic content-derived one (stable across re-publishes);
 // an HA-supplied key is preserved; non-upsert events are untouched.
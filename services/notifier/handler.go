@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,12 +12,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/primaryrutabaga/ruby-core/pkg/audit"
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
@@ -37,6 +40,16 @@ type handler struct {
 	client  *http.Client
 	rec     *audit.Publisher
 	log     *slog.Logger
+
+	// rejects, when set, routes commands that fail schema validation (ADR-0014) to
+	// rejected.{subject} on behalf of consumer; they are acked, not retried.
+	rejects  rejectPublisher
+	consumer string
+}
+
+// rejectPublisher is the subset of nats.JetStreamContext used to route rejected commands.
+type rejectPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
 }
 
 func newHandler(haURL, haToken string, rec *audit.Publisher, log *slog.Logger) *handler {
@@ -49,16 +62,55 @@ func newHandler(haURL, haToken string, rec *audit.Publisher, log *slog.Logger) *
 	}
 }
 
+// handle is the natsx.Handler for the notifier worker pool: it runs process and
+// naks a failed command for redelivery (malformed payloads are terminated). Commands
+// arrive in either CloudEvents content mode.
+func (h *handler) handle(ctx context.Context, msg *nats.Msg) natsx.Result {
+	data, err := natsx.EventData(msg)
+	if err == nil && h.rejects != nil {
+		_, err = schemas.ValidateJSON(data)
+	}
+	if err != nil {
+		if h.rejects == nil {
+			return natsx.Term(err)
+		}
+		return h.reject(msg, err)
+	}
+	err = h.process(ctx, msg.Subject, data)
+	if err != nil && !errors.Is(err, natsx.ErrPoison) {
+		h.log.Warn("notifier: process failed, naking",
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+	}
+	return natsx.ResultOf(err)
+}
+
+// reject publishes a command that failed schema validation to the REJECTED stream and
+// acks it. If the rejection cannot be published the command is naked so it is not lost.
+func (h *handler) reject(msg *nats.Msg, reason error) natsx.Result {
+	rej := natsx.RejectedMsg(msg.Subject, msg.Header, msg.Data, h.consumer, reason)
+	if _, err := h.rejects.PublishMsg(rej); err != nil {
+		h.log.Error("notifier: publish rejected command failed",
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+		return natsx.Nak(fmt.Errorf("notifier: publish rejected command: %w", err))
+	}
+	h.log.Warn("notifier: command rejected",
+		slog.String("subject", msg.Subject),
+		slog.String("reason", reason.Error()),
+	)
+	return natsx.Result{Decision: natsx.DecisionAck, Outcome: natsx.OutcomeRejected}
+}
+
 // process is the consumer process func for the notifier pull consumer.
 // subject is the NATS subject (e.g. "ruby_engine.commands.notify.{evtID}").
 func (h *handler) process(ctx context.Context, subject string, data []byte) error {
 	var evt schemas.CloudEvent
 	if err := json.Unmarshal(data, &evt); err != nil {
-		h.log.Warn("notifier: unmarshal command",
-			slog.String("subject", subject),
-			slog.String("error", err.Error()),
-		)
-		return nil // malformed: ack and skip to avoid poison-pill loop
+		// Malformed: terminate rather than nak to avoid a poison-pill loop.
+		return fmt.Errorf("%w: unmarshal command: %v", natsx.ErrPoison, err)
 	}
 
 	if evt.Data == nil {
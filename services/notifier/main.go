@@ -9,8 +9,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/nats-io/nats.go"
-
 	"github.com/primaryrutabaga/ruby-core/pkg/audit"
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
 	"github.com/primaryrutabaga/ruby-core/pkg/logging"
@@ -91,7 +89,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	consumerCfg := natsx.DefaultPullConsumerConfig("COMMANDS", "notifier_processor", "ruby_engine.commands.notify.>")
+	consumerCfg, err := natsx.Manifest().Consumer("COMMANDS", "notifier_processor")
+	if err != nil {
+		logger.Error("nats: consumer config failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	sub, err := natsx.EnsurePullConsumer(js, consumerCfg)
 	if err != nil {
 		logger.Error("nats: ensure pull consumer failed", slog.String("error", err.Error()))
@@ -99,10 +101,29 @@ func main() {
 	}
 	logger.Info("nats: pull consumer ready", slog.String("consumer", "notifier_processor"))
 
+	// Commands that exhaust MaxDeliver are dead-lettered instead of dropped (ADR-0022).
+	// EnsureDLQStream is idempotent; the engine also calls it on startup.
+	if err := natsx.EnsureDLQStream(js); err != nil {
+		logger.Error("nats: ensure DLQ stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	// Commands that fail schema validation are rejected instead of retried (ADR-0014).
+	if err := natsx.EnsureRejectedStream(js); err != nil {
+		logger.Error("nats: ensure REJECTED stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	failures := natsx.NewFailureLog()
+	dlqFwd, err := natsx.NewDLQForwarder(nc, js, consumerCfg.Stream, consumerCfg.Durable, failures, logger)
+	if err != nil {
+		logger.Error("dlq forwarder init failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	auditPub := audit.NewPublisher(nc, "ruby_notifier", logger)
 	defer auditPub.Close()
 
 	h := newHandler(haCfg.URL, haCfg.Token, auditPub, logger)
+	h.rejects, h.consumer = js, consumerCfg.Durable
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
@@ -118,53 +139,20 @@ func main() {
 		logger.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
 	}
 
+	// Unlike the engine, the notifier does not use idempotency dedup — notifications are
+	// best-effort with JetStream redelivery backoff as the only retry mechanism; a command
+	// that still fails at MaxDeliver is dead-lettered with the last error recorded in failures.
+	pool, err := natsx.NewWorkerPool(sub, consumerCfg, h.handle, msgInstr, failures, logger)
+	if err != nil {
+		logger.Error("worker pool init failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	logger.Info("notifier running")
-	runConsumer(ctx, sub, h, consumerCfg.FetchBatch, "COMMANDS", "notifier_processor", msgInstr, logger)
+	go func() { _ = dlqFwd.Run(ctx) }()
+	_ = pool.Run(ctx)
 	logger.Info("notifier stopped")
 	if natsLost.Load() {
 		os.Exit(1)
 	}
 }
-
-// runConsumer is a simple pull consumer loop for the notifier. Unlike the engine,
-// the notifier does not use idempotency dedup or DLQ routing — notifications are
-// best-effort with JetStream redelivery backoff as the only retry mechanism.
-func runConsumer(ctx context.Context, sub *nats.Subscription, h *handler, batchSize int, stream, consumer string, instr *natsx.MsgInstruments, log *slog.Logger) {
-	for {
-		if ctx.Err() != nil {
-			return
-		}
-		msgs, err := sub.Fetch(batchSize, nats.MaxWait(5*nats.DefaultTimeout))
-		if err != nil {
-			// Survive a NATS bounce (#18): exit only on ctx cancellation; back off on a
-			// transient error so we don't hot-spin while nats.go reconnects.
-			switch natsx.ClassifyFetchErr(err, ctx.Err()) {
-			case natsx.FetchStop:
-				return
-			case natsx.FetchBackoff:
-				log.Warn("notifier: transient fetch error, retrying", slog.String("error", err.Error()))
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(natsx.FetchRetryBackoff):
-				}
-			}
-			continue
-		}
-		for _, msg := range msgs {
-			m := msg
-			instr.Observe(ctx, m, stream, consumer, func(sctx context.Context) string {
-				if err := h.process(sctx, m.Subject, m.Data); err != nil {
-					log.Warn("notifier: process failed, naking",
-						slog.String("subject", m.Subject),
-						slog.String("error", err.Error()),
-					)
-					_ = m.Nak()
-					return natsx.OutcomeFailure
-				}
-				_ = m.Ack()
-				return natsx.OutcomeSuccess
-			})
-		}
-	}
-}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -20,6 +21,7 @@ import (
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
 	"github.com/primaryrutabaga/ruby-core/pkg/schemas"
 )
 
@@ -33,7 +35,7 @@ type handler struct {
 	haURL   string
 	haToken string
 	client  *http.Client
-	nc      *nats.Conn
+	pub     natsx.MsgPublisher // acked JetStream publishes to PRESENCE
 	kv      nats.KeyValue
 	log     *slog.Logger
 
@@ -47,7 +49,7 @@ type handler struct {
 func newHandler(
 	cfg *PresenceConfig,
 	haURL, haToken string,
-	nc *nats.Conn,
+	pub natsx.MsgPublisher,
 	kv nats.KeyValue,
 	log *slog.Logger,
 ) *handler {
@@ -60,7 +62,7 @@ func newHandler(
 		haURL:          haURL,
 		haToken:        haToken,
 		client:         &http.Client{Timeout: 10 * time.Second},
-		nc:             nc,
+		pub:            pub,
 		kv:             kv,
 		log:            log,
 		statePublished: statePublished,
@@ -84,15 +86,29 @@ func (h *handler) initState() {
 	)
 }
 
+// handle is the natsx.Handler for the presence worker pool: it runs process and
+// naks a failed event for redelivery (malformed payloads are terminated). Events
+// arrive in either CloudEvents content mode.
+func (h *handler) handle(ctx context.Context, msg *nats.Msg) natsx.Result {
+	data, err := natsx.EventData(msg)
+	if err != nil {
+		return natsx.Term(err)
+	}
+	err = h.process(ctx, msg.Subject, data)
+	if err != nil && !errors.Is(err, natsx.ErrPoison) {
+		h.log.Warn("presence: process failed, naking",
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+	}
+	return natsx.ResultOf(err)
+}
+
 // process handles a NATS message from the HA_EVENTS pull consumer.
 func (h *handler) process(ctx context.Context, subject string, data []byte) error {
 	var evt schemas.CloudEvent
 	if err := json.Unmarshal(data, &evt); err != nil {
-		h.log.Warn("presence: unmarshal event",
-			slog.String("subject", subject),
-			slog.String("error", err.Error()),
-		)
-		return nil // malformed: ack and skip
+		return fmt.Errorf("%w: unmarshal event: %v", natsx.ErrPoison, err)
 	}
 
 	if evt.Data == nil {
@@ -270,7 +286,7 @@ func (h *handler) publishState(state string) {
 	}
 
 	subject := "ruby_presence.events.state." + h.cfg.PersonID
-	if err := h.nc.Publish(subject, data); err != nil {
+	if err := natsx.PublishWithContext(context.Background(), h.pub, subject, data); err != nil {
 		h.log.Error("presence: publish state event",
 			slog.String("person", h.cfg.PersonID),
 			slog.String("subject", subject),
@@ -9,8 +9,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/nats-io/nats.go"
-
 	"github.com/primaryrutabaga/ruby-core/pkg/boot"
 	"github.com/primaryrutabaga/ruby-core/pkg/logging"
 	"github.com/primaryrutabaga/ruby-core/pkg/natsx"
@@ -106,14 +104,21 @@ func main() {
 	}
 	logger.Info("nats: presence KV ready")
 
-	h := newHandler(presenceCfg, haCfg.URL, haCfg.Token, nc, kv, logger)
+	h := newHandler(presenceCfg, haCfg.URL, haCfg.Token, natsx.NewAckedPublisher(js), kv, logger)
 	h.initState()
 
 	// Filter subject: ha.events.{domain}.{name}
 	filterSubject := "ha.events." + presenceCfg.phoneEntityDomain() + "." + presenceCfg.phoneEntityName()
 	durableName := "presence_" + presenceCfg.PersonID
 
-	consumerCfg := natsx.DefaultPullConsumerConfig("HA_EVENTS", durableName, filterSubject)
+	// The manifest runs presence consumers on one worker: transitions must apply in the
+	// order the phone reported them.
+	consumerCfg, err := natsx.Manifest().Consumer("HA_EVENTS", durableName)
+	if err != nil {
+		logger.Error("nats: consumer config failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	consumerCfg.FilterSubject = filterSubject
 	sub, err := natsx.EnsurePullConsumer(js, consumerCfg)
 	if err != nil {
 		logger.Error("nats: ensure pull consumer failed", slog.String("error", err.Error()))
@@ -124,6 +129,19 @@ func main() {
 		slog.String("filter", filterSubject),
 	)
 
+	// Events that exhaust MaxDeliver are dead-lettered instead of dropped (ADR-0022).
+	// EnsureDLQStream is idempotent; the engine also calls it on startup.
+	if err := natsx.EnsureDLQStream(js); err != nil {
+		logger.Error("nats: ensure DLQ stream failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	failures := natsx.NewFailureLog()
+	dlqFwd, err := natsx.NewDLQForwarder(nc, js, consumerCfg.Stream, durableName, failures, logger)
+	if err != nil {
+		logger.Error("dlq forwarder init failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
@@ -139,49 +157,16 @@ func main() {
 		logger.Warn("otel: message instruments unavailable", slog.String("error", err.Error()))
 	}
 
-	runConsumer(ctx, sub, h, consumerCfg.FetchBatch, consumerCfg.Stream, consumerCfg.Durable, msgInstr, logger)
-	logger.Info("presence stopped")
-	if natsLost.Load() {
+	pool, err := natsx.NewWorkerPool(sub, consumerCfg, h.handle, msgInstr, failures, logger)
+	if err != nil {
+		logger.Error("worker pool init failed", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
-}
 
-func runConsumer(ctx context.Context, sub *nats.Subscription, h *handler, batchSize int, stream, consumer string, instr *natsx.MsgInstruments, log *slog.Logger) {
-	for {
-		if ctx.Err() != nil {
-			return
-		}
-		msgs, err := sub.Fetch(batchSize, nats.MaxWait(5*nats.DefaultTimeout))
-		if err != nil {
-			// Survive a NATS bounce (#18): exit only on ctx cancellation; back off on a
-			// transient error so we don't hot-spin while nats.go reconnects.
-			switch natsx.ClassifyFetchErr(err, ctx.Err()) {
-			case natsx.FetchStop:
-				return
-			case natsx.FetchBackoff:
-				log.Warn("presence: transient fetch error, retrying", slog.String("error", err.Error()))
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(natsx.FetchRetryBackoff):
-				}
-			}
-			continue
-		}
-		for _, msg := range msgs {
-			m := msg
-			instr.Observe(ctx, m, stream, consumer, func(sctx context.Context) string {
-				if err := h.process(sctx, m.Subject, m.Data); err != nil {
-					log.Warn("presence: process failed, naking",
-						slog.String("subject", m.Subject),
-						slog.String("error", err.Error()),
-					)
-					_ = m.Nak()
-					return natsx.OutcomeFailure
-				}
-				_ = m.Ack()
-				return natsx.OutcomeSuccess
-			})
-		}
+	go func() { _ = dlqFwd.Run(ctx) }()
+	_ = pool.Run(ctx)
+	logger.Info("presence stopped")
+	if natsLost.Load() {
+		os.Exit(1)
 	}
 }